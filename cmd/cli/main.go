@@ -4,6 +4,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
+
+	"loan-service/internal/application"
+	"loan-service/pkg/config"
+	"loan-service/pkg/database"
+	"loan-service/pkg/logger"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
@@ -18,6 +24,11 @@ var (
 	host           string
 	debug          bool
 	migrationsPath string
+
+	appConfigPath  string
+	appEnv         string
+	reconcileDate  string
+	settlementFile string
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -231,6 +242,85 @@ var configCmd = &cobra.Command{
 	},
 }
 
+// reconcileCmd represents the settlement reconciliation command
+var reconcileCmd = &cobra.Command{
+	Use:   "reconcile",
+	Short: "Reconcile a payment provider settlement report against recorded payments",
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("Running settlement reconciliation...")
+		if debug {
+			fmt.Println("Debug mode enabled")
+		}
+
+		// Default to yesterday, same as the scheduled job
+		date := time.Now().AddDate(0, 0, -1)
+		if reconcileDate != "" {
+			parsed, err := time.ParseInLocation("2006-01-02", reconcileDate, time.Local)
+			if err != nil {
+				fmt.Printf("Invalid date %q, expected YYYY-MM-DD: %v\n", reconcileDate, err)
+				return
+			}
+			date = parsed
+		}
+
+		// The reconciliation job uses the server configuration (database, payment, email)
+		cfg, err := config.Load(appConfigPath, appEnv)
+		if err != nil {
+			fmt.Printf("Error loading application config: %v\n", err)
+			return
+		}
+
+		appLogger := logger.NewLogger(cfg.Logger)
+
+		db, err := database.NewConnection(cfg.Database, appLogger)
+		if err != nil {
+			fmt.Printf("Error connecting to database: %v\n", err)
+			return
+		}
+		defer db.Close()
+
+		app := application.NewApplication().
+			WithConfig(cfg).
+			WithLogger(appLogger).
+			WithDatabase(db).
+			WithStorage().
+			WithScanner().
+			WithRepositories().
+			WithAdapters().
+			WithServices()
+
+		if app.Storage == nil {
+			fmt.Printf("Error initializing storage backend %q\n", cfg.Storage.Backend)
+			return
+		}
+
+		if app.Scanner == nil {
+			fmt.Printf("Error initializing malware scanner %q\n", cfg.Scanner.Backend)
+			return
+		}
+
+		if debug {
+			fmt.Printf("Report Date: %s\n", date.Format("2006-01-02"))
+			fmt.Printf("Settlement File: %s\n", settlementFile)
+		}
+
+		run, err := app.ReconciliationService.RunReconciliation(date, settlementFile)
+		if err != nil {
+			fmt.Printf("Error running reconciliation: %v\n", err)
+			return
+		}
+
+		fmt.Println("Reconciliation completed successfully!")
+		fmt.Printf("  Run ID: %s\n", run.ID)
+		fmt.Printf("  Settlement Records: %d\n", run.TotalRecords)
+		fmt.Printf("  Matched: %d\n", run.MatchedCount)
+		fmt.Printf("  Mismatches: %d\n", run.MismatchCount)
+		for _, m := range run.Mismatches {
+			fmt.Printf("    - [%s] %s %s\n", m.MismatchType, m.TransactionID, m.Notes)
+		}
+	},
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() {
 	err := rootCmd.Execute()
@@ -281,6 +371,12 @@ func init() {
 	migrateCmd.Flags().IntVarP(&port, "port", "p", 5432, "database port")
 	migrateCmd.Flags().StringVarP(&migrationsPath, "path", "m", "migrations", "migrations directory path")
 
+	// Local flags for reconcile command
+	reconcileCmd.Flags().StringVarP(&reconcileDate, "date", "d", "", "settlement report date YYYY-MM-DD (default yesterday)")
+	reconcileCmd.Flags().StringVarP(&settlementFile, "file", "f", "", "settlement report CSV file (default uses configured source)")
+	reconcileCmd.Flags().StringVar(&appConfigPath, "app-config", "./configs", "application config file path")
+	reconcileCmd.Flags().StringVarP(&appEnv, "env", "e", "local", "application environment (local, staging, production)")
+
 	// Bind flags to viper
 	viper.BindPFlag("database.host", migrateCmd.Flags().Lookup("host"))
 	viper.BindPFlag("database.port", migrateCmd.Flags().Lookup("port"))
@@ -292,6 +388,7 @@ func init() {
 	rootCmd.AddCommand(migrateDownCmd)
 	rootCmd.AddCommand(envCmd)
 	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(reconcileCmd)
 }
 
 func main() {
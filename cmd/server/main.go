@@ -2,8 +2,15 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"loan-service/internal/application"
 	"loan-service/internal/routes"
@@ -14,6 +21,9 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// shutdownTimeout bounds how long in-flight requests get to finish once the server is asked to stop
+const shutdownTimeout = 30 * time.Second
+
 func main() {
 	// Parse command line flags
 	env := flag.String("env", "local", "Environment (local, staging, production)")
@@ -44,6 +54,8 @@ func main() {
 		WithLogger(appLogger).
 		WithDatabase(db).
 		WithRedis().
+		WithStorage().
+		WithScanner().
 		WithRepositories().
 		WithAdapters().
 		WithServices().
@@ -79,16 +91,50 @@ func main() {
 
 	// Start cron service
 	app.CronService.Start()
-	defer app.CronService.Stop()
+
+	srv := &http.Server{
+		Addr:         ":" + cfg.Server.Port,
+		Handler:      r,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	appLogger.Info("Server starting", map[string]interface{}{
 		"port":        cfg.Server.Port,
 		"environment": cfg.App.Environment,
 	})
 
-	if err := r.Run(":" + cfg.Server.Port); err != nil {
-		appLogger.Fatal("Server failed to start", map[string]interface{}{
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- srv.ListenAndServe()
+	}()
+
+	var serveErr error
+	select {
+	case serveErr = <-serverErr:
+	case <-ctx.Done():
+		appLogger.Info("Shutting down server", map[string]interface{}{})
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		appLogger.Error("Server shutdown did not complete", map[string]interface{}{
 			"error": err.Error(),
 		})
 	}
+
+	// No new jobs or approvals can start now; let the running ones finish before the database is closed
+	app.CronService.Stop()
+	app.AutoInvestService.Wait()
+
+	if serveErr != nil && !errors.Is(serveErr, http.ErrServerClosed) {
+		appLogger.Fatal("Server failed to start", map[string]interface{}{
+			"error": serveErr.Error(),
+		})
+	}
+	appLogger.Info("Server stopped", map[string]interface{}{})
 }
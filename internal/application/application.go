@@ -12,30 +12,67 @@ import (
 	"loan-service/pkg/config"
 	"loan-service/pkg/logger"
 	"loan-service/pkg/redis"
+	"loan-service/pkg/scanner"
+	"loan-service/pkg/storage"
 )
 
 type Application struct {
 	// Core dependencies
-	Config *config.Config
-	Logger *logger.Logger
-	DB     *sql.DB
-	Redis  *redis.RedisClient
+	Config  *config.Config
+	Logger  *logger.Logger
+	DB      *sql.DB
+	Redis   *redis.RedisClient
+	Storage storage.Storage
+	Scanner scanner.ScannerInterface
 
 	// Repositories
-	LoanRepo repositories.LoanRepositoryInterface
+	LoanRepo           repositories.LoanRepositoryInterface
+	ReconciliationRepo repositories.ReconciliationRepositoryInterface
+	WalletRepo         repositories.WalletRepositoryInterface
+	WithdrawalRepo     repositories.WithdrawalRepositoryInterface
+	AutoInvestRepo     repositories.AutoInvestRepositoryInterface
+	ReservationRepo    repositories.ReservationRepositoryInterface
+	WaitlistRepo       repositories.WaitlistRepositoryInterface
+	TransferRepo       repositories.TransferRepositoryInterface
+	SignatureRepo      repositories.SignatureRepositoryInterface
+	FileRepo           repositories.FileRepositoryInterface
+	EmailTemplateRepo  repositories.EmailTemplateRepositoryInterface
+	NotificationRepo   repositories.NotificationRepositoryInterface
 
 	// Adapters
-	EmailAdapter   adapters.EmailAdapterInterface
-	PaymentAdapter adapters.PaymentAdapterInterface
-	FileAdapter    adapters.FileAdapterInterface
+	EmailAdapter     adapters.EmailAdapterInterface
+	PaymentAdapter   adapters.PaymentAdapterInterface
+	FileAdapter      adapters.FileAdapterInterface
+	DocumentAdapter  adapters.DocumentAdapterInterface
+	SignatureAdapter adapters.SignatureAdapterInterface
 
 	// Services
-	LoanService services.LoanServiceInterface
-	CronService *services.CronService
+	LoanService           services.LoanServiceInterface
+	ReconciliationService services.ReconciliationServiceInterface
+	WalletService         services.WalletServiceInterface
+	WithdrawalService     services.WithdrawalServiceInterface
+	AutoInvestService     services.AutoInvestServiceInterface
+	MarketplaceService    services.MarketplaceServiceInterface
+	ReservationService    services.ReservationServiceInterface
+	WaitlistService       services.WaitlistServiceInterface
+	TransferService       services.TransferServiceInterface
+	SignatureService      services.SignatureServiceInterface
+	FileService           services.FileServiceInterface
+	NotificationService   services.NotificationServiceInterface
+	CronService           *services.CronService
 
 	// Handlers
-	LoanHandler *handlers.LoanHandler
-	FileHandler *handlers.FileHandler
+	LoanHandler         *handlers.LoanHandler
+	FileHandler         *handlers.FileHandler
+	WalletHandler       *handlers.WalletHandler
+	WithdrawalHandler   *handlers.WithdrawalHandler
+	AutoInvestHandler   *handlers.AutoInvestHandler
+	MarketplaceHandler  *handlers.MarketplaceHandler
+	ReservationHandler  *handlers.ReservationHandler
+	WaitlistHandler     *handlers.WaitlistHandler
+	TransferHandler     *handlers.TransferHandler
+	SignatureHandler    *handlers.SignatureHandler
+	NotificationHandler *handlers.NotificationHandler
 }
 
 func NewApplication() *Application {
@@ -70,29 +107,201 @@ func (app *Application) WithRedis() *Application {
 	return app
 }
 
+func (app *Application) WithStorage() *Application {
+	store, err := storage.New(app.Config.Storage, app.Logger)
+	if err != nil {
+		app.Logger.Error("Failed to initialize storage", map[string]interface{}{
+			"error":   err.Error(),
+			"backend": app.Config.Storage.Backend,
+		})
+	}
+	app.Storage = store
+	return app
+}
+
+func (app *Application) WithScanner() *Application {
+	fileScanner, err := scanner.New(app.Config.Scanner, app.Logger)
+	if err != nil {
+		app.Logger.Error("Failed to initialize malware scanner", map[string]interface{}{
+			"error":   err.Error(),
+			"backend": app.Config.Scanner.Backend,
+		})
+	}
+	app.Scanner = fileScanner
+	return app
+}
+
 func (app *Application) WithRepositories() *Application {
 	app.LoanRepo = repositories.NewLoanRepository(app.DB, app.Logger)
+	app.ReconciliationRepo = repositories.NewReconciliationRepository(app.DB, app.Logger)
+	app.WalletRepo = repositories.NewWalletRepository(app.DB, app.Logger)
+	app.WithdrawalRepo = repositories.NewWithdrawalRepository(app.DB, app.Logger)
+	app.AutoInvestRepo = repositories.NewAutoInvestRepository(app.DB, app.Logger)
+	app.WaitlistRepo = repositories.NewWaitlistRepository(app.DB, app.Logger)
+	app.TransferRepo = repositories.NewTransferRepository(app.DB, app.Logger)
+	app.SignatureRepo = repositories.NewSignatureRepository(app.DB, app.Logger)
+	app.FileRepo = repositories.NewFileRepository(app.DB, app.Logger)
+	app.EmailTemplateRepo = repositories.NewEmailTemplateRepository(app.DB, app.Logger)
+	app.NotificationRepo = repositories.NewNotificationRepository(app.DB, app.Logger)
+
+	// Reservations live in Redis; without it investments are taken without reservations
+	if app.Redis != nil {
+		app.ReservationRepo = repositories.NewReservationRepository(app.Redis, app.Logger)
+	}
 	return app
 }
 
 func (app *Application) WithAdapters() *Application {
-	app.EmailAdapter = adapters.NewEmailAdapter(app.Config.Email, app.Logger)
+	emailTemplates, err := adapters.NewEmailTemplateSource(app.Config.Email, app.EmailTemplateRepo)
+	if err != nil {
+		app.Logger.Error("Failed to initialize email templates", map[string]interface{}{
+			"error":  err.Error(),
+			"source": app.Config.Email.TemplateSource,
+		})
+	}
+	app.EmailAdapter = adapters.NewEmailAdapter(app.Config.Email, emailTemplates, app.Logger)
 	app.PaymentAdapter = adapters.NewPaymentAdapter(app.Config.Payment, app.Logger)
-	app.FileAdapter = adapters.NewFileAdapter(app.Logger)
+	app.FileAdapter = adapters.NewFileAdapter(app.Storage, app.Logger)
+	app.DocumentAdapter = adapters.NewDocumentAdapter(app.Config.Documents, app.Logger)
+	app.SignatureAdapter = adapters.NewSignatureAdapter(app.Config.Signature, app.Logger)
 	return app
 }
 
 func (app *Application) WithServices() *Application {
+	app.NotificationService = services.NewNotificationService(
+		app.NotificationRepo,
+		app.LoanRepo,
+		app.EmailAdapter,
+		app.Config.Email,
+		app.Logger,
+	)
+
+	app.FileService = services.NewFileService(
+		app.FileRepo,
+		app.LoanRepo,
+		app.TransferRepo,
+		app.FileAdapter,
+		app.Scanner,
+		app.Config.Uploads,
+		app.Config.Downloads,
+		app.Logger,
+		app.DB,
+	)
+
 	app.LoanService = services.NewLoanService(
+		app.LoanRepo,
+		app.WalletRepo,
+		app.ReservationRepo,
+		app.SignatureRepo,
+		app.PaymentAdapter,
+		app.EmailAdapter,
+		app.DocumentAdapter,
+		app.FileService,
+		app.Config.InvestmentLimits,
+		app.Config.VisitProof,
+		app.Logger,
+		app.DB,
+	)
+
+	app.AutoInvestService = services.NewAutoInvestService(
+		app.AutoInvestRepo,
+		app.LoanRepo,
+		app.ReservationRepo,
+		app.LoanService,
+		app.Logger,
+	)
+	app.LoanService.AddApprovalListener(app.AutoInvestService)
+
+	app.ReservationService = services.NewReservationService(
+		app.ReservationRepo,
+		app.LoanRepo,
+		app.LoanService,
+		app.Config.Loan,
+		app.Logger,
+		app.DB,
+	)
+
+	// Without Redis the marketplace reads straight from the database
+	var marketplaceCache services.CacheInterface
+	if app.Redis != nil {
+		marketplaceCache = redis.NewCacheService(app.Redis, app.Logger)
+	}
+	app.MarketplaceService = services.NewMarketplaceService(
+		app.LoanRepo,
+		marketplaceCache,
+		app.Config.Marketplace,
+		app.Logger,
+	)
+	app.LoanService.AddChangeListener(app.MarketplaceService)
+
+	app.WaitlistService = services.NewWaitlistService(
+		app.WaitlistRepo,
+		app.LoanRepo,
+		app.ReservationRepo,
+		app.LoanService,
+		app.EmailAdapter,
+		app.NotificationService,
+		app.Logger,
+	)
+	app.LoanService.AddChangeListener(app.WaitlistService)
+
+	app.TransferService = services.NewTransferService(
+		app.TransferRepo,
+		app.LoanRepo,
+		app.WalletRepo,
+		app.DocumentAdapter,
+		app.FileService,
+		app.Config.InvestmentLimits,
+		app.Logger,
+		app.DB,
+	)
+
+	app.SignatureService = services.NewSignatureService(
+		app.SignatureRepo,
+		app.LoanRepo,
+		app.SignatureAdapter,
+		app.FileService,
+		app.Config.Signature,
+		app.Logger,
+		app.DB,
+	)
+
+	app.WalletService = services.NewWalletService(
+		app.WalletRepo,
 		app.LoanRepo,
 		app.PaymentAdapter,
+		app.Logger,
+		app.DB,
+	)
+
+	app.WithdrawalService = services.NewWithdrawalService(
+		app.WithdrawalRepo,
+		app.WalletRepo,
+		app.LoanRepo,
+		app.PaymentAdapter,
+		app.Config.Wallet,
+		app.Logger,
+		app.DB,
+	)
+
+	app.ReconciliationService = services.NewReconciliationService(
+		app.ReconciliationRepo,
+		app.PaymentAdapter,
 		app.EmailAdapter,
+		app.Config.Reconciliation,
 		app.Logger,
 		app.DB,
 	)
 
 	app.CronService = services.NewCronService(
 		app.LoanRepo,
+		app.LoanService,
+		app.ReconciliationService,
+		app.WaitlistService,
+		app.FileService,
+		app.NotificationService,
+		app.WithdrawalService,
+		app.WalletService,
 		app.EmailAdapter,
 		app.Logger,
 		app.DB,
@@ -104,7 +313,16 @@ func (app *Application) WithServices() *Application {
 
 func (app *Application) WithHandlers() *Application {
 	app.LoanHandler = handlers.NewLoanHandler(app.LoanService, app.Logger)
-	app.FileHandler = handlers.NewFileHandler(app.Logger, app.FileAdapter)
+	app.FileHandler = handlers.NewFileHandler(app.Logger, app.FileService)
+	app.WalletHandler = handlers.NewWalletHandler(app.WalletService, app.Logger)
+	app.WithdrawalHandler = handlers.NewWithdrawalHandler(app.WithdrawalService, app.Logger)
+	app.AutoInvestHandler = handlers.NewAutoInvestHandler(app.AutoInvestService, app.Logger)
+	app.MarketplaceHandler = handlers.NewMarketplaceHandler(app.MarketplaceService, app.Logger)
+	app.ReservationHandler = handlers.NewReservationHandler(app.ReservationService, app.Logger)
+	app.WaitlistHandler = handlers.NewWaitlistHandler(app.WaitlistService, app.Logger)
+	app.TransferHandler = handlers.NewTransferHandler(app.TransferService, app.Logger)
+	app.SignatureHandler = handlers.NewSignatureHandler(app.SignatureService, app.Logger)
+	app.NotificationHandler = handlers.NewNotificationHandler(app.NotificationService, app.Logger)
 	return app
 }
 
@@ -121,6 +339,12 @@ func (app *Application) Validate() error {
 	if app.Redis == nil {
 		return errors.New("redis not initialized")
 	}
+	if app.Storage == nil {
+		return errors.New("storage not initialized")
+	}
+	if app.Scanner == nil {
+		return errors.New("scanner not initialized")
+	}
 
 	return nil
 }
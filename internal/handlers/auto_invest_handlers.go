@@ -0,0 +1,116 @@
+// internal/handlers/auto_invest_handlers.go
+package handlers
+
+import (
+	"loan-service/internal/models"
+	"loan-service/internal/services"
+	"loan-service/pkg/logger"
+	"loan-service/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type AutoInvestHandler struct {
+	autoInvestService services.AutoInvestServiceInterface
+	logger            *logger.Logger
+}
+
+func NewAutoInvestHandler(autoInvestService services.AutoInvestServiceInterface, logger *logger.Logger) *AutoInvestHandler {
+	return &AutoInvestHandler{
+		autoInvestService: autoInvestService,
+		logger:            logger,
+	}
+}
+
+// CreateRule handles creating an auto-invest rule for an investor
+func (h *AutoInvestHandler) CreateRule(c *gin.Context) {
+
+	investorID := c.Param("investor_id")
+
+	// Parse investor ID
+	id, err := uuid.Parse(investorID)
+	if err != nil {
+		response.BadRequest(c, "Invalid investor ID format")
+		return
+	}
+
+	var req models.CreateAutoInvestRuleRequest
+
+	// First, bind JSON to get the raw data
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+
+	// Validate the request using struct tags
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		response.ValidationErrorFromValidator(c, "Validation failed", err)
+		return
+	}
+
+	rule, err := h.autoInvestService.CreateRule(id, &req)
+	if err != nil {
+		h.logger.Error("Failed to create auto-invest rule", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": id.String(),
+		})
+		response.BadRequest(c, "Failed to create auto-invest rule: "+err.Error())
+		return
+	}
+
+	response.Created(c, "Auto-invest rule created successfully", rule)
+}
+
+// GetRules handles listing the auto-invest rules of an investor
+func (h *AutoInvestHandler) GetRules(c *gin.Context) {
+
+	investorID := c.Param("investor_id")
+
+	// Parse investor ID
+	id, err := uuid.Parse(investorID)
+	if err != nil {
+		response.BadRequest(c, "Invalid investor ID format")
+		return
+	}
+
+	rules, err := h.autoInvestService.GetRules(id)
+	if err != nil {
+		response.BadRequest(c, "Failed to get auto-invest rules")
+		return
+	}
+
+	response.Success(c, "Auto-invest rules retrieved successfully", rules)
+}
+
+// DeactivateRule handles switching off an auto-invest rule
+func (h *AutoInvestHandler) DeactivateRule(c *gin.Context) {
+
+	// Parse investor ID
+	investorID, err := uuid.Parse(c.Param("investor_id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid investor ID format")
+		return
+	}
+
+	// Parse rule ID
+	ruleID, err := uuid.Parse(c.Param("rule_id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid rule ID format")
+		return
+	}
+
+	if err := h.autoInvestService.DeactivateRule(investorID, ruleID); err != nil {
+		h.logger.Error("Failed to deactivate auto-invest rule", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": investorID.String(),
+			"rule_id":     ruleID.String(),
+		})
+		response.BadRequest(c, "Failed to deactivate auto-invest rule: "+err.Error())
+		return
+	}
+
+	response.Deleted(c, "Auto-invest rule deactivated successfully")
+}
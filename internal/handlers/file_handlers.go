@@ -1,41 +1,115 @@
+// internal/handlers/file_handlers.go
 package handlers
 
 import (
-	"loan-service/pkg/adapters"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"path"
+
+	"loan-service/internal/constant"
+	"loan-service/internal/models"
+	"loan-service/internal/services"
 	"loan-service/pkg/logger"
 	"loan-service/pkg/response"
+	"loan-service/pkg/signedurl"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
 )
 
 type FileHandler struct {
 	logger      *logger.Logger
-	fileAdapter adapters.FileAdapterInterface
+	fileService services.FileServiceInterface
 }
 
-func NewFileHandler(logger *logger.Logger, fileAdapter adapters.FileAdapterInterface) *FileHandler {
+func NewFileHandler(logger *logger.Logger, fileService services.FileServiceInterface) *FileHandler {
 	return &FileHandler{
 		logger:      logger,
-		fileAdapter: fileAdapter,
+		fileService: fileService,
 	}
 }
 
 func (h *FileHandler) UploadFile(c *gin.Context) {
 
+	form, ok := h.parseUploadForm(c)
+	if !ok {
+		return
+	}
+
+	if len(form.files) > 1 {
+		response.BadRequest(c, "Upload several files together through /api/v1/files/bundles")
+		return
+	}
+
+	fileUpload, err := h.fileService.UploadFile(form.files[0], form.entityType, form.entityID, form.uploadedBy)
+	if err != nil {
+		h.logger.Error("Failed to upload file", map[string]interface{}{
+			"error": err.Error(),
+		})
+		var validationErr *models.FileValidationError
+		if errors.As(err, &validationErr) {
+			response.BadRequestWithCode(c, validationErr.Code, "Invalid file: "+validationErr.Message)
+			return
+		}
+		response.InternalError(c, "Failed to upload file")
+		return
+	}
+
+	response.Success(c, "File uploaded successfully", fileUpload)
+}
+
+// UploadBundle handles uploading several files, e.g. the proof images of one visit, as one document bundle
+func (h *FileHandler) UploadBundle(c *gin.Context) {
+
+	form, ok := h.parseUploadForm(c)
+	if !ok {
+		return
+	}
+
+	bundle, err := h.fileService.UploadBundle(form.files, form.entityType, form.entityID, form.uploadedBy)
+	if err != nil {
+		h.logger.Error("Failed to upload file bundle", map[string]interface{}{
+			"error":      err.Error(),
+			"file_count": len(form.files),
+		})
+		var validationErr *models.FileValidationError
+		if errors.As(err, &validationErr) {
+			response.BadRequestWithCode(c, validationErr.Code, "Invalid file: "+err.Error())
+			return
+		}
+		response.InternalError(c, "Failed to upload file bundle")
+		return
+	}
+
+	response.Created(c, "File bundle uploaded successfully", bundle)
+}
+
+type uploadForm struct {
+	files      []*multipart.FileHeader
+	entityType string
+	entityID   uuid.UUID
+	uploadedBy uuid.UUID
+}
+
+// parseUploadForm reads the files and their entity from a multipart upload, responding itself when it is invalid
+func (h *FileHandler) parseUploadForm(c *gin.Context) (*uploadForm, bool) {
 	form, err := c.MultipartForm()
 	if err != nil {
 		h.logger.Error("Failed to get multipart form", map[string]interface{}{
 			"error": err.Error(),
 		})
 		response.BadRequest(c, "Failed to get multipart form")
-		return
+		return nil, false
 	}
 
 	files := form.File["file"]
 	if len(files) == 0 {
 		h.logger.Error("No files uploaded", map[string]interface{}{})
 		response.BadRequest(c, "No files uploaded")
-		return
+		return nil, false
 	}
 
 	// Extract entity type from form
@@ -55,19 +129,211 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 			"entity_type": entityType,
 		})
 		response.BadRequest(c, "Invalid entity type. Must be 'approval', 'disbursement', or 'loan'")
-		return
+		return nil, false
+	}
+
+	// The entity may not exist yet, e.g. a visit proof uploaded before the approval is created
+	entityID := uuid.Nil
+	if entityIDs := form.Value["entity_id"]; len(entityIDs) > 0 && entityIDs[0] != "" {
+		entityID, err = uuid.Parse(entityIDs[0])
+		if err != nil {
+			response.BadRequest(c, "Invalid entity ID")
+			return nil, false
+		}
+	}
+
+	uploadedBy := uuid.MustParse(constant.SystemEmployeeID)
+	if uploaders := form.Value["uploaded_by"]; len(uploaders) > 0 && uploaders[0] != "" {
+		uploadedBy, err = uuid.Parse(uploaders[0])
+		if err != nil {
+			response.BadRequest(c, "Invalid uploader ID")
+			return nil, false
+		}
 	}
 
-	file := files[0]
+	return &uploadForm{
+		files:      files,
+		entityType: entityType,
+		entityID:   entityID,
+		uploadedBy: uploadedBy,
+	}, true
+}
 
-	fileUpload, err := h.fileAdapter.UploadFile(file, entityType)
+// ReplaceFile handles uploading a new version of a document, which deactivates the version it replaces
+func (h *FileHandler) ReplaceFile(c *gin.Context) {
+
+	fileID, err := uuid.Parse(c.Param("file_id"))
 	if err != nil {
-		h.logger.Error("Failed to upload file", map[string]interface{}{
+		response.BadRequest(c, "Invalid file ID format")
+		return
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		h.logger.Error("Failed to get multipart form", map[string]interface{}{
 			"error": err.Error(),
 		})
-		response.BadRequest(c, "Failed to upload file")
+		response.BadRequest(c, "Failed to get multipart form")
 		return
 	}
 
-	response.Success(c, "File uploaded successfully", fileUpload)
+	files := form.File["file"]
+	if len(files) != 1 {
+		response.BadRequest(c, "Upload exactly one file as the new version")
+		return
+	}
+
+	uploadedBy := uuid.MustParse(constant.SystemEmployeeID)
+	if uploaders := form.Value["uploaded_by"]; len(uploaders) > 0 && uploaders[0] != "" {
+		uploadedBy, err = uuid.Parse(uploaders[0])
+		if err != nil {
+			response.BadRequest(c, "Invalid uploader ID")
+			return
+		}
+	}
+
+	fileUpload, err := h.fileService.ReplaceFile(fileID, files[0], uploadedBy)
+	if err != nil {
+		h.logger.Error("Failed to replace file", map[string]interface{}{
+			"error":   err.Error(),
+			"file_id": fileID.String(),
+		})
+		var validationErr *models.FileValidationError
+		switch {
+		case errors.Is(err, services.ErrFileNotFound):
+			response.NotFound(c, "File not found")
+		case errors.As(err, &validationErr):
+			response.BadRequestWithCode(c, validationErr.Code, "Invalid file: "+validationErr.Message)
+		default:
+			response.InternalError(c, "Failed to replace file")
+		}
+		return
+	}
+
+	response.Created(c, "File version uploaded successfully", fileUpload)
+}
+
+// GetFileVersions lists every version of a document for staff
+func (h *FileHandler) GetFileVersions(c *gin.Context) {
+
+	fileID, err := uuid.Parse(c.Param("file_id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid file ID format")
+		return
+	}
+
+	employeeID, err := uuid.Parse(c.Query("employee_id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid or missing employee ID")
+		return
+	}
+
+	versions, err := h.fileService.GetFileVersions(fileID, employeeID)
+	if err != nil {
+		h.logger.Error("Failed to get file versions", map[string]interface{}{
+			"error":   err.Error(),
+			"file_id": fileID.String(),
+		})
+		switch {
+		case errors.Is(err, services.ErrFileNotFound):
+			response.NotFound(c, "File not found")
+		case errors.Is(err, services.ErrFileAccessDenied):
+			response.Forbidden(c, "Not allowed to view this file's history")
+		default:
+			response.InternalError(c, "Failed to get file versions")
+		}
+		return
+	}
+
+	response.Success(c, "File versions retrieved successfully", versions)
+}
+
+// CreateDownloadURL handles a request for a signed, expiring URL to download a file
+func (h *FileHandler) CreateDownloadURL(c *gin.Context) {
+
+	fileID, err := uuid.Parse(c.Param("file_id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid file ID format")
+		return
+	}
+
+	var req models.CreateDownloadURLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+
+	if err := validator.New().Struct(req); err != nil {
+		response.ValidationErrorFromValidator(c, "Validation failed", err)
+		return
+	}
+
+	downloadURL, err := h.fileService.CreateDownloadURL(fileID, &req)
+	if err != nil {
+		h.logger.Error("Failed to create download URL", map[string]interface{}{
+			"error":   err.Error(),
+			"file_id": fileID.String(),
+		})
+		switch {
+		case errors.Is(err, services.ErrFileNotFound):
+			response.NotFound(c, "File not found")
+		case errors.Is(err, services.ErrVariantNotFound):
+			response.NotFound(c, "File has no "+string(req.Variant))
+		case errors.Is(err, services.ErrFileAccessDenied):
+			response.Forbidden(c, "Not allowed to access this file")
+		case errors.Is(err, services.ErrFileQuarantined):
+			response.Forbidden(c, "File is quarantined until it passes the malware scan")
+		default:
+			response.InternalError(c, "Failed to create download URL")
+		}
+		return
+	}
+
+	response.Created(c, "Download URL created successfully", downloadURL)
+}
+
+// Download streams a file through a signed URL; Range requests are served as partial content
+func (h *FileHandler) Download(c *gin.Context) {
+
+	fileID, err := uuid.Parse(c.Param("file_id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid file ID format")
+		return
+	}
+
+	variant := models.FileVariantName(c.Query("variant"))
+	if variant != "" && variant != models.FileVariantThumbnail && variant != models.FileVariantPreview {
+		response.BadRequest(c, "Invalid variant. Must be 'thumbnail' or 'preview'")
+		return
+	}
+
+	upload, content, err := h.fileService.OpenDownload(fileID, variant, c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		switch {
+		case errors.Is(err, signedurl.ErrExpired):
+			response.Forbidden(c, "Download URL has expired")
+		case errors.Is(err, signedurl.ErrInvalidSignature):
+			response.Forbidden(c, "Invalid download URL signature")
+		case errors.Is(err, services.ErrFileNotFound):
+			response.NotFound(c, "File not found")
+		case errors.Is(err, services.ErrVariantNotFound):
+			response.NotFound(c, "File has no "+string(variant))
+		case errors.Is(err, services.ErrFileQuarantined):
+			response.Forbidden(c, "File is quarantined until it passes the malware scan")
+		default:
+			h.logger.Error("Failed to open file for download", map[string]interface{}{
+				"error":   err.Error(),
+				"file_id": fileID.String(),
+			})
+			response.InternalError(c, "Failed to download file")
+		}
+		return
+	}
+	defer content.Close()
+
+	// Signed URLs are handed to a single caller, so shared caches must not keep the content
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("Content-Type", upload.ContentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(upload.FileName)))
+	http.ServeContent(c.Writer, c.Request, path.Base(upload.FileName), upload.UpdatedAt, content)
 }
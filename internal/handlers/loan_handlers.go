@@ -2,6 +2,7 @@
 package handlers
 
 import (
+	"errors"
 	"time"
 
 	"loan-service/internal/models"
@@ -126,19 +127,35 @@ func (h *LoanHandler) ApproveLoan(c *gin.Context) {
 
 	approval, err := h.loanService.ProcessApproveLoan(id, &req)
 	if err != nil {
+		var fileErr *models.FileValidationError
+		if errors.As(err, &fileErr) {
+			response.BadRequestWithCode(c, fileErr.Code, "Invalid visit proof: "+fileErr.Message)
+			return
+		}
+		var visitErr *models.VisitProofError
+		if errors.As(err, &visitErr) {
+			response.BadRequestWithCode(c, visitErr.Code, "Visit proof check failed: "+visitErr.Message)
+			return
+		}
 		response.BadRequest(c, "Failed to approve loan")
 		return
 	}
 
 	response.Success(c, "Loan approved successfully", gin.H{
-		"id":                     approval.ID,
-		"loan_id":                approval.LoanID,
-		"validator_id":           approval.ValidatorID,
-		"approval_date":          approval.ApprovalDate,
-		"visit_proof_image_url":  approval.VisitProofImageURL,
-		"visit_proof_image_type": approval.VisitProofImageType,
-		"notes":                  approval.Notes,
-		"state":                  models.LoanStateApproved,
+		"id":                      approval.ID,
+		"loan_id":                 approval.LoanID,
+		"validator_id":            approval.ValidatorID,
+		"approval_date":           approval.ApprovalDate,
+		"visit_proof_image_url":   approval.VisitProofImageURL,
+		"visit_proof_image_type":  approval.VisitProofImageType,
+		"visit_proof_bundle_id":   approval.VisitProofBundleID,
+		"visit_proof_captured_at": approval.VisitProofCapturedAt,
+		"visit_proof_latitude":    approval.VisitProofLatitude,
+		"visit_proof_longitude":   approval.VisitProofLongitude,
+		"visit_distance_meters":   approval.VisitDistanceMeters,
+		"visit_proof_warnings":    approval.VisitProofWarnings,
+		"notes":                   approval.Notes,
+		"state":                   models.LoanStateApproved,
 	})
 
 }
@@ -188,6 +205,11 @@ func (h *LoanHandler) AddInvestment(c *gin.Context) {
 			"error":   err.Error(),
 			"loan_id": id.String(),
 		})
+		var limitErr *models.InvestmentLimitError
+		if errors.As(err, &limitErr) {
+			response.BadRequestWithCode(c, limitErr.Code, "Failed to process investment: "+limitErr.Message)
+			return
+		}
 		response.BadRequest(c, "Failed to process investment: "+err.Error())
 		return
 	}
@@ -240,6 +262,11 @@ func (h *LoanHandler) DisburseLoan(c *gin.Context) {
 			"error":   err.Error(),
 			"loan_id": id.String(),
 		})
+		var fileErr *models.FileValidationError
+		if errors.As(err, &fileErr) {
+			response.BadRequestWithCode(c, fileErr.Code, "Invalid disbursement file: "+fileErr.Message)
+			return
+		}
 		response.BadRequest(c, "Failed to process disbursement: "+err.Error())
 		return
 	}
@@ -247,3 +274,176 @@ func (h *LoanHandler) DisburseLoan(c *gin.Context) {
 	response.Success(c, "Loan disbursed successfully", disbursement)
 
 }
+
+// CancelLoan handles cancelling a loan before disbursement
+func (h *LoanHandler) CancelLoan(c *gin.Context) {
+
+	loanID := c.Param("loan_id")
+
+	// Parse loan ID
+	id, err := uuid.Parse(loanID)
+	if err != nil {
+		response.BadRequest(c, "Invalid loan ID format")
+		return
+	}
+
+	var req models.CancelLoanRequest
+
+	// First, bind JSON to get the raw data
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+
+	// Validate the request using struct tags
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		response.ValidationErrorFromValidator(c, "Validation failed", err)
+		return
+	}
+
+	h.logger.Info("Cancelling loan", map[string]interface{}{
+		"loan_id": id.String(),
+		"request": req,
+	})
+
+	loan, err := h.loanService.ProcessCancelLoan(id, &req)
+	if err != nil {
+		h.logger.Error("Failed to cancel loan", map[string]interface{}{
+			"error":   err.Error(),
+			"loan_id": id.String(),
+		})
+		response.BadRequest(c, "Failed to cancel loan: "+err.Error())
+		return
+	}
+
+	response.Success(c, "Loan cancelled successfully", loan)
+}
+
+// CancelInvestment handles an investor backing out of an investment while the loan is still approved
+func (h *LoanHandler) CancelInvestment(c *gin.Context) {
+
+	// Parse loan ID
+	loanID, err := uuid.Parse(c.Param("loan_id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid loan ID format")
+		return
+	}
+
+	// Parse investment ID
+	investmentID, err := uuid.Parse(c.Param("investment_id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid investment ID format")
+		return
+	}
+
+	var req models.CancelInvestmentRequest
+
+	// First, bind JSON to get the raw data
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+
+	// Validate the request using struct tags
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		response.ValidationErrorFromValidator(c, "Validation failed", err)
+		return
+	}
+
+	cancellation, err := h.loanService.ProcessCancelInvestment(loanID, investmentID, &req)
+	if err != nil {
+		h.logger.Error("Failed to cancel investment", map[string]interface{}{
+			"error":         err.Error(),
+			"loan_id":       loanID.String(),
+			"investment_id": investmentID.String(),
+		})
+		response.BadRequest(c, "Failed to cancel investment: "+err.Error())
+		return
+	}
+
+	response.Success(c, "Investment cancelled successfully", cancellation)
+}
+
+// GetInvestorLimits handles getting the investment limits applied to an investor
+func (h *LoanHandler) GetInvestorLimits(c *gin.Context) {
+
+	investorID := c.Param("investor_id")
+
+	// Parse investor ID
+	id, err := uuid.Parse(investorID)
+	if err != nil {
+		response.BadRequest(c, "Invalid investor ID format")
+		return
+	}
+
+	limits, err := h.loanService.GetInvestorLimits(id)
+	if err != nil {
+		response.BadRequest(c, "Failed to get investor limits")
+		return
+	}
+
+	response.Success(c, "Investor limits retrieved successfully", limits)
+}
+
+// GetInvestorPortfolio handles getting an investor's investments, totals and yield
+func (h *LoanHandler) GetInvestorPortfolio(c *gin.Context) {
+
+	investorID := c.Param("investor_id")
+
+	// Parse investor ID
+	id, err := uuid.Parse(investorID)
+	if err != nil {
+		response.BadRequest(c, "Invalid investor ID format")
+		return
+	}
+
+	portfolio, err := h.loanService.GetInvestorPortfolio(id)
+	if err != nil {
+		response.BadRequest(c, "Failed to get investor portfolio")
+		return
+	}
+
+	response.Success(c, "Investor portfolio retrieved successfully", portfolio)
+}
+
+// SetInvestorLimits handles overriding the global investment limits for an investor
+func (h *LoanHandler) SetInvestorLimits(c *gin.Context) {
+
+	investorID := c.Param("investor_id")
+
+	// Parse investor ID
+	id, err := uuid.Parse(investorID)
+	if err != nil {
+		response.BadRequest(c, "Invalid investor ID format")
+		return
+	}
+
+	var req models.SetInvestorLimitsRequest
+
+	// First, bind JSON to get the raw data
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+
+	// Validate the request using struct tags
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		response.ValidationErrorFromValidator(c, "Validation failed", err)
+		return
+	}
+
+	limits, err := h.loanService.SetInvestorLimits(id, &req)
+	if err != nil {
+		h.logger.Error("Failed to set investor limits", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": id.String(),
+		})
+		response.BadRequest(c, "Failed to set investor limits: "+err.Error())
+		return
+	}
+
+	response.Updated(c, "Investor limits updated successfully", limits)
+}
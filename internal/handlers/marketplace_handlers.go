@@ -0,0 +1,53 @@
+// internal/handlers/marketplace_handlers.go
+package handlers
+
+import (
+	"loan-service/internal/models"
+	"loan-service/internal/services"
+	"loan-service/pkg/logger"
+	"loan-service/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type MarketplaceHandler struct {
+	marketplaceService services.MarketplaceServiceInterface
+	logger             *logger.Logger
+}
+
+func NewMarketplaceHandler(marketplaceService services.MarketplaceServiceInterface, logger *logger.Logger) *MarketplaceHandler {
+	return &MarketplaceHandler{
+		marketplaceService: marketplaceService,
+		logger:             logger,
+	}
+}
+
+// GetLoans handles listing the loans open for investment
+func (h *MarketplaceHandler) GetLoans(c *gin.Context) {
+
+	var query models.MarketplaceLoansQuery
+
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "Invalid query parameters")
+		return
+	}
+
+	// Validate the query using struct tags
+	validate := validator.New()
+	if err := validate.Struct(query); err != nil {
+		response.ValidationErrorFromValidator(c, "Validation failed", err)
+		return
+	}
+
+	loans, err := h.marketplaceService.GetLoans(query)
+	if err != nil {
+		h.logger.Error("Failed to get marketplace loans", map[string]interface{}{
+			"error": err.Error(),
+		})
+		response.InternalError(c, "Failed to get marketplace loans")
+		return
+	}
+
+	response.Success(c, "Marketplace loans retrieved successfully", loans)
+}
@@ -0,0 +1,104 @@
+// internal/handlers/notification_handlers.go
+package handlers
+
+import (
+	"errors"
+
+	"loan-service/internal/models"
+	"loan-service/internal/services"
+	"loan-service/pkg/logger"
+	"loan-service/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type NotificationHandler struct {
+	notificationService services.NotificationServiceInterface
+	logger              *logger.Logger
+}
+
+func NewNotificationHandler(notificationService services.NotificationServiceInterface, logger *logger.Logger) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: notificationService,
+		logger:              logger,
+	}
+}
+
+// GetFailedEmailNotifications lists the emails that ran out of delivery attempts for staff
+func (h *NotificationHandler) GetFailedEmailNotifications(c *gin.Context) {
+
+	employeeID, err := uuid.Parse(c.Query("employee_id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid or missing employee ID")
+		return
+	}
+
+	notifications, err := h.notificationService.GetFailedEmailNotifications(employeeID)
+	if err != nil {
+		h.logger.Error("Failed to get failed email notifications", map[string]interface{}{
+			"error":       err.Error(),
+			"employee_id": employeeID.String(),
+		})
+		if errors.Is(err, services.ErrEmailRetryNotAllowed) {
+			response.Forbidden(c, "Not allowed to view failed email notifications")
+			return
+		}
+		response.InternalError(c, "Failed to get failed email notifications")
+		return
+	}
+
+	response.Success(c, "Failed email notifications retrieved successfully", notifications)
+}
+
+// RetryEmailNotification handles an employee sending a failed email notification again
+func (h *NotificationHandler) RetryEmailNotification(c *gin.Context) {
+
+	notificationID, err := uuid.Parse(c.Param("notification_id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid notification ID format")
+		return
+	}
+
+	var req models.RetryEmailNotificationRequest
+
+	// First, bind JSON to get the raw data
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+
+	// Validate the request using struct tags
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		response.ValidationErrorFromValidator(c, "Validation failed", err)
+		return
+	}
+
+	notification, err := h.notificationService.RetryEmailNotification(notificationID, &req)
+	if err != nil {
+		h.logger.Error("Failed to retry email notification", map[string]interface{}{
+			"error":           err.Error(),
+			"notification_id": notificationID.String(),
+		})
+		switch {
+		case errors.Is(err, services.ErrEmailNotificationNotFound):
+			response.NotFound(c, "Email notification not found")
+		case errors.Is(err, services.ErrEmailRetryNotAllowed):
+			response.Forbidden(c, "Not allowed to retry email notifications")
+		case errors.Is(err, services.ErrEmailNotificationNotFailed):
+			response.BadRequest(c, "Only failed email notifications can be retried: "+err.Error())
+		default:
+			response.InternalError(c, "Failed to retry email notification")
+		}
+		return
+	}
+
+	if notification.Status != models.EmailStatusSent {
+		response.Success(c, "Email notification retried but sending failed again", notification)
+		return
+	}
+
+	response.Success(c, "Email notification sent successfully", notification)
+}
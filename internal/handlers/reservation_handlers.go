@@ -0,0 +1,122 @@
+// internal/handlers/reservation_handlers.go
+package handlers
+
+import (
+	"errors"
+
+	"loan-service/internal/models"
+	"loan-service/internal/services"
+	"loan-service/pkg/logger"
+	"loan-service/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type ReservationHandler struct {
+	reservationService services.ReservationServiceInterface
+	logger             *logger.Logger
+}
+
+func NewReservationHandler(reservationService services.ReservationServiceInterface, logger *logger.Logger) *ReservationHandler {
+	return &ReservationHandler{
+		reservationService: reservationService,
+		logger:             logger,
+	}
+}
+
+// Reserve handles holding part of a loan's remaining principal for an investor
+func (h *ReservationHandler) Reserve(c *gin.Context) {
+
+	loanID := c.Param("loan_id")
+
+	// Parse loan ID
+	id, err := uuid.Parse(loanID)
+	if err != nil {
+		response.BadRequest(c, "Invalid loan ID format")
+		return
+	}
+
+	var req models.CreateReservationRequest
+
+	// First, bind JSON to get the raw data
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+
+	// Validate the request using struct tags
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		response.ValidationErrorFromValidator(c, "Validation failed", err)
+		return
+	}
+
+	reservation, err := h.reservationService.ProcessReserve(id, &req)
+	if err != nil {
+		h.logger.Error("Failed to reserve investment", map[string]interface{}{
+			"error":   err.Error(),
+			"loan_id": id.String(),
+		})
+		response.BadRequest(c, "Failed to reserve investment: "+err.Error())
+		return
+	}
+
+	response.Created(c, "Investment reserved successfully", reservation)
+}
+
+// Confirm handles converting a reservation into an investment
+func (h *ReservationHandler) Confirm(c *gin.Context) {
+
+	// Parse loan ID
+	loanID, err := uuid.Parse(c.Param("loan_id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid loan ID format")
+		return
+	}
+
+	// Parse reservation ID
+	reservationID, err := uuid.Parse(c.Param("reservation_id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid reservation ID format")
+		return
+	}
+
+	var req models.ConfirmReservationRequest
+
+	// First, bind JSON to get the raw data
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+
+	// Validate the request using struct tags
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		response.ValidationErrorFromValidator(c, "Validation failed", err)
+		return
+	}
+
+	investment, err := h.reservationService.ConfirmReservation(loanID, reservationID, &req)
+	if err != nil {
+		h.logger.Error("Failed to confirm reservation", map[string]interface{}{
+			"error":          err.Error(),
+			"loan_id":        loanID.String(),
+			"reservation_id": reservationID.String(),
+		})
+		if errors.Is(err, services.ErrReservationNotFound) {
+			response.NotFound(c, "Failed to confirm reservation: "+err.Error())
+			return
+		}
+		var limitErr *models.InvestmentLimitError
+		if errors.As(err, &limitErr) {
+			response.BadRequestWithCode(c, limitErr.Code, "Failed to confirm reservation: "+limitErr.Message)
+			return
+		}
+		response.BadRequest(c, "Failed to confirm reservation: "+err.Error())
+		return
+	}
+
+	response.Accepted(c, "Reservation confirmed successfully", investment)
+}
@@ -0,0 +1,78 @@
+// internal/handlers/signature_handlers.go
+package handlers
+
+import (
+	"errors"
+
+	"loan-service/internal/services"
+	"loan-service/pkg/logger"
+	"loan-service/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// SignatureHeader carries the provider's HMAC of the callback body
+const SignatureHeader = "X-Signature"
+
+type SignatureHandler struct {
+	signatureService services.SignatureServiceInterface
+	logger           *logger.Logger
+}
+
+func NewSignatureHandler(signatureService services.SignatureServiceInterface, logger *logger.Logger) *SignatureHandler {
+	return &SignatureHandler{
+		signatureService: signatureService,
+		logger:           logger,
+	}
+}
+
+// SendForSignature handles sending a loan agreement to the borrower for e-signature
+func (h *SignatureHandler) SendForSignature(c *gin.Context) {
+
+	loanID := c.Param("loan_id")
+
+	// Parse loan ID
+	id, err := uuid.Parse(loanID)
+	if err != nil {
+		response.BadRequest(c, "Invalid loan ID format")
+		return
+	}
+
+	signature, err := h.signatureService.ProcessSendForSignature(id)
+	if err != nil {
+		h.logger.Error("Failed to send agreement for signature", map[string]interface{}{
+			"error":   err.Error(),
+			"loan_id": id.String(),
+		})
+		response.BadRequest(c, "Failed to send agreement for signature: "+err.Error())
+		return
+	}
+
+	response.Created(c, "Agreement sent for signature successfully", signature)
+}
+
+// Callback handles the e-signature provider reporting that the borrower signed or declined
+func (h *SignatureHandler) Callback(c *gin.Context) {
+	// The raw body is needed to verify the provider's signature
+	payload, err := c.GetRawData()
+	if err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+
+	signature, err := h.signatureService.ProcessCallback(payload, c.GetHeader(SignatureHeader))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCallbackSignature) {
+			response.Unauthorized(c, "Invalid callback signature")
+			return
+		}
+		h.logger.Error("Failed to process signature callback", map[string]interface{}{
+			"error": err.Error(),
+		})
+		response.BadRequest(c, "Failed to process signature callback: "+err.Error())
+		return
+	}
+
+	response.Success(c, "Signature callback processed successfully", signature)
+}
@@ -0,0 +1,184 @@
+// internal/handlers/transfer_handlers.go
+package handlers
+
+import (
+	"errors"
+
+	"loan-service/internal/models"
+	"loan-service/internal/services"
+	"loan-service/pkg/logger"
+	"loan-service/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type TransferHandler struct {
+	transferService services.TransferServiceInterface
+	logger          *logger.Logger
+}
+
+func NewTransferHandler(transferService services.TransferServiceInterface, logger *logger.Logger) *TransferHandler {
+	return &TransferHandler{
+		transferService: transferService,
+		logger:          logger,
+	}
+}
+
+// ListInvestment handles offering an investment, or a slice of it, on the secondary market
+func (h *TransferHandler) ListInvestment(c *gin.Context) {
+
+	investmentID := c.Param("investment_id")
+
+	// Parse investment ID
+	id, err := uuid.Parse(investmentID)
+	if err != nil {
+		response.BadRequest(c, "Invalid investment ID format")
+		return
+	}
+
+	var req models.CreateTransferListingRequest
+
+	// First, bind JSON to get the raw data
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+
+	// Validate the request using struct tags
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		response.ValidationErrorFromValidator(c, "Validation failed", err)
+		return
+	}
+
+	transfer, err := h.transferService.ProcessListInvestment(id, &req)
+	if err != nil {
+		h.logger.Error("Failed to list investment for transfer", map[string]interface{}{
+			"error":         err.Error(),
+			"investment_id": id.String(),
+		})
+		response.BadRequest(c, "Failed to list investment: "+err.Error())
+		return
+	}
+
+	response.Created(c, "Investment listed successfully", transfer)
+}
+
+// GetListings handles listing the secondary market offers open for buying
+func (h *TransferHandler) GetListings(c *gin.Context) {
+	transfers, err := h.transferService.GetListings()
+	if err != nil {
+		response.InternalError(c, "Failed to get secondary market listings")
+		return
+	}
+
+	response.Success(c, "Secondary market listings retrieved successfully", transfers)
+}
+
+// Buy handles an investor buying a secondary market listing
+func (h *TransferHandler) Buy(c *gin.Context) {
+
+	transferID := c.Param("transfer_id")
+
+	// Parse transfer ID
+	id, err := uuid.Parse(transferID)
+	if err != nil {
+		response.BadRequest(c, "Invalid transfer ID format")
+		return
+	}
+
+	var req models.BuyTransferRequest
+
+	// First, bind JSON to get the raw data
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+
+	// Validate the request using struct tags
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		response.ValidationErrorFromValidator(c, "Validation failed", err)
+		return
+	}
+
+	transfer, err := h.transferService.ProcessBuyTransfer(id, &req)
+	if err != nil {
+		h.logger.Error("Failed to buy investment transfer", map[string]interface{}{
+			"error":       err.Error(),
+			"transfer_id": id.String(),
+		})
+		var limitErr *models.InvestmentLimitError
+		if errors.As(err, &limitErr) {
+			response.BadRequestWithCode(c, limitErr.Code, "Failed to buy investment: "+limitErr.Message)
+			return
+		}
+		response.BadRequest(c, "Failed to buy investment: "+err.Error())
+		return
+	}
+
+	response.Success(c, "Investment transferred successfully", transfer)
+}
+
+// CancelListing handles the seller withdrawing a secondary market listing
+func (h *TransferHandler) CancelListing(c *gin.Context) {
+
+	transferID := c.Param("transfer_id")
+
+	// Parse transfer ID
+	id, err := uuid.Parse(transferID)
+	if err != nil {
+		response.BadRequest(c, "Invalid transfer ID format")
+		return
+	}
+
+	var req models.CancelTransferListingRequest
+
+	// First, bind JSON to get the raw data
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+
+	// Validate the request using struct tags
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		response.ValidationErrorFromValidator(c, "Validation failed", err)
+		return
+	}
+
+	transfer, err := h.transferService.ProcessCancelListing(id, &req)
+	if err != nil {
+		h.logger.Error("Failed to cancel investment transfer", map[string]interface{}{
+			"error":       err.Error(),
+			"transfer_id": id.String(),
+		})
+		response.BadRequest(c, "Failed to cancel listing: "+err.Error())
+		return
+	}
+
+	response.Success(c, "Listing cancelled successfully", transfer)
+}
+
+// GetLineage handles getting the transfer history of an investment
+func (h *TransferHandler) GetLineage(c *gin.Context) {
+
+	investmentID := c.Param("investment_id")
+
+	// Parse investment ID
+	id, err := uuid.Parse(investmentID)
+	if err != nil {
+		response.BadRequest(c, "Invalid investment ID format")
+		return
+	}
+
+	transfers, err := h.transferService.GetInvestmentLineage(id)
+	if err != nil {
+		response.InternalError(c, "Failed to get investment lineage")
+		return
+	}
+
+	response.Success(c, "Investment lineage retrieved successfully", transfers)
+}
@@ -0,0 +1,65 @@
+// internal/handlers/waitlist_handlers.go
+package handlers
+
+import (
+	"loan-service/internal/models"
+	"loan-service/internal/services"
+	"loan-service/pkg/logger"
+	"loan-service/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type WaitlistHandler struct {
+	waitlistService services.WaitlistServiceInterface
+	logger          *logger.Logger
+}
+
+func NewWaitlistHandler(waitlistService services.WaitlistServiceInterface, logger *logger.Logger) *WaitlistHandler {
+	return &WaitlistHandler{
+		waitlistService: waitlistService,
+		logger:          logger,
+	}
+}
+
+// Join handles queueing an investor on an oversubscribed loan
+func (h *WaitlistHandler) Join(c *gin.Context) {
+
+	loanID := c.Param("loan_id")
+
+	// Parse loan ID
+	id, err := uuid.Parse(loanID)
+	if err != nil {
+		response.BadRequest(c, "Invalid loan ID format")
+		return
+	}
+
+	var req models.JoinWaitlistRequest
+
+	// First, bind JSON to get the raw data
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+
+	// Validate the request using struct tags
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		response.ValidationErrorFromValidator(c, "Validation failed", err)
+		return
+	}
+
+	entry, err := h.waitlistService.JoinWaitlist(id, &req)
+	if err != nil {
+		h.logger.Error("Failed to join waitlist", map[string]interface{}{
+			"error":   err.Error(),
+			"loan_id": id.String(),
+		})
+		response.BadRequest(c, "Failed to join waitlist: "+err.Error())
+		return
+	}
+
+	response.Created(c, "Joined loan waitlist successfully", entry)
+}
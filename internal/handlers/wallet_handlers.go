@@ -0,0 +1,86 @@
+// internal/handlers/wallet_handlers.go
+package handlers
+
+import (
+	"loan-service/internal/models"
+	"loan-service/internal/services"
+	"loan-service/pkg/logger"
+	"loan-service/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type WalletHandler struct {
+	walletService services.WalletServiceInterface
+	logger        *logger.Logger
+}
+
+func NewWalletHandler(walletService services.WalletServiceInterface, logger *logger.Logger) *WalletHandler {
+	return &WalletHandler{
+		walletService: walletService,
+		logger:        logger,
+	}
+}
+
+// GetWallet handles getting an investor wallet balance and ledger
+func (h *WalletHandler) GetWallet(c *gin.Context) {
+
+	investorID := c.Param("investor_id")
+
+	// Parse investor ID
+	id, err := uuid.Parse(investorID)
+	if err != nil {
+		response.BadRequest(c, "Invalid investor ID format")
+		return
+	}
+
+	wallet, err := h.walletService.GetWallet(id)
+	if err != nil {
+		response.BadRequest(c, "Failed to get wallet")
+		return
+	}
+
+	response.Success(c, "Wallet retrieved successfully", wallet)
+}
+
+// TopUp handles adding funds to an investor wallet
+func (h *WalletHandler) TopUp(c *gin.Context) {
+
+	investorID := c.Param("investor_id")
+
+	// Parse investor ID
+	id, err := uuid.Parse(investorID)
+	if err != nil {
+		response.BadRequest(c, "Invalid investor ID format")
+		return
+	}
+
+	var req models.WalletTopUpRequest
+
+	// First, bind JSON to get the raw data
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+
+	// Validate the request using struct tags
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		response.ValidationErrorFromValidator(c, "Validation failed", err)
+		return
+	}
+
+	transaction, err := h.walletService.TopUp(id, &req)
+	if err != nil {
+		h.logger.Error("Failed to top up wallet", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": id.String(),
+		})
+		response.BadRequest(c, "Failed to top up wallet: "+err.Error())
+		return
+	}
+
+	response.Created(c, "Wallet topped up successfully", transaction)
+}
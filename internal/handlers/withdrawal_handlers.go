@@ -0,0 +1,206 @@
+// internal/handlers/withdrawal_handlers.go
+package handlers
+
+import (
+	"loan-service/internal/models"
+	"loan-service/internal/services"
+	"loan-service/pkg/logger"
+	"loan-service/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type WithdrawalHandler struct {
+	withdrawalService services.WithdrawalServiceInterface
+	logger            *logger.Logger
+}
+
+func NewWithdrawalHandler(withdrawalService services.WithdrawalServiceInterface, logger *logger.Logger) *WithdrawalHandler {
+	return &WithdrawalHandler{
+		withdrawalService: withdrawalService,
+		logger:            logger,
+	}
+}
+
+// RegisterBankAccount handles registering an investor bank account for withdrawals
+func (h *WithdrawalHandler) RegisterBankAccount(c *gin.Context) {
+
+	investorID := c.Param("investor_id")
+
+	// Parse investor ID
+	id, err := uuid.Parse(investorID)
+	if err != nil {
+		response.BadRequest(c, "Invalid investor ID format")
+		return
+	}
+
+	var req models.CreateBankAccountRequest
+
+	// First, bind JSON to get the raw data
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+
+	// Validate the request using struct tags
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		response.ValidationErrorFromValidator(c, "Validation failed", err)
+		return
+	}
+
+	account, err := h.withdrawalService.RegisterBankAccount(id, &req)
+	if err != nil {
+		h.logger.Error("Failed to register bank account", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": id.String(),
+		})
+		response.BadRequest(c, "Failed to register bank account: "+err.Error())
+		return
+	}
+
+	response.Created(c, "Bank account registered successfully", account)
+}
+
+// GetBankAccounts handles listing the bank accounts of an investor
+func (h *WithdrawalHandler) GetBankAccounts(c *gin.Context) {
+
+	investorID := c.Param("investor_id")
+
+	// Parse investor ID
+	id, err := uuid.Parse(investorID)
+	if err != nil {
+		response.BadRequest(c, "Invalid investor ID format")
+		return
+	}
+
+	accounts, err := h.withdrawalService.GetBankAccounts(id)
+	if err != nil {
+		response.BadRequest(c, "Failed to get bank accounts")
+		return
+	}
+
+	response.Success(c, "Bank accounts retrieved successfully", accounts)
+}
+
+// RequestWithdrawal handles withdrawing wallet funds to a registered bank account
+func (h *WithdrawalHandler) RequestWithdrawal(c *gin.Context) {
+
+	investorID := c.Param("investor_id")
+
+	// Parse investor ID
+	id, err := uuid.Parse(investorID)
+	if err != nil {
+		response.BadRequest(c, "Invalid investor ID format")
+		return
+	}
+
+	var req models.CreateWithdrawalRequest
+
+	// First, bind JSON to get the raw data
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+
+	// Validate the request using struct tags
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		response.ValidationErrorFromValidator(c, "Validation failed", err)
+		return
+	}
+
+	withdrawal, err := h.withdrawalService.RequestWithdrawal(id, &req)
+	if err != nil {
+		h.logger.Error("Failed to request withdrawal", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": id.String(),
+		})
+		response.BadRequest(c, "Failed to request withdrawal: "+err.Error())
+		return
+	}
+
+	if withdrawal.Status == models.WithdrawalStatusPendingApproval {
+		response.Accepted(c, "Withdrawal is waiting for approval", withdrawal)
+		return
+	}
+
+	response.Created(c, "Withdrawal processed", withdrawal)
+}
+
+// GetWithdrawals handles listing the withdrawals of an investor
+func (h *WithdrawalHandler) GetWithdrawals(c *gin.Context) {
+
+	investorID := c.Param("investor_id")
+
+	// Parse investor ID
+	id, err := uuid.Parse(investorID)
+	if err != nil {
+		response.BadRequest(c, "Invalid investor ID format")
+		return
+	}
+
+	withdrawals, err := h.withdrawalService.GetWithdrawals(id)
+	if err != nil {
+		response.BadRequest(c, "Failed to get withdrawals")
+		return
+	}
+
+	response.Success(c, "Withdrawals retrieved successfully", withdrawals)
+}
+
+// ApproveWithdrawal handles an employee approving a withdrawal above the approval threshold
+func (h *WithdrawalHandler) ApproveWithdrawal(c *gin.Context) {
+	h.reviewWithdrawal(c, h.withdrawalService.ApproveWithdrawal, "approve")
+}
+
+// RejectWithdrawal handles an employee rejecting a withdrawal above the approval threshold
+func (h *WithdrawalHandler) RejectWithdrawal(c *gin.Context) {
+	h.reviewWithdrawal(c, h.withdrawalService.RejectWithdrawal, "reject")
+}
+
+func (h *WithdrawalHandler) reviewWithdrawal(
+	c *gin.Context,
+	review func(uuid.UUID, *models.ReviewWithdrawalRequest) (*models.WithdrawalResponse, error),
+	action string,
+) {
+
+	withdrawalID := c.Param("withdrawal_id")
+
+	// Parse withdrawal ID
+	id, err := uuid.Parse(withdrawalID)
+	if err != nil {
+		response.BadRequest(c, "Invalid withdrawal ID format")
+		return
+	}
+
+	var req models.ReviewWithdrawalRequest
+
+	// First, bind JSON to get the raw data
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+
+	// Validate the request using struct tags
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		response.ValidationErrorFromValidator(c, "Validation failed", err)
+		return
+	}
+
+	withdrawal, err := review(id, &req)
+	if err != nil {
+		h.logger.Error("Failed to review withdrawal", map[string]interface{}{
+			"error":         err.Error(),
+			"withdrawal_id": id.String(),
+			"action":        action,
+		})
+		response.BadRequest(c, "Failed to "+action+" withdrawal: "+err.Error())
+		return
+	}
+
+	response.Success(c, "Withdrawal reviewed successfully", withdrawal)
+}
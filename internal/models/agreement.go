@@ -0,0 +1,81 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoanAgreementData is everything an agreement template renders: the loan, its borrower and who funded it
+type LoanAgreementData struct {
+	Loan        *Loan
+	Borrower    *Borrower
+	Investments []*AgreementInvestment
+	GeneratedAt time.Time
+}
+
+// InvestmentAgreementData is what a single investor's agreement renders: the loan and their own share of it
+type InvestmentAgreementData struct {
+	Loan        *Loan
+	Borrower    *Borrower
+	Investment  *AgreementInvestment
+	GeneratedAt time.Time
+}
+
+// TransferAgreementData is what a secondary market transfer agreement renders: who sold what to whom and for how much
+type TransferAgreementData struct {
+	Transfer    *InvestmentTransfer
+	Loan        *Loan
+	Seller      *Investor
+	Buyer       *Investor
+	GeneratedAt time.Time
+}
+
+// AgreementInvestment is a single investor's share of the loan as printed on the agreement
+type AgreementInvestment struct {
+	InvestmentID   uuid.UUID
+	InvestorCode   string
+	InvestorName   string
+	Amount         float64
+	ExpectedReturn float64
+	InvestmentDate time.Time
+}
+
+// GeneratedDocument is a rendered document ready to be stored
+type GeneratedDocument struct {
+	FileName        string
+	ContentType     string
+	Content         []byte
+	TemplateVersion string
+}
+
+// AgreementSignatureStatus represents where the borrower is in the e-signature workflow
+type AgreementSignatureStatus string
+
+const (
+	AgreementSignatureStatusPending  AgreementSignatureStatus = "pending"  // sent to the borrower, waiting for the provider callback
+	AgreementSignatureStatusSigned   AgreementSignatureStatus = "signed"   // signed and the signed file stored
+	AgreementSignatureStatusDeclined AgreementSignatureStatus = "declined" // refused by the borrower; the agreement can be sent again
+)
+
+// AgreementSignature tracks a loan agreement sent to the e-signature provider for the borrower to sign
+type AgreementSignature struct {
+	BaseModel
+	LoanID         uuid.UUID                `json:"loan_id" validate:"required"`
+	Provider       string                   `json:"provider" validate:"required"`
+	EnvelopeID     string                   `json:"envelope_id" validate:"required"` // provider reference used by the callback
+	Status         AgreementSignatureStatus `json:"status" validate:"required"`
+	DocumentURL    string                   `json:"document_url" validate:"required"`
+	SigningURL     string                   `json:"signing_url,omitempty"`
+	SignerName     string                   `json:"signer_name" validate:"required"`
+	SignerEmail    string                   `json:"signer_email" validate:"required,email"`
+	SignedFileURL  string                   `json:"signed_file_url,omitempty"`
+	SignedFileType FileType                 `json:"signed_file_type,omitempty"`
+	SentAt         time.Time                `json:"sent_at"`
+	CompletedAt    *time.Time               `json:"completed_at,omitempty"`
+}
+
+// IsCompleted checks if the borrower signed and the signed file was stored
+func (s *AgreementSignature) IsCompleted() bool {
+	return s.Status == AgreementSignatureStatusSigned && s.SignedFileURL != ""
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultLoanProduct is used for loans created without a product
+const DefaultLoanProduct = "general"
+
+// AutoInvestRule invests on behalf of an investor in newly approved loans that match its criteria
+type AutoInvestRule struct {
+	BaseModel
+	InvestorID      uuid.UUID  `json:"investor_id" validate:"required"`
+	MinROI          float64    `json:"min_roi" validate:"gte=0,lte=1"`
+	MaxPrincipal    *float64   `json:"max_principal,omitempty"`  // nil matches any principal
+	Product         string     `json:"product,omitempty"`        // empty matches any product
+	MaxRiskGrade    string     `json:"max_risk_grade,omitempty"` // worst accepted grade, empty matches any borrower
+	AmountPerLoan   float64    `json:"amount_per_loan" validate:"required,gt=0"`
+	IsActive        bool       `json:"is_active"`
+	LastAllocatedAt *time.Time `json:"last_allocated_at,omitempty"`
+}
+
+// Matches checks if the loan satisfies every criterion of the rule
+func (r *AutoInvestRule) Matches(loan *Loan) bool {
+	if !r.IsActive || loan.State != LoanStateApproved {
+		return false
+	}
+
+	if loan.ROI < r.MinROI {
+		return false
+	}
+
+	if r.MaxPrincipal != nil && loan.PrincipalAmount > *r.MaxPrincipal {
+		return false
+	}
+
+	if r.Product != "" && loan.Product != r.Product {
+		return false
+	}
+
+	if r.MaxRiskGrade != "" {
+		// Grades run A (best) to E (worst); unrated borrowers never match a graded rule
+		if loan.Borrower == nil || loan.Borrower.RiskGrade == "" || loan.Borrower.RiskGrade > r.MaxRiskGrade {
+			return false
+		}
+	}
+
+	return true
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"bytes"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,6 +24,8 @@ const (
 	LoanStateApproved  LoanState = "approved"
 	LoanStateInvested  LoanState = "invested"
 	LoanStateDisbursed LoanState = "disbursed"
+	LoanStateCancelled LoanState = "cancelled"
+	LoanStateExpired   LoanState = "expired"
 )
 
 // Valid states for transition validation
@@ -30,14 +34,18 @@ var validStates = map[LoanState]bool{
 	LoanStateApproved:  true,
 	LoanStateInvested:  true,
 	LoanStateDisbursed: true,
+	LoanStateCancelled: true,
+	LoanStateExpired:   true,
 }
 
 // Valid state transitions
 var validTransitions = map[LoanState][]LoanState{
-	LoanStateProposed:  {LoanStateApproved},
-	LoanStateApproved:  {LoanStateInvested},
-	LoanStateInvested:  {LoanStateDisbursed},
+	LoanStateProposed:  {LoanStateApproved, LoanStateCancelled},
+	LoanStateApproved:  {LoanStateInvested, LoanStateCancelled, LoanStateExpired},
+	LoanStateInvested:  {LoanStateDisbursed, LoanStateCancelled},
 	LoanStateDisbursed: {}, // Final state, no transitions allowed
+	LoanStateCancelled: {}, // Final state, no transitions allowed
+	LoanStateExpired:   {}, // Final state, no transitions allowed
 }
 
 // IsValid checks if the loan state is valid
@@ -69,3 +77,51 @@ const (
 	FileTypeJPEG FileType = "jpeg"
 	FileTypePNG  FileType = "png"
 )
+
+// fileSignatures are the magic bytes each supported file type starts with
+var fileSignatures = []struct {
+	fileType FileType
+	magic    []byte
+}{
+	{FileTypePDF, []byte("%PDF-")},
+	{FileTypeJPEG, []byte{0xFF, 0xD8, 0xFF}},
+	{FileTypePNG, []byte{0x89, 'P', 'N', 'G', 0x0D, 0x0A, 0x1A, 0x0A}},
+}
+
+// DetectFileType identifies a supported file type from the first bytes of its content
+func DetectFileType(header []byte) (FileType, bool) {
+	for _, signature := range fileSignatures {
+		if bytes.HasPrefix(header, signature.magic) {
+			return signature.fileType, true
+		}
+	}
+	return "", false
+}
+
+// FileTypeFromExtension maps a file extension such as ".jpg" to its file type
+func FileTypeFromExtension(ext string) (FileType, bool) {
+	switch strings.ToLower(ext) {
+	case ".pdf":
+		return FileTypePDF, true
+	case ".jpg", ".jpeg":
+		return FileTypeJPEG, true
+	case ".png":
+		return FileTypePNG, true
+	default:
+		return "", false
+	}
+}
+
+// ContentType returns the MIME type of the file type
+func (ft FileType) ContentType() string {
+	switch ft {
+	case FileTypeJPEG:
+		return "image/jpeg"
+	case FileTypePNG:
+		return "image/png"
+	case FileTypePDF:
+		return "application/pdf"
+	default:
+		return "application/octet-stream"
+	}
+}
@@ -0,0 +1,29 @@
+package models
+
+// File validation error codes, returned to clients so each rejected upload can be handled separately
+const (
+	CodeFileEmpty           = "FILE_EMPTY"
+	CodeFileTooLarge        = "FILE_TOO_LARGE"
+	CodeFileTypeUnsupported = "FILE_TYPE_UNSUPPORTED"
+	CodeFileTypeMismatch    = "FILE_TYPE_MISMATCH" // the content is not what the file extension claims
+	CodeTooManyFiles        = "TOO_MANY_FILES"     // more files in one bundle than allowed
+
+	// Codes for a file_id that cannot be attached to an approval or disbursement
+	CodeFileNotFound       = "FILE_NOT_FOUND"
+	CodeFileInactive       = "FILE_INACTIVE"
+	CodeFileEntityMismatch = "FILE_ENTITY_MISMATCH"
+	CodeFileAlreadyLinked  = "FILE_ALREADY_LINKED"
+	CodeFileNotScanned     = "FILE_NOT_SCANNED" // still quarantined, waiting for the malware scan
+	CodeFileInfected       = "FILE_INFECTED"
+)
+
+// FileValidationError is returned when an uploaded file is rejected for its content or size, or when a
+// referenced file cannot be attached
+type FileValidationError struct {
+	Code    string
+	Message string
+}
+
+func (e *FileValidationError) Error() string {
+	return e.Message
+}
@@ -16,6 +16,10 @@ type Investment struct {
 	ExpectedReturn  float64    `json:"expected_return"`
 	AgreementSent   bool       `json:"agreement_sent"`
 	AgreementSentAt *time.Time `json:"agreement_sent_at,omitempty"`
+	AgreementURL    string     `json:"agreement_url,omitempty"`
+
+	// ParentInvestmentID is the investment this one was bought from on the secondary market
+	ParentInvestmentID *uuid.UUID `json:"parent_investment_id,omitempty"`
 
 	// Relationships
 	Loan     *Loan     `json:"loan,omitempty"`
@@ -26,3 +30,48 @@ type Investment struct {
 func (i *Investment) CalculateExpectedReturn(loanROI float64) float64 {
 	return i.Amount * loanROI
 }
+
+// InvestmentCancellation is the audit record of an investor backing out of an investment
+// while the loan was still raising funds
+type InvestmentCancellation struct {
+	BaseModel
+	InvestmentID uuid.UUID `json:"investment_id" validate:"required"`
+	LoanID       uuid.UUID `json:"loan_id" validate:"required"`
+	InvestorID   uuid.UUID `json:"investor_id" validate:"required"`
+	Amount       float64   `json:"amount" validate:"required,gt=0"`
+	Reason       string    `json:"reason"`
+}
+
+// InvestmentTransferStatus represents the lifecycle of a secondary market listing
+type InvestmentTransferStatus string
+
+const (
+	InvestmentTransferStatusListed    InvestmentTransferStatus = "listed"    // open for another investor to buy
+	InvestmentTransferStatusCompleted InvestmentTransferStatus = "completed" // bought, ownership and funds moved
+	InvestmentTransferStatusCancelled InvestmentTransferStatus = "cancelled" // withdrawn by the seller
+)
+
+// WalletReferenceInvestmentTransfer links ledger entries to the transfer that caused them
+const WalletReferenceInvestmentTransfer = "investment_transfer"
+
+// InvestmentTransfer is a secondary market listing of a whole investment or a slice of it.
+// Once completed it is the lineage record linking the seller's investment to the buyer's.
+type InvestmentTransfer struct {
+	BaseModel
+	InvestmentID      uuid.UUID                `json:"investment_id" validate:"required"`
+	LoanID            uuid.UUID                `json:"loan_id" validate:"required"`
+	SellerID          uuid.UUID                `json:"seller_id" validate:"required"`
+	Amount            float64                  `json:"amount" validate:"required,gt=0"` // principal being sold
+	Price             float64                  `json:"price" validate:"required,gt=0"`  // paid by the buyer to the seller
+	Status            InvestmentTransferStatus `json:"status" validate:"required"`
+	BuyerID           *uuid.UUID               `json:"buyer_id,omitempty"`
+	BuyerInvestmentID *uuid.UUID               `json:"buyer_investment_id,omitempty"`
+	AgreementURL      string                   `json:"agreement_url,omitempty"`
+	CompletedAt       *time.Time               `json:"completed_at,omitempty"`
+	CancelledAt       *time.Time               `json:"cancelled_at,omitempty"`
+}
+
+// IsWhole checks if the transfer sells the entire investment
+func (t *InvestmentTransfer) IsWhole(investment *Investment) bool {
+	return t.Amount >= investment.Amount
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Investment limit error codes, returned to clients so each violation can be handled separately
+const (
+	CodeInvestmentBelowMinTicket    = "INVESTMENT_BELOW_MIN_TICKET"
+	CodeInvestmentLoanShareExceeded = "INVESTMENT_LOAN_SHARE_EXCEEDED"
+	CodeInvestmentExposureExceeded  = "INVESTMENT_EXPOSURE_EXCEEDED"
+	CodeInvestmentOversubscribed    = "INVESTMENT_OVERSUBSCRIBED" // the loan has no room left for the amount; the investor may join the waitlist
+)
+
+// InvestmentLimitError is returned when an investment breaks one of the investor limits
+type InvestmentLimitError struct {
+	Code    string
+	Message string
+}
+
+func (e *InvestmentLimitError) Error() string {
+	return e.Message
+}
+
+// InvestmentLimits are the concentration and exposure limits applied to an investor; zero disables a limit
+type InvestmentLimits struct {
+	MaxLoanShare     float64 `json:"max_loan_share"`     // fraction of a loan's principal one investor may hold, e.g. 0.25
+	MaxTotalExposure float64 `json:"max_total_exposure"` // outstanding amount across all loans
+	MinTicket        float64 `json:"min_ticket"`         // smallest single investment
+}
+
+// InvestorInvestmentLimit overrides the global limits for one investor; nil fields inherit the global value
+type InvestorInvestmentLimit struct {
+	BaseModel
+	InvestorID       uuid.UUID `json:"investor_id" validate:"required"`
+	MaxLoanShare     *float64  `json:"max_loan_share,omitempty"`
+	MaxTotalExposure *float64  `json:"max_total_exposure,omitempty"`
+	MinTicket        *float64  `json:"min_ticket,omitempty"`
+	UpdatedBy        uuid.UUID `json:"updated_by" validate:"required"`
+}
+
+// InvestorExposure is what an investor currently has at stake
+type InvestorExposure struct {
+	LoanInvested     float64 `json:"loan_invested"`     // active investments in the loan being invested in
+	TotalOutstanding float64 `json:"total_outstanding"` // active investments in loans that are not closed
+}
+
+// WithOverride returns the limits with the investor specific values applied
+func (l InvestmentLimits) WithOverride(override *InvestorInvestmentLimit) InvestmentLimits {
+	if override == nil {
+		return l
+	}
+	if override.MaxLoanShare != nil {
+		l.MaxLoanShare = *override.MaxLoanShare
+	}
+	if override.MaxTotalExposure != nil {
+		l.MaxTotalExposure = *override.MaxTotalExposure
+	}
+	if override.MinTicket != nil {
+		l.MinTicket = *override.MinTicket
+	}
+	return l
+}
+
+// Validate checks a new investment against the limits.
+// A ticket that takes the whole remaining amount may be below the minimum so the loan can still be filled.
+func (l InvestmentLimits) Validate(amount float64, loan *Loan, exposure *InvestorExposure) error {
+	if l.MinTicket > 0 && amount < l.MinTicket && amount < loan.RemainingInvestmentAmount() {
+		return &InvestmentLimitError{
+			Code:    CodeInvestmentBelowMinTicket,
+			Message: fmt.Sprintf("investment amount %.2f is below the minimum ticket %.2f", amount, l.MinTicket),
+		}
+	}
+
+	if l.MaxLoanShare > 0 {
+		maxAmount := loan.PrincipalAmount * l.MaxLoanShare
+		if exposure.LoanInvested+amount > maxAmount {
+			return &InvestmentLimitError{
+				Code: CodeInvestmentLoanShareExceeded,
+				Message: fmt.Sprintf("investment would bring the investor share of the loan to %.2f, above the maximum %.2f (%.0f%% of principal)",
+					exposure.LoanInvested+amount, maxAmount, l.MaxLoanShare*100),
+			}
+		}
+	}
+
+	if l.MaxTotalExposure > 0 && exposure.TotalOutstanding+amount > l.MaxTotalExposure {
+		return &InvestmentLimitError{
+			Code: CodeInvestmentExposureExceeded,
+			Message: fmt.Sprintf("investment would bring the investor total exposure to %.2f, above the maximum %.2f",
+				exposure.TotalOutstanding+amount, l.MaxTotalExposure),
+		}
+	}
+
+	return nil
+}
@@ -7,6 +7,9 @@ import (
 	"github.com/google/uuid"
 )
 
+// DefaultLoanTenorMonths is used for loans created without a tenor
+const DefaultLoanTenorMonths = 12
+
 // Loan represents the main loan entity
 type Loan struct {
 	BaseModel
@@ -15,15 +18,18 @@ type Loan struct {
 	InterestRate       float64   `json:"interest_rate" validate:"required,gte=0,lte=1"` // Percentage as decimal (0.10 for 10%)
 	ROI                float64   `json:"roi" validate:"required,gte=0,lte=1"`           // Return on Investment for investors
 	State              LoanState `json:"state" validate:"required"`
+	Product            string    `json:"product"`
+	TenorMonths        int       `json:"tenor_months"`
 	AgreementLetterURL string    `json:"agreement_letter_url"`
 	TotalInvested      float64   `json:"total_invested"`
 
 	// Relationships - these will be populated by joins or separate queries
-	Borrower     *Borrower          `json:"borrower,omitempty"`
-	Approval     *Approval          `json:"approval,omitempty"`
-	Investments  []Investment       `json:"investments,omitempty"`
-	Disbursement *Disbursement      `json:"disbursement,omitempty"`
-	StateHistory []LoanStateHistory `json:"state_history,omitempty"`
+	Borrower     *Borrower           `json:"borrower,omitempty"`
+	Approval     *Approval           `json:"approval,omitempty"`
+	Investments  []Investment        `json:"investments,omitempty"`
+	Disbursement *Disbursement       `json:"disbursement,omitempty"`
+	StateHistory []LoanStateHistory  `json:"state_history,omitempty"`
+	Signature    *AgreementSignature `json:"signature,omitempty"`
 }
 
 // ValidateStateTransition validates if the loan can transition to the target state
@@ -47,6 +53,8 @@ func (l *Loan) ValidateStateTransition(targetState LoanState) error {
 		return l.validateInvestmentTransition()
 	case LoanStateDisbursed:
 		return l.validateDisbursementTransition()
+	case LoanStateExpired:
+		return l.validateExpiryTransition()
 	}
 
 	return nil
@@ -88,16 +96,34 @@ func (l *Loan) validateDisbursementTransition() error {
 			l.PrincipalAmount, l.TotalInvested)
 	}
 
-	// check if agreement letter is signed
 	if l.AgreementLetterURL == "" {
 		return fmt.Errorf("loan must have an agreement letter to be disbursed")
 	}
 
+	// check if the borrower signed the agreement letter
+	if l.Signature == nil || l.Signature.LoanID != l.ID || !l.Signature.IsCompleted() {
+		return fmt.Errorf("loan agreement must be signed by the borrower before disbursement")
+	}
+
 	return nil
 }
 
-// ValidateInvestmentAmount validates if the investment amount is valid
-func (l *Loan) ValidateInvestmentAmount(amount float64) error {
+// validateExpiryTransition validates business rules for funding expiry
+func (l *Loan) validateExpiryTransition() error {
+	if l.State != LoanStateApproved {
+		return fmt.Errorf("only loans still raising funds can expire, current state: %s", l.State)
+	}
+
+	if l.IsFullyInvested() {
+		return fmt.Errorf("loan is fully invested and cannot expire")
+	}
+
+	return nil
+}
+
+// ValidateInvestmentAmount validates if the investment amount is valid.
+// Reserved is the principal held by other investors' active reservations and is not available.
+func (l *Loan) ValidateInvestmentAmount(amount, reserved float64) error {
 	if amount <= 0 {
 		return fmt.Errorf("investment amount must be greater than 0")
 	}
@@ -108,7 +134,17 @@ func (l *Loan) ValidateInvestmentAmount(amount float64) error {
 
 	remaining := l.RemainingInvestmentAmount()
 	if amount > remaining {
-		return fmt.Errorf("investment amount %.2f exceeds remaining amount %.2f", amount, remaining)
+		return &InvestmentLimitError{
+			Code:    CodeInvestmentOversubscribed,
+			Message: fmt.Sprintf("investment amount %.2f exceeds remaining amount %.2f", amount, remaining),
+		}
+	}
+
+	if available := remaining - reserved; amount > available {
+		return &InvestmentLimitError{
+			Code:    CodeInvestmentOversubscribed,
+			Message: fmt.Sprintf("investment amount %.2f exceeds unreserved amount %.2f", amount, available),
+		}
 	}
 
 	return nil
@@ -141,12 +177,19 @@ type LoanStateHistory struct {
 // Approval represents loan approval details
 type Approval struct {
 	BaseModel
-	LoanID              uuid.UUID `json:"loan_id" validate:"required"`
-	ValidatorID         uuid.UUID `json:"validator_id" validate:"required"`
-	ApprovalDate        time.Time `json:"approval_date" validate:"required"`
-	VisitProofImageURL  string    `json:"visit_proof_image_url" validate:"required"`
-	VisitProofImageType FileType  `json:"visit_proof_image_type" validate:"required"`
-	Notes               string    `json:"notes"`
+	LoanID              uuid.UUID  `json:"loan_id" validate:"required"`
+	ValidatorID         uuid.UUID  `json:"validator_id" validate:"required"`
+	ApprovalDate        time.Time  `json:"approval_date" validate:"required"`
+	VisitProofImageURL  string     `json:"visit_proof_image_url" validate:"required"`
+	VisitProofImageType FileType   `json:"visit_proof_image_type" validate:"required"` // first image when a bundle was given
+	VisitProofBundleID  *uuid.UUID `json:"visit_proof_bundle_id,omitempty"`
+	Notes               string     `json:"notes"`
+
+	// Read from the visit proof photo's EXIF data; nil when the photo did not carry it
+	VisitProofCapturedAt *time.Time `json:"visit_proof_captured_at,omitempty"`
+	VisitProofLatitude   *float64   `json:"visit_proof_latitude,omitempty"`
+	VisitProofLongitude  *float64   `json:"visit_proof_longitude,omitempty"`
+	VisitDistanceMeters  *float64   `json:"visit_distance_meters,omitempty"` // from the borrower's geocoded address
 
 	// Relationships
 	Loan      *Loan     `json:"loan,omitempty"`
@@ -162,6 +205,7 @@ type Disbursement struct {
 	SignedAgreementURL      string    `json:"signed_agreement_url" validate:"required"`
 	SignedAgreementFileType FileType  `json:"signed_agreement_file_type" validate:"required"`
 	DisbursedAmount         float64   `json:"disbursed_amount" validate:"required,gt=0"`
+	TransactionID           string    `json:"transaction_id,omitempty"` // payment provider transaction reference
 	Notes                   string    `json:"notes"`
 
 	// Relationships
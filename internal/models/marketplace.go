@@ -0,0 +1,81 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Marketplace sort options
+const (
+	MarketplaceSortROI             = "roi"
+	MarketplaceSortRemainingAmount = "remaining_amount"
+	MarketplaceSortAge             = "age"
+
+	SortOrderAsc  = "asc"
+	SortOrderDesc = "desc"
+)
+
+// MarketplaceLoan is an approved loan that is still raising funds, as shown to investors.
+// It carries no borrower PII, only the borrower's risk grade.
+type MarketplaceLoan struct {
+	LoanID          uuid.UUID `json:"loan_id"`
+	Product         string    `json:"product"`
+	PrincipalAmount float64   `json:"principal_amount"`
+	TotalInvested   float64   `json:"total_invested"`
+	RemainingAmount float64   `json:"remaining_amount"`
+	FundingProgress float64   `json:"funding_progress"` // fraction of the principal already invested
+	ROI             float64   `json:"roi"`
+	TenorMonths     int       `json:"tenor_months"`
+	RiskGrade       string    `json:"risk_grade,omitempty"`
+	ListedAt        time.Time `json:"listed_at"` // approval date
+}
+
+// NewMarketplaceLoan builds the marketplace view of a loan; the loan must have its borrower and approval loaded
+func NewMarketplaceLoan(loan *Loan) *MarketplaceLoan {
+	listing := &MarketplaceLoan{
+		LoanID:          loan.ID,
+		Product:         loan.Product,
+		PrincipalAmount: loan.PrincipalAmount,
+		TotalInvested:   loan.TotalInvested,
+		RemainingAmount: loan.RemainingInvestmentAmount(),
+		ROI:             loan.ROI,
+		TenorMonths:     loan.TenorMonths,
+	}
+
+	if loan.PrincipalAmount > 0 {
+		listing.FundingProgress = loan.TotalInvested / loan.PrincipalAmount
+	}
+
+	if loan.Borrower != nil {
+		listing.RiskGrade = loan.Borrower.RiskGrade
+	}
+
+	if loan.Approval != nil {
+		listing.ListedAt = loan.Approval.ApprovalDate
+	}
+
+	return listing
+}
+
+// SortMarketplaceLoans sorts listings in place. Sorting by age ascending puts the newest listings first.
+func SortMarketplaceLoans(listings []*MarketplaceLoan, sortBy, order string) {
+	less := func(a, b *MarketplaceLoan) bool {
+		switch sortBy {
+		case MarketplaceSortROI:
+			return a.ROI < b.ROI
+		case MarketplaceSortRemainingAmount:
+			return a.RemainingAmount < b.RemainingAmount
+		default:
+			return a.ListedAt.After(b.ListedAt)
+		}
+	}
+
+	sort.SliceStable(listings, func(i, j int) bool {
+		if order == SortOrderDesc {
+			return less(listings[j], listings[i])
+		}
+		return less(listings[i], listings[j])
+	})
+}
@@ -6,36 +6,172 @@ import (
 	"github.com/google/uuid"
 )
 
+// Email notification statuses; a pending email is retried with backoff until it is sent or runs out of attempts
+const (
+	EmailStatusPending   = "pending"
+	EmailStatusSent      = "sent"
+	EmailStatusDelivered = "delivered"
+	EmailStatusOpened    = "opened"
+	EmailStatusFailed    = "failed" // gave up after the last attempt; an employee may retry it
+)
+
 // EmailNotification tracks email notifications sent to investors
 type EmailNotification struct {
 	BaseModel
 	InvestorID   uuid.UUID  `json:"investor_id" validate:"required"`
 	LoanID       uuid.UUID  `json:"loan_id" validate:"required"`
 	EmailType    string     `json:"email_type" validate:"required"` // agreement_notification, etc.
+	Recipient    string     `json:"recipient" validate:"required,email"`
 	EmailSubject string     `json:"email_subject" validate:"required"`
-	EmailBody    string     `json:"email_body" validate:"required"`
-	SentAt       time.Time  `json:"sent_at"`
+	EmailBody    string     `json:"email_body" validate:"required"` // text version
+	EmailHTML    string     `json:"email_html,omitempty"`
+	Locale       string     `json:"locale,omitempty"`
+	SentAt       *time.Time `json:"sent_at,omitempty"`
 	DeliveredAt  *time.Time `json:"delivered_at,omitempty"`
 	OpenedAt     *time.Time `json:"opened_at,omitempty"`
-	Status       string     `json:"status" validate:"required"` // sent, delivered, opened, failed
-	ErrorMessage string     `json:"error_message,omitempty"`
+	Status       string     `json:"status" validate:"required"` // pending, sent, delivered, opened, failed
+	ErrorMessage string     `json:"error_message,omitempty"`    // error of the last failed attempt
+
+	// Delivery attempts
+	Attempts      int        `json:"attempts"`
+	LastAttemptAt *time.Time `json:"last_attempt_at,omitempty"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"` // when a pending email is retried
 
 	// Relationships
 	Investor *Investor `json:"investor,omitempty"`
 	Loan     *Loan     `json:"loan,omitempty"`
 }
 
+// Message returns the stored email so it can be sent again
+func (n *EmailNotification) Message() *EmailMessage {
+	return &EmailMessage{
+		Locale:   n.Locale,
+		Subject:  n.EmailSubject,
+		TextBody: n.EmailBody,
+		HTMLBody: n.EmailHTML,
+	}
+}
+
+// EmailDeliveryAttempt records one try to send an email notification
+type EmailDeliveryAttempt struct {
+	ID             uuid.UUID  `json:"id"`
+	NotificationID uuid.UUID  `json:"notification_id"`
+	AttemptNumber  int        `json:"attempt_number"`
+	AttemptedAt    time.Time  `json:"attempted_at"`
+	Succeeded      bool       `json:"succeeded"`
+	ErrorMessage   string     `json:"error_message,omitempty"`
+	RequestedBy    *uuid.UUID `json:"requested_by,omitempty"` // employee who retried a failed email by hand
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// EmailMessage is an email rendered for one recipient; it is sent as HTML with a text alternative
+type EmailMessage struct {
+	Locale   string `json:"locale"`
+	Subject  string `json:"subject"`
+	TextBody string `json:"text_body"`
+	HTMLBody string `json:"html_body,omitempty"`
+}
+
+// EmailTemplate is a versioned email template kept in the database, overriding the template files
+type EmailTemplate struct {
+	BaseModel
+	Name     string `json:"name" validate:"required"`
+	Locale   string `json:"locale" validate:"required"`
+	Version  string `json:"version" validate:"required"`
+	Subject  string `json:"subject" validate:"required"`
+	HTMLBody string `json:"html_body,omitempty"`
+	TextBody string `json:"text_body" validate:"required"`
+}
+
 // FileUpload tracks uploaded files for audit and management
 type FileUpload struct {
 	BaseModel
-	FileName    string    `json:"file_name" validate:"required"`
-	FileType    FileType  `json:"file_type" validate:"required"`
-	FileSize    int64     `json:"file_size" validate:"required"`
-	FilePath    string    `json:"file_path" validate:"required"`
-	FileURL     string    `json:"file_url" validate:"required"`
-	ContentType string    `json:"content_type" validate:"required"`
-	UploadedBy  uuid.UUID `json:"uploaded_by" validate:"required"`
-	EntityType  string    `json:"entity_type" validate:"required"` // loan, approval, disbursement
-	EntityID    uuid.UUID `json:"entity_id" validate:"required"`
-	IsActive    bool      `json:"is_active"`
+	FileName       string     `json:"file_name" validate:"required"`
+	FileType       FileType   `json:"file_type" validate:"required"`
+	FileSize       int64      `json:"file_size" validate:"required"`
+	FilePath       string     `json:"file_path" validate:"required"` // storage key
+	FileURL        string     `json:"file_url" validate:"required"`
+	ContentType    string     `json:"content_type" validate:"required"`
+	Checksum       string     `json:"checksum"`        // hex SHA-256 of the content
+	StorageBackend string     `json:"storage_backend"` // local or s3
+	UploadedBy     uuid.UUID  `json:"uploaded_by" validate:"required"`
+	EntityType     string     `json:"entity_type" validate:"required"` // loan, approval, disbursement
+	EntityID       uuid.UUID  `json:"entity_id" validate:"required"`
+	BundleID       *uuid.UUID `json:"bundle_id,omitempty"`
+	IsActive       bool       `json:"is_active"` // false once a newer version replaced it
+
+	// Versions of one document share the ID of its first version
+	DocumentID uuid.UUID  `json:"document_id"`
+	Version    int        `json:"version"`
+	ReplacesID *uuid.UUID `json:"replaces_id,omitempty"` // the previous version
+
+	ScanStatus    FileScanStatus `json:"scan_status"`
+	ScanSignature string         `json:"scan_signature,omitempty"` // malware found by the scanner
+	ScannedAt     *time.Time     `json:"scanned_at,omitempty"`
+
+	// Read from the EXIF data of jpeg uploads
+	CapturedAt *time.Time `json:"captured_at,omitempty"`
+	Latitude   *float64   `json:"latitude,omitempty"`
+	Longitude  *float64   `json:"longitude,omitempty"`
+
+	// Relationships
+	Variants []*FileVariant `json:"variants,omitempty"`
+	Uploader *Employee      `json:"uploader,omitempty"`
+}
+
+// FileScanStatus is the malware scan state of a file; only clean uploads may be used or downloaded
+type FileScanStatus string
+
+const (
+	FileScanStatusPending  FileScanStatus = "pending" // quarantined until the scanner reports it clean
+	FileScanStatusClean    FileScanStatus = "clean"
+	FileScanStatusInfected FileScanStatus = "infected"
+	FileScanStatusSkipped  FileScanStatus = "skipped" // documents the platform generated itself
+)
+
+// Downloadable reports whether the file is out of quarantine
+func (f *FileUpload) Downloadable() bool {
+	return f.ScanStatus == FileScanStatusClean || f.ScanStatus == FileScanStatusSkipped
+}
+
+// FileVariantName identifies a resized copy of an uploaded image
+type FileVariantName string
+
+const (
+	FileVariantThumbnail FileVariantName = "thumbnail" // small image for listings
+	FileVariantPreview   FileVariantName = "preview"   // compressed image for viewing in the back office
+)
+
+// FileVariant is a resized, compressed jpeg derived from an uploaded image and stored next to it
+type FileVariant struct {
+	BaseModel
+	FileUploadID uuid.UUID       `json:"file_upload_id"`
+	Variant      FileVariantName `json:"variant"`
+	FilePath     string          `json:"file_path"` // storage key
+	ContentType  string          `json:"content_type"`
+	FileSize     int64           `json:"file_size"`
+	Width        int             `json:"width"`
+	Height       int             `json:"height"`
+	Checksum     string          `json:"checksum"` // hex SHA-256 of the content
+}
+
+// Variant returns the named variant of the upload, or nil when it has none
+func (f *FileUpload) Variant(name FileVariantName) *FileVariant {
+	for _, variant := range f.Variants {
+		if variant.Variant == name {
+			return variant
+		}
+	}
+	return nil
+}
+
+// FileBundle groups files uploaded together, such as the proof images of one visit
+type FileBundle struct {
+	BaseModel
+	EntityType string    `json:"entity_type" validate:"required"`
+	EntityID   uuid.UUID `json:"entity_id"`
+	CreatedBy  uuid.UUID `json:"created_by" validate:"required"`
+
+	// Relationships
+	Files []*FileUpload `json:"files"`
 }
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PortfolioPosition is a single active investment of an investor together with its loan and repayment figures
+type PortfolioPosition struct {
+	InvestmentID       uuid.UUID `json:"investment_id"`
+	LoanID             uuid.UUID `json:"loan_id"`
+	LoanState          LoanState `json:"loan_state"`
+	LoanProduct        string    `json:"loan_product"`
+	LoanROI            float64   `json:"loan_roi"`
+	Amount             float64   `json:"amount"`
+	ExpectedReturn     float64   `json:"expected_return"`
+	AmountReceived     float64   `json:"amount_received"`
+	AmountOutstanding  float64   `json:"amount_outstanding"`
+	AgreementLetterURL string    `json:"agreement_letter_url,omitempty"`
+	InvestmentDate     time.Time `json:"investment_date"`
+}
+
+// IsLive checks if the position still has money at work; investments in cancelled or expired loans were refunded
+func (p *PortfolioPosition) IsLive() bool {
+	return p.LoanState != LoanStateCancelled && p.LoanState != LoanStateExpired
+}
+
+// Outstanding calculates what the investor is still owed on the position
+func (p *PortfolioPosition) Outstanding() float64 {
+	if !p.IsLive() || p.AmountReceived >= p.ExpectedReturn {
+		return 0
+	}
+	return p.ExpectedReturn - p.AmountReceived
+}
+
+// RealizedReturn calculates the return part of the amount received.
+// Every repayment is split between principal and return in the same proportion as the expected return.
+func (p *PortfolioPosition) RealizedReturn() float64 {
+	if p.ExpectedReturn <= 0 {
+		return 0
+	}
+	return p.AmountReceived * (p.ExpectedReturn - p.Amount) / p.ExpectedReturn
+}
+
+// PortfolioTotals sums a group of portfolio positions
+type PortfolioTotals struct {
+	InvestmentCount   int     `json:"investment_count"`
+	Invested          float64 `json:"invested"`
+	ExpectedReturn    float64 `json:"expected_return"`
+	AmountReceived    float64 `json:"amount_received"`
+	AmountOutstanding float64 `json:"amount_outstanding"`
+}
+
+// Add adds the position to the totals
+func (t *PortfolioTotals) Add(p *PortfolioPosition) {
+	t.InvestmentCount++
+	t.Invested += p.Amount
+	t.ExpectedReturn += p.ExpectedReturn
+	t.AmountReceived += p.AmountReceived
+	t.AmountOutstanding += p.Outstanding()
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReconciliationStatus represents the lifecycle of a reconciliation run
+type ReconciliationStatus string
+
+const (
+	ReconciliationStatusRunning   ReconciliationStatus = "running"
+	ReconciliationStatusCompleted ReconciliationStatus = "completed"
+	ReconciliationStatusFailed    ReconciliationStatus = "failed"
+)
+
+// MismatchType represents the kind of discrepancy found during reconciliation
+type MismatchType string
+
+const (
+	MismatchTypeMissingInReport  MismatchType = "missing_in_report"  // we recorded it, provider did not settle it
+	MismatchTypeMissingInRecords MismatchType = "missing_in_records" // provider settled it, we have no record
+	MismatchTypeDuplicate        MismatchType = "duplicate"          // same transaction ID appears more than once
+	MismatchTypeAmountMismatch   MismatchType = "amount_mismatch"    // amounts differ between both sides
+	MismatchTypeNotSettled       MismatchType = "not_settled"        // provider reported it with a failed or reversed status
+)
+
+// Payment reference types used to match settlement records against our own records
+const (
+	PaymentReferenceDisbursement = "disbursement"
+	PaymentReferenceRepayment    = "repayment"
+	PaymentReferenceWithdrawal   = "withdrawal"
+)
+
+// ReconciliationRun tracks a single execution of the settlement reconciliation job
+type ReconciliationRun struct {
+	BaseModel
+	ReportDate    time.Time            `json:"report_date" validate:"required"`
+	Source        string               `json:"source" validate:"required"` // adapter, csv
+	Status        ReconciliationStatus `json:"status" validate:"required"`
+	TotalRecords  int                  `json:"total_records"`
+	MatchedCount  int                  `json:"matched_count"`
+	MismatchCount int                  `json:"mismatch_count"`
+	CompletedAt   *time.Time           `json:"completed_at,omitempty"`
+	ErrorMessage  string               `json:"error_message,omitempty"`
+
+	// Relationships
+	Mismatches []*ReconciliationMismatch `json:"mismatches,omitempty"`
+}
+
+// ReconciliationMismatch records a discrepancy between our records and the settlement report
+type ReconciliationMismatch struct {
+	BaseModel
+	RunID          uuid.UUID    `json:"run_id" validate:"required"`
+	TransactionID  string       `json:"transaction_id" validate:"required"`
+	MismatchType   MismatchType `json:"mismatch_type" validate:"required"`
+	ReferenceType  string       `json:"reference_type,omitempty"` // disbursement, repayment, withdrawal
+	ReferenceID    *uuid.UUID   `json:"reference_id,omitempty"`
+	ExpectedAmount *float64     `json:"expected_amount,omitempty"`
+	ReportedAmount *float64     `json:"reported_amount,omitempty"`
+	Notes          string       `json:"notes"`
+}
+
+// ExpectedPayment is a money movement we recorded and expect to see in the settlement report
+type ExpectedPayment struct {
+	ReferenceType string    `json:"reference_type"`
+	ReferenceID   uuid.UUID `json:"reference_id"`
+	TransactionID string    `json:"transaction_id"`
+	Amount        float64   `json:"amount"`
+	PaidAt        time.Time `json:"paid_at"`
+}
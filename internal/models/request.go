@@ -12,6 +12,8 @@ type CreateLoanRequest struct {
 	PrincipalAmount float64   `json:"principal_amount" validate:"required,gt=0"`
 	InterestRate    float64   `json:"interest_rate" validate:"required,gte=0,lte=1"`
 	ROI             float64   `json:"roi" validate:"required,gte=0,lte=1"`
+	Product         string    `json:"product,omitempty" validate:"omitempty,max=50"`
+	TenorMonths     int       `json:"tenor_months,omitempty" validate:"omitempty,gt=0,lte=360"`
 }
 
 // UpdateLoanStateRequest represents the request to update loan state
@@ -23,12 +25,14 @@ type UpdateLoanStateRequest struct {
 
 // CreateApprovalRequest represents the request to approve a loan
 type CreateApprovalRequest struct {
-	LoanID              uuid.UUID `json:"loan_id" validate:"required"`
-	ValidatorID         uuid.UUID `json:"validator_id" validate:"required"`
-	ApprovalDate        time.Time `json:"approval_date" validate:"required"`
-	VisitProofImageURL  string    `json:"visit_proof_image_url" validate:"required"`
-	VisitProofImageType FileType  `json:"visit_proof_image_type" validate:"required"`
-	Notes               string    `json:"notes,omitempty"`
+	LoanID       uuid.UUID `json:"loan_id" validate:"required"`
+	ValidatorID  uuid.UUID `json:"validator_id" validate:"required"`
+	ApprovalDate time.Time `json:"approval_date" validate:"required"`
+	Notes        string    `json:"notes,omitempty"`
+
+	// The visit proof is either a single jpeg or png or a bundle of them, uploaded with entity_type "approval"
+	VisitProofFileID   *uuid.UUID `json:"visit_proof_file_id,omitempty" validate:"required_without=VisitProofBundleID,excluded_with=VisitProofBundleID"`
+	VisitProofBundleID *uuid.UUID `json:"visit_proof_bundle_id,omitempty" validate:"required_without=VisitProofFileID"`
 }
 
 // CreateInvestmentRequest represents the request to create an investment
@@ -37,17 +41,137 @@ type CreateInvestmentRequest struct {
 	InvestorID     uuid.UUID `json:"investor_id" validate:"required"`
 	Amount         float64   `json:"amount" validate:"required,gt=0"`
 	InvestmentDate time.Time `json:"investment_date" validate:"required"`
+
+	// ReservationID is set when the investment converts a reservation; the reservation is claimed with the investment
+	ReservationID *uuid.UUID `json:"-"`
+}
+
+// CreateReservationRequest represents the request to hold part of a loan's remaining principal for an investor
+type CreateReservationRequest struct {
+	InvestorID uuid.UUID `json:"investor_id" validate:"required"`
+	Amount     float64   `json:"amount" validate:"required,gt=0"`
+}
+
+// ConfirmReservationRequest represents the request to convert a reservation into an investment
+type ConfirmReservationRequest struct {
+	InvestorID uuid.UUID `json:"investor_id" validate:"required"`
+}
+
+// JoinWaitlistRequest represents the request to queue for capacity on a fully subscribed loan
+type JoinWaitlistRequest struct {
+	InvestorID uuid.UUID `json:"investor_id" validate:"required"`
+	Amount     float64   `json:"amount" validate:"required,gt=0"`
 }
 
 // CreateDisbursementRequest represents the request to disburse a loan
 type CreateDisbursementRequest struct {
-	LoanID                  uuid.UUID `json:"loan_id" validate:"required"`
-	FieldOfficerID          uuid.UUID `json:"field_officer_id" validate:"required"`
-	DisbursementDate        time.Time `json:"disbursement_date" validate:"required"`
-	SignedAgreementURL      string    `json:"signed_agreement_url" validate:"required"`
-	SignedAgreementFileType FileType  `json:"signed_agreement_file_type" validate:"required"`
-	DisbursedAmount         float64   `json:"disbursed_amount" validate:"required,gt=0"`
-	Notes                   string    `json:"notes,omitempty"`
+	LoanID           uuid.UUID `json:"loan_id" validate:"required"`
+	FieldOfficerID   uuid.UUID `json:"field_officer_id" validate:"required"`
+	DisbursementDate time.Time `json:"disbursement_date" validate:"required"`
+	DisbursedAmount  float64   `json:"disbursed_amount" validate:"required,gt=0"`
+	Notes            string    `json:"notes,omitempty"`
+
+	// FileID optionally attaches a document uploaded with entity_type "disbursement", such as the transfer receipt
+	FileID *uuid.UUID `json:"file_id,omitempty"`
+}
+
+// SignatureCallbackRequest represents the e-signature provider's notification that the borrower signed or declined
+type SignatureCallbackRequest struct {
+	EnvelopeID string                   `json:"envelope_id" validate:"required"`
+	Status     AgreementSignatureStatus `json:"status" validate:"required,oneof=signed declined"`
+	SignedAt   *time.Time               `json:"signed_at,omitempty"`
+}
+
+// CancelLoanRequest represents the request to cancel a loan before disbursement
+type CancelLoanRequest struct {
+	ChangedBy    uuid.UUID `json:"changed_by" validate:"required"`
+	ChangeReason string    `json:"change_reason" validate:"required"`
+}
+
+// CancelInvestmentRequest represents the request to cancel an investment before the loan is fully funded
+type CancelInvestmentRequest struct {
+	InvestorID uuid.UUID `json:"investor_id" validate:"required"`
+	Reason     string    `json:"reason,omitempty"`
+}
+
+// CreateTransferListingRequest represents the request to list a whole investment or a slice of it on the secondary market
+type CreateTransferListingRequest struct {
+	InvestorID uuid.UUID `json:"investor_id" validate:"required"`
+	Amount     float64   `json:"amount" validate:"required,gt=0"`
+	Price      float64   `json:"price" validate:"required,gt=0"`
+}
+
+// BuyTransferRequest represents the request to buy a secondary market listing
+type BuyTransferRequest struct {
+	InvestorID uuid.UUID `json:"investor_id" validate:"required"`
+}
+
+// CancelTransferListingRequest represents the request to withdraw a secondary market listing
+type CancelTransferListingRequest struct {
+	InvestorID uuid.UUID `json:"investor_id" validate:"required"`
+}
+
+// SetInvestorLimitsRequest represents the request to override the global investment limits for an investor.
+// Omitted fields fall back to the global configuration.
+type SetInvestorLimitsRequest struct {
+	MaxLoanShare     *float64  `json:"max_loan_share,omitempty" validate:"omitempty,gte=0,lte=1"`
+	MaxTotalExposure *float64  `json:"max_total_exposure,omitempty" validate:"omitempty,gte=0"`
+	MinTicket        *float64  `json:"min_ticket,omitempty" validate:"omitempty,gte=0"`
+	UpdatedBy        uuid.UUID `json:"updated_by" validate:"required"`
+}
+
+// CreateAutoInvestRuleRequest represents the request to create an auto-invest rule for an investor
+type CreateAutoInvestRuleRequest struct {
+	MinROI        float64  `json:"min_roi" validate:"gte=0,lte=1"`
+	MaxPrincipal  *float64 `json:"max_principal,omitempty" validate:"omitempty,gt=0"`
+	Product       string   `json:"product,omitempty" validate:"omitempty,max=50"`
+	MaxRiskGrade  string   `json:"max_risk_grade,omitempty" validate:"omitempty,oneof=A B C D E"`
+	AmountPerLoan float64  `json:"amount_per_loan" validate:"required,gt=0"`
+}
+
+// MarketplaceLoansQuery represents the query parameters of the marketplace loan listing
+type MarketplaceLoansQuery struct {
+	SortBy string `form:"sort_by" validate:"omitempty,oneof=roi remaining_amount age"`
+	Order  string `form:"order" validate:"omitempty,oneof=asc desc"`
+}
+
+// WithDefaults fills in the sort; newest listings come first, ROI and remaining amount sort highest first
+func (q MarketplaceLoansQuery) WithDefaults() MarketplaceLoansQuery {
+	if q.SortBy == "" {
+		q.SortBy = MarketplaceSortAge
+	}
+	if q.Order == "" {
+		q.Order = SortOrderDesc
+		if q.SortBy == MarketplaceSortAge {
+			q.Order = SortOrderAsc
+		}
+	}
+	return q
+}
+
+// WalletTopUpRequest represents the request to add funds to an investor wallet
+type WalletTopUpRequest struct {
+	Amount       float64 `json:"amount" validate:"required,gt=0"`
+	PaymentToken string  `json:"payment_token" validate:"required"`
+}
+
+// CreateBankAccountRequest represents the request to register an investor bank account
+type CreateBankAccountRequest struct {
+	BankCode          string `json:"bank_code" validate:"required"`
+	AccountNumber     string `json:"account_number" validate:"required,numeric,min=6,max=20"`
+	AccountHolderName string `json:"account_holder_name" validate:"required"`
+}
+
+// CreateWithdrawalRequest represents the request to withdraw wallet funds to a bank account
+type CreateWithdrawalRequest struct {
+	BankAccountID uuid.UUID `json:"bank_account_id" validate:"required"`
+	Amount        float64   `json:"amount" validate:"required,gt=0"`
+}
+
+// ReviewWithdrawalRequest represents an employee decision on a withdrawal above the approval threshold
+type ReviewWithdrawalRequest struct {
+	ReviewedBy uuid.UUID `json:"reviewed_by" validate:"required"`
+	Reason     string    `json:"reason,omitempty"`
 }
 
 // CreateBorrowerRequest represents the request to create a new borrower
@@ -95,6 +219,7 @@ type CreateInvestorRequest struct {
 	Name         string `json:"name" validate:"required"`
 	Email        string `json:"email" validate:"required,email"`
 	PhoneNumber  string `json:"phone_number"`
+	Locale       string `json:"locale,omitempty" validate:"omitempty,oneof=id-ID en-US"`
 }
 
 // UpdateInvestorRequest represents the request to update investor information
@@ -102,5 +227,20 @@ type UpdateInvestorRequest struct {
 	Name        string `json:"name,omitempty"`
 	Email       string `json:"email,omitempty" validate:"omitempty,email"`
 	PhoneNumber string `json:"phone_number,omitempty"`
+	Locale      string `json:"locale,omitempty" validate:"omitempty,oneof=id-ID en-US"`
 	IsActive    *bool  `json:"is_active,omitempty"`
 }
+
+// CreateDownloadURLRequest represents the request for a signed download URL; the caller is either an investor or an employee
+type CreateDownloadURLRequest struct {
+	InvestorID *uuid.UUID `json:"investor_id,omitempty" validate:"required_without=EmployeeID,excluded_with=EmployeeID"`
+	EmployeeID *uuid.UUID `json:"employee_id,omitempty" validate:"required_without=InvestorID"`
+
+	// Variant asks for the thumbnail or preview of an image instead of the original
+	Variant FileVariantName `json:"variant,omitempty" validate:"omitempty,oneof=thumbnail preview"`
+}
+
+// RetryEmailNotificationRequest represents an employee sending a failed email notification again
+type RetryEmailNotificationRequest struct {
+	RequestedBy uuid.UUID `json:"requested_by" validate:"required"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InvestmentReservation holds part of a loan's remaining principal for an investor while their payment is collected.
+// Reservations live in Redis only and stop counting against the loan once they expire.
+type InvestmentReservation struct {
+	ID         uuid.UUID `json:"id"`
+	LoanID     uuid.UUID `json:"loan_id"`
+	InvestorID uuid.UUID `json:"investor_id"`
+	Amount     float64   `json:"amount"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// IsExpired checks if the reservation no longer holds capacity
+func (r *InvestmentReservation) IsExpired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
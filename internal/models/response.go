@@ -24,15 +24,23 @@ type LoanSummaryResponse struct {
 }
 
 type LoanApprovalResponse struct {
-	ID                  uuid.UUID `json:"id"`
-	LoanID              uuid.UUID `json:"loan_id"`
-	ValidatorID         uuid.UUID `json:"validator_id"`
-	ApprovalDate        time.Time `json:"approval_date"`
-	VisitProofImageURL  string    `json:"visit_proof_image_url"`
-	VisitProofImageType FileType  `json:"visit_proof_image_type"`
-	Notes               string    `json:"notes"`
-	CreatedAt           time.Time `json:"created_at"`
-	UpdatedAt           time.Time `json:"updated_at"`
+	ID                  uuid.UUID  `json:"id"`
+	LoanID              uuid.UUID  `json:"loan_id"`
+	ValidatorID         uuid.UUID  `json:"validator_id"`
+	ApprovalDate        time.Time  `json:"approval_date"`
+	VisitProofImageURL  string     `json:"visit_proof_image_url"`
+	VisitProofImageType FileType   `json:"visit_proof_image_type"`
+	VisitProofBundleID  *uuid.UUID `json:"visit_proof_bundle_id,omitempty"`
+	Notes               string     `json:"notes"`
+
+	VisitProofCapturedAt *time.Time `json:"visit_proof_captured_at,omitempty"`
+	VisitProofLatitude   *float64   `json:"visit_proof_latitude,omitempty"`
+	VisitProofLongitude  *float64   `json:"visit_proof_longitude,omitempty"`
+	VisitDistanceMeters  *float64   `json:"visit_distance_meters,omitempty"`
+	VisitProofWarnings   []string   `json:"visit_proof_warnings,omitempty"` // checks that failed in warn mode
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // BorrowerSummaryResponse represents a summary view of a borrower
@@ -85,6 +93,18 @@ type InvestmentResponse struct {
 	UpdatedAt      time.Time `json:"updated_at"`
 }
 
+// InvestmentCancellationResponse represents the response for investment cancellation
+type InvestmentCancellationResponse struct {
+	InvestmentID        uuid.UUID `json:"investment_id"`
+	LoanID              uuid.UUID `json:"loan_id"`
+	InvestorID          uuid.UUID `json:"investor_id"`
+	Amount              float64   `json:"amount"`
+	Reason              string    `json:"reason,omitempty"`
+	LoanTotalInvested   float64   `json:"loan_total_invested"`
+	RemainingInvestment float64   `json:"remaining_investment"`
+	CancelledAt         time.Time `json:"cancelled_at"`
+}
+
 // // InvestmentSummaryResponse represents a summary view of an investment
 // type InvestmentSummaryResponse struct {
 // 	ID             uuid.UUID `json:"id"`
@@ -108,7 +128,114 @@ type DisbursementResponse struct {
 	SignedAgreementURL      string    `json:"signed_agreement_url"`
 	SignedAgreementFileType FileType  `json:"signed_agreement_file_type"`
 	DisbursedAmount         float64   `json:"disbursed_amount"`
+	TransactionID           string    `json:"transaction_id,omitempty"`
 	Notes                   string    `json:"notes"`
 	CreatedAt               time.Time `json:"created_at"`
 	UpdatedAt               time.Time `json:"updated_at"`
 }
+
+// WalletResponse represents an investor wallet with its ledger-derived balance
+type WalletResponse struct {
+	WalletBalance
+	Currency     string               `json:"currency"`
+	Transactions []*WalletTransaction `json:"transactions"`
+}
+
+// BankAccountResponse represents an investor bank account with the account number masked
+type BankAccountResponse struct {
+	ID                uuid.UUID `json:"id"`
+	InvestorID        uuid.UUID `json:"investor_id"`
+	BankCode          string    `json:"bank_code"`
+	AccountNumber     string    `json:"account_number"`
+	AccountHolderName string    `json:"account_holder_name"`
+	IsActive          bool      `json:"is_active"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// WithdrawalResponse represents the response for withdrawal requests and reviews
+type WithdrawalResponse struct {
+	ID              uuid.UUID        `json:"id"`
+	InvestorID      uuid.UUID        `json:"investor_id"`
+	BankAccountID   uuid.UUID        `json:"bank_account_id"`
+	Amount          float64          `json:"amount"`
+	Status          WithdrawalStatus `json:"status"`
+	RequiresReview  bool             `json:"requires_review"`
+	ReviewedBy      *uuid.UUID       `json:"reviewed_by,omitempty"`
+	ReviewedAt      *time.Time       `json:"reviewed_at,omitempty"`
+	RejectionReason string           `json:"rejection_reason,omitempty"`
+	TransactionID   string           `json:"transaction_id,omitempty"`
+	FailureReason   string           `json:"failure_reason,omitempty"`
+	CompletedAt     *time.Time       `json:"completed_at,omitempty"`
+	CreatedAt       time.Time        `json:"created_at"`
+	UpdatedAt       time.Time        `json:"updated_at"`
+}
+
+// InvestorLimitsResponse represents the limits applied to an investor and any investor specific override
+type InvestorLimitsResponse struct {
+	InvestorID uuid.UUID                `json:"investor_id"`
+	Effective  InvestmentLimits         `json:"effective"`
+	Override   *InvestorInvestmentLimit `json:"override,omitempty"`
+}
+
+// PortfolioResponse represents an investor's positions with totals per loan state and yield figures.
+// Totals, ExpectedYield and RealizedYield cover live positions only.
+type PortfolioResponse struct {
+	InvestorID    uuid.UUID                      `json:"investor_id"`
+	Investments   []*PortfolioPosition           `json:"investments"`
+	TotalsByState map[LoanState]*PortfolioTotals `json:"totals_by_state"`
+	Totals        PortfolioTotals                `json:"totals"`
+	ExpectedYield float64                        `json:"expected_yield"` // expected return over invested principal
+	RealizedYield float64                        `json:"realized_yield"` // return received so far over invested principal
+}
+
+// WaitlistEntryResponse represents a waitlist entry and its place in the loan's queue
+type WaitlistEntryResponse struct {
+	ID         uuid.UUID      `json:"id"`
+	LoanID     uuid.UUID      `json:"loan_id"`
+	InvestorID uuid.UUID      `json:"investor_id"`
+	Amount     float64        `json:"amount"`
+	Status     WaitlistStatus `json:"status"`
+	Position   int            `json:"position"` // 1 is next in line
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// FileVersionResponse describes one version of a document in its history
+type FileVersionResponse struct {
+	FileID       uuid.UUID      `json:"file_id"`
+	DocumentID   uuid.UUID      `json:"document_id"`
+	Version      int            `json:"version"`
+	FileName     string         `json:"file_name"`
+	FileType     FileType       `json:"file_type"`
+	FileSize     int64          `json:"file_size"`
+	Checksum     string         `json:"checksum"`
+	IsCurrent    bool           `json:"is_current"`
+	ScanStatus   FileScanStatus `json:"scan_status"`
+	UploadedBy   uuid.UUID      `json:"uploaded_by"`
+	UploaderName string         `json:"uploader_name,omitempty"`
+	UploadedAt   time.Time      `json:"uploaded_at"`
+}
+
+// FileDownloadURLResponse is a signed link to download a stored file until it expires
+type FileDownloadURLResponse struct {
+	FileID    uuid.UUID       `json:"file_id"`
+	Variant   FileVariantName `json:"variant,omitempty"`
+	URL       string          `json:"url"`
+	ExpiresAt time.Time       `json:"expires_at"`
+}
+
+// EmailNotificationResponse describes the delivery state of an email notification
+type EmailNotificationResponse struct {
+	ID            uuid.UUID  `json:"id"`
+	InvestorID    uuid.UUID  `json:"investor_id"`
+	LoanID        uuid.UUID  `json:"loan_id"`
+	EmailType     string     `json:"email_type"`
+	Recipient     string     `json:"recipient"`
+	EmailSubject  string     `json:"email_subject"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	ErrorMessage  string     `json:"error_message,omitempty"`
+	LastAttemptAt *time.Time `json:"last_attempt_at,omitempty"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
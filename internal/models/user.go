@@ -13,6 +13,11 @@ type Borrower struct {
 	Email       string `json:"email" validate:"email"`
 	PhoneNumber string `json:"phone_number" validate:"required"`
 	Address     string `json:"address"`
+	RiskGrade   string `json:"risk_grade,omitempty"` // A (best) to E (worst), empty when unrated
+
+	// Geocoded position of the address, nil until it has been geocoded
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
 
 	// Relationships
 	Loans []Loan `json:"loans,omitempty"`
@@ -51,6 +56,7 @@ type Investor struct {
 	Name         string `json:"name" validate:"required"`
 	Email        string `json:"email" validate:"required,email"`
 	PhoneNumber  string `json:"phone_number"`
+	Locale       string `json:"locale"` // id-ID or en-US; emails are sent in it
 	IsActive     bool   `json:"is_active"`
 
 	// Relationships
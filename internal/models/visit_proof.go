@@ -0,0 +1,43 @@
+package models
+
+import "math"
+
+// Visit proof check modes
+const (
+	VisitProofModeOff   = "off"
+	VisitProofModeWarn  = "warn"  // approve, but report failed checks in the response
+	VisitProofModeBlock = "block" // reject approvals whose photo fails a check
+)
+
+// Visit proof error codes, returned to clients when a check blocks an approval
+const (
+	CodeVisitProofTooFar        = "VISIT_PROOF_TOO_FAR"
+	CodeVisitProofTooOld        = "VISIT_PROOF_TOO_OLD"
+	CodeVisitProofAfterApproval = "VISIT_PROOF_AFTER_APPROVAL"
+	CodeVisitProofNoLocation    = "VISIT_PROOF_NO_LOCATION"
+	CodeVisitProofNoCaptureTime = "VISIT_PROOF_NO_CAPTURE_TIME"
+)
+
+// VisitProofError is returned when the visit proof photo was taken too far from the borrower, outside the window
+// before the approval, or carries no position or capture time to check
+type VisitProofError struct {
+	Code    string
+	Message string
+}
+
+func (e *VisitProofError) Error() string {
+	return e.Message
+}
+
+const earthRadiusMeters = 6371000
+
+// DistanceMeters returns the great-circle distance between two positions in decimal degrees
+func DistanceMeters(lat1, lon1, lat2, lon2 float64) float64 {
+	toRadians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+
+	dLat := toRadians(lat2 - lat1)
+	dLon := toRadians(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(a))
+}
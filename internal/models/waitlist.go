@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WaitlistStatus represents where a waitlist entry is in its lifecycle
+type WaitlistStatus string
+
+const (
+	WaitlistStatusWaiting    WaitlistStatus = "waiting"
+	WaitlistStatusProcessing WaitlistStatus = "processing" // claimed by the run serving the loan's queue
+	WaitlistStatusAllocated  WaitlistStatus = "allocated"  // freed capacity was invested on the investor's behalf
+	WaitlistStatusFailed     WaitlistStatus = "failed"     // the investment was refused, e.g. insufficient wallet balance
+	WaitlistStatusClosed     WaitlistStatus = "closed"     // the loan stopped raising funds before capacity was freed
+)
+
+// WaitlistEntry queues an investor for capacity freed on a fully subscribed loan; entries are served first in, first out
+type WaitlistEntry struct {
+	BaseModel
+	LoanID          uuid.UUID      `json:"loan_id" validate:"required"`
+	InvestorID      uuid.UUID      `json:"investor_id" validate:"required"`
+	Amount          float64        `json:"amount" validate:"required,gt=0"` // the most the investor wants to invest
+	Status          WaitlistStatus `json:"status"`
+	AllocatedAmount *float64       `json:"allocated_amount,omitempty"` // may be less than Amount when less capacity was freed
+	InvestmentID    *uuid.UUID     `json:"investment_id,omitempty"`
+	FailureReason   string         `json:"failure_reason,omitempty"`
+	ProcessedAt     *time.Time     `json:"processed_at,omitempty"`
+	ClaimedUntil    *time.Time     `json:"-"` // while processing, when the claim of the run serving it lapses
+}
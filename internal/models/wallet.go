@@ -0,0 +1,105 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WalletTransactionType represents the kind of entry in the append-only wallet ledger
+type WalletTransactionType string
+
+const (
+	WalletTransactionTopUp   WalletTransactionType = "top_up"  // funds added to the wallet
+	WalletTransactionHold    WalletTransactionType = "hold"    // funds reserved for an investment
+	WalletTransactionRelease WalletTransactionType = "release" // reserved funds returned to available balance
+	WalletTransactionCapture WalletTransactionType = "capture" // reserved funds moved out to the disbursed loan
+	WalletTransactionPayout  WalletTransactionType = "payout"  // funds paid out of the wallet to the investor
+
+	WalletTransactionTransferIn  WalletTransactionType = "transfer_in"  // sale proceeds received for a transferred investment
+	WalletTransactionTransferOut WalletTransactionType = "transfer_out" // price paid for an investment bought from another investor
+)
+
+// DefaultWalletCurrency is the currency wallets are opened in
+const DefaultWalletCurrency = "IDR"
+
+// Wallet reference types link ledger entries to the entity that caused them
+const (
+	WalletReferenceInvestment = "investment"
+	WalletReferenceLoan       = "loan"
+	WalletReferencePayment    = "payment"
+	WalletReferenceTopUp      = "top_up"
+)
+
+// WalletTopUpStatus represents the lifecycle of a wallet top up
+type WalletTopUpStatus string
+
+const (
+	WalletTopUpStatusPending   WalletTopUpStatus = "pending"   // recorded, investor not charged yet
+	WalletTopUpStatusCharged   WalletTopUpStatus = "charged"   // investor charged, wallet not credited yet
+	WalletTopUpStatusCompleted WalletTopUpStatus = "completed" // credited to the wallet
+	WalletTopUpStatusFailed    WalletTopUpStatus = "failed"    // charge failed, nothing credited
+)
+
+// Wallet is the investor's account; balances are never stored here, they are derived from the ledger
+type Wallet struct {
+	BaseModel
+	InvestorID uuid.UUID `json:"investor_id" validate:"required"`
+	Currency   string    `json:"currency" validate:"required"`
+}
+
+// WalletTopUp is recorded before the investor is charged so every charge can be credited exactly once
+type WalletTopUp struct {
+	BaseModel
+	InvestorID    uuid.UUID         `json:"investor_id" validate:"required"`
+	Amount        float64           `json:"amount" validate:"required,gt=0"`
+	Status        WalletTopUpStatus `json:"status" validate:"required"`
+	TransactionID string            `json:"transaction_id,omitempty"` // payment provider charge reference
+	FailureReason string            `json:"failure_reason,omitempty"`
+	CompletedAt   *time.Time        `json:"completed_at,omitempty"`
+}
+
+// WalletTransaction is a single immutable ledger entry
+type WalletTransaction struct {
+	BaseModel
+	WalletID        uuid.UUID             `json:"wallet_id" validate:"required"`
+	InvestorID      uuid.UUID             `json:"investor_id" validate:"required"`
+	TransactionType WalletTransactionType `json:"transaction_type" validate:"required"`
+	Amount          float64               `json:"amount" validate:"required,gt=0"`
+	ReferenceType   string                `json:"reference_type,omitempty"`
+	ReferenceID     *uuid.UUID            `json:"reference_id,omitempty"`
+	HoldID          *uuid.UUID            `json:"hold_id,omitempty"` // hold settled by a release or capture
+	Description     string                `json:"description"`
+}
+
+// WalletBalance is the balance derived from the ledger
+type WalletBalance struct {
+	InvestorID       uuid.UUID `json:"investor_id"`
+	Balance          float64   `json:"balance"`           // top ups and transfers in minus captures, payouts and transfers out
+	HeldAmount       float64   `json:"held_amount"`       // open holds
+	AvailableBalance float64   `json:"available_balance"` // balance minus held amount
+}
+
+// Apply adds the effect of ledger entries of one type to the balance. A capture both spends the funds and settles
+// the hold they were reserved under; a release only settles the hold.
+func (b *WalletBalance) Apply(transactionType WalletTransactionType, amount float64) {
+	switch transactionType {
+	case WalletTransactionTopUp, WalletTransactionTransferIn:
+		b.Balance += amount
+	case WalletTransactionPayout, WalletTransactionTransferOut:
+		b.Balance -= amount
+	case WalletTransactionHold:
+		b.HeldAmount += amount
+	case WalletTransactionRelease:
+		b.HeldAmount -= amount
+	case WalletTransactionCapture:
+		b.Balance -= amount
+		b.HeldAmount -= amount
+	}
+	b.AvailableBalance = b.Balance - b.HeldAmount
+}
+
+// CanCover checks if the available balance covers the amount
+func (b *WalletBalance) CanCover(amount float64) bool {
+	return b.AvailableBalance >= amount
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WithdrawalStatus represents the lifecycle of an investor withdrawal
+type WithdrawalStatus string
+
+const (
+	WithdrawalStatusPendingApproval WithdrawalStatus = "pending_approval" // above threshold, waiting for an employee
+	WithdrawalStatusApproved        WithdrawalStatus = "approved"         // cleared for payout
+	WithdrawalStatusProcessing      WithdrawalStatus = "processing"       // payout sent, hold not settled yet
+	WithdrawalStatusCompleted       WithdrawalStatus = "completed"        // paid out to the bank account
+	WithdrawalStatusRejected        WithdrawalStatus = "rejected"         // rejected by an employee, funds released
+	WithdrawalStatusFailed          WithdrawalStatus = "failed"           // payout failed, funds released
+)
+
+// WalletReferenceWithdrawal links ledger entries to the withdrawal that caused them
+const WalletReferenceWithdrawal = "withdrawal"
+
+// BankAccount is an investor bank account that withdrawals are paid out to
+type BankAccount struct {
+	BaseModel
+	InvestorID        uuid.UUID `json:"investor_id" validate:"required"`
+	BankCode          string    `json:"bank_code" validate:"required"`
+	AccountNumber     string    `json:"account_number" validate:"required"`
+	AccountHolderName string    `json:"account_holder_name" validate:"required"`
+	IsActive          bool      `json:"is_active"`
+
+	// Relationships
+	Investor *Investor `json:"investor,omitempty"`
+}
+
+// MaskedAccountNumber returns the account number with all but the last four digits hidden
+func (b *BankAccount) MaskedAccountNumber() string {
+	if len(b.AccountNumber) <= 4 {
+		return b.AccountNumber
+	}
+	return strings.Repeat("*", len(b.AccountNumber)-4) + b.AccountNumber[len(b.AccountNumber)-4:]
+}
+
+// Withdrawal moves funds from the investor wallet to a registered bank account
+type Withdrawal struct {
+	BaseModel
+	InvestorID      uuid.UUID        `json:"investor_id" validate:"required"`
+	BankAccountID   uuid.UUID        `json:"bank_account_id" validate:"required"`
+	Amount          float64          `json:"amount" validate:"required,gt=0"`
+	Status          WithdrawalStatus `json:"status" validate:"required"`
+	HoldID          uuid.UUID        `json:"hold_id"` // wallet hold reserving the funds until payout
+	RequiresReview  bool             `json:"requires_review"`
+	ReviewedBy      *uuid.UUID       `json:"reviewed_by,omitempty"`
+	ReviewedAt      *time.Time       `json:"reviewed_at,omitempty"`
+	RejectionReason string           `json:"rejection_reason,omitempty"`
+	TransactionID   string           `json:"transaction_id,omitempty"` // payment provider payout reference
+	FailureReason   string           `json:"failure_reason,omitempty"`
+	CompletedAt     *time.Time       `json:"completed_at,omitempty"`
+
+	// Relationships
+	BankAccount *BankAccount `json:"bank_account,omitempty"`
+}
+
+// CanBeReviewed checks if an employee can still approve or reject the withdrawal
+func (w *Withdrawal) CanBeReviewed() bool {
+	return w.Status == WithdrawalStatusPendingApproval
+}
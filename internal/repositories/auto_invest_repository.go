@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"loan-service/internal/models"
+	"loan-service/pkg/logger"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AutoInvestRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+func NewAutoInvestRepository(db *sql.DB, logger *logger.Logger) AutoInvestRepositoryInterface {
+	return &AutoInvestRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+const autoInvestRuleColumns = `r.id, r.investor_id, r.min_roi, r.max_principal, COALESCE(r.product, ''),
+		COALESCE(r.max_risk_grade, ''), r.amount_per_loan, r.is_active, r.last_allocated_at, r.created_at, r.updated_at`
+
+func (r *AutoInvestRepository) CreateAutoInvestRule(rule *models.AutoInvestRule) (*models.AutoInvestRule, error) {
+	if rule.ID == uuid.Nil {
+		rule.ID = uuid.New()
+	}
+
+	query := `INSERT INTO auto_invest_rules (id, investor_id, min_roi, max_principal, product, max_risk_grade, amount_per_loan, is_active, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7, $8, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			  RETURNING created_at, updated_at`
+
+	err := r.db.QueryRow(query,
+		rule.ID,
+		rule.InvestorID,
+		rule.MinROI,
+		rule.MaxPrincipal,
+		rule.Product,
+		rule.MaxRiskGrade,
+		rule.AmountPerLoan,
+		rule.IsActive,
+	).Scan(&rule.CreatedAt, &rule.UpdatedAt)
+
+	return rule, err
+}
+
+// GetAutoInvestRulesByInvestorID gets the rules of an investor, newest first
+func (r *AutoInvestRepository) GetAutoInvestRulesByInvestorID(investorID uuid.UUID) ([]*models.AutoInvestRule, error) {
+	query := `SELECT ` + autoInvestRuleColumns + `
+			  FROM auto_invest_rules r
+			  WHERE r.investor_id = $1 AND r.deleted_at IS NULL
+			  ORDER BY r.created_at DESC`
+
+	return r.queryAutoInvestRules(query, investorID)
+}
+
+// GetActiveAutoInvestRules gets the active rules of active investors in rotation order:
+// rules that have never allocated or allocated longest ago come first
+func (r *AutoInvestRepository) GetActiveAutoInvestRules() ([]*models.AutoInvestRule, error) {
+	query := `SELECT ` + autoInvestRuleColumns + `
+			  FROM auto_invest_rules r
+			  INNER JOIN investors i ON r.investor_id = i.id AND i.deleted_at IS NULL AND i.is_active = true
+			  WHERE r.is_active = true AND r.deleted_at IS NULL
+			  ORDER BY r.last_allocated_at ASC NULLS FIRST, r.created_at ASC`
+
+	return r.queryAutoInvestRules(query)
+}
+
+// DeactivateAutoInvestRule switches off a rule of the investor
+func (r *AutoInvestRepository) DeactivateAutoInvestRule(investorID, ruleID uuid.UUID) error {
+	query := `UPDATE auto_invest_rules SET is_active = false, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $1 AND investor_id = $2 AND deleted_at IS NULL`
+
+	result, err := r.db.Exec(query, ruleID, investorID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("auto-invest rule %s not found for investor %s", ruleID, investorID)
+	}
+
+	return nil
+}
+
+// MarkAutoInvestRuleAllocated moves the rule to the back of the rotation
+func (r *AutoInvestRepository) MarkAutoInvestRuleAllocated(ruleID uuid.UUID, allocatedAt time.Time) error {
+	query := `UPDATE auto_invest_rules SET last_allocated_at = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+
+	_, err := r.db.Exec(query, allocatedAt, ruleID)
+	return err
+}
+
+func (r *AutoInvestRepository) queryAutoInvestRules(query string, args ...interface{}) ([]*models.AutoInvestRule, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []*models.AutoInvestRule
+	for rows.Next() {
+		var rule models.AutoInvestRule
+		err := rows.Scan(
+			&rule.ID,
+			&rule.InvestorID,
+			&rule.MinROI,
+			&rule.MaxPrincipal,
+			&rule.Product,
+			&rule.MaxRiskGrade,
+			&rule.AmountPerLoan,
+			&rule.IsActive,
+			&rule.LastAllocatedAt,
+			&rule.CreatedAt,
+			&rule.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, &rule)
+	}
+
+	return rules, rows.Err()
+}
@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"database/sql"
+	"loan-service/internal/models"
+	"loan-service/pkg/logger"
+)
+
+type EmailTemplateRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+func NewEmailTemplateRepository(db *sql.DB, logger *logger.Logger) EmailTemplateRepositoryInterface {
+	return &EmailTemplateRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// GetEmailTemplate gets one version of a template in a locale, or nil when the database does not override it
+func (r *EmailTemplateRepository) GetEmailTemplate(name, locale, version string) (*models.EmailTemplate, error) {
+	query := `SELECT id, name, locale, version, subject, COALESCE(html_body, ''), text_body, created_at, updated_at
+			  FROM email_templates
+			  WHERE name = $1 AND locale = $2 AND version = $3 AND deleted_at IS NULL`
+
+	var tmpl models.EmailTemplate
+	err := r.db.QueryRow(query, name, locale, version).Scan(
+		&tmpl.ID,
+		&tmpl.Name,
+		&tmpl.Locale,
+		&tmpl.Version,
+		&tmpl.Subject,
+		&tmpl.HTMLBody,
+		&tmpl.TextBody,
+		&tmpl.CreatedAt,
+		&tmpl.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &tmpl, nil
+}
@@ -0,0 +1,437 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"loan-service/internal/models"
+	"loan-service/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+type FileRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+func NewFileRepository(db *sql.DB, logger *logger.Logger) FileRepositoryInterface {
+	return &FileRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+const fileUploadColumns = `f.id, f.file_name, f.file_type, f.file_size, f.file_path, f.file_url, f.content_type,
+		f.checksum, f.storage_backend, f.uploaded_by, f.entity_type, f.entity_id, f.bundle_id, COALESCE(f.is_active, true),
+		f.document_id, f.version, f.replaces_id,
+		f.scan_status, COALESCE(f.scan_signature, ''), f.scanned_at, f.captured_at, f.latitude, f.longitude,
+		f.created_at, f.updated_at`
+
+func (r *FileRepository) CreateFileUpload(tx *sql.Tx, upload *models.FileUpload) (*models.FileUpload, error) {
+	if upload.ID == uuid.Nil {
+		upload.ID = uuid.New()
+	}
+	// A first version starts its own document
+	if upload.DocumentID == uuid.Nil {
+		upload.DocumentID = upload.ID
+	}
+	if upload.Version == 0 {
+		upload.Version = 1
+	}
+
+	query := `INSERT INTO file_uploads (id, file_name, file_type, file_size, file_path, file_url, content_type,
+			  checksum, storage_backend, uploaded_by, entity_type, entity_id, bundle_id, is_active, scan_status,
+			  captured_at, latitude, longitude, document_id, version, replaces_id, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21,
+			  CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			  RETURNING created_at, updated_at`
+
+	args := []interface{}{
+		upload.ID,
+		upload.FileName,
+		upload.FileType,
+		upload.FileSize,
+		upload.FilePath,
+		upload.FileURL,
+		upload.ContentType,
+		upload.Checksum,
+		upload.StorageBackend,
+		upload.UploadedBy,
+		upload.EntityType,
+		upload.EntityID,
+		upload.BundleID,
+		upload.IsActive,
+		upload.ScanStatus,
+		upload.CapturedAt,
+		upload.Latitude,
+		upload.Longitude,
+		upload.DocumentID,
+		upload.Version,
+		upload.ReplacesID,
+	}
+
+	var err error
+	if tx != nil {
+		err = tx.QueryRow(query, args...).Scan(&upload.CreatedAt, &upload.UpdatedAt)
+	} else {
+		err = r.db.QueryRow(query, args...).Scan(&upload.CreatedAt, &upload.UpdatedAt)
+	}
+
+	return upload, err
+}
+
+// GetFileUploadByID returns nil without an error when there is no such upload
+func (r *FileRepository) GetFileUploadByID(tx *sql.Tx, id uuid.UUID) (*models.FileUpload, error) {
+	query := `SELECT ` + fileUploadColumns + `
+			  FROM file_uploads f
+			  WHERE f.id = $1 AND f.deleted_at IS NULL`
+
+	var row *sql.Row
+	if tx != nil {
+		row = tx.QueryRow(query, id)
+	} else {
+		row = r.db.QueryRow(query, id)
+	}
+
+	upload, err := scanFileUpload(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return upload, err
+}
+
+// LockFileUpload locks the upload row until the transaction ends; it returns nil without an error when there is no such upload
+func (r *FileRepository) LockFileUpload(tx *sql.Tx, id uuid.UUID) (*models.FileUpload, error) {
+	query := `SELECT ` + fileUploadColumns + `
+			  FROM file_uploads f
+			  WHERE f.id = $1 AND f.deleted_at IS NULL
+			  FOR UPDATE`
+
+	var row *sql.Row
+	if tx != nil {
+		row = tx.QueryRow(query, id)
+	} else {
+		row = r.db.QueryRow(query, id)
+	}
+
+	upload, err := scanFileUpload(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return upload, err
+}
+
+func (r *FileRepository) UpdateFileUploadEntityID(tx *sql.Tx, id, entityID uuid.UUID) error {
+	query := `UPDATE file_uploads SET entity_id = $1, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $2 AND deleted_at IS NULL`
+
+	var err error
+	if tx != nil {
+		_, err = tx.Exec(query, entityID, id)
+	} else {
+		_, err = r.db.Exec(query, entityID, id)
+	}
+	return err
+}
+
+// DeactivateFileUpload marks an upload as replaced by a newer version
+func (r *FileRepository) DeactivateFileUpload(tx *sql.Tx, id uuid.UUID) error {
+	query := `UPDATE file_uploads SET is_active = false, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $1 AND deleted_at IS NULL`
+
+	var err error
+	if tx != nil {
+		_, err = tx.Exec(query, id)
+	} else {
+		_, err = r.db.Exec(query, id)
+	}
+	return err
+}
+
+// fileURLColumns are the columns that link a record to a stored file by its URL
+var fileURLColumns = []struct{ table, column string }{
+	{"loans", "agreement_letter_url"},
+	{"approvals", "visit_proof_image_url"},
+	{"disbursements", "signed_agreement_url"},
+	{"investments", "agreement_url"},
+	{"investment_transfers", "agreement_url"},
+	{"agreement_signatures", "signed_file_url"},
+}
+
+// RepointFileURL moves the records that link to a file over to the version that replaces it
+func (r *FileRepository) RepointFileURL(tx *sql.Tx, oldURL, newURL string) error {
+	for _, c := range fileURLColumns {
+		query := fmt.Sprintf(`UPDATE %s SET %s = $2, updated_at = CURRENT_TIMESTAMP WHERE %s = $1`, c.table, c.column, c.column)
+
+		var err error
+		if tx != nil {
+			_, err = tx.Exec(query, oldURL, newURL)
+		} else {
+			_, err = r.db.Exec(query, oldURL, newURL)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to repoint %s.%s: %w", c.table, c.column, err)
+		}
+	}
+	return nil
+}
+
+// GetFileVersions returns every version of a document, newest first, with the name of the employee who uploaded each
+func (r *FileRepository) GetFileVersions(documentID uuid.UUID) ([]*models.FileUpload, error) {
+	query := `SELECT ` + fileUploadColumns + `, COALESCE(e.first_name, ''), COALESCE(e.last_name, '')
+			  FROM file_uploads f
+			  LEFT JOIN employees e ON e.id = f.uploaded_by
+			  WHERE f.document_id = $1 AND f.deleted_at IS NULL
+			  ORDER BY f.version DESC`
+
+	rows, err := r.db.Query(query, documentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var uploads []*models.FileUpload
+	for rows.Next() {
+		uploader := &models.Employee{}
+		upload, err := scanFileUpload(rows, &uploader.FirstName, &uploader.LastName)
+		if err != nil {
+			return nil, err
+		}
+		uploader.ID = upload.UploadedBy
+		upload.Uploader = uploader
+		uploads = append(uploads, upload)
+	}
+
+	return uploads, rows.Err()
+}
+
+// UpdateFileUploadScanResult records the verdict of a malware scan
+func (r *FileRepository) UpdateFileUploadScanResult(tx *sql.Tx, id uuid.UUID, status models.FileScanStatus, signature string) error {
+	query := `UPDATE file_uploads SET scan_status = $1, scan_signature = NULLIF($2, ''), scanned_at = CURRENT_TIMESTAMP,
+			  updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $3 AND deleted_at IS NULL`
+
+	var err error
+	if tx != nil {
+		_, err = tx.Exec(query, status, signature, id)
+	} else {
+		_, err = r.db.Exec(query, status, signature, id)
+	}
+	return err
+}
+
+// GetFileUploadsByScanStatus returns the oldest uploads in the scan status first
+func (r *FileRepository) GetFileUploadsByScanStatus(status models.FileScanStatus, limit int) ([]*models.FileUpload, error) {
+	query := `SELECT ` + fileUploadColumns + `
+			  FROM file_uploads f
+			  WHERE f.scan_status = $1 AND f.deleted_at IS NULL
+			  ORDER BY f.created_at
+			  LIMIT $2`
+
+	rows, err := r.db.Query(query, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var uploads []*models.FileUpload
+	for rows.Next() {
+		upload, err := scanFileUpload(rows)
+		if err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, upload)
+	}
+
+	return uploads, rows.Err()
+}
+
+func (r *FileRepository) CreateFileBundle(tx *sql.Tx, bundle *models.FileBundle) (*models.FileBundle, error) {
+	if bundle.ID == uuid.Nil {
+		bundle.ID = uuid.New()
+	}
+
+	query := `INSERT INTO file_bundles (id, entity_type, entity_id, created_by, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			  RETURNING created_at, updated_at`
+
+	var err error
+	if tx != nil {
+		err = tx.QueryRow(query, bundle.ID, bundle.EntityType, bundle.EntityID, bundle.CreatedBy).Scan(&bundle.CreatedAt, &bundle.UpdatedAt)
+	} else {
+		err = r.db.QueryRow(query, bundle.ID, bundle.EntityType, bundle.EntityID, bundle.CreatedBy).Scan(&bundle.CreatedAt, &bundle.UpdatedAt)
+	}
+
+	return bundle, err
+}
+
+// LockFileBundle locks the bundle row until the transaction ends and loads its files; it returns nil without an
+// error when there is no such bundle
+func (r *FileRepository) LockFileBundle(tx *sql.Tx, id uuid.UUID) (*models.FileBundle, error) {
+	query := `SELECT id, entity_type, entity_id, created_by, created_at, updated_at
+			  FROM file_bundles
+			  WHERE id = $1 AND deleted_at IS NULL
+			  FOR UPDATE`
+
+	var row *sql.Row
+	if tx != nil {
+		row = tx.QueryRow(query, id)
+	} else {
+		row = r.db.QueryRow(query, id)
+	}
+
+	bundle := &models.FileBundle{}
+	err := row.Scan(&bundle.ID, &bundle.EntityType, &bundle.EntityID, &bundle.CreatedBy, &bundle.CreatedAt, &bundle.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	filesQuery := `SELECT ` + fileUploadColumns + `
+				   FROM file_uploads f
+				   WHERE f.bundle_id = $1 AND COALESCE(f.is_active, true) AND f.deleted_at IS NULL
+				   ORDER BY f.created_at, f.id`
+
+	var rows *sql.Rows
+	if tx != nil {
+		rows, err = tx.Query(filesQuery, id)
+	} else {
+		rows, err = r.db.Query(filesQuery, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		upload, err := scanFileUpload(rows)
+		if err != nil {
+			return nil, err
+		}
+		bundle.Files = append(bundle.Files, upload)
+	}
+
+	return bundle, rows.Err()
+}
+
+// UpdateFileBundleEntityID links the bundle and every file in it to the entity
+func (r *FileRepository) UpdateFileBundleEntityID(tx *sql.Tx, id, entityID uuid.UUID) error {
+	bundleQuery := `UPDATE file_bundles SET entity_id = $1, updated_at = CURRENT_TIMESTAMP
+					WHERE id = $2 AND deleted_at IS NULL`
+	filesQuery := `UPDATE file_uploads SET entity_id = $1, updated_at = CURRENT_TIMESTAMP
+				   WHERE bundle_id = $2 AND deleted_at IS NULL`
+
+	for _, query := range []string{bundleQuery, filesQuery} {
+		var err error
+		if tx != nil {
+			_, err = tx.Exec(query, entityID, id)
+		} else {
+			_, err = r.db.Exec(query, entityID, id)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *FileRepository) CreateFileVariant(tx *sql.Tx, variant *models.FileVariant) (*models.FileVariant, error) {
+	if variant.ID == uuid.Nil {
+		variant.ID = uuid.New()
+	}
+
+	query := `INSERT INTO file_variants (id, file_upload_id, variant, file_path, content_type, file_size, width, height,
+			  checksum, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			  RETURNING created_at, updated_at`
+
+	args := []interface{}{
+		variant.ID,
+		variant.FileUploadID,
+		variant.Variant,
+		variant.FilePath,
+		variant.ContentType,
+		variant.FileSize,
+		variant.Width,
+		variant.Height,
+		variant.Checksum,
+	}
+
+	var err error
+	if tx != nil {
+		err = tx.QueryRow(query, args...).Scan(&variant.CreatedAt, &variant.UpdatedAt)
+	} else {
+		err = r.db.QueryRow(query, args...).Scan(&variant.CreatedAt, &variant.UpdatedAt)
+	}
+
+	return variant, err
+}
+
+// GetFileVariant returns nil without an error when the upload has no such variant
+func (r *FileRepository) GetFileVariant(fileUploadID uuid.UUID, variant models.FileVariantName) (*models.FileVariant, error) {
+	query := `SELECT id, file_upload_id, variant, file_path, content_type, file_size, width, height,
+			  COALESCE(checksum, ''), created_at, updated_at
+			  FROM file_variants
+			  WHERE file_upload_id = $1 AND variant = $2 AND deleted_at IS NULL`
+
+	result := &models.FileVariant{}
+	err := r.db.QueryRow(query, fileUploadID, variant).Scan(
+		&result.ID,
+		&result.FileUploadID,
+		&result.Variant,
+		&result.FilePath,
+		&result.ContentType,
+		&result.FileSize,
+		&result.Width,
+		&result.Height,
+		&result.Checksum,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// scanFileUpload scans the fileUploadColumns, followed by any extra columns the query selects
+func scanFileUpload(row rowScanner, extra ...interface{}) (*models.FileUpload, error) {
+	upload := &models.FileUpload{}
+	dest := []interface{}{
+		&upload.ID,
+		&upload.FileName,
+		&upload.FileType,
+		&upload.FileSize,
+		&upload.FilePath,
+		&upload.FileURL,
+		&upload.ContentType,
+		&upload.Checksum,
+		&upload.StorageBackend,
+		&upload.UploadedBy,
+		&upload.EntityType,
+		&upload.EntityID,
+		&upload.BundleID,
+		&upload.IsActive,
+		&upload.DocumentID,
+		&upload.Version,
+		&upload.ReplacesID,
+		&upload.ScanStatus,
+		&upload.ScanSignature,
+		&upload.ScannedAt,
+		&upload.CapturedAt,
+		&upload.Latitude,
+		&upload.Longitude,
+		&upload.CreatedAt,
+		&upload.UpdatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return upload, nil
+}
@@ -15,6 +15,9 @@ type LoanRepositoryInterface interface {
 	// Loan creating and basic operations
 	CreateLoan(tx *sql.Tx, loan *models.Loan) (*models.Loan, error)
 	GetLoanByID(tx *sql.Tx, loanID uuid.UUID) (*models.Loan, error)
+	LockLoan(tx *sql.Tx, loanID uuid.UUID) error
+	GetLoansPastFundingDeadline(approvedBefore time.Time) ([]uuid.UUID, error)
+	GetMarketplaceLoans() ([]*models.Loan, error)
 	UpdateLoanState(tx *sql.Tx, loanID uuid.UUID, newState models.LoanState) (*models.Loan, error)
 	RecordLoanStateHistory(tx *sql.Tx, prevState models.LoanState, loan *models.Loan, employeeID uuid.UUID, changeReason string) (*models.LoanStateHistory, error)
 
@@ -27,14 +30,158 @@ type LoanRepositoryInterface interface {
 	GetInvestmentsNeedingAgreementEmail() ([]*models.Investment, error)
 	UpdateInvestmentAgreementSent(investmentID uuid.UUID, agreementSent bool, agreementSentAt *time.Time) error
 	UpdateLoanAgreementLetterURL(tx *sql.Tx, loanID uuid.UUID, agreementURL string) error
+	GetInvestmentsByLoanID(tx *sql.Tx, loanID uuid.UUID) ([]*models.Investment, error)
+	UpdateInvestmentAgreementURL(tx *sql.Tx, investmentID uuid.UUID, agreementURL string) error
+
+	// investor limits, checked inside the investment transaction
+	GetInvestorExposure(tx *sql.Tx, investorID, loanID uuid.UUID) (*models.InvestorExposure, error)
+	GetInvestorInvestmentLimit(tx *sql.Tx, investorID uuid.UUID) (*models.InvestorInvestmentLimit, error)
+	UpsertInvestorInvestmentLimit(tx *sql.Tx, limit *models.InvestorInvestmentLimit) (*models.InvestorInvestmentLimit, error)
+
+	// investor portfolio
+	GetInvestorPortfolio(investorID uuid.UUID) ([]*models.PortfolioPosition, error)
+
+	// investment cancellation (while Approved)
+	GetInvestmentByID(tx *sql.Tx, investmentID uuid.UUID) (*models.Investment, error)
+	SoftDeleteInvestment(tx *sql.Tx, investmentID uuid.UUID) error
+	DecrementLoanTotalInvested(tx *sql.Tx, loanID uuid.UUID, amount float64) (*models.Loan, error)
+	CreateInvestmentCancellation(tx *sql.Tx, cancellation *models.InvestmentCancellation) (*models.InvestmentCancellation, error)
+
+	// investment transfer (secondary market, while Disbursed)
+	LockInvestment(tx *sql.Tx, investmentID uuid.UUID) (*models.Investment, error)
+	UpdateInvestmentAmount(tx *sql.Tx, investmentID uuid.UUID, amount, expectedReturn float64) error
 
 	// loan disbursement (Invested → Disbursed)
 	CreateDisbursement(tx *sql.Tx, disbursement *models.Disbursement) (*models.Disbursement, error)
+	UpdateDisbursementTransactionID(tx *sql.Tx, disbursementID uuid.UUID, transactionID string) error
 
 	// User Management
 	GetInvestorByID(investorID uuid.UUID) (*models.Investor, error)
 	GetBorrowerByID(borrowerID uuid.UUID) (*models.Borrower, error)
+	GetEmployeeByID(employeeID uuid.UUID) (*models.Employee, error)
+}
+
+// ReconciliationRepositoryInterface persists settlement reconciliation runs and their findings
+type ReconciliationRepositoryInterface interface {
+	CreateReconciliationRun(tx *sql.Tx, run *models.ReconciliationRun) (*models.ReconciliationRun, error)
+	UpdateReconciliationRun(tx *sql.Tx, run *models.ReconciliationRun) error
+	CreateReconciliationMismatch(tx *sql.Tx, mismatch *models.ReconciliationMismatch) (*models.ReconciliationMismatch, error)
+
+	// GetExpectedPayments returns recorded money movements with a provider transaction ID in [from, to)
+	GetExpectedPayments(from, to time.Time) ([]*models.ExpectedPayment, error)
+}
+
+// WalletRepositoryInterface persists investor wallets and their append-only ledger
+type WalletRepositoryInterface interface {
+	LockWallet(tx *sql.Tx, investorID uuid.UUID) (*models.Wallet, error)
+	GetWalletBalance(tx *sql.Tx, investorID uuid.UUID) (*models.WalletBalance, error)
+	CreateWalletTransaction(tx *sql.Tx, transaction *models.WalletTransaction) (*models.WalletTransaction, error)
+	GetWalletTransactions(investorID uuid.UUID) ([]*models.WalletTransaction, error)
+	ReleaseHold(tx *sql.Tx, holdID uuid.UUID, description string) error
+	ReleaseInvestmentHold(tx *sql.Tx, investmentID uuid.UUID, description string) (int64, error)
+
+	// Settle open investment holds of a loan
+	CaptureLoanHolds(tx *sql.Tx, loanID uuid.UUID, description string) (int64, error)
+	ReleaseLoanHolds(tx *sql.Tx, loanID uuid.UUID, description string) (int64, error)
+
+	// Top ups are recorded before the charge and credited to the ledger after it
+	CreateWalletTopUp(tx *sql.Tx, topUp *models.WalletTopUp) (*models.WalletTopUp, error)
+	LockWalletTopUp(tx *sql.Tx, topUpID uuid.UUID) (*models.WalletTopUp, error)
+	UpdateWalletTopUp(tx *sql.Tx, topUp *models.WalletTopUp) error
+	GetWalletTopUpsByStatus(status models.WalletTopUpStatus, updatedBefore time.Time, limit int) ([]*models.WalletTopUp, error)
+}
+
+// WithdrawalRepositoryInterface persists investor bank accounts and withdrawals
+type WithdrawalRepositoryInterface interface {
+	CreateBankAccount(tx *sql.Tx, account *models.BankAccount) (*models.BankAccount, error)
+	GetBankAccountByID(bankAccountID uuid.UUID) (*models.BankAccount, error)
+	GetBankAccountsByInvestorID(investorID uuid.UUID) ([]*models.BankAccount, error)
+
+	CreateWithdrawal(tx *sql.Tx, withdrawal *models.Withdrawal) (*models.Withdrawal, error)
+	LockWithdrawal(tx *sql.Tx, withdrawalID uuid.UUID) (*models.Withdrawal, error)
+	UpdateWithdrawal(tx *sql.Tx, withdrawal *models.Withdrawal) error
+	GetWithdrawalsByInvestorID(investorID uuid.UUID) ([]*models.Withdrawal, error)
+	GetWithdrawalsByStatus(status models.WithdrawalStatus, updatedBefore time.Time, limit int) ([]*models.Withdrawal, error)
+}
+
+// AutoInvestRepositoryInterface persists investor auto-invest rules
+type AutoInvestRepositoryInterface interface {
+	CreateAutoInvestRule(rule *models.AutoInvestRule) (*models.AutoInvestRule, error)
+	GetAutoInvestRulesByInvestorID(investorID uuid.UUID) ([]*models.AutoInvestRule, error)
+	GetActiveAutoInvestRules() ([]*models.AutoInvestRule, error)
+	DeactivateAutoInvestRule(investorID, ruleID uuid.UUID) error
+	MarkAutoInvestRuleAllocated(ruleID uuid.UUID, allocatedAt time.Time) error
+}
+
+// TransferRepositoryInterface persists secondary market listings and the transfer lineage of investments
+type TransferRepositoryInterface interface {
+	CreateInvestmentTransfer(tx *sql.Tx, transfer *models.InvestmentTransfer) (*models.InvestmentTransfer, error)
+	LockInvestmentTransfer(tx *sql.Tx, transferID uuid.UUID) (*models.InvestmentTransfer, error)
+	GetInvestmentTransferByID(transferID uuid.UUID) (*models.InvestmentTransfer, error)
+	UpdateInvestmentTransfer(tx *sql.Tx, transfer *models.InvestmentTransfer) error
+	CancelUncoveredInvestmentTransfers(tx *sql.Tx, investmentID uuid.UUID, remaining float64) (int64, error)
+	GetListedInvestmentTransfers() ([]*models.InvestmentTransfer, error)
+	GetInvestmentLineage(investmentID uuid.UUID) ([]*models.InvestmentTransfer, error)
+}
+
+// SignatureRepositoryInterface persists the e-signature envelopes of loan agreements
+type SignatureRepositoryInterface interface {
+	CreateAgreementSignature(tx *sql.Tx, signature *models.AgreementSignature) (*models.AgreementSignature, error)
+	LockAgreementSignature(tx *sql.Tx, provider, envelopeID string) (*models.AgreementSignature, error)
+	GetAgreementSignature(provider, envelopeID string) (*models.AgreementSignature, error)
+	UpdateAgreementSignature(tx *sql.Tx, signature *models.AgreementSignature) error
+	GetOpenAgreementSignature(tx *sql.Tx, loanID uuid.UUID) (*models.AgreementSignature, error)
+}
+
+// FileRepositoryInterface persists the file_uploads records of stored files
+type FileRepositoryInterface interface {
+	CreateFileUpload(tx *sql.Tx, upload *models.FileUpload) (*models.FileUpload, error)
+	GetFileUploadByID(tx *sql.Tx, id uuid.UUID) (*models.FileUpload, error)
+	LockFileUpload(tx *sql.Tx, id uuid.UUID) (*models.FileUpload, error)
+	UpdateFileUploadEntityID(tx *sql.Tx, id, entityID uuid.UUID) error
+	DeactivateFileUpload(tx *sql.Tx, id uuid.UUID) error
+	RepointFileURL(tx *sql.Tx, oldURL, newURL string) error
+	GetFileVersions(documentID uuid.UUID) ([]*models.FileUpload, error)
+	UpdateFileUploadScanResult(tx *sql.Tx, id uuid.UUID, status models.FileScanStatus, signature string) error
+	GetFileUploadsByScanStatus(status models.FileScanStatus, limit int) ([]*models.FileUpload, error)
+	CreateFileBundle(tx *sql.Tx, bundle *models.FileBundle) (*models.FileBundle, error)
+	LockFileBundle(tx *sql.Tx, id uuid.UUID) (*models.FileBundle, error)
+	UpdateFileBundleEntityID(tx *sql.Tx, id, entityID uuid.UUID) error
+	CreateFileVariant(tx *sql.Tx, variant *models.FileVariant) (*models.FileVariant, error)
+	GetFileVariant(fileUploadID uuid.UUID, variant models.FileVariantName) (*models.FileVariant, error)
+}
+
+// WaitlistRepositoryInterface persists the per-loan oversubscription waitlists
+type WaitlistRepositoryInterface interface {
+	CreateWaitlistEntry(entry *models.WaitlistEntry) (*models.WaitlistEntry, error)
+	GetWaitingEntries(loanID uuid.UUID) ([]*models.WaitlistEntry, error)
+	ClaimNextWaitingEntry(loanID uuid.UUID, now, leaseUntil time.Time) (*models.WaitlistEntry, error)
+	ReleaseWaitlistEntry(entryID uuid.UUID) error
+	GetLoansWithWaitingEntries() ([]uuid.UUID, error)
+	UpdateWaitlistEntry(entry *models.WaitlistEntry) error
+	CloseWaitingEntries(loanID uuid.UUID) (int64, error)
+}
+
+// ReservationRepositoryInterface keeps investment reservations; reservations expire on their own
+type ReservationRepositoryInterface interface {
+	CreateReservation(reservation *models.InvestmentReservation) error
+	GetReservation(loanID, reservationID uuid.UUID) (*models.InvestmentReservation, error)
+	ClaimReservation(loanID, reservationID uuid.UUID) (*models.InvestmentReservation, error)
+	GetReservedAmount(loanID uuid.UUID) (float64, error)
+	DeleteReservation(loanID, reservationID uuid.UUID) error
+}
+
+// EmailTemplateRepositoryInterface reads the email templates kept in the database
+type EmailTemplateRepositoryInterface interface {
+	GetEmailTemplate(name, locale, version string) (*models.EmailTemplate, error)
+}
 
-	// Communication & Notifications
+// NotificationRepositoryInterface persists email notifications and their delivery attempts
+type NotificationRepositoryInterface interface {
 	CreateEmailNotification(notification *models.EmailNotification) error
+	GetEmailNotificationByID(notificationID uuid.UUID) (*models.EmailNotification, error)
+	GetFailedEmailNotifications(limit int) ([]*models.EmailNotification, error)
+	ClaimDueEmailNotifications(now, leaseUntil time.Time, limit int) ([]*models.EmailNotification, error)
+	ClaimFailedEmailNotification(notificationID uuid.UUID, leaseUntil time.Time) (*models.EmailNotification, error)
+	RecordEmailDeliveryAttempt(notification *models.EmailNotification, attempt *models.EmailDeliveryAttempt) error
 }
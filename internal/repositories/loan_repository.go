@@ -26,8 +26,8 @@ func (r *LoanRepository) CreateLoan(tx *sql.Tx, loan *models.Loan) (*models.Loan
 	// Generate UUID for new loan
 	loan.ID = uuid.New()
 
-	query := `INSERT INTO loans (id, borrower_id, principal_amount, interest_rate, roi, state, agreement_letter_url, total_invested, created_at, updated_at)
-			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+	query := `INSERT INTO loans (id, borrower_id, principal_amount, interest_rate, roi, state, agreement_letter_url, total_invested, product, tenor_months, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 			  RETURNING created_at, updated_at`
 
 	var err error
@@ -41,6 +41,8 @@ func (r *LoanRepository) CreateLoan(tx *sql.Tx, loan *models.Loan) (*models.Loan
 			loan.State,
 			loan.AgreementLetterURL,
 			loan.TotalInvested,
+			loan.Product,
+			loan.TenorMonths,
 		).Scan(&loan.CreatedAt, &loan.UpdatedAt)
 	} else {
 		err = r.db.QueryRow(query,
@@ -52,6 +54,8 @@ func (r *LoanRepository) CreateLoan(tx *sql.Tx, loan *models.Loan) (*models.Loan
 			loan.State,
 			loan.AgreementLetterURL,
 			loan.TotalInvested,
+			loan.Product,
+			loan.TenorMonths,
 		).Scan(&loan.CreatedAt, &loan.UpdatedAt)
 	}
 
@@ -62,9 +66,9 @@ func (r *LoanRepository) GetLoanByID(tx *sql.Tx, loanID uuid.UUID) (*models.Loan
 	query := `
 		SELECT 
 			l.id, l.borrower_id, l.principal_amount, l.interest_rate, l.roi, l.state, 
-			l.agreement_letter_url, l.total_invested, l.created_at, l.updated_at,
+			l.agreement_letter_url, l.total_invested, l.product, l.tenor_months, l.created_at, l.updated_at,
 			b.id, b.id_number, b.first_name, b.last_name, b.email, b.phone_number, b.address,
-			b.created_at, b.updated_at
+			COALESCE(b.risk_grade, ''), b.latitude, b.longitude, b.created_at, b.updated_at
 		FROM loans l
 		INNER JOIN borrowers b ON l.borrower_id = b.id
 		WHERE l.id = $1 AND l.deleted_at IS NULL
@@ -77,16 +81,16 @@ func (r *LoanRepository) GetLoanByID(tx *sql.Tx, loanID uuid.UUID) (*models.Loan
 	if tx != nil {
 		err = tx.QueryRow(query, loanID).Scan(
 			&loan.ID, &loan.BorrowerID, &loan.PrincipalAmount, &loan.InterestRate, &loan.ROI, &loan.State,
-			&loan.AgreementLetterURL, &loan.TotalInvested, &loan.CreatedAt, &loan.UpdatedAt,
+			&loan.AgreementLetterURL, &loan.TotalInvested, &loan.Product, &loan.TenorMonths, &loan.CreatedAt, &loan.UpdatedAt,
 			&borrower.ID, &borrower.IDNumber, &borrower.FirstName, &borrower.LastName, &borrower.Email, &borrower.PhoneNumber, &borrower.Address,
-			&borrower.CreatedAt, &borrower.UpdatedAt,
+			&borrower.RiskGrade, &borrower.Latitude, &borrower.Longitude, &borrower.CreatedAt, &borrower.UpdatedAt,
 		)
 	} else {
 		err = r.db.QueryRow(query, loanID).Scan(
 			&loan.ID, &loan.BorrowerID, &loan.PrincipalAmount, &loan.InterestRate, &loan.ROI, &loan.State,
-			&loan.AgreementLetterURL, &loan.TotalInvested, &loan.CreatedAt, &loan.UpdatedAt,
+			&loan.AgreementLetterURL, &loan.TotalInvested, &loan.Product, &loan.TenorMonths, &loan.CreatedAt, &loan.UpdatedAt,
 			&borrower.ID, &borrower.IDNumber, &borrower.FirstName, &borrower.LastName, &borrower.Email, &borrower.PhoneNumber, &borrower.Address,
-			&borrower.CreatedAt, &borrower.UpdatedAt,
+			&borrower.RiskGrade, &borrower.Latitude, &borrower.Longitude, &borrower.CreatedAt, &borrower.UpdatedAt,
 		)
 	}
 
@@ -98,9 +102,96 @@ func (r *LoanRepository) GetLoanByID(tx *sql.Tx, loanID uuid.UUID) (*models.Loan
 	return &loan, nil
 }
 
+// GetMarketplaceLoans gets approved loans that are still raising funds, newest approval first.
+// Only the borrower's risk grade is loaded.
+func (r *LoanRepository) GetMarketplaceLoans() ([]*models.Loan, error) {
+	query := `
+		SELECT l.id, l.principal_amount, l.roi, l.state, l.total_invested, l.product, l.tenor_months,
+			COALESCE(b.risk_grade, ''), a.approval_date
+		FROM loans l
+		INNER JOIN borrowers b ON l.borrower_id = b.id
+		INNER JOIN approvals a ON a.loan_id = l.id AND a.deleted_at IS NULL
+		WHERE l.state = 'approved'
+		AND l.deleted_at IS NULL
+		AND l.total_invested < l.principal_amount
+		ORDER BY a.approval_date DESC
+	`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var loans []*models.Loan
+	for rows.Next() {
+		loan := models.Loan{Borrower: &models.Borrower{}, Approval: &models.Approval{}}
+		err := rows.Scan(
+			&loan.ID,
+			&loan.PrincipalAmount,
+			&loan.ROI,
+			&loan.State,
+			&loan.TotalInvested,
+			&loan.Product,
+			&loan.TenorMonths,
+			&loan.Borrower.RiskGrade,
+			&loan.Approval.ApprovalDate,
+		)
+		if err != nil {
+			return nil, err
+		}
+		loans = append(loans, &loan)
+	}
+
+	return loans, rows.Err()
+}
+
+// LockLoan locks the loan row for the rest of the transaction so state checks and updates cannot interleave
+func (r *LoanRepository) LockLoan(tx *sql.Tx, loanID uuid.UUID) error {
+	query := `SELECT id FROM loans WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`
+
+	var id uuid.UUID
+	if tx != nil {
+		return tx.QueryRow(query, loanID).Scan(&id)
+	}
+	return r.db.QueryRow(query, loanID).Scan(&id)
+}
+
+// GetLoansPastFundingDeadline gets approved loans that were approved before the given time and are still not fully invested
+func (r *LoanRepository) GetLoansPastFundingDeadline(approvedBefore time.Time) ([]uuid.UUID, error) {
+	query := `
+		SELECT l.id
+		FROM loans l
+		INNER JOIN approvals a ON a.loan_id = l.id AND a.deleted_at IS NULL
+		WHERE l.state = 'approved'
+		AND l.deleted_at IS NULL
+		AND l.total_invested < l.principal_amount
+		AND a.approval_date < $1
+		ORDER BY a.approval_date ASC
+	`
+
+	rows, err := r.db.Query(query, approvedBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var loanIDs []uuid.UUID
+	for rows.Next() {
+		var loanID uuid.UUID
+		if err := rows.Scan(&loanID); err != nil {
+			return nil, err
+		}
+		loanIDs = append(loanIDs, loanID)
+	}
+
+	return loanIDs, rows.Err()
+}
+
 func (r *LoanRepository) CreateApproval(tx *sql.Tx, approval *models.Approval) (*models.Approval, error) {
-	query := `INSERT INTO approvals (id, loan_id, validator_id, approval_date, visit_proof_image_url, visit_proof_image_type, notes, created_at, updated_at)
-			  VALUES ($1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+	query := `INSERT INTO approvals (id, loan_id, validator_id, approval_date, visit_proof_image_url, visit_proof_image_type, visit_proof_bundle_id, notes,
+			  visit_proof_captured_at, visit_proof_latitude, visit_proof_longitude, visit_distance_meters, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 			  RETURNING created_at, updated_at`
 
 	var err error
@@ -112,7 +203,12 @@ func (r *LoanRepository) CreateApproval(tx *sql.Tx, approval *models.Approval) (
 			approval.ApprovalDate,
 			approval.VisitProofImageURL,
 			approval.VisitProofImageType,
+			approval.VisitProofBundleID,
 			approval.Notes,
+			approval.VisitProofCapturedAt,
+			approval.VisitProofLatitude,
+			approval.VisitProofLongitude,
+			approval.VisitDistanceMeters,
 		).Scan(&approval.CreatedAt, &approval.UpdatedAt)
 	} else {
 		err = r.db.QueryRow(query,
@@ -122,7 +218,12 @@ func (r *LoanRepository) CreateApproval(tx *sql.Tx, approval *models.Approval) (
 			approval.ApprovalDate,
 			approval.VisitProofImageURL,
 			approval.VisitProofImageType,
+			approval.VisitProofBundleID,
 			approval.Notes,
+			approval.VisitProofCapturedAt,
+			approval.VisitProofLatitude,
+			approval.VisitProofLongitude,
+			approval.VisitDistanceMeters,
 		).Scan(&approval.CreatedAt, &approval.UpdatedAt)
 	}
 
@@ -194,8 +295,8 @@ func (r *LoanRepository) RecordLoanStateHistory(tx *sql.Tx, prevState models.Loa
 }
 
 func (r *LoanRepository) CreateInvestment(tx *sql.Tx, investment *models.Investment) (*models.Investment, error) {
-	query := `INSERT INTO investments (id, loan_id, investor_id, amount, expected_return, investment_date, created_at, updated_at)
-			  VALUES ($1, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+	query := `INSERT INTO investments (id, loan_id, investor_id, amount, expected_return, investment_date, parent_investment_id, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 			  RETURNING created_at, updated_at`
 
 	var err error
@@ -207,6 +308,7 @@ func (r *LoanRepository) CreateInvestment(tx *sql.Tx, investment *models.Investm
 			investment.Amount,
 			investment.ExpectedReturn,
 			investment.InvestmentDate,
+			investment.ParentInvestmentID,
 		).Scan(&investment.CreatedAt, &investment.UpdatedAt)
 	} else {
 		err = r.db.QueryRow(query,
@@ -216,6 +318,7 @@ func (r *LoanRepository) CreateInvestment(tx *sql.Tx, investment *models.Investm
 			investment.Amount,
 			investment.ExpectedReturn,
 			investment.InvestmentDate,
+			investment.ParentInvestmentID,
 		).Scan(&investment.CreatedAt, &investment.UpdatedAt)
 	}
 
@@ -259,6 +362,351 @@ func (r *LoanRepository) UpdateLoanTotalInvested(tx *sql.Tx, loanID uuid.UUID, n
 	return &loan, nil
 }
 
+// DecrementLoanTotalInvested atomically takes a cancelled investment amount off the loan total
+func (r *LoanRepository) DecrementLoanTotalInvested(tx *sql.Tx, loanID uuid.UUID, amount float64) (*models.Loan, error) {
+	// Mirror of UpdateLoanTotalInvested: validation and update in one database operation
+	query := `UPDATE loans 
+		SET total_invested = total_invested - $1, 
+		    updated_at = CURRENT_TIMESTAMP 
+		WHERE id = $2 
+		  AND deleted_at IS NULL
+		  AND state = 'approved'
+		  AND (total_invested - $1) >= 0
+		RETURNING id, borrower_id, principal_amount, interest_rate, roi, state, 
+		          agreement_letter_url, total_invested, created_at, updated_at`
+
+	var loan models.Loan
+	var err error
+	if tx != nil {
+		err = tx.QueryRow(query, amount, loanID).Scan(
+			&loan.ID, &loan.BorrowerID, &loan.PrincipalAmount, &loan.InterestRate, &loan.ROI, &loan.State,
+			&loan.AgreementLetterURL, &loan.TotalInvested, &loan.CreatedAt, &loan.UpdatedAt,
+		)
+	} else {
+		err = r.db.QueryRow(query, amount, loanID).Scan(
+			&loan.ID, &loan.BorrowerID, &loan.PrincipalAmount, &loan.InterestRate, &loan.ROI, &loan.State,
+			&loan.AgreementLetterURL, &loan.TotalInvested, &loan.CreatedAt, &loan.UpdatedAt,
+		)
+	}
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("loan not found, not in approved state, or total invested would become negative")
+		}
+		return nil, err
+	}
+
+	return &loan, nil
+}
+
+const investmentColumns = `id, loan_id, investor_id, amount, investment_date, expected_return,
+			  agreement_sent, agreement_sent_at, agreement_url, parent_investment_id, created_at, updated_at`
+
+// GetInvestmentByID gets an active (not cancelled) investment by ID
+func (r *LoanRepository) GetInvestmentByID(tx *sql.Tx, investmentID uuid.UUID) (*models.Investment, error) {
+	query := `SELECT ` + investmentColumns + `
+			  FROM investments WHERE id = $1 AND deleted_at IS NULL`
+
+	return r.getInvestment(tx, query, investmentID)
+}
+
+// LockInvestment gets an investment and locks it for the rest of the transaction so its principal changes hands only once
+func (r *LoanRepository) LockInvestment(tx *sql.Tx, investmentID uuid.UUID) (*models.Investment, error) {
+	query := `SELECT ` + investmentColumns + `
+			  FROM investments WHERE id = $1 AND deleted_at IS NULL
+			  FOR UPDATE`
+
+	return r.getInvestment(tx, query, investmentID)
+}
+
+func (r *LoanRepository) getInvestment(tx *sql.Tx, query string, investmentID uuid.UUID) (*models.Investment, error) {
+	var row *sql.Row
+	if tx != nil {
+		row = tx.QueryRow(query, investmentID)
+	} else {
+		row = r.db.QueryRow(query, investmentID)
+	}
+
+	var investment models.Investment
+	err := row.Scan(
+		&investment.ID, &investment.LoanID, &investment.InvestorID, &investment.Amount, &investment.InvestmentDate,
+		&investment.ExpectedReturn, &investment.AgreementSent, &investment.AgreementSentAt, &investment.AgreementURL, &investment.ParentInvestmentID,
+		&investment.CreatedAt, &investment.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &investment, nil
+}
+
+// GetInvestmentsByLoanID gets the live investments in a loan, oldest first
+func (r *LoanRepository) GetInvestmentsByLoanID(tx *sql.Tx, loanID uuid.UUID) ([]*models.Investment, error) {
+	query := `SELECT ` + investmentColumns + `
+			  FROM investments WHERE loan_id = $1 AND deleted_at IS NULL
+			  ORDER BY investment_date ASC, created_at ASC`
+
+	var rows *sql.Rows
+	var err error
+	if tx != nil {
+		rows, err = tx.Query(query, loanID)
+	} else {
+		rows, err = r.db.Query(query, loanID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var investments []*models.Investment
+	for rows.Next() {
+		var investment models.Investment
+		err := rows.Scan(
+			&investment.ID, &investment.LoanID, &investment.InvestorID, &investment.Amount, &investment.InvestmentDate,
+			&investment.ExpectedReturn, &investment.AgreementSent, &investment.AgreementSentAt, &investment.AgreementURL, &investment.ParentInvestmentID,
+			&investment.CreatedAt, &investment.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		investments = append(investments, &investment)
+	}
+
+	return investments, rows.Err()
+}
+
+// UpdateInvestmentAmount sets the principal and expected return left after part of an investment was transferred
+func (r *LoanRepository) UpdateInvestmentAmount(tx *sql.Tx, investmentID uuid.UUID, amount, expectedReturn float64) error {
+	query := `UPDATE investments SET amount = $2, expected_return = $3, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $1 AND deleted_at IS NULL`
+
+	var result sql.Result
+	var err error
+	if tx != nil {
+		result, err = tx.Exec(query, investmentID, amount, expectedReturn)
+	} else {
+		result, err = r.db.Exec(query, investmentID, amount, expectedReturn)
+	}
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("investment %s not found", investmentID)
+	}
+
+	return nil
+}
+
+// SoftDeleteInvestment marks an investment as deleted; it fails if the investment is already gone
+func (r *LoanRepository) SoftDeleteInvestment(tx *sql.Tx, investmentID uuid.UUID) error {
+	query := `UPDATE investments SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $1 AND deleted_at IS NULL`
+
+	var result sql.Result
+	var err error
+	if tx != nil {
+		result, err = tx.Exec(query, investmentID)
+	} else {
+		result, err = r.db.Exec(query, investmentID)
+	}
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("investment %s not found or already cancelled", investmentID)
+	}
+
+	return nil
+}
+
+func (r *LoanRepository) CreateInvestmentCancellation(tx *sql.Tx, cancellation *models.InvestmentCancellation) (*models.InvestmentCancellation, error) {
+	if cancellation.ID == uuid.Nil {
+		cancellation.ID = uuid.New()
+	}
+
+	query := `INSERT INTO investment_cancellations (id, investment_id, loan_id, investor_id, amount, reason, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			  RETURNING created_at, updated_at`
+
+	var err error
+	if tx != nil {
+		err = tx.QueryRow(query,
+			cancellation.ID,
+			cancellation.InvestmentID,
+			cancellation.LoanID,
+			cancellation.InvestorID,
+			cancellation.Amount,
+			cancellation.Reason,
+		).Scan(&cancellation.CreatedAt, &cancellation.UpdatedAt)
+	} else {
+		err = r.db.QueryRow(query,
+			cancellation.ID,
+			cancellation.InvestmentID,
+			cancellation.LoanID,
+			cancellation.InvestorID,
+			cancellation.Amount,
+			cancellation.Reason,
+		).Scan(&cancellation.CreatedAt, &cancellation.UpdatedAt)
+	}
+
+	return cancellation, err
+}
+
+// GetInvestorExposure sums the investor's active investments in the loan and in every loan that is not closed.
+// Disbursed loans count as outstanding until repayments are tracked.
+func (r *LoanRepository) GetInvestorExposure(tx *sql.Tx, investorID, loanID uuid.UUID) (*models.InvestorExposure, error) {
+	query := `
+		SELECT
+			COALESCE(SUM(CASE WHEN i.loan_id = $2 THEN i.amount ELSE 0 END), 0),
+			COALESCE(SUM(i.amount), 0)
+		FROM investments i
+		INNER JOIN loans l ON i.loan_id = l.id AND l.deleted_at IS NULL
+		WHERE i.investor_id = $1
+		AND i.deleted_at IS NULL
+		AND l.state IN ('approved', 'invested', 'disbursed')
+	`
+
+	var exposure models.InvestorExposure
+	var err error
+	if tx != nil {
+		err = tx.QueryRow(query, investorID, loanID).Scan(&exposure.LoanInvested, &exposure.TotalOutstanding)
+	} else {
+		err = r.db.QueryRow(query, investorID, loanID).Scan(&exposure.LoanInvested, &exposure.TotalOutstanding)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &exposure, nil
+}
+
+// GetInvestorPortfolio gets every active investment of the investor with its loan, newest first.
+// Amounts received stay at zero until repayments are recorded; they will be joined in here.
+// The agreement is the investor's own, or the loan's combined letter for investments made before each got one.
+func (r *LoanRepository) GetInvestorPortfolio(investorID uuid.UUID) ([]*models.PortfolioPosition, error) {
+	query := `
+		SELECT i.id, i.loan_id, l.state, l.product, l.roi, i.amount, i.expected_return,
+			0 AS amount_received, COALESCE(NULLIF(i.agreement_url, ''), l.agreement_letter_url, ''), i.investment_date
+		FROM investments i
+		INNER JOIN loans l ON i.loan_id = l.id AND l.deleted_at IS NULL
+		WHERE i.investor_id = $1
+		AND i.deleted_at IS NULL
+		ORDER BY i.investment_date DESC
+	`
+
+	rows, err := r.db.Query(query, investorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var positions []*models.PortfolioPosition
+	for rows.Next() {
+		var position models.PortfolioPosition
+		err := rows.Scan(
+			&position.InvestmentID,
+			&position.LoanID,
+			&position.LoanState,
+			&position.LoanProduct,
+			&position.LoanROI,
+			&position.Amount,
+			&position.ExpectedReturn,
+			&position.AmountReceived,
+			&position.AgreementLetterURL,
+			&position.InvestmentDate,
+		)
+		if err != nil {
+			return nil, err
+		}
+		position.AmountOutstanding = position.Outstanding()
+		positions = append(positions, &position)
+	}
+
+	return positions, rows.Err()
+}
+
+// GetInvestorInvestmentLimit gets the investor specific limit override; it returns nil without error when there is none
+func (r *LoanRepository) GetInvestorInvestmentLimit(tx *sql.Tx, investorID uuid.UUID) (*models.InvestorInvestmentLimit, error) {
+	query := `SELECT id, investor_id, max_loan_share, max_total_exposure, min_ticket, updated_by, created_at, updated_at
+			  FROM investor_investment_limits WHERE investor_id = $1 AND deleted_at IS NULL`
+
+	var limit models.InvestorInvestmentLimit
+	var err error
+	if tx != nil {
+		err = tx.QueryRow(query, investorID).Scan(
+			&limit.ID, &limit.InvestorID, &limit.MaxLoanShare, &limit.MaxTotalExposure, &limit.MinTicket,
+			&limit.UpdatedBy, &limit.CreatedAt, &limit.UpdatedAt,
+		)
+	} else {
+		err = r.db.QueryRow(query, investorID).Scan(
+			&limit.ID, &limit.InvestorID, &limit.MaxLoanShare, &limit.MaxTotalExposure, &limit.MinTicket,
+			&limit.UpdatedBy, &limit.CreatedAt, &limit.UpdatedAt,
+		)
+	}
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &limit, nil
+}
+
+// UpsertInvestorInvestmentLimit creates or replaces the investor specific limit override
+func (r *LoanRepository) UpsertInvestorInvestmentLimit(tx *sql.Tx, limit *models.InvestorInvestmentLimit) (*models.InvestorInvestmentLimit, error) {
+	if limit.ID == uuid.Nil {
+		limit.ID = uuid.New()
+	}
+
+	query := `INSERT INTO investor_investment_limits (id, investor_id, max_loan_share, max_total_exposure, min_ticket, updated_by, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			  ON CONFLICT (investor_id) DO UPDATE
+			  SET max_loan_share = EXCLUDED.max_loan_share,
+			      max_total_exposure = EXCLUDED.max_total_exposure,
+			      min_ticket = EXCLUDED.min_ticket,
+			      updated_by = EXCLUDED.updated_by,
+			      updated_at = CURRENT_TIMESTAMP
+			  RETURNING id, created_at, updated_at`
+
+	var err error
+	if tx != nil {
+		err = tx.QueryRow(query,
+			limit.ID,
+			limit.InvestorID,
+			limit.MaxLoanShare,
+			limit.MaxTotalExposure,
+			limit.MinTicket,
+			limit.UpdatedBy,
+		).Scan(&limit.ID, &limit.CreatedAt, &limit.UpdatedAt)
+	} else {
+		err = r.db.QueryRow(query,
+			limit.ID,
+			limit.InvestorID,
+			limit.MaxLoanShare,
+			limit.MaxTotalExposure,
+			limit.MinTicket,
+			limit.UpdatedBy,
+		).Scan(&limit.ID, &limit.CreatedAt, &limit.UpdatedAt)
+	}
+
+	return limit, err
+}
+
 func (r *LoanRepository) CreateDisbursement(tx *sql.Tx, disbursement *models.Disbursement) (*models.Disbursement, error) {
 	query := `INSERT INTO disbursements (id, loan_id, field_officer_id, disbursement_date, signed_agreement_url, signed_agreement_file_type, disbursed_amount, notes, created_at, updated_at)
 			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
@@ -292,14 +740,28 @@ func (r *LoanRepository) CreateDisbursement(tx *sql.Tx, disbursement *models.Dis
 	return disbursement, err
 }
 
+// UpdateDisbursementTransactionID stores the payment provider transaction reference for a disbursement
+func (r *LoanRepository) UpdateDisbursementTransactionID(tx *sql.Tx, disbursementID uuid.UUID, transactionID string) error {
+	query := `UPDATE disbursements SET transaction_id = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND deleted_at IS NULL`
+
+	var err error
+	if tx != nil {
+		_, err = tx.Exec(query, transactionID, disbursementID)
+	} else {
+		_, err = r.db.Exec(query, transactionID, disbursementID)
+	}
+	return err
+}
+
 // GetInvestmentsNeedingAgreementEmail gets investments that need agreement emails sent
 func (r *LoanRepository) GetInvestmentsNeedingAgreementEmail() ([]*models.Investment, error) {
 	query := `
 		SELECT i.id, i.loan_id, i.investor_id, i.amount, i.investment_date, i.expected_return, 
-		       i.agreement_sent, i.agreement_sent_at, i.created_at, i.updated_at
+		       i.agreement_sent, i.agreement_sent_at, i.agreement_url, i.created_at, i.updated_at
 		FROM investments i
 		INNER JOIN loans l ON i.loan_id = l.id AND l.deleted_at IS NULL
 		WHERE i.agreement_sent = false 
+		AND i.deleted_at IS NULL
 		AND l.state = 'invested'
 		ORDER BY i.created_at ASC
 	`
@@ -322,6 +784,7 @@ func (r *LoanRepository) GetInvestmentsNeedingAgreementEmail() ([]*models.Invest
 			&investment.ExpectedReturn,
 			&investment.AgreementSent,
 			&investment.AgreementSentAt,
+			&investment.AgreementURL,
 			&investment.CreatedAt,
 			&investment.UpdatedAt,
 		)
@@ -344,7 +807,7 @@ func (r *LoanRepository) UpdateInvestmentAgreementSent(investmentID uuid.UUID, a
 
 // GetInvestorByID gets an investor by ID
 func (r *LoanRepository) GetInvestorByID(investorID uuid.UUID) (*models.Investor, error) {
-	query := `SELECT id, investor_code, name, email, phone_number, is_active, created_at, updated_at
+	query := `SELECT id, investor_code, name, email, phone_number, locale, is_active, created_at, updated_at
 			  FROM investors WHERE id = $1 AND deleted_at IS NULL`
 
 	var investor models.Investor
@@ -354,6 +817,7 @@ func (r *LoanRepository) GetInvestorByID(investorID uuid.UUID) (*models.Investor
 		&investor.Name,
 		&investor.Email,
 		&investor.PhoneNumber,
+		&investor.Locale,
 		&investor.IsActive,
 		&investor.CreatedAt,
 		&investor.UpdatedAt,
@@ -366,9 +830,35 @@ func (r *LoanRepository) GetInvestorByID(investorID uuid.UUID) (*models.Investor
 	return &investor, nil
 }
 
+// GetEmployeeByID gets an employee by ID
+func (r *LoanRepository) GetEmployeeByID(employeeID uuid.UUID) (*models.Employee, error) {
+	query := `SELECT id, employee_id, first_name, last_name, email, role, COALESCE(phone_number, ''), is_active, created_at, updated_at
+			  FROM employees WHERE id = $1 AND deleted_at IS NULL`
+
+	var employee models.Employee
+	err := r.db.QueryRow(query, employeeID).Scan(
+		&employee.ID,
+		&employee.EmployeeID,
+		&employee.FirstName,
+		&employee.LastName,
+		&employee.Email,
+		&employee.Role,
+		&employee.PhoneNumber,
+		&employee.IsActive,
+		&employee.CreatedAt,
+		&employee.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &employee, nil
+}
+
 // GetBorrowerByID gets a borrower by ID
 func (r *LoanRepository) GetBorrowerByID(borrowerID uuid.UUID) (*models.Borrower, error) {
-	query := `SELECT id, id_number, first_name, last_name, email, phone_number, address, created_at, updated_at
+	query := `SELECT id, id_number, first_name, last_name, email, phone_number, address, latitude, longitude, created_at, updated_at
 			  FROM borrowers WHERE id = $1 AND deleted_at IS NULL`
 
 	var borrower models.Borrower
@@ -380,6 +870,8 @@ func (r *LoanRepository) GetBorrowerByID(borrowerID uuid.UUID) (*models.Borrower
 		&borrower.Email,
 		&borrower.PhoneNumber,
 		&borrower.Address,
+		&borrower.Latitude,
+		&borrower.Longitude,
 		&borrower.CreatedAt,
 		&borrower.UpdatedAt,
 	)
@@ -391,24 +883,6 @@ func (r *LoanRepository) GetBorrowerByID(borrowerID uuid.UUID) (*models.Borrower
 	return &borrower, nil
 }
 
-// CreateEmailNotification creates an email notification record
-func (r *LoanRepository) CreateEmailNotification(notification *models.EmailNotification) error {
-	query := `INSERT INTO email_notifications (id, investor_id, loan_id, email_type, email_subject, email_body, sent_at, status, created_at, updated_at)
-			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
-			  RETURNING created_at, updated_at`
-
-	return r.db.QueryRow(query,
-		notification.ID,
-		notification.InvestorID,
-		notification.LoanID,
-		notification.EmailType,
-		notification.EmailSubject,
-		notification.EmailBody,
-		notification.SentAt,
-		notification.Status,
-	).Scan(&notification.CreatedAt, &notification.UpdatedAt)
-}
-
 // UpdateLoanAgreementLetterURL updates the agreement letter URL for a loan
 func (r *LoanRepository) UpdateLoanAgreementLetterURL(tx *sql.Tx, loanID uuid.UUID, agreementURL string) error {
 	query := `UPDATE loans SET agreement_letter_url = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND deleted_at IS NULL`
@@ -421,3 +895,16 @@ func (r *LoanRepository) UpdateLoanAgreementLetterURL(tx *sql.Tx, loanID uuid.UU
 	}
 	return err
 }
+
+// UpdateInvestmentAgreementURL records where an investor's own agreement letter is stored
+func (r *LoanRepository) UpdateInvestmentAgreementURL(tx *sql.Tx, investmentID uuid.UUID, agreementURL string) error {
+	query := `UPDATE investments SET agreement_url = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND deleted_at IS NULL`
+
+	var err error
+	if tx != nil {
+		_, err = tx.Exec(query, agreementURL, investmentID)
+	} else {
+		_, err = r.db.Exec(query, agreementURL, investmentID)
+	}
+	return err
+}
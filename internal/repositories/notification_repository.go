@@ -0,0 +1,198 @@
+package repositories
+
+import (
+	"database/sql"
+	"loan-service/internal/models"
+	"loan-service/pkg/logger"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type NotificationRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+func NewNotificationRepository(db *sql.DB, logger *logger.Logger) NotificationRepositoryInterface {
+	return &NotificationRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+const emailNotificationColumns = `n.id, n.investor_id, n.loan_id, n.email_type, COALESCE(n.recipient, ''), n.email_subject,
+		n.email_body, COALESCE(n.email_html, ''), COALESCE(n.locale, ''), n.sent_at, n.delivered_at, n.opened_at, n.status,
+		COALESCE(n.error_message, ''), n.attempts, n.last_attempt_at, n.next_attempt_at, n.created_at, n.updated_at`
+
+// CreateEmailNotification creates an email notification record
+func (r *NotificationRepository) CreateEmailNotification(notification *models.EmailNotification) error {
+	if notification.ID == uuid.Nil {
+		notification.ID = uuid.New()
+	}
+
+	query := `INSERT INTO email_notifications (id, investor_id, loan_id, email_type, recipient, email_subject, email_body,
+			  email_html, locale, sent_at, status, error_message, attempts, last_attempt_at, next_attempt_at, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''), $10, $11, NULLIF($12, ''), $13, $14, $15,
+			  CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			  RETURNING created_at, updated_at`
+
+	return r.db.QueryRow(query,
+		notification.ID,
+		notification.InvestorID,
+		notification.LoanID,
+		notification.EmailType,
+		notification.Recipient,
+		notification.EmailSubject,
+		notification.EmailBody,
+		notification.EmailHTML,
+		notification.Locale,
+		notification.SentAt,
+		notification.Status,
+		notification.ErrorMessage,
+		notification.Attempts,
+		notification.LastAttemptAt,
+		notification.NextAttemptAt,
+	).Scan(&notification.CreatedAt, &notification.UpdatedAt)
+}
+
+// GetEmailNotificationByID returns nil without an error when there is no such notification
+func (r *NotificationRepository) GetEmailNotificationByID(notificationID uuid.UUID) (*models.EmailNotification, error) {
+	query := `SELECT ` + emailNotificationColumns + `
+			  FROM email_notifications n
+			  WHERE n.id = $1 AND n.deleted_at IS NULL`
+
+	notification, err := scanEmailNotification(r.db.QueryRow(query, notificationID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return notification, err
+}
+
+// GetFailedEmailNotifications returns the emails that ran out of attempts, most recent first
+func (r *NotificationRepository) GetFailedEmailNotifications(limit int) ([]*models.EmailNotification, error) {
+	query := `SELECT ` + emailNotificationColumns + `
+			  FROM email_notifications n
+			  WHERE n.status = $1 AND n.deleted_at IS NULL
+			  ORDER BY n.last_attempt_at DESC NULLS LAST
+			  LIMIT $2`
+
+	rows, err := r.db.Query(query, models.EmailStatusFailed, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanEmailNotifications(rows)
+}
+
+// ClaimDueEmailNotifications takes pending emails whose next attempt is due and pushes their next attempt to
+// leaseUntil, so a concurrent run does not send them as well. An email whose sender dies is retried once the lease
+// ends.
+func (r *NotificationRepository) ClaimDueEmailNotifications(now, leaseUntil time.Time, limit int) ([]*models.EmailNotification, error) {
+	query := `UPDATE email_notifications n SET next_attempt_at = $1, updated_at = CURRENT_TIMESTAMP
+			  WHERE n.id IN (
+				  SELECT id FROM email_notifications
+				  WHERE status = $2 AND next_attempt_at <= $3 AND deleted_at IS NULL
+				  ORDER BY next_attempt_at
+				  LIMIT $4
+				  FOR UPDATE SKIP LOCKED
+			  )
+			  RETURNING ` + emailNotificationColumns
+
+	rows, err := r.db.Query(query, leaseUntil, models.EmailStatusPending, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanEmailNotifications(rows)
+}
+
+// ClaimFailedEmailNotification moves a failed email back to pending, leased until leaseUntil, so it can be sent again.
+// It returns nil without an error when there is no failed notification with the ID.
+func (r *NotificationRepository) ClaimFailedEmailNotification(notificationID uuid.UUID, leaseUntil time.Time) (*models.EmailNotification, error) {
+	query := `UPDATE email_notifications n SET status = $1, next_attempt_at = $2, updated_at = CURRENT_TIMESTAMP
+			  WHERE n.id = $3 AND n.status = $4 AND n.deleted_at IS NULL
+			  RETURNING ` + emailNotificationColumns
+
+	notification, err := scanEmailNotification(r.db.QueryRow(query, models.EmailStatusPending, leaseUntil, notificationID, models.EmailStatusFailed))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return notification, err
+}
+
+// RecordEmailDeliveryAttempt stores an attempt together with the delivery state it left the notification in
+func (r *NotificationRepository) RecordEmailDeliveryAttempt(notification *models.EmailNotification, attempt *models.EmailDeliveryAttempt) error {
+	if attempt.ID == uuid.Nil {
+		attempt.ID = uuid.New()
+	}
+
+	query := `WITH attempt AS (
+				  INSERT INTO email_delivery_attempts (id, notification_id, attempt_number, attempted_at, succeeded,
+				  error_message, requested_by, created_at)
+				  VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, CURRENT_TIMESTAMP)
+			  )
+			  UPDATE email_notifications SET status = $8, attempts = $3, last_attempt_at = $4, next_attempt_at = $9,
+			  sent_at = $10, error_message = NULLIF($11, ''), updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $2
+			  RETURNING updated_at`
+
+	return r.db.QueryRow(query,
+		attempt.ID,
+		notification.ID,
+		attempt.AttemptNumber,
+		attempt.AttemptedAt,
+		attempt.Succeeded,
+		attempt.ErrorMessage,
+		attempt.RequestedBy,
+		notification.Status,
+		notification.NextAttemptAt,
+		notification.SentAt,
+		notification.ErrorMessage,
+	).Scan(&notification.UpdatedAt)
+}
+
+func scanEmailNotifications(rows *sql.Rows) ([]*models.EmailNotification, error) {
+	var notifications []*models.EmailNotification
+	for rows.Next() {
+		notification, err := scanEmailNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, rows.Err()
+}
+
+// scanEmailNotification scans the emailNotificationColumns
+func scanEmailNotification(row rowScanner) (*models.EmailNotification, error) {
+	notification := &models.EmailNotification{}
+	err := row.Scan(
+		&notification.ID,
+		&notification.InvestorID,
+		&notification.LoanID,
+		&notification.EmailType,
+		&notification.Recipient,
+		&notification.EmailSubject,
+		&notification.EmailBody,
+		&notification.EmailHTML,
+		&notification.Locale,
+		&notification.SentAt,
+		&notification.DeliveredAt,
+		&notification.OpenedAt,
+		&notification.Status,
+		&notification.ErrorMessage,
+		&notification.Attempts,
+		&notification.LastAttemptAt,
+		&notification.NextAttemptAt,
+		&notification.CreatedAt,
+		&notification.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return notification, nil
+}
@@ -0,0 +1,156 @@
+package repositories
+
+import (
+	"database/sql"
+	"loan-service/internal/models"
+	"loan-service/pkg/logger"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ReconciliationRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+func NewReconciliationRepository(db *sql.DB, logger *logger.Logger) ReconciliationRepositoryInterface {
+	return &ReconciliationRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *ReconciliationRepository) CreateReconciliationRun(tx *sql.Tx, run *models.ReconciliationRun) (*models.ReconciliationRun, error) {
+	if run.ID == uuid.Nil {
+		run.ID = uuid.New()
+	}
+
+	query := `INSERT INTO reconciliation_runs (id, report_date, source, status, total_records, matched_count, mismatch_count, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			  RETURNING created_at, updated_at`
+
+	var err error
+	if tx != nil {
+		err = tx.QueryRow(query,
+			run.ID,
+			run.ReportDate,
+			run.Source,
+			run.Status,
+			run.TotalRecords,
+			run.MatchedCount,
+			run.MismatchCount,
+		).Scan(&run.CreatedAt, &run.UpdatedAt)
+	} else {
+		err = r.db.QueryRow(query,
+			run.ID,
+			run.ReportDate,
+			run.Source,
+			run.Status,
+			run.TotalRecords,
+			run.MatchedCount,
+			run.MismatchCount,
+		).Scan(&run.CreatedAt, &run.UpdatedAt)
+	}
+
+	return run, err
+}
+
+// UpdateReconciliationRun stores the outcome counters and final status of a run
+func (r *ReconciliationRepository) UpdateReconciliationRun(tx *sql.Tx, run *models.ReconciliationRun) error {
+	query := `UPDATE reconciliation_runs
+			  SET status = $1, total_records = $2, matched_count = $3, mismatch_count = $4,
+			      completed_at = $5, error_message = $6, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $7 AND deleted_at IS NULL`
+
+	var err error
+	if tx != nil {
+		_, err = tx.Exec(query, run.Status, run.TotalRecords, run.MatchedCount, run.MismatchCount, run.CompletedAt, run.ErrorMessage, run.ID)
+	} else {
+		_, err = r.db.Exec(query, run.Status, run.TotalRecords, run.MatchedCount, run.MismatchCount, run.CompletedAt, run.ErrorMessage, run.ID)
+	}
+	return err
+}
+
+func (r *ReconciliationRepository) CreateReconciliationMismatch(tx *sql.Tx, mismatch *models.ReconciliationMismatch) (*models.ReconciliationMismatch, error) {
+	if mismatch.ID == uuid.Nil {
+		mismatch.ID = uuid.New()
+	}
+
+	query := `INSERT INTO reconciliation_mismatches (id, run_id, transaction_id, mismatch_type, reference_type, reference_id, expected_amount, reported_amount, notes, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			  RETURNING created_at, updated_at`
+
+	var err error
+	if tx != nil {
+		err = tx.QueryRow(query,
+			mismatch.ID,
+			mismatch.RunID,
+			mismatch.TransactionID,
+			mismatch.MismatchType,
+			mismatch.ReferenceType,
+			mismatch.ReferenceID,
+			mismatch.ExpectedAmount,
+			mismatch.ReportedAmount,
+			mismatch.Notes,
+		).Scan(&mismatch.CreatedAt, &mismatch.UpdatedAt)
+	} else {
+		err = r.db.QueryRow(query,
+			mismatch.ID,
+			mismatch.RunID,
+			mismatch.TransactionID,
+			mismatch.MismatchType,
+			mismatch.ReferenceType,
+			mismatch.ReferenceID,
+			mismatch.ExpectedAmount,
+			mismatch.ReportedAmount,
+			mismatch.Notes,
+		).Scan(&mismatch.CreatedAt, &mismatch.UpdatedAt)
+	}
+
+	return mismatch, err
+}
+
+// GetExpectedPayments gets the disbursements and completed withdrawal payouts paid out through the payment provider in [from, to).
+// Repayments will be unioned in here once repayments are recorded.
+func (r *ReconciliationRepository) GetExpectedPayments(from, to time.Time) ([]*models.ExpectedPayment, error) {
+	query := `
+		SELECT 'disbursement', d.id, d.transaction_id, d.disbursed_amount, d.created_at
+		FROM disbursements d
+		WHERE d.transaction_id IS NOT NULL
+		AND d.deleted_at IS NULL
+		AND d.created_at >= $1 AND d.created_at < $2
+		UNION ALL
+		SELECT 'withdrawal', w.id, w.transaction_id, w.amount, w.completed_at
+		FROM withdrawals w
+		WHERE w.status = 'completed'
+		AND w.transaction_id IS NOT NULL
+		AND w.deleted_at IS NULL
+		AND w.completed_at >= $1 AND w.completed_at < $2
+		ORDER BY 5 ASC
+	`
+
+	rows, err := r.db.Query(query, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []*models.ExpectedPayment
+	for rows.Next() {
+		var payment models.ExpectedPayment
+		err := rows.Scan(
+			&payment.ReferenceType,
+			&payment.ReferenceID,
+			&payment.TransactionID,
+			&payment.Amount,
+			&payment.PaidAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, &payment)
+	}
+
+	return payments, rows.Err()
+}
@@ -0,0 +1,154 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"loan-service/internal/models"
+	"loan-service/pkg/logger"
+	"loan-service/pkg/redis"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReservationRepository keeps investment reservations in one Redis hash per loan, keyed by reservation ID.
+// Expired entries are ignored when read and pruned lazily; the hash itself expires with its last reservation.
+type ReservationRepository struct {
+	redis  *redis.RedisClient
+	logger *logger.Logger
+}
+
+func NewReservationRepository(redis *redis.RedisClient, logger *logger.Logger) ReservationRepositoryInterface {
+	return &ReservationRepository{
+		redis:  redis,
+		logger: logger,
+	}
+}
+
+func reservationsKey(loanID uuid.UUID) string {
+	return fmt.Sprintf("loan:%s:reservations", loanID)
+}
+
+// CreateReservation stores the reservation and keeps the loan's hash alive until it expires
+func (r *ReservationRepository) CreateReservation(reservation *models.InvestmentReservation) error {
+	ctx := context.Background()
+	key := reservationsKey(reservation.LoanID)
+
+	data, err := json.Marshal(reservation)
+	if err != nil {
+		return fmt.Errorf("failed to marshal reservation: %w", err)
+	}
+
+	if err := r.redis.HSet(ctx, key, reservation.ID.String(), data); err != nil {
+		return fmt.Errorf("failed to store reservation: %w", err)
+	}
+
+	ttl, err := r.redis.TTL(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to get reservations TTL: %w", err)
+	}
+
+	if remaining := time.Until(reservation.ExpiresAt); remaining > ttl {
+		if err := r.redis.Expire(ctx, key, remaining); err != nil {
+			return fmt.Errorf("failed to extend reservations TTL: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetReservation gets an active reservation of the loan, or nil when it is gone or has expired
+func (r *ReservationRepository) GetReservation(loanID, reservationID uuid.UUID) (*models.InvestmentReservation, error) {
+	reservations, err := r.getActiveReservations(loanID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, reservation := range reservations {
+		if reservation.ID == reservationID {
+			return reservation, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// GetReservedAmount sums the active reservations of the loan
+func (r *ReservationRepository) GetReservedAmount(loanID uuid.UUID) (float64, error) {
+	reservations, err := r.getActiveReservations(loanID)
+	if err != nil {
+		return 0, err
+	}
+
+	var reserved float64
+	for _, reservation := range reservations {
+		reserved += reservation.Amount
+	}
+
+	return reserved, nil
+}
+
+// ClaimReservation removes an active reservation and returns it, or nil when it is gone or has expired. Only one
+// caller can claim a reservation, so it cannot be converted twice.
+func (r *ReservationRepository) ClaimReservation(loanID, reservationID uuid.UUID) (*models.InvestmentReservation, error) {
+	data, ok, err := r.redis.HTake(context.Background(), reservationsKey(loanID), reservationID.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim reservation: %w", err)
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	var reservation models.InvestmentReservation
+	if err := json.Unmarshal([]byte(data), &reservation); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal reservation %s: %w", reservationID, err)
+	}
+
+	if reservation.IsExpired(time.Now()) {
+		return nil, nil
+	}
+	return &reservation, nil
+}
+
+// DeleteReservation removes the reservation once it has been converted or abandoned
+func (r *ReservationRepository) DeleteReservation(loanID, reservationID uuid.UUID) error {
+	return r.redis.HDel(context.Background(), reservationsKey(loanID), reservationID.String())
+}
+
+func (r *ReservationRepository) getActiveReservations(loanID uuid.UUID) ([]*models.InvestmentReservation, error) {
+	ctx := context.Background()
+	key := reservationsKey(loanID)
+
+	entries, err := r.redis.HGetAll(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reservations: %w", err)
+	}
+
+	now := time.Now()
+	var active []*models.InvestmentReservation
+	var expired []string
+	for field, data := range entries {
+		var reservation models.InvestmentReservation
+		if err := json.Unmarshal([]byte(data), &reservation); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal reservation %s: %w", field, err)
+		}
+
+		if reservation.IsExpired(now) {
+			expired = append(expired, field)
+			continue
+		}
+		active = append(active, &reservation)
+	}
+
+	if len(expired) > 0 {
+		if err := r.redis.HDel(ctx, key, expired...); err != nil {
+			r.logger.Warn("Failed to prune expired reservations", map[string]interface{}{
+				"error":   err.Error(),
+				"loan_id": loanID.String(),
+			})
+		}
+	}
+
+	return active, nil
+}
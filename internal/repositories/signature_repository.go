@@ -0,0 +1,178 @@
+package repositories
+
+import (
+	"database/sql"
+	"loan-service/internal/models"
+	"loan-service/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+type SignatureRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+func NewSignatureRepository(db *sql.DB, logger *logger.Logger) SignatureRepositoryInterface {
+	return &SignatureRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+const signatureColumns = `s.id, s.loan_id, s.provider, s.envelope_id, s.status, s.document_url, COALESCE(s.signing_url, ''),
+		s.signer_name, s.signer_email, COALESCE(s.signed_file_url, ''), COALESCE(s.signed_file_type, ''),
+		s.sent_at, s.completed_at, s.created_at, s.updated_at`
+
+func (r *SignatureRepository) CreateAgreementSignature(tx *sql.Tx, signature *models.AgreementSignature) (*models.AgreementSignature, error) {
+	if signature.ID == uuid.Nil {
+		signature.ID = uuid.New()
+	}
+
+	query := `INSERT INTO agreement_signatures (id, loan_id, provider, envelope_id, status, document_url, signing_url,
+			  signer_name, signer_email, sent_at, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			  RETURNING created_at, updated_at`
+
+	var err error
+	if tx != nil {
+		err = tx.QueryRow(query,
+			signature.ID,
+			signature.LoanID,
+			signature.Provider,
+			signature.EnvelopeID,
+			signature.Status,
+			signature.DocumentURL,
+			signature.SigningURL,
+			signature.SignerName,
+			signature.SignerEmail,
+			signature.SentAt,
+		).Scan(&signature.CreatedAt, &signature.UpdatedAt)
+	} else {
+		err = r.db.QueryRow(query,
+			signature.ID,
+			signature.LoanID,
+			signature.Provider,
+			signature.EnvelopeID,
+			signature.Status,
+			signature.DocumentURL,
+			signature.SigningURL,
+			signature.SignerName,
+			signature.SignerEmail,
+			signature.SentAt,
+		).Scan(&signature.CreatedAt, &signature.UpdatedAt)
+	}
+
+	return signature, err
+}
+
+// LockAgreementSignature gets a signature by its provider envelope and locks it so a callback is applied only once
+func (r *SignatureRepository) LockAgreementSignature(tx *sql.Tx, provider, envelopeID string) (*models.AgreementSignature, error) {
+	query := `SELECT ` + signatureColumns + `
+			  FROM agreement_signatures s
+			  WHERE s.provider = $1 AND s.envelope_id = $2 AND s.deleted_at IS NULL
+			  FOR UPDATE`
+
+	var row *sql.Row
+	if tx != nil {
+		row = tx.QueryRow(query, provider, envelopeID)
+	} else {
+		row = r.db.QueryRow(query, provider, envelopeID)
+	}
+
+	var signature models.AgreementSignature
+	if err := scanSignature(row, &signature); err != nil {
+		return nil, err
+	}
+
+	return &signature, nil
+}
+
+// GetAgreementSignature gets the signature of a provider envelope without locking it
+func (r *SignatureRepository) GetAgreementSignature(provider, envelopeID string) (*models.AgreementSignature, error) {
+	query := `SELECT ` + signatureColumns + `
+			  FROM agreement_signatures s
+			  WHERE s.provider = $1 AND s.envelope_id = $2 AND s.deleted_at IS NULL`
+
+	var signature models.AgreementSignature
+	if err := scanSignature(r.db.QueryRow(query, provider, envelopeID), &signature); err != nil {
+		return nil, err
+	}
+
+	return &signature, nil
+}
+
+// UpdateAgreementSignature persists the outcome of a provider callback
+func (r *SignatureRepository) UpdateAgreementSignature(tx *sql.Tx, signature *models.AgreementSignature) error {
+	query := `UPDATE agreement_signatures
+			  SET status = $2, signed_file_url = NULLIF($3, ''), signed_file_type = NULLIF($4, ''),
+			      completed_at = $5, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $1 AND deleted_at IS NULL
+			  RETURNING updated_at`
+
+	var err error
+	if tx != nil {
+		err = tx.QueryRow(query,
+			signature.ID,
+			signature.Status,
+			signature.SignedFileURL,
+			signature.SignedFileType,
+			signature.CompletedAt,
+		).Scan(&signature.UpdatedAt)
+	} else {
+		err = r.db.QueryRow(query,
+			signature.ID,
+			signature.Status,
+			signature.SignedFileURL,
+			signature.SignedFileType,
+			signature.CompletedAt,
+		).Scan(&signature.UpdatedAt)
+	}
+
+	return err
+}
+
+// GetOpenAgreementSignature gets the signature of a loan that is still pending or already signed.
+// Returns nil when the agreement was never sent or every attempt was declined.
+func (r *SignatureRepository) GetOpenAgreementSignature(tx *sql.Tx, loanID uuid.UUID) (*models.AgreementSignature, error) {
+	query := `SELECT ` + signatureColumns + `
+			  FROM agreement_signatures s
+			  WHERE s.loan_id = $1 AND s.status IN ('pending', 'signed') AND s.deleted_at IS NULL`
+
+	var row *sql.Row
+	if tx != nil {
+		row = tx.QueryRow(query, loanID)
+	} else {
+		row = r.db.QueryRow(query, loanID)
+	}
+
+	var signature models.AgreementSignature
+	if err := scanSignature(row, &signature); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &signature, nil
+}
+
+func scanSignature(row rowScanner, signature *models.AgreementSignature) error {
+	return row.Scan(
+		&signature.ID,
+		&signature.LoanID,
+		&signature.Provider,
+		&signature.EnvelopeID,
+		&signature.Status,
+		&signature.DocumentURL,
+		&signature.SigningURL,
+		&signature.SignerName,
+		&signature.SignerEmail,
+		&signature.SignedFileURL,
+		&signature.SignedFileType,
+		&signature.SentAt,
+		&signature.CompletedAt,
+		&signature.CreatedAt,
+		&signature.UpdatedAt,
+	)
+}
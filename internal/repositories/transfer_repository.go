@@ -0,0 +1,216 @@
+package repositories
+
+import (
+	"database/sql"
+	"loan-service/internal/models"
+	"loan-service/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+type TransferRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+func NewTransferRepository(db *sql.DB, logger *logger.Logger) TransferRepositoryInterface {
+	return &TransferRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+const transferColumns = `t.id, t.investment_id, t.loan_id, t.seller_id, t.amount, t.price, t.status, t.buyer_id,
+		t.buyer_investment_id, COALESCE(t.agreement_url, ''), t.completed_at, t.cancelled_at, t.created_at, t.updated_at`
+
+func (r *TransferRepository) CreateInvestmentTransfer(tx *sql.Tx, transfer *models.InvestmentTransfer) (*models.InvestmentTransfer, error) {
+	if transfer.ID == uuid.Nil {
+		transfer.ID = uuid.New()
+	}
+
+	query := `INSERT INTO investment_transfers (id, investment_id, loan_id, seller_id, amount, price, status, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			  RETURNING created_at, updated_at`
+
+	var err error
+	if tx != nil {
+		err = tx.QueryRow(query,
+			transfer.ID,
+			transfer.InvestmentID,
+			transfer.LoanID,
+			transfer.SellerID,
+			transfer.Amount,
+			transfer.Price,
+			transfer.Status,
+		).Scan(&transfer.CreatedAt, &transfer.UpdatedAt)
+	} else {
+		err = r.db.QueryRow(query,
+			transfer.ID,
+			transfer.InvestmentID,
+			transfer.LoanID,
+			transfer.SellerID,
+			transfer.Amount,
+			transfer.Price,
+			transfer.Status,
+		).Scan(&transfer.CreatedAt, &transfer.UpdatedAt)
+	}
+
+	return transfer, err
+}
+
+// LockInvestmentTransfer gets a transfer and locks it for the rest of the transaction so it is bought or cancelled only once
+func (r *TransferRepository) LockInvestmentTransfer(tx *sql.Tx, transferID uuid.UUID) (*models.InvestmentTransfer, error) {
+	query := `SELECT ` + transferColumns + `
+			  FROM investment_transfers t WHERE t.id = $1 AND t.deleted_at IS NULL
+			  FOR UPDATE`
+
+	var row *sql.Row
+	if tx != nil {
+		row = tx.QueryRow(query, transferID)
+	} else {
+		row = r.db.QueryRow(query, transferID)
+	}
+
+	var transfer models.InvestmentTransfer
+	if err := scanTransfer(row, &transfer); err != nil {
+		return nil, err
+	}
+
+	return &transfer, nil
+}
+
+// GetInvestmentTransferByID gets a transfer without locking it
+func (r *TransferRepository) GetInvestmentTransferByID(transferID uuid.UUID) (*models.InvestmentTransfer, error) {
+	query := `SELECT ` + transferColumns + `
+			  FROM investment_transfers t WHERE t.id = $1 AND t.deleted_at IS NULL`
+
+	var transfer models.InvestmentTransfer
+	if err := scanTransfer(r.db.QueryRow(query, transferID), &transfer); err != nil {
+		return nil, err
+	}
+
+	return &transfer, nil
+}
+
+// UpdateInvestmentTransfer persists the settlement or cancellation of a transfer
+func (r *TransferRepository) UpdateInvestmentTransfer(tx *sql.Tx, transfer *models.InvestmentTransfer) error {
+	query := `UPDATE investment_transfers
+			  SET status = $2, buyer_id = $3, buyer_investment_id = $4, agreement_url = NULLIF($5, ''),
+			      completed_at = $6, cancelled_at = $7, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $1 AND deleted_at IS NULL
+			  RETURNING updated_at`
+
+	var err error
+	if tx != nil {
+		err = tx.QueryRow(query,
+			transfer.ID,
+			transfer.Status,
+			transfer.BuyerID,
+			transfer.BuyerInvestmentID,
+			transfer.AgreementURL,
+			transfer.CompletedAt,
+			transfer.CancelledAt,
+		).Scan(&transfer.UpdatedAt)
+	} else {
+		err = r.db.QueryRow(query,
+			transfer.ID,
+			transfer.Status,
+			transfer.BuyerID,
+			transfer.BuyerInvestmentID,
+			transfer.AgreementURL,
+			transfer.CompletedAt,
+			transfer.CancelledAt,
+		).Scan(&transfer.UpdatedAt)
+	}
+
+	return err
+}
+
+// CancelUncoveredInvestmentTransfers cancels the listings of an investment that ask for more than it still holds,
+// so a listing left over from a sale is not offered for principal the seller no longer has
+func (r *TransferRepository) CancelUncoveredInvestmentTransfers(tx *sql.Tx, investmentID uuid.UUID, remaining float64) (int64, error) {
+	query := `UPDATE investment_transfers
+			  SET status = 'cancelled', cancelled_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+			  WHERE investment_id = $1 AND status = 'listed' AND amount > $2 AND deleted_at IS NULL`
+
+	var result sql.Result
+	var err error
+	if tx != nil {
+		result, err = tx.Exec(query, investmentID, remaining)
+	} else {
+		result, err = r.db.Exec(query, investmentID, remaining)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// GetListedInvestmentTransfers gets the listings open for buying, newest first
+func (r *TransferRepository) GetListedInvestmentTransfers() ([]*models.InvestmentTransfer, error) {
+	query := `SELECT ` + transferColumns + `
+			  FROM investment_transfers t
+			  WHERE t.status = 'listed' AND t.deleted_at IS NULL
+			  ORDER BY t.created_at DESC`
+
+	return r.queryTransfers(query)
+}
+
+// GetInvestmentLineage gets the completed transfers in the ownership chain of an investment, oldest first:
+// the transfers that created it and its ancestors, followed by the slices sold off from it
+func (r *TransferRepository) GetInvestmentLineage(investmentID uuid.UUID) ([]*models.InvestmentTransfer, error) {
+	query := `
+		WITH RECURSIVE chain AS (
+			SELECT id, parent_investment_id FROM investments WHERE id = $1
+			UNION ALL
+			SELECT i.id, i.parent_investment_id FROM investments i
+			INNER JOIN chain c ON i.id = c.parent_investment_id
+		)
+		SELECT ` + transferColumns + `
+		FROM investment_transfers t
+		WHERE t.status = 'completed' AND t.deleted_at IS NULL
+		AND (t.buyer_investment_id IN (SELECT id FROM chain) OR t.investment_id = $1)
+		ORDER BY t.completed_at ASC
+	`
+
+	return r.queryTransfers(query, investmentID)
+}
+
+func (r *TransferRepository) queryTransfers(query string, args ...interface{}) ([]*models.InvestmentTransfer, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transfers []*models.InvestmentTransfer
+	for rows.Next() {
+		var transfer models.InvestmentTransfer
+		if err := scanTransfer(rows, &transfer); err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, &transfer)
+	}
+
+	return transfers, rows.Err()
+}
+
+func scanTransfer(row rowScanner, transfer *models.InvestmentTransfer) error {
+	return row.Scan(
+		&transfer.ID,
+		&transfer.InvestmentID,
+		&transfer.LoanID,
+		&transfer.SellerID,
+		&transfer.Amount,
+		&transfer.Price,
+		&transfer.Status,
+		&transfer.BuyerID,
+		&transfer.BuyerInvestmentID,
+		&transfer.AgreementURL,
+		&transfer.CompletedAt,
+		&transfer.CancelledAt,
+		&transfer.CreatedAt,
+		&transfer.UpdatedAt,
+	)
+}
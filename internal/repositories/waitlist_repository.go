@@ -0,0 +1,195 @@
+package repositories
+
+import (
+	"database/sql"
+	"loan-service/internal/models"
+	"loan-service/pkg/logger"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type WaitlistRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+func NewWaitlistRepository(db *sql.DB, logger *logger.Logger) WaitlistRepositoryInterface {
+	return &WaitlistRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *WaitlistRepository) CreateWaitlistEntry(entry *models.WaitlistEntry) (*models.WaitlistEntry, error) {
+	if entry.ID == uuid.Nil {
+		entry.ID = uuid.New()
+	}
+
+	query := `INSERT INTO loan_waitlist_entries (id, loan_id, investor_id, amount, status, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			  RETURNING created_at, updated_at`
+
+	err := r.db.QueryRow(query,
+		entry.ID,
+		entry.LoanID,
+		entry.InvestorID,
+		entry.Amount,
+		entry.Status,
+	).Scan(&entry.CreatedAt, &entry.UpdatedAt)
+
+	return entry, err
+}
+
+// waitlistEntryColumns are the columns scanned by scanWaitlistEntry
+const waitlistEntryColumns = `id, loan_id, investor_id, amount, status, allocated_amount, investment_id,
+		COALESCE(failure_reason, ''), processed_at, claimed_until, created_at, updated_at`
+
+// GetWaitingEntries gets the loan's queue in order, including the entry being served
+func (r *WaitlistRepository) GetWaitingEntries(loanID uuid.UUID) ([]*models.WaitlistEntry, error) {
+	query := `SELECT ` + waitlistEntryColumns + `
+			  FROM loan_waitlist_entries
+			  WHERE loan_id = $1 AND status IN ('waiting', 'processing') AND deleted_at IS NULL
+			  ORDER BY created_at ASC, id ASC`
+
+	rows, err := r.db.Query(query, loanID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.WaitlistEntry
+	for rows.Next() {
+		entry, err := scanWaitlistEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// ClaimNextWaitingEntry marks the head of the loan's queue as processing until leaseUntil and returns it. It returns
+// nil when the queue is empty or its head is already being served, so a loan's queue is served by one run at a time
+// and in order, whichever process the runs are in.
+//
+// An entry whose claim lapsed belongs to a run that died, possibly after investing; rather than risk investing twice
+// it is failed for staff to look into.
+func (r *WaitlistRepository) ClaimNextWaitingEntry(loanID uuid.UUID, now, leaseUntil time.Time) (*models.WaitlistEntry, error) {
+	interrupted := `UPDATE loan_waitlist_entries
+					SET status = 'failed', failure_reason = 'processing was interrupted', claimed_until = NULL,
+						processed_at = $2, updated_at = CURRENT_TIMESTAMP
+					WHERE loan_id = $1 AND status = 'processing' AND claimed_until < $2 AND deleted_at IS NULL`
+
+	if _, err := r.db.Exec(interrupted, loanID, now); err != nil {
+		return nil, err
+	}
+
+	// Locking the head without SKIP LOCKED makes a concurrent claim wait and then find the head taken, instead of
+	// skipping ahead of it
+	query := `UPDATE loan_waitlist_entries
+			  SET status = 'processing', claimed_until = $2, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = (
+				  SELECT id FROM loan_waitlist_entries
+				  WHERE loan_id = $1 AND status IN ('waiting', 'processing') AND deleted_at IS NULL
+				  ORDER BY created_at ASC, id ASC
+				  LIMIT 1
+				  FOR UPDATE
+			  ) AND status = 'waiting'
+			  RETURNING ` + waitlistEntryColumns
+
+	entry, err := scanWaitlistEntry(r.db.QueryRow(query, loanID, leaseUntil))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return entry, err
+}
+
+// ReleaseWaitlistEntry puts a claimed entry back in the queue in its place, e.g. when there is too little capacity
+// to serve it yet
+func (r *WaitlistRepository) ReleaseWaitlistEntry(entryID uuid.UUID) error {
+	query := `UPDATE loan_waitlist_entries
+			  SET status = 'waiting', claimed_until = NULL, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $1 AND status = 'processing' AND deleted_at IS NULL`
+
+	_, err := r.db.Exec(query, entryID)
+	return err
+}
+
+// GetLoansWithWaitingEntries gets the loans that have investors waiting for capacity
+func (r *WaitlistRepository) GetLoansWithWaitingEntries() ([]uuid.UUID, error) {
+	query := `SELECT DISTINCT loan_id FROM loan_waitlist_entries WHERE status IN ('waiting', 'processing') AND deleted_at IS NULL`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var loanIDs []uuid.UUID
+	for rows.Next() {
+		var loanID uuid.UUID
+		if err := rows.Scan(&loanID); err != nil {
+			return nil, err
+		}
+		loanIDs = append(loanIDs, loanID)
+	}
+
+	return loanIDs, rows.Err()
+}
+
+// UpdateWaitlistEntry records the outcome of serving an entry, which ends its claim
+func (r *WaitlistRepository) UpdateWaitlistEntry(entry *models.WaitlistEntry) error {
+	query := `UPDATE loan_waitlist_entries
+			  SET status = $1, allocated_amount = $2, investment_id = $3, failure_reason = NULLIF($4, ''),
+				  processed_at = $5, claimed_until = NULL, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $6 AND deleted_at IS NULL`
+
+	_, err := r.db.Exec(query,
+		entry.Status,
+		entry.AllocatedAmount,
+		entry.InvestmentID,
+		entry.FailureReason,
+		entry.ProcessedAt,
+		entry.ID,
+	)
+	return err
+}
+
+// CloseWaitingEntries closes every waiting entry of a loan that no longer raises funds
+func (r *WaitlistRepository) CloseWaitingEntries(loanID uuid.UUID) (int64, error) {
+	query := `UPDATE loan_waitlist_entries
+			  SET status = 'closed', processed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+			  WHERE loan_id = $1 AND status = 'waiting' AND deleted_at IS NULL`
+
+	result, err := r.db.Exec(query, loanID)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// scanWaitlistEntry scans a row selected with waitlistEntryColumns
+func scanWaitlistEntry(row rowScanner) (*models.WaitlistEntry, error) {
+	var entry models.WaitlistEntry
+	err := row.Scan(
+		&entry.ID,
+		&entry.LoanID,
+		&entry.InvestorID,
+		&entry.Amount,
+		&entry.Status,
+		&entry.AllocatedAmount,
+		&entry.InvestmentID,
+		&entry.FailureReason,
+		&entry.ProcessedAt,
+		&entry.ClaimedUntil,
+		&entry.CreatedAt,
+		&entry.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
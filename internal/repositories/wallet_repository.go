@@ -0,0 +1,386 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"loan-service/internal/models"
+	"loan-service/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+type WalletRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+func NewWalletRepository(db *sql.DB, logger *logger.Logger) WalletRepositoryInterface {
+	return &WalletRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+const walletTopUpColumns = `id, investor_id, amount, status, COALESCE(transaction_id, ''), COALESCE(failure_reason, ''),
+		completed_at, created_at, updated_at`
+
+// LockWallet gets the investor wallet, creating it on first use, and locks it for the rest of the transaction.
+// Every balance check followed by a hold or payout must happen under this lock.
+func (r *WalletRepository) LockWallet(tx *sql.Tx, investorID uuid.UUID) (*models.Wallet, error) {
+	insertQuery := `INSERT INTO investor_wallets (id, investor_id, created_at, updated_at)
+			  VALUES ($1, $2, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			  ON CONFLICT (investor_id) DO NOTHING`
+
+	lockQuery := `SELECT id, investor_id, currency, created_at, updated_at
+			  FROM investor_wallets WHERE investor_id = $1 AND deleted_at IS NULL
+			  FOR UPDATE`
+
+	var wallet models.Wallet
+	var err error
+	if tx != nil {
+		if _, err = tx.Exec(insertQuery, uuid.New(), investorID); err != nil {
+			return nil, err
+		}
+		err = tx.QueryRow(lockQuery, investorID).Scan(
+			&wallet.ID, &wallet.InvestorID, &wallet.Currency, &wallet.CreatedAt, &wallet.UpdatedAt,
+		)
+	} else {
+		if _, err = r.db.Exec(insertQuery, uuid.New(), investorID); err != nil {
+			return nil, err
+		}
+		err = r.db.QueryRow(lockQuery, investorID).Scan(
+			&wallet.ID, &wallet.InvestorID, &wallet.Currency, &wallet.CreatedAt, &wallet.UpdatedAt,
+		)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &wallet, nil
+}
+
+// GetWalletBalance derives the investor balance from the ledger
+func (r *WalletRepository) GetWalletBalance(tx *sql.Tx, investorID uuid.UUID) (*models.WalletBalance, error) {
+	query := `
+		SELECT transaction_type, SUM(amount)
+		FROM wallet_transactions
+		WHERE investor_id = $1 AND deleted_at IS NULL
+		GROUP BY transaction_type
+	`
+
+	var rows *sql.Rows
+	var err error
+	if tx != nil {
+		rows, err = tx.Query(query, investorID)
+	} else {
+		rows, err = r.db.Query(query, investorID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	balance := models.WalletBalance{InvestorID: investorID}
+	for rows.Next() {
+		var transactionType models.WalletTransactionType
+		var amount float64
+		if err := rows.Scan(&transactionType, &amount); err != nil {
+			return nil, err
+		}
+		balance.Apply(transactionType, amount)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return &balance, nil
+}
+
+func (r *WalletRepository) CreateWalletTransaction(tx *sql.Tx, transaction *models.WalletTransaction) (*models.WalletTransaction, error) {
+	if transaction.ID == uuid.Nil {
+		transaction.ID = uuid.New()
+	}
+
+	query := `INSERT INTO wallet_transactions (id, wallet_id, investor_id, transaction_type, amount, reference_type, reference_id, hold_id, description, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			  RETURNING created_at, updated_at`
+
+	var err error
+	if tx != nil {
+		err = tx.QueryRow(query,
+			transaction.ID,
+			transaction.WalletID,
+			transaction.InvestorID,
+			transaction.TransactionType,
+			transaction.Amount,
+			transaction.ReferenceType,
+			transaction.ReferenceID,
+			transaction.HoldID,
+			transaction.Description,
+		).Scan(&transaction.CreatedAt, &transaction.UpdatedAt)
+	} else {
+		err = r.db.QueryRow(query,
+			transaction.ID,
+			transaction.WalletID,
+			transaction.InvestorID,
+			transaction.TransactionType,
+			transaction.Amount,
+			transaction.ReferenceType,
+			transaction.ReferenceID,
+			transaction.HoldID,
+			transaction.Description,
+		).Scan(&transaction.CreatedAt, &transaction.UpdatedAt)
+	}
+
+	return transaction, err
+}
+
+// GetWalletTransactions gets the ledger entries of an investor, newest first
+func (r *WalletRepository) GetWalletTransactions(investorID uuid.UUID) ([]*models.WalletTransaction, error) {
+	query := `
+		SELECT id, wallet_id, investor_id, transaction_type, amount, COALESCE(reference_type, ''),
+		       reference_id, hold_id, COALESCE(description, ''), created_at, updated_at
+		FROM wallet_transactions
+		WHERE investor_id = $1 AND deleted_at IS NULL
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Query(query, investorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []*models.WalletTransaction
+	for rows.Next() {
+		var transaction models.WalletTransaction
+		err := rows.Scan(
+			&transaction.ID,
+			&transaction.WalletID,
+			&transaction.InvestorID,
+			&transaction.TransactionType,
+			&transaction.Amount,
+			&transaction.ReferenceType,
+			&transaction.ReferenceID,
+			&transaction.HoldID,
+			&transaction.Description,
+			&transaction.CreatedAt,
+			&transaction.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, &transaction)
+	}
+
+	return transactions, rows.Err()
+}
+
+// ReleaseHold returns a single open hold to the available balance
+func (r *WalletRepository) ReleaseHold(tx *sql.Tx, holdID uuid.UUID, description string) error {
+	query := `
+		INSERT INTO wallet_transactions (id, wallet_id, investor_id, transaction_type, amount, reference_type, reference_id, hold_id, description, created_at, updated_at)
+		SELECT gen_random_uuid(), h.wallet_id, h.investor_id, 'release', h.amount, h.reference_type, h.reference_id, h.id, $2, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+		FROM wallet_transactions h
+		WHERE h.id = $1
+		AND h.transaction_type = 'hold'
+		AND NOT EXISTS (SELECT 1 FROM wallet_transactions s WHERE s.hold_id = h.id)
+	`
+
+	var result sql.Result
+	var err error
+	if tx != nil {
+		result, err = tx.Exec(query, holdID, description)
+	} else {
+		result, err = r.db.Exec(query, holdID, description)
+	}
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("hold %s not found or already settled", holdID)
+	}
+
+	return nil
+}
+
+// ReleaseInvestmentHold releases the open hold placed for a single investment.
+// Investments made before the wallet ledger existed have no hold, so zero rows is not an error.
+func (r *WalletRepository) ReleaseInvestmentHold(tx *sql.Tx, investmentID uuid.UUID, description string) (int64, error) {
+	query := `
+		INSERT INTO wallet_transactions (id, wallet_id, investor_id, transaction_type, amount, reference_type, reference_id, hold_id, description, created_at, updated_at)
+		SELECT gen_random_uuid(), h.wallet_id, h.investor_id, 'release', h.amount, 'investment', $1, h.id, $2, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+		FROM wallet_transactions h
+		WHERE h.reference_type = 'investment' AND h.reference_id = $1
+		AND h.transaction_type = 'hold'
+		AND NOT EXISTS (SELECT 1 FROM wallet_transactions s WHERE s.hold_id = h.id)
+	`
+
+	var result sql.Result
+	var err error
+	if tx != nil {
+		result, err = tx.Exec(query, investmentID, description)
+	} else {
+		result, err = r.db.Exec(query, investmentID, description)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// CaptureLoanHolds captures every open hold placed for investments in the loan
+func (r *WalletRepository) CaptureLoanHolds(tx *sql.Tx, loanID uuid.UUID, description string) (int64, error) {
+	return r.settleLoanHolds(tx, loanID, models.WalletTransactionCapture, description)
+}
+
+// ReleaseLoanHolds releases every open hold placed for investments in the loan
+func (r *WalletRepository) ReleaseLoanHolds(tx *sql.Tx, loanID uuid.UUID, description string) (int64, error) {
+	return r.settleLoanHolds(tx, loanID, models.WalletTransactionRelease, description)
+}
+
+// settleLoanHolds appends a release or capture entry for each open hold of the loan's investments
+func (r *WalletRepository) settleLoanHolds(tx *sql.Tx, loanID uuid.UUID, transactionType models.WalletTransactionType, description string) (int64, error) {
+	query := `
+		INSERT INTO wallet_transactions (id, wallet_id, investor_id, transaction_type, amount, reference_type, reference_id, hold_id, description, created_at, updated_at)
+		SELECT gen_random_uuid(), h.wallet_id, h.investor_id, $2, h.amount, 'loan', $1, h.id, $3, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+		FROM wallet_transactions h
+		INNER JOIN investments i ON h.reference_type = 'investment' AND h.reference_id = i.id
+		WHERE i.loan_id = $1
+		AND h.transaction_type = 'hold'
+		AND NOT EXISTS (SELECT 1 FROM wallet_transactions s WHERE s.hold_id = h.id)
+	`
+
+	var result sql.Result
+	var err error
+	if tx != nil {
+		result, err = tx.Exec(query, loanID, transactionType, description)
+	} else {
+		result, err = r.db.Exec(query, loanID, transactionType, description)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (r *WalletRepository) CreateWalletTopUp(tx *sql.Tx, topUp *models.WalletTopUp) (*models.WalletTopUp, error) {
+	if topUp.ID == uuid.Nil {
+		topUp.ID = uuid.New()
+	}
+
+	query := `INSERT INTO wallet_top_ups (id, investor_id, amount, status, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			  RETURNING created_at, updated_at`
+
+	var err error
+	if tx != nil {
+		err = tx.QueryRow(query, topUp.ID, topUp.InvestorID, topUp.Amount, topUp.Status).Scan(&topUp.CreatedAt, &topUp.UpdatedAt)
+	} else {
+		err = r.db.QueryRow(query, topUp.ID, topUp.InvestorID, topUp.Amount, topUp.Status).Scan(&topUp.CreatedAt, &topUp.UpdatedAt)
+	}
+
+	return topUp, err
+}
+
+// LockWalletTopUp gets a top up and locks it for the rest of the transaction so it is credited only once
+func (r *WalletRepository) LockWalletTopUp(tx *sql.Tx, topUpID uuid.UUID) (*models.WalletTopUp, error) {
+	query := `SELECT ` + walletTopUpColumns + `
+			  FROM wallet_top_ups WHERE id = $1 AND deleted_at IS NULL
+			  FOR UPDATE`
+
+	var row *sql.Row
+	if tx != nil {
+		row = tx.QueryRow(query, topUpID)
+	} else {
+		row = r.db.QueryRow(query, topUpID)
+	}
+
+	var topUp models.WalletTopUp
+	if err := scanWalletTopUp(row, &topUp); err != nil {
+		return nil, err
+	}
+
+	return &topUp, nil
+}
+
+// UpdateWalletTopUp persists the status and charge outcome of a top up
+func (r *WalletRepository) UpdateWalletTopUp(tx *sql.Tx, topUp *models.WalletTopUp) error {
+	query := `UPDATE wallet_top_ups
+			  SET status = $2, transaction_id = NULLIF($3, ''), failure_reason = NULLIF($4, ''), completed_at = $5,
+			      updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $1 AND deleted_at IS NULL
+			  RETURNING updated_at`
+
+	var err error
+	if tx != nil {
+		err = tx.QueryRow(query,
+			topUp.ID,
+			topUp.Status,
+			topUp.TransactionID,
+			topUp.FailureReason,
+			topUp.CompletedAt,
+		).Scan(&topUp.UpdatedAt)
+	} else {
+		err = r.db.QueryRow(query,
+			topUp.ID,
+			topUp.Status,
+			topUp.TransactionID,
+			topUp.FailureReason,
+			topUp.CompletedAt,
+		).Scan(&topUp.UpdatedAt)
+	}
+
+	return err
+}
+
+// GetWalletTopUpsByStatus gets up to limit top ups in the status that were last updated before updatedBefore,
+// oldest first
+func (r *WalletRepository) GetWalletTopUpsByStatus(status models.WalletTopUpStatus, updatedBefore time.Time, limit int) ([]*models.WalletTopUp, error) {
+	query := `SELECT ` + walletTopUpColumns + `
+			  FROM wallet_top_ups WHERE status = $1 AND updated_at < $2 AND deleted_at IS NULL
+			  ORDER BY updated_at ASC
+			  LIMIT $3`
+
+	rows, err := r.db.Query(query, status, updatedBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var topUps []*models.WalletTopUp
+	for rows.Next() {
+		var topUp models.WalletTopUp
+		if err := scanWalletTopUp(rows, &topUp); err != nil {
+			return nil, err
+		}
+		topUps = append(topUps, &topUp)
+	}
+
+	return topUps, rows.Err()
+}
+
+func scanWalletTopUp(row rowScanner, topUp *models.WalletTopUp) error {
+	return row.Scan(
+		&topUp.ID,
+		&topUp.InvestorID,
+		&topUp.Amount,
+		&topUp.Status,
+		&topUp.TransactionID,
+		&topUp.FailureReason,
+		&topUp.CompletedAt,
+		&topUp.CreatedAt,
+		&topUp.UpdatedAt,
+	)
+}
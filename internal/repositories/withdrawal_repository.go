@@ -0,0 +1,287 @@
+package repositories
+
+import (
+	"database/sql"
+	"time"
+
+	"loan-service/internal/models"
+	"loan-service/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+type WithdrawalRepository struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+func NewWithdrawalRepository(db *sql.DB, logger *logger.Logger) WithdrawalRepositoryInterface {
+	return &WithdrawalRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+const withdrawalColumns = `id, investor_id, bank_account_id, amount, status, hold_id, requires_review,
+		reviewed_by, reviewed_at, COALESCE(rejection_reason, ''), COALESCE(transaction_id, ''),
+		COALESCE(failure_reason, ''), completed_at, created_at, updated_at`
+
+func (r *WithdrawalRepository) CreateBankAccount(tx *sql.Tx, account *models.BankAccount) (*models.BankAccount, error) {
+	if account.ID == uuid.Nil {
+		account.ID = uuid.New()
+	}
+
+	query := `INSERT INTO investor_bank_accounts (id, investor_id, bank_code, account_number, account_holder_name, is_active, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			  RETURNING created_at, updated_at`
+
+	var err error
+	if tx != nil {
+		err = tx.QueryRow(query,
+			account.ID,
+			account.InvestorID,
+			account.BankCode,
+			account.AccountNumber,
+			account.AccountHolderName,
+			account.IsActive,
+		).Scan(&account.CreatedAt, &account.UpdatedAt)
+	} else {
+		err = r.db.QueryRow(query,
+			account.ID,
+			account.InvestorID,
+			account.BankCode,
+			account.AccountNumber,
+			account.AccountHolderName,
+			account.IsActive,
+		).Scan(&account.CreatedAt, &account.UpdatedAt)
+	}
+
+	return account, err
+}
+
+// GetBankAccountByID gets a bank account by ID
+func (r *WithdrawalRepository) GetBankAccountByID(bankAccountID uuid.UUID) (*models.BankAccount, error) {
+	query := `SELECT id, investor_id, bank_code, account_number, account_holder_name, is_active, created_at, updated_at
+			  FROM investor_bank_accounts WHERE id = $1 AND deleted_at IS NULL`
+
+	var account models.BankAccount
+	err := r.db.QueryRow(query, bankAccountID).Scan(
+		&account.ID,
+		&account.InvestorID,
+		&account.BankCode,
+		&account.AccountNumber,
+		&account.AccountHolderName,
+		&account.IsActive,
+		&account.CreatedAt,
+		&account.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &account, nil
+}
+
+// GetBankAccountsByInvestorID gets the bank accounts registered by an investor, newest first
+func (r *WithdrawalRepository) GetBankAccountsByInvestorID(investorID uuid.UUID) ([]*models.BankAccount, error) {
+	query := `SELECT id, investor_id, bank_code, account_number, account_holder_name, is_active, created_at, updated_at
+			  FROM investor_bank_accounts WHERE investor_id = $1 AND deleted_at IS NULL
+			  ORDER BY created_at DESC`
+
+	rows, err := r.db.Query(query, investorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []*models.BankAccount
+	for rows.Next() {
+		var account models.BankAccount
+		err := rows.Scan(
+			&account.ID,
+			&account.InvestorID,
+			&account.BankCode,
+			&account.AccountNumber,
+			&account.AccountHolderName,
+			&account.IsActive,
+			&account.CreatedAt,
+			&account.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, &account)
+	}
+
+	return accounts, rows.Err()
+}
+
+func (r *WithdrawalRepository) CreateWithdrawal(tx *sql.Tx, withdrawal *models.Withdrawal) (*models.Withdrawal, error) {
+	if withdrawal.ID == uuid.Nil {
+		withdrawal.ID = uuid.New()
+	}
+
+	query := `INSERT INTO withdrawals (id, investor_id, bank_account_id, amount, status, hold_id, requires_review, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			  RETURNING created_at, updated_at`
+
+	var err error
+	if tx != nil {
+		err = tx.QueryRow(query,
+			withdrawal.ID,
+			withdrawal.InvestorID,
+			withdrawal.BankAccountID,
+			withdrawal.Amount,
+			withdrawal.Status,
+			withdrawal.HoldID,
+			withdrawal.RequiresReview,
+		).Scan(&withdrawal.CreatedAt, &withdrawal.UpdatedAt)
+	} else {
+		err = r.db.QueryRow(query,
+			withdrawal.ID,
+			withdrawal.InvestorID,
+			withdrawal.BankAccountID,
+			withdrawal.Amount,
+			withdrawal.Status,
+			withdrawal.HoldID,
+			withdrawal.RequiresReview,
+		).Scan(&withdrawal.CreatedAt, &withdrawal.UpdatedAt)
+	}
+
+	return withdrawal, err
+}
+
+// LockWithdrawal gets a withdrawal and locks it for the rest of the transaction so it is reviewed only once
+func (r *WithdrawalRepository) LockWithdrawal(tx *sql.Tx, withdrawalID uuid.UUID) (*models.Withdrawal, error) {
+	query := `SELECT ` + withdrawalColumns + `
+			  FROM withdrawals WHERE id = $1 AND deleted_at IS NULL
+			  FOR UPDATE`
+
+	var row *sql.Row
+	if tx != nil {
+		row = tx.QueryRow(query, withdrawalID)
+	} else {
+		row = r.db.QueryRow(query, withdrawalID)
+	}
+
+	var withdrawal models.Withdrawal
+	if err := scanWithdrawal(row, &withdrawal); err != nil {
+		return nil, err
+	}
+
+	return &withdrawal, nil
+}
+
+// UpdateWithdrawal persists the status and review/payout outcome of a withdrawal
+func (r *WithdrawalRepository) UpdateWithdrawal(tx *sql.Tx, withdrawal *models.Withdrawal) error {
+	query := `UPDATE withdrawals
+			  SET status = $2, reviewed_by = $3, reviewed_at = $4, rejection_reason = NULLIF($5, ''),
+			      transaction_id = NULLIF($6, ''), failure_reason = NULLIF($7, ''), completed_at = $8,
+			      updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $1 AND deleted_at IS NULL
+			  RETURNING updated_at`
+
+	var err error
+	if tx != nil {
+		err = tx.QueryRow(query,
+			withdrawal.ID,
+			withdrawal.Status,
+			withdrawal.ReviewedBy,
+			withdrawal.ReviewedAt,
+			withdrawal.RejectionReason,
+			withdrawal.TransactionID,
+			withdrawal.FailureReason,
+			withdrawal.CompletedAt,
+		).Scan(&withdrawal.UpdatedAt)
+	} else {
+		err = r.db.QueryRow(query,
+			withdrawal.ID,
+			withdrawal.Status,
+			withdrawal.ReviewedBy,
+			withdrawal.ReviewedAt,
+			withdrawal.RejectionReason,
+			withdrawal.TransactionID,
+			withdrawal.FailureReason,
+			withdrawal.CompletedAt,
+		).Scan(&withdrawal.UpdatedAt)
+	}
+
+	return err
+}
+
+// GetWithdrawalsByInvestorID gets the withdrawals of an investor, newest first
+func (r *WithdrawalRepository) GetWithdrawalsByInvestorID(investorID uuid.UUID) ([]*models.Withdrawal, error) {
+	query := `SELECT ` + withdrawalColumns + `
+			  FROM withdrawals WHERE investor_id = $1 AND deleted_at IS NULL
+			  ORDER BY created_at DESC`
+
+	rows, err := r.db.Query(query, investorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var withdrawals []*models.Withdrawal
+	for rows.Next() {
+		var withdrawal models.Withdrawal
+		if err := scanWithdrawal(rows, &withdrawal); err != nil {
+			return nil, err
+		}
+		withdrawals = append(withdrawals, &withdrawal)
+	}
+
+	return withdrawals, rows.Err()
+}
+
+// GetWithdrawalsByStatus gets up to limit withdrawals in the status that were last updated before updatedBefore,
+// oldest first
+func (r *WithdrawalRepository) GetWithdrawalsByStatus(status models.WithdrawalStatus, updatedBefore time.Time, limit int) ([]*models.Withdrawal, error) {
+	query := `SELECT ` + withdrawalColumns + `
+			  FROM withdrawals WHERE status = $1 AND updated_at < $2 AND deleted_at IS NULL
+			  ORDER BY updated_at ASC
+			  LIMIT $3`
+
+	rows, err := r.db.Query(query, status, updatedBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var withdrawals []*models.Withdrawal
+	for rows.Next() {
+		var withdrawal models.Withdrawal
+		if err := scanWithdrawal(rows, &withdrawal); err != nil {
+			return nil, err
+		}
+		withdrawals = append(withdrawals, &withdrawal)
+	}
+
+	return withdrawals, rows.Err()
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanWithdrawal scans a row selected with withdrawalColumns
+func scanWithdrawal(row rowScanner, withdrawal *models.Withdrawal) error {
+	return row.Scan(
+		&withdrawal.ID,
+		&withdrawal.InvestorID,
+		&withdrawal.BankAccountID,
+		&withdrawal.Amount,
+		&withdrawal.Status,
+		&withdrawal.HoldID,
+		&withdrawal.RequiresReview,
+		&withdrawal.ReviewedBy,
+		&withdrawal.ReviewedAt,
+		&withdrawal.RejectionReason,
+		&withdrawal.TransactionID,
+		&withdrawal.FailureReason,
+		&withdrawal.CompletedAt,
+		&withdrawal.CreatedAt,
+		&withdrawal.UpdatedAt,
+	)
+}
@@ -52,12 +52,81 @@ func SetupRoutes(r *gin.Engine, app *application.Application) {
 		loans.GET("/:loan_id", app.LoanHandler.GetLoanByID)
 		loans.POST("/:loan_id/approve", app.LoanHandler.ApproveLoan)
 		loans.POST("/:loan_id/invest", app.LoanHandler.AddInvestment)
+		loans.DELETE("/:loan_id/investments/:investment_id", app.LoanHandler.CancelInvestment)
+		loans.POST("/:loan_id/reservations", app.ReservationHandler.Reserve)
+		loans.POST("/:loan_id/reservations/:reservation_id/confirm", app.ReservationHandler.Confirm)
+		loans.POST("/:loan_id/waitlist", app.WaitlistHandler.Join)
+		loans.POST("/:loan_id/signatures", app.SignatureHandler.SendForSignature)
 		loans.POST("/:loan_id/disburse", app.LoanHandler.DisburseLoan)
+		loans.POST("/:loan_id/cancel", app.LoanHandler.CancelLoan)
+	}
+
+	// Investor routes
+	investors := api.Group("/investors")
+	{
+		investors.GET("/:investor_id/wallet", app.WalletHandler.GetWallet)
+		investors.POST("/:investor_id/wallet/top-ups", app.WalletHandler.TopUp)
+		investors.POST("/:investor_id/bank-accounts", app.WithdrawalHandler.RegisterBankAccount)
+		investors.GET("/:investor_id/bank-accounts", app.WithdrawalHandler.GetBankAccounts)
+		investors.POST("/:investor_id/withdrawals", app.WithdrawalHandler.RequestWithdrawal)
+		investors.GET("/:investor_id/withdrawals", app.WithdrawalHandler.GetWithdrawals)
+		investors.GET("/:investor_id/investment-limits", app.LoanHandler.GetInvestorLimits)
+		investors.PUT("/:investor_id/investment-limits", app.LoanHandler.SetInvestorLimits)
+		investors.GET("/:investor_id/portfolio", app.LoanHandler.GetInvestorPortfolio)
+		investors.POST("/:investor_id/auto-invest-rules", app.AutoInvestHandler.CreateRule)
+		investors.GET("/:investor_id/auto-invest-rules", app.AutoInvestHandler.GetRules)
+		investors.DELETE("/:investor_id/auto-invest-rules/:rule_id", app.AutoInvestHandler.DeactivateRule)
+	}
+
+	// Marketplace routes
+	marketplace := api.Group("/marketplace")
+	{
+		marketplace.GET("/loans", app.MarketplaceHandler.GetLoans)
+	}
+
+	// Investment routes
+	investments := api.Group("/investments")
+	{
+		investments.POST("/:investment_id/transfers", app.TransferHandler.ListInvestment)
+		investments.GET("/:investment_id/lineage", app.TransferHandler.GetLineage)
+	}
+
+	// Secondary market routes
+	secondaryMarket := api.Group("/secondary-market")
+	{
+		secondaryMarket.GET("/listings", app.TransferHandler.GetListings)
+		secondaryMarket.POST("/listings/:transfer_id/buy", app.TransferHandler.Buy)
+		secondaryMarket.POST("/listings/:transfer_id/cancel", app.TransferHandler.CancelListing)
+	}
+
+	// E-signature provider callbacks
+	signatures := api.Group("/signatures")
+	{
+		signatures.POST("/callback", app.SignatureHandler.Callback)
+	}
+
+	// Withdrawal review routes (employees)
+	withdrawals := api.Group("/withdrawals")
+	{
+		withdrawals.POST("/:withdrawal_id/approve", app.WithdrawalHandler.ApproveWithdrawal)
+		withdrawals.POST("/:withdrawal_id/reject", app.WithdrawalHandler.RejectWithdrawal)
 	}
 
 	// File upload routes
 	files := api.Group("/files")
 	{
 		files.POST("/upload", app.FileHandler.UploadFile)
+		files.POST("/bundles", app.FileHandler.UploadBundle)
+		files.POST("/:file_id/download-url", app.FileHandler.CreateDownloadURL)
+		files.GET("/:file_id/download", app.FileHandler.Download)
+		files.POST("/:file_id/versions", app.FileHandler.ReplaceFile)
+		files.GET("/:file_id/versions", app.FileHandler.GetFileVersions)
+	}
+
+	// Admin routes (employees)
+	admin := api.Group("/admin")
+	{
+		admin.GET("/email-notifications/failed", app.NotificationHandler.GetFailedEmailNotifications)
+		admin.POST("/email-notifications/:notification_id/retry", app.NotificationHandler.RetryEmailNotification)
 	}
 }
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"loan-service/internal/models"
+	"loan-service/internal/repositories"
+	"loan-service/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+type AutoInvestService struct {
+	autoInvestRepo  repositories.AutoInvestRepositoryInterface
+	loanRepo        repositories.LoanRepositoryInterface
+	reservationRepo repositories.ReservationRepositoryInterface
+	investments     InvestmentProcessor
+	logger          logger.LoggerInterface
+
+	// allocations tracks the allocations started by approvals that are still running
+	allocations sync.WaitGroup
+}
+
+func NewAutoInvestService(
+	autoInvestRepo repositories.AutoInvestRepositoryInterface,
+	loanRepo repositories.LoanRepositoryInterface,
+	reservationRepo repositories.ReservationRepositoryInterface,
+	investments InvestmentProcessor,
+	logger logger.LoggerInterface,
+) AutoInvestServiceInterface {
+	return &AutoInvestService{
+		autoInvestRepo:  autoInvestRepo,
+		loanRepo:        loanRepo,
+		reservationRepo: reservationRepo,
+		investments:     investments,
+		logger:          logger,
+	}
+}
+
+// CreateRule adds an auto-invest rule for the investor
+func (s *AutoInvestService) CreateRule(investorID uuid.UUID, req *models.CreateAutoInvestRuleRequest) (*models.AutoInvestRule, error) {
+	s.logger.Info("Creating auto-invest rule", map[string]interface{}{"investor_id": investorID, "request": req})
+
+	investor, err := s.loanRepo.GetInvestorByID(investorID)
+	if err != nil {
+		s.logger.Error("Failed to get investor by ID", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": investorID.String(),
+		})
+		return nil, err
+	}
+
+	if !investor.IsActive {
+		return nil, fmt.Errorf("investor %s is not active", investor.InvestorCode)
+	}
+
+	rule, err := s.autoInvestRepo.CreateAutoInvestRule(&models.AutoInvestRule{
+		InvestorID:    investorID,
+		MinROI:        req.MinROI,
+		MaxPrincipal:  req.MaxPrincipal,
+		Product:       req.Product,
+		MaxRiskGrade:  req.MaxRiskGrade,
+		AmountPerLoan: req.AmountPerLoan,
+		IsActive:      true,
+	})
+	if err != nil {
+		s.logger.Error("Failed to create auto-invest rule", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": investorID.String(),
+		})
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+// GetRules returns the auto-invest rules of the investor
+func (s *AutoInvestService) GetRules(investorID uuid.UUID) ([]*models.AutoInvestRule, error) {
+	rules, err := s.autoInvestRepo.GetAutoInvestRulesByInvestorID(investorID)
+	if err != nil {
+		s.logger.Error("Failed to get auto-invest rules", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": investorID.String(),
+		})
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// DeactivateRule stops a rule from taking part in future allocations
+func (s *AutoInvestService) DeactivateRule(investorID, ruleID uuid.UUID) error {
+	if err := s.autoInvestRepo.DeactivateAutoInvestRule(investorID, ruleID); err != nil {
+		s.logger.Error("Failed to deactivate auto-invest rule", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": investorID.String(),
+			"rule_id":     ruleID.String(),
+		})
+		return err
+	}
+
+	return nil
+}
+
+// OnLoanApproved starts the allocation in the background once the approval has committed, so the approver does not
+// wait for every matching rule to invest; failures are logged, never surfaced to the approver
+func (s *AutoInvestService) OnLoanApproved(loanID uuid.UUID) {
+	s.allocations.Add(1)
+	go func() {
+		defer s.allocations.Done()
+		defer func() {
+			if p := recover(); p != nil {
+				s.logger.Error("Auto-invest allocation panicked", map[string]interface{}{
+					"panic":   p,
+					"loan_id": loanID.String(),
+				})
+			}
+		}()
+
+		if _, err := s.AllocateLoan(loanID); err != nil {
+			s.logger.Error("Auto-invest allocation failed", map[string]interface{}{
+				"error":   err.Error(),
+				"loan_id": loanID.String(),
+			})
+		}
+	}()
+}
+
+// Wait blocks until the allocations started by approvals have finished
+func (s *AutoInvestService) Wait() {
+	s.allocations.Wait()
+}
+
+// AllocateLoan invests in the loan on behalf of every matching rule, in rotation order, until the loan is fully funded.
+// Each investor takes part once per loan and each investment goes through the regular investment path,
+// so wallet balance and investor limits still apply. Tickets never take principal held by active reservations.
+func (s *AutoInvestService) AllocateLoan(loanID uuid.UUID) ([]*models.InvestmentResponse, error) {
+	loan, err := s.loanRepo.GetLoanByID(nil, loanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get loan: %w", err)
+	}
+
+	if loan.State != models.LoanStateApproved {
+		return nil, nil
+	}
+
+	rules, err := s.autoInvestRepo.GetActiveAutoInvestRules()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auto-invest rules: %w", err)
+	}
+
+	remaining := loan.RemainingInvestmentAmount()
+	allocated := make(map[uuid.UUID]bool)
+	var investments []*models.InvestmentResponse
+
+	for _, rule := range rules {
+		if remaining <= 0 {
+			break
+		}
+
+		if allocated[rule.InvestorID] || !rule.Matches(loan) {
+			continue
+		}
+
+		// Reservations come and go while the loan is allocated, so they are read again for every ticket
+		unreserved, err := s.unreservedAmount(loanID, remaining)
+		if err != nil || unreserved <= 0 {
+			break
+		}
+
+		amount := math.Min(rule.AmountPerLoan, unreserved)
+		investment, err := s.investments.ProcessInvestment(loanID, &models.CreateInvestmentRequest{
+			LoanID:         loanID,
+			InvestorID:     rule.InvestorID,
+			Amount:         amount,
+			InvestmentDate: time.Now(),
+		})
+		if err != nil {
+			s.logger.Warn("Auto-invest rule skipped", map[string]interface{}{
+				"error":       err.Error(),
+				"rule_id":     rule.ID.String(),
+				"investor_id": rule.InvestorID.String(),
+				"loan_id":     loanID.String(),
+			})
+			continue
+		}
+
+		allocated[rule.InvestorID] = true
+		remaining -= amount
+		investments = append(investments, investment)
+
+		if err := s.autoInvestRepo.MarkAutoInvestRuleAllocated(rule.ID, time.Now()); err != nil {
+			s.logger.Error("Failed to rotate auto-invest rule", map[string]interface{}{
+				"error":   err.Error(),
+				"rule_id": rule.ID.String(),
+			})
+		}
+	}
+
+	s.logger.Info("Auto-invest allocation completed", map[string]interface{}{
+		"loan_id":     loanID.String(),
+		"investments": len(investments),
+		"remaining":   remaining,
+	})
+
+	return investments, nil
+}
+
+// unreservedAmount is the part of the remaining amount that is not held by active reservations
+func (s *AutoInvestService) unreservedAmount(loanID uuid.UUID, remaining float64) (float64, error) {
+	if s.reservationRepo == nil {
+		return remaining, nil
+	}
+
+	reserved, err := s.reservationRepo.GetReservedAmount(loanID)
+	if err != nil {
+		s.logger.Error("Failed to get reserved amount", map[string]interface{}{
+			"error":   err.Error(),
+			"loan_id": loanID.String(),
+		})
+		return 0, err
+	}
+
+	return remaining - reserved, nil
+}
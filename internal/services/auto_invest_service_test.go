@@ -0,0 +1,245 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"loan-service/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAutoInvestRepository struct {
+	mock.Mock
+}
+
+func (m *MockAutoInvestRepository) CreateAutoInvestRule(rule *models.AutoInvestRule) (*models.AutoInvestRule, error) {
+	args := m.Called(rule)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AutoInvestRule), args.Error(1)
+}
+
+func (m *MockAutoInvestRepository) GetAutoInvestRulesByInvestorID(investorID uuid.UUID) ([]*models.AutoInvestRule, error) {
+	args := m.Called(investorID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.AutoInvestRule), args.Error(1)
+}
+
+func (m *MockAutoInvestRepository) GetActiveAutoInvestRules() ([]*models.AutoInvestRule, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.AutoInvestRule), args.Error(1)
+}
+
+func (m *MockAutoInvestRepository) DeactivateAutoInvestRule(investorID, ruleID uuid.UUID) error {
+	args := m.Called(investorID, ruleID)
+	return args.Error(0)
+}
+
+func (m *MockAutoInvestRepository) MarkAutoInvestRuleAllocated(ruleID uuid.UUID, allocatedAt time.Time) error {
+	args := m.Called(ruleID, allocatedAt)
+	return args.Error(0)
+}
+
+type MockInvestmentProcessor struct {
+	mock.Mock
+}
+
+func (m *MockInvestmentProcessor) ProcessInvestment(loanID uuid.UUID, req *models.CreateInvestmentRequest) (*models.InvestmentResponse, error) {
+	args := m.Called(loanID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.InvestmentResponse), args.Error(1)
+}
+
+func setupTestAutoInvestService() (*AutoInvestService, *MockAutoInvestRepository, *MockLoanRepository, *MockInvestmentProcessor) {
+	mockAutoInvest := &MockAutoInvestRepository{}
+	mockRepo := &MockLoanRepository{}
+	mockInvestments := &MockInvestmentProcessor{}
+
+	service := NewAutoInvestService(mockAutoInvest, mockRepo, nil, mockInvestments, &TestLogger{}).(*AutoInvestService)
+
+	return service, mockAutoInvest, mockRepo, mockInvestments
+}
+
+func createTestAutoInvestRule(investorID uuid.UUID, amountPerLoan float64) *models.AutoInvestRule {
+	return &models.AutoInvestRule{
+		BaseModel:     models.BaseModel{ID: uuid.New()},
+		InvestorID:    investorID,
+		AmountPerLoan: amountPerLoan,
+		IsActive:      true,
+	}
+}
+
+// expectInvestment accepts an investment of the given amount by the investor
+func expectInvestment(mockInvestments *MockInvestmentProcessor, loanID, investorID uuid.UUID, amount float64) *mock.Call {
+	return mockInvestments.On("ProcessInvestment", loanID, mock.MatchedBy(func(req *models.CreateInvestmentRequest) bool {
+		return req.InvestorID == investorID && req.Amount == amount
+	}))
+}
+
+func TestAutoInvestService_AllocateLoan_RotatesAndRespectsRemaining(t *testing.T) {
+	service, mockAutoInvest, mockRepo, mockInvestments := setupTestAutoInvestService()
+
+	loanID := uuid.New()
+	loan := createTestLoan(loanID, models.LoanStateApproved, 2000.0) // 8000 remaining
+
+	investorA := uuid.New()
+	investorB := uuid.New()
+	investorC := uuid.New()
+	ruleA := createTestAutoInvestRule(investorA, 5000.0)
+	ruleA2 := createTestAutoInvestRule(investorA, 1000.0) // same investor, skipped for this loan
+	ruleB := createTestAutoInvestRule(investorB, 5000.0)  // only 3000 left by now
+	ruleC := createTestAutoInvestRule(investorC, 1000.0)  // loan fully funded before its turn
+
+	mockRepo.On("GetLoanByID", (*sql.Tx)(nil), loanID).Return(loan, nil)
+	mockAutoInvest.On("GetActiveAutoInvestRules").Return([]*models.AutoInvestRule{ruleA, ruleA2, ruleB, ruleC}, nil)
+	expectInvestment(mockInvestments, loanID, investorA, 5000.0).Return(&models.InvestmentResponse{Amount: 5000.0}, nil)
+	expectInvestment(mockInvestments, loanID, investorB, 3000.0).Return(&models.InvestmentResponse{Amount: 3000.0}, nil)
+	mockAutoInvest.On("MarkAutoInvestRuleAllocated", ruleA.ID, mock.AnythingOfType("time.Time")).Return(nil)
+	mockAutoInvest.On("MarkAutoInvestRuleAllocated", ruleB.ID, mock.AnythingOfType("time.Time")).Return(nil)
+
+	investments, err := service.AllocateLoan(loanID)
+
+	assert.NoError(t, err)
+	assert.Len(t, investments, 2)
+
+	mockInvestments.AssertExpectations(t)
+	mockAutoInvest.AssertExpectations(t)
+	mockInvestments.AssertNumberOfCalls(t, "ProcessInvestment", 2)
+	mockAutoInvest.AssertNotCalled(t, "MarkAutoInvestRuleAllocated", ruleC.ID, mock.Anything)
+}
+
+func TestAutoInvestService_AllocateLoan_LeavesReservedAmount(t *testing.T) {
+	service, mockAutoInvest, mockRepo, mockInvestments := setupTestAutoInvestService()
+	mockReservations := &MockReservationRepository{}
+	service.reservationRepo = mockReservations
+
+	loanID := uuid.New()
+	loan := createTestLoan(loanID, models.LoanStateApproved, 2000.0) // 8000 remaining, 2000 of it reserved
+
+	investorA := uuid.New()
+	investorB := uuid.New()
+	investorC := uuid.New()
+	ruleA := createTestAutoInvestRule(investorA, 5000.0)
+	ruleB := createTestAutoInvestRule(investorB, 5000.0) // only 1000 unreserved left by now
+	ruleC := createTestAutoInvestRule(investorC, 1000.0) // nothing unreserved left
+
+	mockRepo.On("GetLoanByID", (*sql.Tx)(nil), loanID).Return(loan, nil)
+	mockAutoInvest.On("GetActiveAutoInvestRules").Return([]*models.AutoInvestRule{ruleA, ruleB, ruleC}, nil)
+	mockReservations.On("GetReservedAmount", loanID).Return(2000.0, nil)
+	expectInvestment(mockInvestments, loanID, investorA, 5000.0).Return(&models.InvestmentResponse{Amount: 5000.0}, nil)
+	expectInvestment(mockInvestments, loanID, investorB, 1000.0).Return(&models.InvestmentResponse{Amount: 1000.0}, nil)
+	mockAutoInvest.On("MarkAutoInvestRuleAllocated", ruleA.ID, mock.AnythingOfType("time.Time")).Return(nil)
+	mockAutoInvest.On("MarkAutoInvestRuleAllocated", ruleB.ID, mock.AnythingOfType("time.Time")).Return(nil)
+
+	investments, err := service.AllocateLoan(loanID)
+
+	assert.NoError(t, err)
+	assert.Len(t, investments, 2)
+	mockInvestments.AssertExpectations(t)
+	mockInvestments.AssertNumberOfCalls(t, "ProcessInvestment", 2)
+	mockAutoInvest.AssertNotCalled(t, "MarkAutoInvestRuleAllocated", ruleC.ID, mock.Anything)
+}
+
+func TestAutoInvestService_AllocateLoan_SkipsFailedAndNonMatchingRules(t *testing.T) {
+	service, mockAutoInvest, mockRepo, mockInvestments := setupTestAutoInvestService()
+
+	loanID := uuid.New()
+	loan := createTestLoan(loanID, models.LoanStateApproved, 0.0)
+
+	broke := createTestAutoInvestRule(uuid.New(), 2000.0)
+	picky := createTestAutoInvestRule(uuid.New(), 2000.0)
+	picky.MinROI = 0.20 // loan ROI is 0.15
+	funded := createTestAutoInvestRule(uuid.New(), 2000.0)
+
+	mockRepo.On("GetLoanByID", (*sql.Tx)(nil), loanID).Return(loan, nil)
+	mockAutoInvest.On("GetActiveAutoInvestRules").Return([]*models.AutoInvestRule{broke, picky, funded}, nil)
+	expectInvestment(mockInvestments, loanID, broke.InvestorID, 2000.0).Return(nil, errors.New("insufficient wallet balance"))
+	expectInvestment(mockInvestments, loanID, funded.InvestorID, 2000.0).Return(&models.InvestmentResponse{Amount: 2000.0}, nil)
+	mockAutoInvest.On("MarkAutoInvestRuleAllocated", funded.ID, mock.AnythingOfType("time.Time")).Return(nil)
+
+	investments, err := service.AllocateLoan(loanID)
+
+	assert.NoError(t, err)
+	assert.Len(t, investments, 1)
+
+	mockInvestments.AssertExpectations(t)
+	mockAutoInvest.AssertNotCalled(t, "MarkAutoInvestRuleAllocated", broke.ID, mock.Anything)
+}
+
+func TestAutoInvestService_OnLoanApproved_AllocatesInBackground(t *testing.T) {
+	service, mockAutoInvest, mockRepo, mockInvestments := setupTestAutoInvestService()
+
+	loanID := uuid.New()
+	rule := createTestAutoInvestRule(uuid.New(), 2000.0)
+	release := make(chan time.Time)
+
+	mockRepo.On("GetLoanByID", (*sql.Tx)(nil), loanID).Return(createTestLoan(loanID, models.LoanStateApproved, 0.0), nil)
+	mockAutoInvest.On("GetActiveAutoInvestRules").Return([]*models.AutoInvestRule{rule}, nil)
+	expectInvestment(mockInvestments, loanID, rule.InvestorID, 2000.0).WaitUntil(release).Return(&models.InvestmentResponse{Amount: 2000.0}, nil)
+	mockAutoInvest.On("MarkAutoInvestRuleAllocated", rule.ID, mock.AnythingOfType("time.Time")).Return(nil)
+
+	// The approval returns while the investment is still being processed
+	service.OnLoanApproved(loanID)
+	mockAutoInvest.AssertNotCalled(t, "MarkAutoInvestRuleAllocated", rule.ID, mock.Anything)
+
+	close(release)
+	service.Wait()
+
+	mockInvestments.AssertExpectations(t)
+	mockAutoInvest.AssertExpectations(t)
+}
+
+func TestAutoInvestService_OnLoanApproved_LogsFailure(t *testing.T) {
+	service, _, mockRepo, mockInvestments := setupTestAutoInvestService()
+
+	loanID := uuid.New()
+	mockRepo.On("GetLoanByID", (*sql.Tx)(nil), loanID).Return(nil, errors.New("database error"))
+
+	service.OnLoanApproved(loanID)
+	service.Wait()
+
+	mockRepo.AssertExpectations(t)
+	mockInvestments.AssertNotCalled(t, "ProcessInvestment", mock.Anything, mock.Anything)
+}
+
+func TestAutoInvestRule_Matches(t *testing.T) {
+	maxPrincipal := 5000.0
+	loan := createTestLoan(uuid.New(), models.LoanStateApproved, 0.0)
+	loan.Product = "working_capital"
+	loan.Borrower.RiskGrade = "B"
+
+	tests := []struct {
+		name    string
+		rule    models.AutoInvestRule
+		matches bool
+	}{
+		{"no criteria", models.AutoInvestRule{IsActive: true}, true},
+		{"inactive", models.AutoInvestRule{}, false},
+		{"roi floor met", models.AutoInvestRule{IsActive: true, MinROI: 0.15}, true},
+		{"roi floor missed", models.AutoInvestRule{IsActive: true, MinROI: 0.16}, false},
+		{"principal too large", models.AutoInvestRule{IsActive: true, MaxPrincipal: &maxPrincipal}, false},
+		{"other product", models.AutoInvestRule{IsActive: true, Product: "invoice"}, false},
+		{"same product", models.AutoInvestRule{IsActive: true, Product: "working_capital"}, true},
+		{"grade within limit", models.AutoInvestRule{IsActive: true, MaxRiskGrade: "C"}, true},
+		{"grade too risky", models.AutoInvestRule{IsActive: true, MaxRiskGrade: "A"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.matches, tt.rule.Matches(loan))
+		})
+	}
+}
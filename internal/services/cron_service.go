@@ -16,28 +16,49 @@ import (
 )
 
 type CronService struct {
-	loanRepo     repositories.LoanRepositoryInterface
-	emailAdapter adapters.EmailAdapterInterface
-	logger       *logger.Logger
-	db           *sql.DB
-	cron         *cron.Cron
-	config       *config.Config
+	loanRepo              repositories.LoanRepositoryInterface
+	loanService           LoanServiceInterface
+	reconciliationService ReconciliationServiceInterface
+	waitlistService       WaitlistServiceInterface
+	fileService           FileServiceInterface
+	notificationService   NotificationServiceInterface
+	withdrawalService     WithdrawalServiceInterface
+	walletService         WalletServiceInterface
+	emailAdapter          adapters.EmailAdapterInterface
+	logger                *logger.Logger
+	db                    *sql.DB
+	cron                  *cron.Cron
+	config                *config.Config
 }
 
 func NewCronService(
 	loanRepo repositories.LoanRepositoryInterface,
+	loanService LoanServiceInterface,
+	reconciliationService ReconciliationServiceInterface,
+	waitlistService WaitlistServiceInterface,
+	fileService FileServiceInterface,
+	notificationService NotificationServiceInterface,
+	withdrawalService WithdrawalServiceInterface,
+	walletService WalletServiceInterface,
 	emailAdapter adapters.EmailAdapterInterface,
 	logger *logger.Logger,
 	db *sql.DB,
 	config *config.Config,
 ) *CronService {
 	return &CronService{
-		loanRepo:     loanRepo,
-		emailAdapter: emailAdapter,
-		logger:       logger,
-		db:           db,
-		cron:         cron.New(cron.WithSeconds()),
-		config:       config,
+		loanRepo:              loanRepo,
+		loanService:           loanService,
+		reconciliationService: reconciliationService,
+		waitlistService:       waitlistService,
+		fileService:           fileService,
+		notificationService:   notificationService,
+		withdrawalService:     withdrawalService,
+		walletService:         walletService,
+		emailAdapter:          emailAdapter,
+		logger:                logger,
+		db:                    db,
+		cron:                  cron.New(cron.WithSeconds()),
+		config:                config,
 	}
 }
 
@@ -63,16 +84,149 @@ func (s *CronService) Start() {
 		return
 	}
 
+	// Schedule daily settlement reconciliation job using configuration
+	reconciliationSchedule := s.config.Cron.ReconciliationSchedule
+	if reconciliationSchedule == "" {
+		reconciliationSchedule = "0 0 1 * * *" // Default fallback, daily at 01:00
+		s.logger.Warn("Using default cron schedule for settlement reconciliation", map[string]interface{}{
+			"schedule": reconciliationSchedule,
+		})
+	}
+
+	_, err = s.cron.AddFunc(reconciliationSchedule, s.processDailyReconciliation)
+	if err != nil {
+		s.logger.Error("Failed to schedule settlement reconciliation job", map[string]interface{}{
+			"error":    err.Error(),
+			"schedule": reconciliationSchedule,
+		})
+		return
+	}
+
+	// Schedule loan funding expiry job using configuration
+	loanExpirySchedule := s.config.Cron.LoanExpirySchedule
+	if loanExpirySchedule == "" {
+		loanExpirySchedule = "0 0 * * * *" // Default fallback, hourly
+		s.logger.Warn("Using default cron schedule for loan expiry", map[string]interface{}{
+			"schedule": loanExpirySchedule,
+		})
+	}
+
+	_, err = s.cron.AddFunc(loanExpirySchedule, s.processLoanExpiry)
+	if err != nil {
+		s.logger.Error("Failed to schedule loan expiry job", map[string]interface{}{
+			"error":    err.Error(),
+			"schedule": loanExpirySchedule,
+		})
+		return
+	}
+
+	// Schedule waitlist job using configuration; it picks up capacity freed by expired reservations
+	waitlistSchedule := s.config.Cron.WaitlistSchedule
+	if waitlistSchedule == "" {
+		waitlistSchedule = "0 * * * * *" // Default fallback, every minute
+		s.logger.Warn("Using default cron schedule for loan waitlists", map[string]interface{}{
+			"schedule": waitlistSchedule,
+		})
+	}
+
+	_, err = s.cron.AddFunc(waitlistSchedule, s.processWaitlists)
+	if err != nil {
+		s.logger.Error("Failed to schedule loan waitlist job", map[string]interface{}{
+			"error":    err.Error(),
+			"schedule": waitlistSchedule,
+		})
+		return
+	}
+
+	// Schedule malware scan job using configuration; it retries files the scanner could not check at upload
+	fileScanSchedule := s.config.Cron.FileScanSchedule
+	if fileScanSchedule == "" {
+		fileScanSchedule = "30 * * * * *" // Default fallback, every minute
+		s.logger.Warn("Using default cron schedule for file scans", map[string]interface{}{
+			"schedule": fileScanSchedule,
+		})
+	}
+
+	_, err = s.cron.AddFunc(fileScanSchedule, s.processFileScans)
+	if err != nil {
+		s.logger.Error("Failed to schedule file scan job", map[string]interface{}{
+			"error":    err.Error(),
+			"schedule": fileScanSchedule,
+		})
+		return
+	}
+
+	// Schedule email retry job using configuration; it resends emails whose earlier attempts failed
+	emailRetrySchedule := s.config.Cron.EmailRetrySchedule
+	if emailRetrySchedule == "" {
+		emailRetrySchedule = "15 * * * * *" // Default fallback, every minute
+		s.logger.Warn("Using default cron schedule for email retries", map[string]interface{}{
+			"schedule": emailRetrySchedule,
+		})
+	}
+
+	_, err = s.cron.AddFunc(emailRetrySchedule, s.processEmailRetries)
+	if err != nil {
+		s.logger.Error("Failed to schedule email retry job", map[string]interface{}{
+			"error":    err.Error(),
+			"schedule": emailRetrySchedule,
+		})
+		return
+	}
+
+	// Schedule payout resume job using configuration; it settles withdrawals left processing after their payout
+	payoutResumeSchedule := s.config.Cron.PayoutResumeSchedule
+	if payoutResumeSchedule == "" {
+		payoutResumeSchedule = "45 * * * * *" // Default fallback, every minute
+		s.logger.Warn("Using default cron schedule for payout resumes", map[string]interface{}{
+			"schedule": payoutResumeSchedule,
+		})
+	}
+
+	_, err = s.cron.AddFunc(payoutResumeSchedule, s.processPayoutResumes)
+	if err != nil {
+		s.logger.Error("Failed to schedule payout resume job", map[string]interface{}{
+			"error":    err.Error(),
+			"schedule": payoutResumeSchedule,
+		})
+		return
+	}
+
+	// Schedule top up resume job using configuration; it credits top ups left charged after their payment
+	topUpResumeSchedule := s.config.Cron.TopUpResumeSchedule
+	if topUpResumeSchedule == "" {
+		topUpResumeSchedule = "50 * * * * *" // Default fallback, every minute
+		s.logger.Warn("Using default cron schedule for top up resumes", map[string]interface{}{
+			"schedule": topUpResumeSchedule,
+		})
+	}
+
+	_, err = s.cron.AddFunc(topUpResumeSchedule, s.processTopUpResumes)
+	if err != nil {
+		s.logger.Error("Failed to schedule top up resume job", map[string]interface{}{
+			"error":    err.Error(),
+			"schedule": topUpResumeSchedule,
+		})
+		return
+	}
+
 	s.cron.Start()
 	s.logger.Info("Cron service started successfully", map[string]interface{}{
 		"investment_agreement_schedule": schedule,
+		"reconciliation_schedule":       reconciliationSchedule,
+		"loan_expiry_schedule":          loanExpirySchedule,
+		"waitlist_schedule":             waitlistSchedule,
+		"file_scan_schedule":            fileScanSchedule,
+		"email_retry_schedule":          emailRetrySchedule,
+		"payout_resume_schedule":        payoutResumeSchedule,
+		"top_up_resume_schedule":        topUpResumeSchedule,
 	})
 }
 
-// Stop stops the cron service
+// Stop stops scheduling jobs and waits for the running ones to finish
 func (s *CronService) Stop() {
 	s.logger.Info("Stopping cron service", map[string]interface{}{})
-	s.cron.Stop()
+	<-s.cron.Stop().Done()
 }
 
 // processInvestmentAgreements processes investments that need agreement emails sent
@@ -123,6 +277,109 @@ func (s *CronService) processInvestmentAgreements() {
 	})
 }
 
+// processDailyReconciliation reconciles the previous day's settlement report against recorded payments
+func (s *CronService) processDailyReconciliation() {
+	reportDate := time.Now().AddDate(0, 0, -1)
+
+	s.logger.Info("Starting settlement reconciliation job", map[string]interface{}{
+		"report_date": reportDate.Format("2006-01-02"),
+	})
+
+	run, err := s.reconciliationService.RunReconciliation(reportDate, "")
+	if err != nil {
+		s.logger.Error("Settlement reconciliation job failed", map[string]interface{}{
+			"report_date": reportDate.Format("2006-01-02"),
+			"error":       err.Error(),
+		})
+		return
+	}
+
+	s.logger.Info("Settlement reconciliation job completed", map[string]interface{}{
+		"run_id":         run.ID.String(),
+		"mismatch_count": run.MismatchCount,
+	})
+}
+
+// processWaitlists serves the loan waitlists with any capacity freed since the last run
+func (s *CronService) processWaitlists() {
+	if err := s.waitlistService.ProcessWaitlists(); err != nil {
+		s.logger.Error("Loan waitlist job failed", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
+
+// processFileScans scans the uploaded files still quarantined without a verdict
+func (s *CronService) processFileScans() {
+	if err := s.fileService.ScanPendingFiles(); err != nil {
+		s.logger.Error("File scan job failed", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
+
+// processEmailRetries resends the emails whose next attempt is due
+func (s *CronService) processEmailRetries() {
+	if err := s.notificationService.RetryDueEmails(); err != nil {
+		s.logger.Error("Email retry job failed", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
+
+// processPayoutResumes settles the withdrawals whose payout was sent but not settled
+func (s *CronService) processPayoutResumes() {
+	if err := s.withdrawalService.ResumeProcessingWithdrawals(); err != nil {
+		s.logger.Error("Payout resume job failed", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
+
+// processTopUpResumes credits the top ups whose payment went through but was not credited
+func (s *CronService) processTopUpResumes() {
+	if err := s.walletService.ResumeChargedTopUps(); err != nil {
+		s.logger.Error("Top up resume job failed", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
+
+// processLoanExpiry expires approved loans that did not reach their principal within the funding period
+func (s *CronService) processLoanExpiry() {
+	fundingPeriod := s.config.Loan.FundingPeriod
+	if fundingPeriod <= 0 {
+		s.logger.Debug("Loan funding period not configured, skipping loan expiry job", map[string]interface{}{})
+		return
+	}
+
+	loanIDs, err := s.loanRepo.GetLoansPastFundingDeadline(time.Now().Add(-fundingPeriod))
+	if err != nil {
+		s.logger.Error("Failed to get loans past funding deadline", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if len(loanIDs) == 0 {
+		return
+	}
+
+	s.logger.Info("Found loans past funding deadline", map[string]interface{}{
+		"count": len(loanIDs),
+	})
+
+	for _, loanID := range loanIDs {
+		if err := s.loanService.ProcessExpireLoan(loanID); err != nil {
+			s.logger.Error("Failed to expire loan", map[string]interface{}{
+				"loan_id": loanID.String(),
+				"error":   err.Error(),
+			})
+			continue
+		}
+	}
+}
+
 // processInvestmentsForLoan processes all investments for a single loan
 func (s *CronService) processInvestmentsForLoan(loanID uuid.UUID, investments []*models.Investment) error {
 	s.logger.Info("Processing investments for loan", map[string]interface{}{
@@ -171,52 +428,41 @@ func (s *CronService) processSendingEmail(investment *models.Investment, loan *m
 		return fmt.Errorf("failed to get investor: %w", err)
 	}
 
-	// Prepare email content
-	subject := fmt.Sprintf("Investment Agreement - Loan #%s", loan.ID.String()[:8])
-	body := s.emailAdapter.GenerateAgreementEmailBody(investment, loan, investor, borrower)
-
-	// Send email
-	if err := s.emailAdapter.SendEmail(investor.Email, subject, body); err != nil {
-		s.logger.Error("Failed to send agreement email", map[string]interface{}{
-			"investment_id":  investment.ID.String(),
-			"investor_email": investor.Email,
-			"error":          err.Error(),
-		})
-		return fmt.Errorf("failed to send email: %w", err)
-	}
-
-	// Update investment to mark agreement as sent
-	now := time.Now()
-	if err := s.loanRepo.UpdateInvestmentAgreementSent(investment.ID, true, &now); err != nil {
-		s.logger.Error("Failed to update investment agreement sent status", map[string]interface{}{
-			"investment_id": investment.ID.String(),
-			"error":         err.Error(),
-		})
-		return fmt.Errorf("failed to update investment: %w", err)
+	// Prepare email content in the investor's locale
+	// Stored agreements are only served through signed links
+	signedInvestment := *investment
+	signedInvestment.AgreementURL = s.fileService.SignFileURL(investment.AgreementURL)
+	signedLoan := *loan
+	signedLoan.AgreementLetterURL = s.fileService.SignFileURL(loan.AgreementLetterURL)
+	message, err := s.emailAdapter.GenerateAgreementEmail(&signedInvestment, &signedLoan, investor, borrower)
+	if err != nil {
+		return fmt.Errorf("failed to render email: %w", err)
 	}
 
-	// Record email notification
+	// Send email; attempts that fail are retried by the email retry job
 	notification := &models.EmailNotification{
-		BaseModel: models.BaseModel{
-			ID:        uuid.New(),
-			CreatedAt: now,
-			UpdatedAt: now,
-		},
 		InvestorID:   investment.InvestorID,
 		LoanID:       investment.LoanID,
 		EmailType:    "agreement_notification",
-		EmailSubject: subject,
-		EmailBody:    body,
-		SentAt:       now,
-		Status:       "sent",
+		Recipient:    investor.Email,
+		EmailSubject: message.Subject,
+		EmailBody:    message.TextBody,
+		EmailHTML:    message.HTMLBody,
+		Locale:       message.Locale,
+	}
+
+	if err := s.notificationService.SendEmail(notification); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
 	}
 
-	if err := s.loanRepo.CreateEmailNotification(notification); err != nil {
-		s.logger.Error("Failed to create email notification record", map[string]interface{}{
+	// Mark the agreement as sent; from here on its notification tracks delivery
+	now := time.Now()
+	if err := s.loanRepo.UpdateInvestmentAgreementSent(investment.ID, true, &now); err != nil {
+		s.logger.Error("Failed to update investment agreement sent status", map[string]interface{}{
 			"investment_id": investment.ID.String(),
 			"error":         err.Error(),
 		})
-		// Don't fail the entire process for notification recording failure
+		return fmt.Errorf("failed to update investment: %w", err)
 	}
 
 	s.logger.Info("Investment agreement processed successfully", map[string]interface{}{
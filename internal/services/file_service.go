@@ -0,0 +1,1000 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"loan-service/internal/constant"
+	"loan-service/internal/models"
+	"loan-service/internal/repositories"
+	"loan-service/pkg/adapters"
+	"loan-service/pkg/config"
+	"loan-service/pkg/exif"
+	"loan-service/pkg/logger"
+	"loan-service/pkg/scanner"
+	"loan-service/pkg/signedurl"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrFileNotFound     = errors.New("file not found")
+	ErrFileAccessDenied = errors.New("file access denied")
+	ErrFileQuarantined  = errors.New("file is quarantined until its malware scan reports it clean")
+	ErrVariantNotFound  = errors.New("file has no such variant")
+)
+
+const (
+	// defaultMaxUploadSize applies when no limit is configured for an entity type
+	defaultMaxUploadSize int64 = 10 << 20
+
+	defaultMaxBundleFiles = 10
+
+	// fileScanBatchSize caps the pending files one run of the scan job checks
+	fileScanBatchSize = 100
+
+	defaultDownloadURLTTL      = 15 * time.Minute
+	defaultEmailDownloadURLTTL = 7 * 24 * time.Hour
+)
+
+type FileService struct {
+	fileRepo       repositories.FileRepositoryInterface
+	loanRepo       repositories.LoanRepositoryInterface
+	transferRepo   repositories.TransferRepositoryInterface
+	fileAdapter    adapters.FileAdapterInterface
+	scanner        scanner.ScannerInterface
+	config         config.UploadConfig
+	downloadConfig config.DownloadConfig
+	signer         *signedurl.Signer
+	logger         logger.LoggerInterface
+	db             *sql.DB
+}
+
+func NewFileService(
+	fileRepo repositories.FileRepositoryInterface,
+	loanRepo repositories.LoanRepositoryInterface,
+	transferRepo repositories.TransferRepositoryInterface,
+	fileAdapter adapters.FileAdapterInterface,
+	scanner scanner.ScannerInterface,
+	cfg config.UploadConfig,
+	downloadCfg config.DownloadConfig,
+	logger logger.LoggerInterface,
+	db *sql.DB,
+) FileServiceInterface {
+	return &FileService{
+		fileRepo:       fileRepo,
+		loanRepo:       loanRepo,
+		transferRepo:   transferRepo,
+		fileAdapter:    fileAdapter,
+		scanner:        scanner,
+		config:         cfg,
+		downloadConfig: downloadCfg,
+		signer:         signedurl.NewSigner(downloadCfg.SigningSecret),
+		logger:         logger,
+		db:             db,
+	}
+}
+
+// withTransaction is a helper method to handle database transactions
+func (s *FileService) withTransaction(fn func(*sql.Tx) error) error {
+	return runInTransaction(s.db, s.logger, fn)
+}
+
+// UploadFile stores the content of an uploaded file and records it in file_uploads. The file stays quarantined until
+// the malware scan reports it clean; when the scanner cannot be reached the scan job retries it later.
+func (s *FileService) UploadFile(file *multipart.FileHeader, entityType string, entityID, uploadedBy uuid.UUID) (*models.FileUpload, error) {
+	if err := s.validateSize(file.Size, entityType); err != nil {
+		return nil, err
+	}
+
+	upload, err := s.fileAdapter.UploadFile(file, entityType)
+	if err != nil {
+		s.logger.Error("Failed to store uploaded file", map[string]interface{}{
+			"error":       err.Error(),
+			"file_name":   file.Filename,
+			"entity_type": entityType,
+		})
+		return nil, err
+	}
+
+	upload.EntityID = entityID
+	upload.UploadedBy = uploadedBy
+	upload.ScanStatus = models.FileScanStatusPending
+	s.readPhotoMetadata(file, upload)
+
+	var created *models.FileUpload
+	err = s.withTransaction(func(tx *sql.Tx) error {
+		var recordErr error
+		created, recordErr = s.recordFile(tx, upload)
+		return recordErr
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.scanFile(created)
+
+	s.logger.Info("File uploaded", map[string]interface{}{
+		"file_id":     created.ID.String(),
+		"file_path":   created.FilePath,
+		"checksum":    created.Checksum,
+		"entity_type": entityType,
+		"scan_status": created.ScanStatus,
+	})
+
+	return created, nil
+}
+
+// UploadBundle stores several uploaded files as one bundle. Either every file is validated, stored and recorded, or
+// none of them is kept.
+func (s *FileService) UploadBundle(files []*multipart.FileHeader, entityType string, entityID, uploadedBy uuid.UUID) (*models.FileBundle, error) {
+	bundle, err := s.storeBundleFiles(files, entityType, entityID, uploadedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.withTransaction(func(tx *sql.Tx) error {
+		return s.recordBundleTx(tx, bundle)
+	})
+	if err != nil {
+		s.deleteStoredFiles(bundle.Files)
+		return nil, err
+	}
+	for _, upload := range bundle.Files {
+		s.scanFile(upload)
+	}
+
+	s.logger.Info("File bundle uploaded", map[string]interface{}{
+		"bundle_id":   bundle.ID.String(),
+		"file_count":  len(bundle.Files),
+		"entity_type": entityType,
+	})
+
+	return bundle, nil
+}
+
+// storeBundleFiles checks the size of every file before storing any, and removes the files already stored when a
+// later one is rejected
+func (s *FileService) storeBundleFiles(files []*multipart.FileHeader, entityType string, entityID, uploadedBy uuid.UUID) (*models.FileBundle, error) {
+	maxFiles := s.config.MaxBundleFiles
+	if maxFiles <= 0 {
+		maxFiles = defaultMaxBundleFiles
+	}
+	if len(files) > maxFiles {
+		return nil, &models.FileValidationError{
+			Code:    models.CodeTooManyFiles,
+			Message: fmt.Sprintf("a bundle holds at most %d files, got %d", maxFiles, len(files)),
+		}
+	}
+
+	for _, file := range files {
+		if err := s.validateSize(file.Size, entityType); err != nil {
+			return nil, fmt.Errorf("%s: %w", file.Filename, err)
+		}
+	}
+
+	bundle := &models.FileBundle{
+		BaseModel:  models.BaseModel{ID: uuid.New()},
+		EntityType: entityType,
+		EntityID:   entityID,
+		CreatedBy:  uploadedBy,
+	}
+
+	for _, file := range files {
+		upload, err := s.fileAdapter.UploadFile(file, entityType)
+		if err != nil {
+			s.logger.Error("Failed to store bundled file", map[string]interface{}{
+				"error":       err.Error(),
+				"file_name":   file.Filename,
+				"entity_type": entityType,
+			})
+			s.deleteStoredFiles(bundle.Files)
+			return nil, fmt.Errorf("%s: %w", file.Filename, err)
+		}
+
+		upload.EntityID = entityID
+		upload.UploadedBy = uploadedBy
+		upload.BundleID = &bundle.ID
+		upload.ScanStatus = models.FileScanStatusPending
+		upload.FileURL = s.fileURL(upload.ID)
+		s.readPhotoMetadata(file, upload)
+		bundle.Files = append(bundle.Files, upload)
+	}
+
+	return bundle, nil
+}
+
+// readPhotoMetadata copies the EXIF capture time and GPS position of a jpeg onto its upload. Photos without them are
+// still accepted; the visit proof checks decide what missing metadata means.
+func (s *FileService) readPhotoMetadata(file *multipart.FileHeader, upload *models.FileUpload) {
+	if upload.FileType != models.FileTypeJPEG {
+		return
+	}
+
+	content, err := file.Open()
+	if err != nil {
+		s.logger.Warn("Failed to open photo for metadata", map[string]interface{}{
+			"error":     err.Error(),
+			"file_name": file.Filename,
+		})
+		return
+	}
+	defer content.Close()
+
+	// Cameras write the local time without an offset unless they know it
+	metadata, err := exif.Parse(content, time.Local)
+	if err != nil {
+		if !errors.Is(err, exif.ErrNoExif) {
+			s.logger.Warn("Failed to read photo metadata", map[string]interface{}{
+				"error":     err.Error(),
+				"file_name": file.Filename,
+			})
+		}
+		return
+	}
+
+	upload.CapturedAt = metadata.CapturedAt
+	upload.Latitude = metadata.Latitude
+	upload.Longitude = metadata.Longitude
+}
+
+func (s *FileService) recordBundleTx(tx *sql.Tx, bundle *models.FileBundle) error {
+	if _, err := s.fileRepo.CreateFileBundle(tx, bundle); err != nil {
+		s.logger.Error("Failed to create file bundle", map[string]interface{}{
+			"error":     err.Error(),
+			"bundle_id": bundle.ID.String(),
+		})
+		return err
+	}
+
+	for _, upload := range bundle.Files {
+		if _, err := s.fileRepo.CreateFileUpload(tx, upload); err != nil {
+			s.logger.Error("Failed to create file upload", map[string]interface{}{
+				"error":     err.Error(),
+				"bundle_id": bundle.ID.String(),
+				"file_path": upload.FilePath,
+			})
+			return err
+		}
+		if err := s.recordVariants(tx, upload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordVariants inserts the file_variants rows of the thumbnail and preview stored with an image
+func (s *FileService) recordVariants(tx *sql.Tx, upload *models.FileUpload) error {
+	for _, variant := range upload.Variants {
+		variant.FileUploadID = upload.ID
+		if _, err := s.fileRepo.CreateFileVariant(tx, variant); err != nil {
+			s.logger.Error("Failed to create file variant", map[string]interface{}{
+				"error":     err.Error(),
+				"file_id":   upload.ID.String(),
+				"file_path": variant.FilePath,
+			})
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteStoredFiles removes the content of files and their variants that could not be recorded
+func (s *FileService) deleteStoredFiles(uploads []*models.FileUpload) {
+	for _, upload := range uploads {
+		paths := []string{upload.FilePath}
+		for _, variant := range upload.Variants {
+			paths = append(paths, variant.FilePath)
+		}
+
+		for _, filePath := range paths {
+			if err := s.fileAdapter.DeleteFile(filePath); err != nil {
+				s.logger.Error("Failed to delete orphaned file", map[string]interface{}{
+					"error":     err.Error(),
+					"file_path": filePath,
+				})
+			}
+		}
+	}
+}
+
+// ReplaceFile uploads a new version of a document, such as a re-signed agreement, and deactivates the version it
+// replaces. Only the current version can be replaced, with a file of the same type; the new version belongs to the
+// same entity and is quarantined until its malware scan like any upload.
+func (s *FileService) ReplaceFile(fileID uuid.UUID, file *multipart.FileHeader, uploadedBy uuid.UUID) (*models.FileUpload, error) {
+	current, err := s.fileRepo.GetFileUploadByID(nil, fileID)
+	if err != nil {
+		s.logger.Error("Failed to get file upload", map[string]interface{}{
+			"error":   err.Error(),
+			"file_id": fileID.String(),
+		})
+		return nil, err
+	}
+	if current == nil {
+		return nil, ErrFileNotFound
+	}
+
+	if err := s.validateSize(file.Size, current.EntityType); err != nil {
+		return nil, err
+	}
+
+	upload, err := s.fileAdapter.UploadFile(file, current.EntityType)
+	if err != nil {
+		s.logger.Error("Failed to store replacement file", map[string]interface{}{
+			"error":       err.Error(),
+			"file_name":   file.Filename,
+			"entity_type": current.EntityType,
+		})
+		return nil, err
+	}
+
+	upload.UploadedBy = uploadedBy
+	upload.ScanStatus = models.FileScanStatusPending
+	s.readPhotoMetadata(file, upload)
+
+	var created *models.FileUpload
+	err = s.withTransaction(func(tx *sql.Tx) error {
+		var replaceErr error
+		created, replaceErr = s.replaceFileTx(tx, fileID, upload)
+		return replaceErr
+	})
+	if err != nil {
+		s.deleteStoredFiles([]*models.FileUpload{upload})
+		return nil, err
+	}
+	s.scanFile(created)
+
+	s.logger.Info("File replaced", map[string]interface{}{
+		"file_id":     created.ID.String(),
+		"replaces_id": fileID.String(),
+		"document_id": created.DocumentID.String(),
+		"version":     created.Version,
+		"scan_status": created.ScanStatus,
+	})
+
+	return created, nil
+}
+
+func (s *FileService) replaceFileTx(tx *sql.Tx, fileID uuid.UUID, upload *models.FileUpload) (*models.FileUpload, error) {
+	// Locking the current version keeps two replacements from both building on it
+	previous, err := s.fileRepo.LockFileUpload(tx, fileID)
+	if err != nil {
+		s.logger.Error("Failed to lock file upload", map[string]interface{}{
+			"error":   err.Error(),
+			"file_id": fileID.String(),
+		})
+		return nil, err
+	}
+	if previous == nil {
+		return nil, ErrFileNotFound
+	}
+	if !previous.IsActive {
+		return nil, &models.FileValidationError{
+			Code:    models.CodeFileInactive,
+			Message: fmt.Sprintf("file %s has already been replaced; only the current version can be replaced", fileID),
+		}
+	}
+	if upload.FileType != previous.FileType {
+		return nil, &models.FileValidationError{
+			Code:    models.CodeFileTypeMismatch,
+			Message: fmt.Sprintf("replacement is %s but the document is %s", upload.FileType, previous.FileType),
+		}
+	}
+
+	if err := s.fileRepo.DeactivateFileUpload(tx, previous.ID); err != nil {
+		s.logger.Error("Failed to deactivate replaced file", map[string]interface{}{
+			"error":   err.Error(),
+			"file_id": previous.ID.String(),
+		})
+		return nil, err
+	}
+
+	upload.EntityID = previous.EntityID
+	upload.BundleID = previous.BundleID
+	upload.DocumentID = previous.DocumentID
+	upload.Version = previous.Version + 1
+	upload.ReplacesID = &previous.ID
+	upload.FileURL = s.fileURL(upload.ID)
+
+	created, err := s.fileRepo.CreateFileUpload(tx, upload)
+	if err != nil {
+		s.logger.Error("Failed to create file upload", map[string]interface{}{
+			"error":     err.Error(),
+			"file_path": upload.FilePath,
+		})
+		return nil, err
+	}
+	if err := s.recordVariants(tx, upload); err != nil {
+		return nil, err
+	}
+
+	// Loans, approvals and the like link to the file by URL; they follow it to the new version, which investors can
+	// open while the replaced one is for staff only
+	if err := s.fileRepo.RepointFileURL(tx, previous.FileURL, created.FileURL); err != nil {
+		s.logger.Error("Failed to repoint records to the replacement file", map[string]interface{}{
+			"error":   err.Error(),
+			"file_id": previous.ID.String(),
+		})
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// GetFileVersions lists every version of the document a file belongs to, newest first. The history is for staff only.
+func (s *FileService) GetFileVersions(fileID, employeeID uuid.UUID) ([]*models.FileVersionResponse, error) {
+	upload, err := s.fileRepo.GetFileUploadByID(nil, fileID)
+	if err != nil {
+		s.logger.Error("Failed to get file upload", map[string]interface{}{
+			"error":   err.Error(),
+			"file_id": fileID.String(),
+		})
+		return nil, err
+	}
+	if upload == nil {
+		return nil, ErrFileNotFound
+	}
+
+	if err := s.checkAccess(upload, &models.CreateDownloadURLRequest{EmployeeID: &employeeID}); err != nil {
+		return nil, err
+	}
+
+	versions, err := s.fileRepo.GetFileVersions(upload.DocumentID)
+	if err != nil {
+		s.logger.Error("Failed to get file versions", map[string]interface{}{
+			"error":       err.Error(),
+			"document_id": upload.DocumentID.String(),
+		})
+		return nil, err
+	}
+
+	responses := make([]*models.FileVersionResponse, 0, len(versions))
+	for _, version := range versions {
+		response := &models.FileVersionResponse{
+			FileID:     version.ID,
+			DocumentID: version.DocumentID,
+			Version:    version.Version,
+			FileName:   version.FileName,
+			FileType:   version.FileType,
+			FileSize:   version.FileSize,
+			Checksum:   version.Checksum,
+			IsCurrent:  version.IsActive,
+			ScanStatus: version.ScanStatus,
+			UploadedBy: version.UploadedBy,
+			UploadedAt: version.CreatedAt,
+		}
+		if version.Uploader != nil && version.Uploader.FirstName != "" {
+			response.UploaderName = version.Uploader.FullName()
+		}
+		responses = append(responses, response)
+	}
+
+	return responses, nil
+}
+
+// StoreFile stores a document the platform generated and records it in file_uploads within the caller's transaction
+func (s *FileService) StoreFile(tx *sql.Tx, content []byte, fileName, contentType, entityType string, entityID uuid.UUID) (*models.FileUpload, error) {
+	upload, err := s.fileAdapter.StoreFile(content, fileName, contentType, entityType, entityID)
+	if err != nil {
+		s.logger.Error("Failed to store file", map[string]interface{}{
+			"error":       err.Error(),
+			"file_name":   fileName,
+			"entity_type": entityType,
+			"entity_id":   entityID.String(),
+		})
+		return nil, err
+	}
+
+	upload.UploadedBy = uuid.MustParse(constant.SystemEmployeeID)
+	upload.ScanStatus = models.FileScanStatusSkipped
+
+	return s.recordFile(tx, upload)
+}
+
+// DeleteStoredFile removes the stored content of a file whose record was rolled back with the caller's transaction
+func (s *FileService) DeleteStoredFile(upload *models.FileUpload) {
+	s.deleteStoredFiles([]*models.FileUpload{upload})
+}
+
+// ScanPendingFiles scans the quarantined files whose scan has not completed yet, such as when the scanner was
+// unreachable at upload time
+func (s *FileService) ScanPendingFiles() error {
+	uploads, err := s.fileRepo.GetFileUploadsByScanStatus(models.FileScanStatusPending, fileScanBatchSize)
+	if err != nil {
+		s.logger.Error("Failed to get files pending a malware scan", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return err
+	}
+
+	scanned := 0
+	for _, upload := range uploads {
+		if s.scanFile(upload) {
+			scanned++
+		}
+	}
+
+	if len(uploads) > 0 {
+		s.logger.Info("Scanned pending files", map[string]interface{}{
+			"pending": len(uploads),
+			"scanned": scanned,
+		})
+	}
+	return nil
+}
+
+// scanFile scans the stored content of the upload and records the verdict on it. It reports whether the scan
+// completed; on failure the file stays pending.
+func (s *FileService) scanFile(upload *models.FileUpload) bool {
+	content, err := s.fileAdapter.OpenFile(upload.FilePath)
+	if err != nil {
+		s.logger.Error("Failed to open file for malware scan", map[string]interface{}{
+			"error":     err.Error(),
+			"file_id":   upload.ID.String(),
+			"file_path": upload.FilePath,
+		})
+		return false
+	}
+	defer content.Close()
+
+	result, err := s.scanner.Scan(content)
+	if err != nil {
+		s.logger.Error("Malware scan failed", map[string]interface{}{
+			"error":   err.Error(),
+			"file_id": upload.ID.String(),
+			"scanner": s.scanner.Name(),
+		})
+		return false
+	}
+
+	status := models.FileScanStatusClean
+	if !result.Clean {
+		status = models.FileScanStatusInfected
+		s.logger.Warn("Uploaded file is infected, keeping it quarantined", map[string]interface{}{
+			"file_id":   upload.ID.String(),
+			"file_path": upload.FilePath,
+			"signature": result.Signature,
+		})
+	}
+
+	if err := s.fileRepo.UpdateFileUploadScanResult(nil, upload.ID, status, result.Signature); err != nil {
+		s.logger.Error("Failed to record malware scan result", map[string]interface{}{
+			"error":       err.Error(),
+			"file_id":     upload.ID.String(),
+			"scan_status": status,
+		})
+		return false
+	}
+
+	now := time.Now()
+	upload.ScanStatus = status
+	upload.ScanSignature = result.Signature
+	upload.ScannedAt = &now
+	return true
+}
+
+// recordFile inserts the file_uploads row of stored content and the rows of its variants, deleting the content
+// again if that fails
+func (s *FileService) recordFile(tx *sql.Tx, upload *models.FileUpload) (*models.FileUpload, error) {
+	upload.FileURL = s.fileURL(upload.ID)
+
+	created, err := s.fileRepo.CreateFileUpload(tx, upload)
+	if err != nil {
+		s.logger.Error("Failed to create file upload", map[string]interface{}{
+			"error":     err.Error(),
+			"file_path": upload.FilePath,
+		})
+
+		// Without its record nothing refers to the stored content any more
+		s.deleteStoredFiles([]*models.FileUpload{upload})
+		return nil, err
+	}
+	if err := s.recordVariants(tx, upload); err != nil {
+		s.deleteStoredFiles([]*models.FileUpload{upload})
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// AttachFile links an uploaded file to the approval or disbursement being created in the transaction. The file must
+// be active, uploaded for the entity type, of one of the allowed types and not yet linked to another entity.
+func (s *FileService) AttachFile(tx *sql.Tx, fileID uuid.UUID, entityType string, entityID uuid.UUID, allowedTypes ...models.FileType) (*models.FileUpload, error) {
+	upload, err := s.fileRepo.LockFileUpload(tx, fileID)
+	if err != nil {
+		s.logger.Error("Failed to lock file upload", map[string]interface{}{
+			"error":   err.Error(),
+			"file_id": fileID.String(),
+		})
+		return nil, err
+	}
+
+	if err := checkAttachable(upload, fileID, entityType, entityID, allowedTypes); err != nil {
+		s.logger.Warn("File cannot be attached", map[string]interface{}{
+			"error":       err.Error(),
+			"file_id":     fileID.String(),
+			"entity_type": entityType,
+			"entity_id":   entityID.String(),
+		})
+		return nil, err
+	}
+
+	if err := s.fileRepo.UpdateFileUploadEntityID(tx, fileID, entityID); err != nil {
+		s.logger.Error("Failed to link file upload", map[string]interface{}{
+			"error":     err.Error(),
+			"file_id":   fileID.String(),
+			"entity_id": entityID.String(),
+		})
+		return nil, err
+	}
+	upload.EntityID = entityID
+
+	return upload, nil
+}
+
+// AttachBundle links a bundle and its files to the approval or disbursement being created in the transaction, with
+// the same checks as AttachFile for the bundle and every file in it
+func (s *FileService) AttachBundle(tx *sql.Tx, bundleID uuid.UUID, entityType string, entityID uuid.UUID, allowedTypes ...models.FileType) (*models.FileBundle, error) {
+	bundle, err := s.fileRepo.LockFileBundle(tx, bundleID)
+	if err != nil {
+		s.logger.Error("Failed to lock file bundle", map[string]interface{}{
+			"error":     err.Error(),
+			"bundle_id": bundleID.String(),
+		})
+		return nil, err
+	}
+
+	if err := checkBundleAttachable(bundle, bundleID, entityType, entityID, allowedTypes); err != nil {
+		s.logger.Warn("File bundle cannot be attached", map[string]interface{}{
+			"error":       err.Error(),
+			"bundle_id":   bundleID.String(),
+			"entity_type": entityType,
+			"entity_id":   entityID.String(),
+		})
+		return nil, err
+	}
+
+	if err := s.fileRepo.UpdateFileBundleEntityID(tx, bundleID, entityID); err != nil {
+		s.logger.Error("Failed to link file bundle", map[string]interface{}{
+			"error":     err.Error(),
+			"bundle_id": bundleID.String(),
+			"entity_id": entityID.String(),
+		})
+		return nil, err
+	}
+	bundle.EntityID = entityID
+	for _, upload := range bundle.Files {
+		upload.EntityID = entityID
+	}
+
+	return bundle, nil
+}
+
+func checkBundleAttachable(bundle *models.FileBundle, bundleID uuid.UUID, entityType string, entityID uuid.UUID, allowedTypes []models.FileType) error {
+	if bundle == nil || len(bundle.Files) == 0 {
+		return &models.FileValidationError{
+			Code:    models.CodeFileNotFound,
+			Message: fmt.Sprintf("bundle %s does not exist", bundleID),
+		}
+	}
+	if bundle.EntityType != entityType {
+		return &models.FileValidationError{
+			Code:    models.CodeFileEntityMismatch,
+			Message: fmt.Sprintf("bundle %s was uploaded for %s, not %s", bundleID, bundle.EntityType, entityType),
+		}
+	}
+	if bundle.EntityID != uuid.Nil && bundle.EntityID != entityID {
+		return &models.FileValidationError{
+			Code:    models.CodeFileAlreadyLinked,
+			Message: fmt.Sprintf("bundle %s already belongs to another %s", bundleID, entityType),
+		}
+	}
+	for _, upload := range bundle.Files {
+		if err := checkAttachable(upload, upload.ID, entityType, entityID, allowedTypes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkAttachable(upload *models.FileUpload, fileID uuid.UUID, entityType string, entityID uuid.UUID, allowedTypes []models.FileType) error {
+	if upload == nil {
+		return &models.FileValidationError{
+			Code:    models.CodeFileNotFound,
+			Message: fmt.Sprintf("file %s does not exist", fileID),
+		}
+	}
+	if !upload.IsActive {
+		return &models.FileValidationError{
+			Code:    models.CodeFileInactive,
+			Message: fmt.Sprintf("file %s is no longer active", fileID),
+		}
+	}
+	if upload.EntityType != entityType {
+		return &models.FileValidationError{
+			Code:    models.CodeFileEntityMismatch,
+			Message: fmt.Sprintf("file %s was uploaded for %s, not %s", fileID, upload.EntityType, entityType),
+		}
+	}
+	if upload.EntityID != uuid.Nil && upload.EntityID != entityID {
+		return &models.FileValidationError{
+			Code:    models.CodeFileAlreadyLinked,
+			Message: fmt.Sprintf("file %s already belongs to another %s", fileID, entityType),
+		}
+	}
+	switch upload.ScanStatus {
+	case models.FileScanStatusClean:
+	case models.FileScanStatusInfected:
+		return &models.FileValidationError{
+			Code:    models.CodeFileInfected,
+			Message: fmt.Sprintf("file %s failed the malware scan", fileID),
+		}
+	default:
+		return &models.FileValidationError{
+			Code:    models.CodeFileNotScanned,
+			Message: fmt.Sprintf("file %s has not passed the malware scan yet", fileID),
+		}
+	}
+	if !slices.Contains(allowedTypes, upload.FileType) {
+		return &models.FileValidationError{
+			Code:    models.CodeFileTypeUnsupported,
+			Message: fmt.Sprintf("%s files cannot be attached to %s", upload.FileType, entityType),
+		}
+	}
+	return nil
+}
+
+// CreateDownloadURL signs a short-lived download URL for a caller allowed to access the entity the file belongs to
+func (s *FileService) CreateDownloadURL(fileID uuid.UUID, req *models.CreateDownloadURLRequest) (*models.FileDownloadURLResponse, error) {
+	upload, err := s.fileRepo.GetFileUploadByID(nil, fileID)
+	if err != nil {
+		s.logger.Error("Failed to get file upload", map[string]interface{}{
+			"error":   err.Error(),
+			"file_id": fileID.String(),
+		})
+		return nil, err
+	}
+	if upload == nil {
+		return nil, ErrFileNotFound
+	}
+
+	if err := s.checkAccess(upload, req); err != nil {
+		s.logger.Warn("File access denied", map[string]interface{}{
+			"error":       err.Error(),
+			"file_id":     fileID.String(),
+			"entity_type": upload.EntityType,
+			"entity_id":   upload.EntityID.String(),
+		})
+		return nil, err
+	}
+
+	if !upload.Downloadable() {
+		return nil, ErrFileQuarantined
+	}
+
+	expiresAt := time.Now().Add(durationOr(s.downloadConfig.URLTTL, defaultDownloadURLTTL))
+	url := s.signedURL(fileID, expiresAt)
+	if req.Variant != "" {
+		if _, err := s.getVariant(fileID, req.Variant); err != nil {
+			return nil, err
+		}
+		// The signature covers the file, so the variant only chooses which copy of it is served
+		url += "&variant=" + string(req.Variant)
+	}
+
+	return &models.FileDownloadURLResponse{
+		FileID:    fileID,
+		Variant:   req.Variant,
+		URL:       url,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// SignFileURL signs the URL of a stored file for links the platform hands out itself, such as in emails.
+// URLs of files not stored through this service are returned unchanged.
+func (s *FileService) SignFileURL(fileURL string) string {
+	rest, ok := strings.CutPrefix(fileURL, s.filesURL())
+	if !ok {
+		return fileURL
+	}
+	idPart, ok := strings.CutSuffix(rest, "/download")
+	if !ok {
+		return fileURL
+	}
+	fileID, err := uuid.Parse(idPart)
+	if err != nil {
+		return fileURL
+	}
+
+	return s.signedURL(fileID, time.Now().Add(durationOr(s.downloadConfig.EmailURLTTL, defaultEmailDownloadURLTTL)))
+}
+
+// OpenDownload checks a signed download URL and opens the file it grants access to, or the named variant of it. For
+// a variant the returned upload describes the variant's content.
+func (s *FileService) OpenDownload(fileID uuid.UUID, variantName models.FileVariantName, expires, signature string) (*models.FileUpload, io.ReadSeekCloser, error) {
+	if err := s.signer.Verify(fileID.String(), expires, signature, time.Now()); err != nil {
+		return nil, nil, err
+	}
+
+	upload, err := s.fileRepo.GetFileUploadByID(nil, fileID)
+	if err != nil {
+		s.logger.Error("Failed to get file upload", map[string]interface{}{
+			"error":   err.Error(),
+			"file_id": fileID.String(),
+		})
+		return nil, nil, err
+	}
+	if upload == nil {
+		return nil, nil, ErrFileNotFound
+	}
+	if !upload.Downloadable() {
+		return nil, nil, ErrFileQuarantined
+	}
+
+	if variantName != "" {
+		variant, err := s.getVariant(fileID, variantName)
+		if err != nil {
+			return nil, nil, err
+		}
+		upload = variantDownload(upload, variant)
+	}
+
+	content, err := s.fileAdapter.OpenFile(upload.FilePath)
+	if err != nil {
+		s.logger.Error("Failed to open stored file", map[string]interface{}{
+			"error":     err.Error(),
+			"file_id":   fileID.String(),
+			"file_path": upload.FilePath,
+		})
+		return nil, nil, err
+	}
+
+	return upload, content, nil
+}
+
+func (s *FileService) getVariant(fileID uuid.UUID, name models.FileVariantName) (*models.FileVariant, error) {
+	variant, err := s.fileRepo.GetFileVariant(fileID, name)
+	if err != nil {
+		s.logger.Error("Failed to get file variant", map[string]interface{}{
+			"error":   err.Error(),
+			"file_id": fileID.String(),
+			"variant": name,
+		})
+		return nil, err
+	}
+	if variant == nil {
+		return nil, ErrVariantNotFound
+	}
+	return variant, nil
+}
+
+// variantDownload describes a variant as a file of its own, named after the original
+func variantDownload(upload *models.FileUpload, variant *models.FileVariant) *models.FileUpload {
+	described := *upload
+	described.FileName = strings.TrimSuffix(upload.FileName, path.Ext(upload.FileName)) + "_" + string(variant.Variant) + ".jpg"
+	described.FileType = models.FileTypeJPEG
+	described.FilePath = variant.FilePath
+	described.FileSize = variant.FileSize
+	described.ContentType = variant.ContentType
+	described.Checksum = variant.Checksum
+	described.UpdatedAt = variant.UpdatedAt
+	described.Variants = nil
+	return &described
+}
+
+// checkAccess allows active employees every file, and investors the current version of the files of loans they
+// invested in and of their own investments and transfers
+func (s *FileService) checkAccess(upload *models.FileUpload, req *models.CreateDownloadURLRequest) error {
+	if req.EmployeeID != nil {
+		employee, err := s.loanRepo.GetEmployeeByID(*req.EmployeeID)
+		if err == sql.ErrNoRows {
+			return ErrFileAccessDenied
+		}
+		if err != nil {
+			return err
+		}
+		if !employee.IsActive {
+			return ErrFileAccessDenied
+		}
+		return nil
+	}
+
+	// Replaced versions stay available to staff only
+	if !upload.IsActive {
+		return ErrFileAccessDenied
+	}
+
+	investorID := *req.InvestorID
+	switch upload.EntityType {
+	case "loan":
+		// The combined letter names every investor in the loan, so it is only shown to investors whose investment
+		// predates the per-investor agreements and has nothing else to show
+		investments, err := s.loanRepo.GetInvestmentsByLoanID(nil, upload.EntityID)
+		if err != nil {
+			return err
+		}
+		for _, investment := range investments {
+			if investment.InvestorID == investorID && investment.AgreementURL == "" {
+				return nil
+			}
+		}
+	case "investment":
+		investment, err := s.loanRepo.GetInvestmentByID(nil, upload.EntityID)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if investment != nil && investment.InvestorID == investorID {
+			return nil
+		}
+	case "transfer":
+		transfer, err := s.transferRepo.GetInvestmentTransferByID(upload.EntityID)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if transfer != nil && (transfer.SellerID == investorID || (transfer.BuyerID != nil && *transfer.BuyerID == investorID)) {
+			return nil
+		}
+	}
+
+	// Approval proofs and disbursement files are for employees only
+	return ErrFileAccessDenied
+}
+
+func (s *FileService) filesURL() string {
+	return strings.TrimRight(s.downloadConfig.BaseURL, "/") + "/api/v1/files/"
+}
+
+// fileURL is the permanent URL of a file, which only serves it once signed
+func (s *FileService) fileURL(fileID uuid.UUID) string {
+	return s.filesURL() + fileID.String() + "/download"
+}
+
+func (s *FileService) signedURL(fileID uuid.UUID, expiresAt time.Time) string {
+	return fmt.Sprintf("%s?expires=%s&signature=%s",
+		s.fileURL(fileID),
+		signedurl.FormatExpires(expiresAt),
+		s.signer.Sign(fileID.String(), expiresAt),
+	)
+}
+
+func durationOr(value, fallback time.Duration) time.Duration {
+	if value > 0 {
+		return value
+	}
+	return fallback
+}
+
+// maxUploadSize returns the size limit for files of an entity type
+func (s *FileService) maxUploadSize(entityType string) int64 {
+	if limit := s.config.MaxSizes[entityType]; limit > 0 {
+		return limit
+	}
+	if s.config.MaxSize > 0 {
+		return s.config.MaxSize
+	}
+	return defaultMaxUploadSize
+}
+
+func (s *FileService) validateSize(size int64, entityType string) error {
+	if size <= 0 {
+		return &models.FileValidationError{
+			Code:    models.CodeFileEmpty,
+			Message: "file is empty",
+		}
+	}
+
+	if limit := s.maxUploadSize(entityType); size > limit {
+		return &models.FileValidationError{
+			Code:    models.CodeFileTooLarge,
+			Message: fmt.Sprintf("file is %d bytes, %s files may be at most %d bytes", size, entityType, limit),
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,1280 @@
+package services
+
+import (
+	"database/sql"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"loan-service/internal/constant"
+	"loan-service/internal/models"
+	"loan-service/pkg/config"
+	"loan-service/pkg/scanner"
+	"loan-service/pkg/signedurl"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockFileRepository struct {
+	mock.Mock
+}
+
+func (m *MockFileRepository) CreateFileUpload(tx *sql.Tx, upload *models.FileUpload) (*models.FileUpload, error) {
+	args := m.Called(tx, upload)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.FileUpload), args.Error(1)
+}
+
+func (m *MockFileRepository) LockFileUpload(tx *sql.Tx, id uuid.UUID) (*models.FileUpload, error) {
+	args := m.Called(tx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.FileUpload), args.Error(1)
+}
+
+func (m *MockFileRepository) UpdateFileUploadEntityID(tx *sql.Tx, id, entityID uuid.UUID) error {
+	args := m.Called(tx, id, entityID)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) UpdateFileUploadScanResult(tx *sql.Tx, id uuid.UUID, status models.FileScanStatus, signature string) error {
+	args := m.Called(tx, id, status, signature)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) GetFileUploadsByScanStatus(status models.FileScanStatus, limit int) ([]*models.FileUpload, error) {
+	args := m.Called(status, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.FileUpload), args.Error(1)
+}
+
+func (m *MockFileRepository) CreateFileBundle(tx *sql.Tx, bundle *models.FileBundle) (*models.FileBundle, error) {
+	args := m.Called(tx, bundle)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.FileBundle), args.Error(1)
+}
+
+func (m *MockFileRepository) LockFileBundle(tx *sql.Tx, id uuid.UUID) (*models.FileBundle, error) {
+	args := m.Called(tx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.FileBundle), args.Error(1)
+}
+
+func (m *MockFileRepository) UpdateFileBundleEntityID(tx *sql.Tx, id, entityID uuid.UUID) error {
+	args := m.Called(tx, id, entityID)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) CreateFileVariant(tx *sql.Tx, variant *models.FileVariant) (*models.FileVariant, error) {
+	args := m.Called(tx, variant)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.FileVariant), args.Error(1)
+}
+
+func (m *MockFileRepository) GetFileVariant(fileUploadID uuid.UUID, variant models.FileVariantName) (*models.FileVariant, error) {
+	args := m.Called(fileUploadID, variant)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.FileVariant), args.Error(1)
+}
+
+func (m *MockFileRepository) DeactivateFileUpload(tx *sql.Tx, id uuid.UUID) error {
+	args := m.Called(tx, id)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) RepointFileURL(tx *sql.Tx, oldURL, newURL string) error {
+	args := m.Called(tx, oldURL, newURL)
+	return args.Error(0)
+}
+
+func (m *MockFileRepository) GetFileVersions(documentID uuid.UUID) ([]*models.FileUpload, error) {
+	args := m.Called(documentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.FileUpload), args.Error(1)
+}
+
+func (m *MockFileRepository) GetFileUploadByID(tx *sql.Tx, id uuid.UUID) (*models.FileUpload, error) {
+	args := m.Called(tx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.FileUpload), args.Error(1)
+}
+
+type MockFileService struct {
+	mock.Mock
+}
+
+func (m *MockFileService) UploadFile(file *multipart.FileHeader, entityType string, entityID, uploadedBy uuid.UUID) (*models.FileUpload, error) {
+	args := m.Called(file, entityType, entityID, uploadedBy)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.FileUpload), args.Error(1)
+}
+
+func (m *MockFileService) StoreFile(tx *sql.Tx, content []byte, fileName, contentType, entityType string, entityID uuid.UUID) (*models.FileUpload, error) {
+	args := m.Called(tx, content, fileName, contentType, entityType, entityID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.FileUpload), args.Error(1)
+}
+
+func (m *MockFileService) DeleteStoredFile(upload *models.FileUpload) {
+	m.Called(upload)
+}
+
+func (m *MockFileService) UploadBundle(files []*multipart.FileHeader, entityType string, entityID, uploadedBy uuid.UUID) (*models.FileBundle, error) {
+	args := m.Called(files, entityType, entityID, uploadedBy)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.FileBundle), args.Error(1)
+}
+
+func (m *MockFileService) AttachBundle(tx *sql.Tx, bundleID uuid.UUID, entityType string, entityID uuid.UUID, allowedTypes ...models.FileType) (*models.FileBundle, error) {
+	args := m.Called(tx, bundleID, entityType, entityID, allowedTypes)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.FileBundle), args.Error(1)
+}
+
+func (m *MockFileService) ScanPendingFiles() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockFileService) AttachFile(tx *sql.Tx, fileID uuid.UUID, entityType string, entityID uuid.UUID, allowedTypes ...models.FileType) (*models.FileUpload, error) {
+	args := m.Called(tx, fileID, entityType, entityID, allowedTypes)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.FileUpload), args.Error(1)
+}
+
+func (m *MockFileService) CreateDownloadURL(fileID uuid.UUID, req *models.CreateDownloadURLRequest) (*models.FileDownloadURLResponse, error) {
+	args := m.Called(fileID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.FileDownloadURLResponse), args.Error(1)
+}
+
+func (m *MockFileService) SignFileURL(fileURL string) string {
+	args := m.Called(fileURL)
+	return args.String(0)
+}
+
+func (m *MockFileService) OpenDownload(fileID uuid.UUID, variant models.FileVariantName, expires, signature string) (*models.FileUpload, io.ReadSeekCloser, error) {
+	args := m.Called(fileID, variant, expires, signature)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*models.FileUpload), args.Get(1).(io.ReadSeekCloser), args.Error(2)
+}
+
+func (m *MockFileService) ReplaceFile(fileID uuid.UUID, file *multipart.FileHeader, uploadedBy uuid.UUID) (*models.FileUpload, error) {
+	args := m.Called(fileID, file, uploadedBy)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.FileUpload), args.Error(1)
+}
+
+func (m *MockFileService) GetFileVersions(fileID, employeeID uuid.UUID) ([]*models.FileVersionResponse, error) {
+	args := m.Called(fileID, employeeID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.FileVersionResponse), args.Error(1)
+}
+
+type MockScanner struct {
+	mock.Mock
+}
+
+func (m *MockScanner) Name() string {
+	return "mock"
+}
+
+func (m *MockScanner) Scan(content io.Reader) (*scanner.Result, error) {
+	args := m.Called(content)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*scanner.Result), args.Error(1)
+}
+
+// TestFileService is a test-specific version that overrides withTransaction
+type TestFileService struct {
+	*FileService
+}
+
+func (s *TestFileService) withTransaction(fn func(*sql.Tx) error) error {
+	return fn(nil)
+}
+
+func (s *TestFileService) UploadFile(file *multipart.FileHeader, entityType string, entityID, uploadedBy uuid.UUID) (*models.FileUpload, error) {
+	if err := s.validateSize(file.Size, entityType); err != nil {
+		return nil, err
+	}
+
+	upload, err := s.fileAdapter.UploadFile(file, entityType)
+	if err != nil {
+		return nil, err
+	}
+
+	upload.EntityID = entityID
+	upload.UploadedBy = uploadedBy
+	upload.ScanStatus = models.FileScanStatusPending
+	s.readPhotoMetadata(file, upload)
+
+	var created *models.FileUpload
+	err = s.withTransaction(func(tx *sql.Tx) error {
+		var recordErr error
+		created, recordErr = s.recordFile(tx, upload)
+		return recordErr
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.scanFile(created)
+
+	return created, nil
+}
+
+func (s *TestFileService) UploadBundle(files []*multipart.FileHeader, entityType string, entityID, uploadedBy uuid.UUID) (*models.FileBundle, error) {
+	bundle, err := s.storeBundleFiles(files, entityType, entityID, uploadedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.withTransaction(func(tx *sql.Tx) error {
+		return s.recordBundleTx(tx, bundle)
+	})
+	if err != nil {
+		s.deleteStoredFiles(bundle.Files)
+		return nil, err
+	}
+	for _, upload := range bundle.Files {
+		s.scanFile(upload)
+	}
+
+	return bundle, nil
+}
+
+func (s *TestFileService) ReplaceFile(fileID uuid.UUID, file *multipart.FileHeader, uploadedBy uuid.UUID) (*models.FileUpload, error) {
+	current, err := s.fileRepo.GetFileUploadByID(nil, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, ErrFileNotFound
+	}
+
+	if err := s.validateSize(file.Size, current.EntityType); err != nil {
+		return nil, err
+	}
+
+	upload, err := s.fileAdapter.UploadFile(file, current.EntityType)
+	if err != nil {
+		return nil, err
+	}
+
+	upload.UploadedBy = uploadedBy
+	upload.ScanStatus = models.FileScanStatusPending
+	s.readPhotoMetadata(file, upload)
+
+	var created *models.FileUpload
+	err = s.withTransaction(func(tx *sql.Tx) error {
+		var replaceErr error
+		created, replaceErr = s.replaceFileTx(tx, fileID, upload)
+		return replaceErr
+	})
+	if err != nil {
+		s.deleteStoredFiles([]*models.FileUpload{upload})
+		return nil, err
+	}
+	s.scanFile(created)
+
+	return created, nil
+}
+
+func setupTestFileService() (*TestFileService, *MockFileRepository, *MockFileAdapter) {
+	mockRepo := &MockFileRepository{}
+	mockFile := &MockFileAdapter{}
+
+	cfg := config.UploadConfig{
+		MaxSize:        10 << 20,
+		MaxSizes:       map[string]int64{"approval": 5 << 20},
+		MaxBundleFiles: 3,
+	}
+	downloadCfg := config.DownloadConfig{
+		BaseURL:       "http://localhost:8080",
+		SigningSecret: "test_download_signing_secret",
+	}
+	baseService := NewFileService(mockRepo, &MockLoanRepository{}, &MockTransferRepository{}, mockFile, &MockScanner{}, cfg, downloadCfg, &TestLogger{}, nil).(*FileService)
+
+	return &TestFileService{FileService: baseService}, mockRepo, mockFile
+}
+
+func createTestStoredFile() *models.FileUpload {
+	return &models.FileUpload{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		FileName:       "proof/visit_1a2b3c4d.jpg",
+		FileType:       models.FileTypeJPEG,
+		FileSize:       2048,
+		FilePath:       "proof/visit_1a2b3c4d.jpg",
+		FileURL:        "/uploads/proof/visit_1a2b3c4d.jpg",
+		ContentType:    "image/jpeg",
+		Checksum:       "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		StorageBackend: "local",
+		EntityType:     "approval",
+		IsActive:       true,
+		ScanStatus:     models.FileScanStatusClean,
+	}
+}
+
+// expectCleanScan sets up every stored file to be opened, scanned and recorded clean
+func expectCleanScan(service *TestFileService, mockRepo *MockFileRepository, mockFile *MockFileAdapter) {
+	mockFile.On("OpenFile", mock.AnythingOfType("string")).Return(&readSeekNopCloser{strings.NewReader("content")}, nil)
+	service.scanner.(*MockScanner).On("Scan", mock.Anything).Return(&scanner.Result{Clean: true}, nil)
+	mockRepo.On("UpdateFileUploadScanResult", (*sql.Tx)(nil), mock.AnythingOfType("uuid.UUID"), models.FileScanStatusClean, "").Return(nil)
+}
+
+func TestFileService_UploadFile_Success(t *testing.T) {
+	service, mockRepo, mockFile := setupTestFileService()
+
+	header := &multipart.FileHeader{Filename: "visit.jpg", Size: 2048}
+	stored := createTestStoredFile()
+	entityID := uuid.New()
+	uploadedBy := uuid.MustParse(constant.SystemEmployeeID)
+
+	mockFile.On("UploadFile", header, "approval").Return(stored, nil)
+	mockRepo.On("CreateFileUpload", (*sql.Tx)(nil), mock.MatchedBy(func(f *models.FileUpload) bool {
+		return f.EntityID == entityID && f.UploadedBy == uploadedBy && f.Checksum == stored.Checksum &&
+			f.ScanStatus == models.FileScanStatusPending
+	})).Return(stored, nil)
+	expectCleanScan(service, mockRepo, mockFile)
+
+	result, err := service.UploadFile(header, "approval", entityID, uploadedBy)
+
+	assert.NoError(t, err)
+	assert.Equal(t, stored.Checksum, result.Checksum)
+	assert.Equal(t, entityID, result.EntityID)
+	assert.Equal(t, models.FileScanStatusClean, result.ScanStatus)
+	mockRepo.AssertExpectations(t)
+	mockFile.AssertNotCalled(t, "DeleteFile", mock.Anything)
+}
+
+// createTestVariants returns the thumbnail and preview the adapter stores with an image
+func createTestVariants(upload *models.FileUpload) []*models.FileVariant {
+	return []*models.FileVariant{
+		{Variant: models.FileVariantThumbnail, FilePath: "proof/visit_1a2b3c4d_thumbnail.jpg", ContentType: "image/jpeg", FileSize: 512, Width: 320, Height: 240, FileUploadID: upload.ID},
+		{Variant: models.FileVariantPreview, FilePath: "proof/visit_1a2b3c4d_preview.jpg", ContentType: "image/jpeg", FileSize: 1024, Width: 1600, Height: 1200, FileUploadID: upload.ID},
+	}
+}
+
+func TestFileService_UploadFile_RecordsVariants(t *testing.T) {
+	service, mockRepo, mockFile := setupTestFileService()
+
+	header := &multipart.FileHeader{Filename: "visit.jpg", Size: 2048}
+	stored := createTestStoredFile()
+	stored.Variants = createTestVariants(stored)
+
+	mockFile.On("UploadFile", header, "approval").Return(stored, nil)
+	mockRepo.On("CreateFileUpload", (*sql.Tx)(nil), stored).Return(stored, nil)
+	mockRepo.On("CreateFileVariant", (*sql.Tx)(nil), mock.MatchedBy(func(v *models.FileVariant) bool {
+		return v.FileUploadID == stored.ID
+	})).Return(&models.FileVariant{}, nil).Twice()
+	expectCleanScan(service, mockRepo, mockFile)
+
+	result, err := service.UploadFile(header, "approval", uuid.Nil, uuid.MustParse(constant.SystemEmployeeID))
+
+	assert.NoError(t, err)
+	assert.Len(t, result.Variants, 2)
+	mockRepo.AssertExpectations(t)
+	mockFile.AssertNotCalled(t, "DeleteFile", mock.Anything)
+}
+
+func TestFileService_UploadFile_VariantRecordFailsDeletesEverything(t *testing.T) {
+	service, mockRepo, mockFile := setupTestFileService()
+
+	header := &multipart.FileHeader{Filename: "visit.jpg", Size: 2048}
+	stored := createTestStoredFile()
+	stored.Variants = createTestVariants(stored)
+
+	mockFile.On("UploadFile", header, "approval").Return(stored, nil)
+	mockRepo.On("CreateFileUpload", (*sql.Tx)(nil), stored).Return(stored, nil)
+	mockRepo.On("CreateFileVariant", (*sql.Tx)(nil), stored.Variants[0]).Return(nil, assert.AnError)
+	mockFile.On("DeleteFile", mock.AnythingOfType("string")).Return(nil)
+
+	result, err := service.UploadFile(header, "approval", uuid.Nil, uuid.MustParse(constant.SystemEmployeeID))
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	mockFile.AssertCalled(t, "DeleteFile", stored.FilePath)
+	mockFile.AssertCalled(t, "DeleteFile", stored.Variants[0].FilePath)
+	mockFile.AssertCalled(t, "DeleteFile", stored.Variants[1].FilePath)
+}
+
+func TestFileService_UploadFile_StorageFails(t *testing.T) {
+	service, mockRepo, mockFile := setupTestFileService()
+
+	header := &multipart.FileHeader{Filename: "visit.jpg", Size: 2048}
+
+	mockFile.On("UploadFile", header, "approval").Return(nil, assert.AnError)
+
+	result, err := service.UploadFile(header, "approval", uuid.Nil, uuid.MustParse(constant.SystemEmployeeID))
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	mockRepo.AssertNotCalled(t, "CreateFileUpload", mock.Anything, mock.Anything)
+}
+
+func TestFileService_UploadFile_RecordFailsDeletesStoredFile(t *testing.T) {
+	service, mockRepo, mockFile := setupTestFileService()
+
+	header := &multipart.FileHeader{Filename: "visit.jpg", Size: 2048}
+	stored := createTestStoredFile()
+
+	mockFile.On("UploadFile", header, "approval").Return(stored, nil)
+	mockRepo.On("CreateFileUpload", (*sql.Tx)(nil), stored).Return(nil, assert.AnError)
+	mockFile.On("DeleteFile", stored.FilePath).Return(nil)
+
+	result, err := service.UploadFile(header, "approval", uuid.Nil, uuid.MustParse(constant.SystemEmployeeID))
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	mockFile.AssertExpectations(t)
+}
+
+func TestFileService_UploadFile_TooLargeForEntityType(t *testing.T) {
+	service, mockRepo, mockFile := setupTestFileService()
+
+	// 6 MiB is within the default limit but above the approval limit
+	header := &multipart.FileHeader{Filename: "visit.jpg", Size: 6 << 20}
+
+	result, err := service.UploadFile(header, "approval", uuid.Nil, uuid.MustParse(constant.SystemEmployeeID))
+
+	var validationErr *models.FileValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, models.CodeFileTooLarge, validationErr.Code)
+	assert.Nil(t, result)
+	mockFile.AssertNotCalled(t, "UploadFile", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "CreateFileUpload", mock.Anything, mock.Anything)
+}
+
+func TestFileService_UploadFile_DefaultLimit(t *testing.T) {
+	service, mockRepo, mockFile := setupTestFileService()
+
+	header := &multipart.FileHeader{Filename: "agreement.pdf", Size: 6 << 20}
+	stored := createTestStoredFile()
+
+	mockFile.On("UploadFile", header, "disbursement").Return(stored, nil)
+	mockRepo.On("CreateFileUpload", (*sql.Tx)(nil), stored).Return(stored, nil)
+	expectCleanScan(service, mockRepo, mockFile)
+
+	_, err := service.UploadFile(header, "disbursement", uuid.Nil, uuid.MustParse(constant.SystemEmployeeID))
+	assert.NoError(t, err)
+
+	header = &multipart.FileHeader{Filename: "agreement.pdf", Size: 11 << 20}
+	_, err = service.UploadFile(header, "disbursement", uuid.Nil, uuid.MustParse(constant.SystemEmployeeID))
+
+	var validationErr *models.FileValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, models.CodeFileTooLarge, validationErr.Code)
+}
+
+func TestFileService_UploadFile_Empty(t *testing.T) {
+	service, _, mockFile := setupTestFileService()
+
+	header := &multipart.FileHeader{Filename: "visit.jpg", Size: 0}
+
+	_, err := service.UploadFile(header, "approval", uuid.Nil, uuid.MustParse(constant.SystemEmployeeID))
+
+	var validationErr *models.FileValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, models.CodeFileEmpty, validationErr.Code)
+	mockFile.AssertNotCalled(t, "UploadFile", mock.Anything, mock.Anything)
+}
+
+func TestFileService_StoreFile_RecordsGeneratedDocument(t *testing.T) {
+	service, mockRepo, mockFile := setupTestFileService()
+
+	loanID := uuid.New()
+	stored := createTestStoredFile()
+
+	mockFile.On("StoreFile", []byte("%PDF-1.4"), "agreement_loan.pdf", "application/pdf", "loan", loanID).Return(stored, nil)
+	mockRepo.On("CreateFileUpload", (*sql.Tx)(nil), mock.MatchedBy(func(f *models.FileUpload) bool {
+		return f.UploadedBy == uuid.MustParse(constant.SystemEmployeeID) && f.ScanStatus == models.FileScanStatusSkipped &&
+			f.FileURL == "http://localhost:8080/api/v1/files/"+stored.ID.String()+"/download"
+	})).Return(stored, nil)
+
+	result, err := service.StoreFile(nil, []byte("%PDF-1.4"), "agreement_loan.pdf", "application/pdf", "loan", loanID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, stored, result)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestFileService_CreateDownloadURL_InvestorWithoutOwnAgreement(t *testing.T) {
+	service, mockRepo, _ := setupTestFileService()
+	mockLoans := service.loanRepo.(*MockLoanRepository)
+
+	upload := createTestStoredFile()
+	upload.EntityType = "loan"
+	upload.EntityID = uuid.New()
+	investorID := uuid.New()
+
+	mockRepo.On("GetFileUploadByID", (*sql.Tx)(nil), upload.ID).Return(upload, nil)
+	mockLoans.On("GetInvestmentsByLoanID", (*sql.Tx)(nil), upload.EntityID).Return([]*models.Investment{
+		{InvestorID: uuid.New()},
+		{InvestorID: investorID},
+	}, nil)
+
+	result, err := service.CreateDownloadURL(upload.ID, &models.CreateDownloadURLRequest{InvestorID: &investorID})
+
+	assert.NoError(t, err)
+	assert.Equal(t, upload.ID, result.FileID)
+	assert.WithinDuration(t, time.Now().Add(defaultDownloadURLTTL), result.ExpiresAt, time.Minute)
+
+	// The issued URL opens the file
+	parsed, err := url.Parse(result.URL)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(parsed.Path, "/api/v1/files/"+upload.ID.String()+"/download"))
+	assert.NoError(t, service.signer.Verify(upload.ID.String(), parsed.Query().Get("expires"), parsed.Query().Get("signature"), time.Now()))
+}
+
+func TestFileService_CreateDownloadURL_InvestorWithOwnAgreementDeniedLoanLetter(t *testing.T) {
+	service, mockRepo, _ := setupTestFileService()
+	mockLoans := service.loanRepo.(*MockLoanRepository)
+
+	upload := createTestStoredFile()
+	upload.EntityType = "loan"
+	upload.EntityID = uuid.New()
+	investorID := uuid.New()
+
+	mockRepo.On("GetFileUploadByID", (*sql.Tx)(nil), upload.ID).Return(upload, nil)
+	mockLoans.On("GetInvestmentsByLoanID", (*sql.Tx)(nil), upload.EntityID).Return([]*models.Investment{
+		{InvestorID: uuid.New()},
+		{InvestorID: investorID, AgreementURL: "https://api.example.com/api/v1/files/" + uuid.New().String() + "/download"},
+	}, nil)
+
+	// The combined letter names the other investors; this investor has their own agreement instead
+	result, err := service.CreateDownloadURL(upload.ID, &models.CreateDownloadURLRequest{InvestorID: &investorID})
+
+	assert.ErrorIs(t, err, ErrFileAccessDenied)
+	assert.Nil(t, result)
+}
+
+func TestFileService_CreateDownloadURL_InvestorOfOtherLoan(t *testing.T) {
+	service, mockRepo, _ := setupTestFileService()
+	mockLoans := service.loanRepo.(*MockLoanRepository)
+
+	upload := createTestStoredFile()
+	upload.EntityType = "loan"
+	upload.EntityID = uuid.New()
+	investorID := uuid.New()
+
+	mockRepo.On("GetFileUploadByID", (*sql.Tx)(nil), upload.ID).Return(upload, nil)
+	mockLoans.On("GetInvestmentsByLoanID", (*sql.Tx)(nil), upload.EntityID).Return([]*models.Investment{{InvestorID: uuid.New()}}, nil)
+
+	result, err := service.CreateDownloadURL(upload.ID, &models.CreateDownloadURLRequest{InvestorID: &investorID})
+
+	assert.ErrorIs(t, err, ErrFileAccessDenied)
+	assert.Nil(t, result)
+}
+
+func TestFileService_CreateDownloadURL_InvestorDeniedApprovalProof(t *testing.T) {
+	service, mockRepo, _ := setupTestFileService()
+
+	upload := createTestStoredFile()
+	investorID := uuid.New()
+
+	mockRepo.On("GetFileUploadByID", (*sql.Tx)(nil), upload.ID).Return(upload, nil)
+
+	_, err := service.CreateDownloadURL(upload.ID, &models.CreateDownloadURLRequest{InvestorID: &investorID})
+
+	assert.ErrorIs(t, err, ErrFileAccessDenied)
+}
+
+func TestFileService_CreateDownloadURL_TransferBuyer(t *testing.T) {
+	service, mockRepo, _ := setupTestFileService()
+	mockTransfers := service.transferRepo.(*MockTransferRepository)
+
+	upload := createTestStoredFile()
+	upload.EntityType = "transfer"
+	upload.EntityID = uuid.New()
+	buyerID := uuid.New()
+
+	mockRepo.On("GetFileUploadByID", (*sql.Tx)(nil), upload.ID).Return(upload, nil)
+	mockTransfers.On("GetInvestmentTransferByID", upload.EntityID).Return(&models.InvestmentTransfer{
+		SellerID: uuid.New(),
+		BuyerID:  &buyerID,
+	}, nil)
+
+	_, err := service.CreateDownloadURL(upload.ID, &models.CreateDownloadURLRequest{InvestorID: &buyerID})
+
+	assert.NoError(t, err)
+}
+
+func TestFileService_CreateDownloadURL_Employee(t *testing.T) {
+	service, mockRepo, _ := setupTestFileService()
+	mockLoans := service.loanRepo.(*MockLoanRepository)
+
+	upload := createTestStoredFile()
+	activeID := uuid.New()
+	inactiveID := uuid.New()
+
+	mockRepo.On("GetFileUploadByID", (*sql.Tx)(nil), upload.ID).Return(upload, nil)
+	mockLoans.On("GetEmployeeByID", activeID).Return(&models.Employee{IsActive: true}, nil)
+	mockLoans.On("GetEmployeeByID", inactiveID).Return(&models.Employee{IsActive: false}, nil)
+
+	_, err := service.CreateDownloadURL(upload.ID, &models.CreateDownloadURLRequest{EmployeeID: &activeID})
+	assert.NoError(t, err)
+
+	_, err = service.CreateDownloadURL(upload.ID, &models.CreateDownloadURLRequest{EmployeeID: &inactiveID})
+	assert.ErrorIs(t, err, ErrFileAccessDenied)
+}
+
+func TestFileService_CreateDownloadURL_Variant(t *testing.T) {
+	service, mockRepo, _ := setupTestFileService()
+	mockLoans := service.loanRepo.(*MockLoanRepository)
+
+	upload := createTestStoredFile()
+	employeeID := uuid.New()
+
+	mockRepo.On("GetFileUploadByID", (*sql.Tx)(nil), upload.ID).Return(upload, nil)
+	mockRepo.On("GetFileVariant", upload.ID, models.FileVariantPreview).Return(createTestVariants(upload)[1], nil)
+	mockLoans.On("GetEmployeeByID", employeeID).Return(&models.Employee{IsActive: true}, nil)
+
+	result, err := service.CreateDownloadURL(upload.ID, &models.CreateDownloadURLRequest{EmployeeID: &employeeID, Variant: models.FileVariantPreview})
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.FileVariantPreview, result.Variant)
+	assert.True(t, strings.HasSuffix(result.URL, "&variant=preview"))
+}
+
+func TestFileService_CreateDownloadURL_NotFound(t *testing.T) {
+	service, mockRepo, _ := setupTestFileService()
+
+	fileID := uuid.New()
+	employeeID := uuid.New()
+
+	mockRepo.On("GetFileUploadByID", (*sql.Tx)(nil), fileID).Return(nil, nil)
+
+	_, err := service.CreateDownloadURL(fileID, &models.CreateDownloadURLRequest{EmployeeID: &employeeID})
+
+	assert.ErrorIs(t, err, ErrFileNotFound)
+}
+
+func TestFileService_SignFileURL(t *testing.T) {
+	service, _, _ := setupTestFileService()
+
+	fileID := uuid.New()
+	fileURL := "http://localhost:8080/api/v1/files/" + fileID.String() + "/download"
+
+	signed, err := url.Parse(service.SignFileURL(fileURL))
+	assert.NoError(t, err)
+	assert.Equal(t, "/api/v1/files/"+fileID.String()+"/download", signed.Path)
+
+	// Email links stay valid for days, not minutes
+	assert.NoError(t, service.signer.Verify(fileID.String(), signed.Query().Get("expires"), signed.Query().Get("signature"), time.Now().Add(24*time.Hour)))
+
+	external := "https://storage.example.com/uploads/agreements/agreement_loan.pdf"
+	assert.Equal(t, external, service.SignFileURL(external))
+}
+
+func TestFileService_OpenDownload_Success(t *testing.T) {
+	service, mockRepo, mockFile := setupTestFileService()
+
+	upload := createTestStoredFile()
+	expiresAt := time.Now().Add(time.Minute)
+	content := &readSeekNopCloser{strings.NewReader("jpeg")}
+
+	mockRepo.On("GetFileUploadByID", (*sql.Tx)(nil), upload.ID).Return(upload, nil)
+	mockFile.On("OpenFile", upload.FilePath).Return(content, nil)
+
+	result, opened, err := service.OpenDownload(upload.ID, "", strconv.FormatInt(expiresAt.Unix(), 10), service.signer.Sign(upload.ID.String(), expiresAt))
+
+	assert.NoError(t, err)
+	assert.Equal(t, upload, result)
+	assert.Equal(t, content, opened)
+}
+
+func TestFileService_OpenDownload_Variant(t *testing.T) {
+	service, mockRepo, mockFile := setupTestFileService()
+
+	upload := createTestStoredFile()
+	thumbnail := createTestVariants(upload)[0]
+	expiresAt := time.Now().Add(time.Minute)
+	content := &readSeekNopCloser{strings.NewReader("thumbnail")}
+
+	mockRepo.On("GetFileUploadByID", (*sql.Tx)(nil), upload.ID).Return(upload, nil)
+	mockRepo.On("GetFileVariant", upload.ID, models.FileVariantThumbnail).Return(thumbnail, nil)
+	mockFile.On("OpenFile", thumbnail.FilePath).Return(content, nil)
+
+	result, opened, err := service.OpenDownload(upload.ID, models.FileVariantThumbnail, strconv.FormatInt(expiresAt.Unix(), 10), service.signer.Sign(upload.ID.String(), expiresAt))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "proof/visit_1a2b3c4d_thumbnail.jpg", result.FileName)
+	assert.Equal(t, thumbnail.FileSize, result.FileSize)
+	assert.Equal(t, "image/jpeg", result.ContentType)
+	assert.Equal(t, content, opened)
+	assert.Equal(t, "proof/visit_1a2b3c4d.jpg", upload.FilePath, "the original upload is left unchanged")
+}
+
+func TestFileService_OpenDownload_MissingVariant(t *testing.T) {
+	service, mockRepo, mockFile := setupTestFileService()
+
+	// PDFs have no variants
+	upload := createTestStoredFile()
+	upload.FileType = models.FileTypePDF
+	expiresAt := time.Now().Add(time.Minute)
+
+	mockRepo.On("GetFileUploadByID", (*sql.Tx)(nil), upload.ID).Return(upload, nil)
+	mockRepo.On("GetFileVariant", upload.ID, models.FileVariantPreview).Return(nil, nil)
+
+	_, _, err := service.OpenDownload(upload.ID, models.FileVariantPreview, strconv.FormatInt(expiresAt.Unix(), 10), service.signer.Sign(upload.ID.String(), expiresAt))
+
+	assert.ErrorIs(t, err, ErrVariantNotFound)
+	mockFile.AssertNotCalled(t, "OpenFile", mock.Anything)
+}
+
+func TestFileService_OpenDownload_Expired(t *testing.T) {
+	service, mockRepo, _ := setupTestFileService()
+
+	fileID := uuid.New()
+	expiresAt := time.Now().Add(-time.Minute)
+
+	_, _, err := service.OpenDownload(fileID, "", strconv.FormatInt(expiresAt.Unix(), 10), service.signer.Sign(fileID.String(), expiresAt))
+
+	assert.ErrorIs(t, err, signedurl.ErrExpired)
+	mockRepo.AssertNotCalled(t, "GetFileUploadByID", mock.Anything, mock.Anything)
+}
+
+func TestFileService_OpenDownload_SignatureForOtherFile(t *testing.T) {
+	service, mockRepo, _ := setupTestFileService()
+
+	fileID := uuid.New()
+	expiresAt := time.Now().Add(time.Minute)
+
+	_, _, err := service.OpenDownload(fileID, "", strconv.FormatInt(expiresAt.Unix(), 10), service.signer.Sign(uuid.NewString(), expiresAt))
+
+	assert.ErrorIs(t, err, signedurl.ErrInvalidSignature)
+	mockRepo.AssertNotCalled(t, "GetFileUploadByID", mock.Anything, mock.Anything)
+}
+
+type readSeekNopCloser struct {
+	io.ReadSeeker
+}
+
+func (readSeekNopCloser) Close() error {
+	return nil
+}
+
+func TestFileService_AttachFile_Success(t *testing.T) {
+	service, mockRepo, _ := setupTestFileService()
+
+	upload := createTestStoredFile()
+	approvalID := uuid.New()
+
+	mockRepo.On("LockFileUpload", (*sql.Tx)(nil), upload.ID).Return(upload, nil)
+	mockRepo.On("UpdateFileUploadEntityID", (*sql.Tx)(nil), upload.ID, approvalID).Return(nil)
+
+	result, err := service.AttachFile(nil, upload.ID, "approval", approvalID, models.FileTypeJPEG, models.FileTypePNG)
+
+	assert.NoError(t, err)
+	assert.Equal(t, approvalID, result.EntityID)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestFileService_AttachFile_Rejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(upload *models.FileUpload)
+		code   string
+	}{
+		{"inactive", func(upload *models.FileUpload) { upload.IsActive = false }, models.CodeFileInactive},
+		{"uploaded for another entity type", func(upload *models.FileUpload) { upload.EntityType = "disbursement" }, models.CodeFileEntityMismatch},
+		{"linked to another approval", func(upload *models.FileUpload) { upload.EntityID = uuid.New() }, models.CodeFileAlreadyLinked},
+		{"not an image", func(upload *models.FileUpload) { upload.FileType = models.FileTypePDF }, models.CodeFileTypeUnsupported},
+		{"not scanned yet", func(upload *models.FileUpload) { upload.ScanStatus = models.FileScanStatusPending }, models.CodeFileNotScanned},
+		{"infected", func(upload *models.FileUpload) { upload.ScanStatus = models.FileScanStatusInfected }, models.CodeFileInfected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, mockRepo, _ := setupTestFileService()
+
+			upload := createTestStoredFile()
+			tt.modify(upload)
+
+			mockRepo.On("LockFileUpload", (*sql.Tx)(nil), upload.ID).Return(upload, nil)
+
+			result, err := service.AttachFile(nil, upload.ID, "approval", uuid.New(), models.FileTypeJPEG, models.FileTypePNG)
+
+			var validationErr *models.FileValidationError
+			assert.ErrorAs(t, err, &validationErr)
+			assert.Equal(t, tt.code, validationErr.Code)
+			assert.Nil(t, result)
+			mockRepo.AssertNotCalled(t, "UpdateFileUploadEntityID", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestFileService_AttachFile_NotFound(t *testing.T) {
+	service, mockRepo, _ := setupTestFileService()
+
+	fileID := uuid.New()
+
+	mockRepo.On("LockFileUpload", (*sql.Tx)(nil), fileID).Return(nil, nil)
+
+	_, err := service.AttachFile(nil, fileID, "disbursement", uuid.New(), models.FileTypePDF)
+
+	var validationErr *models.FileValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, models.CodeFileNotFound, validationErr.Code)
+}
+
+func createTestBundleHeaders(count int) []*multipart.FileHeader {
+	headers := make([]*multipart.FileHeader, count)
+	for i := range headers {
+		headers[i] = &multipart.FileHeader{Filename: "visit_" + strconv.Itoa(i) + ".jpg", Size: 2048}
+	}
+	return headers
+}
+
+func TestFileService_UploadBundle_Success(t *testing.T) {
+	service, mockRepo, mockFile := setupTestFileService()
+
+	headers := createTestBundleHeaders(2)
+	uploadedBy := uuid.New()
+
+	for _, header := range headers {
+		mockFile.On("UploadFile", header, "approval").Return(createTestStoredFile(), nil).Once()
+	}
+	mockRepo.On("CreateFileBundle", (*sql.Tx)(nil), mock.AnythingOfType("*models.FileBundle")).Return(&models.FileBundle{}, nil)
+	mockRepo.On("CreateFileUpload", (*sql.Tx)(nil), mock.AnythingOfType("*models.FileUpload")).Return(&models.FileUpload{}, nil).Twice()
+	expectCleanScan(service, mockRepo, mockFile)
+
+	bundle, err := service.UploadBundle(headers, "approval", uuid.Nil, uploadedBy)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uploadedBy, bundle.CreatedBy)
+	assert.Len(t, bundle.Files, 2)
+	for _, upload := range bundle.Files {
+		assert.Equal(t, bundle.ID, *upload.BundleID)
+		assert.Equal(t, uploadedBy, upload.UploadedBy)
+		assert.Equal(t, "http://localhost:8080/api/v1/files/"+upload.ID.String()+"/download", upload.FileURL)
+		assert.Equal(t, models.FileScanStatusClean, upload.ScanStatus)
+	}
+	mockRepo.AssertExpectations(t)
+	mockFile.AssertNotCalled(t, "DeleteFile", mock.Anything)
+}
+
+func TestFileService_UploadBundle_OneFileTooLargeStoresNone(t *testing.T) {
+	service, mockRepo, mockFile := setupTestFileService()
+
+	headers := createTestBundleHeaders(3)
+	headers[2].Size = 6 << 20
+
+	_, err := service.UploadBundle(headers, "approval", uuid.Nil, uuid.New())
+
+	var validationErr *models.FileValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, models.CodeFileTooLarge, validationErr.Code)
+	assert.Contains(t, err.Error(), headers[2].Filename)
+	mockFile.AssertNotCalled(t, "UploadFile", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "CreateFileBundle", mock.Anything, mock.Anything)
+}
+
+func TestFileService_UploadBundle_RejectedContentDeletesStoredFiles(t *testing.T) {
+	service, mockRepo, mockFile := setupTestFileService()
+
+	headers := createTestBundleHeaders(2)
+	stored := createTestStoredFile()
+
+	mockFile.On("UploadFile", headers[0], "approval").Return(stored, nil)
+	mockFile.On("UploadFile", headers[1], "approval").Return(nil, &models.FileValidationError{
+		Code:    models.CodeFileTypeMismatch,
+		Message: "content is not a jpeg image",
+	})
+	mockFile.On("DeleteFile", stored.FilePath).Return(nil)
+
+	_, err := service.UploadBundle(headers, "approval", uuid.Nil, uuid.New())
+
+	var validationErr *models.FileValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, models.CodeFileTypeMismatch, validationErr.Code)
+	mockFile.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "CreateFileBundle", mock.Anything, mock.Anything)
+}
+
+func TestFileService_UploadBundle_RecordFailsDeletesStoredFiles(t *testing.T) {
+	service, mockRepo, mockFile := setupTestFileService()
+
+	headers := createTestBundleHeaders(2)
+	first, second := createTestStoredFile(), createTestStoredFile()
+	second.FilePath = "proof/visit_5e6f7a8b.jpg"
+
+	mockFile.On("UploadFile", headers[0], "approval").Return(first, nil)
+	mockFile.On("UploadFile", headers[1], "approval").Return(second, nil)
+	mockRepo.On("CreateFileBundle", (*sql.Tx)(nil), mock.AnythingOfType("*models.FileBundle")).Return(&models.FileBundle{}, nil)
+	mockRepo.On("CreateFileUpload", (*sql.Tx)(nil), first).Return(first, nil)
+	mockRepo.On("CreateFileUpload", (*sql.Tx)(nil), second).Return(nil, assert.AnError)
+	mockFile.On("DeleteFile", first.FilePath).Return(nil)
+	mockFile.On("DeleteFile", second.FilePath).Return(nil)
+
+	result, err := service.UploadBundle(headers, "approval", uuid.Nil, uuid.New())
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	mockFile.AssertExpectations(t)
+}
+
+func TestFileService_UploadBundle_TooManyFiles(t *testing.T) {
+	service, _, mockFile := setupTestFileService()
+
+	_, err := service.UploadBundle(createTestBundleHeaders(4), "approval", uuid.Nil, uuid.New())
+
+	var validationErr *models.FileValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, models.CodeTooManyFiles, validationErr.Code)
+	mockFile.AssertNotCalled(t, "UploadFile", mock.Anything, mock.Anything)
+}
+
+func TestFileService_AttachBundle_Success(t *testing.T) {
+	service, mockRepo, _ := setupTestFileService()
+
+	bundle := &models.FileBundle{
+		BaseModel:  models.BaseModel{ID: uuid.New()},
+		EntityType: "approval",
+		Files:      []*models.FileUpload{createTestStoredFile(), createTestStoredFile()},
+	}
+	approvalID := uuid.New()
+
+	mockRepo.On("LockFileBundle", (*sql.Tx)(nil), bundle.ID).Return(bundle, nil)
+	mockRepo.On("UpdateFileBundleEntityID", (*sql.Tx)(nil), bundle.ID, approvalID).Return(nil)
+
+	result, err := service.AttachBundle(nil, bundle.ID, "approval", approvalID, models.FileTypeJPEG, models.FileTypePNG)
+
+	assert.NoError(t, err)
+	assert.Equal(t, approvalID, result.EntityID)
+	for _, upload := range result.Files {
+		assert.Equal(t, approvalID, upload.EntityID)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestFileService_AttachBundle_RejectsInvalidFile(t *testing.T) {
+	service, mockRepo, _ := setupTestFileService()
+
+	inactive := createTestStoredFile()
+	inactive.IsActive = false
+	bundle := &models.FileBundle{
+		BaseModel:  models.BaseModel{ID: uuid.New()},
+		EntityType: "approval",
+		Files:      []*models.FileUpload{createTestStoredFile(), inactive},
+	}
+
+	mockRepo.On("LockFileBundle", (*sql.Tx)(nil), bundle.ID).Return(bundle, nil)
+
+	_, err := service.AttachBundle(nil, bundle.ID, "approval", uuid.New(), models.FileTypeJPEG, models.FileTypePNG)
+
+	var validationErr *models.FileValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, models.CodeFileInactive, validationErr.Code)
+	mockRepo.AssertNotCalled(t, "UpdateFileBundleEntityID", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestFileService_UploadFile_InfectedStaysQuarantined(t *testing.T) {
+	service, mockRepo, mockFile := setupTestFileService()
+	mockScanner := service.scanner.(*MockScanner)
+
+	header := &multipart.FileHeader{Filename: "visit.jpg", Size: 2048}
+	stored := createTestStoredFile()
+
+	mockFile.On("UploadFile", header, "approval").Return(stored, nil)
+	mockRepo.On("CreateFileUpload", (*sql.Tx)(nil), stored).Return(stored, nil)
+	mockFile.On("OpenFile", stored.FilePath).Return(&readSeekNopCloser{strings.NewReader("content")}, nil)
+	mockScanner.On("Scan", mock.Anything).Return(&scanner.Result{Signature: "Eicar-Test-Signature"}, nil)
+	mockRepo.On("UpdateFileUploadScanResult", (*sql.Tx)(nil), stored.ID, models.FileScanStatusInfected, "Eicar-Test-Signature").Return(nil)
+
+	result, err := service.UploadFile(header, "approval", uuid.Nil, uuid.MustParse(constant.SystemEmployeeID))
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.FileScanStatusInfected, result.ScanStatus)
+	assert.Equal(t, "Eicar-Test-Signature", result.ScanSignature)
+	assert.False(t, result.Downloadable())
+	mockRepo.AssertExpectations(t)
+}
+
+func TestFileService_UploadFile_ScannerUnavailableStaysPending(t *testing.T) {
+	service, mockRepo, mockFile := setupTestFileService()
+	mockScanner := service.scanner.(*MockScanner)
+
+	header := &multipart.FileHeader{Filename: "visit.jpg", Size: 2048}
+	stored := createTestStoredFile()
+
+	mockFile.On("UploadFile", header, "approval").Return(stored, nil)
+	mockRepo.On("CreateFileUpload", (*sql.Tx)(nil), stored).Return(stored, nil)
+	mockFile.On("OpenFile", stored.FilePath).Return(&readSeekNopCloser{strings.NewReader("content")}, nil)
+	mockScanner.On("Scan", mock.Anything).Return(nil, assert.AnError)
+
+	result, err := service.UploadFile(header, "approval", uuid.Nil, uuid.MustParse(constant.SystemEmployeeID))
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.FileScanStatusPending, result.ScanStatus)
+	mockRepo.AssertNotCalled(t, "UpdateFileUploadScanResult", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestFileService_ScanPendingFiles(t *testing.T) {
+	service, mockRepo, mockFile := setupTestFileService()
+	mockScanner := service.scanner.(*MockScanner)
+
+	clean, infected := createTestStoredFile(), createTestStoredFile()
+	clean.ScanStatus = models.FileScanStatusPending
+	infected.ScanStatus = models.FileScanStatusPending
+	infected.FilePath = "proof/visit_5e6f7a8b.jpg"
+
+	mockRepo.On("GetFileUploadsByScanStatus", models.FileScanStatusPending, fileScanBatchSize).Return([]*models.FileUpload{clean, infected}, nil)
+	mockFile.On("OpenFile", clean.FilePath).Return(&readSeekNopCloser{strings.NewReader("clean")}, nil)
+	mockFile.On("OpenFile", infected.FilePath).Return(&readSeekNopCloser{strings.NewReader("infected")}, nil)
+	// Files are scanned oldest first
+	mockScanner.On("Scan", mock.Anything).Return(&scanner.Result{Clean: true}, nil).Once()
+	mockScanner.On("Scan", mock.Anything).Return(&scanner.Result{Signature: "Win.Trojan.Agent"}, nil).Once()
+	mockRepo.On("UpdateFileUploadScanResult", (*sql.Tx)(nil), clean.ID, models.FileScanStatusClean, "").Return(nil)
+	mockRepo.On("UpdateFileUploadScanResult", (*sql.Tx)(nil), infected.ID, models.FileScanStatusInfected, "Win.Trojan.Agent").Return(nil)
+
+	err := service.ScanPendingFiles()
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestFileService_OpenDownload_Quarantined(t *testing.T) {
+	service, mockRepo, mockFile := setupTestFileService()
+
+	upload := createTestStoredFile()
+	upload.ScanStatus = models.FileScanStatusPending
+	expiresAt := time.Now().Add(time.Minute)
+
+	mockRepo.On("GetFileUploadByID", (*sql.Tx)(nil), upload.ID).Return(upload, nil)
+
+	_, _, err := service.OpenDownload(upload.ID, "", strconv.FormatInt(expiresAt.Unix(), 10), service.signer.Sign(upload.ID.String(), expiresAt))
+
+	assert.ErrorIs(t, err, ErrFileQuarantined)
+	mockFile.AssertNotCalled(t, "OpenFile", mock.Anything)
+}
+
+// createTestDocumentVersion returns the current version of a loan agreement
+func createTestDocumentVersion() *models.FileUpload {
+	upload := createTestStoredFile()
+	upload.FileName = "agreement.pdf"
+	upload.FileType = models.FileTypePDF
+	upload.FilePath = "documents/agreement_1a2b3c4d.pdf"
+	upload.ContentType = "application/pdf"
+	upload.EntityType = "loan"
+	upload.EntityID = uuid.New()
+	upload.DocumentID = upload.ID
+	upload.Version = 1
+	return upload
+}
+
+func TestFileService_ReplaceFile_Success(t *testing.T) {
+	service, mockRepo, mockFile := setupTestFileService()
+
+	previous := createTestDocumentVersion()
+	header := &multipart.FileHeader{Filename: "agreement_signed.pdf", Size: 4096}
+	stored := createTestDocumentVersion()
+	stored.ID = uuid.New()
+	stored.EntityID = uuid.Nil
+	uploadedBy := uuid.New()
+
+	mockRepo.On("GetFileUploadByID", (*sql.Tx)(nil), previous.ID).Return(previous, nil)
+	mockFile.On("UploadFile", header, "loan").Return(stored, nil)
+	mockRepo.On("LockFileUpload", (*sql.Tx)(nil), previous.ID).Return(previous, nil)
+	mockRepo.On("DeactivateFileUpload", (*sql.Tx)(nil), previous.ID).Return(nil)
+	mockRepo.On("CreateFileUpload", (*sql.Tx)(nil), mock.MatchedBy(func(f *models.FileUpload) bool {
+		return f.DocumentID == previous.DocumentID && f.Version == 2 && f.ReplacesID != nil && *f.ReplacesID == previous.ID &&
+			f.EntityID == previous.EntityID && f.UploadedBy == uploadedBy && f.ScanStatus == models.FileScanStatusPending
+	})).Return(stored, nil)
+	mockRepo.On("RepointFileURL", (*sql.Tx)(nil), previous.FileURL, service.fileURL(stored.ID)).Return(nil)
+	expectCleanScan(service, mockRepo, mockFile)
+
+	result, err := service.ReplaceFile(previous.ID, header, uploadedBy)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.Version)
+	assert.Equal(t, previous.DocumentID, result.DocumentID)
+	assert.Equal(t, models.FileScanStatusClean, result.ScanStatus)
+	mockRepo.AssertExpectations(t)
+	mockFile.AssertNotCalled(t, "DeleteFile", mock.Anything)
+}
+
+func TestFileService_ReplaceFile_Rejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(previous, stored *models.FileUpload)
+		code   string
+	}{
+		{"already replaced", func(previous, _ *models.FileUpload) { previous.IsActive = false }, models.CodeFileInactive},
+		{"different type", func(_, stored *models.FileUpload) { stored.FileType = models.FileTypePNG }, models.CodeFileTypeMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, mockRepo, mockFile := setupTestFileService()
+
+			previous := createTestDocumentVersion()
+			header := &multipart.FileHeader{Filename: "agreement_signed.pdf", Size: 4096}
+			stored := createTestDocumentVersion()
+			stored.ID = uuid.New()
+			tt.modify(previous, stored)
+
+			mockRepo.On("GetFileUploadByID", (*sql.Tx)(nil), previous.ID).Return(previous, nil)
+			mockFile.On("UploadFile", header, "loan").Return(stored, nil)
+			mockRepo.On("LockFileUpload", (*sql.Tx)(nil), previous.ID).Return(previous, nil)
+			mockFile.On("DeleteFile", stored.FilePath).Return(nil)
+
+			result, err := service.ReplaceFile(previous.ID, header, uuid.New())
+
+			var validationErr *models.FileValidationError
+			assert.ErrorAs(t, err, &validationErr)
+			assert.Equal(t, tt.code, validationErr.Code)
+			assert.Nil(t, result)
+			mockRepo.AssertNotCalled(t, "DeactivateFileUpload", mock.Anything, mock.Anything)
+			mockRepo.AssertNotCalled(t, "CreateFileUpload", mock.Anything, mock.Anything)
+			mockFile.AssertExpectations(t)
+		})
+	}
+}
+
+func TestFileService_ReplaceFile_RepointFailsDeletesStoredFile(t *testing.T) {
+	service, mockRepo, mockFile := setupTestFileService()
+
+	previous := createTestDocumentVersion()
+	header := &multipart.FileHeader{Filename: "agreement_signed.pdf", Size: 4096}
+	stored := createTestDocumentVersion()
+	stored.ID = uuid.New()
+
+	mockRepo.On("GetFileUploadByID", (*sql.Tx)(nil), previous.ID).Return(previous, nil)
+	mockFile.On("UploadFile", header, "loan").Return(stored, nil)
+	mockRepo.On("LockFileUpload", (*sql.Tx)(nil), previous.ID).Return(previous, nil)
+	mockRepo.On("DeactivateFileUpload", (*sql.Tx)(nil), previous.ID).Return(nil)
+	mockRepo.On("CreateFileUpload", (*sql.Tx)(nil), mock.Anything).Return(stored, nil)
+	mockRepo.On("RepointFileURL", (*sql.Tx)(nil), previous.FileURL, mock.Anything).Return(assert.AnError)
+	mockFile.On("DeleteFile", stored.FilePath).Return(nil)
+
+	// The loan would still link to the deactivated version, so the replacement is not kept
+	result, err := service.ReplaceFile(previous.ID, header, uuid.New())
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	mockFile.AssertExpectations(t)
+}
+
+func TestFileService_ReplaceFile_NotFound(t *testing.T) {
+	service, mockRepo, mockFile := setupTestFileService()
+
+	fileID := uuid.New()
+	mockRepo.On("GetFileUploadByID", (*sql.Tx)(nil), fileID).Return(nil, nil)
+
+	result, err := service.ReplaceFile(fileID, &multipart.FileHeader{Filename: "agreement.pdf", Size: 4096}, uuid.New())
+
+	assert.ErrorIs(t, err, ErrFileNotFound)
+	assert.Nil(t, result)
+	mockFile.AssertNotCalled(t, "UploadFile", mock.Anything, mock.Anything)
+}
+
+func TestFileService_GetFileVersions(t *testing.T) {
+	service, mockRepo, _ := setupTestFileService()
+	mockLoans := service.loanRepo.(*MockLoanRepository)
+
+	first := createTestDocumentVersion()
+	first.IsActive = false
+	first.Uploader = &models.Employee{FirstName: "Budi", LastName: "Santoso"}
+	second := createTestDocumentVersion()
+	second.ID = uuid.New()
+	second.DocumentID = first.DocumentID
+	second.Version = 2
+	second.ReplacesID = &first.ID
+	employeeID := uuid.New()
+
+	mockRepo.On("GetFileUploadByID", (*sql.Tx)(nil), first.ID).Return(first, nil)
+	mockLoans.On("GetEmployeeByID", employeeID).Return(&models.Employee{IsActive: true}, nil)
+	mockRepo.On("GetFileVersions", first.DocumentID).Return([]*models.FileUpload{second, first}, nil)
+
+	versions, err := service.GetFileVersions(first.ID, employeeID)
+
+	assert.NoError(t, err)
+	assert.Len(t, versions, 2)
+	assert.Equal(t, 2, versions[0].Version)
+	assert.True(t, versions[0].IsCurrent)
+	assert.Empty(t, versions[0].UploaderName)
+	assert.Equal(t, 1, versions[1].Version)
+	assert.False(t, versions[1].IsCurrent)
+	assert.Equal(t, "Budi Santoso", versions[1].UploaderName)
+}
+
+func TestFileService_GetFileVersions_InactiveEmployee(t *testing.T) {
+	service, mockRepo, _ := setupTestFileService()
+	mockLoans := service.loanRepo.(*MockLoanRepository)
+
+	upload := createTestDocumentVersion()
+	employeeID := uuid.New()
+
+	mockRepo.On("GetFileUploadByID", (*sql.Tx)(nil), upload.ID).Return(upload, nil)
+	mockLoans.On("GetEmployeeByID", employeeID).Return(&models.Employee{IsActive: false}, nil)
+
+	_, err := service.GetFileVersions(upload.ID, employeeID)
+
+	assert.ErrorIs(t, err, ErrFileAccessDenied)
+	mockRepo.AssertNotCalled(t, "GetFileVersions", mock.Anything)
+}
+
+func TestFileService_CreateDownloadURL_ReplacedVersionStaffOnly(t *testing.T) {
+	service, mockRepo, _ := setupTestFileService()
+	mockLoans := service.loanRepo.(*MockLoanRepository)
+
+	upload := createTestDocumentVersion()
+	upload.IsActive = false
+	investorID := uuid.New()
+	employeeID := uuid.New()
+
+	mockRepo.On("GetFileUploadByID", (*sql.Tx)(nil), upload.ID).Return(upload, nil)
+	mockLoans.On("GetEmployeeByID", employeeID).Return(&models.Employee{IsActive: true}, nil)
+
+	_, err := service.CreateDownloadURL(upload.ID, &models.CreateDownloadURLRequest{InvestorID: &investorID})
+	assert.ErrorIs(t, err, ErrFileAccessDenied)
+	mockLoans.AssertNotCalled(t, "GetInvestmentsByLoanID", mock.Anything, mock.Anything)
+
+	result, err := service.CreateDownloadURL(upload.ID, &models.CreateDownloadURLRequest{EmployeeID: &employeeID})
+	assert.NoError(t, err)
+	assert.Equal(t, upload.ID, result.FileID)
+}
@@ -2,6 +2,12 @@
 package services
 
 import (
+	"context"
+	"database/sql"
+	"io"
+	"mime/multipart"
+	"time"
+
 	"loan-service/internal/models"
 
 	"github.com/google/uuid"
@@ -16,4 +22,130 @@ type LoanServiceInterface interface {
 	ProcessApproveLoan(id uuid.UUID, req *models.CreateApprovalRequest) (*models.LoanApprovalResponse, error)
 	ProcessInvestment(loanID uuid.UUID, req *models.CreateInvestmentRequest) (*models.InvestmentResponse, error)
 	ProcessDisbursement(loanID uuid.UUID, req *models.CreateDisbursementRequest) (*models.DisbursementResponse, error)
+	ProcessCancelLoan(loanID uuid.UUID, req *models.CancelLoanRequest) (*models.LoanSummaryResponse, error)
+	ProcessExpireLoan(loanID uuid.UUID) error
+	ProcessCancelInvestment(loanID, investmentID uuid.UUID, req *models.CancelInvestmentRequest) (*models.InvestmentCancellationResponse, error)
+
+	// Investor limits
+	GetInvestorLimits(investorID uuid.UUID) (*models.InvestorLimitsResponse, error)
+	SetInvestorLimits(investorID uuid.UUID, req *models.SetInvestorLimitsRequest) (*models.InvestorLimitsResponse, error)
+
+	// Investor portfolio
+	GetInvestorPortfolio(investorID uuid.UUID) (*models.PortfolioResponse, error)
+
+	// AddApprovalListener registers a listener that is notified after each loan approval commits
+	AddApprovalListener(listener LoanApprovalListener)
+
+	// AddChangeListener registers a listener that is notified after any committed change to a loan open for funding
+	AddChangeListener(listener LoanChangeListener)
+}
+
+// LoanApprovalListener is notified after a loan approval has committed
+type LoanApprovalListener interface {
+	OnLoanApproved(loanID uuid.UUID)
+}
+
+// LoanChangeListener is notified after an approval, investment, investment cancellation or closure has committed
+type LoanChangeListener interface {
+	OnLoanChanged(loanID uuid.UUID)
+}
+
+// InvestmentProcessor is the investment path shared by investors and the auto-invest engine
+type InvestmentProcessor interface {
+	ProcessInvestment(loanID uuid.UUID, req *models.CreateInvestmentRequest) (*models.InvestmentResponse, error)
+}
+
+type AutoInvestServiceInterface interface {
+	LoanApprovalListener
+	CreateRule(investorID uuid.UUID, req *models.CreateAutoInvestRuleRequest) (*models.AutoInvestRule, error)
+	GetRules(investorID uuid.UUID) ([]*models.AutoInvestRule, error)
+	DeactivateRule(investorID, ruleID uuid.UUID) error
+	AllocateLoan(loanID uuid.UUID) ([]*models.InvestmentResponse, error)
+	Wait()
+}
+
+type ReservationServiceInterface interface {
+	ProcessReserve(loanID uuid.UUID, req *models.CreateReservationRequest) (*models.InvestmentReservation, error)
+	ConfirmReservation(loanID, reservationID uuid.UUID, req *models.ConfirmReservationRequest) (*models.InvestmentResponse, error)
+}
+
+type TransferServiceInterface interface {
+	ProcessListInvestment(investmentID uuid.UUID, req *models.CreateTransferListingRequest) (*models.InvestmentTransfer, error)
+	GetListings() ([]*models.InvestmentTransfer, error)
+	ProcessBuyTransfer(transferID uuid.UUID, req *models.BuyTransferRequest) (*models.InvestmentTransfer, error)
+	ProcessCancelListing(transferID uuid.UUID, req *models.CancelTransferListingRequest) (*models.InvestmentTransfer, error)
+	GetInvestmentLineage(investmentID uuid.UUID) ([]*models.InvestmentTransfer, error)
+}
+
+type WaitlistServiceInterface interface {
+	LoanChangeListener
+	JoinWaitlist(loanID uuid.UUID, req *models.JoinWaitlistRequest) (*models.WaitlistEntryResponse, error)
+	ProcessWaitlist(loanID uuid.UUID) ([]*models.WaitlistEntry, error)
+	ProcessWaitlists() error
+}
+
+type MarketplaceServiceInterface interface {
+	LoanChangeListener
+	GetLoans(query models.MarketplaceLoansQuery) ([]*models.MarketplaceLoan, error)
+}
+
+// CacheInterface is the part of redis.CacheService used by the services
+type CacheInterface interface {
+	SetCache(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+	GetCacheAs(ctx context.Context, key string, target interface{}) error
+	DeleteCache(ctx context.Context, key string) error
+}
+
+type WalletServiceInterface interface {
+	GetWallet(investorID uuid.UUID) (*models.WalletResponse, error)
+	TopUp(investorID uuid.UUID, req *models.WalletTopUpRequest) (*models.WalletTransaction, error)
+	ResumeChargedTopUps() error
+}
+
+type WithdrawalServiceInterface interface {
+	RegisterBankAccount(investorID uuid.UUID, req *models.CreateBankAccountRequest) (*models.BankAccountResponse, error)
+	GetBankAccounts(investorID uuid.UUID) ([]*models.BankAccountResponse, error)
+	RequestWithdrawal(investorID uuid.UUID, req *models.CreateWithdrawalRequest) (*models.WithdrawalResponse, error)
+	GetWithdrawals(investorID uuid.UUID) ([]*models.WithdrawalResponse, error)
+	ApproveWithdrawal(withdrawalID uuid.UUID, req *models.ReviewWithdrawalRequest) (*models.WithdrawalResponse, error)
+	RejectWithdrawal(withdrawalID uuid.UUID, req *models.ReviewWithdrawalRequest) (*models.WithdrawalResponse, error)
+	ResumeProcessingWithdrawals() error
+}
+
+// NotificationServiceInterface sends email notifications, retrying failed sends with backoff
+type NotificationServiceInterface interface {
+	SendEmail(notification *models.EmailNotification) error
+	RetryDueEmails() error
+
+	// Staff review emails that ran out of attempts and may send them again
+	GetFailedEmailNotifications(employeeID uuid.UUID) ([]*models.EmailNotificationResponse, error)
+	RetryEmailNotification(notificationID uuid.UUID, req *models.RetryEmailNotificationRequest) (*models.EmailNotificationResponse, error)
+}
+
+type ReconciliationServiceInterface interface {
+	RunReconciliation(date time.Time, settlementFile string) (*models.ReconciliationRun, error)
+}
+
+type SignatureServiceInterface interface {
+	ProcessSendForSignature(loanID uuid.UUID) (*models.AgreementSignature, error)
+	ProcessCallback(payload []byte, callbackSignature string) (*models.AgreementSignature, error)
+}
+
+type FileServiceInterface interface {
+	UploadFile(file *multipart.FileHeader, entityType string, entityID, uploadedBy uuid.UUID) (*models.FileUpload, error)
+	StoreFile(tx *sql.Tx, content []byte, fileName, contentType, entityType string, entityID uuid.UUID) (*models.FileUpload, error)
+	DeleteStoredFile(upload *models.FileUpload)
+	UploadBundle(files []*multipart.FileHeader, entityType string, entityID, uploadedBy uuid.UUID) (*models.FileBundle, error)
+	AttachBundle(tx *sql.Tx, bundleID uuid.UUID, entityType string, entityID uuid.UUID, allowedTypes ...models.FileType) (*models.FileBundle, error)
+	ScanPendingFiles() error
+	AttachFile(tx *sql.Tx, fileID uuid.UUID, entityType string, entityID uuid.UUID, allowedTypes ...models.FileType) (*models.FileUpload, error)
+
+	// Replacing a document keeps its earlier versions
+	ReplaceFile(fileID uuid.UUID, file *multipart.FileHeader, uploadedBy uuid.UUID) (*models.FileUpload, error)
+	GetFileVersions(fileID, employeeID uuid.UUID) ([]*models.FileVersionResponse, error)
+
+	// Downloads go through signed, expiring URLs
+	CreateDownloadURL(fileID uuid.UUID, req *models.CreateDownloadURLRequest) (*models.FileDownloadURLResponse, error)
+	SignFileURL(fileURL string) string
+	OpenDownload(fileID uuid.UUID, variant models.FileVariantName, expires, signature string) (*models.FileUpload, io.ReadSeekCloser, error)
 }
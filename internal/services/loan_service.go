@@ -10,79 +10,75 @@ import (
 	"loan-service/internal/models"
 	"loan-service/internal/repositories"
 	"loan-service/pkg/adapters"
+	"loan-service/pkg/config"
 	"loan-service/pkg/logger"
 
 	"github.com/google/uuid"
 )
 
+const (
+	defaultVisitProofMaxDistance = 500.0
+	defaultVisitProofMaxAge      = 72 * time.Hour
+)
+
 type LoanService struct {
-	loanRepo       repositories.LoanRepositoryInterface
-	paymentAdapter adapters.PaymentAdapterInterface
-	emailAdapter   adapters.EmailAdapterInterface
-	logger         logger.LoggerInterface
-	db             *sql.DB
+	loanRepo        repositories.LoanRepositoryInterface
+	walletRepo      repositories.WalletRepositoryInterface
+	reservationRepo repositories.ReservationRepositoryInterface
+	signatureRepo   repositories.SignatureRepositoryInterface
+	paymentAdapter  adapters.PaymentAdapterInterface
+	emailAdapter    adapters.EmailAdapterInterface
+	documentAdapter adapters.DocumentAdapterInterface
+	fileService     FileServiceInterface
+	limits          models.InvestmentLimits
+	visitProof      config.VisitProofConfig
+	logger          logger.LoggerInterface
+	db              *sql.DB
+
+	approvalListeners []LoanApprovalListener
+	changeListeners   []LoanChangeListener
 }
 
 func NewLoanService(
 	loanRepo repositories.LoanRepositoryInterface,
+	walletRepo repositories.WalletRepositoryInterface,
+	reservationRepo repositories.ReservationRepositoryInterface,
+	signatureRepo repositories.SignatureRepositoryInterface,
 	paymentAdapter adapters.PaymentAdapterInterface,
 	emailAdapter adapters.EmailAdapterInterface,
+	documentAdapter adapters.DocumentAdapterInterface,
+	fileService FileServiceInterface,
+	limitsCfg config.InvestmentLimitsConfig,
+	visitProofCfg config.VisitProofConfig,
 	logger logger.LoggerInterface,
 	db *sql.DB,
 ) LoanServiceInterface {
 	return &LoanService{
-		loanRepo:       loanRepo,
-		paymentAdapter: paymentAdapter,
-		emailAdapter:   emailAdapter,
-		logger:         logger,
-		db:             db,
+		loanRepo:        loanRepo,
+		walletRepo:      walletRepo,
+		reservationRepo: reservationRepo,
+		signatureRepo:   signatureRepo,
+		paymentAdapter:  paymentAdapter,
+		emailAdapter:    emailAdapter,
+		documentAdapter: documentAdapter,
+		fileService:     fileService,
+		limits:          newInvestmentLimits(limitsCfg),
+		visitProof:      visitProofCfg,
+		logger:          logger,
+		db:              db,
 	}
 }
 
-func (s *LoanService) withTransaction(fn func(*sql.Tx) error) error {
-	tx, err := s.db.Begin()
-	if err != nil {
-		s.logger.Error("Failed to begin transaction", map[string]interface{}{
-			"error": err.Error(),
-		})
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-
-	defer func() {
-		if p := recover(); p != nil {
-			s.logger.Error("Panic in transaction, rolling back", map[string]interface{}{
-				"panic": p,
-			})
-			tx.Rollback()
-			panic(p)
-		}
-	}()
-
-	err = fn(tx)
-	if err != nil {
-		s.logger.Error("Transaction failed, rolling back", map[string]interface{}{
-			"error": err.Error(),
-		})
-		rollbackErr := tx.Rollback()
-		if rollbackErr != nil {
-			s.logger.Error("Failed to rollback transaction", map[string]interface{}{
-				"rollback_error": rollbackErr.Error(),
-				"original_error": err.Error(),
-			})
-			return fmt.Errorf("failed to rollback transaction: %w (original error: %w)", rollbackErr, err)
-		}
-		return err
-	}
-
-	err = tx.Commit()
-	if err != nil {
-		s.logger.Error("Failed to commit transaction", map[string]interface{}{
-			"error": err.Error(),
-		})
-		return fmt.Errorf("failed to commit transaction: %w", err)
+func newInvestmentLimits(cfg config.InvestmentLimitsConfig) models.InvestmentLimits {
+	return models.InvestmentLimits{
+		MaxLoanShare:     cfg.MaxLoanShare,
+		MaxTotalExposure: cfg.MaxTotalExposure,
+		MinTicket:        cfg.MinTicket,
 	}
+}
 
-	return nil
+func (s *LoanService) withTransaction(fn func(*sql.Tx) error) error {
+	return runInTransaction(s.db, s.logger, fn)
 }
 
 func (s *LoanService) GetLoanByID(id uuid.UUID) (*models.LoanSummaryResponse, error) {
@@ -114,6 +110,16 @@ func (s *LoanService) GetLoanByID(id uuid.UUID) (*models.LoanSummaryResponse, er
 func (s *LoanService) ProcessCreateLoan(req *models.CreateLoanRequest) (*models.LoanSummaryResponse, error) {
 	s.logger.Info("Processing loan creation", map[string]interface{}{"request": req})
 
+	product := req.Product
+	if product == "" {
+		product = models.DefaultLoanProduct
+	}
+
+	tenorMonths := req.TenorMonths
+	if tenorMonths == 0 {
+		tenorMonths = models.DefaultLoanTenorMonths
+	}
+
 	loan := &models.Loan{
 		BaseModel: models.BaseModel{
 			CreatedAt: time.Now(),
@@ -124,6 +130,8 @@ func (s *LoanService) ProcessCreateLoan(req *models.CreateLoanRequest) (*models.
 		InterestRate:    req.InterestRate,
 		ROI:             req.ROI,
 		State:           models.LoanStateProposed,
+		Product:         product,
+		TenorMonths:     tenorMonths,
 		TotalInvested:   0,
 	}
 
@@ -172,8 +180,31 @@ func (s *LoanService) ProcessApproveLoan(id uuid.UUID, req *models.CreateApprova
 		result, approvalErr = s.processApproveLoanTx(tx, id, req)
 		return approvalErr
 	})
+	if err != nil {
+		return nil, err
+	}
 
-	return result, err
+	// Listeners run only once the approval is committed so they see the loan as approved
+	s.notifyLoanChanged(id)
+	for _, listener := range s.approvalListeners {
+		listener.OnLoanApproved(id)
+	}
+
+	return result, nil
+}
+
+func (s *LoanService) AddApprovalListener(listener LoanApprovalListener) {
+	s.approvalListeners = append(s.approvalListeners, listener)
+}
+
+func (s *LoanService) AddChangeListener(listener LoanChangeListener) {
+	s.changeListeners = append(s.changeListeners, listener)
+}
+
+func (s *LoanService) notifyLoanChanged(loanID uuid.UUID) {
+	for _, listener := range s.changeListeners {
+		listener.OnLoanChanged(loanID)
+	}
 }
 
 func (s *LoanService) processApproveLoanTx(tx *sql.Tx, id uuid.UUID, req *models.CreateApprovalRequest) (*models.LoanApprovalResponse, error) {
@@ -183,12 +214,10 @@ func (s *LoanService) processApproveLoanTx(tx *sql.Tx, id uuid.UUID, req *models
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		},
-		LoanID:              id,
-		ValidatorID:         req.ValidatorID,
-		ApprovalDate:        req.ApprovalDate,
-		VisitProofImageURL:  req.VisitProofImageURL,
-		VisitProofImageType: models.FileType(req.VisitProofImageType),
-		Notes:               req.Notes,
+		LoanID:       id,
+		ValidatorID:  req.ValidatorID,
+		ApprovalDate: req.ApprovalDate,
+		Notes:        req.Notes,
 	}
 
 	// check if loan is already approved
@@ -211,6 +240,21 @@ func (s *LoanService) processApproveLoanTx(tx *sql.Tx, id uuid.UUID, req *models
 		return nil, fmt.Errorf("loan approval validation failed: %w", err)
 	}
 
+	// The visit proof must be images uploaded for an approval; they are linked to this approval in the same transaction
+	photos, err := s.attachVisitProof(tx, req, approval.ID)
+	if err != nil {
+		return nil, err
+	}
+	approval.VisitProofImageURL = photos[0].FileURL
+	approval.VisitProofImageType = photos[0].FileType
+	approval.VisitProofBundleID = req.VisitProofBundleID
+
+	recordVisitProofMetadata(approval, photos, loan.Borrower)
+	visitProofWarnings, err := s.checkVisitProof(approval, loan.Borrower)
+	if err != nil {
+		return nil, err
+	}
+
 	approval, err = s.loanRepo.CreateApproval(tx, approval)
 	if err != nil {
 		s.logger.Error("Failed to create approval", map[string]interface{}{
@@ -248,27 +292,176 @@ func (s *LoanService) processApproveLoanTx(tx *sql.Tx, id uuid.UUID, req *models
 		ApprovalDate:        approval.ApprovalDate,
 		VisitProofImageURL:  approval.VisitProofImageURL,
 		VisitProofImageType: approval.VisitProofImageType,
+		VisitProofBundleID:  approval.VisitProofBundleID,
 		Notes:               approval.Notes,
-		CreatedAt:           approval.CreatedAt,
-		UpdatedAt:           approval.UpdatedAt,
+
+		VisitProofCapturedAt: approval.VisitProofCapturedAt,
+		VisitProofLatitude:   approval.VisitProofLatitude,
+		VisitProofLongitude:  approval.VisitProofLongitude,
+		VisitDistanceMeters:  approval.VisitDistanceMeters,
+		VisitProofWarnings:   visitProofWarnings,
+
+		CreatedAt: approval.CreatedAt,
+		UpdatedAt: approval.UpdatedAt,
 	}, nil
 }
 
+// attachVisitProof links the visit proof file or bundle of the request to the approval and returns its images; the
+// approval record points at the first
+func (s *LoanService) attachVisitProof(tx *sql.Tx, req *models.CreateApprovalRequest, approvalID uuid.UUID) ([]*models.FileUpload, error) {
+	if req.VisitProofBundleID != nil {
+		bundle, err := s.fileService.AttachBundle(tx, *req.VisitProofBundleID, "approval", approvalID, models.FileTypeJPEG, models.FileTypePNG)
+		if err != nil {
+			return nil, err
+		}
+		return bundle.Files, nil
+	}
+
+	upload, err := s.fileService.AttachFile(tx, *req.VisitProofFileID, "approval", approvalID, models.FileTypeJPEG, models.FileTypePNG)
+	if err != nil {
+		return nil, err
+	}
+	return []*models.FileUpload{upload}, nil
+}
+
+// recordVisitProofMetadata copies the photo metadata onto the approval. Of a bundle, the photo with a GPS position is
+// used, or else one with a capture time.
+func recordVisitProofMetadata(approval *models.Approval, photos []*models.FileUpload, borrower *models.Borrower) {
+	var evidence *models.FileUpload
+	for _, photo := range photos {
+		if photo.Latitude != nil && photo.Longitude != nil {
+			evidence = photo
+			break
+		}
+		if evidence == nil && photo.CapturedAt != nil {
+			evidence = photo
+		}
+	}
+	if evidence == nil {
+		return
+	}
+
+	approval.VisitProofCapturedAt = evidence.CapturedAt
+	approval.VisitProofLatitude = evidence.Latitude
+	approval.VisitProofLongitude = evidence.Longitude
+
+	if evidence.Latitude != nil && evidence.Longitude != nil &&
+		borrower != nil && borrower.Latitude != nil && borrower.Longitude != nil {
+		distance := models.DistanceMeters(*evidence.Latitude, *evidence.Longitude, *borrower.Latitude, *borrower.Longitude)
+		approval.VisitDistanceMeters = &distance
+	}
+}
+
+// checkVisitProof checks that the visit proof photo was taken near the borrower's address and shortly before the
+// approval date. It returns the failed checks as warnings, or a VisitProofError in block mode. A photo without a GPS
+// position or capture time fails like one taken too far or too early, unless allow_missing_metadata is set for phones
+// that strip it.
+func (s *LoanService) checkVisitProof(approval *models.Approval, borrower *models.Borrower) ([]string, error) {
+	mode := s.visitProof.Mode
+	if mode == "" || mode == models.VisitProofModeOff {
+		return nil, nil
+	}
+
+	maxDistance := s.visitProof.MaxDistance
+	if maxDistance <= 0 {
+		maxDistance = defaultVisitProofMaxDistance
+	}
+	maxAge := durationOr(s.visitProof.MaxAge, defaultVisitProofMaxAge)
+
+	var violations []*models.VisitProofError
+	var warnings []string
+
+	missingMetadata := func(code, message string) {
+		if s.visitProof.AllowMissingMetadata {
+			warnings = append(warnings, message)
+			return
+		}
+		violations = append(violations, &models.VisitProofError{Code: code, Message: message})
+	}
+
+	switch {
+	case approval.VisitProofLatitude == nil || approval.VisitProofLongitude == nil:
+		missingMetadata(models.CodeVisitProofNoLocation, "visit proof photo has no GPS position")
+	case borrower == nil || borrower.Latitude == nil || borrower.Longitude == nil:
+		warnings = append(warnings, "borrower address has not been geocoded")
+	case *approval.VisitDistanceMeters > maxDistance:
+		violations = append(violations, &models.VisitProofError{
+			Code: models.CodeVisitProofTooFar,
+			Message: fmt.Sprintf("visit proof photo was taken %.0f m from the borrower's address, more than the allowed %.0f m",
+				*approval.VisitDistanceMeters, maxDistance),
+		})
+	}
+
+	switch {
+	case approval.VisitProofCapturedAt == nil:
+		missingMetadata(models.CodeVisitProofNoCaptureTime, "visit proof photo has no capture time")
+	case approval.VisitProofCapturedAt.After(approval.ApprovalDate):
+		violations = append(violations, &models.VisitProofError{
+			Code: models.CodeVisitProofAfterApproval,
+			Message: fmt.Sprintf("visit proof photo was taken at %s, after the approval date %s",
+				approval.VisitProofCapturedAt.Format(time.RFC3339), approval.ApprovalDate.Format(time.RFC3339)),
+		})
+	case approval.VisitProofCapturedAt.Before(approval.ApprovalDate.Add(-maxAge)):
+		violations = append(violations, &models.VisitProofError{
+			Code: models.CodeVisitProofTooOld,
+			Message: fmt.Sprintf("visit proof photo was taken at %s, more than %s before the approval date",
+				approval.VisitProofCapturedAt.Format(time.RFC3339), maxAge),
+		})
+	}
+
+	if len(violations) > 0 && mode == models.VisitProofModeBlock {
+		s.logger.Warn("Visit proof check blocked approval", map[string]interface{}{
+			"loan_id": approval.LoanID.String(),
+			"code":    violations[0].Code,
+			"reason":  violations[0].Message,
+		})
+		return nil, violations[0]
+	}
+
+	for _, violation := range violations {
+		warnings = append(warnings, violation.Message)
+	}
+	if len(warnings) > 0 {
+		s.logger.Warn("Visit proof checks failed", map[string]interface{}{
+			"loan_id":  approval.LoanID.String(),
+			"warnings": warnings,
+		})
+	}
+	return warnings, nil
+}
+
 func (s *LoanService) ProcessInvestment(loanID uuid.UUID, req *models.CreateInvestmentRequest) (*models.InvestmentResponse, error) {
 	s.logger.Info("Processing investment", map[string]interface{}{"loan_id": loanID, "request": req})
 
 	// Use transaction to ensure data consistency
 	var result *models.InvestmentResponse
+	var undo rollbackActions
 	err := s.withTransaction(func(tx *sql.Tx) error {
 		var investmentErr error
-		result, investmentErr = s.processInvestmentTx(tx, loanID, req)
+		result, investmentErr = s.processInvestmentTx(tx, loanID, req, &undo)
 		return investmentErr
 	})
+	if err != nil {
+		undo.run()
+		return nil, err
+	}
 
-	return result, err
+	s.notifyLoanChanged(loanID)
+	return result, nil
 }
 
-func (s *LoanService) processInvestmentTx(tx *sql.Tx, loanID uuid.UUID, req *models.CreateInvestmentRequest) (*models.InvestmentResponse, error) {
+// processInvestmentTx records the investment in the transaction; undo collects what must be reverted outside the
+// database if the transaction does not commit
+func (s *LoanService) processInvestmentTx(tx *sql.Tx, loanID uuid.UUID, req *models.CreateInvestmentRequest, undo *rollbackActions) (*models.InvestmentResponse, error) {
+	// Lock the loan so reservations cannot be taken while the remaining amount is checked
+	if err := s.loanRepo.LockLoan(tx, loanID); err != nil {
+		s.logger.Error("Failed to lock loan", map[string]interface{}{
+			"error":   err.Error(),
+			"loan_id": loanID.String(),
+		})
+		return nil, err
+	}
+
 	// Get the loan with current state for validation
 	loan, err := s.loanRepo.GetLoanByID(tx, loanID)
 	if err != nil {
@@ -278,8 +471,20 @@ func (s *LoanService) processInvestmentTx(tx *sql.Tx, loanID uuid.UUID, req *mod
 		return nil, err
 	}
 
+	// The reservation is claimed under the loan lock, so it can be converted into one investment only
+	if req.ReservationID != nil {
+		if err := s.claimReservation(loanID, req, undo); err != nil {
+			return nil, err
+		}
+	}
+
+	reserved, err := s.getReservedAmount(loanID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Use the existing ValidateInvestmentAmount for business rule validation
-	if err := loan.ValidateInvestmentAmount(req.Amount); err != nil {
+	if err := loan.ValidateInvestmentAmount(req.Amount, reserved); err != nil {
 		s.logger.Error("Investment amount validation failed", map[string]interface{}{
 			"error":   err.Error(),
 			"loan_id": loanID.String(),
@@ -289,6 +494,39 @@ func (s *LoanService) processInvestmentTx(tx *sql.Tx, loanID uuid.UUID, req *mod
 		return nil, fmt.Errorf("investment validation failed: %w", err)
 	}
 
+	// Lock the investor wallet so concurrent investments cannot spend the same available balance
+	wallet, err := s.walletRepo.LockWallet(tx, req.InvestorID)
+	if err != nil {
+		s.logger.Error("Failed to lock investor wallet", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": req.InvestorID.String(),
+		})
+		return nil, err
+	}
+
+	// Limits are checked under the wallet lock so concurrent investments by the same investor see each other
+	if err := s.checkInvestmentLimitsTx(tx, loan, req.InvestorID, req.Amount); err != nil {
+		return nil, err
+	}
+
+	balance, err := s.walletRepo.GetWalletBalance(tx, req.InvestorID)
+	if err != nil {
+		s.logger.Error("Failed to get investor wallet balance", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": req.InvestorID.String(),
+		})
+		return nil, err
+	}
+
+	if !balance.CanCover(req.Amount) {
+		s.logger.Error("Insufficient wallet balance for investment", map[string]interface{}{
+			"investor_id":       req.InvestorID.String(),
+			"available_balance": balance.AvailableBalance,
+			"amount":            req.Amount,
+		})
+		return nil, fmt.Errorf("insufficient wallet balance: available %.2f, required %.2f", balance.AvailableBalance, req.Amount)
+	}
+
 	// Create investment record first
 	investment := &models.Investment{
 		BaseModel: models.BaseModel{
@@ -311,6 +549,24 @@ func (s *LoanService) processInvestmentTx(tx *sql.Tx, loanID uuid.UUID, req *mod
 		return nil, err
 	}
 
+	// Hold the invested funds until the loan is disbursed, cancelled or expires
+	_, err = s.walletRepo.CreateWalletTransaction(tx, &models.WalletTransaction{
+		WalletID:        wallet.ID,
+		InvestorID:      req.InvestorID,
+		TransactionType: models.WalletTransactionHold,
+		Amount:          req.Amount,
+		ReferenceType:   models.WalletReferenceInvestment,
+		ReferenceID:     &investment.ID,
+		Description:     fmt.Sprintf("Hold for investment in loan #%s", loanID.String()[:8]),
+	})
+	if err != nil {
+		s.logger.Error("Failed to place wallet hold for investment", map[string]interface{}{
+			"error":         err.Error(),
+			"investment_id": investment.ID.String(),
+		})
+		return nil, err
+	}
+
 	// Use atomic update to prevent race conditions
 	// This is the key fix - combines validation and update in one atomic operation
 	updatedLoan, err := s.loanRepo.UpdateLoanTotalInvested(tx, loanID, req.Amount)
@@ -342,11 +598,13 @@ func (s *LoanService) processInvestmentTx(tx *sql.Tx, loanID uuid.UUID, req *mod
 			return nil, err
 		}
 
-		// Update loan agreement letter URL
-		agreementURL, err := s.mockAgreementLetterURL(newState)
+		// Generate and store the loan agreement letter
+		newState.Borrower = loan.Borrower
+		agreementURL, err := s.generateAgreementLetter(tx, newState, undo)
 		if err != nil {
-			s.logger.Error("Failed to generate loan agreement letter URL", map[string]interface{}{
-				"error": err.Error(),
+			s.logger.Error("Failed to generate loan agreement letter", map[string]interface{}{
+				"error":   err.Error(),
+				"loan_id": loanID.String(),
 			})
 			return nil, err
 		}
@@ -411,6 +669,17 @@ func (s *LoanService) processDisbursementTx(tx *sql.Tx, loanID uuid.UUID, req *m
 		return nil, err
 	}
 
+	// The borrower must have signed the agreement through the e-signature provider
+	signature, err := s.signatureRepo.GetOpenAgreementSignature(tx, loanID)
+	if err != nil {
+		s.logger.Error("Failed to get agreement signature", map[string]interface{}{
+			"error":   err.Error(),
+			"loan_id": loanID.String(),
+		})
+		return nil, err
+	}
+	loan.Signature = signature
+
 	// Validate state transition to disbursed
 	if err := loan.ValidateStateTransition(models.LoanStateDisbursed); err != nil {
 		s.logger.Error("State transition validation failed", map[string]interface{}{
@@ -438,12 +707,18 @@ func (s *LoanService) processDisbursementTx(tx *sql.Tx, loanID uuid.UUID, req *m
 		LoanID:                  loanID,
 		FieldOfficerID:          req.FieldOfficerID,
 		DisbursementDate:        req.DisbursementDate,
-		SignedAgreementURL:      req.SignedAgreementURL,
-		SignedAgreementFileType: models.FileType(req.SignedAgreementFileType),
+		SignedAgreementURL:      signature.SignedFileURL,
+		SignedAgreementFileType: signature.SignedFileType,
 		DisbursedAmount:         req.DisbursedAmount,
 		Notes:                   req.Notes,
 	}
 
+	if req.FileID != nil {
+		if _, err := s.fileService.AttachFile(tx, *req.FileID, "disbursement", disbursement.ID, models.FileTypePDF, models.FileTypeJPEG, models.FileTypePNG); err != nil {
+			return nil, err
+		}
+	}
+
 	disbursement, err = s.loanRepo.CreateDisbursement(tx, disbursement)
 	if err != nil {
 		s.logger.Error("Failed to create disbursement", map[string]interface{}{
@@ -470,13 +745,24 @@ func (s *LoanService) processDisbursementTx(tx *sql.Tx, loanID uuid.UUID, req *m
 		return nil, err
 	}
 
+	// Move the held investor funds out of their wallets into the disbursed loan
+	captured, err := s.walletRepo.CaptureLoanHolds(tx, loanID, "Loan disbursed")
+	if err != nil {
+		s.logger.Error("Failed to capture investor wallet holds", map[string]interface{}{
+			"error":   err.Error(),
+			"loan_id": loanID.String(),
+		})
+		return nil, err
+	}
+
 	s.logger.Info("Loan disbursed on process disbursement", map[string]interface{}{
-		"loan_id":   loanID.String(),
-		"new_state": newState.State.String(),
+		"loan_id":        loanID.String(),
+		"new_state":      newState.State.String(),
+		"captured_holds": captured,
 	})
 
 	// Process payment for the disbursed amount
-	paymentResult, err := s.paymentAdapter.ProcessPayment(req.DisbursedAmount, "disbursement_token_"+loanID.String())
+	paymentResult, err := s.paymentAdapter.ProcessPayment(req.DisbursedAmount, "disbursement_token_"+loanID.String(), disbursement.ID.String())
 	if err != nil {
 		s.logger.Error("Failed to process payment for disbursement", map[string]interface{}{
 			"error":            err.Error(),
@@ -493,6 +779,18 @@ func (s *LoanService) processDisbursementTx(tx *sql.Tx, loanID uuid.UUID, req *m
 		"amount":         req.DisbursedAmount,
 	})
 
+	// Keep the provider reference so the disbursement can be reconciled against the settlement report
+	err = s.loanRepo.UpdateDisbursementTransactionID(tx, disbursement.ID, paymentResult.TransactionID)
+	if err != nil {
+		s.logger.Error("Failed to store disbursement transaction ID", map[string]interface{}{
+			"error":          err.Error(),
+			"loan_id":        loanID.String(),
+			"transaction_id": paymentResult.TransactionID,
+		})
+		return nil, err
+	}
+	disbursement.TransactionID = paymentResult.TransactionID
+
 	return &models.DisbursementResponse{
 		ID:                      disbursement.ID,
 		LoanID:                  disbursement.LoanID,
@@ -501,26 +799,534 @@ func (s *LoanService) processDisbursementTx(tx *sql.Tx, loanID uuid.UUID, req *m
 		SignedAgreementURL:      disbursement.SignedAgreementURL,
 		SignedAgreementFileType: disbursement.SignedAgreementFileType,
 		DisbursedAmount:         disbursement.DisbursedAmount,
+		TransactionID:           disbursement.TransactionID,
 		Notes:                   disbursement.Notes,
 		CreatedAt:               disbursement.CreatedAt,
 		UpdatedAt:               disbursement.UpdatedAt,
 	}, nil
 }
 
-// mockAgreementLetterURL generates a URL for the loan agreement letter
-func (s *LoanService) mockAgreementLetterURL(loan *models.Loan) (string, error) {
-	// Generate a unique filename for the agreement letter
-	filename := fmt.Sprintf("agreement_loan_%s.pdf", loan.ID.String()[:8])
+func (s *LoanService) ProcessCancelLoan(loanID uuid.UUID, req *models.CancelLoanRequest) (*models.LoanSummaryResponse, error) {
+	s.logger.Info("Cancelling loan", map[string]interface{}{"loan_id": loanID, "request": req})
+
+	// Use transaction to ensure data consistency
+	var result *models.LoanSummaryResponse
+	err := s.withTransaction(func(tx *sql.Tx) error {
+		var cancelErr error
+		result, cancelErr = s.processCancelLoanTx(tx, loanID, req)
+		return cancelErr
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyLoanChanged(loanID)
+	return result, nil
+}
+
+func (s *LoanService) processCancelLoanTx(tx *sql.Tx, loanID uuid.UUID, req *models.CancelLoanRequest) (*models.LoanSummaryResponse, error) {
+	loan, err := s.closeLoanTx(tx, loanID, models.LoanStateCancelled, req.ChangedBy, req.ChangeReason)
+	if err != nil {
+		return nil, err
+	}
 
-	// Construct the URL - this could be a template URL or generated based on configuration
-	// For now, using a simple template URL structure
-	agreementURL := fmt.Sprintf("https://storage.example.com/agreements?loan_id=%s&filename=%s&token=%s", loan.ID.String(), filename, "mock-token-123")
+	return &models.LoanSummaryResponse{
+		ID:                  loan.ID,
+		BorrowerName:        loan.Borrower.FullName(),
+		PrincipalAmount:     loan.PrincipalAmount,
+		InterestRate:        loan.InterestRate,
+		ROI:                 loan.ROI,
+		State:               loan.State,
+		TotalInvested:       loan.TotalInvested,
+		RemainingInvestment: loan.RemainingInvestmentAmount(),
+		CreatedAt:           loan.CreatedAt,
+		UpdatedAt:           loan.UpdatedAt,
+	}, nil
+}
 
-	s.logger.Info("Generated agreement letter URL", map[string]interface{}{
-		"loan_id":       loan.ID.String(),
-		"filename":      filename,
-		"agreement_url": agreementURL,
+func (s *LoanService) ProcessExpireLoan(loanID uuid.UUID) error {
+	s.logger.Info("Expiring loan", map[string]interface{}{"loan_id": loanID})
+
+	err := s.withTransaction(func(tx *sql.Tx) error {
+		_, closeErr := s.closeLoanTx(tx, loanID, models.LoanStateExpired, uuid.MustParse(constant.SystemEmployeeID), "Funding period ended")
+		return closeErr
 	})
+	if err != nil {
+		return err
+	}
+
+	s.notifyLoanChanged(loanID)
+	return nil
+}
+
+// closeLoanTx moves a loan into a final non-disbursed state and releases every investor hold placed for it
+func (s *LoanService) closeLoanTx(tx *sql.Tx, loanID uuid.UUID, target models.LoanState, changedBy uuid.UUID, reason string) (*models.Loan, error) {
+	// Lock the loan so a concurrent investment cannot complete funding while we close it
+	if err := s.loanRepo.LockLoan(tx, loanID); err != nil {
+		s.logger.Error("Failed to lock loan", map[string]interface{}{
+			"error":   err.Error(),
+			"loan_id": loanID.String(),
+		})
+		return nil, err
+	}
 
-	return agreementURL, nil
+	loan, err := s.loanRepo.GetLoanByID(tx, loanID)
+	if err != nil {
+		s.logger.Error("Failed to get loan by ID", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+
+	if err := loan.ValidateStateTransition(target); err != nil {
+		s.logger.Error("State transition validation failed", map[string]interface{}{
+			"error":         err.Error(),
+			"loan_id":       loanID.String(),
+			"current_state": loan.State.String(),
+			"target_state":  target.String(),
+		})
+		return nil, fmt.Errorf("loan %s validation failed: %w", target, err)
+	}
+
+	prevState := loan.State
+	updatedLoan, err := s.loanRepo.UpdateLoanState(tx, loanID, target)
+	if err != nil {
+		s.logger.Error("Failed to update loan state", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+
+	_, err = s.loanRepo.RecordLoanStateHistory(tx, prevState, updatedLoan, changedBy, reason)
+	if err != nil {
+		s.logger.Error("Failed to record loan state history", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+
+	released, err := s.walletRepo.ReleaseLoanHolds(tx, loanID, fmt.Sprintf("Loan %s: %s", target, reason))
+	if err != nil {
+		s.logger.Error("Failed to release investor wallet holds", map[string]interface{}{
+			"error":   err.Error(),
+			"loan_id": loanID.String(),
+		})
+		return nil, err
+	}
+
+	updatedLoan.Borrower = loan.Borrower
+
+	s.logger.Info("Loan closed", map[string]interface{}{
+		"loan_id":        loanID.String(),
+		"new_state":      updatedLoan.State.String(),
+		"released_holds": released,
+	})
+
+	return updatedLoan, nil
+}
+
+// claimReservation takes the reservation the investment converts, putting it back if the investment is rolled back.
+// A reservation that was already converted, has expired or does not match the investment is refused.
+func (s *LoanService) claimReservation(loanID uuid.UUID, req *models.CreateInvestmentRequest, undo *rollbackActions) error {
+	if s.reservationRepo == nil {
+		return fmt.Errorf("investment reservations are not available")
+	}
+
+	reservation, err := s.reservationRepo.ClaimReservation(loanID, *req.ReservationID)
+	if err != nil {
+		s.logger.Error("Failed to claim reservation", map[string]interface{}{
+			"error":          err.Error(),
+			"reservation_id": req.ReservationID.String(),
+		})
+		return err
+	}
+	if reservation == nil {
+		return fmt.Errorf("%w: %s", ErrReservationNotFound, req.ReservationID)
+	}
+
+	undo.add(func() {
+		if err := s.reservationRepo.CreateReservation(reservation); err != nil {
+			s.logger.Error("Failed to restore reservation of rolled back investment", map[string]interface{}{
+				"error":          err.Error(),
+				"reservation_id": reservation.ID.String(),
+			})
+		}
+	})
+
+	if reservation.InvestorID != req.InvestorID || reservation.Amount != req.Amount {
+		return fmt.Errorf("reservation %s is for %.2f by investor %s", reservation.ID, reservation.Amount, reservation.InvestorID)
+	}
+
+	return nil
+}
+
+// getReservedAmount sums the other active reservations on the loan; without a reservation store nothing is reserved
+func (s *LoanService) getReservedAmount(loanID uuid.UUID) (float64, error) {
+	if s.reservationRepo == nil {
+		return 0, nil
+	}
+
+	reserved, err := s.reservationRepo.GetReservedAmount(loanID)
+	if err != nil {
+		s.logger.Error("Failed to get reserved amount", map[string]interface{}{
+			"error":   err.Error(),
+			"loan_id": loanID.String(),
+		})
+		return 0, err
+	}
+
+	return reserved, nil
+}
+
+// checkInvestmentLimitsTx enforces the minimum ticket, single loan share and total exposure limits for the investor
+func (s *LoanService) checkInvestmentLimitsTx(tx *sql.Tx, loan *models.Loan, investorID uuid.UUID, amount float64) error {
+	return validateInvestmentLimitsTx(tx, s.loanRepo, s.limits, s.logger, loan, investorID, amount)
+}
+
+// validateInvestmentLimitsTx checks an amount the investor is about to take on in the loan, whether invested directly or
+// bought from another investor, against the limits with the investor's override applied
+func validateInvestmentLimitsTx(
+	tx *sql.Tx,
+	loanRepo repositories.LoanRepositoryInterface,
+	limits models.InvestmentLimits,
+	log logger.LoggerInterface,
+	loan *models.Loan,
+	investorID uuid.UUID,
+	amount float64,
+) error {
+	override, err := loanRepo.GetInvestorInvestmentLimit(tx, investorID)
+	if err != nil {
+		log.Error("Failed to get investor investment limit", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": investorID.String(),
+		})
+		return err
+	}
+
+	exposure, err := loanRepo.GetInvestorExposure(tx, investorID, loan.ID)
+	if err != nil {
+		log.Error("Failed to get investor exposure", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": investorID.String(),
+		})
+		return err
+	}
+
+	if err := limits.WithOverride(override).Validate(amount, loan, exposure); err != nil {
+		log.Error("Investment limit exceeded", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": investorID.String(),
+			"loan_id":     loan.ID.String(),
+			"amount":      amount,
+		})
+		return err
+	}
+
+	return nil
+}
+
+// GetInvestorLimits returns the limits applied to the investor together with their override, if any
+func (s *LoanService) GetInvestorLimits(investorID uuid.UUID) (*models.InvestorLimitsResponse, error) {
+	if _, err := s.loanRepo.GetInvestorByID(investorID); err != nil {
+		s.logger.Error("Failed to get investor by ID", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": investorID.String(),
+		})
+		return nil, err
+	}
+
+	override, err := s.loanRepo.GetInvestorInvestmentLimit(nil, investorID)
+	if err != nil {
+		s.logger.Error("Failed to get investor investment limit", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": investorID.String(),
+		})
+		return nil, err
+	}
+
+	return &models.InvestorLimitsResponse{
+		InvestorID: investorID,
+		Effective:  s.limits.WithOverride(override),
+		Override:   override,
+	}, nil
+}
+
+// SetInvestorLimits replaces the investor specific limit override
+func (s *LoanService) SetInvestorLimits(investorID uuid.UUID, req *models.SetInvestorLimitsRequest) (*models.InvestorLimitsResponse, error) {
+	s.logger.Info("Setting investor investment limits", map[string]interface{}{"investor_id": investorID, "request": req})
+
+	if _, err := s.loanRepo.GetInvestorByID(investorID); err != nil {
+		s.logger.Error("Failed to get investor by ID", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": investorID.String(),
+		})
+		return nil, err
+	}
+
+	override, err := s.loanRepo.UpsertInvestorInvestmentLimit(nil, &models.InvestorInvestmentLimit{
+		InvestorID:       investorID,
+		MaxLoanShare:     req.MaxLoanShare,
+		MaxTotalExposure: req.MaxTotalExposure,
+		MinTicket:        req.MinTicket,
+		UpdatedBy:        req.UpdatedBy,
+	})
+	if err != nil {
+		s.logger.Error("Failed to save investor investment limit", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": investorID.String(),
+		})
+		return nil, err
+	}
+
+	return &models.InvestorLimitsResponse{
+		InvestorID: investorID,
+		Effective:  s.limits.WithOverride(override),
+		Override:   override,
+	}, nil
+}
+
+// GetInvestorPortfolio lists the investor's investments with totals per loan state and yield figures
+func (s *LoanService) GetInvestorPortfolio(investorID uuid.UUID) (*models.PortfolioResponse, error) {
+	if _, err := s.loanRepo.GetInvestorByID(investorID); err != nil {
+		s.logger.Error("Failed to get investor by ID", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": investorID.String(),
+		})
+		return nil, err
+	}
+
+	positions, err := s.loanRepo.GetInvestorPortfolio(investorID)
+	if err != nil {
+		s.logger.Error("Failed to get investor portfolio", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": investorID.String(),
+		})
+		return nil, err
+	}
+
+	portfolio := &models.PortfolioResponse{
+		InvestorID:    investorID,
+		Investments:   positions,
+		TotalsByState: make(map[models.LoanState]*models.PortfolioTotals),
+	}
+	if portfolio.Investments == nil {
+		portfolio.Investments = []*models.PortfolioPosition{}
+	}
+
+	var realizedReturn float64
+	for _, position := range positions {
+		totals, ok := portfolio.TotalsByState[position.LoanState]
+		if !ok {
+			totals = &models.PortfolioTotals{}
+			portfolio.TotalsByState[position.LoanState] = totals
+		}
+		totals.Add(position)
+
+		if position.IsLive() {
+			portfolio.Totals.Add(position)
+			realizedReturn += position.RealizedReturn()
+		}
+	}
+
+	if portfolio.Totals.Invested > 0 {
+		portfolio.ExpectedYield = (portfolio.Totals.ExpectedReturn - portfolio.Totals.Invested) / portfolio.Totals.Invested
+		portfolio.RealizedYield = realizedReturn / portfolio.Totals.Invested
+	}
+
+	return portfolio, nil
+}
+
+func (s *LoanService) ProcessCancelInvestment(loanID, investmentID uuid.UUID, req *models.CancelInvestmentRequest) (*models.InvestmentCancellationResponse, error) {
+	s.logger.Info("Cancelling investment", map[string]interface{}{"loan_id": loanID, "investment_id": investmentID, "request": req})
+
+	// Use transaction to ensure data consistency
+	var result *models.InvestmentCancellationResponse
+	err := s.withTransaction(func(tx *sql.Tx) error {
+		var cancelErr error
+		result, cancelErr = s.processCancelInvestmentTx(tx, loanID, investmentID, req)
+		return cancelErr
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyLoanChanged(loanID)
+	return result, nil
+}
+
+func (s *LoanService) processCancelInvestmentTx(tx *sql.Tx, loanID, investmentID uuid.UUID, req *models.CancelInvestmentRequest) (*models.InvestmentCancellationResponse, error) {
+	// Lock the loan so the cancellation cannot race a closing state change
+	if err := s.loanRepo.LockLoan(tx, loanID); err != nil {
+		s.logger.Error("Failed to lock loan", map[string]interface{}{
+			"error":   err.Error(),
+			"loan_id": loanID.String(),
+		})
+		return nil, err
+	}
+
+	loan, err := s.loanRepo.GetLoanByID(tx, loanID)
+	if err != nil {
+		s.logger.Error("Failed to get loan by ID", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+
+	if loan.State != models.LoanStateApproved {
+		return nil, fmt.Errorf("investments can only be cancelled while the loan is approved, current state: %s", loan.State)
+	}
+
+	investment, err := s.loanRepo.GetInvestmentByID(tx, investmentID)
+	if err != nil {
+		s.logger.Error("Failed to get investment by ID", map[string]interface{}{
+			"error":         err.Error(),
+			"investment_id": investmentID.String(),
+		})
+		return nil, err
+	}
+
+	if investment.LoanID != loanID || investment.InvestorID != req.InvestorID {
+		return nil, fmt.Errorf("investment %s does not belong to investor %s in loan %s", investmentID, req.InvestorID, loanID)
+	}
+
+	if err := s.loanRepo.SoftDeleteInvestment(tx, investmentID); err != nil {
+		s.logger.Error("Failed to soft delete investment", map[string]interface{}{
+			"error":         err.Error(),
+			"investment_id": investmentID.String(),
+		})
+		return nil, err
+	}
+
+	updatedLoan, err := s.loanRepo.DecrementLoanTotalInvested(tx, loanID, investment.Amount)
+	if err != nil {
+		s.logger.Error("Failed to decrement loan total invested atomically", map[string]interface{}{
+			"error":   err.Error(),
+			"loan_id": loanID.String(),
+			"amount":  investment.Amount,
+		})
+		return nil, fmt.Errorf("investment cancellation failed: %w", err)
+	}
+
+	cancellation, err := s.loanRepo.CreateInvestmentCancellation(tx, &models.InvestmentCancellation{
+		InvestmentID: investmentID,
+		LoanID:       loanID,
+		InvestorID:   investment.InvestorID,
+		Amount:       investment.Amount,
+		Reason:       req.Reason,
+	})
+	if err != nil {
+		s.logger.Error("Failed to record investment cancellation", map[string]interface{}{
+			"error":         err.Error(),
+			"investment_id": investmentID.String(),
+		})
+		return nil, err
+	}
+
+	released, err := s.walletRepo.ReleaseInvestmentHold(tx, investmentID, "Investment cancelled")
+	if err != nil {
+		s.logger.Error("Failed to release investment wallet hold", map[string]interface{}{
+			"error":         err.Error(),
+			"investment_id": investmentID.String(),
+		})
+		return nil, err
+	}
+
+	s.logger.Info("Investment cancelled", map[string]interface{}{
+		"investment_id":  investmentID.String(),
+		"loan_id":        loanID.String(),
+		"total_invested": updatedLoan.TotalInvested,
+		"released_holds": released,
+	})
+
+	return &models.InvestmentCancellationResponse{
+		InvestmentID:        investmentID,
+		LoanID:              loanID,
+		InvestorID:          investment.InvestorID,
+		Amount:              investment.Amount,
+		Reason:              cancellation.Reason,
+		LoanTotalInvested:   updatedLoan.TotalInvested,
+		RemainingInvestment: updatedLoan.RemainingInvestmentAmount(),
+		CancelledAt:         cancellation.CreatedAt,
+	}, nil
+}
+
+// generateAgreementLetter renders the loan agreement with everyone who funded the loan and stores it, returning its URL.
+// Each investor also gets their own agreement, stored on their investment. The stored documents are deleted through
+// undo if the transaction does not commit.
+func (s *LoanService) generateAgreementLetter(tx *sql.Tx, loan *models.Loan, undo *rollbackActions) (string, error) {
+	investments, err := s.loanRepo.GetInvestmentsByLoanID(tx, loan.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get loan investments: %w", err)
+	}
+
+	data := &models.LoanAgreementData{
+		Loan:        loan,
+		Borrower:    loan.Borrower,
+		GeneratedAt: time.Now(),
+	}
+	for _, investment := range investments {
+		investor, err := s.loanRepo.GetInvestorByID(investment.InvestorID)
+		if err != nil {
+			return "", fmt.Errorf("failed to get investor %s: %w", investment.InvestorID, err)
+		}
+
+		agreementInvestment := &models.AgreementInvestment{
+			InvestmentID:   investment.ID,
+			InvestorCode:   investor.InvestorCode,
+			InvestorName:   investor.Name,
+			Amount:         investment.Amount,
+			ExpectedReturn: investment.ExpectedReturn,
+			InvestmentDate: investment.InvestmentDate,
+		}
+		data.Investments = append(data.Investments, agreementInvestment)
+
+		if err := s.generateInvestmentAgreement(tx, loan, agreementInvestment, data.GeneratedAt, undo); err != nil {
+			return "", err
+		}
+	}
+
+	doc, err := s.documentAdapter.GenerateLoanAgreement(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to render loan agreement: %w", err)
+	}
+
+	upload, err := s.fileService.StoreFile(tx, doc.Content, doc.FileName, doc.ContentType, "loan", loan.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to store loan agreement: %w", err)
+	}
+	undo.add(func() { s.fileService.DeleteStoredFile(upload) })
+
+	s.logger.Info("Generated agreement letter", map[string]interface{}{
+		"loan_id":          loan.ID.String(),
+		"filename":         doc.FileName,
+		"template_version": doc.TemplateVersion,
+		"agreement_url":    upload.FileURL,
+	})
+
+	return upload.FileURL, nil
+}
+
+// generateInvestmentAgreement renders and stores one investor's agreement and records its URL on the investment
+func (s *LoanService) generateInvestmentAgreement(tx *sql.Tx, loan *models.Loan, investment *models.AgreementInvestment, generatedAt time.Time, undo *rollbackActions) error {
+	doc, err := s.documentAdapter.GenerateInvestmentAgreement(&models.InvestmentAgreementData{
+		Loan:        loan,
+		Borrower:    loan.Borrower,
+		Investment:  investment,
+		GeneratedAt: generatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to render agreement for investment %s: %w", investment.InvestmentID, err)
+	}
+
+	upload, err := s.fileService.StoreFile(tx, doc.Content, doc.FileName, doc.ContentType, "investment", investment.InvestmentID)
+	if err != nil {
+		return fmt.Errorf("failed to store agreement for investment %s: %w", investment.InvestmentID, err)
+	}
+	undo.add(func() { s.fileService.DeleteStoredFile(upload) })
+
+	if err := s.loanRepo.UpdateInvestmentAgreementURL(tx, investment.InvestmentID, upload.FileURL); err != nil {
+		return fmt.Errorf("failed to update agreement URL for investment %s: %w", investment.InvestmentID, err)
+	}
+
+	return nil
 }
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"loan-service/internal/models"
+	"loan-service/internal/repositories"
+	"loan-service/pkg/config"
+	"loan-service/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// marketplaceLoansCacheKey holds every open listing; sorting happens after the cache so one key serves all sort orders
+const marketplaceLoansCacheKey = "marketplace:loans"
+
+type MarketplaceService struct {
+	loanRepo repositories.LoanRepositoryInterface
+	cache    CacheInterface
+	cacheTTL time.Duration
+	logger   logger.LoggerInterface
+}
+
+// NewMarketplaceService creates the marketplace service; a nil cache reads every listing straight from the database
+func NewMarketplaceService(
+	loanRepo repositories.LoanRepositoryInterface,
+	cache CacheInterface,
+	cfg config.MarketplaceConfig,
+	logger logger.LoggerInterface,
+) MarketplaceServiceInterface {
+	return &MarketplaceService{
+		loanRepo: loanRepo,
+		cache:    cache,
+		cacheTTL: cfg.CacheTTL,
+		logger:   logger,
+	}
+}
+
+// GetLoans lists the approved loans that still need funding in the requested order
+func (s *MarketplaceService) GetLoans(query models.MarketplaceLoansQuery) ([]*models.MarketplaceLoan, error) {
+	listings, err := s.getListings()
+	if err != nil {
+		return nil, err
+	}
+
+	query = query.WithDefaults()
+	models.SortMarketplaceLoans(listings, query.SortBy, query.Order)
+
+	return listings, nil
+}
+
+// OnLoanChanged drops the cached listings so the next request sees the change
+func (s *MarketplaceService) OnLoanChanged(loanID uuid.UUID) {
+	if s.cache == nil {
+		return
+	}
+
+	if err := s.cache.DeleteCache(context.Background(), marketplaceLoansCacheKey); err != nil {
+		s.logger.Error("Failed to invalidate marketplace cache", map[string]interface{}{
+			"error":   err.Error(),
+			"loan_id": loanID.String(),
+		})
+	}
+}
+
+func (s *MarketplaceService) getListings() ([]*models.MarketplaceLoan, error) {
+	ctx := context.Background()
+
+	listings := []*models.MarketplaceLoan{}
+	if s.cache != nil {
+		// A miss and an unreachable cache look the same here; both fall back to the database
+		if err := s.cache.GetCacheAs(ctx, marketplaceLoansCacheKey, &listings); err == nil {
+			return listings, nil
+		}
+	}
+
+	loans, err := s.loanRepo.GetMarketplaceLoans()
+	if err != nil {
+		s.logger.Error("Failed to get marketplace loans", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+
+	for _, loan := range loans {
+		if loan.RemainingInvestmentAmount() <= 0 {
+			continue
+		}
+		listings = append(listings, models.NewMarketplaceLoan(loan))
+	}
+
+	if s.cache != nil {
+		if err := s.cache.SetCache(ctx, marketplaceLoansCacheKey, listings, s.cacheTTL); err != nil {
+			s.logger.Warn("Failed to cache marketplace loans", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	return listings, nil
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"loan-service/internal/models"
+	"loan-service/pkg/config"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockCache struct {
+	mock.Mock
+}
+
+func (m *MockCache) SetCache(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	args := m.Called(ctx, key, value, expiration)
+	return args.Error(0)
+}
+
+func (m *MockCache) GetCacheAs(ctx context.Context, key string, target interface{}) error {
+	args := m.Called(ctx, key, target)
+	return args.Error(0)
+}
+
+func (m *MockCache) DeleteCache(ctx context.Context, key string) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func setupTestMarketplaceService() (*MarketplaceService, *MockLoanRepository, *MockCache) {
+	mockRepo := &MockLoanRepository{}
+	mockCache := &MockCache{}
+
+	service := NewMarketplaceService(mockRepo, mockCache, config.MarketplaceConfig{CacheTTL: time.Minute}, &TestLogger{}).(*MarketplaceService)
+
+	return service, mockRepo, mockCache
+}
+
+func createTestMarketplaceLoan(roi, principal, totalInvested float64, approvedAt time.Time) *models.Loan {
+	loan := createTestLoan(uuid.New(), models.LoanStateApproved, totalInvested)
+	loan.ROI = roi
+	loan.PrincipalAmount = principal
+	loan.TenorMonths = 12
+	loan.Borrower.RiskGrade = "B"
+	loan.Approval = &models.Approval{ApprovalDate: approvedAt}
+	return loan
+}
+
+func TestMarketplaceService_GetLoans_CacheMiss(t *testing.T) {
+	service, mockRepo, mockCache := setupTestMarketplaceService()
+
+	now := time.Now()
+	older := createTestMarketplaceLoan(0.12, 10000.0, 2500.0, now.Add(-48*time.Hour))
+	newer := createTestMarketplaceLoan(0.15, 20000.0, 0.0, now.Add(-time.Hour))
+	funded := createTestMarketplaceLoan(0.20, 5000.0, 5000.0, now)
+
+	mockCache.On("GetCacheAs", mock.Anything, marketplaceLoansCacheKey, mock.Anything).Return(errors.New("redis: nil"))
+	mockRepo.On("GetMarketplaceLoans").Return([]*models.Loan{funded, newer, older}, nil)
+	mockCache.On("SetCache", mock.Anything, marketplaceLoansCacheKey, mock.Anything, time.Minute).Return(nil)
+
+	listings, err := service.GetLoans(models.MarketplaceLoansQuery{})
+
+	assert.NoError(t, err)
+	if assert.Len(t, listings, 2) {
+		// Newest listing first by default
+		assert.Equal(t, newer.ID, listings[0].LoanID)
+		assert.Equal(t, older.ID, listings[1].LoanID)
+		assert.Equal(t, 7500.0, listings[1].RemainingAmount)
+		assert.Equal(t, 0.25, listings[1].FundingProgress)
+		assert.Equal(t, "B", listings[1].RiskGrade)
+	}
+
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestMarketplaceService_GetLoans_CacheHit(t *testing.T) {
+	service, mockRepo, mockCache := setupTestMarketplaceService()
+
+	cached := []*models.MarketplaceLoan{
+		{LoanID: uuid.New(), ROI: 0.10, RemainingAmount: 9000.0},
+		{LoanID: uuid.New(), ROI: 0.18, RemainingAmount: 1000.0},
+		{LoanID: uuid.New(), ROI: 0.14, RemainingAmount: 5000.0},
+	}
+
+	mockCache.On("GetCacheAs", mock.Anything, marketplaceLoansCacheKey, mock.Anything).
+		Run(func(args mock.Arguments) {
+			*args.Get(2).(*[]*models.MarketplaceLoan) = cached
+		}).
+		Return(nil)
+
+	listings, err := service.GetLoans(models.MarketplaceLoansQuery{SortBy: models.MarketplaceSortROI})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{0.18, 0.14, 0.10}, []float64{listings[0].ROI, listings[1].ROI, listings[2].ROI})
+
+	listings, err = service.GetLoans(models.MarketplaceLoansQuery{SortBy: models.MarketplaceSortRemainingAmount, Order: models.SortOrderAsc})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{1000.0, 5000.0, 9000.0}, []float64{listings[0].RemainingAmount, listings[1].RemainingAmount, listings[2].RemainingAmount})
+
+	mockRepo.AssertNotCalled(t, "GetMarketplaceLoans")
+}
+
+func TestMarketplaceService_OnLoanChanged_InvalidatesCache(t *testing.T) {
+	service, _, mockCache := setupTestMarketplaceService()
+
+	mockCache.On("DeleteCache", mock.Anything, marketplaceLoansCacheKey).Return(nil)
+
+	service.OnLoanChanged(uuid.New())
+
+	mockCache.AssertExpectations(t)
+}
@@ -0,0 +1,288 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"loan-service/internal/models"
+	"loan-service/internal/repositories"
+	"loan-service/pkg/adapters"
+	"loan-service/pkg/config"
+	"loan-service/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrEmailNotificationNotFound  = errors.New("email notification not found")
+	ErrEmailNotificationNotFailed = errors.New("email notification has not failed")
+	ErrEmailRetryNotAllowed       = errors.New("not allowed to retry email notifications")
+)
+
+const (
+	defaultEmailMaxAttempts     = 5
+	defaultEmailRetryBackoff    = time.Minute
+	defaultEmailMaxRetryBackoff = time.Hour
+
+	// emailSendLease is how long a claimed email is left to its sender before another run may pick it up
+	emailSendLease = 5 * time.Minute
+
+	// emailRetryBatchSize caps the emails one run of the retry job sends
+	emailRetryBatchSize = 100
+
+	// failedEmailListLimit caps the failed emails listed for staff
+	failedEmailListLimit = 200
+)
+
+type NotificationService struct {
+	notificationRepo repositories.NotificationRepositoryInterface
+	loanRepo         repositories.LoanRepositoryInterface
+	emailAdapter     adapters.EmailAdapterInterface
+	config           config.EmailConfig
+	logger           logger.LoggerInterface
+}
+
+func NewNotificationService(
+	notificationRepo repositories.NotificationRepositoryInterface,
+	loanRepo repositories.LoanRepositoryInterface,
+	emailAdapter adapters.EmailAdapterInterface,
+	cfg config.EmailConfig,
+	logger logger.LoggerInterface,
+) NotificationServiceInterface {
+	return &NotificationService{
+		notificationRepo: notificationRepo,
+		loanRepo:         loanRepo,
+		emailAdapter:     emailAdapter,
+		config:           cfg,
+		logger:           logger,
+	}
+}
+
+// SendEmail records the notification as pending and makes the first attempt to send it. A failed attempt is retried
+// by RetryDueEmails, so only a failure to record the email is returned; the notification's status tells whether it
+// went out.
+func (s *NotificationService) SendEmail(notification *models.EmailNotification) error {
+	now := time.Now()
+	leaseUntil := now.Add(emailSendLease)
+
+	notification.Status = models.EmailStatusPending
+	notification.Attempts = 0
+	notification.NextAttemptAt = &leaseUntil
+
+	if err := s.notificationRepo.CreateEmailNotification(notification); err != nil {
+		s.logger.Error("Failed to create email notification record", map[string]interface{}{
+			"error":      err.Error(),
+			"email_type": notification.EmailType,
+		})
+		return fmt.Errorf("failed to create email notification: %w", err)
+	}
+
+	s.attempt(notification, nil)
+	return nil
+}
+
+// RetryDueEmails sends the pending emails whose next attempt is due
+func (s *NotificationService) RetryDueEmails() error {
+	now := time.Now()
+	notifications, err := s.notificationRepo.ClaimDueEmailNotifications(now, now.Add(emailSendLease), emailRetryBatchSize)
+	if err != nil {
+		s.logger.Error("Failed to claim email notifications due for retry", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return err
+	}
+
+	sent := 0
+	for _, notification := range notifications {
+		if s.attempt(notification, nil) {
+			sent++
+		}
+	}
+
+	if len(notifications) > 0 {
+		s.logger.Info("Retried email notifications", map[string]interface{}{
+			"due":  len(notifications),
+			"sent": sent,
+		})
+	}
+	return nil
+}
+
+// RetryEmailNotification lets an employee send a failed email once more. If the attempt fails the email stays failed
+// with the new error.
+func (s *NotificationService) RetryEmailNotification(notificationID uuid.UUID, req *models.RetryEmailNotificationRequest) (*models.EmailNotificationResponse, error) {
+	s.logger.Info("Retrying failed email notification", map[string]interface{}{
+		"notification_id": notificationID.String(),
+		"requested_by":    req.RequestedBy.String(),
+	})
+
+	if err := s.validateEmployee(req.RequestedBy); err != nil {
+		return nil, err
+	}
+
+	notification, err := s.notificationRepo.ClaimFailedEmailNotification(notificationID, time.Now().Add(emailSendLease))
+	if err != nil {
+		s.logger.Error("Failed to claim failed email notification", map[string]interface{}{
+			"error":           err.Error(),
+			"notification_id": notificationID.String(),
+		})
+		return nil, err
+	}
+
+	if notification == nil {
+		existing, err := s.notificationRepo.GetEmailNotificationByID(notificationID)
+		if err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			return nil, ErrEmailNotificationNotFound
+		}
+		return nil, fmt.Errorf("%w: it is %s", ErrEmailNotificationNotFailed, existing.Status)
+	}
+
+	s.attempt(notification, &req.RequestedBy)
+	return emailNotificationResponse(notification), nil
+}
+
+// GetFailedEmailNotifications lists the emails that ran out of attempts for staff to review and retry
+func (s *NotificationService) GetFailedEmailNotifications(employeeID uuid.UUID) ([]*models.EmailNotificationResponse, error) {
+	if err := s.validateEmployee(employeeID); err != nil {
+		return nil, err
+	}
+
+	notifications, err := s.notificationRepo.GetFailedEmailNotifications(failedEmailListLimit)
+	if err != nil {
+		s.logger.Error("Failed to get failed email notifications", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+
+	responses := make([]*models.EmailNotificationResponse, 0, len(notifications))
+	for _, notification := range notifications {
+		responses = append(responses, emailNotificationResponse(notification))
+	}
+	return responses, nil
+}
+
+// attempt sends the email once and records the attempt with the state it leaves the notification in: sent, pending
+// until the backed-off next attempt, or failed once it is out of attempts. A retry an employee requested is not
+// retried again, so if it fails the email stays failed. It reports whether the email was sent.
+func (s *NotificationService) attempt(notification *models.EmailNotification, requestedBy *uuid.UUID) bool {
+	now := time.Now()
+	attempt := &models.EmailDeliveryAttempt{
+		NotificationID: notification.ID,
+		AttemptNumber:  notification.Attempts + 1,
+		AttemptedAt:    now,
+		RequestedBy:    requestedBy,
+	}
+
+	sendErr := s.emailAdapter.SendEmail(notification.Recipient, notification.Message())
+
+	notification.Attempts = attempt.AttemptNumber
+	notification.LastAttemptAt = &now
+	if sendErr == nil {
+		attempt.Succeeded = true
+		notification.Status = models.EmailStatusSent
+		notification.SentAt = &now
+		notification.NextAttemptAt = nil
+		notification.ErrorMessage = ""
+	} else {
+		attempt.ErrorMessage = sendErr.Error()
+		notification.ErrorMessage = sendErr.Error()
+		if requestedBy != nil || notification.Attempts >= s.maxAttempts() {
+			notification.Status = models.EmailStatusFailed
+			notification.NextAttemptAt = nil
+		} else {
+			next := now.Add(s.retryBackoff(notification.Attempts))
+			notification.Status = models.EmailStatusPending
+			notification.NextAttemptAt = &next
+		}
+
+		s.logger.Error("Failed to send email", map[string]interface{}{
+			"error":           sendErr.Error(),
+			"notification_id": notification.ID.String(),
+			"email_type":      notification.EmailType,
+			"attempt":         notification.Attempts,
+			"status":          notification.Status,
+		})
+	}
+
+	if err := s.notificationRepo.RecordEmailDeliveryAttempt(notification, attempt); err != nil {
+		s.logger.Error("Failed to record email delivery attempt", map[string]interface{}{
+			"error":           err.Error(),
+			"notification_id": notification.ID.String(),
+			"attempt":         attempt.AttemptNumber,
+		})
+	}
+
+	return sendErr == nil
+}
+
+// retryBackoff is the wait after the given failed attempt: the configured backoff, doubled after each further
+// attempt, up to the maximum
+func (s *NotificationService) retryBackoff(attempts int) time.Duration {
+	backoff := s.config.RetryBackoff
+	if backoff <= 0 {
+		backoff = defaultEmailRetryBackoff
+	}
+	maxBackoff := s.config.MaxRetryBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultEmailMaxRetryBackoff
+	}
+
+	for i := 1; i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		return maxBackoff
+	}
+	return backoff
+}
+
+func (s *NotificationService) maxAttempts() int {
+	if s.config.MaxAttempts > 0 {
+		return s.config.MaxAttempts
+	}
+	return defaultEmailMaxAttempts
+}
+
+func (s *NotificationService) validateEmployee(employeeID uuid.UUID) error {
+	employee, err := s.loanRepo.GetEmployeeByID(employeeID)
+	if err != nil {
+		s.logger.Error("Failed to get employee by ID", map[string]interface{}{
+			"error":       err.Error(),
+			"employee_id": employeeID.String(),
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrEmailRetryNotAllowed
+		}
+		return err
+	}
+
+	if !employee.IsActive {
+		return fmt.Errorf("%w: employee %s is not active", ErrEmailRetryNotAllowed, employee.EmployeeID)
+	}
+
+	return nil
+}
+
+func emailNotificationResponse(notification *models.EmailNotification) *models.EmailNotificationResponse {
+	return &models.EmailNotificationResponse{
+		ID:            notification.ID,
+		InvestorID:    notification.InvestorID,
+		LoanID:        notification.LoanID,
+		EmailType:     notification.EmailType,
+		Recipient:     notification.Recipient,
+		EmailSubject:  notification.EmailSubject,
+		Status:        notification.Status,
+		Attempts:      notification.Attempts,
+		ErrorMessage:  notification.ErrorMessage,
+		LastAttemptAt: notification.LastAttemptAt,
+		NextAttemptAt: notification.NextAttemptAt,
+		SentAt:        notification.SentAt,
+		CreatedAt:     notification.CreatedAt,
+	}
+}
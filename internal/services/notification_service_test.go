@@ -0,0 +1,282 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"loan-service/internal/models"
+	"loan-service/pkg/config"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockNotificationRepository struct {
+	mock.Mock
+}
+
+func (m *MockNotificationRepository) CreateEmailNotification(notification *models.EmailNotification) error {
+	args := m.Called(notification)
+	return args.Error(0)
+}
+
+func (m *MockNotificationRepository) GetEmailNotificationByID(notificationID uuid.UUID) (*models.EmailNotification, error) {
+	args := m.Called(notificationID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.EmailNotification), args.Error(1)
+}
+
+func (m *MockNotificationRepository) GetFailedEmailNotifications(limit int) ([]*models.EmailNotification, error) {
+	args := m.Called(limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.EmailNotification), args.Error(1)
+}
+
+func (m *MockNotificationRepository) ClaimDueEmailNotifications(now, leaseUntil time.Time, limit int) ([]*models.EmailNotification, error) {
+	args := m.Called(now, leaseUntil, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.EmailNotification), args.Error(1)
+}
+
+func (m *MockNotificationRepository) ClaimFailedEmailNotification(notificationID uuid.UUID, leaseUntil time.Time) (*models.EmailNotification, error) {
+	args := m.Called(notificationID, leaseUntil)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.EmailNotification), args.Error(1)
+}
+
+func (m *MockNotificationRepository) RecordEmailDeliveryAttempt(notification *models.EmailNotification, attempt *models.EmailDeliveryAttempt) error {
+	args := m.Called(notification, attempt)
+	return args.Error(0)
+}
+
+type MockNotificationService struct {
+	mock.Mock
+}
+
+func (m *MockNotificationService) SendEmail(notification *models.EmailNotification) error {
+	args := m.Called(notification)
+	return args.Error(0)
+}
+
+func (m *MockNotificationService) RetryDueEmails() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockNotificationService) GetFailedEmailNotifications(employeeID uuid.UUID) ([]*models.EmailNotificationResponse, error) {
+	args := m.Called(employeeID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.EmailNotificationResponse), args.Error(1)
+}
+
+func (m *MockNotificationService) RetryEmailNotification(notificationID uuid.UUID, req *models.RetryEmailNotificationRequest) (*models.EmailNotificationResponse, error) {
+	args := m.Called(notificationID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.EmailNotificationResponse), args.Error(1)
+}
+
+func setupTestNotificationService(cfg config.EmailConfig) (*NotificationService, *MockNotificationRepository, *MockLoanRepository, *MockEmailAdapter) {
+	mockNotifications := &MockNotificationRepository{}
+	mockRepo := &MockLoanRepository{}
+	mockEmail := &MockEmailAdapter{}
+
+	service := NewNotificationService(mockNotifications, mockRepo, mockEmail, cfg, &TestLogger{}).(*NotificationService)
+
+	return service, mockNotifications, mockRepo, mockEmail
+}
+
+func createTestEmailNotification(status string, attempts int) *models.EmailNotification {
+	return &models.EmailNotification{
+		BaseModel:    models.BaseModel{ID: uuid.New()},
+		InvestorID:   uuid.New(),
+		LoanID:       uuid.New(),
+		EmailType:    "agreement_notification",
+		Recipient:    "investor@example.com",
+		EmailSubject: "subject",
+		EmailBody:    "body",
+		EmailHTML:    "<p>body</p>",
+		Locale:       "id-ID",
+		Status:       status,
+		Attempts:     attempts,
+	}
+}
+
+func TestNotificationService_SendEmail_Sent(t *testing.T) {
+	service, mockNotifications, _, mockEmail := setupTestNotificationService(config.EmailConfig{})
+	notification := createTestEmailNotification("", 0)
+
+	mockNotifications.On("CreateEmailNotification", mock.MatchedBy(func(n *models.EmailNotification) bool {
+		return n.Status == models.EmailStatusPending && n.Attempts == 0 && n.NextAttemptAt != nil
+	})).Return(nil)
+	mockEmail.On("SendEmail", "investor@example.com", notification.Message()).Return(nil)
+	mockNotifications.On("RecordEmailDeliveryAttempt", notification, mock.MatchedBy(func(a *models.EmailDeliveryAttempt) bool {
+		return a.AttemptNumber == 1 && a.Succeeded && a.ErrorMessage == "" && a.RequestedBy == nil
+	})).Return(nil)
+
+	err := service.SendEmail(notification)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.EmailStatusSent, notification.Status)
+	assert.Equal(t, 1, notification.Attempts)
+	assert.NotNil(t, notification.SentAt)
+	assert.Nil(t, notification.NextAttemptAt)
+	mockNotifications.AssertExpectations(t)
+}
+
+func TestNotificationService_SendEmail_FailedAttemptIsRetriedLater(t *testing.T) {
+	service, mockNotifications, _, mockEmail := setupTestNotificationService(config.EmailConfig{RetryBackoff: 2 * time.Minute})
+	notification := createTestEmailNotification("", 0)
+
+	mockNotifications.On("CreateEmailNotification", notification).Return(nil)
+	mockEmail.On("SendEmail", "investor@example.com", mock.Anything).Return(errors.New("smtp: connection refused"))
+	mockNotifications.On("RecordEmailDeliveryAttempt", notification, mock.MatchedBy(func(a *models.EmailDeliveryAttempt) bool {
+		return a.AttemptNumber == 1 && !a.Succeeded && a.ErrorMessage == "smtp: connection refused"
+	})).Return(nil)
+
+	before := time.Now()
+	err := service.SendEmail(notification)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.EmailStatusPending, notification.Status)
+	assert.Equal(t, "smtp: connection refused", notification.ErrorMessage)
+	assert.Nil(t, notification.SentAt)
+	if assert.NotNil(t, notification.NextAttemptAt) {
+		assert.WithinDuration(t, before.Add(2*time.Minute), *notification.NextAttemptAt, time.Second)
+	}
+	mockNotifications.AssertExpectations(t)
+}
+
+func TestNotificationService_SendEmail_RecordFails(t *testing.T) {
+	service, mockNotifications, _, mockEmail := setupTestNotificationService(config.EmailConfig{})
+	notification := createTestEmailNotification("", 0)
+
+	mockNotifications.On("CreateEmailNotification", notification).Return(errors.New("database error"))
+
+	err := service.SendEmail(notification)
+
+	assert.Error(t, err)
+	mockEmail.AssertNotCalled(t, "SendEmail", mock.Anything, mock.Anything)
+}
+
+func TestNotificationService_RetryBackoff(t *testing.T) {
+	service, _, _, _ := setupTestNotificationService(config.EmailConfig{RetryBackoff: time.Minute, MaxRetryBackoff: 10 * time.Minute})
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{4, 8 * time.Minute},
+		{5, 10 * time.Minute},
+		{50, 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, service.retryBackoff(tt.attempts), "attempts %d", tt.attempts)
+	}
+}
+
+func TestNotificationService_RetryDueEmails_FailsAfterLastAttempt(t *testing.T) {
+	service, mockNotifications, _, mockEmail := setupTestNotificationService(config.EmailConfig{MaxAttempts: 3})
+	retried := createTestEmailNotification(models.EmailStatusPending, 1)
+	exhausted := createTestEmailNotification(models.EmailStatusPending, 2)
+
+	mockNotifications.On("ClaimDueEmailNotifications", mock.Anything, mock.Anything, emailRetryBatchSize).
+		Return([]*models.EmailNotification{retried, exhausted}, nil)
+	mockEmail.On("SendEmail", "investor@example.com", mock.Anything).Return(errors.New("smtp: mailbox unavailable"))
+	mockNotifications.On("RecordEmailDeliveryAttempt", mock.Anything, mock.Anything).Return(nil)
+
+	err := service.RetryDueEmails()
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.EmailStatusPending, retried.Status)
+	assert.Equal(t, 2, retried.Attempts)
+	assert.NotNil(t, retried.NextAttemptAt)
+
+	assert.Equal(t, models.EmailStatusFailed, exhausted.Status)
+	assert.Equal(t, 3, exhausted.Attempts)
+	assert.Equal(t, "smtp: mailbox unavailable", exhausted.ErrorMessage)
+	assert.Nil(t, exhausted.NextAttemptAt)
+}
+
+func TestNotificationService_RetryEmailNotification_Sent(t *testing.T) {
+	service, mockNotifications, mockRepo, mockEmail := setupTestNotificationService(config.EmailConfig{})
+	employeeID := uuid.New()
+	notification := createTestEmailNotification(models.EmailStatusPending, 5)
+	notification.ErrorMessage = "smtp: connection refused"
+
+	mockRepo.On("GetEmployeeByID", employeeID).Return(&models.Employee{IsActive: true}, nil)
+	mockNotifications.On("ClaimFailedEmailNotification", notification.ID, mock.Anything).Return(notification, nil)
+	mockEmail.On("SendEmail", "investor@example.com", mock.Anything).Return(nil)
+	mockNotifications.On("RecordEmailDeliveryAttempt", notification, mock.MatchedBy(func(a *models.EmailDeliveryAttempt) bool {
+		return a.AttemptNumber == 6 && a.Succeeded && a.RequestedBy != nil && *a.RequestedBy == employeeID
+	})).Return(nil)
+
+	result, err := service.RetryEmailNotification(notification.ID, &models.RetryEmailNotificationRequest{RequestedBy: employeeID})
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.EmailStatusSent, result.Status)
+	assert.Equal(t, 6, result.Attempts)
+	assert.Empty(t, result.ErrorMessage)
+	mockNotifications.AssertExpectations(t)
+}
+
+func TestNotificationService_RetryEmailNotification_StaysFailed(t *testing.T) {
+	service, mockNotifications, mockRepo, mockEmail := setupTestNotificationService(config.EmailConfig{MaxAttempts: 10})
+	employeeID := uuid.New()
+	notification := createTestEmailNotification(models.EmailStatusPending, 5)
+
+	mockRepo.On("GetEmployeeByID", employeeID).Return(&models.Employee{IsActive: true}, nil)
+	mockNotifications.On("ClaimFailedEmailNotification", notification.ID, mock.Anything).Return(notification, nil)
+	mockEmail.On("SendEmail", "investor@example.com", mock.Anything).Return(errors.New("smtp: mailbox unavailable"))
+	mockNotifications.On("RecordEmailDeliveryAttempt", notification, mock.Anything).Return(nil)
+
+	result, err := service.RetryEmailNotification(notification.ID, &models.RetryEmailNotificationRequest{RequestedBy: employeeID})
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.EmailStatusFailed, result.Status)
+	assert.Equal(t, "smtp: mailbox unavailable", result.ErrorMessage)
+	assert.Nil(t, result.NextAttemptAt)
+}
+
+func TestNotificationService_RetryEmailNotification_Rejected(t *testing.T) {
+	service, mockNotifications, mockRepo, mockEmail := setupTestNotificationService(config.EmailConfig{})
+	activeID := uuid.New()
+	inactiveID := uuid.New()
+	sent := createTestEmailNotification(models.EmailStatusSent, 1)
+	missingID := uuid.New()
+
+	mockRepo.On("GetEmployeeByID", activeID).Return(&models.Employee{IsActive: true}, nil)
+	mockRepo.On("GetEmployeeByID", inactiveID).Return(&models.Employee{IsActive: false}, nil)
+	mockNotifications.On("ClaimFailedEmailNotification", sent.ID, mock.Anything).Return(nil, nil)
+	mockNotifications.On("GetEmailNotificationByID", sent.ID).Return(sent, nil)
+	mockNotifications.On("ClaimFailedEmailNotification", missingID, mock.Anything).Return(nil, nil)
+	mockNotifications.On("GetEmailNotificationByID", missingID).Return(nil, nil)
+
+	_, err := service.RetryEmailNotification(sent.ID, &models.RetryEmailNotificationRequest{RequestedBy: inactiveID})
+	assert.ErrorIs(t, err, ErrEmailRetryNotAllowed)
+
+	_, err = service.RetryEmailNotification(sent.ID, &models.RetryEmailNotificationRequest{RequestedBy: activeID})
+	assert.ErrorIs(t, err, ErrEmailNotificationNotFailed)
+
+	_, err = service.RetryEmailNotification(missingID, &models.RetryEmailNotificationRequest{RequestedBy: activeID})
+	assert.ErrorIs(t, err, ErrEmailNotificationNotFound)
+
+	mockEmail.AssertNotCalled(t, "SendEmail", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,422 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"loan-service/internal/models"
+	"loan-service/internal/repositories"
+	"loan-service/pkg/adapters"
+	"loan-service/pkg/config"
+	"loan-service/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+const (
+	SettlementSourceAdapter = "adapter"
+	SettlementSourceCSV     = "csv"
+)
+
+type ReconciliationService struct {
+	reconciliationRepo repositories.ReconciliationRepositoryInterface
+	paymentAdapter     adapters.PaymentAdapterInterface
+	emailAdapter       adapters.EmailAdapterInterface
+	config             config.ReconciliationConfig
+	logger             logger.LoggerInterface
+	db                 *sql.DB
+}
+
+func NewReconciliationService(
+	reconciliationRepo repositories.ReconciliationRepositoryInterface,
+	paymentAdapter adapters.PaymentAdapterInterface,
+	emailAdapter adapters.EmailAdapterInterface,
+	cfg config.ReconciliationConfig,
+	logger logger.LoggerInterface,
+	db *sql.DB,
+) ReconciliationServiceInterface {
+	return &ReconciliationService{
+		reconciliationRepo: reconciliationRepo,
+		paymentAdapter:     paymentAdapter,
+		emailAdapter:       emailAdapter,
+		config:             cfg,
+		logger:             logger,
+		db:                 db,
+	}
+}
+
+// RunReconciliation matches the settlement report of the given day against recorded payments.
+// When settlementFile is set the report is read from that CSV file instead of the configured source.
+func (s *ReconciliationService) RunReconciliation(date time.Time, settlementFile string) (*models.ReconciliationRun, error) {
+	reportDate := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+
+	source := s.config.Source
+	if settlementFile != "" {
+		source = SettlementSourceCSV
+	}
+	if source == "" {
+		source = SettlementSourceAdapter
+	}
+
+	s.logger.Info("Starting settlement reconciliation", map[string]interface{}{
+		"report_date": reportDate.Format("2006-01-02"),
+		"source":      source,
+	})
+
+	run := &models.ReconciliationRun{
+		BaseModel: models.BaseModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		},
+		ReportDate: reportDate,
+		Source:     source,
+		Status:     models.ReconciliationStatusRunning,
+	}
+
+	run, err := s.reconciliationRepo.CreateReconciliationRun(nil, run)
+	if err != nil {
+		s.logger.Error("Failed to create reconciliation run", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+
+	records, err := s.loadSettlementReport(reportDate, source, settlementFile)
+	if err != nil {
+		return run, s.failRun(run, fmt.Errorf("failed to load settlement report: %w", err))
+	}
+
+	// A payment may settle up to lagDays after it was made, so it is only flagged as missing once
+	// that window has passed, and the earlier reports of the window tell whether it settled there
+	lagDays := s.config.SettlementLagDays
+	if lagDays < 0 {
+		lagDays = 0
+	}
+
+	settledEarlier := s.loadSettledEarlier(reportDate, lagDays, source, settlementFile)
+
+	expected, err := s.reconciliationRepo.GetExpectedPayments(reportDate.AddDate(0, 0, -lagDays), reportDate.AddDate(0, 0, 1))
+	if err != nil {
+		return run, s.failRun(run, fmt.Errorf("failed to get expected payments: %w", err))
+	}
+
+	dueBefore := reportDate.AddDate(0, 0, 1-lagDays)
+	matched, mismatches := matchSettlementRecords(run.ID, expected, records, settledEarlier, dueBefore, s.config.AmountTolerance)
+
+	err = runInTransaction(s.db, s.logger, func(tx *sql.Tx) error {
+		for _, mismatch := range mismatches {
+			if _, err := s.reconciliationRepo.CreateReconciliationMismatch(tx, mismatch); err != nil {
+				return fmt.Errorf("failed to record mismatch %s: %w", mismatch.TransactionID, err)
+			}
+		}
+
+		now := time.Now()
+		run.Status = models.ReconciliationStatusCompleted
+		run.TotalRecords = len(records)
+		run.MatchedCount = matched
+		run.MismatchCount = len(mismatches)
+		run.CompletedAt = &now
+
+		return s.reconciliationRepo.UpdateReconciliationRun(tx, run)
+	})
+	if err != nil {
+		return run, s.failRun(run, err)
+	}
+
+	run.Mismatches = mismatches
+
+	s.logger.Info("Settlement reconciliation completed", map[string]interface{}{
+		"run_id":         run.ID.String(),
+		"total_records":  run.TotalRecords,
+		"matched_count":  run.MatchedCount,
+		"mismatch_count": run.MismatchCount,
+	})
+
+	s.sendSummary(run, mismatches)
+
+	return run, nil
+}
+
+// loadSettledEarlier collects the transactions settled in the reports of the lagDays before the report date.
+// With an explicit settlement file the earlier reports are read from the same directory. A day without a report,
+// such as a weekend without a file drop, counts as one without settlements.
+func (s *ReconciliationService) loadSettledEarlier(reportDate time.Time, lagDays int, source, settlementFile string) map[string]bool {
+	settledEarlier := make(map[string]bool)
+	for day := 1; day <= lagDays; day++ {
+		earlierDate := reportDate.AddDate(0, 0, -day)
+
+		earlierFile := ""
+		if settlementFile != "" {
+			earlierFile = filepath.Join(filepath.Dir(settlementFile), settlementFileName(earlierDate))
+		}
+
+		records, err := s.loadSettlementReport(earlierDate, source, earlierFile)
+		if err != nil {
+			s.logger.Warn("Earlier settlement report unavailable, treating it as empty", map[string]interface{}{
+				"report_date": earlierDate.Format("2006-01-02"),
+				"error":       err.Error(),
+			})
+			continue
+		}
+
+		for _, record := range records {
+			if settlementStatusOf(record) == settlementSettled {
+				settledEarlier[record.TransactionID] = true
+			}
+		}
+	}
+	return settledEarlier
+}
+
+// settlementFileName is the name of the CSV file dropped for a report date
+func settlementFileName(reportDate time.Time) string {
+	return fmt.Sprintf("settlement_%s.csv", reportDate.Format("2006-01-02"))
+}
+
+// loadSettlementReport reads the settlement report from the payment adapter or a CSV file drop
+func (s *ReconciliationService) loadSettlementReport(reportDate time.Time, source, settlementFile string) ([]adapters.SettlementRecord, error) {
+	switch source {
+	case SettlementSourceAdapter:
+		return s.paymentAdapter.GetSettlementReport(reportDate)
+	case SettlementSourceCSV:
+		if settlementFile == "" {
+			settlementFile = filepath.Join(s.config.SettlementDir, settlementFileName(reportDate))
+		}
+		return adapters.ReadSettlementCSV(settlementFile)
+	default:
+		return nil, fmt.Errorf("unsupported settlement source: %s", source)
+	}
+}
+
+// failRun marks the run as failed and notifies ops, returning the original error
+func (s *ReconciliationService) failRun(run *models.ReconciliationRun, cause error) error {
+	s.logger.Error("Settlement reconciliation failed", map[string]interface{}{
+		"run_id": run.ID.String(),
+		"error":  cause.Error(),
+	})
+
+	now := time.Now()
+	run.Status = models.ReconciliationStatusFailed
+	run.CompletedAt = &now
+	run.ErrorMessage = cause.Error()
+
+	if err := s.reconciliationRepo.UpdateReconciliationRun(nil, run); err != nil {
+		s.logger.Error("Failed to mark reconciliation run as failed", map[string]interface{}{
+			"run_id": run.ID.String(),
+			"error":  err.Error(),
+		})
+	}
+
+	s.sendSummary(run, nil)
+
+	return cause
+}
+
+// sendSummary emails the run outcome to ops; failures are logged but never fail the run
+func (s *ReconciliationService) sendSummary(run *models.ReconciliationRun, mismatches []*models.ReconciliationMismatch) {
+	if s.config.OpsEmail == "" {
+		s.logger.Warn("No ops email configured, skipping reconciliation summary", map[string]interface{}{
+			"run_id": run.ID.String(),
+		})
+		return
+	}
+
+	message, err := s.emailAdapter.GenerateReconciliationSummary(run, mismatches)
+	if err != nil {
+		s.logger.Error("Failed to render reconciliation summary email", map[string]interface{}{
+			"run_id": run.ID.String(),
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	if err := s.emailAdapter.SendEmail(s.config.OpsEmail, message); err != nil {
+		s.logger.Error("Failed to send reconciliation summary email", map[string]interface{}{
+			"run_id":    run.ID.String(),
+			"ops_email": s.config.OpsEmail,
+			"error":     err.Error(),
+		})
+	}
+}
+
+// settlementState classifies the status a settlement record was reported with
+type settlementState int
+
+const (
+	settlementSettled settlementState = iota
+	settlementPending
+	settlementFailed
+)
+
+// settlementStatusOf classifies a record by its provider status. An empty status counts as settled
+// because some report formats only list settled transactions; unknown statuses count as failed so
+// that ops get to see them.
+func settlementStatusOf(record adapters.SettlementRecord) settlementState {
+	switch strings.ToLower(strings.TrimSpace(record.Status)) {
+	case "", "settled", "success", "succeeded", "paid", "available", "completed":
+		return settlementSettled
+	case "pending":
+		return settlementPending
+	default:
+		return settlementFailed
+	}
+}
+
+// matchSettlementRecords matches settlement records against expected payments by transaction ID and amount.
+// Payments made before dueBefore are flagged when neither the report nor settledEarlier contains them;
+// later payments may still settle in a following report. Pending records are left for a later report
+// and records with a failed status are flagged as not settled.
+// It returns the number of cleanly matched transactions and the mismatches found.
+func matchSettlementRecords(
+	runID uuid.UUID,
+	expected []*models.ExpectedPayment,
+	records []adapters.SettlementRecord,
+	settledEarlier map[string]bool,
+	dueBefore time.Time,
+	tolerance float64,
+) (int, []*models.ReconciliationMismatch) {
+	expectedByTxn := make(map[string][]*models.ExpectedPayment)
+	var expectedOrder []string
+	for _, payment := range expected {
+		if _, ok := expectedByTxn[payment.TransactionID]; !ok {
+			expectedOrder = append(expectedOrder, payment.TransactionID)
+		}
+		expectedByTxn[payment.TransactionID] = append(expectedByTxn[payment.TransactionID], payment)
+	}
+
+	recordsByTxn := make(map[string][]adapters.SettlementRecord)
+	failedByTxn := make(map[string]adapters.SettlementRecord)
+	var recordOrder, failedOrder []string
+	for _, record := range records {
+		switch settlementStatusOf(record) {
+		case settlementPending:
+			continue
+		case settlementFailed:
+			if _, ok := failedByTxn[record.TransactionID]; !ok {
+				failedOrder = append(failedOrder, record.TransactionID)
+				failedByTxn[record.TransactionID] = record
+			}
+			continue
+		}
+		if _, ok := recordsByTxn[record.TransactionID]; !ok {
+			recordOrder = append(recordOrder, record.TransactionID)
+		}
+		recordsByTxn[record.TransactionID] = append(recordsByTxn[record.TransactionID], record)
+	}
+
+	newMismatch := func(txnID string, mismatchType models.MismatchType, payment *models.ExpectedPayment, record *adapters.SettlementRecord, notes string) *models.ReconciliationMismatch {
+		mismatch := &models.ReconciliationMismatch{
+			BaseModel: models.BaseModel{
+				ID:        uuid.New(),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+			},
+			RunID:         runID,
+			TransactionID: txnID,
+			MismatchType:  mismatchType,
+			Notes:         notes,
+		}
+		if payment != nil {
+			referenceID := payment.ReferenceID
+			expectedAmount := payment.Amount
+			mismatch.ReferenceType = payment.ReferenceType
+			mismatch.ReferenceID = &referenceID
+			mismatch.ExpectedAmount = &expectedAmount
+		}
+		if record != nil {
+			reportedAmount := record.Amount
+			mismatch.ReportedAmount = &reportedAmount
+		}
+		return mismatch
+	}
+
+	matched := 0
+	var mismatches []*models.ReconciliationMismatch
+
+	for _, txnID := range recordOrder {
+		txnRecords := recordsByTxn[txnID]
+		record := txnRecords[0]
+		payments := expectedByTxn[txnID]
+
+		var payment *models.ExpectedPayment
+		if len(payments) > 0 {
+			payment = payments[0]
+		}
+
+		if len(txnRecords) > 1 {
+			mismatches = append(mismatches, newMismatch(txnID, models.MismatchTypeDuplicate, payment, &record,
+				fmt.Sprintf("reported %d times in settlement report", len(txnRecords))))
+			continue
+		}
+
+		if settledEarlier[txnID] {
+			mismatches = append(mismatches, newMismatch(txnID, models.MismatchTypeDuplicate, payment, &record,
+				"already settled in an earlier settlement report"))
+			continue
+		}
+
+		if payment == nil {
+			mismatches = append(mismatches, newMismatch(txnID, models.MismatchTypeMissingInRecords, nil, &record,
+				"settled by provider but not recorded"))
+			continue
+		}
+
+		if math.Abs(payment.Amount-record.Amount) > tolerance {
+			mismatches = append(mismatches, newMismatch(txnID, models.MismatchTypeAmountMismatch, payment, &record,
+				fmt.Sprintf("difference %.2f", record.Amount-payment.Amount)))
+			continue
+		}
+
+		if len(payments) == 1 {
+			matched++
+		}
+	}
+
+	// A failed record only counts when the provider did not also settle the transaction
+	failedReported := make(map[string]bool)
+	for _, txnID := range failedOrder {
+		if _, settled := recordsByTxn[txnID]; settled {
+			continue
+		}
+
+		failed := failedByTxn[txnID]
+		var payment *models.ExpectedPayment
+		if payments := expectedByTxn[txnID]; len(payments) > 0 {
+			payment = payments[0]
+		}
+		mismatches = append(mismatches, newMismatch(txnID, models.MismatchTypeNotSettled, payment, &failed,
+			fmt.Sprintf("reported with status %q", failed.Status)))
+		failedReported[txnID] = true
+	}
+
+	for _, txnID := range expectedOrder {
+		payments := expectedByTxn[txnID]
+
+		// Payments still inside their settlement window are flagged by a later run
+		if !payments[0].PaidAt.Before(dueBefore) {
+			continue
+		}
+
+		if len(payments) > 1 {
+			mismatches = append(mismatches, newMismatch(txnID, models.MismatchTypeDuplicate, payments[0], nil,
+				fmt.Sprintf("recorded %d times", len(payments))))
+			continue
+		}
+
+		if failedReported[txnID] {
+			continue
+		}
+
+		if _, ok := recordsByTxn[txnID]; !ok && !settledEarlier[txnID] {
+			mismatches = append(mismatches, newMismatch(txnID, models.MismatchTypeMissingInReport, payments[0], nil,
+				"recorded but not settled by provider"))
+		}
+	}
+
+	return matched, mismatches
+}
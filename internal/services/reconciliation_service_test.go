@@ -0,0 +1,209 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"loan-service/internal/models"
+	"loan-service/pkg/adapters"
+	"loan-service/pkg/config"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestExpectedPayment(transactionID string, amount float64) *models.ExpectedPayment {
+	return &models.ExpectedPayment{
+		ReferenceType: models.PaymentReferenceDisbursement,
+		ReferenceID:   uuid.New(),
+		TransactionID: transactionID,
+		Amount:        amount,
+		PaidAt:        time.Now(),
+	}
+}
+
+func TestMatchSettlementRecords_AllMatched(t *testing.T) {
+	runID := uuid.New()
+	expected := []*models.ExpectedPayment{
+		createTestExpectedPayment("txn_1", 10000.0),
+		createTestExpectedPayment("txn_2", 5000.0),
+	}
+	records := []adapters.SettlementRecord{
+		{TransactionID: "txn_2", Amount: 5000.0, Status: "settled"},
+		{TransactionID: "txn_1", Amount: 10000.004, Status: "settled"},
+	}
+
+	matched, mismatches := matchSettlementRecords(runID, expected, records, nil, time.Now().Add(time.Minute), 0.01)
+
+	assert.Equal(t, 2, matched)
+	assert.Empty(t, mismatches)
+}
+
+func TestMatchSettlementRecords_Mismatches(t *testing.T) {
+	runID := uuid.New()
+	expected := []*models.ExpectedPayment{
+		createTestExpectedPayment("txn_amount", 10000.0),
+		createTestExpectedPayment("txn_missing_report", 2000.0),
+		createTestExpectedPayment("txn_ok", 3000.0),
+	}
+	records := []adapters.SettlementRecord{
+		{TransactionID: "txn_amount", Amount: 9000.0},
+		{TransactionID: "txn_ok", Amount: 3000.0},
+		{TransactionID: "txn_unknown", Amount: 500.0},
+		{TransactionID: "txn_dup", Amount: 100.0},
+		{TransactionID: "txn_dup", Amount: 100.0},
+	}
+
+	matched, mismatches := matchSettlementRecords(runID, expected, records, nil, time.Now().Add(time.Minute), 0.01)
+
+	assert.Equal(t, 1, matched)
+	assert.Len(t, mismatches, 4)
+
+	byTxn := make(map[string]*models.ReconciliationMismatch)
+	for _, m := range mismatches {
+		assert.Equal(t, runID, m.RunID)
+		byTxn[m.TransactionID] = m
+	}
+
+	assert.Equal(t, models.MismatchTypeAmountMismatch, byTxn["txn_amount"].MismatchType)
+	assert.Equal(t, 10000.0, *byTxn["txn_amount"].ExpectedAmount)
+	assert.Equal(t, 9000.0, *byTxn["txn_amount"].ReportedAmount)
+	assert.NotNil(t, byTxn["txn_amount"].ReferenceID)
+
+	assert.Equal(t, models.MismatchTypeMissingInReport, byTxn["txn_missing_report"].MismatchType)
+	assert.Nil(t, byTxn["txn_missing_report"].ReportedAmount)
+
+	assert.Equal(t, models.MismatchTypeMissingInRecords, byTxn["txn_unknown"].MismatchType)
+	assert.Nil(t, byTxn["txn_unknown"].ReferenceID)
+
+	assert.Equal(t, models.MismatchTypeDuplicate, byTxn["txn_dup"].MismatchType)
+}
+
+func TestMatchSettlementRecords_DuplicateInRecords(t *testing.T) {
+	expected := []*models.ExpectedPayment{
+		createTestExpectedPayment("txn_1", 10000.0),
+		createTestExpectedPayment("txn_1", 10000.0),
+	}
+	records := []adapters.SettlementRecord{
+		{TransactionID: "txn_1", Amount: 10000.0},
+	}
+
+	matched, mismatches := matchSettlementRecords(uuid.New(), expected, records, nil, time.Now().Add(time.Minute), 0.01)
+
+	assert.Equal(t, 0, matched)
+	assert.Len(t, mismatches, 1)
+	assert.Equal(t, models.MismatchTypeDuplicate, mismatches[0].MismatchType)
+	assert.Contains(t, mismatches[0].Notes, "recorded 2 times")
+}
+
+func TestMatchSettlementRecords_SettlementWindow(t *testing.T) {
+	dueBefore := time.Now().Add(-time.Hour)
+
+	settledYesterday := createTestExpectedPayment("txn_settled_yesterday", 1000.0)
+	settledYesterday.PaidAt = dueBefore.Add(-2 * time.Hour)
+	lateSettled := createTestExpectedPayment("txn_late", 2000.0)
+	lateSettled.PaidAt = dueBefore.Add(-time.Hour)
+	neverSettled := createTestExpectedPayment("txn_never", 3000.0)
+	neverSettled.PaidAt = dueBefore.Add(-time.Hour)
+	notDueYet := createTestExpectedPayment("txn_not_due", 4000.0)
+
+	expected := []*models.ExpectedPayment{settledYesterday, lateSettled, neverSettled, notDueYet}
+	records := []adapters.SettlementRecord{
+		{TransactionID: "txn_late", Amount: 2000.0, Status: "settled"},
+		{TransactionID: "txn_twice", Amount: 500.0, Status: "settled"},
+	}
+	settledEarlier := map[string]bool{
+		"txn_settled_yesterday": true,
+		"txn_twice":             true,
+	}
+
+	matched, mismatches := matchSettlementRecords(uuid.New(), expected, records, settledEarlier, dueBefore, 0.01)
+
+	assert.Equal(t, 1, matched)
+	assert.Len(t, mismatches, 2)
+
+	byTxn := make(map[string]*models.ReconciliationMismatch)
+	for _, m := range mismatches {
+		byTxn[m.TransactionID] = m
+	}
+
+	assert.Equal(t, models.MismatchTypeMissingInReport, byTxn["txn_never"].MismatchType)
+	assert.Equal(t, models.MismatchTypeDuplicate, byTxn["txn_twice"].MismatchType)
+	assert.Contains(t, byTxn["txn_twice"].Notes, "earlier settlement report")
+}
+
+func TestMatchSettlementRecords_SettlementStatus(t *testing.T) {
+	withdrawal := createTestExpectedPayment("txn_withdrawal", 750.0)
+	withdrawal.ReferenceType = models.PaymentReferenceWithdrawal
+
+	expected := []*models.ExpectedPayment{
+		createTestExpectedPayment("txn_failed", 1000.0),
+		createTestExpectedPayment("txn_pending", 2000.0),
+		createTestExpectedPayment("txn_retried", 3000.0),
+		withdrawal,
+	}
+	records := []adapters.SettlementRecord{
+		{TransactionID: "txn_failed", Amount: 1000.0, Status: "Reversed"},
+		{TransactionID: "txn_pending", Amount: 2000.0, Status: "pending"},
+		{TransactionID: "txn_retried", Amount: 3000.0, Status: "failed"},
+		{TransactionID: "txn_retried", Amount: 3000.0, Status: "succeeded"},
+		{TransactionID: "txn_withdrawal", Amount: 750.0, Status: "paid"},
+	}
+
+	matched, mismatches := matchSettlementRecords(uuid.New(), expected, records, nil, time.Now().Add(time.Minute), 0.01)
+
+	assert.Equal(t, 2, matched)
+	assert.Len(t, mismatches, 2)
+
+	byTxn := make(map[string]*models.ReconciliationMismatch)
+	for _, m := range mismatches {
+		byTxn[m.TransactionID] = m
+	}
+
+	assert.Equal(t, models.MismatchTypeNotSettled, byTxn["txn_failed"].MismatchType)
+	assert.Equal(t, models.PaymentReferenceDisbursement, byTxn["txn_failed"].ReferenceType)
+	assert.Equal(t, 1000.0, *byTxn["txn_failed"].ReportedAmount)
+	assert.Contains(t, byTxn["txn_failed"].Notes, "Reversed")
+
+	assert.Equal(t, models.MismatchTypeMissingInReport, byTxn["txn_pending"].MismatchType)
+}
+
+func writeSettlementFile(t *testing.T, dir string, date time.Time, rows string) string {
+	path := filepath.Join(dir, settlementFileName(date))
+	content := "transaction_id,amount,status,settled_at\n" + rows
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write settlement file: %v", err)
+	}
+	return path
+}
+
+func TestReconciliationService_LoadSettledEarlier_NextToExplicitFile(t *testing.T) {
+	reportDate := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	dropDir := t.TempDir()
+	configuredDir := t.TempDir()
+
+	settlementFile := writeSettlementFile(t, dropDir, reportDate, "")
+	writeSettlementFile(t, dropDir, reportDate.AddDate(0, 0, -1), "txn_dropped,1000.00,settled,2025-03-09T10:00:00Z\n")
+	writeSettlementFile(t, configuredDir, reportDate.AddDate(0, 0, -1), "txn_configured,1000.00,settled,2025-03-09T10:00:00Z\n")
+
+	service := NewReconciliationService(nil, nil, nil, config.ReconciliationConfig{SettlementDir: configuredDir}, &TestLogger{}, nil).(*ReconciliationService)
+
+	settledEarlier := service.loadSettledEarlier(reportDate, 1, SettlementSourceCSV, settlementFile)
+
+	assert.Equal(t, map[string]bool{"txn_dropped": true}, settledEarlier)
+}
+
+func TestReconciliationService_LoadSettledEarlier_MissingReportCountsAsEmpty(t *testing.T) {
+	reportDate := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC) // Monday, no drops over the weekend
+	dir := t.TempDir()
+	writeSettlementFile(t, dir, reportDate.AddDate(0, 0, -3), "txn_friday,1000.00,settled,2025-03-07T10:00:00Z\n"+
+		"txn_friday_failed,500.00,failed,2025-03-07T11:00:00Z\n")
+
+	service := NewReconciliationService(nil, nil, nil, config.ReconciliationConfig{SettlementDir: dir}, &TestLogger{}, nil).(*ReconciliationService)
+
+	settledEarlier := service.loadSettledEarlier(reportDate, 3, SettlementSourceCSV, "")
+
+	assert.Equal(t, map[string]bool{"txn_friday": true}, settledEarlier)
+}
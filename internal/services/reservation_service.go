@@ -0,0 +1,167 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"loan-service/internal/models"
+	"loan-service/internal/repositories"
+	"loan-service/pkg/config"
+	"loan-service/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+var ErrReservationNotFound = errors.New("reservation not found, expired or already confirmed")
+
+type ReservationService struct {
+	reservationRepo repositories.ReservationRepositoryInterface
+	loanRepo        repositories.LoanRepositoryInterface
+	investments     InvestmentProcessor
+	ttl             time.Duration
+	logger          logger.LoggerInterface
+	db              *sql.DB
+}
+
+// NewReservationService creates the reservation service; a nil reservation store disables reservations
+func NewReservationService(
+	reservationRepo repositories.ReservationRepositoryInterface,
+	loanRepo repositories.LoanRepositoryInterface,
+	investments InvestmentProcessor,
+	cfg config.LoanConfig,
+	logger logger.LoggerInterface,
+	db *sql.DB,
+) ReservationServiceInterface {
+	return &ReservationService{
+		reservationRepo: reservationRepo,
+		loanRepo:        loanRepo,
+		investments:     investments,
+		ttl:             cfg.ReservationTTL,
+		logger:          logger,
+		db:              db,
+	}
+}
+
+func (s *ReservationService) withTransaction(fn func(*sql.Tx) error) error {
+	return runInTransaction(s.db, s.logger, fn)
+}
+
+// ProcessReserve holds part of the loan's remaining principal for the investor until the reservation expires
+func (s *ReservationService) ProcessReserve(loanID uuid.UUID, req *models.CreateReservationRequest) (*models.InvestmentReservation, error) {
+	s.logger.Info("Processing investment reservation", map[string]interface{}{"loan_id": loanID, "request": req})
+
+	if s.reservationRepo == nil || s.ttl <= 0 {
+		return nil, fmt.Errorf("investment reservations are not available")
+	}
+
+	investor, err := s.loanRepo.GetInvestorByID(req.InvestorID)
+	if err != nil {
+		s.logger.Error("Failed to get investor by ID", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": req.InvestorID.String(),
+		})
+		return nil, err
+	}
+
+	if !investor.IsActive {
+		return nil, fmt.Errorf("investor %s is not active", investor.InvestorCode)
+	}
+
+	var result *models.InvestmentReservation
+	err = s.withTransaction(func(tx *sql.Tx) error {
+		var reserveErr error
+		result, reserveErr = s.processReserveTx(tx, loanID, req)
+		return reserveErr
+	})
+
+	return result, err
+}
+
+func (s *ReservationService) processReserveTx(tx *sql.Tx, loanID uuid.UUID, req *models.CreateReservationRequest) (*models.InvestmentReservation, error) {
+	// The loan lock serialises reservations with investments, so the reserved total cannot be overtaken
+	if err := s.loanRepo.LockLoan(tx, loanID); err != nil {
+		s.logger.Error("Failed to lock loan", map[string]interface{}{
+			"error":   err.Error(),
+			"loan_id": loanID.String(),
+		})
+		return nil, err
+	}
+
+	loan, err := s.loanRepo.GetLoanByID(tx, loanID)
+	if err != nil {
+		s.logger.Error("Failed to get loan by ID", map[string]interface{}{
+			"error":   err.Error(),
+			"loan_id": loanID.String(),
+		})
+		return nil, err
+	}
+
+	reserved, err := s.reservationRepo.GetReservedAmount(loanID)
+	if err != nil {
+		s.logger.Error("Failed to get reserved amount", map[string]interface{}{
+			"error":   err.Error(),
+			"loan_id": loanID.String(),
+		})
+		return nil, err
+	}
+
+	if err := loan.ValidateInvestmentAmount(req.Amount, reserved); err != nil {
+		return nil, fmt.Errorf("reservation validation failed: %w", err)
+	}
+
+	now := time.Now()
+	reservation := &models.InvestmentReservation{
+		ID:         uuid.New(),
+		LoanID:     loanID,
+		InvestorID: req.InvestorID,
+		Amount:     req.Amount,
+		ExpiresAt:  now.Add(s.ttl),
+		CreatedAt:  now,
+	}
+
+	if err := s.reservationRepo.CreateReservation(reservation); err != nil {
+		s.logger.Error("Failed to create reservation", map[string]interface{}{
+			"error":   err.Error(),
+			"loan_id": loanID.String(),
+		})
+		return nil, err
+	}
+
+	return reservation, nil
+}
+
+// ConfirmReservation converts an active reservation into an investment through the regular investment path
+func (s *ReservationService) ConfirmReservation(loanID, reservationID uuid.UUID, req *models.ConfirmReservationRequest) (*models.InvestmentResponse, error) {
+	s.logger.Info("Confirming investment reservation", map[string]interface{}{"loan_id": loanID, "reservation_id": reservationID})
+
+	if s.reservationRepo == nil {
+		return nil, fmt.Errorf("investment reservations are not available")
+	}
+
+	reservation, err := s.reservationRepo.GetReservation(loanID, reservationID)
+	if err != nil {
+		s.logger.Error("Failed to get reservation", map[string]interface{}{
+			"error":          err.Error(),
+			"reservation_id": reservationID.String(),
+		})
+		return nil, err
+	}
+	if reservation == nil {
+		return nil, fmt.Errorf("%w: %s", ErrReservationNotFound, reservationID)
+	}
+
+	if reservation.InvestorID != req.InvestorID {
+		return nil, fmt.Errorf("reservation %s does not belong to investor %s", reservationID, req.InvestorID)
+	}
+
+	// The investment claims the reservation in its own transaction, so a second confirmation is refused there
+	return s.investments.ProcessInvestment(loanID, &models.CreateInvestmentRequest{
+		LoanID:         loanID,
+		InvestorID:     reservation.InvestorID,
+		Amount:         reservation.Amount,
+		InvestmentDate: time.Now(),
+		ReservationID:  &reservation.ID,
+	})
+}
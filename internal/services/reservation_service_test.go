@@ -0,0 +1,311 @@
+package services
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"loan-service/internal/models"
+	"loan-service/pkg/config"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockReservationRepository struct {
+	mock.Mock
+}
+
+func (m *MockReservationRepository) CreateReservation(reservation *models.InvestmentReservation) error {
+	args := m.Called(reservation)
+	return args.Error(0)
+}
+
+func (m *MockReservationRepository) GetReservation(loanID, reservationID uuid.UUID) (*models.InvestmentReservation, error) {
+	args := m.Called(loanID, reservationID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.InvestmentReservation), args.Error(1)
+}
+
+func (m *MockReservationRepository) ClaimReservation(loanID, reservationID uuid.UUID) (*models.InvestmentReservation, error) {
+	args := m.Called(loanID, reservationID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.InvestmentReservation), args.Error(1)
+}
+
+func (m *MockReservationRepository) GetReservedAmount(loanID uuid.UUID) (float64, error) {
+	args := m.Called(loanID)
+	return args.Get(0).(float64), args.Error(1)
+}
+
+func (m *MockReservationRepository) DeleteReservation(loanID, reservationID uuid.UUID) error {
+	args := m.Called(loanID, reservationID)
+	return args.Error(0)
+}
+
+// TestReservationService is a test-specific version that overrides withTransaction
+type TestReservationService struct {
+	*ReservationService
+}
+
+func (s *TestReservationService) withTransaction(fn func(*sql.Tx) error) error {
+	return fn(nil)
+}
+
+func (s *TestReservationService) ProcessReserve(loanID uuid.UUID, req *models.CreateReservationRequest) (*models.InvestmentReservation, error) {
+	var result *models.InvestmentReservation
+	err := s.withTransaction(func(tx *sql.Tx) error {
+		var reserveErr error
+		result, reserveErr = s.processReserveTx(tx, loanID, req)
+		return reserveErr
+	})
+
+	return result, err
+}
+
+func setupTestReservationService() (*TestReservationService, *MockReservationRepository, *MockLoanRepository, *MockInvestmentProcessor) {
+	mockReservations := &MockReservationRepository{}
+	mockRepo := &MockLoanRepository{}
+	mockInvestments := &MockInvestmentProcessor{}
+
+	baseService := NewReservationService(mockReservations, mockRepo, mockInvestments, config.LoanConfig{ReservationTTL: 15 * time.Minute}, &TestLogger{}, nil).(*ReservationService)
+
+	return &TestReservationService{ReservationService: baseService}, mockReservations, mockRepo, mockInvestments
+}
+
+func TestReservationService_ProcessReserve_Success(t *testing.T) {
+	service, mockReservations, mockRepo, _ := setupTestReservationService()
+
+	loanID := uuid.New()
+	req := &models.CreateReservationRequest{InvestorID: uuid.New(), Amount: 3000.0}
+
+	mockRepo.On("LockLoan", (*sql.Tx)(nil), loanID).Return(nil)
+	mockRepo.On("GetLoanByID", (*sql.Tx)(nil), loanID).Return(createTestLoan(loanID, models.LoanStateApproved, 5000.0), nil)
+	mockReservations.On("GetReservedAmount", loanID).Return(2000.0, nil)
+	mockReservations.On("CreateReservation", mock.MatchedBy(func(r *models.InvestmentReservation) bool {
+		return r.LoanID == loanID && r.InvestorID == req.InvestorID && r.Amount == 3000.0
+	})).Return(nil)
+
+	reservation, err := service.ProcessReserve(loanID, req)
+
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(15*time.Minute), reservation.ExpiresAt, time.Second)
+	mockReservations.AssertExpectations(t)
+}
+
+func TestReservationService_ProcessReserve_ExceedsUnreserved(t *testing.T) {
+	service, mockReservations, mockRepo, _ := setupTestReservationService()
+
+	loanID := uuid.New()
+	req := &models.CreateReservationRequest{InvestorID: uuid.New(), Amount: 3000.0}
+
+	mockRepo.On("LockLoan", (*sql.Tx)(nil), loanID).Return(nil)
+	mockRepo.On("GetLoanByID", (*sql.Tx)(nil), loanID).Return(createTestLoan(loanID, models.LoanStateApproved, 5000.0), nil)
+	mockReservations.On("GetReservedAmount", loanID).Return(2500.0, nil) // 5000 remaining, 2500 unreserved
+
+	reservation, err := service.ProcessReserve(loanID, req)
+
+	assert.Error(t, err)
+	assert.Nil(t, reservation)
+	assert.Contains(t, err.Error(), "exceeds unreserved amount")
+	mockReservations.AssertNotCalled(t, "CreateReservation", mock.Anything)
+}
+
+func TestReservationService_ConfirmReservation(t *testing.T) {
+	service, mockReservations, _, mockInvestments := setupTestReservationService()
+
+	loanID := uuid.New()
+	reservation := &models.InvestmentReservation{
+		ID:         uuid.New(),
+		LoanID:     loanID,
+		InvestorID: uuid.New(),
+		Amount:     3000.0,
+		ExpiresAt:  time.Now().Add(time.Minute),
+	}
+
+	mockReservations.On("GetReservation", loanID, reservation.ID).Return(reservation, nil)
+	mockInvestments.On("ProcessInvestment", loanID, mock.MatchedBy(func(req *models.CreateInvestmentRequest) bool {
+		return req.InvestorID == reservation.InvestorID && req.Amount == 3000.0 && *req.ReservationID == reservation.ID
+	})).Return(&models.InvestmentResponse{Amount: 3000.0}, nil)
+
+	investment, err := service.ConfirmReservation(loanID, reservation.ID, &models.ConfirmReservationRequest{InvestorID: reservation.InvestorID})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3000.0, investment.Amount)
+	mockInvestments.AssertExpectations(t)
+	mockReservations.AssertExpectations(t)
+}
+
+func TestReservationService_ConfirmReservation_NotFound(t *testing.T) {
+	service, mockReservations, _, mockInvestments := setupTestReservationService()
+
+	loanID := uuid.New()
+	reservationID := uuid.New()
+
+	mockReservations.On("GetReservation", loanID, reservationID).Return(nil, nil)
+
+	investment, err := service.ConfirmReservation(loanID, reservationID, &models.ConfirmReservationRequest{InvestorID: uuid.New()})
+
+	assert.ErrorIs(t, err, ErrReservationNotFound)
+	assert.Nil(t, investment)
+	mockInvestments.AssertNotCalled(t, "ProcessInvestment", mock.Anything, mock.Anything)
+}
+
+func TestReservationService_ConfirmReservation_OtherInvestor(t *testing.T) {
+	service, mockReservations, _, mockInvestments := setupTestReservationService()
+
+	loanID := uuid.New()
+	reservation := &models.InvestmentReservation{ID: uuid.New(), LoanID: loanID, InvestorID: uuid.New(), Amount: 3000.0}
+
+	mockReservations.On("GetReservation", loanID, reservation.ID).Return(reservation, nil)
+
+	investment, err := service.ConfirmReservation(loanID, reservation.ID, &models.ConfirmReservationRequest{InvestorID: uuid.New()})
+
+	assert.Error(t, err)
+	assert.Nil(t, investment)
+	mockInvestments.AssertNotCalled(t, "ProcessInvestment", mock.Anything, mock.Anything)
+}
+
+func TestLoanService_ProcessInvestment_HonoursReservations(t *testing.T) {
+	service, mockRepo, _, _, _ := setupTestLoanService()
+	mockReservations := &MockReservationRepository{}
+	service.reservationRepo = mockReservations
+
+	loanID := uuid.New()
+	req := &models.CreateInvestmentRequest{
+		InvestorID:     uuid.New(),
+		Amount:         2000.0,
+		InvestmentDate: time.Now(),
+	}
+
+	mockRepo.On("LockLoan", mock.AnythingOfType("*sql.Tx"), loanID).Return(nil)
+	mockRepo.On("GetLoanByID", mock.AnythingOfType("*sql.Tx"), loanID).Return(createTestLoan(loanID, models.LoanStateApproved, 8000.0), nil)
+	mockReservations.On("GetReservedAmount", loanID).Return(1000.0, nil)
+
+	result, err := service.ProcessInvestment(loanID, req)
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	assert.Contains(t, err.Error(), "exceeds unreserved amount 1000.00")
+	mockRepo.AssertNotCalled(t, "CreateInvestment", mock.Anything, mock.Anything)
+}
+
+func TestLoanService_ProcessInvestment_ClaimsReservation(t *testing.T) {
+	service, mockRepo, mockWallet, _, _ := setupTestLoanService()
+	mockReservations := &MockReservationRepository{}
+	service.reservationRepo = mockReservations
+
+	loanID := uuid.New()
+	reservation := &models.InvestmentReservation{ID: uuid.New(), LoanID: loanID, InvestorID: uuid.New(), Amount: 3000.0, ExpiresAt: time.Now().Add(time.Minute)}
+	req := &models.CreateInvestmentRequest{
+		InvestorID:     reservation.InvestorID,
+		Amount:         3000.0,
+		InvestmentDate: time.Now(),
+		ReservationID:  &reservation.ID,
+	}
+	investment := &models.Investment{BaseModel: models.BaseModel{ID: uuid.New()}, LoanID: loanID, InvestorID: req.InvestorID, Amount: req.Amount}
+
+	mockRepo.On("LockLoan", mock.AnythingOfType("*sql.Tx"), loanID).Return(nil)
+	mockRepo.On("GetLoanByID", mock.AnythingOfType("*sql.Tx"), loanID).Return(createTestLoan(loanID, models.LoanStateApproved, 5000.0), nil)
+	mockReservations.On("ClaimReservation", loanID, reservation.ID).Return(reservation, nil)
+	// Once claimed, the reservation no longer counts against the remaining principal
+	mockReservations.On("GetReservedAmount", loanID).Return(2000.0, nil)
+	mockRepo.On("CreateInvestment", mock.AnythingOfType("*sql.Tx"), mock.AnythingOfType("*models.Investment")).Return(investment, nil)
+	mockRepo.On("UpdateLoanTotalInvested", mock.AnythingOfType("*sql.Tx"), loanID, 3000.0).Return(createTestLoan(loanID, models.LoanStateApproved, 8000.0), nil)
+	expectFundedWallet(mockWallet, req.InvestorID, 20000.0)
+	expectNoInvestorLimits(mockRepo, req.InvestorID)
+
+	result, err := service.ProcessInvestment(loanID, req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3000.0, result.Amount)
+	mockReservations.AssertExpectations(t)
+	mockReservations.AssertNotCalled(t, "CreateReservation", mock.Anything)
+}
+
+func TestLoanService_ProcessInvestment_ReservationAlreadyClaimed(t *testing.T) {
+	service, mockRepo, _, _, _ := setupTestLoanService()
+	mockReservations := &MockReservationRepository{}
+	service.reservationRepo = mockReservations
+
+	loanID := uuid.New()
+	reservationID := uuid.New()
+	req := &models.CreateInvestmentRequest{
+		InvestorID:     uuid.New(),
+		Amount:         3000.0,
+		InvestmentDate: time.Now(),
+		ReservationID:  &reservationID,
+	}
+
+	mockRepo.On("LockLoan", mock.AnythingOfType("*sql.Tx"), loanID).Return(nil)
+	mockRepo.On("GetLoanByID", mock.AnythingOfType("*sql.Tx"), loanID).Return(createTestLoan(loanID, models.LoanStateApproved, 5000.0), nil)
+	mockReservations.On("ClaimReservation", loanID, reservationID).Return(nil, nil)
+
+	result, err := service.ProcessInvestment(loanID, req)
+
+	assert.ErrorIs(t, err, ErrReservationNotFound)
+	assert.Nil(t, result)
+	mockRepo.AssertNotCalled(t, "CreateInvestment", mock.Anything, mock.Anything)
+	mockReservations.AssertNotCalled(t, "CreateReservation", mock.Anything)
+}
+
+func TestLoanService_ProcessInvestment_RestoresReservationOnRollback(t *testing.T) {
+	service, mockRepo, mockWallet, _, _ := setupTestLoanService()
+	mockReservations := &MockReservationRepository{}
+	service.reservationRepo = mockReservations
+
+	loanID := uuid.New()
+	reservation := &models.InvestmentReservation{ID: uuid.New(), LoanID: loanID, InvestorID: uuid.New(), Amount: 3000.0, ExpiresAt: time.Now().Add(time.Minute)}
+	req := &models.CreateInvestmentRequest{
+		InvestorID:     reservation.InvestorID,
+		Amount:         3000.0,
+		InvestmentDate: time.Now(),
+		ReservationID:  &reservation.ID,
+	}
+
+	mockRepo.On("LockLoan", mock.AnythingOfType("*sql.Tx"), loanID).Return(nil)
+	mockRepo.On("GetLoanByID", mock.AnythingOfType("*sql.Tx"), loanID).Return(createTestLoan(loanID, models.LoanStateApproved, 5000.0), nil)
+	mockReservations.On("ClaimReservation", loanID, reservation.ID).Return(reservation, nil)
+	mockReservations.On("GetReservedAmount", loanID).Return(0.0, nil)
+	mockReservations.On("CreateReservation", reservation).Return(nil)
+	expectFundedWallet(mockWallet, req.InvestorID, 1000.0)
+	expectNoInvestorLimits(mockRepo, req.InvestorID)
+
+	result, err := service.ProcessInvestment(loanID, req)
+
+	assert.ErrorContains(t, err, "insufficient wallet balance")
+	assert.Nil(t, result)
+	mockReservations.AssertCalled(t, "CreateReservation", reservation)
+}
+
+func TestLoanService_ProcessInvestment_ReservationOfOtherInvestor(t *testing.T) {
+	service, mockRepo, _, _, _ := setupTestLoanService()
+	mockReservations := &MockReservationRepository{}
+	service.reservationRepo = mockReservations
+
+	loanID := uuid.New()
+	reservation := &models.InvestmentReservation{ID: uuid.New(), LoanID: loanID, InvestorID: uuid.New(), Amount: 3000.0, ExpiresAt: time.Now().Add(time.Minute)}
+	req := &models.CreateInvestmentRequest{
+		InvestorID:     uuid.New(),
+		Amount:         3000.0,
+		InvestmentDate: time.Now(),
+		ReservationID:  &reservation.ID,
+	}
+
+	mockRepo.On("LockLoan", mock.AnythingOfType("*sql.Tx"), loanID).Return(nil)
+	mockRepo.On("GetLoanByID", mock.AnythingOfType("*sql.Tx"), loanID).Return(createTestLoan(loanID, models.LoanStateApproved, 5000.0), nil)
+	mockReservations.On("ClaimReservation", loanID, reservation.ID).Return(reservation, nil)
+	mockReservations.On("CreateReservation", reservation).Return(nil)
+
+	result, err := service.ProcessInvestment(loanID, req)
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	mockRepo.AssertNotCalled(t, "CreateInvestment", mock.Anything, mock.Anything)
+	mockReservations.AssertCalled(t, "CreateReservation", reservation)
+}
@@ -0,0 +1,291 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"loan-service/internal/models"
+	"loan-service/internal/repositories"
+	"loan-service/pkg/adapters"
+	"loan-service/pkg/config"
+	"loan-service/pkg/logger"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// ErrInvalidCallbackSignature is returned for callbacks that were not signed by the e-signature provider
+var ErrInvalidCallbackSignature = errors.New("invalid callback signature")
+
+type SignatureService struct {
+	signatureRepo    repositories.SignatureRepositoryInterface
+	loanRepo         repositories.LoanRepositoryInterface
+	signatureAdapter adapters.SignatureAdapterInterface
+	fileService      FileServiceInterface
+	config           config.SignatureConfig
+	logger           logger.LoggerInterface
+	db               *sql.DB
+}
+
+func NewSignatureService(
+	signatureRepo repositories.SignatureRepositoryInterface,
+	loanRepo repositories.LoanRepositoryInterface,
+	signatureAdapter adapters.SignatureAdapterInterface,
+	fileService FileServiceInterface,
+	cfg config.SignatureConfig,
+	logger logger.LoggerInterface,
+	db *sql.DB,
+) SignatureServiceInterface {
+	return &SignatureService{
+		signatureRepo:    signatureRepo,
+		loanRepo:         loanRepo,
+		signatureAdapter: signatureAdapter,
+		fileService:      fileService,
+		config:           cfg,
+		logger:           logger,
+		db:               db,
+	}
+}
+
+func (s *SignatureService) withTransaction(fn func(*sql.Tx) error) error {
+	return runInTransaction(s.db, s.logger, fn)
+}
+
+// ProcessSendForSignature sends the agreement letter of an invested loan to the borrower for e-signature
+func (s *SignatureService) ProcessSendForSignature(loanID uuid.UUID) (*models.AgreementSignature, error) {
+	s.logger.Info("Sending loan agreement for signature", map[string]interface{}{"loan_id": loanID})
+
+	var result *models.AgreementSignature
+	err := s.withTransaction(func(tx *sql.Tx) error {
+		var sendErr error
+		result, sendErr = s.processSendForSignatureTx(tx, loanID)
+		return sendErr
+	})
+
+	return result, err
+}
+
+func (s *SignatureService) processSendForSignatureTx(tx *sql.Tx, loanID uuid.UUID) (*models.AgreementSignature, error) {
+	// Lock the loan so the agreement is not sent twice at the same time
+	if err := s.loanRepo.LockLoan(tx, loanID); err != nil {
+		s.logger.Error("Failed to lock loan", map[string]interface{}{
+			"error":   err.Error(),
+			"loan_id": loanID.String(),
+		})
+		return nil, err
+	}
+
+	loan, err := s.loanRepo.GetLoanByID(tx, loanID)
+	if err != nil {
+		s.logger.Error("Failed to get loan by ID", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+
+	if loan.State != models.LoanStateInvested {
+		return nil, fmt.Errorf("agreement can only be sent for signature while the loan is invested, current state: %s", loan.State)
+	}
+	if loan.AgreementLetterURL == "" {
+		return nil, fmt.Errorf("loan has no agreement letter to sign")
+	}
+
+	open, err := s.signatureRepo.GetOpenAgreementSignature(tx, loanID)
+	if err != nil {
+		s.logger.Error("Failed to get agreement signature", map[string]interface{}{
+			"error":   err.Error(),
+			"loan_id": loanID.String(),
+		})
+		return nil, err
+	}
+	if open != nil {
+		return nil, fmt.Errorf("loan agreement is already %s", open.Status)
+	}
+
+	envelope, err := s.signatureAdapter.SendForSignature(adapters.SignatureRequest{
+		DocumentName: fmt.Sprintf("Loan Agreement #%s", loanID.String()[:8]),
+		DocumentURL:  s.fileService.SignFileURL(loan.AgreementLetterURL), // the provider fetches the document itself
+		SignerName:   loan.Borrower.FullName(),
+		SignerEmail:  loan.Borrower.Email,
+		CallbackURL:  s.config.CallbackURL,
+	})
+	if err != nil {
+		s.logger.Error("Failed to send agreement for signature", map[string]interface{}{
+			"error":   err.Error(),
+			"loan_id": loanID.String(),
+		})
+		return nil, fmt.Errorf("signature provider failed: %w", err)
+	}
+
+	signature, err := s.signatureRepo.CreateAgreementSignature(tx, &models.AgreementSignature{
+		LoanID:      loanID,
+		Provider:    s.signatureAdapter.Provider(),
+		EnvelopeID:  envelope.EnvelopeID,
+		Status:      models.AgreementSignatureStatusPending,
+		DocumentURL: loan.AgreementLetterURL,
+		SigningURL:  envelope.SigningURL,
+		SignerName:  loan.Borrower.FullName(),
+		SignerEmail: loan.Borrower.Email,
+		SentAt:      time.Now(),
+	})
+	if err != nil {
+		s.logger.Error("Failed to create agreement signature", map[string]interface{}{
+			"error":       err.Error(),
+			"loan_id":     loanID.String(),
+			"envelope_id": envelope.EnvelopeID,
+		})
+		return nil, err
+	}
+
+	s.logger.Info("Loan agreement sent for signature", map[string]interface{}{
+		"loan_id":     loanID.String(),
+		"envelope_id": envelope.EnvelopeID,
+	})
+
+	return signature, nil
+}
+
+// ProcessCallback applies a provider callback: a signed agreement has its signed file downloaded and stored
+func (s *SignatureService) ProcessCallback(payload []byte, callbackSignature string) (*models.AgreementSignature, error) {
+	req, err := s.parseCallback(payload, callbackSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Processing signature callback", map[string]interface{}{"envelope_id": req.EnvelopeID, "status": req.Status})
+
+	content, err := s.downloadSignedDocument(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *models.AgreementSignature
+	var undo rollbackActions
+	err = s.withTransaction(func(tx *sql.Tx) error {
+		var callbackErr error
+		result, callbackErr = s.processCallbackTx(tx, req, content, &undo)
+		return callbackErr
+	})
+	if err != nil {
+		undo.run()
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// downloadSignedDocument fetches the signed file of a signed callback before the signature is locked, so the
+// provider is not waited on inside the transaction. Returns nil when there is nothing to download.
+func (s *SignatureService) downloadSignedDocument(req *models.SignatureCallbackRequest) ([]byte, error) {
+	if req.Status != models.AgreementSignatureStatusSigned {
+		return nil, nil
+	}
+
+	signature, err := s.signatureRepo.GetAgreementSignature(s.signatureAdapter.Provider(), req.EnvelopeID)
+	if err != nil {
+		s.logger.Error("Failed to get agreement signature", map[string]interface{}{
+			"error":       err.Error(),
+			"envelope_id": req.EnvelopeID,
+		})
+		return nil, err
+	}
+
+	// A redelivered or conflicting callback is settled in the transaction without the file
+	if signature.Status != models.AgreementSignatureStatusPending {
+		return nil, nil
+	}
+
+	content, err := s.signatureAdapter.DownloadSignedDocument(req.EnvelopeID)
+	if err != nil {
+		s.logger.Error("Failed to download signed agreement", map[string]interface{}{
+			"error":       err.Error(),
+			"envelope_id": req.EnvelopeID,
+		})
+		return nil, fmt.Errorf("signature provider failed: %w", err)
+	}
+
+	return content, nil
+}
+
+// parseCallback only accepts payloads signed by the provider
+func (s *SignatureService) parseCallback(payload []byte, callbackSignature string) (*models.SignatureCallbackRequest, error) {
+	if err := s.signatureAdapter.VerifyCallback(payload, callbackSignature); err != nil {
+		s.logger.Error("Rejected signature callback", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, ErrInvalidCallbackSignature
+	}
+
+	var req models.SignatureCallbackRequest
+	if err := json.Unmarshal(payload, &req); err != nil {
+		return nil, fmt.Errorf("invalid callback payload: %w", err)
+	}
+	if err := validator.New().Struct(req); err != nil {
+		return nil, fmt.Errorf("invalid callback payload: %w", err)
+	}
+
+	return &req, nil
+}
+
+// processCallbackTx applies the callback to the locked signature, storing the downloaded content of a signed
+// agreement; undo deletes the stored file if the transaction does not commit
+func (s *SignatureService) processCallbackTx(tx *sql.Tx, req *models.SignatureCallbackRequest, content []byte, undo *rollbackActions) (*models.AgreementSignature, error) {
+	signature, err := s.signatureRepo.LockAgreementSignature(tx, s.signatureAdapter.Provider(), req.EnvelopeID)
+	if err != nil {
+		s.logger.Error("Failed to lock agreement signature", map[string]interface{}{
+			"error":       err.Error(),
+			"envelope_id": req.EnvelopeID,
+		})
+		return nil, err
+	}
+
+	if signature.Status != models.AgreementSignatureStatusPending {
+		// Providers redeliver callbacks; the same outcome again is not an error
+		if signature.Status == req.Status {
+			return signature, nil
+		}
+		return nil, fmt.Errorf("agreement signature is already %s", signature.Status)
+	}
+
+	completedAt := time.Now()
+	if req.SignedAt != nil {
+		completedAt = *req.SignedAt
+	}
+	signature.CompletedAt = &completedAt
+	signature.Status = req.Status
+
+	if req.Status == models.AgreementSignatureStatusSigned {
+		fileName := fmt.Sprintf("signed_agreement_loan_%s.pdf", signature.LoanID.String())
+		upload, err := s.fileService.StoreFile(tx, content, fileName, "application/pdf", "loan", signature.LoanID)
+		if err != nil {
+			s.logger.Error("Failed to store signed agreement", map[string]interface{}{
+				"error":   err.Error(),
+				"loan_id": signature.LoanID.String(),
+			})
+			return nil, err
+		}
+		undo.add(func() { s.fileService.DeleteStoredFile(upload) })
+
+		signature.SignedFileURL = upload.FileURL
+		signature.SignedFileType = models.FileTypePDF
+	}
+
+	if err := s.signatureRepo.UpdateAgreementSignature(tx, signature); err != nil {
+		s.logger.Error("Failed to update agreement signature", map[string]interface{}{
+			"error":       err.Error(),
+			"envelope_id": req.EnvelopeID,
+		})
+		return nil, err
+	}
+
+	s.logger.Info("Agreement signature completed", map[string]interface{}{
+		"loan_id":     signature.LoanID.String(),
+		"envelope_id": req.EnvelopeID,
+		"status":      signature.Status,
+	})
+
+	return signature, nil
+}
@@ -0,0 +1,284 @@
+package services
+
+import (
+	"database/sql"
+	"testing"
+
+	"loan-service/internal/models"
+	"loan-service/pkg/adapters"
+	"loan-service/pkg/config"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSignatureRepository struct {
+	mock.Mock
+}
+
+func (m *MockSignatureRepository) CreateAgreementSignature(tx *sql.Tx, signature *models.AgreementSignature) (*models.AgreementSignature, error) {
+	args := m.Called(tx, signature)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AgreementSignature), args.Error(1)
+}
+
+func (m *MockSignatureRepository) LockAgreementSignature(tx *sql.Tx, provider, envelopeID string) (*models.AgreementSignature, error) {
+	args := m.Called(tx, provider, envelopeID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AgreementSignature), args.Error(1)
+}
+
+func (m *MockSignatureRepository) GetAgreementSignature(provider, envelopeID string) (*models.AgreementSignature, error) {
+	args := m.Called(provider, envelopeID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AgreementSignature), args.Error(1)
+}
+
+func (m *MockSignatureRepository) UpdateAgreementSignature(tx *sql.Tx, signature *models.AgreementSignature) error {
+	args := m.Called(tx, signature)
+	return args.Error(0)
+}
+
+func (m *MockSignatureRepository) GetOpenAgreementSignature(tx *sql.Tx, loanID uuid.UUID) (*models.AgreementSignature, error) {
+	args := m.Called(tx, loanID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AgreementSignature), args.Error(1)
+}
+
+type MockSignatureAdapter struct {
+	mock.Mock
+}
+
+func (m *MockSignatureAdapter) Provider() string {
+	return "mock"
+}
+
+func (m *MockSignatureAdapter) SendForSignature(req adapters.SignatureRequest) (*adapters.SignatureEnvelope, error) {
+	args := m.Called(req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*adapters.SignatureEnvelope), args.Error(1)
+}
+
+func (m *MockSignatureAdapter) VerifyCallback(payload []byte, signature string) error {
+	args := m.Called(payload, signature)
+	return args.Error(0)
+}
+
+func (m *MockSignatureAdapter) DownloadSignedDocument(envelopeID string) ([]byte, error) {
+	args := m.Called(envelopeID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+// TestSignatureService is a test-specific version that overrides withTransaction
+type TestSignatureService struct {
+	*SignatureService
+}
+
+func (s *TestSignatureService) withTransaction(fn func(*sql.Tx) error) error {
+	return fn(nil)
+}
+
+func (s *TestSignatureService) ProcessSendForSignature(loanID uuid.UUID) (*models.AgreementSignature, error) {
+	var result *models.AgreementSignature
+	err := s.withTransaction(func(tx *sql.Tx) error {
+		var sendErr error
+		result, sendErr = s.processSendForSignatureTx(tx, loanID)
+		return sendErr
+	})
+
+	return result, err
+}
+
+func (s *TestSignatureService) ProcessCallback(payload []byte, callbackSignature string) (*models.AgreementSignature, error) {
+	req, err := s.parseCallback(payload, callbackSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := s.downloadSignedDocument(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *models.AgreementSignature
+	var undo rollbackActions
+	err = s.withTransaction(func(tx *sql.Tx) error {
+		var callbackErr error
+		result, callbackErr = s.processCallbackTx(tx, req, content, &undo)
+		return callbackErr
+	})
+	if err != nil {
+		undo.run()
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func setupTestSignatureService() (*TestSignatureService, *MockSignatureRepository, *MockLoanRepository, *MockSignatureAdapter, *MockFileService) {
+	mockSignatures := &MockSignatureRepository{}
+	mockRepo := &MockLoanRepository{}
+	mockAdapter := &MockSignatureAdapter{}
+	mockFile := &MockFileService{}
+
+	baseService := NewSignatureService(mockSignatures, mockRepo, mockAdapter, mockFile, config.SignatureConfig{Provider: "mock"}, &TestLogger{}, nil).(*SignatureService)
+
+	return &TestSignatureService{SignatureService: baseService}, mockSignatures, mockRepo, mockAdapter, mockFile
+}
+
+func createTestPendingSignature(loanID uuid.UUID) *models.AgreementSignature {
+	return &models.AgreementSignature{
+		BaseModel:   models.BaseModel{ID: uuid.New()},
+		LoanID:      loanID,
+		Provider:    "mock",
+		EnvelopeID:  "mock_env_1234",
+		Status:      models.AgreementSignatureStatusPending,
+		DocumentURL: "https://storage.example.com/uploads/agreements/agreement_loan.pdf",
+	}
+}
+
+func TestSignatureService_ProcessSendForSignature_Success(t *testing.T) {
+	service, mockSignatures, mockRepo, mockAdapter, mockFile := setupTestSignatureService()
+
+	loanID := uuid.New()
+	loan := createTestLoan(loanID, models.LoanStateInvested, 10000.0)
+	loan.AgreementLetterURL = "https://storage.example.com/uploads/agreements/agreement_loan.pdf"
+	signedURL := loan.AgreementLetterURL + "?expires=1700000000&signature=abc"
+
+	mockRepo.On("LockLoan", (*sql.Tx)(nil), loanID).Return(nil)
+	mockRepo.On("GetLoanByID", (*sql.Tx)(nil), loanID).Return(loan, nil)
+	mockSignatures.On("GetOpenAgreementSignature", (*sql.Tx)(nil), loanID).Return(nil, nil)
+	mockFile.On("SignFileURL", loan.AgreementLetterURL).Return(signedURL)
+	mockAdapter.On("SendForSignature", mock.MatchedBy(func(req adapters.SignatureRequest) bool {
+		return req.DocumentURL == signedURL && req.SignerEmail == loan.Borrower.Email
+	})).Return(&adapters.SignatureEnvelope{EnvelopeID: "mock_env_1234", SigningURL: "https://sign.example.com/envelopes/mock_env_1234"}, nil)
+	mockSignatures.On("CreateAgreementSignature", (*sql.Tx)(nil), mock.MatchedBy(func(s *models.AgreementSignature) bool {
+		return s.LoanID == loanID && s.EnvelopeID == "mock_env_1234" && s.Status == models.AgreementSignatureStatusPending
+	})).Return(&models.AgreementSignature{LoanID: loanID, Status: models.AgreementSignatureStatusPending}, nil)
+
+	result, err := service.ProcessSendForSignature(loanID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.AgreementSignatureStatusPending, result.Status)
+	mockSignatures.AssertExpectations(t)
+	mockAdapter.AssertExpectations(t)
+}
+
+func TestSignatureService_ProcessSendForSignature_AlreadyPending(t *testing.T) {
+	service, mockSignatures, mockRepo, mockAdapter, _ := setupTestSignatureService()
+
+	loanID := uuid.New()
+	loan := createTestLoan(loanID, models.LoanStateInvested, 10000.0)
+	loan.AgreementLetterURL = "https://storage.example.com/uploads/agreements/agreement_loan.pdf"
+
+	mockRepo.On("LockLoan", (*sql.Tx)(nil), loanID).Return(nil)
+	mockRepo.On("GetLoanByID", (*sql.Tx)(nil), loanID).Return(loan, nil)
+	mockSignatures.On("GetOpenAgreementSignature", (*sql.Tx)(nil), loanID).Return(createTestPendingSignature(loanID), nil)
+
+	result, err := service.ProcessSendForSignature(loanID)
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	assert.Contains(t, err.Error(), "already pending")
+	mockAdapter.AssertNotCalled(t, "SendForSignature", mock.Anything)
+}
+
+func TestSignatureService_ProcessCallback_InvalidSignature(t *testing.T) {
+	service, mockSignatures, _, mockAdapter, _ := setupTestSignatureService()
+
+	payload := []byte(`{"envelope_id":"mock_env_1234","status":"signed"}`)
+	mockAdapter.On("VerifyCallback", payload, "forged").Return(assert.AnError)
+
+	result, err := service.ProcessCallback(payload, "forged")
+
+	assert.ErrorIs(t, err, ErrInvalidCallbackSignature)
+	assert.Nil(t, result)
+	mockSignatures.AssertNotCalled(t, "LockAgreementSignature", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSignatureService_ProcessCallback_Signed(t *testing.T) {
+	service, mockSignatures, _, mockAdapter, mockFile := setupTestSignatureService()
+
+	loanID := uuid.New()
+	signature := createTestPendingSignature(loanID)
+	payload := []byte(`{"envelope_id":"mock_env_1234","status":"signed"}`)
+	signedURL := "https://storage.example.com/uploads/agreements/signed_agreement_loan.pdf"
+
+	mockAdapter.On("VerifyCallback", payload, "valid").Return(nil)
+	mockSignatures.On("GetAgreementSignature", "mock", "mock_env_1234").Return(createTestPendingSignature(loanID), nil)
+	mockSignatures.On("LockAgreementSignature", (*sql.Tx)(nil), "mock", "mock_env_1234").Return(signature, nil)
+	mockAdapter.On("DownloadSignedDocument", "mock_env_1234").Return([]byte("%PDF-1.4"), nil)
+	mockFile.On("StoreFile", (*sql.Tx)(nil), []byte("%PDF-1.4"), mock.AnythingOfType("string"), "application/pdf", "loan", loanID).Return(&models.FileUpload{FileURL: signedURL}, nil)
+	mockSignatures.On("UpdateAgreementSignature", (*sql.Tx)(nil), signature).Return(nil)
+
+	result, err := service.ProcessCallback(payload, "valid")
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.AgreementSignatureStatusSigned, result.Status)
+	assert.Equal(t, signedURL, result.SignedFileURL)
+	assert.NotNil(t, result.CompletedAt)
+	assert.True(t, result.IsCompleted())
+	mockSignatures.AssertExpectations(t)
+}
+
+func TestSignatureService_ProcessCallback_Redelivered(t *testing.T) {
+	service, mockSignatures, _, mockAdapter, _ := setupTestSignatureService()
+
+	loanID := uuid.New()
+	signature := createTestPendingSignature(loanID)
+	signature.Status = models.AgreementSignatureStatusSigned
+	signature.SignedFileURL = "https://storage.example.com/uploads/agreements/signed_agreement_loan.pdf"
+	payload := []byte(`{"envelope_id":"mock_env_1234","status":"signed"}`)
+
+	mockAdapter.On("VerifyCallback", payload, "valid").Return(nil)
+	mockSignatures.On("GetAgreementSignature", "mock", "mock_env_1234").Return(signature, nil)
+	mockSignatures.On("LockAgreementSignature", (*sql.Tx)(nil), "mock", "mock_env_1234").Return(signature, nil)
+
+	result, err := service.ProcessCallback(payload, "valid")
+
+	assert.NoError(t, err)
+	assert.Equal(t, signature, result)
+	mockAdapter.AssertNotCalled(t, "DownloadSignedDocument", mock.Anything)
+	mockSignatures.AssertNotCalled(t, "UpdateAgreementSignature", mock.Anything, mock.Anything)
+}
+
+func TestSignatureService_ProcessCallback_RollbackDeletesStoredFile(t *testing.T) {
+	service, mockSignatures, _, mockAdapter, mockFile := setupTestSignatureService()
+
+	loanID := uuid.New()
+	signature := createTestPendingSignature(loanID)
+	payload := []byte(`{"envelope_id":"mock_env_1234","status":"signed"}`)
+	upload := &models.FileUpload{FilePath: "agreements/signed_agreement_loan.pdf", FileURL: "https://storage.example.com/uploads/agreements/signed_agreement_loan.pdf"}
+
+	mockAdapter.On("VerifyCallback", payload, "valid").Return(nil)
+	mockSignatures.On("GetAgreementSignature", "mock", "mock_env_1234").Return(createTestPendingSignature(loanID), nil)
+	mockAdapter.On("DownloadSignedDocument", "mock_env_1234").Run(func(mock.Arguments) {
+		// The provider is not waited on while the signature row is locked
+		mockSignatures.AssertNotCalled(t, "LockAgreementSignature", mock.Anything, mock.Anything, mock.Anything)
+	}).Return([]byte("%PDF-1.4"), nil)
+	mockSignatures.On("LockAgreementSignature", (*sql.Tx)(nil), "mock", "mock_env_1234").Return(signature, nil)
+	mockFile.On("StoreFile", (*sql.Tx)(nil), []byte("%PDF-1.4"), mock.AnythingOfType("string"), "application/pdf", "loan", loanID).Return(upload, nil)
+	mockSignatures.On("UpdateAgreementSignature", (*sql.Tx)(nil), signature).Return(assert.AnError)
+	mockFile.On("DeleteStoredFile", upload).Return()
+
+	result, err := service.ProcessCallback(payload, "valid")
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	mockFile.AssertCalled(t, "DeleteStoredFile", upload)
+	mockAdapter.AssertExpectations(t)
+}
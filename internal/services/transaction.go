@@ -0,0 +1,70 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+
+	"loan-service/pkg/logger"
+)
+
+// runInTransaction executes fn inside a database transaction, rolling back on error or panic
+func runInTransaction(db *sql.DB, log logger.LoggerInterface, fn func(*sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		log.Error("Failed to begin transaction", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			log.Error("Panic in transaction, rolling back", map[string]interface{}{
+				"panic": p,
+			})
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	err = fn(tx)
+	if err != nil {
+		log.Error("Transaction failed, rolling back", map[string]interface{}{
+			"error": err.Error(),
+		})
+		rollbackErr := tx.Rollback()
+		if rollbackErr != nil {
+			log.Error("Failed to rollback transaction", map[string]interface{}{
+				"rollback_error": rollbackErr.Error(),
+				"original_error": err.Error(),
+			})
+			return fmt.Errorf("failed to rollback transaction: %w (original error: %w)", rollbackErr, err)
+		}
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Error("Failed to commit transaction", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// rollbackActions undo work done outside the database, such as a claimed reservation, when the transaction it was
+// done for does not commit
+type rollbackActions []func()
+
+func (a *rollbackActions) add(action func()) {
+	*a = append(*a, action)
+}
+
+// run undoes the work in reverse order
+func (a rollbackActions) run() {
+	for i := len(a) - 1; i >= 0; i-- {
+		a[i]()
+	}
+}
@@ -0,0 +1,501 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"loan-service/internal/models"
+	"loan-service/internal/repositories"
+	"loan-service/pkg/adapters"
+	"loan-service/pkg/config"
+	"loan-service/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+type TransferService struct {
+	transferRepo    repositories.TransferRepositoryInterface
+	loanRepo        repositories.LoanRepositoryInterface
+	walletRepo      repositories.WalletRepositoryInterface
+	documentAdapter adapters.DocumentAdapterInterface
+	fileService     FileServiceInterface
+	limits          models.InvestmentLimits
+	logger          logger.LoggerInterface
+	db              *sql.DB
+}
+
+func NewTransferService(
+	transferRepo repositories.TransferRepositoryInterface,
+	loanRepo repositories.LoanRepositoryInterface,
+	walletRepo repositories.WalletRepositoryInterface,
+	documentAdapter adapters.DocumentAdapterInterface,
+	fileService FileServiceInterface,
+	limitsCfg config.InvestmentLimitsConfig,
+	logger logger.LoggerInterface,
+	db *sql.DB,
+) TransferServiceInterface {
+	return &TransferService{
+		transferRepo:    transferRepo,
+		loanRepo:        loanRepo,
+		walletRepo:      walletRepo,
+		documentAdapter: documentAdapter,
+		fileService:     fileService,
+		limits:          newInvestmentLimits(limitsCfg),
+		logger:          logger,
+		db:              db,
+	}
+}
+
+func (s *TransferService) withTransaction(fn func(*sql.Tx) error) error {
+	return runInTransaction(s.db, s.logger, fn)
+}
+
+// ProcessListInvestment offers a whole investment or a slice of its principal for sale at the given price
+func (s *TransferService) ProcessListInvestment(investmentID uuid.UUID, req *models.CreateTransferListingRequest) (*models.InvestmentTransfer, error) {
+	s.logger.Info("Listing investment for transfer", map[string]interface{}{"investment_id": investmentID, "request": req})
+
+	if err := s.checkActiveInvestor(req.InvestorID); err != nil {
+		return nil, err
+	}
+
+	var result *models.InvestmentTransfer
+	err := s.withTransaction(func(tx *sql.Tx) error {
+		var listErr error
+		result, listErr = s.processListInvestmentTx(tx, investmentID, req)
+		return listErr
+	})
+
+	return result, err
+}
+
+func (s *TransferService) processListInvestmentTx(tx *sql.Tx, investmentID uuid.UUID, req *models.CreateTransferListingRequest) (*models.InvestmentTransfer, error) {
+	investment, err := s.loanRepo.LockInvestment(tx, investmentID)
+	if err != nil {
+		s.logger.Error("Failed to lock investment", map[string]interface{}{
+			"error":         err.Error(),
+			"investment_id": investmentID.String(),
+		})
+		return nil, err
+	}
+
+	if investment.InvestorID != req.InvestorID {
+		return nil, fmt.Errorf("investment %s does not belong to investor %s", investmentID, req.InvestorID)
+	}
+
+	if req.Amount > investment.Amount {
+		return nil, fmt.Errorf("transfer amount %.2f exceeds investment amount %.2f", req.Amount, investment.Amount)
+	}
+
+	if err := s.checkTransferableLoan(tx, investment.LoanID); err != nil {
+		return nil, err
+	}
+
+	transfer, err := s.transferRepo.CreateInvestmentTransfer(tx, &models.InvestmentTransfer{
+		InvestmentID: investmentID,
+		LoanID:       investment.LoanID,
+		SellerID:     req.InvestorID,
+		Amount:       req.Amount,
+		Price:        req.Price,
+		Status:       models.InvestmentTransferStatusListed,
+	})
+	if err != nil {
+		s.logger.Error("Failed to create investment transfer", map[string]interface{}{
+			"error":         err.Error(),
+			"investment_id": investmentID.String(),
+		})
+		return nil, err
+	}
+
+	return transfer, nil
+}
+
+// GetListings returns the secondary market listings open for buying
+func (s *TransferService) GetListings() ([]*models.InvestmentTransfer, error) {
+	transfers, err := s.transferRepo.GetListedInvestmentTransfers()
+	if err != nil {
+		s.logger.Error("Failed to get listed investment transfers", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+
+	return transfers, nil
+}
+
+// ProcessBuyTransfer settles a listing: the buyer pays the price into the seller's wallet and
+// takes over the listed principal, and with it the future repayments, as a new investment
+func (s *TransferService) ProcessBuyTransfer(transferID uuid.UUID, req *models.BuyTransferRequest) (*models.InvestmentTransfer, error) {
+	s.logger.Info("Buying investment transfer", map[string]interface{}{"transfer_id": transferID, "request": req})
+
+	if err := s.checkActiveInvestor(req.InvestorID); err != nil {
+		return nil, err
+	}
+
+	var result *models.InvestmentTransfer
+	var undo rollbackActions
+	err := s.withTransaction(func(tx *sql.Tx) error {
+		var buyErr error
+		result, buyErr = s.processBuyTransferTx(tx, transferID, req, &undo)
+		return buyErr
+	})
+	if err != nil {
+		undo.run()
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// processBuyTransferTx settles the listing in the transaction; undo collects what must be reverted outside the
+// database if the transaction does not commit
+func (s *TransferService) processBuyTransferTx(tx *sql.Tx, transferID uuid.UUID, req *models.BuyTransferRequest, undo *rollbackActions) (*models.InvestmentTransfer, error) {
+	transfer, err := s.transferRepo.LockInvestmentTransfer(tx, transferID)
+	if err != nil {
+		s.logger.Error("Failed to lock investment transfer", map[string]interface{}{
+			"error":       err.Error(),
+			"transfer_id": transferID.String(),
+		})
+		return nil, err
+	}
+
+	if transfer.Status != models.InvestmentTransferStatusListed {
+		return nil, fmt.Errorf("transfer %s is not listed, current status: %s", transferID, transfer.Status)
+	}
+
+	if transfer.SellerID == req.InvestorID {
+		return nil, fmt.Errorf("investor %s cannot buy their own listing", req.InvestorID)
+	}
+
+	investment, err := s.loanRepo.LockInvestment(tx, transfer.InvestmentID)
+	if err != nil {
+		s.logger.Error("Failed to lock investment", map[string]interface{}{
+			"error":         err.Error(),
+			"investment_id": transfer.InvestmentID.String(),
+		})
+		return nil, err
+	}
+
+	if investment.InvestorID != transfer.SellerID || transfer.Amount > investment.Amount {
+		return nil, fmt.Errorf("investment %s no longer covers transfer %s", investment.ID, transferID)
+	}
+
+	loan, err := s.loanRepo.GetLoanByID(tx, transfer.LoanID)
+	if err != nil {
+		s.logger.Error("Failed to get loan by ID", map[string]interface{}{
+			"error":   err.Error(),
+			"loan_id": transfer.LoanID.String(),
+		})
+		return nil, err
+	}
+
+	if loan.State != models.LoanStateDisbursed {
+		return nil, fmt.Errorf("investments can only be transferred while the loan is disbursed, current state: %s", loan.State)
+	}
+
+	buyerWallet, sellerWallet, err := s.lockWallets(tx, req.InvestorID, transfer.SellerID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The buyer takes on the principal, so it counts against their limits like an investment of that amount
+	if err := validateInvestmentLimitsTx(tx, s.loanRepo, s.limits, s.logger, loan, req.InvestorID, transfer.Amount); err != nil {
+		return nil, err
+	}
+
+	balance, err := s.walletRepo.GetWalletBalance(tx, req.InvestorID)
+	if err != nil {
+		s.logger.Error("Failed to get investor wallet balance", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": req.InvestorID.String(),
+		})
+		return nil, err
+	}
+
+	if !balance.CanCover(transfer.Price) {
+		return nil, fmt.Errorf("insufficient wallet balance: available %.2f, required %.2f", balance.AvailableBalance, transfer.Price)
+	}
+
+	// The buyer's investment is carved out of the seller's, keeping the link for the lineage
+	now := time.Now()
+	buyerInvestment, err := s.loanRepo.CreateInvestment(tx, &models.Investment{
+		BaseModel: models.BaseModel{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		LoanID:             transfer.LoanID,
+		InvestorID:         req.InvestorID,
+		Amount:             transfer.Amount,
+		ExpectedReturn:     transfer.Amount * (1 + loan.ROI),
+		InvestmentDate:     now,
+		ParentInvestmentID: &investment.ID,
+	})
+	if err != nil {
+		s.logger.Error("Failed to create buyer investment", map[string]interface{}{
+			"error":       err.Error(),
+			"transfer_id": transferID.String(),
+		})
+		return nil, err
+	}
+
+	remaining := investment.Amount - transfer.Amount
+	if transfer.IsWhole(investment) {
+		err = s.loanRepo.SoftDeleteInvestment(tx, investment.ID)
+	} else {
+		err = s.loanRepo.UpdateInvestmentAmount(tx, investment.ID, remaining, remaining*(1+loan.ROI))
+	}
+	if err != nil {
+		s.logger.Error("Failed to reduce seller investment", map[string]interface{}{
+			"error":         err.Error(),
+			"investment_id": investment.ID.String(),
+		})
+		return nil, err
+	}
+
+	description := fmt.Sprintf("Transfer of investment in loan #%s", transfer.LoanID.String()[:8])
+	for _, entry := range []*models.WalletTransaction{
+		{
+			WalletID:        buyerWallet.ID,
+			InvestorID:      req.InvestorID,
+			TransactionType: models.WalletTransactionTransferOut,
+			Amount:          transfer.Price,
+			ReferenceType:   models.WalletReferenceInvestmentTransfer,
+			ReferenceID:     &transfer.ID,
+			Description:     description,
+		},
+		{
+			WalletID:        sellerWallet.ID,
+			InvestorID:      transfer.SellerID,
+			TransactionType: models.WalletTransactionTransferIn,
+			Amount:          transfer.Price,
+			ReferenceType:   models.WalletReferenceInvestmentTransfer,
+			ReferenceID:     &transfer.ID,
+			Description:     description,
+		},
+	} {
+		if _, err := s.walletRepo.CreateWalletTransaction(tx, entry); err != nil {
+			s.logger.Error("Failed to settle investment transfer", map[string]interface{}{
+				"error":       err.Error(),
+				"transfer_id": transferID.String(),
+				"investor_id": entry.InvestorID.String(),
+			})
+			return nil, err
+		}
+	}
+
+	transfer.Status = models.InvestmentTransferStatusCompleted
+	transfer.BuyerID = &req.InvestorID
+	transfer.BuyerInvestmentID = &buyerInvestment.ID
+	transfer.CompletedAt = &now
+
+	agreementURL, err := s.generateTransferAgreement(tx, transfer, loan, undo)
+	if err != nil {
+		s.logger.Error("Failed to generate transfer agreement", map[string]interface{}{
+			"error":       err.Error(),
+			"transfer_id": transferID.String(),
+		})
+		return nil, err
+	}
+	transfer.AgreementURL = agreementURL
+
+	// The transfer agreement is the buyer's agreement for the investment
+	if err := s.loanRepo.UpdateInvestmentAgreementURL(tx, buyerInvestment.ID, agreementURL); err != nil {
+		s.logger.Error("Failed to update buyer investment agreement URL", map[string]interface{}{
+			"error":         err.Error(),
+			"investment_id": buyerInvestment.ID.String(),
+		})
+		return nil, err
+	}
+
+	if err := s.transferRepo.UpdateInvestmentTransfer(tx, transfer); err != nil {
+		s.logger.Error("Failed to complete investment transfer", map[string]interface{}{
+			"error":       err.Error(),
+			"transfer_id": transferID.String(),
+		})
+		return nil, err
+	}
+
+	// The seller's other listings of this investment cannot be bought once it no longer holds their amount
+	cancelled, err := s.transferRepo.CancelUncoveredInvestmentTransfers(tx, investment.ID, remaining)
+	if err != nil {
+		s.logger.Error("Failed to cancel uncovered investment transfers", map[string]interface{}{
+			"error":         err.Error(),
+			"investment_id": investment.ID.String(),
+		})
+		return nil, err
+	}
+	if cancelled > 0 {
+		s.logger.Info("Cancelled listings no longer covered by the investment", map[string]interface{}{
+			"investment_id": investment.ID.String(),
+			"remaining":     remaining,
+			"cancelled":     cancelled,
+		})
+	}
+
+	s.logger.Info("Investment transfer completed", map[string]interface{}{
+		"transfer_id":         transferID.String(),
+		"seller_id":           transfer.SellerID.String(),
+		"buyer_id":            req.InvestorID.String(),
+		"buyer_investment_id": buyerInvestment.ID.String(),
+	})
+
+	return transfer, nil
+}
+
+// ProcessCancelListing withdraws a listing that has not been bought yet
+func (s *TransferService) ProcessCancelListing(transferID uuid.UUID, req *models.CancelTransferListingRequest) (*models.InvestmentTransfer, error) {
+	s.logger.Info("Cancelling investment transfer listing", map[string]interface{}{"transfer_id": transferID, "request": req})
+
+	var result *models.InvestmentTransfer
+	err := s.withTransaction(func(tx *sql.Tx) error {
+		var cancelErr error
+		result, cancelErr = s.processCancelListingTx(tx, transferID, req)
+		return cancelErr
+	})
+
+	return result, err
+}
+
+func (s *TransferService) processCancelListingTx(tx *sql.Tx, transferID uuid.UUID, req *models.CancelTransferListingRequest) (*models.InvestmentTransfer, error) {
+	transfer, err := s.transferRepo.LockInvestmentTransfer(tx, transferID)
+	if err != nil {
+		s.logger.Error("Failed to lock investment transfer", map[string]interface{}{
+			"error":       err.Error(),
+			"transfer_id": transferID.String(),
+		})
+		return nil, err
+	}
+
+	if transfer.SellerID != req.InvestorID {
+		return nil, fmt.Errorf("transfer %s does not belong to investor %s", transferID, req.InvestorID)
+	}
+
+	if transfer.Status != models.InvestmentTransferStatusListed {
+		return nil, fmt.Errorf("transfer %s is not listed, current status: %s", transferID, transfer.Status)
+	}
+
+	now := time.Now()
+	transfer.Status = models.InvestmentTransferStatusCancelled
+	transfer.CancelledAt = &now
+
+	if err := s.transferRepo.UpdateInvestmentTransfer(tx, transfer); err != nil {
+		s.logger.Error("Failed to cancel investment transfer", map[string]interface{}{
+			"error":       err.Error(),
+			"transfer_id": transferID.String(),
+		})
+		return nil, err
+	}
+
+	return transfer, nil
+}
+
+// GetInvestmentLineage returns the completed transfers in the ownership chain of an investment
+func (s *TransferService) GetInvestmentLineage(investmentID uuid.UUID) ([]*models.InvestmentTransfer, error) {
+	transfers, err := s.transferRepo.GetInvestmentLineage(investmentID)
+	if err != nil {
+		s.logger.Error("Failed to get investment lineage", map[string]interface{}{
+			"error":         err.Error(),
+			"investment_id": investmentID.String(),
+		})
+		return nil, err
+	}
+
+	return transfers, nil
+}
+
+func (s *TransferService) checkActiveInvestor(investorID uuid.UUID) error {
+	investor, err := s.loanRepo.GetInvestorByID(investorID)
+	if err != nil {
+		s.logger.Error("Failed to get investor by ID", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": investorID.String(),
+		})
+		return err
+	}
+
+	if !investor.IsActive {
+		return fmt.Errorf("investor %s is not active", investor.InvestorCode)
+	}
+
+	return nil
+}
+
+// checkTransferableLoan checks the loan has been disbursed, so its repayments are the only thing left to change hands
+func (s *TransferService) checkTransferableLoan(tx *sql.Tx, loanID uuid.UUID) error {
+	loan, err := s.loanRepo.GetLoanByID(tx, loanID)
+	if err != nil {
+		s.logger.Error("Failed to get loan by ID", map[string]interface{}{
+			"error":   err.Error(),
+			"loan_id": loanID.String(),
+		})
+		return err
+	}
+
+	if loan.State != models.LoanStateDisbursed {
+		return fmt.Errorf("investments can only be transferred while the loan is disbursed, current state: %s", loan.State)
+	}
+
+	return nil
+}
+
+// lockWallets locks the buyer and seller wallets in investor ID order so opposite transfers cannot deadlock
+func (s *TransferService) lockWallets(tx *sql.Tx, buyerID, sellerID uuid.UUID) (*models.Wallet, *models.Wallet, error) {
+	first, second := buyerID, sellerID
+	if sellerID.String() < buyerID.String() {
+		first, second = sellerID, buyerID
+	}
+
+	wallets := make(map[uuid.UUID]*models.Wallet, 2)
+	for _, investorID := range []uuid.UUID{first, second} {
+		wallet, err := s.walletRepo.LockWallet(tx, investorID)
+		if err != nil {
+			s.logger.Error("Failed to lock investor wallet", map[string]interface{}{
+				"error":       err.Error(),
+				"investor_id": investorID.String(),
+			})
+			return nil, nil, err
+		}
+		wallets[investorID] = wallet
+	}
+
+	return wallets[buyerID], wallets[sellerID], nil
+}
+
+// generateTransferAgreement renders and stores the agreement between the seller and buyer of a completed transfer.
+// The stored document is deleted through undo if the transaction does not commit.
+func (s *TransferService) generateTransferAgreement(tx *sql.Tx, transfer *models.InvestmentTransfer, loan *models.Loan, undo *rollbackActions) (string, error) {
+	seller, err := s.loanRepo.GetInvestorByID(transfer.SellerID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get seller %s: %w", transfer.SellerID, err)
+	}
+	buyer, err := s.loanRepo.GetInvestorByID(*transfer.BuyerID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get buyer %s: %w", *transfer.BuyerID, err)
+	}
+
+	doc, err := s.documentAdapter.GenerateTransferAgreement(&models.TransferAgreementData{
+		Transfer:    transfer,
+		Loan:        loan,
+		Seller:      seller,
+		Buyer:       buyer,
+		GeneratedAt: time.Now(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render transfer agreement: %w", err)
+	}
+
+	upload, err := s.fileService.StoreFile(tx, doc.Content, doc.FileName, doc.ContentType, "transfer", transfer.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to store transfer agreement: %w", err)
+	}
+	undo.add(func() { s.fileService.DeleteStoredFile(upload) })
+
+	s.logger.Info("Generated transfer agreement", map[string]interface{}{
+		"transfer_id":   transfer.ID.String(),
+		"filename":      doc.FileName,
+		"agreement_url": upload.FileURL,
+	})
+
+	return upload.FileURL, nil
+}
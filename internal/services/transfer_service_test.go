@@ -0,0 +1,354 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"loan-service/internal/models"
+	"loan-service/pkg/config"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockTransferRepository struct {
+	mock.Mock
+}
+
+func (m *MockTransferRepository) CreateInvestmentTransfer(tx *sql.Tx, transfer *models.InvestmentTransfer) (*models.InvestmentTransfer, error) {
+	args := m.Called(tx, transfer)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.InvestmentTransfer), args.Error(1)
+}
+
+func (m *MockTransferRepository) LockInvestmentTransfer(tx *sql.Tx, transferID uuid.UUID) (*models.InvestmentTransfer, error) {
+	args := m.Called(tx, transferID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.InvestmentTransfer), args.Error(1)
+}
+
+func (m *MockTransferRepository) UpdateInvestmentTransfer(tx *sql.Tx, transfer *models.InvestmentTransfer) error {
+	args := m.Called(tx, transfer)
+	return args.Error(0)
+}
+
+func (m *MockTransferRepository) CancelUncoveredInvestmentTransfers(tx *sql.Tx, investmentID uuid.UUID, remaining float64) (int64, error) {
+	args := m.Called(tx, investmentID, remaining)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockTransferRepository) GetInvestmentTransferByID(transferID uuid.UUID) (*models.InvestmentTransfer, error) {
+	args := m.Called(transferID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.InvestmentTransfer), args.Error(1)
+}
+
+func (m *MockTransferRepository) GetListedInvestmentTransfers() ([]*models.InvestmentTransfer, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.InvestmentTransfer), args.Error(1)
+}
+
+func (m *MockTransferRepository) GetInvestmentLineage(investmentID uuid.UUID) ([]*models.InvestmentTransfer, error) {
+	args := m.Called(investmentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.InvestmentTransfer), args.Error(1)
+}
+
+// TestTransferService is a test-specific version that overrides withTransaction
+type TestTransferService struct {
+	*TransferService
+}
+
+func (s *TestTransferService) withTransaction(fn func(*sql.Tx) error) error {
+	return fn(nil)
+}
+
+func (s *TestTransferService) ProcessListInvestment(investmentID uuid.UUID, req *models.CreateTransferListingRequest) (*models.InvestmentTransfer, error) {
+	var result *models.InvestmentTransfer
+	err := s.withTransaction(func(tx *sql.Tx) error {
+		var listErr error
+		result, listErr = s.processListInvestmentTx(tx, investmentID, req)
+		return listErr
+	})
+
+	return result, err
+}
+
+func (s *TestTransferService) ProcessBuyTransfer(transferID uuid.UUID, req *models.BuyTransferRequest) (*models.InvestmentTransfer, error) {
+	var result *models.InvestmentTransfer
+	var undo rollbackActions
+	err := s.withTransaction(func(tx *sql.Tx) error {
+		var buyErr error
+		result, buyErr = s.processBuyTransferTx(tx, transferID, req, &undo)
+		return buyErr
+	})
+	if err != nil {
+		undo.run()
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func setupTestTransferService() (*TestTransferService, *MockTransferRepository, *MockLoanRepository, *MockWalletRepository) {
+	mockTransfers := &MockTransferRepository{}
+	mockRepo := &MockLoanRepository{}
+	mockWallet := &MockWalletRepository{}
+
+	baseService := NewTransferService(mockTransfers, mockRepo, mockWallet, &MockDocumentAdapter{}, &MockFileService{}, config.InvestmentLimitsConfig{}, &TestLogger{}, nil).(*TransferService)
+
+	return &TestTransferService{TransferService: baseService}, mockTransfers, mockRepo, mockWallet
+}
+
+// expectTransferAgreement sets up rendering and storing the agreement of a completed transfer
+func expectTransferAgreement(service *TestTransferService, mockRepo *MockLoanRepository, transfer *models.InvestmentTransfer, buyerID uuid.UUID) string {
+	mockDocument := service.documentAdapter.(*MockDocumentAdapter)
+	mockFile := service.fileService.(*MockFileService)
+	agreementURL := "https://api.example.com/api/v1/files/" + uuid.New().String() + "/download"
+
+	mockRepo.On("GetInvestorByID", transfer.SellerID).Return(&models.Investor{InvestorCode: "INV-001", Name: "Seller"}, nil)
+	mockRepo.On("GetInvestorByID", buyerID).Return(&models.Investor{InvestorCode: "INV-002", Name: "Buyer"}, nil)
+	mockDocument.On("GenerateTransferAgreement", mock.MatchedBy(func(data *models.TransferAgreementData) bool {
+		return data.Transfer == transfer && data.Seller.Name == "Seller" && data.Buyer.Name == "Buyer"
+	})).Return(&models.GeneratedDocument{
+		FileName:    "transfer_agreement.pdf",
+		ContentType: "application/pdf",
+		Content:     []byte("%PDF-1.4"),
+	}, nil)
+	mockFile.On("StoreFile", (*sql.Tx)(nil), []byte("%PDF-1.4"), "transfer_agreement.pdf", "application/pdf", "transfer", transfer.ID).Return(&models.FileUpload{FileURL: agreementURL}, nil)
+	mockRepo.On("UpdateInvestmentAgreementURL", (*sql.Tx)(nil), mock.AnythingOfType("uuid.UUID"), agreementURL).Return(nil)
+
+	return agreementURL
+}
+
+func createTestListedTransfer(investment *models.Investment, amount, price float64) *models.InvestmentTransfer {
+	return &models.InvestmentTransfer{
+		BaseModel:    models.BaseModel{ID: uuid.New()},
+		InvestmentID: investment.ID,
+		LoanID:       investment.LoanID,
+		SellerID:     investment.InvestorID,
+		Amount:       amount,
+		Price:        price,
+		Status:       models.InvestmentTransferStatusListed,
+	}
+}
+
+func createTestInvestment(loanID uuid.UUID, amount float64) *models.Investment {
+	return &models.Investment{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		LoanID:         loanID,
+		InvestorID:     uuid.New(),
+		Amount:         amount,
+		ExpectedReturn: amount * 1.15,
+		InvestmentDate: time.Now(),
+	}
+}
+
+func TestTransferService_ProcessBuyTransfer_PartialSlice(t *testing.T) {
+	service, mockTransfers, mockRepo, mockWallet := setupTestTransferService()
+
+	loanID := uuid.New()
+	buyerID := uuid.New()
+	investment := createTestInvestment(loanID, 4000.0)
+	transfer := createTestListedTransfer(investment, 1000.0, 950.0)
+
+	mockTransfers.On("LockInvestmentTransfer", (*sql.Tx)(nil), transfer.ID).Return(transfer, nil)
+	mockRepo.On("LockInvestment", (*sql.Tx)(nil), investment.ID).Return(investment, nil)
+	mockRepo.On("GetLoanByID", (*sql.Tx)(nil), loanID).Return(createTestLoan(loanID, models.LoanStateDisbursed, 10000.0), nil)
+	buyerWallet := expectFundedWallet(mockWallet, buyerID, 5000.0)
+	sellerWallet := expectFundedWallet(mockWallet, investment.InvestorID, 0)
+	expectNoInvestorLimits(mockRepo, buyerID)
+
+	mockRepo.On("CreateInvestment", (*sql.Tx)(nil), mock.MatchedBy(func(i *models.Investment) bool {
+		return i.InvestorID == buyerID && i.Amount == 1000.0 && *i.ParentInvestmentID == investment.ID
+	})).Return(&models.Investment{BaseModel: models.BaseModel{ID: uuid.New()}}, nil)
+	mockRepo.On("UpdateInvestmentAmount", (*sql.Tx)(nil), investment.ID, 3000.0, mock.AnythingOfType("float64")).Return(nil)
+	mockWallet.On("CreateWalletTransaction", (*sql.Tx)(nil), mock.MatchedBy(func(wt *models.WalletTransaction) bool {
+		return wt.WalletID == buyerWallet.ID && wt.TransactionType == models.WalletTransactionTransferOut && wt.Amount == 950.0
+	})).Return(&models.WalletTransaction{}, nil)
+	mockWallet.On("CreateWalletTransaction", (*sql.Tx)(nil), mock.MatchedBy(func(wt *models.WalletTransaction) bool {
+		return wt.WalletID == sellerWallet.ID && wt.TransactionType == models.WalletTransactionTransferIn && wt.Amount == 950.0
+	})).Return(&models.WalletTransaction{}, nil)
+	mockTransfers.On("UpdateInvestmentTransfer", (*sql.Tx)(nil), transfer).Return(nil)
+	mockTransfers.On("CancelUncoveredInvestmentTransfers", (*sql.Tx)(nil), investment.ID, 3000.0).Return(int64(1), nil)
+	agreementURL := expectTransferAgreement(service, mockRepo, transfer, buyerID)
+
+	result, err := service.ProcessBuyTransfer(transfer.ID, &models.BuyTransferRequest{InvestorID: buyerID})
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.InvestmentTransferStatusCompleted, result.Status)
+	assert.Equal(t, buyerID, *result.BuyerID)
+	assert.NotNil(t, result.BuyerInvestmentID)
+	assert.Equal(t, agreementURL, result.AgreementURL)
+	mockRepo.AssertNotCalled(t, "SoftDeleteInvestment", mock.Anything, mock.Anything)
+	mockWallet.AssertNumberOfCalls(t, "CreateWalletTransaction", 2)
+	mockRepo.AssertExpectations(t)
+	mockTransfers.AssertExpectations(t)
+}
+
+func TestTransferService_ProcessBuyTransfer_WholeInvestment(t *testing.T) {
+	service, mockTransfers, mockRepo, mockWallet := setupTestTransferService()
+
+	loanID := uuid.New()
+	buyerID := uuid.New()
+	investment := createTestInvestment(loanID, 4000.0)
+	transfer := createTestListedTransfer(investment, 4000.0, 4100.0)
+
+	mockTransfers.On("LockInvestmentTransfer", (*sql.Tx)(nil), transfer.ID).Return(transfer, nil)
+	mockRepo.On("LockInvestment", (*sql.Tx)(nil), investment.ID).Return(investment, nil)
+	mockRepo.On("GetLoanByID", (*sql.Tx)(nil), loanID).Return(createTestLoan(loanID, models.LoanStateDisbursed, 10000.0), nil)
+	expectFundedWallet(mockWallet, buyerID, 5000.0)
+	expectFundedWallet(mockWallet, investment.InvestorID, 0)
+	expectNoInvestorLimits(mockRepo, buyerID)
+	mockRepo.On("CreateInvestment", (*sql.Tx)(nil), mock.Anything).Return(&models.Investment{BaseModel: models.BaseModel{ID: uuid.New()}}, nil)
+	mockRepo.On("SoftDeleteInvestment", (*sql.Tx)(nil), investment.ID).Return(nil)
+	mockWallet.On("CreateWalletTransaction", (*sql.Tx)(nil), mock.Anything).Return(&models.WalletTransaction{}, nil)
+	mockTransfers.On("UpdateInvestmentTransfer", (*sql.Tx)(nil), transfer).Return(nil)
+	mockTransfers.On("CancelUncoveredInvestmentTransfers", (*sql.Tx)(nil), investment.ID, 0.0).Return(int64(2), nil)
+	expectTransferAgreement(service, mockRepo, transfer, buyerID)
+
+	result, err := service.ProcessBuyTransfer(transfer.ID, &models.BuyTransferRequest{InvestorID: buyerID})
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.InvestmentTransferStatusCompleted, result.Status)
+	mockRepo.AssertCalled(t, "SoftDeleteInvestment", (*sql.Tx)(nil), investment.ID)
+	mockRepo.AssertNotCalled(t, "UpdateInvestmentAmount", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	// Every other listing of the sold investment is withdrawn with it
+	mockTransfers.AssertCalled(t, "CancelUncoveredInvestmentTransfers", (*sql.Tx)(nil), investment.ID, 0.0)
+}
+
+func TestTransferService_ProcessBuyTransfer_RollbackDeletesStoredAgreement(t *testing.T) {
+	service, mockTransfers, mockRepo, mockWallet := setupTestTransferService()
+	mockFile := service.fileService.(*MockFileService)
+
+	loanID := uuid.New()
+	buyerID := uuid.New()
+	investment := createTestInvestment(loanID, 4000.0)
+	transfer := createTestListedTransfer(investment, 1000.0, 950.0)
+
+	mockTransfers.On("LockInvestmentTransfer", (*sql.Tx)(nil), transfer.ID).Return(transfer, nil)
+	mockRepo.On("LockInvestment", (*sql.Tx)(nil), investment.ID).Return(investment, nil)
+	mockRepo.On("GetLoanByID", (*sql.Tx)(nil), loanID).Return(createTestLoan(loanID, models.LoanStateDisbursed, 10000.0), nil)
+	expectFundedWallet(mockWallet, buyerID, 5000.0)
+	expectFundedWallet(mockWallet, investment.InvestorID, 0)
+	expectNoInvestorLimits(mockRepo, buyerID)
+	mockRepo.On("CreateInvestment", (*sql.Tx)(nil), mock.Anything).Return(&models.Investment{BaseModel: models.BaseModel{ID: uuid.New()}}, nil)
+	mockRepo.On("UpdateInvestmentAmount", (*sql.Tx)(nil), investment.ID, 3000.0, mock.AnythingOfType("float64")).Return(nil)
+	mockWallet.On("CreateWalletTransaction", (*sql.Tx)(nil), mock.Anything).Return(&models.WalletTransaction{}, nil)
+	agreementURL := expectTransferAgreement(service, mockRepo, transfer, buyerID)
+	mockTransfers.On("UpdateInvestmentTransfer", (*sql.Tx)(nil), transfer).Return(errors.New("database error"))
+	mockFile.On("DeleteStoredFile", mock.AnythingOfType("*models.FileUpload")).Return()
+
+	result, err := service.ProcessBuyTransfer(transfer.ID, &models.BuyTransferRequest{InvestorID: buyerID})
+
+	// The agreement stored before the failed update does not outlive the rolled back transfer
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	mockFile.AssertCalled(t, "DeleteStoredFile", mock.MatchedBy(func(upload *models.FileUpload) bool {
+		return upload.FileURL == agreementURL
+	}))
+	mockFile.AssertNumberOfCalls(t, "DeleteStoredFile", 1)
+	mockTransfers.AssertNotCalled(t, "CancelUncoveredInvestmentTransfers", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestTransferService_ProcessBuyTransfer_BuyerLimitExceeded(t *testing.T) {
+	service, mockTransfers, mockRepo, mockWallet := setupTestTransferService()
+	service.limits = models.InvestmentLimits{MaxLoanShare: 0.25}
+
+	loanID := uuid.New()
+	buyerID := uuid.New()
+	investment := createTestInvestment(loanID, 4000.0)
+	transfer := createTestListedTransfer(investment, 1000.0, 950.0)
+
+	mockTransfers.On("LockInvestmentTransfer", (*sql.Tx)(nil), transfer.ID).Return(transfer, nil)
+	mockRepo.On("LockInvestment", (*sql.Tx)(nil), investment.ID).Return(investment, nil)
+	mockRepo.On("GetLoanByID", (*sql.Tx)(nil), loanID).Return(createTestLoan(loanID, models.LoanStateDisbursed, 10000.0), nil)
+	expectFundedWallet(mockWallet, buyerID, 5000.0)
+	expectFundedWallet(mockWallet, investment.InvestorID, 0)
+	mockRepo.On("GetInvestorInvestmentLimit", (*sql.Tx)(nil), buyerID).Return(nil, nil)
+	mockRepo.On("GetInvestorExposure", (*sql.Tx)(nil), buyerID, loanID).Return(&models.InvestorExposure{LoanInvested: 2000.0, TotalOutstanding: 2000.0}, nil)
+
+	result, err := service.ProcessBuyTransfer(transfer.ID, &models.BuyTransferRequest{InvestorID: buyerID})
+
+	var limitErr *models.InvestmentLimitError
+	assert.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, models.CodeInvestmentLoanShareExceeded, limitErr.Code)
+	assert.Nil(t, result)
+	mockRepo.AssertNotCalled(t, "CreateInvestment", mock.Anything, mock.Anything)
+	mockWallet.AssertNotCalled(t, "CreateWalletTransaction", mock.Anything, mock.Anything)
+	mockTransfers.AssertNotCalled(t, "UpdateInvestmentTransfer", mock.Anything, mock.Anything)
+}
+
+func TestTransferService_ProcessBuyTransfer_InsufficientBalance(t *testing.T) {
+	service, mockTransfers, mockRepo, mockWallet := setupTestTransferService()
+
+	loanID := uuid.New()
+	buyerID := uuid.New()
+	investment := createTestInvestment(loanID, 4000.0)
+	transfer := createTestListedTransfer(investment, 1000.0, 950.0)
+
+	mockTransfers.On("LockInvestmentTransfer", (*sql.Tx)(nil), transfer.ID).Return(transfer, nil)
+	mockRepo.On("LockInvestment", (*sql.Tx)(nil), investment.ID).Return(investment, nil)
+	mockRepo.On("GetLoanByID", (*sql.Tx)(nil), loanID).Return(createTestLoan(loanID, models.LoanStateDisbursed, 10000.0), nil)
+	expectFundedWallet(mockWallet, buyerID, 900.0)
+	expectFundedWallet(mockWallet, investment.InvestorID, 0)
+	expectNoInvestorLimits(mockRepo, buyerID)
+
+	result, err := service.ProcessBuyTransfer(transfer.ID, &models.BuyTransferRequest{InvestorID: buyerID})
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	assert.Contains(t, err.Error(), "insufficient wallet balance")
+	mockRepo.AssertNotCalled(t, "CreateInvestment", mock.Anything, mock.Anything)
+	mockWallet.AssertNotCalled(t, "CreateWalletTransaction", mock.Anything, mock.Anything)
+}
+
+func TestTransferService_ProcessBuyTransfer_OwnListing(t *testing.T) {
+	service, mockTransfers, mockRepo, _ := setupTestTransferService()
+
+	investment := createTestInvestment(uuid.New(), 4000.0)
+	transfer := createTestListedTransfer(investment, 1000.0, 950.0)
+
+	mockTransfers.On("LockInvestmentTransfer", (*sql.Tx)(nil), transfer.ID).Return(transfer, nil)
+
+	result, err := service.ProcessBuyTransfer(transfer.ID, &models.BuyTransferRequest{InvestorID: investment.InvestorID})
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	mockRepo.AssertNotCalled(t, "LockInvestment", mock.Anything, mock.Anything)
+}
+
+func TestTransferService_ProcessListInvestment_RequiresDisbursedLoan(t *testing.T) {
+	service, mockTransfers, mockRepo, _ := setupTestTransferService()
+
+	loanID := uuid.New()
+	investment := createTestInvestment(loanID, 4000.0)
+
+	mockRepo.On("LockInvestment", (*sql.Tx)(nil), investment.ID).Return(investment, nil)
+	mockRepo.On("GetLoanByID", (*sql.Tx)(nil), loanID).Return(createTestLoan(loanID, models.LoanStateInvested, 10000.0), nil)
+
+	result, err := service.ProcessListInvestment(investment.ID, &models.CreateTransferListingRequest{
+		InvestorID: investment.InvestorID,
+		Amount:     1000.0,
+		Price:      950.0,
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	assert.Contains(t, err.Error(), "while the loan is disbursed")
+	mockTransfers.AssertNotCalled(t, "CreateInvestmentTransfer", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,325 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"loan-service/internal/models"
+	"loan-service/internal/repositories"
+	"loan-service/pkg/adapters"
+	"loan-service/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// waitlistClaimLease is how long a run serving a loan's queue may take over one entry before it counts as interrupted
+const waitlistClaimLease = 5 * time.Minute
+
+type WaitlistService struct {
+	waitlistRepo    repositories.WaitlistRepositoryInterface
+	loanRepo        repositories.LoanRepositoryInterface
+	reservationRepo repositories.ReservationRepositoryInterface
+	investments     InvestmentProcessor
+	emailAdapter    adapters.EmailAdapterInterface
+	notifications   NotificationServiceInterface
+	logger          logger.LoggerInterface
+}
+
+// NewWaitlistService creates the waitlist service; a nil reservation store means nothing is reserved
+func NewWaitlistService(
+	waitlistRepo repositories.WaitlistRepositoryInterface,
+	loanRepo repositories.LoanRepositoryInterface,
+	reservationRepo repositories.ReservationRepositoryInterface,
+	investments InvestmentProcessor,
+	emailAdapter adapters.EmailAdapterInterface,
+	notifications NotificationServiceInterface,
+	logger logger.LoggerInterface,
+) WaitlistServiceInterface {
+	return &WaitlistService{
+		waitlistRepo:    waitlistRepo,
+		loanRepo:        loanRepo,
+		reservationRepo: reservationRepo,
+		investments:     investments,
+		emailAdapter:    emailAdapter,
+		notifications:   notifications,
+		logger:          logger,
+	}
+}
+
+// JoinWaitlist queues the investor on a loan that has no room left for the requested amount
+func (s *WaitlistService) JoinWaitlist(loanID uuid.UUID, req *models.JoinWaitlistRequest) (*models.WaitlistEntryResponse, error) {
+	s.logger.Info("Joining loan waitlist", map[string]interface{}{"loan_id": loanID, "request": req})
+
+	investor, err := s.loanRepo.GetInvestorByID(req.InvestorID)
+	if err != nil {
+		s.logger.Error("Failed to get investor by ID", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": req.InvestorID.String(),
+		})
+		return nil, err
+	}
+
+	if !investor.IsActive {
+		return nil, fmt.Errorf("investor %s is not active", investor.InvestorCode)
+	}
+
+	loan, err := s.loanRepo.GetLoanByID(nil, loanID)
+	if err != nil {
+		s.logger.Error("Failed to get loan by ID", map[string]interface{}{
+			"error":   err.Error(),
+			"loan_id": loanID.String(),
+		})
+		return nil, err
+	}
+
+	if loan.State != models.LoanStateApproved {
+		return nil, fmt.Errorf("loan must be in approved state to join its waitlist, current state: %s", loan.State)
+	}
+
+	available, err := s.availableAmount(loan)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Amount <= available {
+		return nil, fmt.Errorf("loan still has %.2f available, invest directly instead", available)
+	}
+
+	entry, err := s.waitlistRepo.CreateWaitlistEntry(&models.WaitlistEntry{
+		LoanID:     loanID,
+		InvestorID: req.InvestorID,
+		Amount:     req.Amount,
+		Status:     models.WaitlistStatusWaiting,
+	})
+	if err != nil {
+		s.logger.Error("Failed to create waitlist entry", map[string]interface{}{
+			"error":       err.Error(),
+			"loan_id":     loanID.String(),
+			"investor_id": req.InvestorID.String(),
+		})
+		return nil, err
+	}
+
+	entries, err := s.waitlistRepo.GetWaitingEntries(loanID)
+	if err != nil {
+		return nil, err
+	}
+
+	position := len(entries)
+	for i, waiting := range entries {
+		if waiting.ID == entry.ID {
+			position = i + 1
+			break
+		}
+	}
+
+	return &models.WaitlistEntryResponse{
+		ID:         entry.ID,
+		LoanID:     entry.LoanID,
+		InvestorID: entry.InvestorID,
+		Amount:     entry.Amount,
+		Status:     entry.Status,
+		Position:   position,
+		CreatedAt:  entry.CreatedAt,
+	}, nil
+}
+
+// OnLoanChanged serves the loan's waitlist; a cancelled investment frees capacity right away
+func (s *WaitlistService) OnLoanChanged(loanID uuid.UUID) {
+	if _, err := s.ProcessWaitlist(loanID); err != nil {
+		s.logger.Error("Waitlist processing failed", map[string]interface{}{
+			"error":   err.Error(),
+			"loan_id": loanID.String(),
+		})
+	}
+}
+
+// ProcessWaitlists serves every loan with waiting investors, picking up capacity freed by expired reservations
+func (s *WaitlistService) ProcessWaitlists() error {
+	loanIDs, err := s.waitlistRepo.GetLoansWithWaitingEntries()
+	if err != nil {
+		return fmt.Errorf("failed to get loans with waiting entries: %w", err)
+	}
+
+	for _, loanID := range loanIDs {
+		if _, err := s.ProcessWaitlist(loanID); err != nil {
+			s.logger.Error("Waitlist processing failed", map[string]interface{}{
+				"error":   err.Error(),
+				"loan_id": loanID.String(),
+			})
+		}
+	}
+
+	return nil
+}
+
+// ProcessWaitlist invests the loan's free capacity on behalf of waiting investors in queue order.
+// The head of the queue gets up to its requested amount; when the free capacity is too small for an
+// investment to be accepted the queue stops and keeps its order until more capacity is freed.
+//
+// Each entry is claimed in the database before it is served, so while one run serves the queue another, whether
+// in this process or another one, finds its head taken and leaves it. That includes the run started by the change
+// notification of an investment made here.
+func (s *WaitlistService) ProcessWaitlist(loanID uuid.UUID) ([]*models.WaitlistEntry, error) {
+	var allocated []*models.WaitlistEntry
+	for {
+		now := time.Now()
+		entry, err := s.waitlistRepo.ClaimNextWaitingEntry(loanID, now, now.Add(waitlistClaimLease))
+		if err != nil {
+			return allocated, fmt.Errorf("failed to claim waitlist entry: %w", err)
+		}
+		if entry == nil {
+			return allocated, nil
+		}
+
+		loan, err := s.loanRepo.GetLoanByID(nil, loanID)
+		if err != nil {
+			s.releaseEntry(entry)
+			return allocated, fmt.Errorf("failed to get loan: %w", err)
+		}
+
+		// Once the loan stops raising funds no capacity can be freed any more
+		if loan.State != models.LoanStateApproved {
+			s.releaseEntry(entry)
+			closed, err := s.waitlistRepo.CloseWaitingEntries(loanID)
+			if err != nil {
+				return allocated, fmt.Errorf("failed to close waitlist: %w", err)
+			}
+			s.logger.Info("Closed loan waitlist", map[string]interface{}{
+				"loan_id": loanID.String(),
+				"state":   loan.State.String(),
+				"closed":  closed,
+			})
+			return allocated, nil
+		}
+
+		served, err := s.serveEntry(entry, loan)
+		if err != nil || !served {
+			s.releaseEntry(entry)
+			return allocated, err
+		}
+
+		if entry.Status == models.WaitlistStatusAllocated {
+			allocated = append(allocated, entry)
+		}
+	}
+}
+
+// serveEntry invests the loan's free capacity for a claimed entry and records the outcome. It reports false,
+// leaving the entry as it was, when there is too little capacity for it yet.
+func (s *WaitlistService) serveEntry(entry *models.WaitlistEntry, loan *models.Loan) (bool, error) {
+	available, err := s.availableAmount(loan)
+	if err != nil {
+		return false, err
+	}
+	if available <= 0 {
+		return false, nil
+	}
+
+	amount := math.Min(entry.Amount, available)
+	investment, err := s.investments.ProcessInvestment(loan.ID, &models.CreateInvestmentRequest{
+		LoanID:         loan.ID,
+		InvestorID:     entry.InvestorID,
+		Amount:         amount,
+		InvestmentDate: time.Now(),
+	})
+
+	now := time.Now()
+	if err != nil {
+		var limitErr *models.InvestmentLimitError
+		if errors.As(err, &limitErr) && (limitErr.Code == models.CodeInvestmentOversubscribed || limitErr.Code == models.CodeInvestmentBelowMinTicket) {
+			// Not enough capacity for this entry yet; keep the queue as it is
+			return false, nil
+		}
+
+		entry.Status = models.WaitlistStatusFailed
+		entry.FailureReason = err.Error()
+		entry.ProcessedAt = &now
+		s.finishEntry(entry, loan)
+		return true, nil
+	}
+
+	entry.Status = models.WaitlistStatusAllocated
+	entry.AllocatedAmount = &amount
+	entry.InvestmentID = &investment.ID
+	entry.ProcessedAt = &now
+	s.finishEntry(entry, loan)
+	return true, nil
+}
+
+// releaseEntry puts an entry that was not served back in its place in the queue
+func (s *WaitlistService) releaseEntry(entry *models.WaitlistEntry) {
+	if err := s.waitlistRepo.ReleaseWaitlistEntry(entry.ID); err != nil {
+		s.logger.Error("Failed to release waitlist entry", map[string]interface{}{
+			"error":    err.Error(),
+			"entry_id": entry.ID.String(),
+		})
+		return
+	}
+	entry.Status = models.WaitlistStatusWaiting
+	entry.ClaimedUntil = nil
+}
+
+// finishEntry records the outcome of a served entry and emails the investor about it
+func (s *WaitlistService) finishEntry(entry *models.WaitlistEntry, loan *models.Loan) {
+	if err := s.waitlistRepo.UpdateWaitlistEntry(entry); err != nil {
+		s.logger.Error("Failed to update waitlist entry", map[string]interface{}{
+			"error":    err.Error(),
+			"entry_id": entry.ID.String(),
+		})
+	}
+
+	investor, err := s.loanRepo.GetInvestorByID(entry.InvestorID)
+	if err != nil {
+		s.logger.Error("Failed to get investor for waitlist email", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": entry.InvestorID.String(),
+		})
+		return
+	}
+
+	message, err := s.emailAdapter.GenerateWaitlistEmail(entry, loan, investor)
+	if err != nil {
+		s.logger.Error("Failed to render waitlist email", map[string]interface{}{
+			"error":    err.Error(),
+			"entry_id": entry.ID.String(),
+		})
+		return
+	}
+
+	notification := &models.EmailNotification{
+		InvestorID:   entry.InvestorID,
+		LoanID:       loan.ID,
+		EmailType:    "waitlist_" + string(entry.Status),
+		Recipient:    investor.Email,
+		EmailSubject: message.Subject,
+		EmailBody:    message.TextBody,
+		EmailHTML:    message.HTMLBody,
+		Locale:       message.Locale,
+	}
+
+	// Attempts that fail are retried by the email retry job
+	if err := s.notifications.SendEmail(notification); err != nil {
+		s.logger.Error("Failed to send waitlist email", map[string]interface{}{
+			"error":    err.Error(),
+			"entry_id": entry.ID.String(),
+		})
+	}
+}
+
+// availableAmount is the loan's remaining principal less what active reservations hold
+func (s *WaitlistService) availableAmount(loan *models.Loan) (float64, error) {
+	remaining := loan.RemainingInvestmentAmount()
+	if s.reservationRepo == nil {
+		return remaining, nil
+	}
+
+	reserved, err := s.reservationRepo.GetReservedAmount(loan.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get reserved amount: %w", err)
+	}
+
+	return remaining - reserved, nil
+}
@@ -0,0 +1,278 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"loan-service/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockWaitlistRepository struct {
+	mock.Mock
+}
+
+func (m *MockWaitlistRepository) CreateWaitlistEntry(entry *models.WaitlistEntry) (*models.WaitlistEntry, error) {
+	args := m.Called(entry)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WaitlistEntry), args.Error(1)
+}
+
+func (m *MockWaitlistRepository) GetWaitingEntries(loanID uuid.UUID) ([]*models.WaitlistEntry, error) {
+	args := m.Called(loanID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.WaitlistEntry), args.Error(1)
+}
+
+func (m *MockWaitlistRepository) ClaimNextWaitingEntry(loanID uuid.UUID, now, leaseUntil time.Time) (*models.WaitlistEntry, error) {
+	args := m.Called(loanID, now, leaseUntil)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WaitlistEntry), args.Error(1)
+}
+
+func (m *MockWaitlistRepository) ReleaseWaitlistEntry(entryID uuid.UUID) error {
+	args := m.Called(entryID)
+	return args.Error(0)
+}
+
+func (m *MockWaitlistRepository) GetLoansWithWaitingEntries() ([]uuid.UUID, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+func (m *MockWaitlistRepository) UpdateWaitlistEntry(entry *models.WaitlistEntry) error {
+	args := m.Called(entry)
+	return args.Error(0)
+}
+
+func (m *MockWaitlistRepository) CloseWaitingEntries(loanID uuid.UUID) (int64, error) {
+	args := m.Called(loanID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func setupTestWaitlistService() (*WaitlistService, *MockWaitlistRepository, *MockLoanRepository, *MockInvestmentProcessor, *waitlistEmailMocks) {
+	mockWaitlist := &MockWaitlistRepository{}
+	mockRepo := &MockLoanRepository{}
+	mockInvestments := &MockInvestmentProcessor{}
+	mockEmails := &waitlistEmailMocks{adapter: &MockEmailAdapter{}, notifications: &MockNotificationService{}}
+
+	service := NewWaitlistService(mockWaitlist, mockRepo, nil, mockInvestments, mockEmails.adapter, mockEmails.notifications, &TestLogger{}).(*WaitlistService)
+
+	return service, mockWaitlist, mockRepo, mockInvestments, mockEmails
+}
+
+// waitlistEmailMocks render waitlist emails and send them
+type waitlistEmailMocks struct {
+	adapter       *MockEmailAdapter
+	notifications *MockNotificationService
+}
+
+func createTestWaitlistEntry(loanID uuid.UUID, amount float64) *models.WaitlistEntry {
+	return &models.WaitlistEntry{
+		BaseModel:  models.BaseModel{ID: uuid.New()},
+		LoanID:     loanID,
+		InvestorID: uuid.New(),
+		Amount:     amount,
+		Status:     models.WaitlistStatusWaiting,
+	}
+}
+
+// expectClaims hands out the entries one claim at a time in queue order, then finds the queue empty
+func expectClaims(mockWaitlist *MockWaitlistRepository, loanID uuid.UUID, entries ...*models.WaitlistEntry) {
+	for _, entry := range entries {
+		entry := entry
+		mockWaitlist.On("ClaimNextWaitingEntry", loanID, mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time")).Return(entry, nil).Run(func(args mock.Arguments) {
+			claimedUntil := args.Get(2).(time.Time)
+			entry.Status = models.WaitlistStatusProcessing
+			entry.ClaimedUntil = &claimedUntil
+		}).Once()
+	}
+	mockWaitlist.On("ClaimNextWaitingEntry", loanID, mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time")).Return(nil, nil)
+}
+
+func expectWaitlistEmail(mockRepo *MockLoanRepository, mockEmails *waitlistEmailMocks, entry *models.WaitlistEntry, emailType string) {
+	mockRepo.On("GetInvestorByID", entry.InvestorID).Return(&models.Investor{BaseModel: models.BaseModel{ID: entry.InvestorID}, Email: "investor@example.com", IsActive: true}, nil)
+	message := &models.EmailMessage{Locale: "id-ID", Subject: "subject", TextBody: "body", HTMLBody: "<p>body</p>"}
+	mockEmails.adapter.On("GenerateWaitlistEmail", entry, mock.Anything, mock.Anything).Return(message, nil)
+	mockEmails.notifications.On("SendEmail", mock.MatchedBy(func(n *models.EmailNotification) bool {
+		return n.InvestorID == entry.InvestorID && n.EmailType == emailType && n.Recipient == "investor@example.com" &&
+			n.EmailBody == "body" && n.EmailHTML == "<p>body</p>"
+	})).Return(nil)
+}
+
+func TestWaitlistService_ProcessWaitlist_AllocatesInQueueOrder(t *testing.T) {
+	service, mockWaitlist, mockRepo, mockInvestments, mockEmails := setupTestWaitlistService()
+
+	loanID := uuid.New()
+	first := createTestWaitlistEntry(loanID, 2000.0)
+	second := createTestWaitlistEntry(loanID, 3000.0)
+	third := createTestWaitlistEntry(loanID, 1000.0)
+
+	expectClaims(mockWaitlist, loanID, first, second, third)
+	// 3000 freed, then taken up by the first two entries
+	mockRepo.On("GetLoanByID", (*sql.Tx)(nil), loanID).Return(createTestLoan(loanID, models.LoanStateApproved, 7000.0), nil).Once()
+	mockRepo.On("GetLoanByID", (*sql.Tx)(nil), loanID).Return(createTestLoan(loanID, models.LoanStateApproved, 9000.0), nil).Once()
+	mockRepo.On("GetLoanByID", (*sql.Tx)(nil), loanID).Return(createTestLoan(loanID, models.LoanStateApproved, 10000.0), nil).Once()
+
+	mockInvestments.On("ProcessInvestment", loanID, mock.MatchedBy(func(req *models.CreateInvestmentRequest) bool {
+		return req.InvestorID == first.InvestorID && req.Amount == 2000.0
+	})).Return(&models.InvestmentResponse{ID: uuid.New(), Amount: 2000.0}, nil)
+	mockInvestments.On("ProcessInvestment", loanID, mock.MatchedBy(func(req *models.CreateInvestmentRequest) bool {
+		return req.InvestorID == second.InvestorID && req.Amount == 1000.0
+	})).Return(&models.InvestmentResponse{ID: uuid.New(), Amount: 1000.0}, nil)
+
+	mockWaitlist.On("UpdateWaitlistEntry", mock.Anything).Return(nil)
+	mockWaitlist.On("ReleaseWaitlistEntry", third.ID).Return(nil)
+	expectWaitlistEmail(mockRepo, mockEmails, first, "waitlist_allocated")
+	expectWaitlistEmail(mockRepo, mockEmails, second, "waitlist_allocated")
+
+	allocated, err := service.ProcessWaitlist(loanID)
+
+	assert.NoError(t, err)
+	assert.Len(t, allocated, 2)
+	assert.Equal(t, 2000.0, *first.AllocatedAmount)
+	assert.Equal(t, 1000.0, *second.AllocatedAmount)
+	assert.Equal(t, models.WaitlistStatusWaiting, third.Status)
+	mockInvestments.AssertNumberOfCalls(t, "ProcessInvestment", 2)
+	mockEmails.notifications.AssertNumberOfCalls(t, "SendEmail", 2)
+	mockWaitlist.AssertCalled(t, "ReleaseWaitlistEntry", third.ID)
+}
+
+func TestWaitlistService_ProcessWaitlist_StopsBelowMinTicket(t *testing.T) {
+	service, mockWaitlist, mockRepo, mockInvestments, _ := setupTestWaitlistService()
+
+	loanID := uuid.New()
+	first := createTestWaitlistEntry(loanID, 2000.0)
+	second := createTestWaitlistEntry(loanID, 50.0)
+
+	expectClaims(mockWaitlist, loanID, first, second)
+	mockRepo.On("GetLoanByID", (*sql.Tx)(nil), loanID).Return(createTestLoan(loanID, models.LoanStateApproved, 9900.0), nil) // 100 freed
+	mockInvestments.On("ProcessInvestment", loanID, mock.Anything).Return(nil, &models.InvestmentLimitError{
+		Code:    models.CodeInvestmentBelowMinTicket,
+		Message: "investment amount 100.00 is below the minimum ticket 500.00",
+	})
+	mockWaitlist.On("ReleaseWaitlistEntry", first.ID).Return(nil)
+
+	allocated, err := service.ProcessWaitlist(loanID)
+
+	assert.NoError(t, err)
+	assert.Empty(t, allocated)
+	assert.Equal(t, models.WaitlistStatusWaiting, first.Status)
+	mockInvestments.AssertNumberOfCalls(t, "ProcessInvestment", 1)
+	mockWaitlist.AssertNotCalled(t, "UpdateWaitlistEntry", mock.Anything)
+	mockWaitlist.AssertNumberOfCalls(t, "ClaimNextWaitingEntry", 1)
+}
+
+func TestWaitlistService_ProcessWaitlist_FailedEntrySkipped(t *testing.T) {
+	service, mockWaitlist, mockRepo, mockInvestments, mockEmails := setupTestWaitlistService()
+
+	loanID := uuid.New()
+	first := createTestWaitlistEntry(loanID, 1000.0)
+	second := createTestWaitlistEntry(loanID, 1000.0)
+
+	expectClaims(mockWaitlist, loanID, first, second)
+	mockRepo.On("GetLoanByID", (*sql.Tx)(nil), loanID).Return(createTestLoan(loanID, models.LoanStateApproved, 9000.0), nil)
+	mockInvestments.On("ProcessInvestment", loanID, mock.MatchedBy(func(req *models.CreateInvestmentRequest) bool {
+		return req.InvestorID == first.InvestorID
+	})).Return(nil, errors.New("insufficient wallet balance"))
+	mockInvestments.On("ProcessInvestment", loanID, mock.MatchedBy(func(req *models.CreateInvestmentRequest) bool {
+		return req.InvestorID == second.InvestorID
+	})).Return(&models.InvestmentResponse{ID: uuid.New(), Amount: 1000.0}, nil)
+
+	mockWaitlist.On("UpdateWaitlistEntry", mock.Anything).Return(nil)
+	expectWaitlistEmail(mockRepo, mockEmails, first, "waitlist_failed")
+	expectWaitlistEmail(mockRepo, mockEmails, second, "waitlist_allocated")
+
+	allocated, err := service.ProcessWaitlist(loanID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*models.WaitlistEntry{second}, allocated)
+	assert.Equal(t, models.WaitlistStatusFailed, first.Status)
+	assert.Equal(t, "insufficient wallet balance", first.FailureReason)
+	mockRepo.AssertExpectations(t)
+	mockWaitlist.AssertNotCalled(t, "ReleaseWaitlistEntry", mock.Anything)
+}
+
+func TestWaitlistService_ProcessWaitlist_ClosesWhenNotApproved(t *testing.T) {
+	service, mockWaitlist, mockRepo, mockInvestments, _ := setupTestWaitlistService()
+
+	loanID := uuid.New()
+	entry := createTestWaitlistEntry(loanID, 1000.0)
+	expectClaims(mockWaitlist, loanID, entry)
+	mockRepo.On("GetLoanByID", (*sql.Tx)(nil), loanID).Return(createTestLoan(loanID, models.LoanStateExpired, 6000.0), nil)
+	mockWaitlist.On("ReleaseWaitlistEntry", entry.ID).Return(nil)
+	mockWaitlist.On("CloseWaitingEntries", loanID).Return(int64(1), nil)
+
+	allocated, err := service.ProcessWaitlist(loanID)
+
+	assert.NoError(t, err)
+	assert.Empty(t, allocated)
+	mockWaitlist.AssertExpectations(t)
+	mockInvestments.AssertNotCalled(t, "ProcessInvestment", mock.Anything, mock.Anything)
+}
+
+func TestWaitlistService_ProcessWaitlist_HeadAlreadyClaimed(t *testing.T) {
+	service, mockWaitlist, mockRepo, mockInvestments, _ := setupTestWaitlistService()
+
+	loanID := uuid.New()
+	// Another run, e.g. in another instance, is serving the head of the queue
+	expectClaims(mockWaitlist, loanID)
+
+	allocated, err := service.ProcessWaitlist(loanID)
+
+	assert.NoError(t, err)
+	assert.Empty(t, allocated)
+	mockRepo.AssertNotCalled(t, "GetLoanByID", mock.Anything, mock.Anything)
+	mockInvestments.AssertNotCalled(t, "ProcessInvestment", mock.Anything, mock.Anything)
+}
+
+func TestWaitlistService_ProcessWaitlist_ClaimLease(t *testing.T) {
+	service, mockWaitlist, mockRepo, mockInvestments, _ := setupTestWaitlistService()
+
+	loanID := uuid.New()
+	entry := createTestWaitlistEntry(loanID, 1000.0)
+	expectClaims(mockWaitlist, loanID, entry)
+	mockRepo.On("GetLoanByID", (*sql.Tx)(nil), loanID).Return(nil, errors.New("connection refused"))
+	mockWaitlist.On("ReleaseWaitlistEntry", entry.ID).Return(nil)
+
+	_, err := service.ProcessWaitlist(loanID)
+
+	assert.ErrorContains(t, err, "connection refused")
+	mockWaitlist.AssertCalled(t, "ClaimNextWaitingEntry", loanID, mock.MatchedBy(func(now time.Time) bool {
+		return time.Since(now) < time.Minute
+	}), mock.MatchedBy(func(leaseUntil time.Time) bool {
+		return time.Until(leaseUntil) > 4*time.Minute && time.Until(leaseUntil) <= waitlistClaimLease
+	}))
+	mockWaitlist.AssertCalled(t, "ReleaseWaitlistEntry", entry.ID)
+	mockInvestments.AssertNotCalled(t, "ProcessInvestment", mock.Anything, mock.Anything)
+}
+
+func TestWaitlistService_JoinWaitlist_RejectedWithCapacity(t *testing.T) {
+	service, mockWaitlist, mockRepo, _, _ := setupTestWaitlistService()
+
+	loanID := uuid.New()
+	investorID := uuid.New()
+	mockRepo.On("GetInvestorByID", investorID).Return(&models.Investor{BaseModel: models.BaseModel{ID: investorID}, IsActive: true}, nil)
+	mockRepo.On("GetLoanByID", (*sql.Tx)(nil), loanID).Return(createTestLoan(loanID, models.LoanStateApproved, 5000.0), nil)
+
+	entry, err := service.JoinWaitlist(loanID, &models.JoinWaitlistRequest{InvestorID: investorID, Amount: 3000.0})
+
+	assert.Error(t, err)
+	assert.Nil(t, entry)
+	assert.Contains(t, err.Error(), "invest directly")
+	mockWaitlist.AssertNotCalled(t, "CreateWaitlistEntry", mock.Anything)
+}
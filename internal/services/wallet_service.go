@@ -0,0 +1,254 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"loan-service/internal/models"
+	"loan-service/internal/repositories"
+	"loan-service/pkg/adapters"
+	"loan-service/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// topUpResumeDelay keeps the resume job away from top ups that are still being credited by their request
+	topUpResumeDelay = 5 * time.Minute
+	// topUpResumeBatchSize caps the charged top ups one run of the resume job credits
+	topUpResumeBatchSize = 50
+)
+
+type WalletService struct {
+	walletRepo     repositories.WalletRepositoryInterface
+	loanRepo       repositories.LoanRepositoryInterface
+	paymentAdapter adapters.PaymentAdapterInterface
+	logger         logger.LoggerInterface
+	db             *sql.DB
+}
+
+func NewWalletService(
+	walletRepo repositories.WalletRepositoryInterface,
+	loanRepo repositories.LoanRepositoryInterface,
+	paymentAdapter adapters.PaymentAdapterInterface,
+	logger logger.LoggerInterface,
+	db *sql.DB,
+) WalletServiceInterface {
+	return &WalletService{
+		walletRepo:     walletRepo,
+		loanRepo:       loanRepo,
+		paymentAdapter: paymentAdapter,
+		logger:         logger,
+		db:             db,
+	}
+}
+
+func (s *WalletService) withTransaction(fn func(*sql.Tx) error) error {
+	return runInTransaction(s.db, s.logger, fn)
+}
+
+// GetWallet returns the investor balance derived from the ledger together with the ledger entries
+func (s *WalletService) GetWallet(investorID uuid.UUID) (*models.WalletResponse, error) {
+	s.logger.Info("Getting investor wallet", map[string]interface{}{"investor_id": investorID})
+
+	if _, err := s.loanRepo.GetInvestorByID(investorID); err != nil {
+		s.logger.Error("Failed to get investor by ID", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": investorID.String(),
+		})
+		return nil, err
+	}
+
+	balance, err := s.walletRepo.GetWalletBalance(nil, investorID)
+	if err != nil {
+		s.logger.Error("Failed to get wallet balance", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": investorID.String(),
+		})
+		return nil, err
+	}
+
+	transactions, err := s.walletRepo.GetWalletTransactions(investorID)
+	if err != nil {
+		s.logger.Error("Failed to get wallet transactions", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": investorID.String(),
+		})
+		return nil, err
+	}
+
+	return &models.WalletResponse{
+		WalletBalance: *balance,
+		Currency:      models.DefaultWalletCurrency,
+		Transactions:  transactions,
+	}, nil
+}
+
+// TopUp collects funds through the payment provider and credits them to the investor wallet. The top up is recorded
+// before the charge and credited after it, so the payment provider is never called while the wallet is locked.
+func (s *WalletService) TopUp(investorID uuid.UUID, req *models.WalletTopUpRequest) (*models.WalletTransaction, error) {
+	s.logger.Info("Processing wallet top up", map[string]interface{}{"investor_id": investorID, "amount": req.Amount})
+
+	investor, err := s.loanRepo.GetInvestorByID(investorID)
+	if err != nil {
+		s.logger.Error("Failed to get investor by ID", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": investorID.String(),
+		})
+		return nil, err
+	}
+
+	if !investor.IsActive {
+		return nil, fmt.Errorf("investor %s is not active", investor.InvestorCode)
+	}
+
+	topUp, err := s.walletRepo.CreateWalletTopUp(nil, &models.WalletTopUp{
+		InvestorID: investorID,
+		Amount:     req.Amount,
+		Status:     models.WalletTopUpStatusPending,
+	})
+	if err != nil {
+		s.logger.Error("Failed to create wallet top up", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": investorID.String(),
+		})
+		return nil, err
+	}
+
+	if err := s.chargeTopUp(topUp, req.PaymentToken); err != nil {
+		return nil, err
+	}
+
+	result, err := s.creditTopUp(topUp)
+	if err != nil {
+		return nil, fmt.Errorf("top up %s was charged and will be credited to the wallet shortly", topUp.ID)
+	}
+
+	s.logger.Info("Wallet topped up", map[string]interface{}{
+		"investor_id":    investorID.String(),
+		"top_up_id":      topUp.ID.String(),
+		"transaction_id": result.ID.String(),
+		"amount":         result.Amount,
+	})
+
+	return result, nil
+}
+
+// ResumeChargedTopUps credits top ups whose charge went through but whose ledger entry was never written
+func (s *WalletService) ResumeChargedTopUps() error {
+	topUps, err := s.walletRepo.GetWalletTopUpsByStatus(models.WalletTopUpStatusCharged, time.Now().Add(-topUpResumeDelay), topUpResumeBatchSize)
+	if err != nil {
+		s.logger.Error("Failed to get charged wallet top ups", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return err
+	}
+
+	credited := 0
+	for _, topUp := range topUps {
+		if _, err := s.creditTopUp(topUp); err == nil {
+			credited++
+		}
+	}
+
+	if len(topUps) > 0 {
+		s.logger.Info("Resumed charged wallet top ups", map[string]interface{}{
+			"charged":  len(topUps),
+			"credited": credited,
+		})
+	}
+	return nil
+}
+
+// chargeTopUp charges the investor with the top up ID as the idempotency key and records the outcome
+func (s *WalletService) chargeTopUp(topUp *models.WalletTopUp, paymentToken string) error {
+	paymentResult, payErr := s.paymentAdapter.ProcessPayment(topUp.Amount, paymentToken, topUp.ID.String())
+	if payErr != nil {
+		topUp.Status = models.WalletTopUpStatusFailed
+		topUp.FailureReason = payErr.Error()
+	} else {
+		topUp.Status = models.WalletTopUpStatusCharged
+		topUp.TransactionID = paymentResult.TransactionID
+	}
+
+	if err := s.walletRepo.UpdateWalletTopUp(nil, topUp); err != nil {
+		// The credit below still goes ahead, it only needs the charge result kept on topUp
+		s.logger.Error("Failed to record wallet top up charge", map[string]interface{}{
+			"error":          err.Error(),
+			"top_up_id":      topUp.ID.String(),
+			"status":         topUp.Status,
+			"transaction_id": topUp.TransactionID,
+		})
+	}
+
+	if payErr != nil {
+		s.logger.Error("Wallet top up payment failed", map[string]interface{}{
+			"error":     payErr.Error(),
+			"top_up_id": topUp.ID.String(),
+		})
+		return fmt.Errorf("payment processing failed: %w", payErr)
+	}
+	return nil
+}
+
+// creditTopUp writes the ledger entry of a charged top up in a short transaction
+func (s *WalletService) creditTopUp(topUp *models.WalletTopUp) (*models.WalletTransaction, error) {
+	charged := *topUp
+	var result *models.WalletTransaction
+	err := s.withTransaction(func(tx *sql.Tx) error {
+		var creditErr error
+		result, creditErr = s.creditTopUpTx(tx, topUp)
+		return creditErr
+	})
+	if err != nil {
+		s.logger.Error("Failed to credit wallet top up, left charged", map[string]interface{}{
+			"error":          err.Error(),
+			"top_up_id":      topUp.ID.String(),
+			"transaction_id": topUp.TransactionID,
+		})
+		*topUp = charged
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *WalletService) creditTopUpTx(tx *sql.Tx, topUp *models.WalletTopUp) (*models.WalletTransaction, error) {
+	locked, err := s.walletRepo.LockWalletTopUp(tx, topUp.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pending only if recording the charge failed, the charge result is then only known to this caller
+	if locked.Status != models.WalletTopUpStatusCharged && locked.Status != models.WalletTopUpStatusPending {
+		return nil, fmt.Errorf("top up %s is %s, it cannot be credited", topUp.ID, locked.Status)
+	}
+
+	wallet, err := s.walletRepo.LockWallet(tx, topUp.InvestorID)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction, err := s.walletRepo.CreateWalletTransaction(tx, &models.WalletTransaction{
+		WalletID:        wallet.ID,
+		InvestorID:      topUp.InvestorID,
+		TransactionType: models.WalletTransactionTopUp,
+		Amount:          topUp.Amount,
+		ReferenceType:   models.WalletReferenceTopUp,
+		ReferenceID:     &topUp.ID,
+		Description:     fmt.Sprintf("Top up via payment %s", topUp.TransactionID),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	topUp.Status = models.WalletTopUpStatusCompleted
+	topUp.CompletedAt = &now
+	if err := s.walletRepo.UpdateWalletTopUp(tx, topUp); err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
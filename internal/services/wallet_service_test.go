@@ -0,0 +1,601 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"loan-service/internal/models"
+	"loan-service/pkg/adapters"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// ledgerWalletRepository keeps the wallet ledger in memory and settles holds the way the database does, so the
+// entries the services append can be checked through the balances derived from them
+type ledgerWalletRepository struct {
+	wallets         map[uuid.UUID]*models.Wallet
+	entries         []*models.WalletTransaction
+	investmentLoans map[uuid.UUID]uuid.UUID // loan of each investment a hold was placed for
+	topUps          map[uuid.UUID]models.WalletTopUp
+	creditErr       error // returned instead of appending top up entries when set
+}
+
+func newLedgerWalletRepository() *ledgerWalletRepository {
+	return &ledgerWalletRepository{
+		wallets:         make(map[uuid.UUID]*models.Wallet),
+		investmentLoans: make(map[uuid.UUID]uuid.UUID),
+		topUps:          make(map[uuid.UUID]models.WalletTopUp),
+	}
+}
+
+func (l *ledgerWalletRepository) LockWallet(tx *sql.Tx, investorID uuid.UUID) (*models.Wallet, error) {
+	wallet, ok := l.wallets[investorID]
+	if !ok {
+		wallet = &models.Wallet{
+			BaseModel:  models.BaseModel{ID: uuid.New()},
+			InvestorID: investorID,
+			Currency:   models.DefaultWalletCurrency,
+		}
+		l.wallets[investorID] = wallet
+	}
+	return wallet, nil
+}
+
+func (l *ledgerWalletRepository) GetWalletBalance(tx *sql.Tx, investorID uuid.UUID) (*models.WalletBalance, error) {
+	balance := &models.WalletBalance{InvestorID: investorID}
+	for _, entry := range l.entries {
+		if entry.InvestorID == investorID {
+			balance.Apply(entry.TransactionType, entry.Amount)
+		}
+	}
+	return balance, nil
+}
+
+func (l *ledgerWalletRepository) CreateWalletTransaction(tx *sql.Tx, transaction *models.WalletTransaction) (*models.WalletTransaction, error) {
+	if l.creditErr != nil && transaction.TransactionType == models.WalletTransactionTopUp {
+		return nil, l.creditErr
+	}
+	if transaction.ID == uuid.Nil {
+		transaction.ID = uuid.New()
+	}
+	transaction.CreatedAt = time.Now()
+	l.entries = append(l.entries, transaction)
+	return transaction, nil
+}
+
+func (l *ledgerWalletRepository) GetWalletTransactions(investorID uuid.UUID) ([]*models.WalletTransaction, error) {
+	var transactions []*models.WalletTransaction
+	for i := len(l.entries) - 1; i >= 0; i-- {
+		if l.entries[i].InvestorID == investorID {
+			transactions = append(transactions, l.entries[i])
+		}
+	}
+	return transactions, nil
+}
+
+func (l *ledgerWalletRepository) ReleaseHold(tx *sql.Tx, holdID uuid.UUID, description string) error {
+	settled := l.settleHolds(models.WalletTransactionRelease, "", nil, description, func(hold *models.WalletTransaction) bool {
+		return hold.ID == holdID
+	})
+	if settled == 0 {
+		return fmt.Errorf("hold %s not found or already settled", holdID)
+	}
+	return nil
+}
+
+func (l *ledgerWalletRepository) ReleaseInvestmentHold(tx *sql.Tx, investmentID uuid.UUID, description string) (int64, error) {
+	return l.settleHolds(models.WalletTransactionRelease, models.WalletReferenceInvestment, &investmentID, description, func(hold *models.WalletTransaction) bool {
+		return hold.ReferenceType == models.WalletReferenceInvestment && *hold.ReferenceID == investmentID
+	}), nil
+}
+
+func (l *ledgerWalletRepository) CaptureLoanHolds(tx *sql.Tx, loanID uuid.UUID, description string) (int64, error) {
+	return l.settleHolds(models.WalletTransactionCapture, models.WalletReferenceLoan, &loanID, description, l.heldForLoan(loanID)), nil
+}
+
+func (l *ledgerWalletRepository) ReleaseLoanHolds(tx *sql.Tx, loanID uuid.UUID, description string) (int64, error) {
+	return l.settleHolds(models.WalletTransactionRelease, models.WalletReferenceLoan, &loanID, description, l.heldForLoan(loanID)), nil
+}
+
+func (l *ledgerWalletRepository) heldForLoan(loanID uuid.UUID) func(*models.WalletTransaction) bool {
+	return func(hold *models.WalletTransaction) bool {
+		return hold.ReferenceType == models.WalletReferenceInvestment && l.investmentLoans[*hold.ReferenceID] == loanID
+	}
+}
+
+// settleHolds appends a release or capture for each matching hold that has not been settled yet
+func (l *ledgerWalletRepository) settleHolds(transactionType models.WalletTransactionType, referenceType string, referenceID *uuid.UUID, description string, match func(*models.WalletTransaction) bool) int64 {
+	settled := make(map[uuid.UUID]bool)
+	for _, entry := range l.entries {
+		if entry.HoldID != nil {
+			settled[*entry.HoldID] = true
+		}
+	}
+
+	var count int64
+	for _, hold := range l.entries {
+		if hold.TransactionType != models.WalletTransactionHold || settled[hold.ID] || !match(hold) {
+			continue
+		}
+		settlement := &models.WalletTransaction{
+			BaseModel:       models.BaseModel{ID: uuid.New(), CreatedAt: time.Now()},
+			WalletID:        hold.WalletID,
+			InvestorID:      hold.InvestorID,
+			TransactionType: transactionType,
+			Amount:          hold.Amount,
+			ReferenceType:   hold.ReferenceType,
+			ReferenceID:     hold.ReferenceID,
+			HoldID:          &hold.ID,
+			Description:     description,
+		}
+		if referenceID != nil {
+			settlement.ReferenceType = referenceType
+			settlement.ReferenceID = referenceID
+		}
+		l.entries = append(l.entries, settlement)
+		count++
+	}
+	return count
+}
+
+func (l *ledgerWalletRepository) CreateWalletTopUp(tx *sql.Tx, topUp *models.WalletTopUp) (*models.WalletTopUp, error) {
+	if topUp.ID == uuid.Nil {
+		topUp.ID = uuid.New()
+	}
+	topUp.CreatedAt = time.Now()
+	l.topUps[topUp.ID] = *topUp
+	return topUp, nil
+}
+
+func (l *ledgerWalletRepository) LockWalletTopUp(tx *sql.Tx, topUpID uuid.UUID) (*models.WalletTopUp, error) {
+	topUp, ok := l.topUps[topUpID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &topUp, nil
+}
+
+func (l *ledgerWalletRepository) UpdateWalletTopUp(tx *sql.Tx, topUp *models.WalletTopUp) error {
+	topUp.UpdatedAt = time.Now()
+	l.topUps[topUp.ID] = *topUp
+	return nil
+}
+
+func (l *ledgerWalletRepository) GetWalletTopUpsByStatus(status models.WalletTopUpStatus, updatedBefore time.Time, limit int) ([]*models.WalletTopUp, error) {
+	var topUps []*models.WalletTopUp
+	for _, topUp := range l.topUps {
+		if topUp.Status == status && topUp.UpdatedAt.Before(updatedBefore) && len(topUps) < limit {
+			topUp := topUp
+			topUps = append(topUps, &topUp)
+		}
+	}
+	return topUps, nil
+}
+
+// topUp credits the investor's wallet as a completed top up would
+func (l *ledgerWalletRepository) topUp(investorID uuid.UUID, amount float64) {
+	wallet, _ := l.LockWallet(nil, investorID)
+	topUpID := uuid.New()
+	l.CreateWalletTransaction(nil, &models.WalletTransaction{
+		WalletID:        wallet.ID,
+		InvestorID:      investorID,
+		TransactionType: models.WalletTransactionTopUp,
+		Amount:          amount,
+		ReferenceType:   models.WalletReferenceTopUp,
+		ReferenceID:     &topUpID,
+	})
+}
+
+// hold reserves funds for a new investment in the loan as an investment would, returning the hold
+func (l *ledgerWalletRepository) hold(investorID, loanID uuid.UUID, amount float64) *models.WalletTransaction {
+	wallet, _ := l.LockWallet(nil, investorID)
+	investmentID := uuid.New()
+	l.investmentLoans[investmentID] = loanID
+	hold, _ := l.CreateWalletTransaction(nil, &models.WalletTransaction{
+		WalletID:        wallet.ID,
+		InvestorID:      investorID,
+		TransactionType: models.WalletTransactionHold,
+		Amount:          amount,
+		ReferenceType:   models.WalletReferenceInvestment,
+		ReferenceID:     &investmentID,
+	})
+	return hold
+}
+
+// assertBalance checks the balance, held amount and available balance derived for the investor
+func (l *ledgerWalletRepository) assertBalance(t *testing.T, investorID uuid.UUID, balance, held, available float64) {
+	t.Helper()
+	derived, _ := l.GetWalletBalance(nil, investorID)
+	assert.Equal(t, balance, derived.Balance, "balance")
+	assert.Equal(t, held, derived.HeldAmount, "held amount")
+	assert.Equal(t, available, derived.AvailableBalance, "available balance")
+}
+
+// TestWalletService is a test-specific version that overrides withTransaction
+type TestWalletService struct {
+	*WalletService
+}
+
+func (s *TestWalletService) withTransaction(fn func(*sql.Tx) error) error {
+	return fn(nil)
+}
+
+func (s *TestWalletService) TopUp(investorID uuid.UUID, req *models.WalletTopUpRequest) (*models.WalletTransaction, error) {
+	investor, err := s.loanRepo.GetInvestorByID(investorID)
+	if err != nil {
+		return nil, err
+	}
+	if !investor.IsActive {
+		return nil, fmt.Errorf("investor %s is not active", investor.InvestorCode)
+	}
+
+	topUp, err := s.walletRepo.CreateWalletTopUp(nil, &models.WalletTopUp{
+		InvestorID: investorID,
+		Amount:     req.Amount,
+		Status:     models.WalletTopUpStatusPending,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.chargeTopUp(topUp, req.PaymentToken); err != nil {
+		return nil, err
+	}
+
+	result, err := s.creditTopUp(topUp)
+	if err != nil {
+		return nil, fmt.Errorf("top up %s was charged and will be credited to the wallet shortly", topUp.ID)
+	}
+
+	return result, nil
+}
+
+func (s *TestWalletService) ResumeChargedTopUps() error {
+	topUps, err := s.walletRepo.GetWalletTopUpsByStatus(models.WalletTopUpStatusCharged, time.Now().Add(-topUpResumeDelay), topUpResumeBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, topUp := range topUps {
+		s.creditTopUp(topUp)
+	}
+	return nil
+}
+
+func (s *TestWalletService) creditTopUp(topUp *models.WalletTopUp) (*models.WalletTransaction, error) {
+	charged := *topUp
+	var result *models.WalletTransaction
+	err := s.withTransaction(func(tx *sql.Tx) error {
+		var creditErr error
+		result, creditErr = s.creditTopUpTx(tx, topUp)
+		return creditErr
+	})
+	if err != nil {
+		*topUp = charged
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func setupTestWalletService() (*TestWalletService, *ledgerWalletRepository, *MockLoanRepository, *MockPaymentAdapter) {
+	ledger := newLedgerWalletRepository()
+	mockRepo := &MockLoanRepository{}
+	mockPayment := &MockPaymentAdapter{}
+
+	baseService := NewWalletService(ledger, mockRepo, mockPayment, &TestLogger{}, nil).(*WalletService)
+
+	return &TestWalletService{WalletService: baseService}, ledger, mockRepo, mockPayment
+}
+
+func TestWalletService_TopUp_Success(t *testing.T) {
+	service, ledger, mockRepo, mockPayment := setupTestWalletService()
+
+	investorID := uuid.New()
+	mockRepo.On("GetInvestorByID", investorID).Return(&models.Investor{BaseModel: models.BaseModel{ID: investorID}, IsActive: true}, nil)
+	mockPayment.On("ProcessPayment", 2500.0, "tok_visa", mock.AnythingOfType("string")).Return(&adapters.PaymentResult{TransactionID: "txn_456", Status: "success"}, nil)
+
+	result, err := service.TopUp(investorID, &models.WalletTopUpRequest{Amount: 2500.0, PaymentToken: "tok_visa"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.WalletTransactionTopUp, result.TransactionType)
+	assert.Equal(t, models.WalletReferenceTopUp, result.ReferenceType)
+	assert.Equal(t, ledger.wallets[investorID].ID, result.WalletID)
+	assert.Contains(t, result.Description, "txn_456")
+	ledger.assertBalance(t, investorID, 2500.0, 0, 2500.0)
+
+	// The top up ID is the idempotency key of the charge
+	topUp := ledger.topUps[*result.ReferenceID]
+	assert.Equal(t, models.WalletTopUpStatusCompleted, topUp.Status)
+	assert.Equal(t, "txn_456", topUp.TransactionID)
+	assert.NotNil(t, topUp.CompletedAt)
+	mockPayment.AssertCalled(t, "ProcessPayment", 2500.0, "tok_visa", topUp.ID.String())
+}
+
+func TestWalletService_TopUp_PaymentFails(t *testing.T) {
+	service, ledger, mockRepo, mockPayment := setupTestWalletService()
+
+	investorID := uuid.New()
+	mockRepo.On("GetInvestorByID", investorID).Return(&models.Investor{BaseModel: models.BaseModel{ID: investorID}, IsActive: true}, nil)
+	mockPayment.On("ProcessPayment", 2500.0, "tok_declined", mock.AnythingOfType("string")).Return(nil, errors.New("card declined"))
+
+	result, err := service.TopUp(investorID, &models.WalletTopUpRequest{Amount: 2500.0, PaymentToken: "tok_declined"})
+
+	assert.ErrorContains(t, err, "payment processing failed")
+	assert.Nil(t, result)
+	assert.Empty(t, ledger.entries)
+	assert.Len(t, ledger.topUps, 1)
+	for _, topUp := range ledger.topUps {
+		assert.Equal(t, models.WalletTopUpStatusFailed, topUp.Status)
+		assert.Equal(t, "card declined", topUp.FailureReason)
+	}
+}
+
+func TestWalletService_TopUp_CreditFailsAfterCharge(t *testing.T) {
+	service, ledger, mockRepo, mockPayment := setupTestWalletService()
+
+	investorID := uuid.New()
+	mockRepo.On("GetInvestorByID", investorID).Return(&models.Investor{BaseModel: models.BaseModel{ID: investorID}, IsActive: true}, nil)
+	mockPayment.On("ProcessPayment", 2500.0, "tok_visa", mock.AnythingOfType("string")).Return(&adapters.PaymentResult{TransactionID: "txn_789", Status: "success"}, nil)
+	ledger.creditErr = errors.New("database error")
+
+	result, err := service.TopUp(investorID, &models.WalletTopUpRequest{Amount: 2500.0, PaymentToken: "tok_visa"})
+
+	// The investor was charged, so the top up is left charged for the resume job instead of failing
+	assert.ErrorContains(t, err, "will be credited")
+	assert.Nil(t, result)
+	assert.Empty(t, ledger.entries)
+	assert.Len(t, ledger.topUps, 1)
+	var topUpID uuid.UUID
+	for id, topUp := range ledger.topUps {
+		topUpID = id
+		assert.Equal(t, models.WalletTopUpStatusCharged, topUp.Status)
+		assert.Equal(t, "txn_789", topUp.TransactionID)
+	}
+
+	// Once the database recovers the resume job credits it exactly once, without charging again
+	ledger.creditErr = nil
+	charged := ledger.topUps[topUpID]
+	charged.UpdatedAt = time.Now().Add(-topUpResumeDelay - time.Minute)
+	ledger.topUps[topUpID] = charged
+
+	assert.NoError(t, service.ResumeChargedTopUps())
+	assert.NoError(t, service.ResumeChargedTopUps())
+
+	assert.Equal(t, models.WalletTopUpStatusCompleted, ledger.topUps[topUpID].Status)
+	assert.Len(t, ledger.entries, 1)
+	assert.Equal(t, topUpID, *ledger.entries[0].ReferenceID)
+	ledger.assertBalance(t, investorID, 2500.0, 0, 2500.0)
+	mockPayment.AssertNumberOfCalls(t, "ProcessPayment", 1)
+}
+
+func TestWalletService_CreditTopUp_AlreadyCompleted(t *testing.T) {
+	service, ledger, _, _ := setupTestWalletService()
+
+	topUp, _ := ledger.CreateWalletTopUp(nil, &models.WalletTopUp{
+		InvestorID:    uuid.New(),
+		Amount:        2500.0,
+		Status:        models.WalletTopUpStatusCompleted,
+		TransactionID: "txn_456",
+	})
+
+	result, err := service.creditTopUp(topUp)
+
+	assert.ErrorContains(t, err, "cannot be credited")
+	assert.Nil(t, result)
+	assert.Empty(t, ledger.entries)
+}
+
+func TestWalletService_TopUp_InactiveInvestor(t *testing.T) {
+	service, ledger, mockRepo, mockPayment := setupTestWalletService()
+
+	investorID := uuid.New()
+	mockRepo.On("GetInvestorByID", investorID).Return(&models.Investor{InvestorCode: "INV-009", IsActive: false}, nil)
+
+	result, err := service.TopUp(investorID, &models.WalletTopUpRequest{Amount: 2500.0, PaymentToken: "tok_visa"})
+
+	assert.ErrorContains(t, err, "not active")
+	assert.Nil(t, result)
+	assert.Empty(t, ledger.entries)
+	mockPayment.AssertNotCalled(t, "ProcessPayment", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWalletService_GetWallet_DerivesBalanceFromLedger(t *testing.T) {
+	service, ledger, mockRepo, _ := setupTestWalletService()
+
+	investorID := uuid.New()
+	loanID := uuid.New()
+	mockRepo.On("GetInvestorByID", investorID).Return(&models.Investor{BaseModel: models.BaseModel{ID: investorID}, IsActive: true}, nil)
+	wallet, _ := ledger.LockWallet(nil, investorID)
+	entry := func(transactionType models.WalletTransactionType, amount float64) {
+		ledger.CreateWalletTransaction(nil, &models.WalletTransaction{
+			WalletID:        wallet.ID,
+			InvestorID:      investorID,
+			TransactionType: transactionType,
+			Amount:          amount,
+		})
+	}
+
+	steps := []struct {
+		name                     string
+		apply                    func()
+		balance, held, available float64
+	}{
+		{"top up", func() { ledger.topUp(investorID, 5000.0) }, 5000.0, 0, 5000.0},
+		{"hold for an investment", func() { ledger.hold(investorID, loanID, 3000.0) }, 5000.0, 3000.0, 2000.0},
+		{"hold for a cancelled investment", func() {
+			hold := ledger.hold(investorID, uuid.New(), 1000.0)
+			assert.NoError(t, ledger.ReleaseHold(nil, hold.ID, "Investment cancelled"))
+		}, 5000.0, 3000.0, 2000.0},
+		{"capture on disbursement", func() { ledger.CaptureLoanHolds(nil, loanID, "Loan disbursed") }, 2000.0, 0, 2000.0},
+		{"sale proceeds", func() { entry(models.WalletTransactionTransferIn, 500.0) }, 2500.0, 0, 2500.0},
+		{"purchase price", func() { entry(models.WalletTransactionTransferOut, 700.0) }, 1800.0, 0, 1800.0},
+		{"withdrawal hold", func() { entry(models.WalletTransactionHold, 800.0) }, 1800.0, 800.0, 1000.0},
+		{"withdrawal paid out", func() {
+			entry(models.WalletTransactionRelease, 800.0)
+			entry(models.WalletTransactionPayout, 800.0)
+		}, 1000.0, 0, 1000.0},
+	}
+
+	for _, step := range steps {
+		step.apply()
+
+		result, err := service.GetWallet(investorID)
+
+		assert.NoError(t, err, step.name)
+		assert.Equal(t, step.balance, result.Balance, step.name)
+		assert.Equal(t, step.held, result.HeldAmount, step.name)
+		assert.Equal(t, step.available, result.AvailableBalance, step.name)
+	}
+
+	result, _ := service.GetWallet(investorID)
+	assert.Len(t, result.Transactions, len(ledger.entries))
+	assert.Equal(t, models.WalletTransactionPayout, result.Transactions[0].TransactionType, "newest entry first")
+}
+
+func TestWalletLedger_DisbursementCapturesHolds(t *testing.T) {
+	service, mockRepo, _, mockPayment, _ := setupTestLoanService()
+	ledger := newLedgerWalletRepository()
+	service.walletRepo = ledger
+
+	loanID := uuid.New()
+	firstInvestor, secondInvestor := uuid.New(), uuid.New()
+	ledger.topUp(firstInvestor, 5000.0)
+	ledger.topUp(secondInvestor, 8000.0)
+	ledger.hold(firstInvestor, loanID, 4000.0)
+	ledger.hold(secondInvestor, loanID, 6000.0)
+	ledger.hold(secondInvestor, uuid.New(), 1000.0) // still raising funds elsewhere
+
+	loan := createTestLoan(loanID, models.LoanStateInvested, 10000.0)
+	loan.AgreementLetterURL = "https://example.com/agreement.pdf"
+	expectSignedAgreement(service, loanID)
+	mockRepo.On("GetLoanByID", mock.AnythingOfType("*sql.Tx"), loanID).Return(loan, nil)
+	mockRepo.On("CreateDisbursement", mock.AnythingOfType("*sql.Tx"), mock.Anything).Return(&models.Disbursement{BaseModel: models.BaseModel{ID: uuid.New()}, LoanID: loanID}, nil)
+	mockRepo.On("UpdateLoanState", mock.AnythingOfType("*sql.Tx"), loanID, models.LoanStateDisbursed).Return(createTestLoan(loanID, models.LoanStateDisbursed, 10000.0), nil)
+	mockRepo.On("RecordLoanStateHistory", mock.AnythingOfType("*sql.Tx"), models.LoanStateInvested, mock.AnythingOfType("*models.Loan"), mock.AnythingOfType("uuid.UUID"), "Loan disbursed").Return(&models.LoanStateHistory{}, nil)
+	mockPayment.On("ProcessPayment", 10000.0, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(&adapters.PaymentResult{TransactionID: "txn_123"}, nil)
+	mockRepo.On("UpdateDisbursementTransactionID", mock.AnythingOfType("*sql.Tx"), mock.AnythingOfType("uuid.UUID"), "txn_123").Return(nil)
+
+	_, err := service.ProcessDisbursement(loanID, &models.CreateDisbursementRequest{
+		FieldOfficerID:   uuid.New(),
+		DisbursementDate: time.Now(),
+		DisbursedAmount:  10000.0,
+	})
+
+	assert.NoError(t, err)
+	// The invested funds leave the wallets; the hold for the other loan stays in place
+	ledger.assertBalance(t, firstInvestor, 1000.0, 0, 1000.0)
+	ledger.assertBalance(t, secondInvestor, 2000.0, 1000.0, 1000.0)
+}
+
+func TestWalletLedger_CancelReleasesHolds(t *testing.T) {
+	service, mockRepo, _, _, _ := setupTestLoanService()
+	ledger := newLedgerWalletRepository()
+	service.walletRepo = ledger
+
+	loanID := uuid.New()
+	investorID := uuid.New()
+	ledger.topUp(investorID, 5000.0)
+	ledger.hold(investorID, loanID, 3000.0)
+	ledger.assertBalance(t, investorID, 5000.0, 3000.0, 2000.0)
+
+	cancelledLoan := createTestLoan(loanID, models.LoanStateCancelled, 3000.0)
+	mockRepo.On("LockLoan", mock.AnythingOfType("*sql.Tx"), loanID).Return(nil)
+	mockRepo.On("GetLoanByID", mock.AnythingOfType("*sql.Tx"), loanID).Return(createTestLoan(loanID, models.LoanStateApproved, 3000.0), nil)
+	mockRepo.On("UpdateLoanState", mock.AnythingOfType("*sql.Tx"), loanID, models.LoanStateCancelled).Return(cancelledLoan, nil)
+	mockRepo.On("RecordLoanStateHistory", mock.AnythingOfType("*sql.Tx"), models.LoanStateApproved, cancelledLoan, mock.AnythingOfType("uuid.UUID"), mock.AnythingOfType("string")).Return(&models.LoanStateHistory{}, nil)
+
+	_, err := service.ProcessCancelLoan(loanID, &models.CancelLoanRequest{ChangedBy: uuid.New(), ChangeReason: "Borrower withdrew application"})
+
+	assert.NoError(t, err)
+	ledger.assertBalance(t, investorID, 5000.0, 0, 5000.0)
+}
+
+func TestWalletLedger_ExpiryReleasesHolds(t *testing.T) {
+	service, mockRepo, _, _, _ := setupTestLoanService()
+	ledger := newLedgerWalletRepository()
+	service.walletRepo = ledger
+
+	loanID := uuid.New()
+	investorID := uuid.New()
+	ledger.topUp(investorID, 5000.0)
+	ledger.hold(investorID, loanID, 2000.0)
+	ledger.hold(investorID, loanID, 1500.0)
+
+	expiredLoan := createTestLoan(loanID, models.LoanStateExpired, 3500.0)
+	mockRepo.On("LockLoan", mock.AnythingOfType("*sql.Tx"), loanID).Return(nil)
+	mockRepo.On("GetLoanByID", mock.AnythingOfType("*sql.Tx"), loanID).Return(createTestLoan(loanID, models.LoanStateApproved, 3500.0), nil)
+	mockRepo.On("UpdateLoanState", mock.AnythingOfType("*sql.Tx"), loanID, models.LoanStateExpired).Return(expiredLoan, nil)
+	mockRepo.On("RecordLoanStateHistory", mock.AnythingOfType("*sql.Tx"), models.LoanStateApproved, expiredLoan, mock.AnythingOfType("uuid.UUID"), "Funding period ended").Return(&models.LoanStateHistory{}, nil)
+
+	err := service.ProcessExpireLoan(loanID)
+
+	assert.NoError(t, err)
+	ledger.assertBalance(t, investorID, 5000.0, 0, 5000.0)
+
+	// A hold is settled once, so running the expiry again releases nothing more
+	released, _ := ledger.ReleaseLoanHolds(nil, loanID, "Funding period ended")
+	assert.Zero(t, released)
+	ledger.assertBalance(t, investorID, 5000.0, 0, 5000.0)
+}
+
+func TestWalletLedger_TransferMovesPrice(t *testing.T) {
+	service, mockTransfers, mockRepo, _ := setupTestTransferService()
+	ledger := newLedgerWalletRepository()
+	service.walletRepo = ledger
+
+	loanID := uuid.New()
+	buyerID := uuid.New()
+	investment := createTestInvestment(loanID, 4000.0)
+	transfer := createTestListedTransfer(investment, 1000.0, 950.0)
+	ledger.topUp(buyerID, 2000.0)
+	ledger.topUp(investment.InvestorID, 4000.0)
+	ledger.hold(investment.InvestorID, loanID, 4000.0)
+	ledger.CaptureLoanHolds(nil, loanID, "Loan disbursed")
+
+	mockTransfers.On("LockInvestmentTransfer", (*sql.Tx)(nil), transfer.ID).Return(transfer, nil)
+	mockRepo.On("LockInvestment", (*sql.Tx)(nil), investment.ID).Return(investment, nil)
+	mockRepo.On("GetLoanByID", (*sql.Tx)(nil), loanID).Return(createTestLoan(loanID, models.LoanStateDisbursed, 10000.0), nil)
+	expectNoInvestorLimits(mockRepo, buyerID)
+	mockRepo.On("CreateInvestment", (*sql.Tx)(nil), mock.Anything).Return(&models.Investment{BaseModel: models.BaseModel{ID: uuid.New()}}, nil)
+	mockRepo.On("UpdateInvestmentAmount", (*sql.Tx)(nil), investment.ID, 3000.0, mock.AnythingOfType("float64")).Return(nil)
+	mockTransfers.On("UpdateInvestmentTransfer", (*sql.Tx)(nil), transfer).Return(nil)
+	mockTransfers.On("CancelUncoveredInvestmentTransfers", (*sql.Tx)(nil), investment.ID, 3000.0).Return(int64(0), nil)
+	expectTransferAgreement(service, mockRepo, transfer, buyerID)
+
+	_, err := service.ProcessBuyTransfer(transfer.ID, &models.BuyTransferRequest{InvestorID: buyerID})
+
+	assert.NoError(t, err)
+	ledger.assertBalance(t, buyerID, 1050.0, 0, 1050.0)
+	ledger.assertBalance(t, investment.InvestorID, 950.0, 0, 950.0)
+}
+
+func TestWalletLedger_TransferCannotSpendHeldFunds(t *testing.T) {
+	service, mockTransfers, mockRepo, _ := setupTestTransferService()
+	ledger := newLedgerWalletRepository()
+	service.walletRepo = ledger
+
+	loanID := uuid.New()
+	buyerID := uuid.New()
+	investment := createTestInvestment(loanID, 4000.0)
+	transfer := createTestListedTransfer(investment, 1000.0, 950.0)
+	// The buyer has enough in the wallet, but most of it is reserved for an investment in another loan
+	ledger.topUp(buyerID, 2000.0)
+	ledger.hold(buyerID, uuid.New(), 1500.0)
+
+	mockTransfers.On("LockInvestmentTransfer", (*sql.Tx)(nil), transfer.ID).Return(transfer, nil)
+	mockRepo.On("LockInvestment", (*sql.Tx)(nil), investment.ID).Return(investment, nil)
+	mockRepo.On("GetLoanByID", (*sql.Tx)(nil), loanID).Return(createTestLoan(loanID, models.LoanStateDisbursed, 10000.0), nil)
+	expectNoInvestorLimits(mockRepo, buyerID)
+
+	_, err := service.ProcessBuyTransfer(transfer.ID, &models.BuyTransferRequest{InvestorID: buyerID})
+
+	assert.ErrorContains(t, err, "insufficient wallet balance: available 500.00")
+	ledger.assertBalance(t, buyerID, 2000.0, 1500.0, 500.0)
+	ledger.assertBalance(t, investment.InvestorID, 0, 0, 0)
+}
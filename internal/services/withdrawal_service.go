@@ -0,0 +1,526 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"loan-service/internal/models"
+	"loan-service/internal/repositories"
+	"loan-service/pkg/adapters"
+	"loan-service/pkg/config"
+	"loan-service/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// withdrawalResumeDelay keeps the resume job away from payouts that are still being settled by their request
+	withdrawalResumeDelay = 5 * time.Minute
+	// withdrawalResumeBatchSize caps the processing withdrawals one run of the resume job settles
+	withdrawalResumeBatchSize = 50
+)
+
+type WithdrawalService struct {
+	withdrawalRepo repositories.WithdrawalRepositoryInterface
+	walletRepo     repositories.WalletRepositoryInterface
+	loanRepo       repositories.LoanRepositoryInterface
+	paymentAdapter adapters.PaymentAdapterInterface
+	config         config.WalletConfig
+	logger         logger.LoggerInterface
+	db             *sql.DB
+}
+
+func NewWithdrawalService(
+	withdrawalRepo repositories.WithdrawalRepositoryInterface,
+	walletRepo repositories.WalletRepositoryInterface,
+	loanRepo repositories.LoanRepositoryInterface,
+	paymentAdapter adapters.PaymentAdapterInterface,
+	cfg config.WalletConfig,
+	logger logger.LoggerInterface,
+	db *sql.DB,
+) WithdrawalServiceInterface {
+	return &WithdrawalService{
+		withdrawalRepo: withdrawalRepo,
+		walletRepo:     walletRepo,
+		loanRepo:       loanRepo,
+		paymentAdapter: paymentAdapter,
+		config:         cfg,
+		logger:         logger,
+		db:             db,
+	}
+}
+
+func (s *WithdrawalService) withTransaction(fn func(*sql.Tx) error) error {
+	return runInTransaction(s.db, s.logger, fn)
+}
+
+// RegisterBankAccount registers a bank account the investor can withdraw to
+func (s *WithdrawalService) RegisterBankAccount(investorID uuid.UUID, req *models.CreateBankAccountRequest) (*models.BankAccountResponse, error) {
+	s.logger.Info("Registering investor bank account", map[string]interface{}{"investor_id": investorID, "bank_code": req.BankCode})
+
+	if _, err := s.getActiveInvestor(investorID); err != nil {
+		return nil, err
+	}
+
+	account, err := s.withdrawalRepo.CreateBankAccount(nil, &models.BankAccount{
+		InvestorID:        investorID,
+		BankCode:          req.BankCode,
+		AccountNumber:     req.AccountNumber,
+		AccountHolderName: req.AccountHolderName,
+		IsActive:          true,
+	})
+	if err != nil {
+		s.logger.Error("Failed to create bank account", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": investorID.String(),
+		})
+		return nil, err
+	}
+
+	return toBankAccountResponse(account), nil
+}
+
+// GetBankAccounts returns the bank accounts registered by the investor
+func (s *WithdrawalService) GetBankAccounts(investorID uuid.UUID) ([]*models.BankAccountResponse, error) {
+	accounts, err := s.withdrawalRepo.GetBankAccountsByInvestorID(investorID)
+	if err != nil {
+		s.logger.Error("Failed to get bank accounts", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": investorID.String(),
+		})
+		return nil, err
+	}
+
+	responses := make([]*models.BankAccountResponse, 0, len(accounts))
+	for _, account := range accounts {
+		responses = append(responses, toBankAccountResponse(account))
+	}
+
+	return responses, nil
+}
+
+// GetWithdrawals returns the withdrawals of the investor, newest first
+func (s *WithdrawalService) GetWithdrawals(investorID uuid.UUID) ([]*models.WithdrawalResponse, error) {
+	withdrawals, err := s.withdrawalRepo.GetWithdrawalsByInvestorID(investorID)
+	if err != nil {
+		s.logger.Error("Failed to get withdrawals", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": investorID.String(),
+		})
+		return nil, err
+	}
+
+	responses := make([]*models.WithdrawalResponse, 0, len(withdrawals))
+	for _, withdrawal := range withdrawals {
+		responses = append(responses, toWithdrawalResponse(withdrawal))
+	}
+
+	return responses, nil
+}
+
+// RequestWithdrawal holds the requested amount in the wallet and pays it out straight away,
+// unless the amount is above the approval threshold in which case it waits for an employee
+func (s *WithdrawalService) RequestWithdrawal(investorID uuid.UUID, req *models.CreateWithdrawalRequest) (*models.WithdrawalResponse, error) {
+	s.logger.Info("Processing withdrawal request", map[string]interface{}{"investor_id": investorID, "amount": req.Amount})
+
+	if _, err := s.getActiveInvestor(investorID); err != nil {
+		return nil, err
+	}
+
+	account, err := s.withdrawalRepo.GetBankAccountByID(req.BankAccountID)
+	if err != nil {
+		s.logger.Error("Failed to get bank account by ID", map[string]interface{}{
+			"error":           err.Error(),
+			"bank_account_id": req.BankAccountID.String(),
+		})
+		return nil, err
+	}
+
+	if account.InvestorID != investorID || !account.IsActive {
+		return nil, fmt.Errorf("bank account %s is not an active account of investor %s", account.ID, investorID)
+	}
+
+	var withdrawal *models.Withdrawal
+	err = s.withTransaction(func(tx *sql.Tx) error {
+		var requestErr error
+		withdrawal, requestErr = s.requestWithdrawalTx(tx, investorID, account, req)
+		return requestErr
+	})
+	if err != nil {
+		s.logger.Error("Failed to request withdrawal", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": investorID.String(),
+		})
+		return nil, err
+	}
+
+	if withdrawal.Status == models.WithdrawalStatusProcessing {
+		s.payoutWithdrawal(withdrawal, account)
+	}
+
+	return toWithdrawalResponse(withdrawal), nil
+}
+
+// requestWithdrawalTx holds the amount and records the withdrawal, marked processing when it needs no review
+func (s *WithdrawalService) requestWithdrawalTx(tx *sql.Tx, investorID uuid.UUID, account *models.BankAccount, req *models.CreateWithdrawalRequest) (*models.Withdrawal, error) {
+	wallet, err := s.walletRepo.LockWallet(tx, investorID)
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := s.walletRepo.GetWalletBalance(tx, investorID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !balance.CanCover(req.Amount) {
+		return nil, fmt.Errorf("insufficient wallet balance: available %.2f, required %.2f", balance.AvailableBalance, req.Amount)
+	}
+
+	withdrawal := &models.Withdrawal{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		InvestorID:     investorID,
+		BankAccountID:  account.ID,
+		Amount:         req.Amount,
+		Status:         models.WithdrawalStatusProcessing,
+		RequiresReview: s.config.WithdrawalApprovalThreshold > 0 && req.Amount > s.config.WithdrawalApprovalThreshold,
+	}
+	if withdrawal.RequiresReview {
+		withdrawal.Status = models.WithdrawalStatusPendingApproval
+	}
+
+	hold, err := s.walletRepo.CreateWalletTransaction(tx, &models.WalletTransaction{
+		WalletID:        wallet.ID,
+		InvestorID:      investorID,
+		TransactionType: models.WalletTransactionHold,
+		Amount:          req.Amount,
+		ReferenceType:   models.WalletReferenceWithdrawal,
+		ReferenceID:     &withdrawal.ID,
+		Description:     fmt.Sprintf("Hold for withdrawal to %s %s", account.BankCode, account.MaskedAccountNumber()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	withdrawal.HoldID = hold.ID
+
+	if _, err := s.withdrawalRepo.CreateWithdrawal(tx, withdrawal); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Withdrawal requested", map[string]interface{}{
+		"withdrawal_id": withdrawal.ID.String(),
+		"investor_id":   investorID.String(),
+		"status":        withdrawal.Status,
+	})
+
+	return withdrawal, nil
+}
+
+// ApproveWithdrawal approves a withdrawal above the threshold and pays it out
+func (s *WithdrawalService) ApproveWithdrawal(withdrawalID uuid.UUID, req *models.ReviewWithdrawalRequest) (*models.WithdrawalResponse, error) {
+	s.logger.Info("Approving withdrawal", map[string]interface{}{"withdrawal_id": withdrawalID, "reviewed_by": req.ReviewedBy})
+
+	if err := s.checkReviewer(req.ReviewedBy); err != nil {
+		return nil, err
+	}
+
+	var withdrawal *models.Withdrawal
+	var account *models.BankAccount
+	err := s.withTransaction(func(tx *sql.Tx) error {
+		var approveErr error
+		withdrawal, account, approveErr = s.approveWithdrawalTx(tx, withdrawalID, req)
+		return approveErr
+	})
+	if err != nil {
+		s.logger.Error("Failed to approve withdrawal", map[string]interface{}{
+			"error":         err.Error(),
+			"withdrawal_id": withdrawalID.String(),
+		})
+		return nil, err
+	}
+
+	s.payoutWithdrawal(withdrawal, account)
+
+	return toWithdrawalResponse(withdrawal), nil
+}
+
+// approveWithdrawalTx marks the withdrawal processing so it cannot be reviewed again once the payout is sent
+func (s *WithdrawalService) approveWithdrawalTx(tx *sql.Tx, withdrawalID uuid.UUID, req *models.ReviewWithdrawalRequest) (*models.Withdrawal, *models.BankAccount, error) {
+	withdrawal, err := s.lockReviewableWithdrawal(tx, withdrawalID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	account, err := s.withdrawalRepo.GetBankAccountByID(withdrawal.BankAccountID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if account.InvestorID != withdrawal.InvestorID || !account.IsActive {
+		return nil, nil, fmt.Errorf("bank account %s is no longer active, reject the withdrawal to release the funds", account.ID)
+	}
+
+	now := time.Now()
+	withdrawal.Status = models.WithdrawalStatusProcessing
+	withdrawal.ReviewedBy = &req.ReviewedBy
+	withdrawal.ReviewedAt = &now
+
+	if err := s.withdrawalRepo.UpdateWithdrawal(tx, withdrawal); err != nil {
+		return nil, nil, err
+	}
+
+	return withdrawal, account, nil
+}
+
+// RejectWithdrawal rejects a withdrawal above the threshold and releases the held funds
+func (s *WithdrawalService) RejectWithdrawal(withdrawalID uuid.UUID, req *models.ReviewWithdrawalRequest) (*models.WithdrawalResponse, error) {
+	s.logger.Info("Rejecting withdrawal", map[string]interface{}{"withdrawal_id": withdrawalID, "reviewed_by": req.ReviewedBy})
+
+	if req.Reason == "" {
+		return nil, fmt.Errorf("a reason is required to reject a withdrawal")
+	}
+
+	if err := s.checkReviewer(req.ReviewedBy); err != nil {
+		return nil, err
+	}
+
+	var result *models.WithdrawalResponse
+	err := s.withTransaction(func(tx *sql.Tx) error {
+		var rejectErr error
+		result, rejectErr = s.rejectWithdrawalTx(tx, withdrawalID, req)
+		return rejectErr
+	})
+	if err != nil {
+		s.logger.Error("Failed to reject withdrawal", map[string]interface{}{
+			"error":         err.Error(),
+			"withdrawal_id": withdrawalID.String(),
+		})
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *WithdrawalService) rejectWithdrawalTx(tx *sql.Tx, withdrawalID uuid.UUID, req *models.ReviewWithdrawalRequest) (*models.WithdrawalResponse, error) {
+	withdrawal, err := s.lockReviewableWithdrawal(tx, withdrawalID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.walletRepo.ReleaseHold(tx, withdrawal.HoldID, "Withdrawal rejected"); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	withdrawal.Status = models.WithdrawalStatusRejected
+	withdrawal.ReviewedBy = &req.ReviewedBy
+	withdrawal.ReviewedAt = &now
+	withdrawal.RejectionReason = req.Reason
+
+	if err := s.withdrawalRepo.UpdateWithdrawal(tx, withdrawal); err != nil {
+		return nil, err
+	}
+
+	return toWithdrawalResponse(withdrawal), nil
+}
+
+// ResumeProcessingWithdrawals settles the withdrawals left processing, such as when the transaction after the payout
+// failed. The payout is sent again with the same idempotency key, so the provider reports the earlier outcome instead
+// of paying out twice.
+func (s *WithdrawalService) ResumeProcessingWithdrawals() error {
+	withdrawals, err := s.withdrawalRepo.GetWithdrawalsByStatus(models.WithdrawalStatusProcessing, time.Now().Add(-withdrawalResumeDelay), withdrawalResumeBatchSize)
+	if err != nil {
+		s.logger.Error("Failed to get processing withdrawals", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return err
+	}
+
+	settled := 0
+	for _, withdrawal := range withdrawals {
+		account, err := s.withdrawalRepo.GetBankAccountByID(withdrawal.BankAccountID)
+		if err != nil {
+			s.logger.Error("Failed to get bank account by ID", map[string]interface{}{
+				"error":         err.Error(),
+				"withdrawal_id": withdrawal.ID.String(),
+			})
+			continue
+		}
+
+		if s.payoutWithdrawal(withdrawal, account) {
+			settled++
+		}
+	}
+
+	if len(withdrawals) > 0 {
+		s.logger.Info("Resumed processing withdrawals", map[string]interface{}{
+			"processing": len(withdrawals),
+			"settled":    settled,
+		})
+	}
+	return nil
+}
+
+// payoutWithdrawal sends the payout of a withdrawal marked processing, outside any transaction, and then settles it.
+// A settlement that fails leaves the withdrawal processing for ResumeProcessingWithdrawals; it reports whether the
+// withdrawal was settled.
+func (s *WithdrawalService) payoutWithdrawal(withdrawal *models.Withdrawal, account *models.BankAccount) bool {
+	result, payoutErr := s.sendPayout(withdrawal, account)
+
+	processing := *withdrawal
+	err := s.withTransaction(func(tx *sql.Tx) error {
+		return s.settleWithdrawalTx(tx, withdrawal, account, result, payoutErr)
+	})
+	if err != nil {
+		s.logger.Error("Failed to settle withdrawal payout, left processing", map[string]interface{}{
+			"error":         err.Error(),
+			"withdrawal_id": withdrawal.ID.String(),
+		})
+		*withdrawal = processing
+		return false
+	}
+
+	return true
+}
+
+// sendPayout sends the funds to the bank account with the withdrawal ID as idempotency key
+func (s *WithdrawalService) sendPayout(withdrawal *models.Withdrawal, account *models.BankAccount) (*adapters.PaymentResult, error) {
+	return s.paymentAdapter.ProcessPayout(withdrawal.Amount, adapters.PayoutAccount{
+		BankCode:          account.BankCode,
+		AccountNumber:     account.AccountNumber,
+		AccountHolderName: account.AccountHolderName,
+	}, withdrawal.ID.String())
+}
+
+// settleWithdrawalTx settles the hold of a processing withdrawal with the payout outcome.
+// A failed payout is recorded on the withdrawal and the funds are released rather than failing the transaction.
+func (s *WithdrawalService) settleWithdrawalTx(tx *sql.Tx, withdrawal *models.Withdrawal, account *models.BankAccount, result *adapters.PaymentResult, payoutErr error) error {
+	locked, err := s.withdrawalRepo.LockWithdrawal(tx, withdrawal.ID)
+	if err != nil {
+		return err
+	}
+
+	// Another run settled it already
+	if locked.Status != models.WithdrawalStatusProcessing {
+		*withdrawal = *locked
+		return nil
+	}
+
+	wallet, err := s.walletRepo.LockWallet(tx, withdrawal.InvestorID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.walletRepo.ReleaseHold(tx, withdrawal.HoldID, "Withdrawal hold settled"); err != nil {
+		return err
+	}
+
+	if payoutErr != nil {
+		s.logger.Error("Withdrawal payout failed", map[string]interface{}{
+			"error":         payoutErr.Error(),
+			"withdrawal_id": withdrawal.ID.String(),
+		})
+		withdrawal.Status = models.WithdrawalStatusFailed
+		withdrawal.FailureReason = payoutErr.Error()
+		return s.withdrawalRepo.UpdateWithdrawal(tx, withdrawal)
+	}
+
+	_, err = s.walletRepo.CreateWalletTransaction(tx, &models.WalletTransaction{
+		WalletID:        wallet.ID,
+		InvestorID:      withdrawal.InvestorID,
+		TransactionType: models.WalletTransactionPayout,
+		Amount:          withdrawal.Amount,
+		ReferenceType:   models.WalletReferenceWithdrawal,
+		ReferenceID:     &withdrawal.ID,
+		Description:     fmt.Sprintf("Withdrawal to %s %s via payout %s", account.BankCode, account.MaskedAccountNumber(), result.TransactionID),
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	withdrawal.Status = models.WithdrawalStatusCompleted
+	withdrawal.TransactionID = result.TransactionID
+	withdrawal.CompletedAt = &now
+
+	return s.withdrawalRepo.UpdateWithdrawal(tx, withdrawal)
+}
+
+func (s *WithdrawalService) lockReviewableWithdrawal(tx *sql.Tx, withdrawalID uuid.UUID) (*models.Withdrawal, error) {
+	withdrawal, err := s.withdrawalRepo.LockWithdrawal(tx, withdrawalID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !withdrawal.CanBeReviewed() {
+		return nil, fmt.Errorf("withdrawal %s cannot be reviewed in status %s", withdrawal.ID, withdrawal.Status)
+	}
+
+	return withdrawal, nil
+}
+
+func (s *WithdrawalService) checkReviewer(employeeID uuid.UUID) error {
+	employee, err := s.loanRepo.GetEmployeeByID(employeeID)
+	if err != nil {
+		s.logger.Error("Failed to get employee by ID", map[string]interface{}{
+			"error":       err.Error(),
+			"employee_id": employeeID.String(),
+		})
+		return err
+	}
+
+	if !employee.IsActive {
+		return fmt.Errorf("employee %s is not active", employee.EmployeeID)
+	}
+
+	return nil
+}
+
+func (s *WithdrawalService) getActiveInvestor(investorID uuid.UUID) (*models.Investor, error) {
+	investor, err := s.loanRepo.GetInvestorByID(investorID)
+	if err != nil {
+		s.logger.Error("Failed to get investor by ID", map[string]interface{}{
+			"error":       err.Error(),
+			"investor_id": investorID.String(),
+		})
+		return nil, err
+	}
+
+	if !investor.IsActive {
+		return nil, fmt.Errorf("investor %s is not active", investor.InvestorCode)
+	}
+
+	return investor, nil
+}
+
+func toBankAccountResponse(account *models.BankAccount) *models.BankAccountResponse {
+	return &models.BankAccountResponse{
+		ID:                account.ID,
+		InvestorID:        account.InvestorID,
+		BankCode:          account.BankCode,
+		AccountNumber:     account.MaskedAccountNumber(),
+		AccountHolderName: account.AccountHolderName,
+		IsActive:          account.IsActive,
+		CreatedAt:         account.CreatedAt,
+	}
+}
+
+func toWithdrawalResponse(withdrawal *models.Withdrawal) *models.WithdrawalResponse {
+	return &models.WithdrawalResponse{
+		ID:              withdrawal.ID,
+		InvestorID:      withdrawal.InvestorID,
+		BankAccountID:   withdrawal.BankAccountID,
+		Amount:          withdrawal.Amount,
+		Status:          withdrawal.Status,
+		RequiresReview:  withdrawal.RequiresReview,
+		ReviewedBy:      withdrawal.ReviewedBy,
+		ReviewedAt:      withdrawal.ReviewedAt,
+		RejectionReason: withdrawal.RejectionReason,
+		TransactionID:   withdrawal.TransactionID,
+		FailureReason:   withdrawal.FailureReason,
+		CompletedAt:     withdrawal.CompletedAt,
+		CreatedAt:       withdrawal.CreatedAt,
+		UpdatedAt:       withdrawal.UpdatedAt,
+	}
+}
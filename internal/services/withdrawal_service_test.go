@@ -0,0 +1,409 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"loan-service/internal/models"
+	"loan-service/pkg/adapters"
+	"loan-service/pkg/config"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockWithdrawalRepository struct {
+	mock.Mock
+}
+
+func (m *MockWithdrawalRepository) CreateBankAccount(tx *sql.Tx, account *models.BankAccount) (*models.BankAccount, error) {
+	args := m.Called(tx, account)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BankAccount), args.Error(1)
+}
+
+func (m *MockWithdrawalRepository) GetBankAccountByID(bankAccountID uuid.UUID) (*models.BankAccount, error) {
+	args := m.Called(bankAccountID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BankAccount), args.Error(1)
+}
+
+func (m *MockWithdrawalRepository) GetBankAccountsByInvestorID(investorID uuid.UUID) ([]*models.BankAccount, error) {
+	args := m.Called(investorID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.BankAccount), args.Error(1)
+}
+
+func (m *MockWithdrawalRepository) CreateWithdrawal(tx *sql.Tx, withdrawal *models.Withdrawal) (*models.Withdrawal, error) {
+	args := m.Called(tx, withdrawal)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Withdrawal), args.Error(1)
+}
+
+func (m *MockWithdrawalRepository) LockWithdrawal(tx *sql.Tx, withdrawalID uuid.UUID) (*models.Withdrawal, error) {
+	args := m.Called(tx, withdrawalID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Withdrawal), args.Error(1)
+}
+
+func (m *MockWithdrawalRepository) UpdateWithdrawal(tx *sql.Tx, withdrawal *models.Withdrawal) error {
+	args := m.Called(tx, withdrawal)
+	return args.Error(0)
+}
+
+func (m *MockWithdrawalRepository) GetWithdrawalsByInvestorID(investorID uuid.UUID) ([]*models.Withdrawal, error) {
+	args := m.Called(investorID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Withdrawal), args.Error(1)
+}
+
+func (m *MockWithdrawalRepository) GetWithdrawalsByStatus(status models.WithdrawalStatus, updatedBefore time.Time, limit int) ([]*models.Withdrawal, error) {
+	args := m.Called(status, updatedBefore, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Withdrawal), args.Error(1)
+}
+
+// TestWithdrawalService is a test-specific version that overrides withTransaction
+type TestWithdrawalService struct {
+	*WithdrawalService
+}
+
+// Override withTransaction to bypass actual transactions in tests
+func (s *TestWithdrawalService) withTransaction(fn func(*sql.Tx) error) error {
+	return fn(nil) // Pass nil transaction to simulate no transaction
+}
+
+// Override methods that use transactions to use our mocked withTransaction
+func (s *TestWithdrawalService) RequestWithdrawal(investorID uuid.UUID, req *models.CreateWithdrawalRequest) (*models.WithdrawalResponse, error) {
+	account, err := s.withdrawalRepo.GetBankAccountByID(req.BankAccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	var withdrawal *models.Withdrawal
+	err = s.withTransaction(func(tx *sql.Tx) error {
+		var requestErr error
+		withdrawal, requestErr = s.requestWithdrawalTx(tx, investorID, account, req)
+		return requestErr
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if withdrawal.Status == models.WithdrawalStatusProcessing {
+		s.payoutWithdrawal(withdrawal, account)
+	}
+
+	return toWithdrawalResponse(withdrawal), nil
+}
+
+func (s *TestWithdrawalService) ApproveWithdrawal(withdrawalID uuid.UUID, req *models.ReviewWithdrawalRequest) (*models.WithdrawalResponse, error) {
+	var withdrawal *models.Withdrawal
+	var account *models.BankAccount
+	err := s.withTransaction(func(tx *sql.Tx) error {
+		var approveErr error
+		withdrawal, account, approveErr = s.approveWithdrawalTx(tx, withdrawalID, req)
+		return approveErr
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.payoutWithdrawal(withdrawal, account)
+
+	return toWithdrawalResponse(withdrawal), nil
+}
+
+func (s *TestWithdrawalService) payoutWithdrawal(withdrawal *models.Withdrawal, account *models.BankAccount) bool {
+	result, payoutErr := s.sendPayout(withdrawal, account)
+
+	processing := *withdrawal
+	err := s.withTransaction(func(tx *sql.Tx) error {
+		return s.settleWithdrawalTx(tx, withdrawal, account, result, payoutErr)
+	})
+	if err != nil {
+		*withdrawal = processing
+		return false
+	}
+
+	return true
+}
+
+func setupTestWithdrawalService(threshold float64) (*TestWithdrawalService, *MockWithdrawalRepository, *MockWalletRepository, *MockPaymentAdapter) {
+	mockWithdrawal := &MockWithdrawalRepository{}
+	mockWallet := &MockWalletRepository{}
+	mockPayment := &MockPaymentAdapter{}
+
+	var db *sql.DB
+	baseService := NewWithdrawalService(
+		mockWithdrawal,
+		mockWallet,
+		&MockLoanRepository{},
+		mockPayment,
+		config.WalletConfig{WithdrawalApprovalThreshold: threshold},
+		&TestLogger{},
+		db,
+	).(*WithdrawalService)
+
+	return &TestWithdrawalService{WithdrawalService: baseService}, mockWithdrawal, mockWallet, mockPayment
+}
+
+func createTestBankAccount(investorID uuid.UUID) *models.BankAccount {
+	return &models.BankAccount{
+		BaseModel:         models.BaseModel{ID: uuid.New()},
+		InvestorID:        investorID,
+		BankCode:          "BCA",
+		AccountNumber:     "1234567890",
+		AccountHolderName: "Test Investor",
+		IsActive:          true,
+	}
+}
+
+func TestWithdrawalService_RequestWithdrawal_BelowThresholdPaysOut(t *testing.T) {
+	service, mockWithdrawal, mockWallet, mockPayment := setupTestWithdrawalService(50000000.0)
+
+	investorID := uuid.New()
+	account := createTestBankAccount(investorID)
+	req := &models.CreateWithdrawalRequest{BankAccountID: account.ID, Amount: 1000000.0}
+
+	mockWithdrawal.On("GetBankAccountByID", account.ID).Return(account, nil)
+	wallet := expectFundedWallet(mockWallet, investorID, 5000000.0)
+	mockWithdrawal.On("CreateWithdrawal", mock.AnythingOfType("*sql.Tx"), mock.MatchedBy(func(w *models.Withdrawal) bool {
+		return w.Status == models.WithdrawalStatusProcessing
+	})).Return(&models.Withdrawal{}, nil)
+	payoutAccount := adapters.PayoutAccount{
+		BankCode:          "BCA",
+		AccountNumber:     "1234567890",
+		AccountHolderName: "Test Investor",
+	}
+	mockPayment.On("ProcessPayout", req.Amount, payoutAccount, mock.AnythingOfType("string")).Return(&adapters.PaymentResult{TransactionID: "po_123", Status: "success"}, nil)
+	mockWithdrawal.On("LockWithdrawal", mock.AnythingOfType("*sql.Tx"), mock.AnythingOfType("uuid.UUID")).Return(&models.Withdrawal{Status: models.WithdrawalStatusProcessing}, nil)
+	mockWallet.On("ReleaseHold", mock.AnythingOfType("*sql.Tx"), mock.AnythingOfType("uuid.UUID"), mock.AnythingOfType("string")).Return(nil)
+	mockWallet.On("CreateWalletTransaction", mock.AnythingOfType("*sql.Tx"), mock.MatchedBy(func(txn *models.WalletTransaction) bool {
+		return txn.TransactionType == models.WalletTransactionPayout && txn.WalletID == wallet.ID && txn.Amount == req.Amount
+	})).Return(&models.WalletTransaction{}, nil)
+	mockWithdrawal.On("UpdateWithdrawal", mock.AnythingOfType("*sql.Tx"), mock.AnythingOfType("*models.Withdrawal")).Return(nil)
+
+	result, err := service.RequestWithdrawal(investorID, req)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, result)
+	assert.Equal(t, models.WithdrawalStatusCompleted, result.Status)
+	assert.False(t, result.RequiresReview)
+	assert.Equal(t, "po_123", result.TransactionID)
+
+	// The withdrawal ID is the idempotency key of the payout
+	mockPayment.AssertCalled(t, "ProcessPayout", req.Amount, payoutAccount, result.ID.String())
+	mockWithdrawal.AssertExpectations(t)
+	mockWallet.AssertExpectations(t)
+	mockPayment.AssertExpectations(t)
+}
+
+func TestWithdrawalService_RequestWithdrawal_AboveThresholdWaitsForApproval(t *testing.T) {
+	service, mockWithdrawal, mockWallet, mockPayment := setupTestWithdrawalService(50000000.0)
+
+	investorID := uuid.New()
+	account := createTestBankAccount(investorID)
+	req := &models.CreateWithdrawalRequest{BankAccountID: account.ID, Amount: 60000000.0}
+
+	mockWithdrawal.On("GetBankAccountByID", account.ID).Return(account, nil)
+	expectFundedWallet(mockWallet, investorID, 100000000.0)
+	mockWithdrawal.On("CreateWithdrawal", mock.AnythingOfType("*sql.Tx"), mock.AnythingOfType("*models.Withdrawal")).Return(&models.Withdrawal{}, nil)
+
+	result, err := service.RequestWithdrawal(investorID, req)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, result)
+	assert.Equal(t, models.WithdrawalStatusPendingApproval, result.Status)
+	assert.True(t, result.RequiresReview)
+
+	mockPayment.AssertNotCalled(t, "ProcessPayout", mock.Anything, mock.Anything, mock.Anything)
+	mockWallet.AssertNotCalled(t, "ReleaseHold", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWithdrawalService_RequestWithdrawal_InsufficientBalance(t *testing.T) {
+	service, mockWithdrawal, mockWallet, _ := setupTestWithdrawalService(50000000.0)
+
+	investorID := uuid.New()
+	account := createTestBankAccount(investorID)
+	req := &models.CreateWithdrawalRequest{BankAccountID: account.ID, Amount: 1000000.0}
+
+	mockWithdrawal.On("GetBankAccountByID", account.ID).Return(account, nil)
+	expectFundedWallet(mockWallet, investorID, 500000.0)
+
+	result, err := service.RequestWithdrawal(investorID, req)
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	assert.Contains(t, err.Error(), "insufficient wallet balance")
+
+	mockWithdrawal.AssertNotCalled(t, "CreateWithdrawal", mock.Anything, mock.Anything)
+}
+
+func TestWithdrawalService_ApproveWithdrawal_PayoutFailureReleasesFunds(t *testing.T) {
+	service, mockWithdrawal, mockWallet, mockPayment := setupTestWithdrawalService(50000000.0)
+
+	investorID := uuid.New()
+	account := createTestBankAccount(investorID)
+	withdrawal := &models.Withdrawal{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		InvestorID:     investorID,
+		BankAccountID:  account.ID,
+		Amount:         60000000.0,
+		Status:         models.WithdrawalStatusPendingApproval,
+		HoldID:         uuid.New(),
+		RequiresReview: true,
+	}
+	req := &models.ReviewWithdrawalRequest{ReviewedBy: uuid.New()}
+
+	mockWithdrawal.On("LockWithdrawal", mock.AnythingOfType("*sql.Tx"), withdrawal.ID).Return(withdrawal, nil)
+	mockWallet.On("LockWallet", mock.AnythingOfType("*sql.Tx"), investorID).Return(&models.Wallet{InvestorID: investorID}, nil)
+	mockWithdrawal.On("GetBankAccountByID", account.ID).Return(account, nil)
+	mockPayment.On("ProcessPayout", withdrawal.Amount, mock.AnythingOfType("adapters.PayoutAccount"), withdrawal.ID.String()).Return(nil, errors.New("bank rejected transfer"))
+	mockWallet.On("ReleaseHold", mock.AnythingOfType("*sql.Tx"), withdrawal.HoldID, mock.AnythingOfType("string")).Return(nil)
+	mockWithdrawal.On("UpdateWithdrawal", mock.AnythingOfType("*sql.Tx"), withdrawal).Return(nil)
+
+	result, err := service.ApproveWithdrawal(withdrawal.ID, req)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, result)
+	assert.Equal(t, models.WithdrawalStatusFailed, result.Status)
+	assert.Equal(t, "bank rejected transfer", result.FailureReason)
+	assert.Equal(t, &req.ReviewedBy, result.ReviewedBy)
+
+	mockWallet.AssertExpectations(t)
+	mockWallet.AssertNotCalled(t, "CreateWalletTransaction", mock.Anything, mock.Anything)
+}
+
+func TestWithdrawalService_ApproveWithdrawal_AlreadyReviewed(t *testing.T) {
+	service, mockWithdrawal, mockWallet, _ := setupTestWithdrawalService(50000000.0)
+
+	withdrawal := &models.Withdrawal{
+		BaseModel: models.BaseModel{ID: uuid.New()},
+		Status:    models.WithdrawalStatusCompleted,
+	}
+
+	mockWithdrawal.On("LockWithdrawal", mock.AnythingOfType("*sql.Tx"), withdrawal.ID).Return(withdrawal, nil)
+
+	result, err := service.ApproveWithdrawal(withdrawal.ID, &models.ReviewWithdrawalRequest{ReviewedBy: uuid.New()})
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	assert.Contains(t, err.Error(), "cannot be reviewed")
+
+	mockWallet.AssertNotCalled(t, "LockWallet", mock.Anything, mock.Anything)
+}
+
+func TestWithdrawalService_ApproveWithdrawal_InactiveBankAccountRefused(t *testing.T) {
+	service, mockWithdrawal, _, mockPayment := setupTestWithdrawalService(50000000.0)
+
+	investorID := uuid.New()
+	account := createTestBankAccount(investorID)
+	account.IsActive = false
+	withdrawal := &models.Withdrawal{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		InvestorID:     investorID,
+		BankAccountID:  account.ID,
+		Amount:         60000000.0,
+		Status:         models.WithdrawalStatusPendingApproval,
+		HoldID:         uuid.New(),
+		RequiresReview: true,
+	}
+
+	mockWithdrawal.On("LockWithdrawal", mock.AnythingOfType("*sql.Tx"), withdrawal.ID).Return(withdrawal, nil)
+	mockWithdrawal.On("GetBankAccountByID", account.ID).Return(account, nil)
+
+	result, err := service.ApproveWithdrawal(withdrawal.ID, &models.ReviewWithdrawalRequest{ReviewedBy: uuid.New()})
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	assert.Contains(t, err.Error(), "no longer active")
+
+	mockPayment.AssertNotCalled(t, "ProcessPayout", mock.Anything, mock.Anything, mock.Anything)
+	mockWithdrawal.AssertNotCalled(t, "UpdateWithdrawal", mock.Anything, mock.Anything)
+}
+
+func TestWithdrawalService_ApproveWithdrawal_LedgerFailureAfterPayoutStaysProcessing(t *testing.T) {
+	service, mockWithdrawal, mockWallet, mockPayment := setupTestWithdrawalService(50000000.0)
+
+	investorID := uuid.New()
+	account := createTestBankAccount(investorID)
+	withdrawal := &models.Withdrawal{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		InvestorID:     investorID,
+		BankAccountID:  account.ID,
+		Amount:         60000000.0,
+		Status:         models.WithdrawalStatusPendingApproval,
+		HoldID:         uuid.New(),
+		RequiresReview: true,
+	}
+	req := &models.ReviewWithdrawalRequest{ReviewedBy: uuid.New()}
+
+	mockWithdrawal.On("LockWithdrawal", mock.AnythingOfType("*sql.Tx"), withdrawal.ID).Return(withdrawal, nil)
+	mockWithdrawal.On("GetBankAccountByID", account.ID).Return(account, nil)
+	mockWithdrawal.On("UpdateWithdrawal", mock.AnythingOfType("*sql.Tx"), withdrawal).Return(nil).Once()
+	mockPayment.On("ProcessPayout", withdrawal.Amount, mock.AnythingOfType("adapters.PayoutAccount"), withdrawal.ID.String()).Return(&adapters.PaymentResult{TransactionID: "po_456", Status: "success"}, nil)
+	mockWallet.On("LockWallet", mock.AnythingOfType("*sql.Tx"), investorID).Return(&models.Wallet{InvestorID: investorID}, nil)
+	mockWallet.On("ReleaseHold", mock.AnythingOfType("*sql.Tx"), withdrawal.HoldID, mock.AnythingOfType("string")).Return(nil)
+	mockWallet.On("CreateWalletTransaction", mock.AnythingOfType("*sql.Tx"), mock.AnythingOfType("*models.WalletTransaction")).Return(nil, errors.New("database error"))
+
+	result, err := service.ApproveWithdrawal(withdrawal.ID, req)
+
+	// The approval committed and the payout went out, so the withdrawal is left processing for the resume job
+	assert.NoError(t, err)
+	assert.Equal(t, models.WithdrawalStatusProcessing, result.Status)
+	assert.Empty(t, result.TransactionID)
+	assert.Nil(t, result.CompletedAt)
+
+	// A second approval cannot pay it out again
+	again, err := service.ApproveWithdrawal(withdrawal.ID, req)
+
+	assert.Error(t, err)
+	assert.Nil(t, again)
+	assert.Contains(t, err.Error(), "cannot be reviewed")
+	mockPayment.AssertNumberOfCalls(t, "ProcessPayout", 1)
+	mockWithdrawal.AssertNumberOfCalls(t, "UpdateWithdrawal", 1)
+}
+
+func TestWithdrawalService_SettleWithdrawal_AlreadySettled(t *testing.T) {
+	service, mockWithdrawal, mockWallet, _ := setupTestWithdrawalService(50000000.0)
+
+	investorID := uuid.New()
+	account := createTestBankAccount(investorID)
+	withdrawal := &models.Withdrawal{
+		BaseModel:  models.BaseModel{ID: uuid.New()},
+		InvestorID: investorID,
+		Amount:     1000000.0,
+		Status:     models.WithdrawalStatusProcessing,
+	}
+	completed := *withdrawal
+	completed.Status = models.WithdrawalStatusCompleted
+	completed.TransactionID = "po_789"
+
+	mockWithdrawal.On("LockWithdrawal", mock.AnythingOfType("*sql.Tx"), withdrawal.ID).Return(&completed, nil)
+
+	err := service.settleWithdrawalTx(nil, withdrawal, account, &adapters.PaymentResult{TransactionID: "po_789"}, nil)
+
+	// A resumed payout that was settled in the meantime leaves the ledger alone
+	assert.NoError(t, err)
+	assert.Equal(t, models.WithdrawalStatusCompleted, withdrawal.Status)
+	mockWallet.AssertNotCalled(t, "ReleaseHold", mock.Anything, mock.Anything, mock.Anything)
+	mockWithdrawal.AssertNotCalled(t, "UpdateWithdrawal", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,18 @@
+-- Migration Down: Drop settlement reconciliation schema
+-- File: 003_create_reconciliation_schema.down.sql
+
+-- Drop indexes first
+DROP INDEX IF EXISTS idx_reconciliation_mismatches_deleted_at;
+DROP INDEX IF EXISTS idx_reconciliation_mismatches_transaction_id;
+DROP INDEX IF EXISTS idx_reconciliation_mismatches_run_id;
+
+DROP INDEX IF EXISTS idx_reconciliation_runs_deleted_at;
+DROP INDEX IF EXISTS idx_reconciliation_runs_report_date;
+
+DROP INDEX IF EXISTS idx_disbursements_transaction_id;
+
+-- Drop tables in correct order (respecting foreign key constraints)
+DROP TABLE IF EXISTS reconciliation_mismatches;
+DROP TABLE IF EXISTS reconciliation_runs;
+
+ALTER TABLE disbursements DROP COLUMN IF EXISTS transaction_id;
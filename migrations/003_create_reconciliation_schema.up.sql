@@ -0,0 +1,55 @@
+-- Migration Up: Create settlement reconciliation schema
+-- File: 003_create_reconciliation_schema.up.sql
+
+-- Store payment provider transaction reference on disbursements
+ALTER TABLE disbursements ADD COLUMN transaction_id VARCHAR(100);
+
+-- Create reconciliation_runs table
+CREATE TABLE reconciliation_runs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    report_date DATE NOT NULL,
+    source VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'running',
+    total_records INTEGER NOT NULL DEFAULT 0,
+    matched_count INTEGER NOT NULL DEFAULT 0,
+    mismatch_count INTEGER NOT NULL DEFAULT 0,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    error_message TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE,
+
+
+    CONSTRAINT chk_reconciliation_source CHECK (source IN ('adapter', 'csv')),
+    CONSTRAINT chk_reconciliation_status CHECK (status IN ('running', 'completed', 'failed'))
+);
+
+-- Create reconciliation_mismatches table
+CREATE TABLE reconciliation_mismatches (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    run_id UUID NOT NULL,
+    transaction_id VARCHAR(100) NOT NULL,
+    mismatch_type VARCHAR(30) NOT NULL,
+    reference_type VARCHAR(30),
+    reference_id UUID,
+    expected_amount DECIMAL(15,2),
+    reported_amount DECIMAL(15,2),
+    notes TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE,
+
+
+    CONSTRAINT fk_reconciliation_mismatches_run FOREIGN KEY (run_id) REFERENCES reconciliation_runs(id),
+    CONSTRAINT chk_mismatch_type CHECK (mismatch_type IN ('missing_in_report', 'missing_in_records', 'duplicate', 'amount_mismatch'))
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_disbursements_transaction_id ON disbursements(transaction_id);
+
+CREATE INDEX idx_reconciliation_runs_report_date ON reconciliation_runs(report_date);
+CREATE INDEX idx_reconciliation_runs_deleted_at ON reconciliation_runs(deleted_at);
+
+CREATE INDEX idx_reconciliation_mismatches_run_id ON reconciliation_mismatches(run_id);
+CREATE INDEX idx_reconciliation_mismatches_transaction_id ON reconciliation_mismatches(transaction_id);
+CREATE INDEX idx_reconciliation_mismatches_deleted_at ON reconciliation_mismatches(deleted_at);
@@ -0,0 +1,43 @@
+-- Migration Down: Drop investor wallet ledger schema
+-- File: 004_create_wallet_schema.down.sql
+
+-- Drop indexes first
+DROP INDEX IF EXISTS idx_wallet_transactions_hold_id;
+DROP INDEX IF EXISTS idx_wallet_transactions_created_at;
+DROP INDEX IF EXISTS idx_wallet_transactions_reference;
+DROP INDEX IF EXISTS idx_wallet_transactions_investor_id;
+DROP INDEX IF EXISTS idx_wallet_transactions_wallet_id;
+
+DROP INDEX IF EXISTS idx_investor_wallets_investor_id;
+
+DROP TRIGGER IF EXISTS trg_wallet_transactions_append_only ON wallet_transactions;
+DROP FUNCTION IF EXISTS wallet_transactions_append_only();
+
+-- Drop tables in correct order (respecting foreign key constraints)
+DROP TABLE IF EXISTS wallet_transactions;
+DROP TABLE IF EXISTS investor_wallets;
+
+-- Cancelled and expired loans have no state to go back to: return them to the state they were closed from and
+-- soft delete them, as their investors were refunded. Their history entries are dropped with the states.
+UPDATE loans l
+SET state = COALESCE((
+        SELECT h.previous_state FROM loan_state_histories h
+        WHERE h.loan_id = l.id AND h.new_state = l.state
+        ORDER BY h.change_date DESC, h.created_at DESC
+        LIMIT 1
+    ), 'proposed'),
+    deleted_at = COALESCE(l.deleted_at, NOW()),
+    updated_at = NOW()
+WHERE l.state IN ('cancelled', 'expired');
+
+DELETE FROM loan_state_histories
+WHERE previous_state IN ('cancelled', 'expired') OR new_state IN ('cancelled', 'expired');
+
+-- Restore original loan state constraints
+ALTER TABLE loan_state_histories DROP CONSTRAINT chk_new_state;
+ALTER TABLE loan_state_histories ADD CONSTRAINT chk_new_state CHECK (new_state IN ('proposed', 'approved', 'invested', 'disbursed'));
+ALTER TABLE loan_state_histories DROP CONSTRAINT chk_previous_state;
+ALTER TABLE loan_state_histories ADD CONSTRAINT chk_previous_state CHECK (previous_state IN ('proposed', 'approved', 'invested', 'disbursed'));
+
+ALTER TABLE loans DROP CONSTRAINT chk_loan_state;
+ALTER TABLE loans ADD CONSTRAINT chk_loan_state CHECK (state IN ('proposed', 'approved', 'invested', 'disbursed'));
@@ -0,0 +1,84 @@
+-- Migration Up: Create investor wallet ledger schema
+-- File: 004_create_wallet_schema.up.sql
+
+-- Allow loans to be cancelled or to expire while raising funds
+ALTER TABLE loans DROP CONSTRAINT chk_loan_state;
+ALTER TABLE loans ADD CONSTRAINT chk_loan_state CHECK (state IN ('proposed', 'approved', 'invested', 'disbursed', 'cancelled', 'expired'));
+
+ALTER TABLE loan_state_histories DROP CONSTRAINT chk_previous_state;
+ALTER TABLE loan_state_histories ADD CONSTRAINT chk_previous_state CHECK (previous_state IN ('proposed', 'approved', 'invested', 'disbursed', 'cancelled', 'expired'));
+ALTER TABLE loan_state_histories DROP CONSTRAINT chk_new_state;
+ALTER TABLE loan_state_histories ADD CONSTRAINT chk_new_state CHECK (new_state IN ('proposed', 'approved', 'invested', 'disbursed', 'cancelled', 'expired'));
+
+-- Create investor_wallets table (one wallet per investor, row is locked while placing holds)
+CREATE TABLE investor_wallets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    investor_id UUID UNIQUE NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'IDR',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE,
+
+
+    CONSTRAINT fk_investor_wallets_investor FOREIGN KEY (investor_id) REFERENCES investors(id)
+);
+
+-- Create wallet_transactions table (append-only ledger, balances are derived from it)
+CREATE TABLE wallet_transactions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    wallet_id UUID NOT NULL,
+    investor_id UUID NOT NULL,
+    transaction_type VARCHAR(20) NOT NULL,
+    amount DECIMAL(15,2) NOT NULL,
+    reference_type VARCHAR(30),
+    reference_id UUID,
+    hold_id UUID,
+    description TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE,
+
+
+    CONSTRAINT fk_wallet_transactions_wallet FOREIGN KEY (wallet_id) REFERENCES investor_wallets(id),
+    CONSTRAINT fk_wallet_transactions_investor FOREIGN KEY (investor_id) REFERENCES investors(id),
+    CONSTRAINT fk_wallet_transactions_hold FOREIGN KEY (hold_id) REFERENCES wallet_transactions(id),
+    CONSTRAINT chk_wallet_transaction_type CHECK (transaction_type IN ('top_up', 'hold', 'release', 'capture', 'payout')),
+    CONSTRAINT chk_wallet_transaction_amount CHECK (amount > 0),
+    CONSTRAINT chk_wallet_transaction_hold CHECK ((transaction_type IN ('release', 'capture')) = (hold_id IS NOT NULL))
+);
+
+-- Reject updates and deletes so the ledger stays append-only
+CREATE OR REPLACE FUNCTION wallet_transactions_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'wallet_transactions is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_wallet_transactions_append_only
+    BEFORE UPDATE OR DELETE ON wallet_transactions
+    FOR EACH ROW EXECUTE FUNCTION wallet_transactions_append_only();
+
+-- Create indexes for better performance
+CREATE INDEX idx_investor_wallets_investor_id ON investor_wallets(investor_id);
+
+CREATE INDEX idx_wallet_transactions_wallet_id ON wallet_transactions(wallet_id);
+CREATE INDEX idx_wallet_transactions_investor_id ON wallet_transactions(investor_id);
+CREATE INDEX idx_wallet_transactions_reference ON wallet_transactions(reference_type, reference_id);
+CREATE INDEX idx_wallet_transactions_created_at ON wallet_transactions(created_at);
+-- A hold can only be released or captured once
+CREATE UNIQUE INDEX idx_wallet_transactions_hold_id ON wallet_transactions(hold_id) WHERE hold_id IS NOT NULL;
+
+-- Seed wallets and opening top ups for sample investors
+INSERT INTO investor_wallets (id, investor_id) VALUES
+('aa0e8400-e29b-41d4-a716-446655440001', '770e8400-e29b-41d4-a716-446655440001'),
+('aa0e8400-e29b-41d4-a716-446655440002', '770e8400-e29b-41d4-a716-446655440002'),
+('aa0e8400-e29b-41d4-a716-446655440003', '770e8400-e29b-41d4-a716-446655440003'),
+('aa0e8400-e29b-41d4-a716-446655440004', '770e8400-e29b-41d4-a716-446655440004'),
+('aa0e8400-e29b-41d4-a716-446655440005', '770e8400-e29b-41d4-a716-446655440005');
+
+INSERT INTO wallet_transactions (wallet_id, investor_id, transaction_type, amount, reference_type, description) VALUES
+('aa0e8400-e29b-41d4-a716-446655440001', '770e8400-e29b-41d4-a716-446655440001', 'top_up', 500000000.00, 'payment', 'Opening balance'),
+('aa0e8400-e29b-41d4-a716-446655440002', '770e8400-e29b-41d4-a716-446655440002', 'top_up', 200000000.00, 'payment', 'Opening balance'),
+('aa0e8400-e29b-41d4-a716-446655440003', '770e8400-e29b-41d4-a716-446655440003', 'top_up', 200000000.00, 'payment', 'Opening balance'),
+('aa0e8400-e29b-41d4-a716-446655440004', '770e8400-e29b-41d4-a716-446655440004', 'top_up', 100000000.00, 'payment', 'Opening balance'),
+('aa0e8400-e29b-41d4-a716-446655440005', '770e8400-e29b-41d4-a716-446655440005', 'top_up', 100000000.00, 'payment', 'Opening balance');
@@ -0,0 +1,14 @@
+-- Migration Down: Drop investor bank account and withdrawal schema
+-- File: 005_create_withdrawal_schema.down.sql
+
+-- Drop indexes first
+DROP INDEX IF EXISTS idx_withdrawals_created_at;
+DROP INDEX IF EXISTS idx_withdrawals_status;
+DROP INDEX IF EXISTS idx_withdrawals_investor_id;
+
+DROP INDEX IF EXISTS idx_investor_bank_accounts_account;
+DROP INDEX IF EXISTS idx_investor_bank_accounts_investor_id;
+
+-- Drop tables in correct order (respecting foreign key constraints)
+DROP TABLE IF EXISTS withdrawals;
+DROP TABLE IF EXISTS investor_bank_accounts;
@@ -0,0 +1,54 @@
+-- Migration Up: Create investor bank account and withdrawal schema
+-- File: 005_create_withdrawal_schema.up.sql
+
+-- Create investor_bank_accounts table (payout destinations registered by investors)
+CREATE TABLE investor_bank_accounts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    investor_id UUID NOT NULL,
+    bank_code VARCHAR(20) NOT NULL,
+    account_number VARCHAR(34) NOT NULL,
+    account_holder_name VARCHAR(255) NOT NULL,
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE,
+
+
+    CONSTRAINT fk_investor_bank_accounts_investor FOREIGN KEY (investor_id) REFERENCES investors(id)
+);
+
+-- Create withdrawals table (funds stay held in the wallet until the payout completes)
+CREATE TABLE withdrawals (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    investor_id UUID NOT NULL,
+    bank_account_id UUID NOT NULL,
+    amount DECIMAL(15,2) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    hold_id UUID NOT NULL,
+    requires_review BOOLEAN NOT NULL DEFAULT false,
+    reviewed_by UUID,
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    rejection_reason TEXT,
+    transaction_id VARCHAR(255),
+    failure_reason TEXT,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE,
+
+
+    CONSTRAINT fk_withdrawals_investor FOREIGN KEY (investor_id) REFERENCES investors(id),
+    CONSTRAINT fk_withdrawals_bank_account FOREIGN KEY (bank_account_id) REFERENCES investor_bank_accounts(id),
+    CONSTRAINT fk_withdrawals_hold FOREIGN KEY (hold_id) REFERENCES wallet_transactions(id),
+    CONSTRAINT fk_withdrawals_reviewer FOREIGN KEY (reviewed_by) REFERENCES employees(id),
+    CONSTRAINT chk_withdrawal_amount CHECK (amount > 0),
+    CONSTRAINT chk_withdrawal_status CHECK (status IN ('pending_approval', 'approved', 'completed', 'rejected', 'failed'))
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_investor_bank_accounts_investor_id ON investor_bank_accounts(investor_id);
+CREATE UNIQUE INDEX idx_investor_bank_accounts_account ON investor_bank_accounts(investor_id, bank_code, account_number) WHERE deleted_at IS NULL;
+
+CREATE INDEX idx_withdrawals_investor_id ON withdrawals(investor_id);
+CREATE INDEX idx_withdrawals_status ON withdrawals(status);
+CREATE INDEX idx_withdrawals_created_at ON withdrawals(created_at);
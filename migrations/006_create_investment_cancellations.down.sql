@@ -0,0 +1,9 @@
+-- Migration Down: Drop investment cancellation audit table
+-- File: 006_create_investment_cancellations.down.sql
+
+-- Drop indexes first
+DROP INDEX IF EXISTS idx_investment_cancellations_investor_id;
+DROP INDEX IF EXISTS idx_investment_cancellations_loan_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS investment_cancellations;
@@ -0,0 +1,25 @@
+-- Migration Up: Create investment cancellation audit table
+-- File: 006_create_investment_cancellations.up.sql
+
+-- Create investment_cancellations table (audit trail of investments withdrawn before funding completed)
+CREATE TABLE investment_cancellations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    investment_id UUID UNIQUE NOT NULL,
+    loan_id UUID NOT NULL,
+    investor_id UUID NOT NULL,
+    amount DECIMAL(15,2) NOT NULL,
+    reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE,
+
+
+    CONSTRAINT fk_investment_cancellations_investment FOREIGN KEY (investment_id) REFERENCES investments(id),
+    CONSTRAINT fk_investment_cancellations_loan FOREIGN KEY (loan_id) REFERENCES loans(id),
+    CONSTRAINT fk_investment_cancellations_investor FOREIGN KEY (investor_id) REFERENCES investors(id),
+    CONSTRAINT chk_investment_cancellation_amount CHECK (amount > 0)
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_investment_cancellations_loan_id ON investment_cancellations(loan_id);
+CREATE INDEX idx_investment_cancellations_investor_id ON investment_cancellations(investor_id);
@@ -0,0 +1,8 @@
+-- Migration Down: Drop per-investor investment limit overrides
+-- File: 007_create_investor_investment_limits.down.sql
+
+-- Drop indexes first
+DROP INDEX IF EXISTS idx_investor_investment_limits_investor_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS investor_investment_limits;
@@ -0,0 +1,25 @@
+-- Migration Up: Create per-investor investment limit overrides
+-- File: 007_create_investor_investment_limits.up.sql
+
+-- Create investor_investment_limits table (NULL columns inherit the global limit)
+CREATE TABLE investor_investment_limits (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    investor_id UUID UNIQUE NOT NULL,
+    max_loan_share DECIMAL(5,4),
+    max_total_exposure DECIMAL(15,2),
+    min_ticket DECIMAL(15,2),
+    updated_by UUID NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE,
+
+
+    CONSTRAINT fk_investor_investment_limits_investor FOREIGN KEY (investor_id) REFERENCES investors(id),
+    CONSTRAINT fk_investor_investment_limits_updated_by FOREIGN KEY (updated_by) REFERENCES employees(id),
+    CONSTRAINT chk_max_loan_share CHECK (max_loan_share IS NULL OR (max_loan_share >= 0 AND max_loan_share <= 1)),
+    CONSTRAINT chk_max_total_exposure CHECK (max_total_exposure IS NULL OR max_total_exposure >= 0),
+    CONSTRAINT chk_min_ticket CHECK (min_ticket IS NULL OR min_ticket >= 0)
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_investor_investment_limits_investor_id ON investor_investment_limits(investor_id);
@@ -0,0 +1,14 @@
+-- Migration Down: Drop auto-invest rules schema
+-- File: 008_create_auto_invest_schema.down.sql
+
+-- Drop indexes first
+DROP INDEX IF EXISTS idx_auto_invest_rules_rotation;
+DROP INDEX IF EXISTS idx_auto_invest_rules_investor_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS auto_invest_rules;
+
+-- Drop loan product and borrower risk grade
+ALTER TABLE borrowers DROP CONSTRAINT IF EXISTS chk_borrower_risk_grade;
+ALTER TABLE borrowers DROP COLUMN IF EXISTS risk_grade;
+ALTER TABLE loans DROP COLUMN IF EXISTS product;
@@ -0,0 +1,45 @@
+-- Migration Up: Create auto-invest rules schema
+-- File: 008_create_auto_invest_schema.up.sql
+
+-- Loans are offered under a product and borrowers carry a risk grade (A best, E worst) so rules can target them
+ALTER TABLE loans ADD COLUMN product VARCHAR(50) NOT NULL DEFAULT 'general';
+ALTER TABLE borrowers ADD COLUMN risk_grade VARCHAR(1);
+ALTER TABLE borrowers ADD CONSTRAINT chk_borrower_risk_grade CHECK (risk_grade IS NULL OR risk_grade IN ('A', 'B', 'C', 'D', 'E'));
+
+-- Create auto_invest_rules table (NULL criteria match any loan)
+CREATE TABLE auto_invest_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    investor_id UUID NOT NULL,
+    min_roi DECIMAL(5,4) NOT NULL DEFAULT 0,
+    max_principal DECIMAL(15,2),
+    product VARCHAR(50),
+    max_risk_grade VARCHAR(1),
+    amount_per_loan DECIMAL(15,2) NOT NULL,
+    is_active BOOLEAN DEFAULT true,
+    last_allocated_at TIMESTAMP WITH TIME ZONE, -- drives the rotation, least recently allocated rules go first
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE,
+
+
+    CONSTRAINT fk_auto_invest_rules_investor FOREIGN KEY (investor_id) REFERENCES investors(id),
+    CONSTRAINT chk_auto_invest_min_roi CHECK (min_roi >= 0 AND min_roi <= 1),
+    CONSTRAINT chk_auto_invest_max_principal CHECK (max_principal IS NULL OR max_principal > 0),
+    CONSTRAINT chk_auto_invest_max_risk_grade CHECK (max_risk_grade IS NULL OR max_risk_grade IN ('A', 'B', 'C', 'D', 'E')),
+    CONSTRAINT chk_auto_invest_amount_per_loan CHECK (amount_per_loan > 0)
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_auto_invest_rules_investor_id ON auto_invest_rules(investor_id);
+CREATE INDEX idx_auto_invest_rules_rotation ON auto_invest_rules(last_allocated_at NULLS FIRST, created_at) WHERE is_active = true AND deleted_at IS NULL;
+
+-- Seed risk grades for sample borrowers
+UPDATE borrowers SET risk_grade = 'A' WHERE id = '550e8400-e29b-41d4-a716-446655440001';
+UPDATE borrowers SET risk_grade = 'B' WHERE id = '550e8400-e29b-41d4-a716-446655440002';
+UPDATE borrowers SET risk_grade = 'B' WHERE id = '550e8400-e29b-41d4-a716-446655440003';
+UPDATE borrowers SET risk_grade = 'C' WHERE id = '550e8400-e29b-41d4-a716-446655440004';
+UPDATE borrowers SET risk_grade = 'D' WHERE id = '550e8400-e29b-41d4-a716-446655440005';
+
+-- Seed an auto-invest rule for Global Investment Fund
+INSERT INTO auto_invest_rules (investor_id, min_roi, max_principal, max_risk_grade, amount_per_loan) VALUES
+('770e8400-e29b-41d4-a716-446655440001', 0.10, 100000000.00, 'B', 5000000.00);
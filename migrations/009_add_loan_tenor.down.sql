@@ -0,0 +1,5 @@
+-- Migration Down: Drop loan tenor
+-- File: 009_add_loan_tenor.down.sql
+
+ALTER TABLE loans DROP CONSTRAINT IF EXISTS chk_loan_tenor_months;
+ALTER TABLE loans DROP COLUMN IF EXISTS tenor_months;
@@ -0,0 +1,6 @@
+-- Migration Up: Add loan tenor
+-- File: 009_add_loan_tenor.up.sql
+
+-- Tenor is the repayment period in months, shown to investors on the marketplace
+ALTER TABLE loans ADD COLUMN tenor_months INTEGER NOT NULL DEFAULT 12;
+ALTER TABLE loans ADD CONSTRAINT chk_loan_tenor_months CHECK (tenor_months > 0);
@@ -0,0 +1,10 @@
+-- Migration Down: Drop loan waitlist
+-- File: 010_create_loan_waitlist.down.sql
+
+-- Drop indexes first
+DROP INDEX IF EXISTS idx_loan_waitlist_entries_waiting_investor;
+DROP INDEX IF EXISTS idx_loan_waitlist_entries_investor_id;
+DROP INDEX IF EXISTS idx_loan_waitlist_entries_queue;
+
+-- Drop tables
+DROP TABLE IF EXISTS loan_waitlist_entries;
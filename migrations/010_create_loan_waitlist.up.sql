@@ -0,0 +1,32 @@
+-- Migration Up: Create loan waitlist
+-- File: 010_create_loan_waitlist.up.sql
+
+-- Create loan_waitlist_entries table (investors queued for capacity freed on fully subscribed loans)
+CREATE TABLE loan_waitlist_entries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    loan_id UUID NOT NULL,
+    investor_id UUID NOT NULL,
+    amount DECIMAL(15,2) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'waiting',
+    allocated_amount DECIMAL(15,2),
+    investment_id UUID,
+    failure_reason TEXT,
+    processed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE,
+
+
+    CONSTRAINT fk_loan_waitlist_entries_loan FOREIGN KEY (loan_id) REFERENCES loans(id),
+    CONSTRAINT fk_loan_waitlist_entries_investor FOREIGN KEY (investor_id) REFERENCES investors(id),
+    CONSTRAINT fk_loan_waitlist_entries_investment FOREIGN KEY (investment_id) REFERENCES investments(id),
+    CONSTRAINT chk_loan_waitlist_amount CHECK (amount > 0),
+    CONSTRAINT chk_loan_waitlist_status CHECK (status IN ('waiting', 'allocated', 'failed', 'closed'))
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_loan_waitlist_entries_queue ON loan_waitlist_entries(loan_id, created_at) WHERE status = 'waiting' AND deleted_at IS NULL;
+CREATE INDEX idx_loan_waitlist_entries_investor_id ON loan_waitlist_entries(investor_id);
+
+-- An investor waits at most once per loan
+CREATE UNIQUE INDEX idx_loan_waitlist_entries_waiting_investor ON loan_waitlist_entries(loan_id, investor_id) WHERE status = 'waiting' AND deleted_at IS NULL;
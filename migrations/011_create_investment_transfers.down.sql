@@ -0,0 +1,20 @@
+-- Migration Down: Drop secondary market investment transfer schema
+-- File: 011_create_investment_transfers.down.sql
+
+-- Drop indexes first
+DROP INDEX IF EXISTS idx_investment_transfers_listed_investment;
+DROP INDEX IF EXISTS idx_investment_transfers_status;
+DROP INDEX IF EXISTS idx_investment_transfers_buyer_investment_id;
+DROP INDEX IF EXISTS idx_investment_transfers_investment_id;
+
+DROP INDEX IF EXISTS idx_investments_parent_investment_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS investment_transfers;
+
+-- Restore original wallet transaction types
+ALTER TABLE wallet_transactions DROP CONSTRAINT chk_wallet_transaction_type;
+ALTER TABLE wallet_transactions ADD CONSTRAINT chk_wallet_transaction_type CHECK (transaction_type IN ('top_up', 'hold', 'release', 'capture', 'payout'));
+
+ALTER TABLE investments DROP CONSTRAINT fk_investments_parent;
+ALTER TABLE investments DROP COLUMN parent_investment_id;
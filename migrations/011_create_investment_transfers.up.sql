@@ -0,0 +1,50 @@
+-- Migration Up: Create secondary market investment transfer schema
+-- File: 011_create_investment_transfers.up.sql
+
+-- An investment bought on the secondary market points at the investment it was carved from
+ALTER TABLE investments ADD COLUMN parent_investment_id UUID;
+ALTER TABLE investments ADD CONSTRAINT fk_investments_parent FOREIGN KEY (parent_investment_id) REFERENCES investments(id);
+
+-- Allow funds to move between wallets when a transfer settles
+ALTER TABLE wallet_transactions DROP CONSTRAINT chk_wallet_transaction_type;
+ALTER TABLE wallet_transactions ADD CONSTRAINT chk_wallet_transaction_type CHECK (transaction_type IN ('top_up', 'hold', 'release', 'capture', 'payout', 'transfer_in', 'transfer_out'));
+
+-- Create investment_transfers table (secondary market listings and the lineage of settled transfers)
+CREATE TABLE investment_transfers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    investment_id UUID NOT NULL,
+    loan_id UUID NOT NULL,
+    seller_id UUID NOT NULL,
+    amount DECIMAL(15,2) NOT NULL,
+    price DECIMAL(15,2) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'listed',
+    buyer_id UUID,
+    buyer_investment_id UUID,
+    agreement_url VARCHAR(500),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    cancelled_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE,
+
+
+    CONSTRAINT fk_investment_transfers_investment FOREIGN KEY (investment_id) REFERENCES investments(id),
+    CONSTRAINT fk_investment_transfers_loan FOREIGN KEY (loan_id) REFERENCES loans(id),
+    CONSTRAINT fk_investment_transfers_seller FOREIGN KEY (seller_id) REFERENCES investors(id),
+    CONSTRAINT fk_investment_transfers_buyer FOREIGN KEY (buyer_id) REFERENCES investors(id),
+    CONSTRAINT fk_investment_transfers_buyer_investment FOREIGN KEY (buyer_investment_id) REFERENCES investments(id),
+    CONSTRAINT chk_investment_transfer_amount CHECK (amount > 0),
+    CONSTRAINT chk_investment_transfer_price CHECK (price > 0),
+    CONSTRAINT chk_investment_transfer_status CHECK (status IN ('listed', 'completed', 'cancelled')),
+    CONSTRAINT chk_investment_transfer_buyer CHECK ((status = 'completed') = (buyer_investment_id IS NOT NULL))
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_investments_parent_investment_id ON investments(parent_investment_id);
+
+CREATE INDEX idx_investment_transfers_investment_id ON investment_transfers(investment_id);
+CREATE INDEX idx_investment_transfers_buyer_investment_id ON investment_transfers(buyer_investment_id);
+CREATE INDEX idx_investment_transfers_status ON investment_transfers(status);
+
+-- An investment is listed at most once at a time
+CREATE UNIQUE INDEX idx_investment_transfers_listed_investment ON investment_transfers(investment_id) WHERE status = 'listed' AND deleted_at IS NULL;
@@ -0,0 +1,4 @@
+-- Migration Down: Drop per-investment agreement letters
+-- File: 012_add_investment_agreement_url.down.sql
+
+ALTER TABLE investments DROP COLUMN IF EXISTS agreement_url;
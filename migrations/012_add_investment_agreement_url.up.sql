@@ -0,0 +1,5 @@
+-- Migration Up: Add per-investment agreement letters
+-- File: 012_add_investment_agreement_url.up.sql
+
+-- Each investor gets an agreement with their own name, amount and expected return
+ALTER TABLE investments ADD COLUMN agreement_url TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,10 @@
+-- Migration Down: Drop borrower agreement e-signature schema
+-- File: 013_create_agreement_signatures.down.sql
+
+-- Drop indexes first
+DROP INDEX IF EXISTS idx_agreement_signatures_open_loan;
+DROP INDEX IF EXISTS idx_agreement_signatures_loan_id;
+DROP INDEX IF EXISTS idx_agreement_signatures_envelope_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS agreement_signatures;
@@ -0,0 +1,34 @@
+-- Migration Up: Create borrower agreement e-signature schema
+-- File: 013_create_agreement_signatures.up.sql
+
+-- Create agreement_signatures table (one row per envelope sent to the e-signature provider)
+CREATE TABLE agreement_signatures (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    loan_id UUID NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    envelope_id VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    document_url TEXT NOT NULL,
+    signing_url TEXT,
+    signer_name VARCHAR(255) NOT NULL,
+    signer_email VARCHAR(255) NOT NULL,
+    signed_file_url TEXT,
+    signed_file_type VARCHAR(10),
+    sent_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE,
+
+    CONSTRAINT fk_agreement_signatures_loan FOREIGN KEY (loan_id) REFERENCES loans(id),
+    CONSTRAINT chk_agreement_signature_status CHECK (status IN ('pending', 'signed', 'declined')),
+    CONSTRAINT chk_agreement_signature_signed_file CHECK ((status = 'signed') = (signed_file_url IS NOT NULL))
+);
+
+-- Create indexes for better performance
+CREATE UNIQUE INDEX idx_agreement_signatures_envelope_id ON agreement_signatures(provider, envelope_id);
+CREATE INDEX idx_agreement_signatures_loan_id ON agreement_signatures(loan_id);
+
+-- A loan has at most one signature in progress or completed; declined ones can be re-sent
+CREATE UNIQUE INDEX idx_agreement_signatures_open_loan ON agreement_signatures(loan_id)
+    WHERE status IN ('pending', 'signed') AND deleted_at IS NULL;
@@ -0,0 +1,8 @@
+-- Migration Down: Stop tracking where and what uploaded files are stored
+-- File: 014_add_file_upload_storage.down.sql
+
+-- Drop indexes first
+DROP INDEX IF EXISTS idx_file_uploads_entity;
+
+ALTER TABLE file_uploads DROP COLUMN IF EXISTS storage_backend;
+ALTER TABLE file_uploads DROP COLUMN IF EXISTS checksum;
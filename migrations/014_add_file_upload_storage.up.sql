@@ -0,0 +1,8 @@
+-- Migration Up: Track where and what uploaded files are stored
+-- File: 014_add_file_upload_storage.up.sql
+
+-- file_path now holds the storage key; checksum is the hex SHA-256 of the stored content
+ALTER TABLE file_uploads ADD COLUMN checksum VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE file_uploads ADD COLUMN storage_backend VARCHAR(20) NOT NULL DEFAULT 'local';
+
+CREATE INDEX idx_file_uploads_entity ON file_uploads(entity_type, entity_id);
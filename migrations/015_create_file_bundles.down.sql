@@ -0,0 +1,13 @@
+-- Migration Down: Drop document bundles
+-- File: 015_create_file_bundles.down.sql
+
+-- Drop indexes first
+DROP INDEX IF EXISTS idx_file_uploads_bundle_id;
+DROP INDEX IF EXISTS idx_file_bundles_deleted_at;
+DROP INDEX IF EXISTS idx_file_bundles_entity;
+
+ALTER TABLE approvals DROP COLUMN IF EXISTS visit_proof_bundle_id;
+ALTER TABLE file_uploads DROP COLUMN IF EXISTS bundle_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS file_bundles;
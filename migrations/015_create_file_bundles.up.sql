@@ -0,0 +1,23 @@
+-- Migration Up: Group uploaded files into document bundles
+-- File: 015_create_file_bundles.up.sql
+
+-- Create file_bundles table (a set of files uploaded together, e.g. the proof images of one visit)
+CREATE TABLE file_bundles (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id UUID NOT NULL,
+    created_by UUID NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE,
+
+    CONSTRAINT fk_file_bundles_created_by FOREIGN KEY (created_by) REFERENCES employees(id)
+);
+
+ALTER TABLE file_uploads ADD COLUMN bundle_id UUID REFERENCES file_bundles(id);
+ALTER TABLE approvals ADD COLUMN visit_proof_bundle_id UUID REFERENCES file_bundles(id);
+
+-- Create indexes for better performance
+CREATE INDEX idx_file_bundles_entity ON file_bundles(entity_type, entity_id);
+CREATE INDEX idx_file_bundles_deleted_at ON file_bundles(deleted_at);
+CREATE INDEX idx_file_uploads_bundle_id ON file_uploads(bundle_id);
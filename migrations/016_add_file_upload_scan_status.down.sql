@@ -0,0 +1,10 @@
+-- Migration Down: Stop tracking malware scans of uploaded files
+-- File: 016_add_file_upload_scan_status.down.sql
+
+-- Drop indexes first
+DROP INDEX IF EXISTS idx_file_uploads_scan_status;
+
+ALTER TABLE file_uploads DROP CONSTRAINT IF EXISTS chk_file_upload_scan_status;
+ALTER TABLE file_uploads DROP COLUMN IF EXISTS scanned_at;
+ALTER TABLE file_uploads DROP COLUMN IF EXISTS scan_signature;
+ALTER TABLE file_uploads DROP COLUMN IF EXISTS scan_status;
@@ -0,0 +1,12 @@
+-- Migration Up: Quarantine uploaded files until a malware scan reports them clean
+-- File: 016_add_file_upload_scan_status.up.sql
+
+-- Files uploaded before scanning existed are pending too, so the scan job checks them
+ALTER TABLE file_uploads ADD COLUMN scan_status VARCHAR(20) NOT NULL DEFAULT 'pending';
+ALTER TABLE file_uploads ADD COLUMN scan_signature VARCHAR(255);
+ALTER TABLE file_uploads ADD COLUMN scanned_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE file_uploads ADD CONSTRAINT chk_file_upload_scan_status CHECK (scan_status IN ('pending', 'clean', 'infected', 'skipped'));
+
+-- Create indexes for better performance
+CREATE INDEX idx_file_uploads_scan_status ON file_uploads(scan_status) WHERE scan_status = 'pending';
@@ -0,0 +1,14 @@
+-- Migration Down: Stop recording where and when visit proof photos were taken
+-- File: 017_add_visit_proof_metadata.down.sql
+
+ALTER TABLE approvals DROP COLUMN IF EXISTS visit_distance_meters;
+ALTER TABLE approvals DROP COLUMN IF EXISTS visit_proof_longitude;
+ALTER TABLE approvals DROP COLUMN IF EXISTS visit_proof_latitude;
+ALTER TABLE approvals DROP COLUMN IF EXISTS visit_proof_captured_at;
+
+ALTER TABLE file_uploads DROP COLUMN IF EXISTS longitude;
+ALTER TABLE file_uploads DROP COLUMN IF EXISTS latitude;
+ALTER TABLE file_uploads DROP COLUMN IF EXISTS captured_at;
+
+ALTER TABLE borrowers DROP COLUMN IF EXISTS longitude;
+ALTER TABLE borrowers DROP COLUMN IF EXISTS latitude;
@@ -0,0 +1,16 @@
+-- Migration Up: Record where and when visit proof photos were taken
+-- File: 017_add_visit_proof_metadata.up.sql
+
+-- Geocoded position of the borrower's address; visit proofs are checked against it
+ALTER TABLE borrowers ADD COLUMN latitude DOUBLE PRECISION;
+ALTER TABLE borrowers ADD COLUMN longitude DOUBLE PRECISION;
+
+-- EXIF capture time and GPS position of uploaded photos
+ALTER TABLE file_uploads ADD COLUMN captured_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE file_uploads ADD COLUMN latitude DOUBLE PRECISION;
+ALTER TABLE file_uploads ADD COLUMN longitude DOUBLE PRECISION;
+
+ALTER TABLE approvals ADD COLUMN visit_proof_captured_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE approvals ADD COLUMN visit_proof_latitude DOUBLE PRECISION;
+ALTER TABLE approvals ADD COLUMN visit_proof_longitude DOUBLE PRECISION;
+ALTER TABLE approvals ADD COLUMN visit_distance_meters DOUBLE PRECISION;
@@ -0,0 +1,8 @@
+-- Migration Down: Drop resized copies of uploaded images
+-- File: 018_create_file_variants.down.sql
+
+-- Drop indexes first
+DROP INDEX IF EXISTS idx_file_variants_deleted_at;
+
+-- Drop tables
+DROP TABLE IF EXISTS file_variants;
@@ -0,0 +1,25 @@
+-- Migration Up: Store resized copies of uploaded images
+-- File: 018_create_file_variants.up.sql
+
+-- Create file_variants table (a thumbnail or preview derived from an uploaded image)
+CREATE TABLE file_variants (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    file_upload_id UUID NOT NULL,
+    variant VARCHAR(20) NOT NULL,
+    file_path TEXT NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    file_size BIGINT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    checksum VARCHAR(64),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE,
+
+    CONSTRAINT fk_file_variants_file_upload_id FOREIGN KEY (file_upload_id) REFERENCES file_uploads(id) ON DELETE CASCADE,
+    CONSTRAINT chk_file_variant CHECK (variant IN ('thumbnail', 'preview')),
+    CONSTRAINT uq_file_variants_upload_variant UNIQUE (file_upload_id, variant)
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_file_variants_deleted_at ON file_variants(deleted_at);
@@ -0,0 +1,7 @@
+-- Migration Down: Stop versioning documents
+-- File: 019_add_file_upload_versions.down.sql
+
+ALTER TABLE file_uploads DROP CONSTRAINT IF EXISTS uq_file_uploads_document_version;
+ALTER TABLE file_uploads DROP COLUMN IF EXISTS replaces_id;
+ALTER TABLE file_uploads DROP COLUMN IF EXISTS version;
+ALTER TABLE file_uploads DROP COLUMN IF EXISTS document_id;
@@ -0,0 +1,12 @@
+-- Migration Up: Chain replacement uploads into document versions
+-- File: 019_add_file_upload_versions.up.sql
+
+-- Every version of a document shares the document_id of its first version
+ALTER TABLE file_uploads ADD COLUMN document_id UUID;
+ALTER TABLE file_uploads ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE file_uploads ADD COLUMN replaces_id UUID REFERENCES file_uploads(id);
+
+UPDATE file_uploads SET document_id = id;
+ALTER TABLE file_uploads ALTER COLUMN document_id SET NOT NULL;
+
+ALTER TABLE file_uploads ADD CONSTRAINT uq_file_uploads_document_version UNIQUE (document_id, version);
@@ -0,0 +1,14 @@
+-- Migration Down: Drop localized email templates
+-- File: 020_create_email_templates.down.sql
+
+-- Drop indexes first
+DROP INDEX IF EXISTS idx_email_templates_deleted_at;
+
+-- Drop tables
+DROP TABLE IF EXISTS email_templates;
+
+ALTER TABLE email_notifications DROP COLUMN IF EXISTS locale;
+ALTER TABLE email_notifications DROP COLUMN IF EXISTS email_html;
+
+ALTER TABLE investors DROP CONSTRAINT IF EXISTS chk_investor_locale;
+ALTER TABLE investors DROP COLUMN IF EXISTS locale;
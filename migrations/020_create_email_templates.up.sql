@@ -0,0 +1,30 @@
+-- Migration Up: Localized email templates
+-- File: 020_create_email_templates.up.sql
+
+-- Emails are sent in the investor's locale
+ALTER TABLE investors ADD COLUMN locale VARCHAR(10) NOT NULL DEFAULT 'id-ID';
+ALTER TABLE investors ADD CONSTRAINT chk_investor_locale CHECK (locale IN ('id-ID', 'en-US'));
+
+-- Keep what was sent: the text body stays in email_body
+ALTER TABLE email_notifications ADD COLUMN email_html TEXT;
+ALTER TABLE email_notifications ADD COLUMN locale VARCHAR(10);
+
+-- Create email_templates table (versioned templates overriding the template files)
+CREATE TABLE email_templates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL,
+    locale VARCHAR(10) NOT NULL,
+    version VARCHAR(20) NOT NULL,
+    subject TEXT NOT NULL,
+    html_body TEXT,
+    text_body TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE,
+
+    CONSTRAINT chk_email_template_locale CHECK (locale IN ('id-ID', 'en-US')),
+    CONSTRAINT uq_email_templates_name_locale_version UNIQUE (name, locale, version)
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_email_templates_deleted_at ON email_templates(deleted_at);
@@ -0,0 +1,20 @@
+-- Migration Down: Stop retrying email notifications
+-- File: 021_add_email_delivery_attempts.down.sql
+
+-- Drop indexes first
+DROP INDEX IF EXISTS idx_email_notifications_status;
+DROP INDEX IF EXISTS idx_email_notifications_retry;
+
+-- Drop tables
+DROP TABLE IF EXISTS email_delivery_attempts;
+
+UPDATE email_notifications SET status = 'failed' WHERE status = 'pending';
+ALTER TABLE email_notifications DROP CONSTRAINT IF EXISTS chk_email_status;
+ALTER TABLE email_notifications ADD CONSTRAINT chk_email_status CHECK (status IN ('sent', 'delivered', 'opened', 'failed'));
+
+UPDATE email_notifications SET sent_at = COALESCE(last_attempt_at, created_at) WHERE sent_at IS NULL;
+ALTER TABLE email_notifications ALTER COLUMN sent_at SET NOT NULL;
+ALTER TABLE email_notifications DROP COLUMN IF EXISTS next_attempt_at;
+ALTER TABLE email_notifications DROP COLUMN IF EXISTS last_attempt_at;
+ALTER TABLE email_notifications DROP COLUMN IF EXISTS attempts;
+ALTER TABLE email_notifications DROP COLUMN IF EXISTS recipient;
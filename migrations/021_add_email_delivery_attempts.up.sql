@@ -0,0 +1,35 @@
+-- Migration Up: Retry email notifications with backoff and record every delivery attempt
+-- File: 021_add_email_delivery_attempts.up.sql
+
+-- Emails are queued as pending and sent_at is set once one is delivered
+ALTER TABLE email_notifications ADD COLUMN recipient VARCHAR(255);
+ALTER TABLE email_notifications ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE email_notifications ADD COLUMN last_attempt_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE email_notifications ADD COLUMN next_attempt_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE email_notifications ALTER COLUMN sent_at DROP NOT NULL;
+
+UPDATE email_notifications SET attempts = 1, last_attempt_at = sent_at;
+UPDATE email_notifications n SET recipient = i.email FROM investors i WHERE i.id = n.investor_id;
+
+ALTER TABLE email_notifications DROP CONSTRAINT IF EXISTS chk_email_status;
+ALTER TABLE email_notifications ADD CONSTRAINT chk_email_status CHECK (status IN ('pending', 'sent', 'delivered', 'opened', 'failed'));
+
+-- Create email_delivery_attempts table (one row per try to send a notification)
+CREATE TABLE email_delivery_attempts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    notification_id UUID NOT NULL,
+    attempt_number INTEGER NOT NULL,
+    attempted_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    succeeded BOOLEAN NOT NULL,
+    error_message TEXT,
+    requested_by UUID,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT fk_email_delivery_attempts_notification FOREIGN KEY (notification_id) REFERENCES email_notifications(id) ON DELETE CASCADE,
+    CONSTRAINT fk_email_delivery_attempts_requested_by FOREIGN KEY (requested_by) REFERENCES employees(id),
+    CONSTRAINT uq_email_delivery_attempts_number UNIQUE (notification_id, attempt_number)
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_email_notifications_retry ON email_notifications(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_email_notifications_status ON email_notifications(status);
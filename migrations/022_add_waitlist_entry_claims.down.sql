@@ -0,0 +1,15 @@
+-- Migration Down: Stop claiming waitlist entries in the database
+-- File: 022_add_waitlist_entry_claims.down.sql
+
+-- Drop indexes first
+DROP INDEX IF EXISTS idx_loan_waitlist_entries_waiting_investor;
+DROP INDEX IF EXISTS idx_loan_waitlist_entries_queue;
+
+UPDATE loan_waitlist_entries SET status = 'waiting' WHERE status = 'processing';
+ALTER TABLE loan_waitlist_entries DROP CONSTRAINT IF EXISTS chk_loan_waitlist_status;
+ALTER TABLE loan_waitlist_entries ADD CONSTRAINT chk_loan_waitlist_status CHECK (status IN ('waiting', 'allocated', 'failed', 'closed'));
+
+CREATE INDEX idx_loan_waitlist_entries_queue ON loan_waitlist_entries(loan_id, created_at) WHERE status = 'waiting' AND deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_loan_waitlist_entries_waiting_investor ON loan_waitlist_entries(loan_id, investor_id) WHERE status = 'waiting' AND deleted_at IS NULL;
+
+ALTER TABLE loan_waitlist_entries DROP COLUMN IF EXISTS claimed_until;
@@ -0,0 +1,15 @@
+-- Migration Up: Claim waitlist entries in the database while they are served
+-- File: 022_add_waitlist_entry_claims.up.sql
+
+-- An entry being served is 'processing' until claimed_until; a run that dies leaves it there
+ALTER TABLE loan_waitlist_entries ADD COLUMN claimed_until TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE loan_waitlist_entries DROP CONSTRAINT IF EXISTS chk_loan_waitlist_status;
+ALTER TABLE loan_waitlist_entries ADD CONSTRAINT chk_loan_waitlist_status CHECK (status IN ('waiting', 'processing', 'allocated', 'failed', 'closed'));
+
+-- The entry being served keeps its place in the queue and still counts as its investor's wait
+DROP INDEX IF EXISTS idx_loan_waitlist_entries_queue;
+CREATE INDEX idx_loan_waitlist_entries_queue ON loan_waitlist_entries(loan_id, created_at) WHERE status IN ('waiting', 'processing') AND deleted_at IS NULL;
+
+DROP INDEX IF EXISTS idx_loan_waitlist_entries_waiting_investor;
+CREATE UNIQUE INDEX idx_loan_waitlist_entries_waiting_investor ON loan_waitlist_entries(loan_id, investor_id) WHERE status IN ('waiting', 'processing') AND deleted_at IS NULL;
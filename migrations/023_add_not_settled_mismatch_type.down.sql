@@ -0,0 +1,8 @@
+-- Migration Down: Record settlement records the provider reported as not settled
+-- File: 023_add_not_settled_mismatch_type.down.sql
+
+-- A payment the provider did not settle is closest to one missing from the report
+UPDATE reconciliation_mismatches SET mismatch_type = 'missing_in_report' WHERE mismatch_type = 'not_settled';
+
+ALTER TABLE reconciliation_mismatches DROP CONSTRAINT chk_mismatch_type;
+ALTER TABLE reconciliation_mismatches ADD CONSTRAINT chk_mismatch_type CHECK (mismatch_type IN ('missing_in_report', 'missing_in_records', 'duplicate', 'amount_mismatch'));
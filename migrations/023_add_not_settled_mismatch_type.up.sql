@@ -0,0 +1,5 @@
+-- Migration Up: Record settlement records the provider reported as not settled
+-- File: 023_add_not_settled_mismatch_type.up.sql
+
+ALTER TABLE reconciliation_mismatches DROP CONSTRAINT chk_mismatch_type;
+ALTER TABLE reconciliation_mismatches ADD CONSTRAINT chk_mismatch_type CHECK (mismatch_type IN ('missing_in_report', 'missing_in_records', 'duplicate', 'amount_mismatch', 'not_settled'));
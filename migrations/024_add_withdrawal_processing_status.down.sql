@@ -0,0 +1,8 @@
+-- Migration Down: Mark withdrawals whose payout was sent before the hold is settled
+-- File: 024_add_withdrawal_processing_status.down.sql
+
+-- Approved was the in-between status before payouts were sent outside the transaction
+UPDATE withdrawals SET status = 'approved' WHERE status = 'processing';
+
+ALTER TABLE withdrawals DROP CONSTRAINT chk_withdrawal_status;
+ALTER TABLE withdrawals ADD CONSTRAINT chk_withdrawal_status CHECK (status IN ('pending_approval', 'approved', 'completed', 'rejected', 'failed'));
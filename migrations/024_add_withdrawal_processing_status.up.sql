@@ -0,0 +1,5 @@
+-- Migration Up: Mark withdrawals whose payout was sent before the hold is settled
+-- File: 024_add_withdrawal_processing_status.up.sql
+
+ALTER TABLE withdrawals DROP CONSTRAINT chk_withdrawal_status;
+ALTER TABLE withdrawals ADD CONSTRAINT chk_withdrawal_status CHECK (status IN ('pending_approval', 'approved', 'processing', 'completed', 'rejected', 'failed'));
@@ -0,0 +1,6 @@
+-- Migration Down: Record wallet top ups before the investor is charged
+-- File: 025_create_wallet_top_ups.down.sql
+
+DROP INDEX IF EXISTS idx_wallet_transactions_top_up;
+
+DROP TABLE IF EXISTS wallet_top_ups;
@@ -0,0 +1,29 @@
+-- Migration Up: Record wallet top ups before the investor is charged
+-- File: 025_create_wallet_top_ups.up.sql
+
+-- Create wallet_top_ups table (a top up is recorded before the charge so every charge has a record to reconcile)
+CREATE TABLE wallet_top_ups (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    investor_id UUID NOT NULL,
+    amount DECIMAL(15,2) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    transaction_id VARCHAR(255),
+    failure_reason TEXT,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE,
+
+
+    CONSTRAINT fk_wallet_top_ups_investor FOREIGN KEY (investor_id) REFERENCES investors(id),
+    CONSTRAINT chk_wallet_top_up_amount CHECK (amount > 0),
+    CONSTRAINT chk_wallet_top_up_status CHECK (status IN ('pending', 'charged', 'completed', 'failed'))
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_wallet_top_ups_investor_id ON wallet_top_ups(investor_id);
+CREATE INDEX idx_wallet_top_ups_status ON wallet_top_ups(status);
+CREATE INDEX idx_wallet_top_ups_created_at ON wallet_top_ups(created_at);
+
+-- A top up is credited to the wallet only once
+CREATE UNIQUE INDEX idx_wallet_transactions_top_up ON wallet_transactions(reference_id) WHERE reference_type = 'top_up';
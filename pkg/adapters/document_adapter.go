@@ -0,0 +1,157 @@
+// pkg/adapters/document_adapter.go
+package adapters
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"loan-service/internal/models"
+	"loan-service/pkg/config"
+	"loan-service/pkg/logger"
+	"loan-service/pkg/pdf"
+
+	"github.com/google/uuid"
+)
+
+// DefaultAgreementTemplateVersion is used when no template version is configured
+const DefaultAgreementTemplateVersion = "v1"
+
+//go:embed templates/agreements/*.tmpl
+var agreementTemplates embed.FS
+
+type DocumentAdapter struct {
+	config config.DocumentConfig
+	logger *logger.Logger
+}
+
+func NewDocumentAdapter(cfg config.DocumentConfig, logger *logger.Logger) DocumentAdapterInterface {
+	return &DocumentAdapter{
+		config: cfg,
+		logger: logger,
+	}
+}
+
+// GenerateLoanAgreement renders the configured agreement template version into a PDF
+func (a *DocumentAdapter) GenerateLoanAgreement(data *models.LoanAgreementData) (*models.GeneratedDocument, error) {
+	version := a.templateVersion()
+	title := fmt.Sprintf("Loan Agreement %s", data.Loan.ID.String()[:8])
+
+	content, err := renderAgreementPDF("loan_agreement_"+version+".tmpl", title, data)
+	if err != nil {
+		return nil, err
+	}
+
+	a.logger.Info("Generated loan agreement", map[string]interface{}{
+		"loan_id":          data.Loan.ID.String(),
+		"template_version": version,
+		"investments":      len(data.Investments),
+	})
+
+	return &models.GeneratedDocument{
+		FileName:        fmt.Sprintf("agreement_loan_%s_%s.pdf", data.Loan.ID.String(), version),
+		ContentType:     "application/pdf",
+		Content:         content,
+		TemplateVersion: version,
+	}, nil
+}
+
+// GenerateInvestmentAgreement renders a single investor's agreement for their share of a loan
+func (a *DocumentAdapter) GenerateInvestmentAgreement(data *models.InvestmentAgreementData) (*models.GeneratedDocument, error) {
+	version := a.templateVersion()
+	title := fmt.Sprintf("Investment Agreement %s", data.Investment.InvestmentID.String()[:8])
+
+	content, err := renderAgreementPDF("investment_agreement_"+version+".tmpl", title, data)
+	if err != nil {
+		return nil, err
+	}
+
+	a.logger.Info("Generated investment agreement", map[string]interface{}{
+		"loan_id":          data.Loan.ID.String(),
+		"investment_id":    data.Investment.InvestmentID.String(),
+		"template_version": version,
+	})
+
+	return &models.GeneratedDocument{
+		FileName:        fmt.Sprintf("agreement_investment_%s_%s.pdf", data.Investment.InvestmentID.String(), version),
+		ContentType:     "application/pdf",
+		Content:         content,
+		TemplateVersion: version,
+	}, nil
+}
+
+// GenerateTransferAgreement renders the agreement between the seller and buyer of an investment on the secondary market
+func (a *DocumentAdapter) GenerateTransferAgreement(data *models.TransferAgreementData) (*models.GeneratedDocument, error) {
+	version := a.templateVersion()
+	title := fmt.Sprintf("Transfer Agreement %s", data.Transfer.ID.String()[:8])
+
+	content, err := renderAgreementPDF("transfer_agreement_"+version+".tmpl", title, data)
+	if err != nil {
+		return nil, err
+	}
+
+	a.logger.Info("Generated transfer agreement", map[string]interface{}{
+		"transfer_id":      data.Transfer.ID.String(),
+		"loan_id":          data.Loan.ID.String(),
+		"template_version": version,
+	})
+
+	return &models.GeneratedDocument{
+		FileName:        fmt.Sprintf("transfer_agreement_%s_%s.pdf", data.Transfer.ID.String(), version),
+		ContentType:     "application/pdf",
+		Content:         content,
+		TemplateVersion: version,
+	}, nil
+}
+
+func (a *DocumentAdapter) templateVersion() string {
+	if a.config.AgreementTemplateVersion == "" {
+		return DefaultAgreementTemplateVersion
+	}
+	return a.config.AgreementTemplateVersion
+}
+
+// renderAgreementPDF lays out a rendered template: lines starting with "# " become headings, every other line a paragraph
+func renderAgreementPDF(name, title string, data interface{}) ([]byte, error) {
+	text, err := renderAgreementTemplate(name, data)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := pdf.NewDocument(title)
+	for _, line := range strings.Split(text, "\n") {
+		if heading, ok := strings.CutPrefix(line, "# "); ok {
+			doc.Paragraph("")
+			doc.Heading(heading)
+			continue
+		}
+		doc.Paragraph(line)
+	}
+
+	return doc.Bytes(), nil
+}
+
+var agreementTemplateFuncs = template.FuncMap{
+	"money":   func(amount float64) string { return fmt.Sprintf("IDR %.2f", amount) },
+	"percent": func(rate float64) string { return fmt.Sprintf("%.2f%%", rate*100) },
+	"date":    func(t time.Time) string { return t.Format("2 January 2006") },
+	"shortID": func(id uuid.UUID) string { return strings.ToUpper(id.String()[:8]) },
+	"inc":     func(i int) int { return i + 1 },
+}
+
+func renderAgreementTemplate(name string, data interface{}) (string, error) {
+	tmpl, err := template.New(name).Funcs(agreementTemplateFuncs).ParseFS(agreementTemplates, "templates/agreements/"+name)
+	if err != nil {
+		return "", fmt.Errorf("failed to load agreement template %s: %w", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render agreement template %s: %w", name, err)
+	}
+
+	return buf.String(), nil
+}
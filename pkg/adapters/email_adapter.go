@@ -2,53 +2,145 @@
 package adapters
 
 import (
+	"bytes"
+	"embed"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
 	"net/smtp"
+	"net/textproto"
+	"os"
 
 	"loan-service/internal/models"
 	"loan-service/pkg/config"
 	"loan-service/pkg/logger"
+	"loan-service/pkg/mailtemplate"
 )
 
+// DefaultEmailTemplateVersion is used when no email template version is configured
+const DefaultEmailTemplateVersion = "v1"
+
+const (
+	EmailTemplateSourceFiles    = "files"
+	EmailTemplateSourceDatabase = "database"
+)
+
+// Email template names
+const (
+	EmailTemplateInvestmentAgreement   = "investment_agreement"
+	EmailTemplateWaitlistUpdate        = "waitlist_update"
+	EmailTemplateReconciliationSummary = "reconciliation_summary"
+)
+
+//go:embed templates/emails
+var emailTemplates embed.FS
+
 type EmailAdapter struct {
-	config config.EmailConfig
-	logger *logger.Logger
+	config    config.EmailConfig
+	templates *mailtemplate.Engine
+	logger    *logger.Logger
 }
 
-func NewEmailAdapter(cfg config.EmailConfig, logger *logger.Logger) EmailAdapterInterface {
+func NewEmailAdapter(cfg config.EmailConfig, templates mailtemplate.Source, logger *logger.Logger) EmailAdapterInterface {
+	version := cfg.TemplateVersion
+	if version == "" {
+		version = DefaultEmailTemplateVersion
+	}
+
 	return &EmailAdapter{
-		config: cfg,
-		logger: logger,
+		config:    cfg,
+		templates: mailtemplate.NewEngine(templates, version, cfg.DefaultLocale),
+		logger:    logger,
+	}
+}
+
+// EmailTemplateStore is the database side of the email templates
+type EmailTemplateStore interface {
+	GetEmailTemplate(name, locale, version string) (*models.EmailTemplate, error)
+}
+
+// NewEmailTemplateSource loads email templates from the built-in files, or from template_dir when it is set. The
+// database source reads the email_templates table and falls back to the files for what it does not override.
+func NewEmailTemplateSource(cfg config.EmailConfig, store EmailTemplateStore) (mailtemplate.Source, error) {
+	var files mailtemplate.Source
+	if cfg.TemplateDir != "" {
+		files = mailtemplate.NewFSSource(os.DirFS(cfg.TemplateDir))
+	} else {
+		builtIn, err := fs.Sub(emailTemplates, "templates/emails")
+		if err != nil {
+			return nil, err
+		}
+		files = mailtemplate.NewFSSource(builtIn)
+	}
+
+	switch cfg.TemplateSource {
+	case "", EmailTemplateSourceFiles:
+		return files, nil
+	case EmailTemplateSourceDatabase:
+		if store == nil {
+			return nil, errors.New("database email templates need a template store")
+		}
+		return mailtemplate.Fallback{&databaseTemplateSource{store: store}, files}, nil
+	default:
+		return nil, fmt.Errorf("unsupported email template source: %s", cfg.TemplateSource)
 	}
 }
 
-func (a *EmailAdapter) SendEmail(to, subject, body string) error {
+type databaseTemplateSource struct {
+	store EmailTemplateStore
+}
+
+func (s *databaseTemplateSource) Load(name, locale, version string) (*mailtemplate.Template, error) {
+	tmpl, err := s.store.GetEmailTemplate(name, locale, version)
+	if err != nil {
+		return nil, err
+	}
+	if tmpl == nil {
+		return nil, mailtemplate.ErrTemplateNotFound
+	}
+
+	return &mailtemplate.Template{
+		Subject: tmpl.Subject,
+		HTML:    tmpl.HTMLBody,
+		Text:    tmpl.TextBody,
+	}, nil
+}
+
+func (a *EmailAdapter) SendEmail(to string, message *models.EmailMessage) error {
 	a.logger.Debug("Sending email", map[string]interface{}{
 		"to":       to,
-		"subject":  subject,
+		"subject":  message.Subject,
+		"locale":   message.Locale,
 		"provider": a.config.Provider,
 	})
 
 	switch a.config.Provider {
 	case "smtp":
-		return a.sendSMTP(to, subject, body)
+		return a.sendSMTP(to, message)
 	case "sendgrid":
-		return a.sendSendGrid(to, subject, body)
+		return a.sendSendGrid(to, message)
 	case "mock":
-		return a.sendMock(to, subject, body)
+		return a.sendMock(to, message)
 	default:
 		return fmt.Errorf("unsupported email provider: %s", a.config.Provider)
 	}
 }
 
-func (a *EmailAdapter) sendSMTP(to, subject, body string) error {
+func (a *EmailAdapter) sendSMTP(to string, message *models.EmailMessage) error {
 	auth := smtp.PlainAuth("", a.config.SMTPUsername, a.config.SMTPPassword, a.config.SMTPHost)
 
-	msg := fmt.Sprintf("To: %s\r\nSubject: %s\r\n\r\n%s", to, subject, body)
+	msg, err := buildMIMEMessage(a.config.FromAddress, to, message)
+	if err != nil {
+		return err
+	}
 
 	addr := fmt.Sprintf("%s:%d", a.config.SMTPHost, a.config.SMTPPort)
 
-	err := smtp.SendMail(addr, auth, a.config.FromAddress, []string{to}, []byte(msg))
+	err = smtp.SendMail(addr, auth, a.config.FromAddress, []string{to}, msg)
 	if err != nil {
 		a.logger.Error("Failed to send SMTP email", map[string]interface{}{
 			"to":    to,
@@ -59,68 +151,159 @@ func (a *EmailAdapter) sendSMTP(to, subject, body string) error {
 
 	a.logger.Info("SMTP email sent successfully", map[string]interface{}{
 		"to":      to,
-		"subject": subject,
+		"subject": message.Subject,
 	})
 
 	return nil
 }
 
-func (a *EmailAdapter) sendSendGrid(to, subject, body string) error {
+func (a *EmailAdapter) sendSendGrid(to string, message *models.EmailMessage) error {
 	// Implementation for SendGrid API
 	a.logger.Info("SendGrid email sent (mock)", map[string]interface{}{
 		"to":      to,
-		"subject": subject,
+		"subject": message.Subject,
 		"api_key": a.config.APIKey,
 	})
 	return nil
 }
 
-func (a *EmailAdapter) sendMock(to, subject, body string) error {
+func (a *EmailAdapter) sendMock(to string, message *models.EmailMessage) error {
 	a.logger.Info("Mock email sent", map[string]interface{}{
-		"to":      to,
-		"subject": subject,
-		"body":    body,
+		"to":       to,
+		"subject":  message.Subject,
+		"locale":   message.Locale,
+		"body":     message.TextBody,
+		"has_html": message.HTMLBody != "",
 	})
 	return nil
 }
 
-// GenerateAgreementEmailBody generates the email body for investment agreements
-func (a *EmailAdapter) GenerateAgreementEmailBody(
+// buildMIMEMessage writes the email as multipart/alternative, text first so clients prefer the HTML; without HTML
+// it is plain text
+func buildMIMEMessage(from, to string, message *models.EmailMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", to)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+
+	if message.HTMLBody == "" {
+		buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeQuotedPrintable(&buf, message.TextBody); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	parts := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", parts.Boundary())
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", message.TextBody},
+		{"text/html; charset=utf-8", message.HTMLBody},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+type agreementEmailData struct {
+	Investor     *models.Investor
+	Investment   *models.Investment
+	Loan         *models.Loan
+	Borrower     *models.Borrower
+	AgreementURL string
+}
+
+// GenerateAgreementEmail renders the investment agreement email in the investor's locale
+func (a *EmailAdapter) GenerateAgreementEmail(
 	investment *models.Investment,
 	loan *models.Loan,
 	investor *models.Investor,
 	borrower *models.Borrower,
-) string {
-	return fmt.Sprintf(`Dear %s,
-
-Your investment in Loan #%s has been successfully processed.
-
-Investment Details:
-- Investment Amount: Rp %.2f
-- Expected Return: Rp %.2f
-- Investment Date: %s
-
-Loan Details:
-- Borrower: %s
-- Principal Amount: Rp %.2f
-- Interest Rate: %.2f%%
-- ROI: %.2f%%
-
-Agreement Letter: %s
-
-Please review the agreement letter and contact us if you have any questions.
-
-Best regards,
-Go10 Team`,
-		investor.Name,
-		loan.ID.String()[:8],
-		investment.Amount,
-		investment.ExpectedReturn,
-		investment.InvestmentDate.Format("January 2, 2006"),
-		borrower.FullName(),
-		loan.PrincipalAmount,
-		loan.InterestRate*100,
-		loan.ROI*100,
-		loan.AgreementLetterURL,
-	)
+) (*models.EmailMessage, error) {
+	// Investments from before per-investor agreements only have the loan's letter
+	agreementURL := investment.AgreementURL
+	if agreementURL == "" {
+		agreementURL = loan.AgreementLetterURL
+	}
+
+	return a.render(EmailTemplateInvestmentAgreement, investor.Locale, &agreementEmailData{
+		Investor:     investor,
+		Investment:   investment,
+		Loan:         loan,
+		Borrower:     borrower,
+		AgreementURL: agreementURL,
+	})
+}
+
+type reconciliationEmailData struct {
+	Run        *models.ReconciliationRun
+	Mismatches []*models.ReconciliationMismatch
+}
+
+// GenerateReconciliationSummary renders the ops summary email for a reconciliation run in the default locale
+func (a *EmailAdapter) GenerateReconciliationSummary(run *models.ReconciliationRun, mismatches []*models.ReconciliationMismatch) (*models.EmailMessage, error) {
+	return a.render(EmailTemplateReconciliationSummary, a.config.DefaultLocale, &reconciliationEmailData{
+		Run:        run,
+		Mismatches: mismatches,
+	})
+}
+
+type waitlistEmailData struct {
+	Investor  *models.Investor
+	Entry     *models.WaitlistEntry
+	Loan      *models.Loan
+	Allocated bool
+}
+
+// GenerateWaitlistEmail renders the email telling a waitlisted investor how their entry was served
+func (a *EmailAdapter) GenerateWaitlistEmail(entry *models.WaitlistEntry, loan *models.Loan, investor *models.Investor) (*models.EmailMessage, error) {
+	return a.render(EmailTemplateWaitlistUpdate, investor.Locale, &waitlistEmailData{
+		Investor:  investor,
+		Entry:     entry,
+		Loan:      loan,
+		Allocated: entry.Status == models.WaitlistStatusAllocated && entry.AllocatedAmount != nil,
+	})
+}
+
+func (a *EmailAdapter) render(name, locale string, data interface{}) (*models.EmailMessage, error) {
+	rendered, err := a.templates.Render(name, locale, data)
+	if err != nil {
+		a.logger.Error("Failed to render email", map[string]interface{}{
+			"template": name,
+			"locale":   locale,
+			"error":    err.Error(),
+		})
+		return nil, err
+	}
+
+	return &models.EmailMessage{
+		Locale:   rendered.Locale,
+		Subject:  rendered.Subject,
+		TextBody: rendered.Text,
+		HTMLBody: rendered.HTML,
+	}, nil
 }
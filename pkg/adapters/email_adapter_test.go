@@ -0,0 +1,181 @@
+package adapters
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"testing"
+	"time"
+
+	"loan-service/internal/models"
+	"loan-service/pkg/config"
+	"loan-service/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEmailAdapter(t *testing.T, cfg config.EmailConfig, store EmailTemplateStore) *EmailAdapter {
+	t.Helper()
+	source, err := NewEmailTemplateSource(cfg, store)
+	require.NoError(t, err)
+	return NewEmailAdapter(cfg, source, logger.NewLogger(config.LoggerConfig{Level: "error"})).(*EmailAdapter)
+}
+
+func testAgreementEmailData(locale string) (*models.Investment, *models.Loan, *models.Investor, *models.Borrower) {
+	loan := &models.Loan{
+		BaseModel:          models.BaseModel{ID: uuid.MustParse("1a2b3c4d-0000-0000-0000-000000000000")},
+		PrincipalAmount:    50000000,
+		InterestRate:       0.12,
+		ROI:                0.1,
+		AgreementLetterURL: "https://files.example.com/loan.pdf",
+	}
+	investment := &models.Investment{
+		Amount:         2500000,
+		ExpectedReturn: 2750000,
+		InvestmentDate: time.Date(2025, time.March, 5, 0, 0, 0, 0, time.UTC),
+	}
+	investor := &models.Investor{Name: "Siti", Email: "siti@example.com", Locale: locale}
+	borrower := &models.Borrower{FirstName: "Budi", LastName: "Santoso"}
+	return investment, loan, investor, borrower
+}
+
+func TestEmailAdapter_GenerateAgreementEmail_PerLocale(t *testing.T) {
+	adapter := newTestEmailAdapter(t, config.EmailConfig{}, nil)
+
+	message, err := adapter.GenerateAgreementEmail(testAgreementEmailData("id-ID"))
+	require.NoError(t, err)
+	assert.Equal(t, "id-ID", message.Locale)
+	assert.Equal(t, "Perjanjian Investasi - Pinjaman #1a2b3c4d", message.Subject)
+	assert.Contains(t, message.TextBody, "Jumlah Investasi: Rp 2.500.000,00")
+	assert.Contains(t, message.TextBody, "Tanggal Investasi: 5 Maret 2025")
+	assert.Contains(t, message.TextBody, "Suku Bunga: 12,00%")
+	assert.Contains(t, message.HTMLBody, `<a href="https://files.example.com/loan.pdf">`)
+
+	message, err = adapter.GenerateAgreementEmail(testAgreementEmailData("en-US"))
+	require.NoError(t, err)
+	assert.Equal(t, "en-US", message.Locale)
+	assert.Equal(t, "Investment Agreement - Loan #1a2b3c4d", message.Subject)
+	assert.Contains(t, message.TextBody, "Investment Amount: IDR 2,500,000.00")
+	assert.Contains(t, message.TextBody, "Investment Date: March 5, 2025")
+	assert.Contains(t, message.HTMLBody, "Budi Santoso")
+}
+
+func TestEmailAdapter_GenerateWaitlistEmail(t *testing.T) {
+	adapter := newTestEmailAdapter(t, config.EmailConfig{}, nil)
+	loan := &models.Loan{BaseModel: models.BaseModel{ID: uuid.New()}, ROI: 0.1}
+	investor := &models.Investor{Name: "Siti", Locale: "en-US"}
+
+	allocated := 1500000.0
+	message, err := adapter.GenerateWaitlistEmail(&models.WaitlistEntry{
+		Amount: 2000000, AllocatedAmount: &allocated, Status: models.WaitlistStatusAllocated,
+	}, loan, investor)
+	require.NoError(t, err)
+	assert.Contains(t, message.TextBody, "Invested Amount: IDR 1,500,000.00")
+	assert.NotEmpty(t, message.HTMLBody)
+
+	// An investor without a locale gets the default one
+	investor.Locale = ""
+	message, err = adapter.GenerateWaitlistEmail(&models.WaitlistEntry{
+		Amount: 2000000, Status: models.WaitlistStatusFailed, FailureReason: "saldo tidak cukup",
+	}, loan, investor)
+	require.NoError(t, err)
+	assert.Equal(t, "id-ID", message.Locale)
+	assert.Contains(t, message.TextBody, "Alasan: saldo tidak cukup")
+}
+
+func TestEmailAdapter_GenerateReconciliationSummary(t *testing.T) {
+	adapter := newTestEmailAdapter(t, config.EmailConfig{DefaultLocale: "en-US"}, nil)
+	expected := 100000.0
+
+	message, err := adapter.GenerateReconciliationSummary(&models.ReconciliationRun{
+		ReportDate:    time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC),
+		Status:        "completed",
+		MismatchCount: 1,
+	}, []*models.ReconciliationMismatch{{MismatchType: "missing", TransactionID: "TX-1", ExpectedAmount: &expected}})
+
+	require.NoError(t, err)
+	assert.Equal(t, "Settlement Reconciliation 2025-06-01 - completed (1 mismatches)", message.Subject)
+	assert.Contains(t, message.TextBody, "- [missing] TX-1 expected IDR 100,000.00\n")
+	assert.Empty(t, message.HTMLBody)
+}
+
+type fakeTemplateStore map[string]*models.EmailTemplate
+
+func (s fakeTemplateStore) GetEmailTemplate(name, locale, version string) (*models.EmailTemplate, error) {
+	return s[version+"/"+locale+"/"+name], nil
+}
+
+func TestEmailAdapter_DatabaseTemplatesOverrideFiles(t *testing.T) {
+	store := fakeTemplateStore{"v1/en-US/investment_agreement": {
+		Subject:  "Your agreement for {{ shortID .Loan.ID }}",
+		TextBody: "Hello {{ .Investor.Name }}",
+	}}
+	adapter := newTestEmailAdapter(t, config.EmailConfig{TemplateSource: EmailTemplateSourceDatabase}, store)
+
+	message, err := adapter.GenerateAgreementEmail(testAgreementEmailData("en-US"))
+	require.NoError(t, err)
+	assert.Equal(t, "Your agreement for 1a2b3c4d", message.Subject)
+	assert.Equal(t, "Hello Siti", message.TextBody)
+
+	// Templates the database does not override come from the files
+	message, err = adapter.GenerateAgreementEmail(testAgreementEmailData("id-ID"))
+	require.NoError(t, err)
+	assert.Equal(t, "Perjanjian Investasi - Pinjaman #1a2b3c4d", message.Subject)
+}
+
+func TestBuildMIMEMessage_MultipartAlternative(t *testing.T) {
+	raw, err := buildMIMEMessage("noreply@example.com", "siti@example.com", &models.EmailMessage{
+		Subject:  "Perjanjian Investasi – Pinjaman",
+		TextBody: "Jumlah Investasi: Rp 2.500.000,00",
+		HTMLBody: "<p>Jumlah Investasi: <strong>Rp 2.500.000,00</strong></p>",
+	})
+	require.NoError(t, err)
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	require.NoError(t, err)
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Perjanjian Investasi – Pinjaman", subject)
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	var contentTypes, bodies []string
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		body, err := io.ReadAll(quotedprintable.NewReader(part))
+		require.NoError(t, err)
+		contentTypes = append(contentTypes, part.Header.Get("Content-Type"))
+		bodies = append(bodies, string(body))
+	}
+
+	assert.Equal(t, []string{"text/plain; charset=utf-8", "text/html; charset=utf-8"}, contentTypes)
+	assert.Equal(t, "Jumlah Investasi: Rp 2.500.000,00", bodies[0])
+	assert.Equal(t, "<p>Jumlah Investasi: <strong>Rp 2.500.000,00</strong></p>", bodies[1])
+}
+
+func TestBuildMIMEMessage_TextOnly(t *testing.T) {
+	raw, err := buildMIMEMessage("noreply@example.com", "ops@example.com", &models.EmailMessage{
+		Subject:  "Settlement Reconciliation",
+		TextBody: "No mismatches",
+	})
+	require.NoError(t, err)
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	require.NoError(t, err)
+	assert.Equal(t, "text/plain; charset=utf-8", msg.Header.Get("Content-Type"))
+	body, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	require.NoError(t, err)
+	assert.Equal(t, "No mismatches", string(body))
+}
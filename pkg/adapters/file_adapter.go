@@ -1,10 +1,16 @@
-// pkg/adapters/email_adapter.go
+// pkg/adapters/file_adapter.go
 package adapters
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
+	"io"
 	"loan-service/internal/models"
+	"loan-service/pkg/exif"
+	"loan-service/pkg/imaging"
 	"loan-service/pkg/logger"
+	"loan-service/pkg/storage"
 	"mime/multipart"
 	"path/filepath"
 	"strings"
@@ -13,16 +19,32 @@ import (
 	"github.com/google/uuid"
 )
 
+// sniffLength is how much content is read ahead to detect the file type
+const sniffLength = 512
+
+// imageVariants are the resized copies stored for every uploaded jpeg or png
+var imageVariants = []struct {
+	name    models.FileVariantName
+	maxEdge int
+	quality int
+}{
+	{name: models.FileVariantThumbnail, maxEdge: 320, quality: 70},
+	{name: models.FileVariantPreview, maxEdge: 1600, quality: 80},
+}
+
 type FileAdapter struct {
-	logger *logger.Logger
+	storage storage.Storage
+	logger  *logger.Logger
 }
 
-func NewFileAdapter(logger *logger.Logger) FileAdapterInterface {
+func NewFileAdapter(store storage.Storage, logger *logger.Logger) FileAdapterInterface {
 	return &FileAdapter{
-		logger: logger,
+		storage: store,
+		logger:  logger,
 	}
 }
 
+// UploadFile streams an uploaded file into storage; the caller persists the returned record and sets its URL
 func (a *FileAdapter) UploadFile(file *multipart.FileHeader, entityType string) (*models.FileUpload, error) {
 	a.logger.Debug("Uploading file", map[string]interface{}{
 		"file_name":    file.Filename,
@@ -31,24 +53,13 @@ func (a *FileAdapter) UploadFile(file *multipart.FileHeader, entityType string)
 		"entity_type":  entityType,
 	})
 
-	// Extract file extension and determine file type
 	ext := strings.ToLower(filepath.Ext(file.Filename))
-	var fileExtensionType models.FileType
-	switch ext {
-	case ".pdf":
-		fileExtensionType = models.FileTypePDF
-	case ".jpg", ".jpeg":
-		fileExtensionType = models.FileTypeJPEG
-	case ".png":
-		fileExtensionType = models.FileTypePNG
-	default:
-		fileExtensionType = models.FileTypePDF // Default fallback
-	}
 
-	// Generate filename based on entity type
-	var filename string
-	uuidStr := uuid.New().String()[:8]
+	// The key carries the record's full ID so no two uploads share one
+	id := uuid.New()
+	uuidStr := id.String()
 
+	var filename string
 	switch entityType {
 	case "disbursement":
 		filename = fmt.Sprintf("agreements/signed_%s%s", uuidStr, ext)
@@ -60,28 +71,212 @@ func (a *FileAdapter) UploadFile(file *multipart.FileHeader, entityType string)
 		filename = fmt.Sprintf("file_%s%s", uuidStr, ext)
 	}
 
-	// Generate file path and URL
-	filePath := "/uploads/" + filename
-	fileURL := "https://storage.example.com" + filePath
+	content, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open uploaded file: %w", err)
+	}
+	defer content.Close()
+
+	// The Content-Type sent by the client is not trusted; the stored type comes from the content itself
+	upload, err := a.put(id, content, file.Size, filename, "", entityType, uuid.Nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if upload.FileType == models.FileTypeJPEG || upload.FileType == models.FileTypePNG {
+		if _, err := content.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to rewind uploaded file: %w", err)
+		}
+		upload.Variants = a.storeVariants(content, upload)
+	}
+
+	return upload, nil
+}
+
+// storeVariants stores a thumbnail and a preview of an uploaded image. The upload is usable without them, so when
+// the image cannot be decoded or a variant not stored the upload goes ahead with none.
+func (a *FileAdapter) storeVariants(content io.ReadSeeker, upload *models.FileUpload) []*models.FileVariant {
+	orientation := 0
+	if upload.FileType == models.FileTypeJPEG {
+		// Phones store portrait photos sideways and record the rotation in EXIF
+		if metadata, err := exif.Parse(content, time.Local); err == nil {
+			orientation = metadata.Orientation
+		}
+		if _, err := content.Seek(0, io.SeekStart); err != nil {
+			return nil
+		}
+	}
+
+	img, err := imaging.Decode(content)
+	if err != nil {
+		a.logger.Warn("Skipping image variants", map[string]interface{}{
+			"error":     err.Error(),
+			"file_path": upload.FilePath,
+		})
+		return nil
+	}
+
+	base := strings.TrimSuffix(upload.FilePath, filepath.Ext(upload.FilePath))
+
+	var variants []*models.FileVariant
+	for _, spec := range imageVariants {
+		resized := imaging.Orient(imaging.Fit(img, spec.maxEdge), orientation)
+
+		var encoded bytes.Buffer
+		if err := imaging.EncodeJPEG(&encoded, resized, spec.quality); err != nil {
+			a.logger.Warn("Failed to encode image variant", map[string]interface{}{
+				"error":     err.Error(),
+				"file_path": upload.FilePath,
+				"variant":   spec.name,
+			})
+			a.deleteVariants(variants)
+			return nil
+		}
+
+		object, err := a.storage.Put(fmt.Sprintf("%s_%s.jpg", base, spec.name), &encoded, int64(encoded.Len()), "image/jpeg")
+		if err != nil {
+			a.logger.Warn("Failed to store image variant", map[string]interface{}{
+				"error":     err.Error(),
+				"file_path": upload.FilePath,
+				"variant":   spec.name,
+			})
+			a.deleteVariants(variants)
+			return nil
+		}
+
+		variants = append(variants, &models.FileVariant{
+			BaseModel: models.BaseModel{
+				ID:        uuid.New(),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+			},
+			FileUploadID: upload.ID,
+			Variant:      spec.name,
+			FilePath:     object.Key,
+			ContentType:  "image/jpeg",
+			FileSize:     object.Size,
+			Width:        resized.Rect.Dx(),
+			Height:       resized.Rect.Dy(),
+			Checksum:     object.Checksum,
+		})
+	}
+
+	return variants
+}
+
+func (a *FileAdapter) deleteVariants(variants []*models.FileVariant) {
+	for _, variant := range variants {
+		if err := a.storage.Delete(variant.FilePath); err != nil {
+			a.logger.Error("Failed to delete image variant", map[string]interface{}{
+				"error":     err.Error(),
+				"file_path": variant.FilePath,
+			})
+		}
+	}
+}
+
+// StoreFile writes generated content to storage and describes it like an upload. Documents generated again for the
+// same entity get the same file name, so the key also carries the record's ID to keep an earlier copy from being
+// overwritten.
+func (a *FileAdapter) StoreFile(content []byte, fileName, contentType, entityType string, entityID uuid.UUID) (*models.FileUpload, error) {
+	if len(content) == 0 {
+		return nil, fmt.Errorf("file %s is empty", fileName)
+	}
+
+	id := uuid.New()
+	ext := filepath.Ext(fileName)
+	name := fmt.Sprintf("%s_%s%s", strings.TrimSuffix(fileName, ext), id.String(), ext)
+
+	var key string
+	switch entityType {
+	case "loan", "investment", "disbursement":
+		key = "agreements/" + name
+	default:
+		key = entityType + "/" + name
+	}
+
+	return a.put(id, bytes.NewReader(content), int64(len(content)), key, contentType, entityType, entityID)
+}
+
+// OpenFile opens stored content for reading
+func (a *FileAdapter) OpenFile(key string) (io.ReadSeekCloser, error) {
+	return a.storage.Open(key)
+}
+
+// DeleteFile removes stored content, e.g. when its file_uploads row could not be saved
+func (a *FileAdapter) DeleteFile(key string) error {
+	return a.storage.Delete(key)
+}
+
+// put checks the content is the type its extension (and declared content type, if any) claims before storing it
+func (a *FileAdapter) put(id uuid.UUID, content io.Reader, size int64, key, declaredContentType, entityType string, entityID uuid.UUID) (*models.FileUpload, error) {
+	ext := strings.ToLower(filepath.Ext(key))
+	extensionType, ok := models.FileTypeFromExtension(ext)
+	if !ok {
+		return nil, &models.FileValidationError{
+			Code:    models.CodeFileTypeUnsupported,
+			Message: fmt.Sprintf("file extension %q is not supported, must be .pdf, .jpg, .jpeg or .png", ext),
+		}
+	}
+
+	// Sniff the magic bytes without consuming them so the whole content is still streamed to storage
+	buffered := bufio.NewReaderSize(content, sniffLength)
+	header, err := buffered.Peek(sniffLength)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read file content: %w", err)
+	}
+
+	fileType, ok := models.DetectFileType(header)
+	if !ok {
+		return nil, &models.FileValidationError{
+			Code:    models.CodeFileTypeUnsupported,
+			Message: "file content is not a PDF, JPEG or PNG",
+		}
+	}
+	if fileType != extensionType {
+		return nil, &models.FileValidationError{
+			Code:    models.CodeFileTypeMismatch,
+			Message: fmt.Sprintf("file content is %s but its extension is %s", fileType, ext),
+		}
+	}
+	if declaredContentType != "" && declaredContentType != fileType.ContentType() {
+		return nil, &models.FileValidationError{
+			Code:    models.CodeFileTypeMismatch,
+			Message: fmt.Sprintf("file content is %s but was declared as %s", fileType, declaredContentType),
+		}
+	}
+
+	contentType := fileType.ContentType()
+	object, err := a.storage.Put(key, buffered, size, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	a.logger.Debug("Stored file", map[string]interface{}{
+		"file_path":   object.Key,
+		"file_size":   object.Size,
+		"checksum":    object.Checksum,
+		"backend":     a.storage.Backend(),
+		"entity_type": entityType,
+		"entity_id":   entityID.String(),
+	})
 
-	// Create FileUpload model
-	fileUpload := &models.FileUpload{
+	return &models.FileUpload{
 		BaseModel: models.BaseModel{
-			ID:        uuid.New(),
+			ID:        id,
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		},
-		FileName:    filename,
-		FileType:    fileExtensionType,
-		FileSize:    file.Size,
-		FilePath:    filePath,
-		FileURL:     fileURL,
-		ContentType: file.Header.Get("Content-Type"),
-		UploadedBy:  uuid.Nil,   // This should be set by the handler based on authenticated user
-		EntityType:  entityType, // Use the entityType parameter from form
-		EntityID:    uuid.Nil,   // This should be set by the handler based on context
-		IsActive:    true,
-	}
-
-	return fileUpload, nil
+		FileName:       object.Key,
+		FileType:       fileType,
+		FileSize:       object.Size,
+		FilePath:       object.Key,
+		ContentType:    contentType,
+		Checksum:       object.Checksum,
+		StorageBackend: a.storage.Backend(),
+		UploadedBy:     uuid.Nil,
+		EntityType:     entityType,
+		EntityID:       entityID,
+		IsActive:       true,
+	}, nil
 }
@@ -2,22 +2,33 @@
 package adapters
 
 import (
+	"io"
 	"loan-service/internal/models"
 	"mime/multipart"
+	"time"
+
+	"github.com/google/uuid"
 )
 
+// EmailAdapterInterface renders emails from templates in the recipient's locale and sends them
 type EmailAdapterInterface interface {
-	SendEmail(to, subject, body string) error
-	GenerateAgreementEmailBody(
+	SendEmail(to string, message *models.EmailMessage) error
+	GenerateAgreementEmail(
 		investment *models.Investment,
 		loan *models.Loan,
 		investor *models.Investor,
 		borrower *models.Borrower,
-	) string
+	) (*models.EmailMessage, error)
+	GenerateReconciliationSummary(run *models.ReconciliationRun, mismatches []*models.ReconciliationMismatch) (*models.EmailMessage, error)
+	GenerateWaitlistEmail(entry *models.WaitlistEntry, loan *models.Loan, investor *models.Investor) (*models.EmailMessage, error)
 }
 
 type PaymentAdapterInterface interface {
-	ProcessPayment(amount float64, token string) (*PaymentResult, error)
+	// ProcessPayment charges a payment token; a retry with the same idempotency key never charges twice
+	ProcessPayment(amount float64, token string, idempotencyKey string) (*PaymentResult, error)
+	// ProcessPayout sends funds to a bank account; a retry with the same idempotency key never pays out twice
+	ProcessPayout(amount float64, account PayoutAccount, idempotencyKey string) (*PaymentResult, error)
+	GetSettlementReport(date time.Time) ([]SettlementRecord, error)
 }
 
 type PaymentResult struct {
@@ -26,6 +37,54 @@ type PaymentResult struct {
 	Message       string `json:"message"`
 }
 
+// PayoutAccount is the bank account a payout is sent to
+type PayoutAccount struct {
+	BankCode          string `json:"bank_code"`
+	AccountNumber     string `json:"account_number"`
+	AccountHolderName string `json:"account_holder_name"`
+}
+
+// SettlementRecord is a single line of the payment provider settlement report
+type SettlementRecord struct {
+	TransactionID string    `json:"transaction_id"`
+	Amount        float64   `json:"amount"`
+	Status        string    `json:"status"`
+	SettledAt     time.Time `json:"settled_at"`
+}
+
 type FileAdapterInterface interface {
 	UploadFile(file *multipart.FileHeader, entityType string) (*models.FileUpload, error)
+	StoreFile(content []byte, fileName, contentType, entityType string, entityID uuid.UUID) (*models.FileUpload, error)
+	OpenFile(key string) (io.ReadSeekCloser, error)
+	DeleteFile(key string) error
+}
+
+// DocumentAdapterInterface renders documents from versioned templates
+type DocumentAdapterInterface interface {
+	GenerateLoanAgreement(data *models.LoanAgreementData) (*models.GeneratedDocument, error)
+	GenerateInvestmentAgreement(data *models.InvestmentAgreementData) (*models.GeneratedDocument, error)
+	GenerateTransferAgreement(data *models.TransferAgreementData) (*models.GeneratedDocument, error)
+}
+
+// SignatureAdapterInterface sends documents to an e-signature provider and handles its callbacks
+type SignatureAdapterInterface interface {
+	Provider() string
+	SendForSignature(req SignatureRequest) (*SignatureEnvelope, error)
+	VerifyCallback(payload []byte, signature string) error
+	DownloadSignedDocument(envelopeID string) ([]byte, error)
+}
+
+// SignatureRequest is a document to be signed and who has to sign it
+type SignatureRequest struct {
+	DocumentName string `json:"document_name"`
+	DocumentURL  string `json:"document_url"`
+	SignerName   string `json:"signer_name"`
+	SignerEmail  string `json:"signer_email"`
+	CallbackURL  string `json:"callback_url"`
+}
+
+// SignatureEnvelope is the provider's reference to a document sent for signature
+type SignatureEnvelope struct {
+	EnvelopeID string `json:"envelope_id"`
+	SigningURL string `json:"signing_url"`
 }
@@ -3,9 +3,12 @@ package adapters
 
 import (
 	"fmt"
+	"time"
 
 	"loan-service/pkg/config"
 	"loan-service/pkg/logger"
+
+	"github.com/google/uuid"
 )
 
 type PaymentAdapter struct {
@@ -20,29 +23,33 @@ func NewPaymentAdapter(cfg config.PaymentConfig, logger *logger.Logger) PaymentA
 	}
 }
 
-func (a *PaymentAdapter) ProcessPayment(amount float64, token string) (*PaymentResult, error) {
+// ProcessPayment charges the payment token. The provider returns the result of the first request for a repeated
+// idempotency key instead of charging again.
+func (a *PaymentAdapter) ProcessPayment(amount float64, token string, idempotencyKey string) (*PaymentResult, error) {
 	a.logger.Debug("Processing payment", map[string]interface{}{
-		"amount":   amount,
-		"token":    token,
-		"provider": a.config.Provider,
+		"amount":          amount,
+		"token":           token,
+		"idempotency_key": idempotencyKey,
+		"provider":        a.config.Provider,
 	})
 
 	switch a.config.Provider {
 	case "stripe":
-		return a.processStripe(amount, token)
+		return a.processStripe(amount, token, idempotencyKey)
 	case "mock":
-		return a.processMock(amount, token)
+		return a.processMock(amount, token, idempotencyKey)
 	default:
 		return nil, fmt.Errorf("unsupported payment provider: %s", a.config.Provider)
 	}
 }
 
-func (a *PaymentAdapter) processStripe(amount float64, token string) (*PaymentResult, error) {
-	// Implementation for Stripe API
+func (a *PaymentAdapter) processStripe(amount float64, token string, idempotencyKey string) (*PaymentResult, error) {
+	// Implementation for Stripe API, sending idempotencyKey as the Idempotency-Key header
 	a.logger.Info("Stripe payment processed (mock)", map[string]interface{}{
-		"amount":     amount,
-		"token":      token,
-		"secret_key": a.config.SecretKey,
+		"amount":          amount,
+		"token":           token,
+		"idempotency_key": idempotencyKey,
+		"secret_key":      a.config.SecretKey,
 	})
 
 	return &PaymentResult{
@@ -52,15 +59,99 @@ func (a *PaymentAdapter) processStripe(amount float64, token string) (*PaymentRe
 	}, nil
 }
 
-func (a *PaymentAdapter) processMock(amount float64, token string) (*PaymentResult, error) {
+func (a *PaymentAdapter) processMock(amount float64, token string, idempotencyKey string) (*PaymentResult, error) {
 	a.logger.Info("Mock payment processed", map[string]interface{}{
-		"amount": amount,
-		"token":  token,
+		"amount":          amount,
+		"token":           token,
+		"idempotency_key": idempotencyKey,
 	})
 
 	return &PaymentResult{
-		TransactionID: "mock_txn_123456",
+		TransactionID: "mock_txn_" + uuid.New().String()[:8],
 		Status:        "success",
 		Message:       "Payment processed successfully (mock)",
 	}, nil
 }
+
+// ProcessPayout sends funds from the platform to an investor bank account. The provider returns the result of the
+// first request for a repeated idempotency key instead of paying out again.
+func (a *PaymentAdapter) ProcessPayout(amount float64, account PayoutAccount, idempotencyKey string) (*PaymentResult, error) {
+	a.logger.Debug("Processing payout", map[string]interface{}{
+		"amount":          amount,
+		"bank_code":       account.BankCode,
+		"idempotency_key": idempotencyKey,
+		"provider":        a.config.Provider,
+	})
+
+	switch a.config.Provider {
+	case "stripe":
+		return a.payoutStripe(amount, account, idempotencyKey)
+	case "mock":
+		return a.payoutMock(amount, account, idempotencyKey)
+	default:
+		return nil, fmt.Errorf("unsupported payment provider: %s", a.config.Provider)
+	}
+}
+
+func (a *PaymentAdapter) payoutStripe(amount float64, account PayoutAccount, idempotencyKey string) (*PaymentResult, error) {
+	// Implementation for Stripe payouts API, sending idempotencyKey as the Idempotency-Key header
+	a.logger.Info("Stripe payout processed (mock)", map[string]interface{}{
+		"amount":          amount,
+		"bank_code":       account.BankCode,
+		"idempotency_key": idempotencyKey,
+	})
+
+	return &PaymentResult{
+		TransactionID: "stripe_po_123456",
+		Status:        "success",
+		Message:       "Payout processed successfully via Stripe",
+	}, nil
+}
+
+func (a *PaymentAdapter) payoutMock(amount float64, account PayoutAccount, idempotencyKey string) (*PaymentResult, error) {
+	a.logger.Info("Mock payout processed", map[string]interface{}{
+		"amount":          amount,
+		"bank_code":       account.BankCode,
+		"idempotency_key": idempotencyKey,
+	})
+
+	return &PaymentResult{
+		TransactionID: "mock_po_" + uuid.New().String()[:8],
+		Status:        "success",
+		Message:       "Payout processed successfully (mock)",
+	}, nil
+}
+
+// GetSettlementReport pulls the settlement report for the given day from the payment provider
+func (a *PaymentAdapter) GetSettlementReport(date time.Time) ([]SettlementRecord, error) {
+	a.logger.Debug("Fetching settlement report", map[string]interface{}{
+		"date":     date.Format("2006-01-02"),
+		"provider": a.config.Provider,
+	})
+
+	switch a.config.Provider {
+	case "stripe":
+		return a.settlementReportStripe(date)
+	case "mock":
+		return a.settlementReportMock(date)
+	default:
+		return nil, fmt.Errorf("unsupported payment provider: %s", a.config.Provider)
+	}
+}
+
+func (a *PaymentAdapter) settlementReportStripe(date time.Time) ([]SettlementRecord, error) {
+	// Implementation for Stripe balance transactions API
+	a.logger.Info("Stripe settlement report fetched (mock)", map[string]interface{}{
+		"date": date.Format("2006-01-02"),
+	})
+
+	return []SettlementRecord{}, nil
+}
+
+func (a *PaymentAdapter) settlementReportMock(date time.Time) ([]SettlementRecord, error) {
+	a.logger.Info("Mock settlement report fetched", map[string]interface{}{
+		"date": date.Format("2006-01-02"),
+	})
+
+	return []SettlementRecord{}, nil
+}
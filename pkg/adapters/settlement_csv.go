@@ -0,0 +1,84 @@
+// pkg/adapters/settlement_csv.go
+package adapters
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// settlementCSVColumns is the header expected in settlement report file drops
+var settlementCSVColumns = []string{"transaction_id", "amount", "status", "settled_at"}
+
+// ReadSettlementCSV reads a settlement report dropped as a CSV file by the payment provider
+func ReadSettlementCSV(path string) ([]SettlementRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open settlement file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	return ParseSettlementCSV(file)
+}
+
+// ParseSettlementCSV parses a settlement report with the columns
+// transaction_id,amount,status,settled_at (settled_at in RFC3339)
+func ParseSettlementCSV(r io.Reader) ([]SettlementRecord, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return []SettlementRecord{}, nil
+		}
+		return nil, fmt.Errorf("failed to read settlement header: %w", err)
+	}
+
+	// Map column names to positions so column order does not matter
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range settlementCSVColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("settlement file is missing column: %s", name)
+		}
+	}
+
+	records := []SettlementRecord{}
+	line := 1
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("failed to read settlement line %d: %w", line, err)
+		}
+
+		amount, err := strconv.ParseFloat(strings.TrimSpace(row[columns["amount"]]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid amount on settlement line %d: %w", line, err)
+		}
+
+		settledAt, err := time.Parse(time.RFC3339, strings.TrimSpace(row[columns["settled_at"]]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid settled_at on settlement line %d: %w", line, err)
+		}
+
+		records = append(records, SettlementRecord{
+			TransactionID: strings.TrimSpace(row[columns["transaction_id"]]),
+			Amount:        amount,
+			Status:        strings.TrimSpace(row[columns["status"]]),
+			SettledAt:     settledAt,
+		})
+	}
+
+	return records, nil
+}
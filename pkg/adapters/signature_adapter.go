@@ -0,0 +1,127 @@
+// pkg/adapters/signature_adapter.go
+package adapters
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"loan-service/pkg/config"
+	"loan-service/pkg/logger"
+	"loan-service/pkg/pdf"
+
+	"github.com/google/uuid"
+)
+
+type SignatureAdapter struct {
+	config config.SignatureConfig
+	logger *logger.Logger
+
+	// mockEnvelopes remembers what the mock provider was asked to sign so it can hand back a signed copy
+	mu            sync.Mutex
+	mockEnvelopes map[string]SignatureRequest
+}
+
+func NewSignatureAdapter(cfg config.SignatureConfig, logger *logger.Logger) SignatureAdapterInterface {
+	return &SignatureAdapter{
+		config:        cfg,
+		logger:        logger,
+		mockEnvelopes: make(map[string]SignatureRequest),
+	}
+}
+
+func (a *SignatureAdapter) Provider() string {
+	return a.config.Provider
+}
+
+// SendForSignature creates an envelope at the provider, which emails the signer a link to sign the document
+func (a *SignatureAdapter) SendForSignature(req SignatureRequest) (*SignatureEnvelope, error) {
+	a.logger.Debug("Sending document for signature", map[string]interface{}{
+		"document_name": req.DocumentName,
+		"signer_email":  req.SignerEmail,
+		"provider":      a.config.Provider,
+	})
+
+	switch a.config.Provider {
+	case "mock":
+		return a.sendMock(req)
+	default:
+		return nil, fmt.Errorf("unsupported signature provider: %s", a.config.Provider)
+	}
+}
+
+func (a *SignatureAdapter) sendMock(req SignatureRequest) (*SignatureEnvelope, error) {
+	envelopeID := "mock_env_" + uuid.New().String()[:8]
+
+	a.mu.Lock()
+	a.mockEnvelopes[envelopeID] = req
+	a.mu.Unlock()
+
+	a.logger.Info("Mock signature envelope created", map[string]interface{}{
+		"envelope_id":  envelopeID,
+		"signer_email": req.SignerEmail,
+	})
+
+	return &SignatureEnvelope{
+		EnvelopeID: envelopeID,
+		SigningURL: "https://sign.example.com/envelopes/" + envelopeID,
+	}, nil
+}
+
+// VerifyCallback checks the callback was sent by the provider: the signature is the hex HMAC-SHA256 of the raw body
+func (a *SignatureAdapter) VerifyCallback(payload []byte, signature string) error {
+	if a.config.WebhookSecret == "" {
+		return fmt.Errorf("signature webhook secret is not configured")
+	}
+
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("invalid callback signature encoding")
+	}
+
+	mac := hmac.New(sha256.New, []byte(a.config.WebhookSecret))
+	mac.Write(payload)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return fmt.Errorf("callback signature does not match")
+	}
+
+	return nil
+}
+
+// DownloadSignedDocument gets the signed copy of the document in an envelope
+func (a *SignatureAdapter) DownloadSignedDocument(envelopeID string) ([]byte, error) {
+	a.logger.Debug("Downloading signed document", map[string]interface{}{
+		"envelope_id": envelopeID,
+		"provider":    a.config.Provider,
+	})
+
+	switch a.config.Provider {
+	case "mock":
+		return a.downloadMock(envelopeID)
+	default:
+		return nil, fmt.Errorf("unsupported signature provider: %s", a.config.Provider)
+	}
+}
+
+func (a *SignatureAdapter) downloadMock(envelopeID string) ([]byte, error) {
+	a.mu.Lock()
+	req, ok := a.mockEnvelopes[envelopeID]
+	a.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("envelope %s not found", envelopeID)
+	}
+
+	// The mock provider has no copy of the original, so it returns a signing certificate for it instead
+	doc := pdf.NewDocument("Signed " + req.DocumentName)
+	doc.Heading("ELECTRONIC SIGNATURE CERTIFICATE")
+	doc.Paragraph("Document: " + req.DocumentName)
+	doc.Paragraph("Original: " + req.DocumentURL)
+	doc.Paragraph(fmt.Sprintf("Signed by %s <%s>", req.SignerName, req.SignerEmail))
+	doc.Paragraph("Signed at: " + time.Now().Format(time.RFC3339))
+	doc.Paragraph("Envelope: " + envelopeID)
+
+	return doc.Bytes(), nil
+}
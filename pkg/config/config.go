@@ -17,6 +17,19 @@ type Config struct {
 	Email    EmailConfig    `toml:"email"`
 	Payment  PaymentConfig  `toml:"payment"`
 	Cron     CronConfig     `toml:"cron"`
+	Loan     LoanConfig     `toml:"loan"`
+	Wallet   WalletConfig   `toml:"wallet"`
+
+	Reconciliation   ReconciliationConfig   `toml:"reconciliation"`
+	InvestmentLimits InvestmentLimitsConfig `toml:"investment_limits"`
+	Marketplace      MarketplaceConfig      `toml:"marketplace"`
+	Storage          StorageConfig          `toml:"storage"`
+	Uploads          UploadConfig           `toml:"uploads"`
+	Downloads        DownloadConfig         `toml:"downloads"`
+	Scanner          ScannerConfig          `toml:"scanner"`
+	VisitProof       VisitProofConfig       `toml:"visit_proof"`
+	Documents        DocumentConfig         `toml:"documents"`
+	Signature        SignatureConfig        `toml:"signature"`
 }
 
 type AppConfig struct {
@@ -75,6 +88,17 @@ type EmailConfig struct {
 	SMTPUsername string `toml:"smtp_username"`
 	SMTPPassword string `toml:"smtp_password"`
 	FromAddress  string `toml:"from_address"`
+
+	// Emails are rendered from versioned templates in the recipient's locale
+	TemplateSource  string `toml:"template_source"`  // files or database; the database falls back to the files
+	TemplateDir     string `toml:"template_dir"`     // replaces the built-in template files
+	TemplateVersion string `toml:"template_version"` // default v1
+	DefaultLocale   string `toml:"default_locale"`   // id-ID or en-US, default id-ID
+
+	// Failed sends are retried with exponential backoff; zero uses the default
+	MaxAttempts     int           `toml:"max_attempts"`      // attempts before an email is marked failed, default 5
+	RetryBackoff    time.Duration `toml:"retry_backoff"`     // wait after the first failed attempt, doubled after each one, default 1m
+	MaxRetryBackoff time.Duration `toml:"max_retry_backoff"` // longest wait between attempts, default 1h
 }
 
 type PaymentConfig struct {
@@ -86,6 +110,101 @@ type PaymentConfig struct {
 
 type CronConfig struct {
 	InvestmentAgreementSchedule string `toml:"investment_agreement_schedule"`
+	ReconciliationSchedule      string `toml:"reconciliation_schedule"`
+	LoanExpirySchedule          string `toml:"loan_expiry_schedule"`
+	WaitlistSchedule            string `toml:"waitlist_schedule"`
+	FileScanSchedule            string `toml:"file_scan_schedule"`
+	EmailRetrySchedule          string `toml:"email_retry_schedule"`
+	PayoutResumeSchedule        string `toml:"payout_resume_schedule"`
+	TopUpResumeSchedule         string `toml:"top_up_resume_schedule"`
+}
+
+type LoanConfig struct {
+	FundingPeriod  time.Duration `toml:"funding_period"`  // how long an approved loan may raise funds before it expires
+	ReservationTTL time.Duration `toml:"reservation_ttl"` // how long a reservation holds principal while the investor pays
+}
+
+// InvestmentLimitsConfig holds the global per-investor limits; investors can have their own overrides. Zero disables a limit.
+type InvestmentLimitsConfig struct {
+	MaxLoanShare     float64 `toml:"max_loan_share"`     // fraction of a loan's principal one investor may hold
+	MaxTotalExposure float64 `toml:"max_total_exposure"` // outstanding amount across all loans
+	MinTicket        float64 `toml:"min_ticket"`         // smallest single investment
+}
+
+type MarketplaceConfig struct {
+	CacheTTL time.Duration `toml:"cache_ttl"` // how long listings stay cached; changes to listed loans invalidate them earlier
+}
+
+type StorageConfig struct {
+	Backend   string   `toml:"backend"`    // local or s3
+	UploadDir string   `toml:"upload_dir"` // directory files are written to by the local backend
+	S3        S3Config `toml:"s3"`
+}
+
+// S3Config points the s3 storage backend at AWS S3 or an S3-compatible store such as MinIO
+type S3Config struct {
+	Endpoint        string `toml:"endpoint"` // e.g. https://s3.ap-southeast-3.amazonaws.com or http://localhost:9000
+	Region          string `toml:"region"`
+	Bucket          string `toml:"bucket"`
+	AccessKeyID     string `toml:"access_key_id"`
+	SecretAccessKey string `toml:"secret_access_key"`
+	UsePathStyle    bool   `toml:"use_path_style"` // bucket in the path instead of the host name, needed by most S3-compatible stores
+}
+
+// UploadConfig limits the size of uploaded files in bytes and the number of files in a bundle; zero uses the default
+type UploadConfig struct {
+	MaxSize        int64            `toml:"max_size"`         // limit for entity types without their own
+	MaxSizes       map[string]int64 `toml:"max_sizes"`        // per entity type, e.g. approval or disbursement
+	MaxBundleFiles int              `toml:"max_bundle_files"` // files uploaded together as one bundle
+}
+
+// ScannerConfig selects the malware scanner uploads are checked with
+type ScannerConfig struct {
+	Backend string        `toml:"backend"` // noop or clamav
+	Address string        `toml:"address"` // clamd socket, e.g. tcp://localhost:3310 or unix:///var/run/clamav/clamd.ctl
+	Timeout time.Duration `toml:"timeout"`
+}
+
+// VisitProofConfig checks that a loan's visit proof photo was taken near the borrower's address and shortly before
+// the approval; zero uses the default
+type VisitProofConfig struct {
+	Mode        string        `toml:"mode"`         // off, warn or block
+	MaxDistance float64       `toml:"max_distance"` // metres between the photo and the borrower's geocoded address
+	MaxAge      time.Duration `toml:"max_age"`      // how long before the approval date the photo may have been taken
+
+	AllowMissingMetadata bool `toml:"allow_missing_metadata"` // only warn about photos without GPS position or capture time
+}
+
+// DownloadConfig configures the signed, expiring URLs files are downloaded through
+type DownloadConfig struct {
+	BaseURL       string        `toml:"base_url"`       // public URL of this API, e.g. https://api.example.com
+	SigningSecret string        `toml:"signing_secret"` // HMAC key download URLs are signed with
+	URLTTL        time.Duration `toml:"url_ttl"`        // lifetime of URLs handed to API callers
+	EmailURLTTL   time.Duration `toml:"email_url_ttl"`  // lifetime of URLs sent out in emails and to providers
+}
+
+type DocumentConfig struct {
+	AgreementTemplateVersion string `toml:"agreement_template_version"` // version of templates/agreements/loan_agreement_<version>.tmpl
+}
+
+// SignatureConfig configures the e-signature provider borrowers sign their loan agreement with
+type SignatureConfig struct {
+	Provider      string `toml:"provider"`
+	APIKey        string `toml:"api_key"`
+	WebhookSecret string `toml:"webhook_secret"` // signs provider callbacks
+	CallbackURL   string `toml:"callback_url"`
+}
+
+type WalletConfig struct {
+	WithdrawalApprovalThreshold float64 `toml:"withdrawal_approval_threshold"` // withdrawals above this amount need employee approval
+}
+
+type ReconciliationConfig struct {
+	Source            string  `toml:"source"`              // adapter, csv
+	SettlementDir     string  `toml:"settlement_dir"`      // directory of settlement_YYYY-MM-DD.csv drops
+	OpsEmail          string  `toml:"ops_email"`           // recipient of the summary email
+	AmountTolerance   float64 `toml:"amount_tolerance"`    // allowed difference before flagging amount mismatch
+	SettlementLagDays int     `toml:"settlement_lag_days"` // days a payment may take to appear in a settlement report
 }
 
 func Load(configPath, environment string) (*Config, error) {
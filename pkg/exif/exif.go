@@ -0,0 +1,259 @@
+// pkg/exif/exif.go
+package exif
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ErrNoExif is returned for JPEGs without an EXIF segment
+var ErrNoExif = errors.New("no exif data")
+
+// Metadata holds what a photo says about where and when it was taken; fields the photo lacks are nil
+type Metadata struct {
+	CapturedAt *time.Time
+	Latitude   *float64
+	Longitude  *float64
+
+	// Orientation is how the image must be rotated or flipped for display, 1 to 8 as in the EXIF specification; 0
+	// when the photo does not say
+	Orientation int
+}
+
+// HasLocation reports whether the photo carries GPS coordinates
+func (m *Metadata) HasLocation() bool {
+	return m.Latitude != nil && m.Longitude != nil
+}
+
+const (
+	tagOrientation        = 0x0112
+	tagDateTime           = 0x0132
+	tagExifIFD            = 0x8769
+	tagGPSIFD             = 0x8825
+	tagDateTimeOriginal   = 0x9003
+	tagOffsetTimeOriginal = 0x9011
+	tagGPSLatitudeRef     = 0x0001
+	tagGPSLatitude        = 0x0002
+	tagGPSLongitudeRef    = 0x0003
+	tagGPSLongitude       = 0x0004
+
+	typeASCII    = 2
+	typeShort    = 3
+	typeLong     = 4
+	typeRational = 5
+
+	exifDateLayout = "2006:01:02 15:04:05"
+)
+
+// Parse reads the EXIF capture time and GPS position from a JPEG. It only reads up to the EXIF segment, which comes
+// before the image data. Capture times without an offset tag are read in loc.
+func Parse(r io.Reader, loc *time.Location) (*Metadata, error) {
+	segment, err := findExifSegment(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+	return parseTIFF(segment, loc)
+}
+
+// findExifSegment walks the JPEG markers up to the APP1 segment holding "Exif\0\0" and a TIFF structure
+func findExifSegment(r *bufio.Reader) ([]byte, error) {
+	var soi [2]byte
+	if _, err := io.ReadFull(r, soi[:]); err != nil || soi != [2]byte{0xFF, 0xD8} {
+		return nil, errors.New("not a jpeg")
+	}
+
+	for {
+		marker, err := readMarker(r)
+		if err != nil {
+			return nil, err
+		}
+		// Start of scan: image data follows and no metadata segment comes after it
+		if marker == 0xDA || marker == 0xD9 {
+			return nil, ErrNoExif
+		}
+
+		var length uint16
+		if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+			return nil, fmt.Errorf("truncated jpeg segment: %w", err)
+		}
+		if length < 2 {
+			return nil, errors.New("invalid jpeg segment length")
+		}
+
+		payload := make([]byte, length-2)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return nil, fmt.Errorf("truncated jpeg segment: %w", err)
+		}
+		if marker == 0xE1 && len(payload) > 6 && string(payload[:6]) == "Exif\x00\x00" {
+			return payload[6:], nil
+		}
+	}
+}
+
+func readMarker(r *bufio.Reader) (byte, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, ErrNoExif
+	}
+	if b != 0xFF {
+		return 0, errors.New("invalid jpeg marker")
+	}
+	// Markers may be padded with extra 0xFF bytes
+	for b == 0xFF {
+		if b, err = r.ReadByte(); err != nil {
+			return 0, ErrNoExif
+		}
+	}
+	return b, nil
+}
+
+type tiff struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+type entry struct {
+	typ    uint16
+	count  uint32
+	offset []byte // the value itself when it fits in 4 bytes, else its offset
+}
+
+func parseTIFF(data []byte, loc *time.Location) (*Metadata, error) {
+	if len(data) < 8 {
+		return nil, errors.New("truncated exif data")
+	}
+
+	t := &tiff{data: data}
+	switch string(data[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return nil, errors.New("invalid exif byte order")
+	}
+
+	ifd0, err := t.readIFD(t.order.Uint32(data[4:8]))
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &Metadata{}
+	if e, ok := ifd0[tagOrientation]; ok && e.typ == typeShort && e.count == 1 {
+		meta.Orientation = int(t.order.Uint16(e.offset))
+	}
+
+	capturedAt, offset := "", ""
+	if e, ok := ifd0[tagExifIFD]; ok {
+		exifIFD, err := t.readIFD(t.order.Uint32(e.offset))
+		if err != nil {
+			return nil, err
+		}
+		capturedAt = t.ascii(exifIFD[tagDateTimeOriginal])
+		offset = t.ascii(exifIFD[tagOffsetTimeOriginal])
+	}
+	if capturedAt == "" {
+		capturedAt = t.ascii(ifd0[tagDateTime])
+	}
+	if capturedAt != "" {
+		meta.CapturedAt = parseCaptureTime(capturedAt, offset, loc)
+	}
+
+	if e, ok := ifd0[tagGPSIFD]; ok {
+		gps, err := t.readIFD(t.order.Uint32(e.offset))
+		if err != nil {
+			return nil, err
+		}
+		meta.Latitude = t.coordinate(gps[tagGPSLatitude], t.ascii(gps[tagGPSLatitudeRef]), "S")
+		meta.Longitude = t.coordinate(gps[tagGPSLongitude], t.ascii(gps[tagGPSLongitudeRef]), "W")
+	}
+
+	return meta, nil
+}
+
+func (t *tiff) readIFD(offset uint32) (map[uint16]entry, error) {
+	if int(offset)+2 > len(t.data) {
+		return nil, errors.New("exif directory out of range")
+	}
+	count := int(t.order.Uint16(t.data[offset:]))
+	start := int(offset) + 2
+	if start+count*12 > len(t.data) {
+		return nil, errors.New("exif directory out of range")
+	}
+
+	entries := make(map[uint16]entry, count)
+	for i := 0; i < count; i++ {
+		raw := t.data[start+i*12 : start+(i+1)*12]
+		entries[t.order.Uint16(raw[0:2])] = entry{
+			typ:    t.order.Uint16(raw[2:4]),
+			count:  t.order.Uint32(raw[4:8]),
+			offset: raw[8:12],
+		}
+	}
+	return entries, nil
+}
+
+// value returns the bytes of an entry's value, which are inline when they fit in 4 bytes
+func (t *tiff) value(e entry, size int) []byte {
+	n := int(e.count) * size
+	if n <= 4 {
+		return e.offset[:n]
+	}
+	start := int(t.order.Uint32(e.offset))
+	if start < 0 || start+n > len(t.data) {
+		return nil
+	}
+	return t.data[start : start+n]
+}
+
+func (t *tiff) ascii(e entry) string {
+	if e.typ != typeASCII {
+		return ""
+	}
+	return strings.TrimRight(string(t.value(e, 1)), "\x00 ")
+}
+
+// coordinate converts degrees, minutes and seconds rationals to signed decimal degrees
+func (t *tiff) coordinate(e entry, ref, negativeRef string) *float64 {
+	if e.typ != typeRational || e.count != 3 {
+		return nil
+	}
+	raw := t.value(e, 8)
+	if raw == nil {
+		return nil
+	}
+
+	var parts [3]float64
+	for i := range parts {
+		numerator := t.order.Uint32(raw[i*8:])
+		denominator := t.order.Uint32(raw[i*8+4:])
+		if denominator == 0 {
+			return nil
+		}
+		parts[i] = float64(numerator) / float64(denominator)
+	}
+
+	degrees := parts[0] + parts[1]/60 + parts[2]/3600
+	if strings.EqualFold(ref, negativeRef) {
+		degrees = -degrees
+	}
+	return &degrees
+}
+
+func parseCaptureTime(value, offset string, loc *time.Location) *time.Time {
+	if offset != "" {
+		if captured, err := time.Parse(exifDateLayout+"-07:00", value+offset); err == nil {
+			return &captured
+		}
+	}
+	captured, err := time.ParseInLocation(exifDateLayout, value, loc)
+	if err != nil {
+		return nil
+	}
+	return &captured
+}
@@ -0,0 +1,155 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	data  []byte
+}
+
+func asciiEntry(tag uint16, value string) testEntry {
+	return testEntry{tag: tag, typ: typeASCII, count: uint32(len(value) + 1), data: append([]byte(value), 0)}
+}
+
+func shortEntry(order binary.ByteOrder, tag uint16, value uint16) testEntry {
+	data := make([]byte, 2)
+	order.PutUint16(data, value)
+	return testEntry{tag: tag, typ: typeShort, count: 1, data: data}
+}
+
+func longEntry(order binary.ByteOrder, tag uint16, value uint32) testEntry {
+	data := make([]byte, 4)
+	order.PutUint32(data, value)
+	return testEntry{tag: tag, typ: typeLong, count: 1, data: data}
+}
+
+// rationalEntry encodes degrees, minutes and seconds, the seconds in hundredths
+func rationalEntry(order binary.ByteOrder, tag uint16, degrees, minutes, centiseconds uint32) testEntry {
+	data := make([]byte, 24)
+	for i, pair := range [][2]uint32{{degrees, 1}, {minutes, 1}, {centiseconds, 100}} {
+		order.PutUint32(data[i*8:], pair[0])
+		order.PutUint32(data[i*8+4:], pair[1])
+	}
+	return testEntry{tag: tag, typ: typeRational, count: 3, data: data}
+}
+
+// writeIFD appends a directory and the values that do not fit inline, returning the directory's offset
+func writeIFD(buf *bytes.Buffer, order binary.ByteOrder, entries []testEntry) uint32 {
+	offset := uint32(buf.Len())
+	dataOffset := offset + 2 + uint32(len(entries))*12 + 4
+
+	var data []byte
+	binary.Write(buf, order, uint16(len(entries)))
+	for _, e := range entries {
+		binary.Write(buf, order, e.tag)
+		binary.Write(buf, order, e.typ)
+		binary.Write(buf, order, e.count)
+		if len(e.data) <= 4 {
+			buf.Write(append(e.data, make([]byte, 4-len(e.data))...))
+			continue
+		}
+		binary.Write(buf, order, dataOffset+uint32(len(data)))
+		data = append(data, e.data...)
+	}
+	binary.Write(buf, order, uint32(0))
+	buf.Write(data)
+	return offset
+}
+
+// testJPEG builds a jpeg whose EXIF data holds a portrait orientation, a capture time and, when withGPS is set, a
+// position near Jakarta
+func testJPEG(order binary.ByteOrder, offset string, withGPS bool) []byte {
+	tiff := &bytes.Buffer{}
+	if order == binary.LittleEndian {
+		tiff.WriteString("II")
+	} else {
+		tiff.WriteString("MM")
+	}
+	binary.Write(tiff, order, uint16(42))
+	binary.Write(tiff, order, uint32(0)) // IFD0 offset, filled in below
+
+	exifEntries := []testEntry{asciiEntry(tagDateTimeOriginal, "2026:10:15 09:30:00")}
+	if offset != "" {
+		exifEntries = append(exifEntries, asciiEntry(tagOffsetTimeOriginal, offset))
+	}
+	ifd0 := []testEntry{
+		shortEntry(order, tagOrientation, 6),
+		longEntry(order, tagExifIFD, writeIFD(tiff, order, exifEntries)),
+	}
+
+	if withGPS {
+		gps := writeIFD(tiff, order, []testEntry{
+			asciiEntry(tagGPSLatitudeRef, "S"),
+			rationalEntry(order, tagGPSLatitude, 6, 12, 0),
+			asciiEntry(tagGPSLongitudeRef, "E"),
+			rationalEntry(order, tagGPSLongitude, 106, 48, 5976),
+		})
+		ifd0 = append(ifd0, longEntry(order, tagGPSIFD, gps))
+	}
+
+	ifd0Offset := writeIFD(tiff, order, ifd0)
+	raw := tiff.Bytes()
+	order.PutUint32(raw[4:8], ifd0Offset)
+
+	segment := append([]byte("Exif\x00\x00"), raw...)
+	jpeg := &bytes.Buffer{}
+	jpeg.Write([]byte{0xFF, 0xD8})
+	// A JFIF segment comes first in most files
+	jpeg.Write([]byte{0xFF, 0xE0, 0x00, 0x07, 'J', 'F', 'I', 'F', 0x00})
+	jpeg.Write([]byte{0xFF, 0xE1})
+	binary.Write(jpeg, binary.BigEndian, uint16(len(segment)+2))
+	jpeg.Write(segment)
+	jpeg.Write([]byte{0xFF, 0xDA, 0x00, 0x02, 0xFF, 0xD9})
+	return jpeg.Bytes()
+}
+
+func TestParse_CaptureTimeAndLocation(t *testing.T) {
+	for name, order := range map[string]binary.ByteOrder{"little endian": binary.LittleEndian, "big endian": binary.BigEndian} {
+		t.Run(name, func(t *testing.T) {
+			metadata, err := Parse(bytes.NewReader(testJPEG(order, "+07:00", true)), time.UTC)
+
+			require.NoError(t, err)
+			require.NotNil(t, metadata.CapturedAt)
+			assert.True(t, metadata.CapturedAt.Equal(time.Date(2026, 10, 15, 2, 30, 0, 0, time.UTC)))
+			require.True(t, metadata.HasLocation())
+			assert.InDelta(t, -6.2, *metadata.Latitude, 1e-9)
+			assert.InDelta(t, 106.8166, *metadata.Longitude, 1e-9)
+			assert.Equal(t, 6, metadata.Orientation)
+		})
+	}
+}
+
+func TestParse_CaptureTimeWithoutOffsetUsesLocation(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*60*60)
+
+	metadata, err := Parse(bytes.NewReader(testJPEG(binary.LittleEndian, "", false)), jakarta)
+
+	require.NoError(t, err)
+	assert.True(t, metadata.CapturedAt.Equal(time.Date(2026, 10, 15, 9, 30, 0, 0, jakarta)))
+	assert.False(t, metadata.HasLocation())
+}
+
+func TestParse_NoExif(t *testing.T) {
+	jpeg := []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x07, 'J', 'F', 'I', 'F', 0x00, 0xFF, 0xDA, 0x00, 0x02}
+
+	_, err := Parse(bytes.NewReader(jpeg), time.UTC)
+
+	assert.ErrorIs(t, err, ErrNoExif)
+}
+
+func TestParse_NotJPEG(t *testing.T) {
+	_, err := Parse(bytes.NewReader([]byte("\x89PNG\r\n\x1a\n")), time.UTC)
+
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrNoExif)
+}
@@ -0,0 +1,134 @@
+// pkg/imaging/imaging.go
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
+
+	// Register the formats uploads may be decoded from
+	_ "image/png"
+)
+
+// maxPixels bounds the images decoded, so a small file that claims huge dimensions cannot exhaust memory
+const maxPixels = 50_000_000
+
+// Decode reads a jpeg or png, refusing images larger than maxPixels
+func Decode(r io.ReadSeeker) (image.Image, error) {
+	config, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image header: %w", err)
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, fmt.Errorf("image of %dx%d pixels is too large", config.Width, config.Height)
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return img, nil
+}
+
+// Fit scales the image down so neither side exceeds maxEdge, averaging the source pixels each target pixel covers.
+// Images that already fit are returned as they are.
+func Fit(src image.Image, maxEdge int) *image.RGBA {
+	rgba := toRGBA(src)
+	width, height := rgba.Rect.Dx(), rgba.Rect.Dy()
+	if width <= maxEdge && height <= maxEdge {
+		return rgba
+	}
+
+	targetWidth, targetHeight := maxEdge, maxEdge
+	if width > height {
+		targetHeight = max(1, height*maxEdge/width)
+	} else {
+		targetWidth = max(1, width*maxEdge/height)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, targetWidth, targetHeight))
+	for y := 0; y < targetHeight; y++ {
+		y0, y1 := y*height/targetHeight, max((y+1)*height/targetHeight, y*height/targetHeight+1)
+		for x := 0; x < targetWidth; x++ {
+			x0, x1 := x*width/targetWidth, max((x+1)*width/targetWidth, x*width/targetWidth+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r, g, b, a = r+uint64(p[0]), g+uint64(p[1]), b+uint64(p[2]), a+uint64(p[3])
+					n++
+				}
+			}
+
+			d := dst.Pix[y*dst.Stride+x*4:]
+			d[0], d[1], d[2], d[3] = uint8(r/n), uint8(g/n), uint8(b/n), uint8(a/n)
+		}
+	}
+	return dst
+}
+
+// Orient rotates and flips the image as an EXIF orientation of 1 to 8 asks; other values leave it unchanged
+func Orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+
+	width, height := src.Rect.Dx(), src.Rect.Dy()
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored
+				dx, dy = width-1-x, y
+			case 3: // rotated 180°
+				dx, dy = width-1-x, height-1-y
+			case 4: // flipped
+				dx, dy = x, height-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated 90° clockwise
+				dx, dy = height-1-y, x
+			case 7: // transversed
+				dx, dy = height-1-y, width-1-x
+			case 8: // rotated 90° counterclockwise
+				dx, dy = y, width-1-x
+			}
+			copy(dst.Pix[dy*dst.Stride+dx*4:dy*dst.Stride+dx*4+4], src.Pix[y*src.Stride+x*4:y*src.Stride+x*4+4])
+		}
+	}
+	return dst
+}
+
+// EncodeJPEG writes the image as a jpeg of the given quality, 1 to 100. Transparent areas become white.
+func EncodeJPEG(w io.Writer, img image.Image, quality int) error {
+	flattened := image.NewRGBA(img.Bounds())
+	draw.Draw(flattened, flattened.Rect, image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flattened, flattened.Rect, img, img.Bounds().Min, draw.Over)
+
+	return jpeg.Encode(w, flattened, &jpeg.Options{Quality: quality})
+}
+
+// toRGBA copies the image into premultiplied RGBA at the origin, so pixels can be averaged without converting colours
+func toRGBA(src image.Image) *image.RGBA {
+	if rgba, ok := src.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	bounds := src.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Rect, src, bounds.Min, draw.Src)
+	return rgba
+}
@@ -0,0 +1,62 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFit_ScalesDownKeepingAspectRatio(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 1000, 500))
+	for i := range src.Pix {
+		src.Pix[i] = 200
+	}
+
+	resized := Fit(src, 320)
+
+	assert.Equal(t, image.Rect(0, 0, 320, 160), resized.Rect)
+	assert.Equal(t, color.RGBA{200, 200, 200, 200}, resized.RGBAAt(100, 100))
+}
+
+func TestFit_LeavesSmallImages(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 200, 100))
+
+	assert.Equal(t, image.Rect(0, 0, 200, 100), Fit(src, 320).Rect)
+}
+
+func TestOrient_RotatesClockwise(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	src.SetRGBA(0, 0, red)
+
+	rotated := Orient(src, 6)
+
+	// The left pixel of a landscape row ends up at the top of the portrait column
+	assert.Equal(t, image.Rect(0, 0, 1, 2), rotated.Rect)
+	assert.Equal(t, red, rotated.RGBAAt(0, 0))
+}
+
+func TestEncodeJPEG_FlattensTransparencyOnWhite(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	var encodedPNG bytes.Buffer
+	require.NoError(t, png.Encode(&encodedPNG, src))
+
+	decoded, err := Decode(bytes.NewReader(encodedPNG.Bytes()))
+	require.NoError(t, err)
+
+	var encoded bytes.Buffer
+	require.NoError(t, EncodeJPEG(&encoded, Fit(decoded, 8), 80))
+
+	result, err := jpeg.Decode(&encoded)
+	require.NoError(t, err)
+	r, g, b, _ := result.At(4, 4).RGBA()
+	assert.Greater(t, r>>8, uint32(250))
+	assert.Greater(t, g>>8, uint32(250))
+	assert.Greater(t, b>>8, uint32(250))
+}
@@ -0,0 +1,47 @@
+// pkg/mailtemplate/fs.go
+package mailtemplate
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+)
+
+// FSSource loads templates laid out as <version>/<locale>/<name>.subject.tmpl, <name>.txt.tmpl and, optionally,
+// <name>.html.tmpl
+type FSSource struct {
+	fsys fs.FS
+}
+
+func NewFSSource(fsys fs.FS) *FSSource {
+	return &FSSource{fsys: fsys}
+}
+
+func (s *FSSource) Load(name, locale, version string) (*Template, error) {
+	dir := path.Join(version, locale)
+
+	subject, err := fs.ReadFile(s.fsys, path.Join(dir, name+".subject.tmpl"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrTemplateNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := fs.ReadFile(s.fsys, path.Join(dir, name+".txt.tmpl"))
+	if err != nil {
+		return nil, fmt.Errorf("email template %s has no text body: %w", path.Join(dir, name), err)
+	}
+
+	html, err := fs.ReadFile(s.fsys, path.Join(dir, name+".html.tmpl"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	return &Template{
+		Subject: string(subject),
+		HTML:    string(html),
+		Text:    string(text),
+	}, nil
+}
@@ -0,0 +1,86 @@
+// pkg/mailtemplate/locale.go
+package mailtemplate
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	LocaleID = "id-ID"
+	LocaleEN = "en-US"
+)
+
+// SupportedLocale returns the canonical form of a supported locale, accepting e.g. "id", "en_us" or "EN-US"
+func SupportedLocale(locale string) (string, bool) {
+	switch strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-")) {
+	case "id", "id-id":
+		return LocaleID, true
+	case "en", "en-us":
+		return LocaleEN, true
+	default:
+		return "", false
+	}
+}
+
+var indonesianMonths = [...]string{
+	"Januari", "Februari", "Maret", "April", "Mei", "Juni",
+	"Juli", "Agustus", "September", "Oktober", "November", "Desember",
+}
+
+// FormatMoney formats a rupiah amount: "Rp 1.234.567,89" in id-ID and "IDR 1,234,567.89" in en-US
+func FormatMoney(locale string, amount float64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+	}
+	cents := int64(math.Round(math.Abs(amount) * 100))
+
+	if locale == LocaleEN {
+		return fmt.Sprintf("%sIDR %s.%02d", sign, groupThousands(cents/100, ","), cents%100)
+	}
+	return fmt.Sprintf("%sRp %s,%02d", sign, groupThousands(cents/100, "."), cents%100)
+}
+
+// FormatPercent formats a rate such as 0.125: "12,50%" in id-ID and "12.50%" in en-US
+func FormatPercent(locale string, rate float64) string {
+	formatted := fmt.Sprintf("%.2f%%", rate*100)
+	if locale == LocaleEN {
+		return formatted
+	}
+	return strings.Replace(formatted, ".", ",", 1)
+}
+
+// FormatDate formats a date: "2 Januari 2006" in id-ID and "January 2, 2006" in en-US
+func FormatDate(locale string, t time.Time) string {
+	if locale == LocaleEN {
+		return t.Format("January 2, 2006")
+	}
+	return fmt.Sprintf("%d %s %d", t.Day(), indonesianMonths[t.Month()-1], t.Year())
+}
+
+func groupThousands(n int64, separator string) string {
+	digits := fmt.Sprintf("%d", n)
+	var b strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteString(separator)
+		}
+		b.WriteRune(digit)
+	}
+	return b.String()
+}
+
+func templateFuncs(locale string) texttemplate.FuncMap {
+	return texttemplate.FuncMap{
+		"money":   func(amount float64) string { return FormatMoney(locale, amount) },
+		"percent": func(rate float64) string { return FormatPercent(locale, rate) },
+		"date":    func(t time.Time) string { return FormatDate(locale, t) },
+		"shortID": func(id uuid.UUID) string { return id.String()[:8] },
+	}
+}
@@ -0,0 +1,162 @@
+// pkg/mailtemplate/mailtemplate.go
+package mailtemplate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+)
+
+// ErrTemplateNotFound is returned by a Source that has no template for the name, locale and version
+var ErrTemplateNotFound = errors.New("email template not found")
+
+// Template is the source of one email in one locale and version. HTML is optional; without it the email is text only.
+type Template struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Source loads templates, e.g. from disk or the database
+type Source interface {
+	Load(name, locale, version string) (*Template, error)
+}
+
+// Message is a rendered email
+type Message struct {
+	Locale  string
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Engine renders versioned email templates in the recipient's locale
+type Engine struct {
+	source        Source
+	version       string
+	defaultLocale string
+
+	mu    sync.Mutex
+	cache map[string]*parsedTemplate
+}
+
+type parsedTemplate struct {
+	subject *texttemplate.Template
+	html    *htmltemplate.Template
+	text    *texttemplate.Template
+}
+
+// NewEngine creates an engine rendering the given template version; recipients without a supported locale get
+// defaultLocale
+func NewEngine(source Source, version, defaultLocale string) *Engine {
+	locale, ok := SupportedLocale(defaultLocale)
+	if !ok {
+		locale = LocaleID
+	}
+
+	return &Engine{
+		source:        source,
+		version:       version,
+		defaultLocale: locale,
+		cache:         make(map[string]*parsedTemplate),
+	}
+}
+
+// Render renders the named template for a recipient. An unsupported locale, or one the template was not translated
+// to, falls back to the default locale.
+func (e *Engine) Render(name, locale string, data interface{}) (*Message, error) {
+	locale, ok := SupportedLocale(locale)
+	if !ok {
+		locale = e.defaultLocale
+	}
+
+	tmpl, err := e.load(name, locale)
+	if errors.Is(err, ErrTemplateNotFound) && locale != e.defaultLocale {
+		locale = e.defaultLocale
+		tmpl, err = e.load(name, locale)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	message := &Message{Locale: locale}
+
+	var buf bytes.Buffer
+	if err := tmpl.subject.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render subject of %s: %w", name, err)
+	}
+	message.Subject = strings.Join(strings.Fields(buf.String()), " ")
+
+	buf.Reset()
+	if err := tmpl.text.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render text of %s: %w", name, err)
+	}
+	message.Text = strings.TrimSpace(buf.String())
+
+	if tmpl.html != nil {
+		buf.Reset()
+		if err := tmpl.html.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("failed to render html of %s: %w", name, err)
+		}
+		message.HTML = buf.String()
+	}
+
+	return message, nil
+}
+
+// load parses a template once per name, locale and version; a published version is never edited, so the cache
+// cannot go stale
+func (e *Engine) load(name, locale string) (*parsedTemplate, error) {
+	key := e.version + "/" + locale + "/" + name
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if tmpl, ok := e.cache[key]; ok {
+		return tmpl, nil
+	}
+
+	if e.source == nil {
+		return nil, errors.New("no email template source configured")
+	}
+
+	source, err := e.source.Load(name, locale, e.version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load email template %s: %w", key, err)
+	}
+
+	funcs := templateFuncs(locale)
+	tmpl := &parsedTemplate{}
+	if tmpl.subject, err = texttemplate.New(name + ".subject").Funcs(funcs).Parse(source.Subject); err != nil {
+		return nil, fmt.Errorf("failed to parse subject of email template %s: %w", key, err)
+	}
+	if tmpl.text, err = texttemplate.New(name + ".txt").Funcs(funcs).Parse(source.Text); err != nil {
+		return nil, fmt.Errorf("failed to parse text of email template %s: %w", key, err)
+	}
+	if source.HTML != "" {
+		if tmpl.html, err = htmltemplate.New(name + ".html").Funcs(htmltemplate.FuncMap(funcs)).Parse(source.HTML); err != nil {
+			return nil, fmt.Errorf("failed to parse html of email template %s: %w", key, err)
+		}
+	}
+
+	e.cache[key] = tmpl
+	return tmpl, nil
+}
+
+// Fallback loads from each source in turn, so database templates can override the files they fall back to
+type Fallback []Source
+
+func (f Fallback) Load(name, locale, version string) (*Template, error) {
+	for _, source := range f {
+		tmpl, err := source.Load(name, locale, version)
+		if errors.Is(err, ErrTemplateNotFound) {
+			continue
+		}
+		return tmpl, err
+	}
+	return nil, ErrTemplateNotFound
+}
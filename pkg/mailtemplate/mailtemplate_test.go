@@ -0,0 +1,141 @@
+package mailtemplate
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testTemplates() fstest.MapFS {
+	return fstest.MapFS{
+		"v1/id-ID/notice.subject.tmpl": {Data: []byte("Pemberitahuan\n")},
+		"v1/id-ID/notice.txt.tmpl":     {Data: []byte("Jumlah {{ money .Amount }} pada {{ date .Date }} ({{ percent .Rate }})\n")},
+		"v1/id-ID/notice.html.tmpl":    {Data: []byte("<p>{{ .Name }}: {{ money .Amount }}</p>")},
+		"v1/en-US/notice.subject.tmpl": {Data: []byte("Notice for\n  {{ .Name }}")},
+		"v1/en-US/notice.txt.tmpl":     {Data: []byte("Amount {{ money .Amount }} on {{ date .Date }} ({{ percent .Rate }})")},
+		"v1/id-ID/plain.subject.tmpl":  {Data: []byte("Teks")},
+		"v1/id-ID/plain.txt.tmpl":      {Data: []byte("Hanya teks")},
+	}
+}
+
+type noticeData struct {
+	Name   string
+	Amount float64
+	Date   time.Time
+	Rate   float64
+}
+
+var testNotice = noticeData{
+	Name:   "<Budi>",
+	Amount: 1234567.891,
+	Date:   time.Date(2025, time.August, 17, 0, 0, 0, 0, time.UTC),
+	Rate:   0.125,
+}
+
+func TestEngine_RendersPerLocale(t *testing.T) {
+	engine := NewEngine(NewFSSource(testTemplates()), "v1", LocaleID)
+
+	message, err := engine.Render("notice", "id-ID", testNotice)
+	require.NoError(t, err)
+	assert.Equal(t, LocaleID, message.Locale)
+	assert.Equal(t, "Pemberitahuan", message.Subject)
+	assert.Equal(t, "Jumlah Rp 1.234.567,89 pada 17 Agustus 2025 (12,50%)", message.Text)
+	assert.Equal(t, "<p>&lt;Budi&gt;: Rp 1.234.567,89</p>", message.HTML)
+
+	message, err = engine.Render("notice", "en_us", testNotice)
+	require.NoError(t, err)
+	assert.Equal(t, LocaleEN, message.Locale)
+	assert.Equal(t, "Notice for <Budi>", message.Subject)
+	assert.Equal(t, "Amount IDR 1,234,567.89 on August 17, 2025 (12.50%)", message.Text)
+	assert.Empty(t, message.HTML)
+}
+
+func TestEngine_FallsBackToDefaultLocale(t *testing.T) {
+	engine := NewEngine(NewFSSource(testTemplates()), "v1", LocaleID)
+
+	// Unsupported locale
+	message, err := engine.Render("notice", "fr-FR", testNotice)
+	require.NoError(t, err)
+	assert.Equal(t, LocaleID, message.Locale)
+
+	// Supported locale without a translation
+	message, err = engine.Render("plain", LocaleEN, nil)
+	require.NoError(t, err)
+	assert.Equal(t, LocaleID, message.Locale)
+	assert.Equal(t, "Hanya teks", message.Text)
+}
+
+func TestEngine_UnknownTemplateOrVersion(t *testing.T) {
+	_, err := NewEngine(NewFSSource(testTemplates()), "v1", LocaleID).Render("missing", LocaleEN, nil)
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+
+	_, err = NewEngine(NewFSSource(testTemplates()), "v2", LocaleID).Render("notice", LocaleID, testNotice)
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+}
+
+type mapSource map[string]*Template
+
+func (s mapSource) Load(name, locale, version string) (*Template, error) {
+	if tmpl, ok := s[version+"/"+locale+"/"+name]; ok {
+		return tmpl, nil
+	}
+	return nil, ErrTemplateNotFound
+}
+
+type failingSource struct{}
+
+func (failingSource) Load(name, locale, version string) (*Template, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestFallback(t *testing.T) {
+	override := mapSource{"v1/id-ID/notice": {Subject: "Diganti", Text: "Isi baru"}}
+	engine := NewEngine(Fallback{override, NewFSSource(testTemplates())}, "v1", LocaleID)
+
+	message, err := engine.Render("notice", LocaleID, testNotice)
+	require.NoError(t, err)
+	assert.Equal(t, "Diganti", message.Subject)
+
+	message, err = engine.Render("notice", LocaleEN, testNotice)
+	require.NoError(t, err)
+	assert.Equal(t, "Notice for <Budi>", message.Subject)
+
+	// A source that fails is not skipped
+	_, err = NewEngine(Fallback{failingSource{}, NewFSSource(testTemplates())}, "v1", LocaleID).Render("notice", LocaleID, testNotice)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrTemplateNotFound)
+}
+
+func TestFormatMoney(t *testing.T) {
+	tests := []struct {
+		locale string
+		amount float64
+		want   string
+	}{
+		{LocaleID, 0, "Rp 0,00"},
+		{LocaleID, 999.5, "Rp 999,50"},
+		{LocaleID, 1000, "Rp 1.000,00"},
+		{LocaleID, -2500000.005, "-Rp 2.500.000,01"},
+		{LocaleEN, 1000000, "IDR 1,000,000.00"},
+		{LocaleEN, 123456.7, "IDR 123,456.70"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, FormatMoney(tt.locale, tt.amount))
+	}
+}
+
+func TestSupportedLocale(t *testing.T) {
+	for input, want := range map[string]string{"id": LocaleID, "ID-id": LocaleID, "en": LocaleEN, " en_US ": LocaleEN} {
+		locale, ok := SupportedLocale(input)
+		assert.True(t, ok, input)
+		assert.Equal(t, want, locale)
+	}
+
+	_, ok := SupportedLocale("ja-JP")
+	assert.False(t, ok)
+}
@@ -0,0 +1,187 @@
+// pkg/pdf/document.go
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Page layout in PDF points (A4)
+const (
+	pageWidth    = 595.28
+	pageHeight   = 841.89
+	marginX      = 56.0
+	marginTop    = 64.0
+	marginBottom = 64.0
+
+	headingSize = 14.0
+	textSize    = 10.5
+	lineSpacing = 1.45
+
+	// Helvetica averages about half an em per character, which is enough to wrap plain text
+	averageCharWidth = 0.5
+)
+
+// maxLineChars is how many characters of body text fit between the margins
+var maxLineChars = func() int {
+	var usableWidth float64 = pageWidth - 2*marginX
+	return int(usableWidth / (textSize * averageCharWidth))
+}()
+
+type line struct {
+	text string
+	bold bool
+	size float64
+}
+
+// Document is a minimal text-only PDF writer using the standard Helvetica fonts, so no font files are embedded
+type Document struct {
+	title string
+	lines []line
+}
+
+func NewDocument(title string) *Document {
+	return &Document{title: title}
+}
+
+// Heading adds a bold heading line
+func (d *Document) Heading(text string) {
+	d.lines = append(d.lines, line{text: text, bold: true, size: headingSize})
+}
+
+// Paragraph adds text wrapped to the page width; an empty string adds a blank line
+func (d *Document) Paragraph(text string) {
+	if strings.TrimSpace(text) == "" {
+		d.lines = append(d.lines, line{size: textSize})
+		return
+	}
+
+	for _, wrapped := range wrap(text, maxLineChars) {
+		d.lines = append(d.lines, line{text: wrapped, size: textSize})
+	}
+}
+
+// Bytes renders the document, starting a new page whenever the current one is full
+func (d *Document) Bytes() []byte {
+	var pages []string
+	var content strings.Builder
+	y := pageHeight - marginTop
+
+	for _, l := range d.lines {
+		height := l.size * lineSpacing
+		if y-height < marginBottom {
+			pages = append(pages, content.String())
+			content.Reset()
+			y = pageHeight - marginTop
+		}
+		y -= height
+
+		if l.text == "" {
+			continue
+		}
+
+		font := "F1"
+		if l.bold {
+			font = "F2"
+		}
+		fmt.Fprintf(&content, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, l.size, marginX, y, escape(l.text))
+	}
+	pages = append(pages, content.String())
+
+	return d.write(pages)
+}
+
+// write lays out the objects: catalog, page tree, two fonts, info, then a page and content stream per page
+func (d *Document) write(pages []string) []byte {
+	var buf bytes.Buffer
+	var offsets []int
+
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	const firstPageObject = 6
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPageObject+2*i)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	object(fmt.Sprintf("<< /Title (%s) /Producer (loan-service) >>", escape(d.title)))
+
+	for i, content := range pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, firstPageObject+2*i+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return buf.Bytes()
+}
+
+// escape makes text safe inside a PDF string; characters outside Latin-1 are replaced since the fonts use WinAnsi
+func escape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\t':
+			b.WriteString("    ")
+		case r < 0x20:
+			continue
+		case r < 0x80:
+			b.WriteRune(r)
+		case r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// wrap splits text into lines of at most maxChars, breaking on spaces where possible
+func wrap(text string, maxChars int) []string {
+	var lines []string
+	var current []rune
+
+	for _, word := range strings.Fields(text) {
+		w := []rune(word)
+		for len(w) > maxChars {
+			if len(current) > 0 {
+				lines = append(lines, string(current))
+				current = nil
+			}
+			lines = append(lines, string(w[:maxChars]))
+			w = w[maxChars:]
+		}
+
+		if len(current) > 0 && len(current)+1+len(w) > maxChars {
+			lines = append(lines, string(current))
+			current = nil
+		}
+		if len(current) > 0 {
+			current = append(current, ' ')
+		}
+		current = append(current, w...)
+	}
+
+	if len(current) > 0 {
+		lines = append(lines, string(current))
+	}
+	return lines
+}
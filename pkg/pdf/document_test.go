@@ -0,0 +1,157 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseXref reads the cross-reference table the trailer points at and returns the object offsets
+func parseXref(t *testing.T, data []byte) []int {
+	t.Helper()
+
+	start := bytes.LastIndex(data, []byte("startxref\n"))
+	require.NotEqual(t, -1, start, "missing startxref")
+	fields := strings.Fields(string(data[start+len("startxref\n"):]))
+	require.NotEmpty(t, fields)
+	xref, err := strconv.Atoi(fields[0])
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(data[xref:], []byte("xref\n")), "startxref does not point at the xref table")
+
+	lines := strings.Split(string(data[xref:]), "\n")
+	var first, count int
+	_, err = fmt.Sscanf(lines[1], "%d %d", &first, &count)
+	require.NoError(t, err)
+	require.Equal(t, 0, first)
+	require.Equal(t, "0000000000 65535 f ", lines[2])
+
+	offsets := make([]int, 0, count-1)
+	for _, entry := range lines[3 : 2+count] {
+		require.Len(t, entry, 19, "xref entries are 20 bytes including the newline")
+		require.True(t, strings.HasSuffix(entry, " 00000 n "))
+		offset, err := strconv.Atoi(entry[:10])
+		require.NoError(t, err)
+		offsets = append(offsets, offset)
+	}
+
+	trailer := string(data[xref:])
+	assert.Contains(t, trailer, fmt.Sprintf("/Size %d", count))
+	return offsets
+}
+
+func TestDocument_XrefPointsAtObjects(t *testing.T) {
+	doc := NewDocument("Agreement (draft)")
+	doc.Heading("Loan Agreement")
+	doc.Paragraph("The borrower agrees to repay the principal with interest.")
+
+	data := doc.Bytes()
+	require.True(t, bytes.HasPrefix(data, []byte("%PDF-1.4\n")))
+	require.True(t, bytes.HasSuffix(data, []byte("%%EOF\n")))
+
+	offsets := parseXref(t, data)
+	require.Len(t, offsets, 7) // catalog, pages, two fonts, info, one page and its content
+	for i, offset := range offsets {
+		assert.True(t, bytes.HasPrefix(data[offset:], []byte(fmt.Sprintf("%d 0 obj\n", i+1))), "object %d offset", i+1)
+	}
+}
+
+func TestDocument_StreamLengthsMatchContent(t *testing.T) {
+	doc := NewDocument("Agreement")
+	for i := 0; i < 60; i++ {
+		doc.Paragraph(fmt.Sprintf("Clause %d", i+1))
+	}
+
+	data := doc.Bytes()
+	streams := regexp.MustCompile(`<< /Length (\d+) >>\nstream\n`).FindAllSubmatchIndex(data, -1)
+	require.Len(t, streams, 2)
+	for _, s := range streams {
+		length, err := strconv.Atoi(string(data[s[2]:s[3]]))
+		require.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(data[s[1]+length:], []byte("endstream")), "stream length does not end at endstream")
+	}
+}
+
+func TestDocument_PageBreaks(t *testing.T) {
+	// A body line takes 10.5 * 1.45 points, so 46 lines fit between the margins of an A4 page
+	tests := []struct {
+		name  string
+		lines int
+		pages int
+	}{
+		{name: "empty document", lines: 0, pages: 1},
+		{name: "full page", lines: 46, pages: 1},
+		{name: "one line over", lines: 47, pages: 2},
+		{name: "three pages", lines: 100, pages: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := NewDocument("Agreement")
+			for i := 0; i < tt.lines; i++ {
+				doc.Paragraph(fmt.Sprintf("Line %d", i+1))
+			}
+
+			data := doc.Bytes()
+			assert.Contains(t, string(data), fmt.Sprintf("/Count %d >>", tt.pages))
+			assert.Equal(t, tt.pages, bytes.Count(data, []byte("/Type /Page /Parent")))
+			assert.Len(t, parseXref(t, data), 5+2*tt.pages)
+
+			// Every line is drawn exactly once and never below the bottom margin
+			positions := regexp.MustCompile(`Td \(Line \d+\) Tj`).FindAll(data, -1)
+			assert.Len(t, positions, tt.lines)
+			for _, m := range regexp.MustCompile(`([\d.]+) ([\d.]+) Td`).FindAllSubmatch(data, -1) {
+				y, err := strconv.ParseFloat(string(m[2]), 64)
+				require.NoError(t, err)
+				assert.GreaterOrEqual(t, y, marginBottom)
+			}
+		})
+	}
+}
+
+func TestDocument_LongParagraphWraps(t *testing.T) {
+	doc := NewDocument("Agreement")
+	doc.Paragraph(strings.Repeat("word ", 60))
+
+	data := doc.Bytes()
+	shown := regexp.MustCompile(`\((word[^)]*)\) Tj`).FindAllSubmatch(data, -1)
+	require.Greater(t, len(shown), 1)
+	for _, s := range shown {
+		assert.LessOrEqual(t, len(s[1]), maxLineChars)
+	}
+}
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "plain ascii", in: "Loan 2024-01", want: "Loan 2024-01"},
+		{name: "delimiters", in: `a(b)c\d`, want: `a\(b\)c\\d`},
+		{name: "latin-1 as octal", in: "Café Müller", want: `Caf\351 M\374ller`},
+		{name: "outside latin-1", in: "€ 100 – ok ✓", want: "? 100 ? ok ?"},
+		{name: "tab expanded", in: "a\tb", want: "a    b"},
+		{name: "control characters dropped", in: "a\nb\rc\x00", want: "abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, escape(tt.in))
+		})
+	}
+}
+
+func TestDocument_EscapesTitleAndText(t *testing.T) {
+	doc := NewDocument("Perjanjian (Pinjaman) ✓")
+	doc.Paragraph("Nama: José")
+
+	data := string(doc.Bytes())
+	assert.Contains(t, data, `/Title (Perjanjian \(Pinjaman\) ?)`)
+	assert.Contains(t, data, `(Nama: Jos\351) Tj`)
+}
@@ -106,6 +106,28 @@ func (r *RedisClient) HDel(ctx context.Context, key string, fields ...string) er
 	return r.client.HDel(ctx, key, fields...).Err()
 }
 
+// hTakeScript removes a hash field and returns its value in one step
+var hTakeScript = redis.NewScript(`
+local value = redis.call('HGET', KEYS[1], ARGV[1])
+if value then
+	redis.call('HDEL', KEYS[1], ARGV[1])
+end
+return value
+`)
+
+// HTake removes a field from the hash and returns the value it had; ok is false when the field was not there. Of
+// several callers taking the same field, only one gets its value.
+func (r *RedisClient) HTake(ctx context.Context, key, field string) (value string, ok bool, err error) {
+	value, err = hTakeScript.Run(ctx, r.client, []string{key}, field).Text()
+	if err == redis.Nil {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
 // List operations
 func (r *RedisClient) LPush(ctx context.Context, key string, values ...interface{}) error {
 	return r.client.LPush(ctx, key, values...).Err()
@@ -117,6 +117,15 @@ func BadRequest(c *gin.Context, message string) {
 	})
 }
 
+// BadRequestWithCode responds with a domain specific error code so clients can tell failures apart
+func BadRequestWithCode(c *gin.Context, code, message string) {
+	c.JSON(http.StatusBadRequest, Response{
+		Status:  "failed",
+		Message: message,
+		Code:    code,
+	})
+}
+
 func NotFound(c *gin.Context, message string) {
 	c.JSON(http.StatusNotFound, Response{
 		Status:  "failed",
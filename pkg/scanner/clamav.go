@@ -0,0 +1,141 @@
+// pkg/scanner/clamav.go
+package scanner
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"loan-service/pkg/config"
+	"loan-service/pkg/logger"
+)
+
+// chunkSize stays well below clamd's default StreamMaxLength
+const chunkSize = 64 << 10
+
+// ClamAVScanner streams content to a clamd daemon with the INSTREAM command
+type ClamAVScanner struct {
+	network string
+	address string
+	timeout time.Duration
+	logger  *logger.Logger
+}
+
+// NewClamAVScanner connects to the clamd socket in cfg.Address, either tcp://host:port or unix:///path/to/clamd.ctl
+func NewClamAVScanner(cfg config.ScannerConfig, logger *logger.Logger) (*ClamAVScanner, error) {
+	address, err := url.Parse(cfg.Address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid clamav address: %w", err)
+	}
+
+	scanner := &ClamAVScanner{
+		network: address.Scheme,
+		timeout: cfg.Timeout,
+		logger:  logger,
+	}
+	switch address.Scheme {
+	case "tcp":
+		scanner.address = address.Host
+	case "unix":
+		scanner.address = address.Path
+	default:
+		return nil, fmt.Errorf("clamav address must be tcp:// or unix://, got %q", cfg.Address)
+	}
+	if scanner.address == "" {
+		return nil, fmt.Errorf("clamav address has no host or socket path: %q", cfg.Address)
+	}
+	if scanner.timeout <= 0 {
+		scanner.timeout = 2 * time.Minute
+	}
+
+	return scanner, nil
+}
+
+func (s *ClamAVScanner) Name() string {
+	return BackendClamAV
+}
+
+// Scan sends the content in length-prefixed chunks and reads the single verdict line clamd answers with
+func (s *ClamAVScanner) Scan(content io.Reader) (*Result, error) {
+	conn, err := net.DialTimeout(s.network, s.address, s.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(s.timeout)); err != nil {
+		return nil, err
+	}
+
+	if err := s.stream(conn, content); err != nil {
+		return nil, err
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && !(err == io.EOF && reply != "") {
+		return nil, fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+
+	result, err := parseReply(strings.TrimRight(reply, "\x00\n"))
+	if err != nil {
+		return nil, err
+	}
+
+	if !result.Clean {
+		s.logger.Warn("Malware found in scanned content", map[string]interface{}{
+			"signature": result.Signature,
+		})
+	}
+	return result, nil
+}
+
+// stream writes the INSTREAM command and content; the writer keeps the first write error, which Flush reports
+func (s *ClamAVScanner) stream(conn net.Conn, content io.Reader) error {
+	writer := bufio.NewWriterSize(conn, chunkSize+4)
+	writer.WriteString("zINSTREAM\x00")
+
+	buf := make([]byte, chunkSize)
+	size := make([]byte, 4)
+	for {
+		n, readErr := io.ReadFull(content, buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			writer.Write(size)
+			writer.Write(buf[:n])
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return fmt.Errorf("failed to read content to scan: %w", readErr)
+		}
+	}
+
+	// A zero-length chunk ends the stream
+	binary.BigEndian.PutUint32(size, 0)
+	writer.Write(size)
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to stream content to clamd: %w", err)
+	}
+	return nil
+}
+
+// parseReply reads "stream: OK", "stream: <signature> FOUND" or "<reason> ERROR"
+func parseReply(reply string) (*Result, error) {
+	verdict := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case verdict == "OK":
+		return &Result{Clean: true}, nil
+	case strings.HasSuffix(verdict, " FOUND"):
+		return &Result{Signature: strings.TrimSuffix(verdict, " FOUND")}, nil
+	case strings.HasSuffix(verdict, " ERROR"):
+		return nil, fmt.Errorf("clamd failed to scan: %s", strings.TrimSuffix(verdict, " ERROR"))
+	default:
+		return nil, fmt.Errorf("unexpected clamd reply: %q", reply)
+	}
+}
@@ -0,0 +1,161 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"loan-service/pkg/config"
+	"loan-service/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClamd speaks enough of the clamd INSTREAM protocol to answer with the given verdict for the streamed content
+func fakeClamd(t *testing.T, listener net.Listener, verdict func(content []byte) string) <-chan []byte {
+	t.Helper()
+	received := make(chan []byte, 1)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		command, err := reader.ReadString(0)
+		if err != nil || command != "zINSTREAM\x00" {
+			conn.Write([]byte("UNKNOWN COMMAND\x00"))
+			return
+		}
+
+		var content bytes.Buffer
+		for {
+			var size uint32
+			if err := binary.Read(reader, binary.BigEndian, &size); err != nil {
+				return
+			}
+			if size == 0 {
+				break
+			}
+			if _, err := io.CopyN(&content, reader, int64(size)); err != nil {
+				return
+			}
+		}
+
+		received <- content.Bytes()
+		conn.Write([]byte(verdict(content.Bytes()) + "\x00"))
+	}()
+
+	return received
+}
+
+func eicarVerdict(content []byte) string {
+	if bytes.Contains(content, []byte("EICAR-STANDARD-ANTIVIRUS-TEST-FILE")) {
+		return "stream: Eicar-Test-Signature FOUND"
+	}
+	return "stream: OK"
+}
+
+func newTestClamAVScanner(t *testing.T, address string) *ClamAVScanner {
+	t.Helper()
+	scanner, err := NewClamAVScanner(config.ScannerConfig{Address: address, Timeout: 5 * time.Second}, logger.NewLogger(config.LoggerConfig{Level: "error"}))
+	require.NoError(t, err)
+	return scanner
+}
+
+func TestClamAVScanner_Clean(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	received := fakeClamd(t, listener, eicarVerdict)
+	scanner := newTestClamAVScanner(t, "tcp://"+listener.Addr().String())
+
+	// Larger than one chunk so the content is streamed in several
+	content := bytes.Repeat([]byte("%PDF-1.4 "), chunkSize/4)
+	result, err := scanner.Scan(bytes.NewReader(content))
+
+	require.NoError(t, err)
+	assert.True(t, result.Clean)
+	assert.Empty(t, result.Signature)
+	assert.Equal(t, content, <-received)
+}
+
+func TestClamAVScanner_Infected(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	fakeClamd(t, listener, eicarVerdict)
+	scanner := newTestClamAVScanner(t, "tcp://"+listener.Addr().String())
+
+	eicar := `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+	result, err := scanner.Scan(strings.NewReader(eicar))
+
+	require.NoError(t, err)
+	assert.False(t, result.Clean)
+	assert.Equal(t, "Eicar-Test-Signature", result.Signature)
+}
+
+func TestClamAVScanner_UnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "clamd.ctl")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	fakeClamd(t, listener, eicarVerdict)
+	scanner := newTestClamAVScanner(t, "unix://"+socket)
+
+	result, err := scanner.Scan(strings.NewReader("\x89PNG\r\n\x1a\n"))
+
+	require.NoError(t, err)
+	assert.True(t, result.Clean)
+}
+
+func TestClamAVScanner_Error(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	fakeClamd(t, listener, func([]byte) string { return "INSTREAM size limit exceeded. ERROR" })
+	scanner := newTestClamAVScanner(t, "tcp://"+listener.Addr().String())
+
+	result, err := scanner.Scan(strings.NewReader("content"))
+
+	assert.ErrorContains(t, err, "size limit exceeded")
+	assert.Nil(t, result)
+}
+
+func TestClamAVScanner_Unreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+
+	scanner := newTestClamAVScanner(t, "tcp://"+address)
+
+	_, err = scanner.Scan(strings.NewReader("content"))
+
+	assert.ErrorContains(t, err, "failed to connect to clamd")
+}
+
+func TestNewClamAVScanner_InvalidAddress(t *testing.T) {
+	_, err := NewClamAVScanner(config.ScannerConfig{Address: "localhost:3310"}, nil)
+	assert.Error(t, err)
+}
+
+func TestNew_InvalidClamAVAddress(t *testing.T) {
+	scanner, err := New(config.ScannerConfig{Backend: BackendClamAV, Address: "localhost:3310"}, logger.NewLogger(config.LoggerConfig{Level: "error"}))
+
+	assert.Error(t, err)
+	assert.True(t, scanner == nil, "the scanner must be an untyped nil so callers can detect it")
+}
@@ -0,0 +1,60 @@
+// pkg/scanner/scanner.go
+package scanner
+
+import (
+	"fmt"
+	"io"
+
+	"loan-service/pkg/config"
+	"loan-service/pkg/logger"
+)
+
+const (
+	BackendNoop   = "noop"
+	BackendClamAV = "clamav"
+)
+
+// ScannerInterface checks file content for malware before the file may be used
+type ScannerInterface interface {
+	Name() string
+	Scan(content io.Reader) (*Result, error)
+}
+
+// Result is the verdict on scanned content; Signature names the malware found when it is not clean
+type Result struct {
+	Clean     bool
+	Signature string
+}
+
+// New creates the configured scanner; on error the returned ScannerInterface is nil
+func New(cfg config.ScannerConfig, logger *logger.Logger) (ScannerInterface, error) {
+	switch cfg.Backend {
+	case "", BackendNoop:
+		logger.Warn("Malware scanning is disabled, uploads are accepted unscanned", map[string]interface{}{})
+		return NewNoopScanner(), nil
+	case BackendClamAV:
+		// A nil *ClamAVScanner must not be returned as a non-nil ScannerInterface
+		clamav, err := NewClamAVScanner(cfg, logger)
+		if err != nil {
+			return nil, err
+		}
+		return clamav, nil
+	default:
+		return nil, fmt.Errorf("unsupported scanner backend: %s", cfg.Backend)
+	}
+}
+
+// NoopScanner reports all content clean, for environments without a scanner
+type NoopScanner struct{}
+
+func NewNoopScanner() *NoopScanner {
+	return &NoopScanner{}
+}
+
+func (s *NoopScanner) Name() string {
+	return BackendNoop
+}
+
+func (s *NoopScanner) Scan(content io.Reader) (*Result, error) {
+	return &Result{Clean: true}, nil
+}
@@ -0,0 +1,112 @@
+package signedurl
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testResource = "3f1c2b4e-8a9d-4c1e-9b7a-2d5e6f708192"
+
+func TestSignVerify(t *testing.T) {
+	signer := NewSigner("download-secret")
+	now := time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC)
+	expires := now.Add(15 * time.Minute)
+
+	signature := signer.Sign(testResource, expires)
+
+	assert.Len(t, signature, 64)
+	assert.NoError(t, signer.Verify(testResource, FormatExpires(expires), signature, now))
+	assert.NoError(t, signer.Verify(testResource, FormatExpires(expires), signature, expires), "a link is valid up to its expiry second")
+}
+
+func TestSignVerify_ExpiresEncoding(t *testing.T) {
+	signer := NewSigner("download-secret")
+	now := time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC)
+	// Sub-second precision is dropped by the encoding, and the time zone does not matter
+	expires := now.Add(15*time.Minute + 750*time.Millisecond).In(time.FixedZone("WIB", 7*60*60))
+
+	signature := signer.Sign(testResource, expires)
+	encoded := FormatExpires(expires)
+
+	assert.Equal(t, strconv.FormatInt(expires.Unix(), 10), encoded)
+	assert.NoError(t, signer.Verify(testResource, encoded, signature, now))
+	assert.Equal(t, signature, signer.Sign(testResource, expires.Truncate(time.Second).UTC()))
+
+	// The same instant spelled differently is not what was signed
+	for _, respelled := range []string{"0" + encoded, "+" + encoded, encoded + ".0", " " + encoded} {
+		assert.ErrorIs(t, signer.Verify(testResource, respelled, signature, now), ErrInvalidSignature, respelled)
+	}
+}
+
+func TestVerify_Tampered(t *testing.T) {
+	signer := NewSigner("download-secret")
+	now := time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC)
+	expires := now.Add(15 * time.Minute)
+	signature := signer.Sign(testResource, expires)
+
+	flipped := []byte(signature)
+	if flipped[0] == 'a' {
+		flipped[0] = 'b'
+	} else {
+		flipped[0] = 'a'
+	}
+
+	tests := []struct {
+		name      string
+		resource  string
+		expires   string
+		signature string
+	}{
+		{name: "other resource", resource: "9c0d1e2f-3a4b-4c5d-8e6f-708192a3b4c5", expires: FormatExpires(expires), signature: signature},
+		{name: "extended expiry", resource: testResource, expires: FormatExpires(expires.Add(24 * time.Hour)), signature: signature},
+		{name: "changed signature", resource: testResource, expires: FormatExpires(expires), signature: string(flipped)},
+		{name: "truncated signature", resource: testResource, expires: FormatExpires(expires), signature: signature[:32]},
+		{name: "not hex", resource: testResource, expires: FormatExpires(expires), signature: "not-a-signature"},
+		{name: "empty signature", resource: testResource, expires: FormatExpires(expires), signature: ""},
+		{name: "expiry not a number", resource: testResource, expires: "tomorrow", signature: signature},
+		{name: "other secret", resource: testResource, expires: FormatExpires(expires), signature: NewSigner("other-secret").Sign(testResource, expires)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := signer.Verify(tt.resource, tt.expires, tt.signature, now)
+			assert.ErrorIs(t, err, ErrInvalidSignature)
+		})
+	}
+}
+
+func TestVerify_Expired(t *testing.T) {
+	signer := NewSigner("download-secret")
+	expires := time.Date(2024, 3, 1, 8, 45, 0, 0, time.UTC)
+	signature := signer.Sign(testResource, expires)
+
+	err := signer.Verify(testResource, FormatExpires(expires), signature, expires.Add(time.Second))
+
+	assert.ErrorIs(t, err, ErrExpired)
+}
+
+func TestVerify_ExpiredTamperedIsInvalid(t *testing.T) {
+	signer := NewSigner("download-secret")
+	expires := time.Date(2024, 3, 1, 8, 45, 0, 0, time.UTC)
+
+	// A bad signature is reported as such even once the link would have expired
+	err := signer.Verify(testResource, FormatExpires(expires), NewSigner("other-secret").Sign(testResource, expires), expires.Add(time.Hour))
+
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestVerify_EmptySecret(t *testing.T) {
+	signer := NewSigner("")
+	now := time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC)
+	expires := now.Add(15 * time.Minute)
+
+	// Anyone can compute a signature with an empty key, so none is accepted
+	err := signer.Verify(testResource, FormatExpires(expires), signer.Sign(testResource, expires), now)
+
+	assert.ErrorContains(t, err, "secret is not configured")
+	assert.NotErrorIs(t, err, ErrInvalidSignature)
+	assert.NotErrorIs(t, err, ErrExpired)
+}
@@ -0,0 +1,105 @@
+// pkg/storage/local.go
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"loan-service/pkg/logger"
+)
+
+// LocalStorage keeps files in a directory on the local filesystem
+type LocalStorage struct {
+	root   string
+	logger *logger.Logger
+}
+
+func NewLocalStorage(root string, logger *logger.Logger) *LocalStorage {
+	if root == "" {
+		root = "./uploads"
+	}
+
+	return &LocalStorage{
+		root:   root,
+		logger: logger,
+	}
+}
+
+func (s *LocalStorage) Backend() string {
+	return BackendLocal
+}
+
+// Put writes to a temporary file next to the destination and renames it, so readers never see a partial file
+func (s *LocalStorage) Put(key string, content io.Reader, size int64, contentType string) (*Object, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	fullPath := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(fullPath), ".upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hasher := sha256.New()
+	written, err := io.Copy(io.MultiWriter(tmp, hasher), content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	if size >= 0 && written != size {
+		return nil, fmt.Errorf("wrote %d bytes of %s, expected %d", written, key, size)
+	}
+
+	if err := os.Rename(tmp.Name(), fullPath); err != nil {
+		return nil, fmt.Errorf("failed to store %s: %w", key, err)
+	}
+
+	s.logger.Debug("Stored file locally", map[string]interface{}{
+		"file_path":    key,
+		"size":         written,
+		"content_type": contentType,
+	})
+
+	return &Object{
+		Key:      key,
+		Size:     written,
+		Checksum: hex.EncodeToString(hasher.Sum(nil)),
+	}, nil
+}
+
+func (s *LocalStorage) Open(key string) (io.ReadSeekCloser, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(s.path(key))
+}
+
+func (s *LocalStorage) Delete(key string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(s.path(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStorage) path(key string) string {
+	return filepath.Join(s.root, filepath.FromSlash(key))
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"loan-service/pkg/config"
+	"loan-service/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLocalStorage(t *testing.T) (*LocalStorage, string) {
+	t.Helper()
+	root := t.TempDir()
+	return NewLocalStorage(root, logger.NewLogger(config.LoggerConfig{Level: "error"})), root
+}
+
+func TestLocalStorage_PutOpenDelete(t *testing.T) {
+	store, root := newTestLocalStorage(t)
+
+	object, err := store.Put("/agreements/agreement.pdf", strings.NewReader("%PDF-1.4 content"), 16, "application/pdf")
+	require.NoError(t, err)
+
+	sum := sha256.Sum256([]byte("%PDF-1.4 content"))
+	assert.Equal(t, "agreements/agreement.pdf", object.Key)
+	assert.Equal(t, int64(16), object.Size)
+	assert.Equal(t, hex.EncodeToString(sum[:]), object.Checksum)
+
+	stored, err := os.ReadFile(filepath.Join(root, "agreements", "agreement.pdf"))
+	require.NoError(t, err)
+	assert.Equal(t, "%PDF-1.4 content", string(stored))
+
+	content, err := store.Open(object.Key)
+	require.NoError(t, err)
+	_, err = content.Seek(9, io.SeekStart)
+	require.NoError(t, err)
+	rest, err := io.ReadAll(content)
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(rest))
+	require.NoError(t, content.Close())
+
+	require.NoError(t, store.Delete(object.Key))
+	_, err = os.Stat(filepath.Join(root, "agreements", "agreement.pdf"))
+	assert.True(t, os.IsNotExist(err))
+
+	assert.NoError(t, store.Delete(object.Key), "deleting a missing file is not an error")
+}
+
+func TestLocalStorage_UnknownSize(t *testing.T) {
+	store, _ := newTestLocalStorage(t)
+
+	object, err := store.Put("generated/file.pdf", strings.NewReader("%PDF"), -1, "application/pdf")
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), object.Size)
+}
+
+func TestLocalStorage_SizeMismatchLeavesNoFile(t *testing.T) {
+	store, root := newTestLocalStorage(t)
+
+	_, err := store.Put("agreements/agreement.pdf", strings.NewReader("%PDF"), 8, "application/pdf")
+	assert.ErrorContains(t, err, "expected 8")
+
+	entries, err := os.ReadDir(filepath.Join(root, "agreements"))
+	require.NoError(t, err)
+	assert.Empty(t, entries, "neither the file nor its temporary copy is left behind")
+}
+
+func TestLocalStorage_RejectsTraversal(t *testing.T) {
+	store, root := newTestLocalStorage(t)
+
+	_, err := store.Put("../escaped.pdf", strings.NewReader("%PDF"), 4, "application/pdf")
+	assert.ErrorContains(t, err, "invalid storage key")
+	_, err = store.Open("agreements/../../escaped.pdf")
+	assert.ErrorContains(t, err, "invalid storage key")
+	assert.ErrorContains(t, store.Delete("../"+filepath.Base(root)), "invalid storage key")
+
+	_, err = os.Stat(filepath.Join(filepath.Dir(root), "escaped.pdf"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,60 @@
+// pkg/storage/storage.go
+package storage
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"loan-service/pkg/config"
+	"loan-service/pkg/logger"
+)
+
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// Storage keeps file content under a slash separated key such as "agreements/agreement_loan_1a2b3c4d_v1.pdf"
+type Storage interface {
+	Backend() string
+	// Put streams content into the key; size is the exact content length
+	Put(key string, content io.Reader, size int64, contentType string) (*Object, error)
+	// Open returns seekable content so it can be served in ranges
+	Open(key string) (io.ReadSeekCloser, error)
+	Delete(key string) error
+}
+
+// Object describes stored content
+type Object struct {
+	Key      string
+	Size     int64
+	Checksum string // hex SHA-256 of the content
+}
+
+// New creates the configured storage backend; on error the returned Storage is nil
+func New(cfg config.StorageConfig, logger *logger.Logger) (Storage, error) {
+	switch cfg.Backend {
+	case "", BackendLocal:
+		return NewLocalStorage(cfg.UploadDir, logger), nil
+	case BackendS3:
+		// A nil *S3Storage must not be returned as a non-nil Storage
+		s3, err := NewS3Storage(cfg.S3, logger)
+		if err != nil {
+			return nil, err
+		}
+		return s3, nil
+	default:
+		return nil, fmt.Errorf("unsupported storage backend: %s", cfg.Backend)
+	}
+}
+
+// cleanKey rejects keys that would escape the storage root
+func cleanKey(key string) (string, error) {
+	cleaned := path.Clean(strings.TrimPrefix(key, "/"))
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("invalid storage key: %q", key)
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"testing"
+
+	"loan-service/pkg/config"
+	"loan-service/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanKey(t *testing.T) {
+	tests := []struct {
+		key      string
+		expected string
+	}{
+		{key: "agreements/agreement.pdf", expected: "agreements/agreement.pdf"},
+		{key: "/agreements/agreement.pdf", expected: "agreements/agreement.pdf"},
+		{key: "agreements/./signed/../agreement.pdf", expected: "agreements/agreement.pdf"},
+		{key: "agreements//agreement.pdf", expected: "agreements/agreement.pdf"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			cleaned, err := cleanKey(tt.key)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, cleaned)
+		})
+	}
+}
+
+func TestCleanKey_RejectsTraversal(t *testing.T) {
+	for _, key := range []string{"", ".", "/", "..", "../secret", "agreements/../../secret", "/../secret", "a/b/../../.."} {
+		t.Run(key, func(t *testing.T) {
+			_, err := cleanKey(key)
+			assert.ErrorContains(t, err, "invalid storage key")
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	log := logger.NewLogger(config.LoggerConfig{Level: "error"})
+
+	local, err := New(config.StorageConfig{UploadDir: t.TempDir()}, log)
+	require.NoError(t, err)
+	assert.Equal(t, BackendLocal, local.Backend())
+
+	s3, err := New(config.StorageConfig{Backend: BackendS3, S3: config.S3Config{
+		Endpoint:        "http://localhost:9000",
+		Bucket:          "loan-documents",
+		AccessKeyID:     "access",
+		SecretAccessKey: "secret",
+	}}, log)
+	require.NoError(t, err)
+	assert.Equal(t, BackendS3, s3.Backend())
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	log := logger.NewLogger(config.LoggerConfig{Level: "error"})
+
+	store, err := New(config.StorageConfig{Backend: BackendS3}, log)
+	assert.Error(t, err)
+	assert.True(t, store == nil, "the storage must be an untyped nil so callers can detect it")
+
+	store, err = New(config.StorageConfig{Backend: "ftp"}, log)
+	assert.ErrorContains(t, err, "unsupported storage backend")
+	assert.True(t, store == nil)
+}